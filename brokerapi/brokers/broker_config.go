@@ -23,11 +23,24 @@ import (
 	"github.com/pivotal/cloud-service-broker/pkg/brokerpak"
 	"github.com/pivotal/cloud-service-broker/pkg/config"
 	"github.com/pivotal/cloud-service-broker/pkg/credstore"
+	"github.com/pivotal/cloud-service-broker/pkg/dedupe"
+	"github.com/pivotal/cloud-service-broker/pkg/events"
+	"github.com/pivotal/cloud-service-broker/pkg/notify"
+	"github.com/pivotal/cloud-service-broker/pkg/quota"
+	"github.com/pivotal/cloud-service-broker/pkg/requestdetails"
+	"github.com/pivotal/cloud-service-broker/pkg/serviceaccounts"
 )
 
 type BrokerConfig struct {
-	Registry   broker.BrokerRegistry
-	Credstore  credstore.CredStore
+	Registry           broker.BrokerRegistry
+	Credstore          credstore.CredStore
+	QuotaRules         []quota.Rule
+	Notifier           notify.Notifier
+	Provisions         *dedupe.Group
+	RequestDetails     requestdetails.Policy
+	ServiceAccounts    serviceaccounts.Manager
+	DeletedInstanceIds string
+	Events             *events.Bus
 }
 
 func NewBrokerConfigFromEnv(logger lager.Logger) (*BrokerConfig, error) {
@@ -51,8 +64,45 @@ func NewBrokerConfigFromEnv(logger lager.Logger) (*BrokerConfig, error) {
 		}
 	}
 
+	quotaRules, err := quota.RulesFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading quotas: %v", err)
+	}
+
+	provisions, err := dedupe.NewProvisionGroupFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading provision deduplication: %v", err)
+	}
+
+	deletedInstanceIds, err := deletedInstanceIdsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	serviceAccounts, err := serviceaccounts.NewManagerFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("Failed creating service account manager: %v", err)
+	}
+
+	bus, err := events.NewBusFromEnv(logger)
+	if err != nil {
+		return nil, fmt.Errorf("Failed configuring events: %v", err)
+	}
+
+	requestDetails, err := requestdetails.NewPolicyFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading request details policy: %v", err)
+	}
+
 	return &BrokerConfig{
-		Registry:   registry,
-		Credstore:  cs,
+		Registry:           registry,
+		Credstore:          cs,
+		QuotaRules:         quotaRules,
+		Notifier:           notify.NewNotifierFromEnv(logger),
+		Provisions:         provisions,
+		RequestDetails:     requestDetails,
+		ServiceAccounts:    serviceAccounts,
+		DeletedInstanceIds: deletedInstanceIds,
+		Events:             bus,
 	}, nil
 }
@@ -16,30 +16,58 @@ package brokers_test
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/pivotal-cf/brokerapi"
 	. "github.com/pivotal/cloud-service-broker/brokerapi/brokers"
 	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/fakes"
 	"github.com/pivotal/cloud-service-broker/db_service/models"
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
 	"github.com/pivotal/cloud-service-broker/pkg/broker/brokerfakes"
+	"github.com/pivotal/cloud-service-broker/pkg/config/secrets"
 	"github.com/pivotal/cloud-service-broker/pkg/credstore"
 	"github.com/pivotal/cloud-service-broker/pkg/credstore/credstorefakes"
+	"github.com/pivotal/cloud-service-broker/pkg/dedupe"
+	"github.com/pivotal/cloud-service-broker/pkg/deprovision"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin/base"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin/storage"
+	"github.com/pivotal/cloud-service-broker/pkg/events"
+	"github.com/pivotal/cloud-service-broker/pkg/experiments"
+	"github.com/pivotal/cloud-service-broker/pkg/imports"
+	"github.com/pivotal/cloud-service-broker/pkg/inventory"
+	"github.com/pivotal/cloud-service-broker/pkg/naming"
+	"github.com/pivotal/cloud-service-broker/pkg/requestdetails"
+	"github.com/pivotal/cloud-service-broker/pkg/notify"
+	"github.com/pivotal/cloud-service-broker/pkg/osberror"
+	"github.com/pivotal/cloud-service-broker/pkg/cmek"
+	"github.com/pivotal/cloud-service-broker/pkg/projects"
+	"github.com/pivotal/cloud-service-broker/pkg/quota"
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+	"github.com/pivotal/cloud-service-broker/pkg/preview"
+	"github.com/pivotal/cloud-service-broker/pkg/protection"
+	"github.com/pivotal/cloud-service-broker/pkg/revocation"
+	"github.com/pivotal/cloud-service-broker/pkg/upgrade"
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
 	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/viper"
 	"google.golang.org/api/googleapi"
 
 	"code.cloudfoundry.org/lager"
-
-	"github.com/jinzhu/gorm"
 )
 
 // InstanceState holds the lifecycle state of a provisioned service instance.
@@ -90,6 +118,7 @@ func (s *serviceStub) DeprovisionDetails() brokerapi.DeprovisionDetails {
 // the given service.
 func (s *serviceStub) BindDetails() brokerapi.BindDetails {
 	return brokerapi.BindDetails{
+		AppGUID:   "fake-app-guid",
 		ServiceID: s.ServiceId,
 		PlanID:    s.PlanId,
 	}
@@ -113,6 +142,24 @@ func (s *serviceStub) UpdateDetails() brokerapi.UpdateDetails {
 	}
 }
 
+// UpgradeDetails creates a brokerapi.UpdateDetails object that upgrades an
+// instance of the given service to the maintenance_info version.
+func (s *serviceStub) UpgradeDetails(version string) brokerapi.UpdateDetails {
+	details := s.UpdateDetails()
+	details.MaintenanceInfo = brokerapi.MaintenanceInfo{Public: map[string]string{"version": version}, Private: version}
+	return details
+}
+
+// setUpgradeConfig sets the service's maintenance_info version and upgrade
+// policy for the duration of the test.
+func setUpgradeConfig(t *testing.T, stub *serviceStub, version, policy string) {
+	viper.Set(stub.ServiceDefinition.MaintenanceVersionProperty(), version)
+	viper.Set(stub.ServiceDefinition.UpgradePolicyProperty(), policy)
+	t.Cleanup(func() {
+		viper.Set(stub.ServiceDefinition.MaintenanceVersionProperty(), nil)
+		viper.Set(stub.ServiceDefinition.UpgradePolicyProperty(), nil)
+	})
+}
 
 // fakeService creates a ServiceDefinition with a mock ServiceProvider and
 // references to some important properties.
@@ -144,7 +191,7 @@ func fakeService(t *testing.T, isAsync bool) *serviceStub {
 		},
 	}
 
-	stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger) broker.ServiceProvider {
+	stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger, store broker.ProviderStore) broker.ServiceProvider {
 		return stub.Provider
 	}
 
@@ -155,7 +202,7 @@ func fakeService(t *testing.T, isAsync bool) *serviceStub {
 // It returns the broker and a callback used to clean up the database when done with it.
 func newStubbedBroker(t *testing.T, registry broker.BrokerRegistry, cs credstore.CredStore) (broker *ServiceBroker, closer func()) {
 	// Set up database
-	db, err := gorm.Open("sqlite3", "test.db")
+	db, err := db_service.OpenSqlite("test.db")
 	if err != nil {
 		t.Fatalf("couldn't create database: %v", err)
 	}
@@ -210,6 +257,26 @@ func assertTrue(t *testing.T, message string, val bool) {
 	}
 }
 
+// assertConcurrencyError fails the test if err isn't a 422 ConcurrencyError
+// failure response.
+func assertConcurrencyError(t *testing.T, err error) {
+	t.Helper()
+
+	failure, ok := err.(*brokerapi.FailureResponse)
+	assertTrue(t, "error should be a failure response", ok)
+	if ok {
+		assertEqual(t, "status should match", http.StatusUnprocessableEntity, failure.ValidatedStatusCode(nil))
+		assertEqual(t, "error code should match", osberror.ConcurrencyError, failure.ErrorResponse().(brokerapi.ErrorResponse).Error)
+	}
+}
+
+// queuePendingJob queues a background job working on the target.
+func queuePendingJob(t *testing.T, target string) {
+	t.Helper()
+
+	failIfErr(t, "queueing job", db_service.CreateJob(context.Background(), &models.Job{Kind: "terraform", Target: target, State: models.JobQueued}))
+}
+
 // BrokerEndpointTestCase is the base test used for testing any
 // brokerapi.ServiceBroker endpoint.
 type BrokerEndpointTestCase struct {
@@ -292,6 +359,55 @@ func TestGCPServiceBroker_Provision(t *testing.T) {
 				assertEqual(t, "provision calls should match", 1, stub.Provider.ProvisionCallCount())
 			},
 		},
+		"deleted-instance-id-rejected": {
+			ServiceState: StateDeprovisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				if ok {
+					assertEqual(t, "status should be conflict", http.StatusConflict, failure.ValidatedStatusCode(nil))
+					assertEqual(t, "error key should match", "instance-id-reused", failure.LoggerAction())
+				}
+				assertEqual(t, "provision calls should match", 1, stub.Provider.ProvisionCallCount())
+			},
+		},
+		"deleted-instance-id-purged": {
+			ServiceState: StateDeprovisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				broker.DeletedInstanceIds = DeletedInstanceIdsPurge
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"name":"second"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning again", err)
+				assertEqual(t, "provision calls should match", 2, stub.Provider.ProvisionCallCount())
+
+				pr, err := db_service.GetProvisionRequestDetailsByInstanceId(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting provision request", err)
+				assertEqual(t, "the new provision request should be used", `{"name":"second"}`, pr.RequestDetails)
+			},
+		},
+		"generates-resource-name": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.ResourceName = &naming.Rule{Variable: "name", Product: "storage", Template: "csb-{{.instance_id}}"}
+				viper.Set(stub.ServiceDefinition.NamingTemplateProperty(), "pcf-{{.space_guid_short}}-{{.instance_id}}")
+				defer viper.Set(stub.ServiceDefinition.NamingTemplateProperty(), nil)
+
+				req := stub.ProvisionDetails()
+				req.SpaceGUID = "0123456789"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+
+				_, vc := stub.Provider.ProvisionArgsForCall(0)
+				assertEqual(t, "name should be generated", "pcf-01234567-"+fakeInstanceId, vc.GetString("name"))
+
+				pr, err := db_service.GetProvisionRequestDetailsByInstanceId(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting provision request", err)
+				assertTrue(t, "name should be saved with the request", strings.Contains(pr.RequestDetails, "pcf-01234567-"+fakeInstanceId))
+			},
+		},
 		"duplicate-request": {
 			ServiceState: StateProvisioned,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
@@ -299,6 +415,103 @@ func TestGCPServiceBroker_Provision(t *testing.T) {
 				assertEqual(t, "errors should match", brokerapi.ErrInstanceAlreadyExists, err)
 			},
 		},
+		"coalesced-duplicate-request": {
+			AsyncService: true,
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				broker.Provisions = &dedupe.Group{Name: "brokers-test", Window: time.Minute}
+				stub.Provider.ProvisionStub = func(ctx context.Context, vc *varcontext.VarContext) (models.ServiceInstanceDetails, error) {
+					return models.ServiceInstanceDetails{OperationId: "operation-1"}, nil
+				}
+
+				first, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+
+				retry, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning again", err)
+				assertEqual(t, "responses should match", first, retry)
+				assertEqual(t, "provision calls should match", 1, stub.Provider.ProvisionCallCount())
+
+				different := stub.ProvisionDetails()
+				different.RawParameters = json.RawMessage(`{"name":"other"}`)
+				_, err = broker.Provision(context.Background(), fakeInstanceId, different, true)
+				assertEqual(t, "errors should match", brokerapi.ErrInstanceAlreadyExists, err)
+				assertEqual(t, "provision calls should match", 1, stub.Provider.ProvisionCallCount())
+			},
+		},
+		"retried-provision-in-progress": {
+			AsyncService: true,
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.ProvisionStub = func(ctx context.Context, vc *varcontext.VarContext) (models.ServiceInstanceDetails, error) {
+					return models.ServiceInstanceDetails{OperationType: models.ProvisionOperationType, OperationId: "operation-1"}, nil
+				}
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"name":"first"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+
+				// e.g. sent to another replica, with the parameters formatted differently
+				retry := stub.ProvisionDetails()
+				retry.RawParameters = json.RawMessage(`{ "name": "first" }`)
+				spec, err := broker.Provision(context.Background(), fakeInstanceId, retry, true)
+				failIfErr(t, "provisioning again", err)
+				assertEqual(t, "retry should poll the provision", brokerapi.ProvisionedServiceSpec{IsAsync: true, OperationData: "operation-1"}, spec)
+				assertEqual(t, "provision calls should match", 1, stub.Provider.ProvisionCallCount())
+
+				different := stub.ProvisionDetails()
+				different.RawParameters = json.RawMessage(`{"name":"other"}`)
+				_, err = broker.Provision(context.Background(), fakeInstanceId, different, true)
+				assertEqual(t, "errors should match", brokerapi.ErrInstanceAlreadyExists, err)
+			},
+		},
+		"redacts-request-details": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set(secrets.PassphraseProp, "brokers-test")
+				defer viper.Set(secrets.PassphraseProp, nil)
+				broker.RequestDetails = requestdetails.Policy{Redact: []string{"name"}, Scheme: secrets.PassphraseScheme}
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"name":"secret"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+
+				_, vc := stub.Provider.ProvisionArgsForCall(0)
+				assertEqual(t, "provider should get the parameter", "secret", vc.GetString("name"))
+
+				pr, err := db_service.GetProvisionRequestDetailsByInstanceId(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting provision request", err)
+				assertTrue(t, "parameter should be redacted", !strings.Contains(pr.RequestDetails, "secret"))
+				assertTrue(t, "request hash should be saved", pr.ParametersHash != "")
+
+				_, err = broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				failIfErr(t, "updating", err)
+
+				_, vc = stub.Provider.UpdateArgsForCall(0)
+				assertEqual(t, "update should get the redacted parameter", "secret", vc.GetString("name"))
+			},
+		},
+		"hash-only-keeps-generated-name": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set(secrets.PassphraseProp, "brokers-test")
+				defer viper.Set(secrets.PassphraseProp, nil)
+				broker.RequestDetails = requestdetails.Policy{HashOnly: true, Scheme: secrets.PassphraseScheme}
+				stub.ServiceDefinition.ResourceName = &naming.Rule{Variable: "name", Product: "storage", Template: "csb-{{.instance_id}}"}
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"location":"us"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+
+				pr, err := db_service.GetProvisionRequestDetailsByInstanceId(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting provision request", err)
+				assertTrue(t, "the generated name should be kept", strings.Contains(pr.RequestDetails, `"name":"csb-`+fakeInstanceId+`"`))
+				assertTrue(t, "the user's parameters shouldn't be kept in plaintext", !strings.Contains(pr.RequestDetails, "location"))
+			},
+		},
 		"requires-async": {
 			AsyncService: true,
 			ServiceState: StateNone,
@@ -335,6 +548,107 @@ func TestGCPServiceBroker_Provision(t *testing.T) {
 				assertEqual(t, "errors should match", ErrInvalidUserInput, err)
 			},
 		},
+		"quota-exceeded": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				broker.Quotas = &quota.Enforcer{
+					Rules: []quota.Rule{{Scope: quota.OrganizationScope, Guid: "org-1", Limit: 1}},
+					Store: db_service.NewSqlDatastore(db_service.DbConnection),
+				}
+
+				req := stub.ProvisionDetails()
+				req.OrganizationGUID = "org-1"
+				_, err := broker.Provision(context.Background(), "instance-1", req, true)
+				failIfErr(t, "provisioning within quota", err)
+
+				_, err = broker.Provision(context.Background(), "instance-2", req, true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				if ok {
+					assertEqual(t, "status should match", http.StatusForbidden, failure.ValidatedStatusCode(nil))
+				}
+
+				req.OrganizationGUID = "org-2"
+				_, err = broker.Provision(context.Background(), "instance-3", req, true)
+				failIfErr(t, "provisioning in another organization", err)
+
+				assertEqual(t, "provision calls should match", 2, stub.Provider.ProvisionCallCount())
+			},
+		},
+		"region-not-permitted": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set(stub.ServiceDefinition.AllowedRegionsProperty(), "us,eu")
+				defer viper.Set(stub.ServiceDefinition.AllowedRegionsProperty(), nil)
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"location":"asia"}`)
+				_, err := broker.Provision(context.Background(), "instance-1", req, true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				if ok {
+					assertEqual(t, "status should match", http.StatusBadRequest, failure.ValidatedStatusCode(nil))
+				}
+				assertEqual(t, "provision calls should match", 0, stub.Provider.ProvisionCallCount())
+
+				req.RawParameters = json.RawMessage(`{"location":"EU"}`)
+				_, err = broker.Provision(context.Background(), "instance-2", req, true)
+				failIfErr(t, "provisioning in a permitted region", err)
+			},
+		},
+		"project-not-permitted": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set(projects.DefaultProjectProp, "default-project")
+				viper.Set(projects.ProjectsProp, `{"team-a":{}}`)
+				defer viper.Set(projects.DefaultProjectProp, nil)
+				defer viper.Set(projects.ProjectsProp, nil)
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"project":"someone-elses-project"}`)
+				_, err := broker.Provision(context.Background(), "instance-1", req, true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				if ok {
+					assertEqual(t, "status should match", http.StatusBadRequest, failure.ValidatedStatusCode(nil))
+				}
+				assertEqual(t, "provision calls should match", 0, stub.Provider.ProvisionCallCount())
+
+				req.RawParameters = json.RawMessage(`{"project":"team-a"}`)
+				_, err = broker.Provision(context.Background(), "instance-2", req, true)
+				failIfErr(t, "provisioning in a permitted project", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), "instance-2")
+				failIfErr(t, "getting instance details", err)
+				assertEqual(t, "instance project should match", "team-a", instance.ProjectId)
+			},
+		},
+		"kms-key-not-permitted": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set(cmek.AllowedProjectsProp, "key-project")
+				defer viper.Set(cmek.AllowedProjectsProp, nil)
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"kms_key_name":"projects/other/locations/us/keyRings/r/cryptoKeys/k"}`)
+				_, err := broker.Provision(context.Background(), "instance-1", req, true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				if ok {
+					assertEqual(t, "status should match", http.StatusBadRequest, failure.ValidatedStatusCode(nil))
+				}
+				assertEqual(t, "provision calls should match", 0, stub.Provider.ProvisionCallCount())
+
+				key := "projects/key-project/locations/us/keyRings/r/cryptoKeys/k"
+				req.RawParameters = json.RawMessage(`{"kms_key_name":"` + key + `"}`)
+				_, err = broker.Provision(context.Background(), "instance-2", req, true)
+				failIfErr(t, "provisioning with a permitted key", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), "instance-2")
+				failIfErr(t, "getting instance details", err)
+				assertEqual(t, "instance key should match", key, instance.KmsKeyName)
+			},
+		},
 	}
 
 	cases.Run(t)
@@ -358,6 +672,32 @@ func TestGCPServiceBroker_Deprovision(t *testing.T) {
 				assertEqual(t, "duplicate deprovision should lead to DNE", brokerapi.ErrInstanceDoesNotExist, err)
 			},
 		},
+		"operation-in-progress": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				queuePendingJob(t, "tf:"+fakeInstanceId+":binding-1")
+
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				assertConcurrencyError(t, err)
+				assertEqual(t, "deprovision calls should match", 0, stub.Provider.DeprovisionCallCount())
+			},
+		},
+		"deprovision-in-progress": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				instance.OperationType = models.DeprovisionOperationType
+				instance.OperationId = "destroy-1"
+				failIfErr(t, "saving instance", db_service.SaveServiceInstanceDetails(context.Background(), instance))
+				queuePendingJob(t, "tf:"+fakeInstanceId+":")
+
+				response, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning again", err)
+				assertEqual(t, "response should match", brokerapi.DeprovisionServiceSpec{IsAsync: true, OperationData: "destroy-1"}, response)
+				assertEqual(t, "deprovision calls should match", 0, stub.Provider.DeprovisionCallCount())
+			},
+		},
 		"instance-does-not-exist": {
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
 				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
@@ -427,6 +767,39 @@ func TestGCPServiceBroker_Bind(t *testing.T) {
 			},
 			Credstore: &credstorefakes.FakeCredStore{},
 		},
+		"unknown-instance": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				assertEqual(t, "errors should match", brokerapi.ErrInstanceDoesNotExist, err)
+			},
+		},
+		"operation-in-progress": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				queuePendingJob(t, "tf:"+fakeInstanceId+":")
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				assertConcurrencyError(t, err)
+				assertEqual(t, "BindCallCount should match", 0, stub.Provider.BindCallCount())
+			},
+		},
+		"credstore-requires-app": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				req.AppGUID = ""
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				if ok {
+					assertEqual(t, "status should match", http.StatusUnprocessableEntity, failure.ValidatedStatusCode(nil))
+					assertEqual(t, "error code should match", brokerapi.ErrorResponse{Error: osberror.RequiresApp, Description: err.Error()}, failure.ErrorResponse())
+				}
+				assertEqual(t, "BindCallCount should match", 0, stub.Provider.BindCallCount())
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},
 		"duplicate-request": {
 			ServiceState: StateBound,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
@@ -445,6 +818,21 @@ func TestGCPServiceBroker_Bind(t *testing.T) {
 				assertEqual(t, "errors should match", expectedErr, err.Error())
 			},
 		},
+		"role-not-whitelisted": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set(stub.ServiceDefinition.RoleWhitelistProperty(), "storage.objectViewer")
+				defer viper.Set(stub.ServiceDefinition.RoleWhitelistProperty(), nil)
+
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage(`{"role":"storage.objectAdmin"}`)
+
+				expectedErr := `role "storage.objectAdmin" is not permitted for service google-storage, permitted roles are: storage.objectViewer`
+				_, err := broker.Bind(context.Background(), fakeInstanceId, "role-not-whitelisted", req, true)
+				assertEqual(t, "errors should match", expectedErr, err.Error())
+				assertEqual(t, "BindCallCount should match", 0, stub.Provider.BindCallCount())
+			},
+		},
 		"bad-request-json": {
 			ServiceState: StateProvisioned,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
@@ -479,6 +867,51 @@ func TestGCPServiceBroker_Bind(t *testing.T) {
 			},
 			Credstore: &credstorefakes.FakeCredStore{},
 		},	
+		"instance-not-shareable": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provisionReq := stub.ProvisionDetails()
+				provisionReq.SpaceGUID = "space-1"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, provisionReq, true)
+				failIfErr(t, "provisioning", err)
+
+				req := stub.BindDetails()
+				req.RawContext = json.RawMessage(`{"organization_guid":"org-2","space_guid":"space-2"}`)
+				_, err = broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				if ok {
+					assertEqual(t, "status should match", http.StatusUnprocessableEntity, failure.ValidatedStatusCode(nil))
+				}
+				assertEqual(t, "BindCallCount should match", 0, stub.Provider.BindCallCount())
+			},
+		},
+		"shared-instance": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Shareable = true
+				defer func() { stub.ServiceDefinition.Shareable = false }()
+
+				provisionReq := stub.ProvisionDetails()
+				provisionReq.SpaceGUID = "space-1"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, provisionReq, true)
+				failIfErr(t, "provisioning", err)
+
+				for i, space := range []string{"space-1", "space-2", "space-2"} {
+					req := stub.BindDetails()
+					req.RawContext = json.RawMessage(`{"organization_guid":"org-1","space_guid":"` + space + `"}`)
+					_, err = broker.Bind(context.Background(), fakeInstanceId, fmt.Sprintf("binding-%d", i), req, true)
+					failIfErr(t, "binding", err)
+				}
+
+				shares, err := db_service.ListInstanceShares(context.Background(), fakeInstanceId)
+				failIfErr(t, "listing shares", err)
+				assertEqual(t, "share count should match", 1, len(shares))
+				if len(shares) == 1 {
+					assertEqual(t, "shared space should match", "space-2", shares[0].SpaceGuid)
+				}
+			},
+		},
 	}
 
 	cases.Run(t)
@@ -486,6 +919,55 @@ func TestGCPServiceBroker_Bind(t *testing.T) {
 
 func TestGCPServiceBroker_Unbind(t *testing.T) {
 	cases := BrokerEndpointTestSuite{
+		"deletes-service-account": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				accounts := &recordingServiceAccounts{}
+				broker.ServiceAccounts = accounts
+				bindServiceAccount(stub, "pcf-binding-1@p.iam.gserviceaccount.com", "key-1")
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failIfErr(t, "binding", err)
+
+				_, err = broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding", err)
+				assertEqual(t, "deleted accounts should match", []string{"pcf-binding-1@p.iam.gserviceaccount.com/key-1"}, accounts.Deleted)
+			},
+		},
+		"service-account-delete-fails": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				broker.ServiceAccounts = &recordingServiceAccounts{DeleteErr: errors.New("permission denied")}
+				bindServiceAccount(stub, "pcf-binding-1@p.iam.gserviceaccount.com", "key-1")
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failIfErr(t, "binding", err)
+
+				_, err = broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				assertTrue(t, "unbinding should fail", err != nil)
+
+				exists, err := db_service.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "checking binding", err)
+				assertTrue(t, "binding should be kept so unbind can be retried", exists)
+			},
+		},
+		"keeps-shared-service-account": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				accounts := &recordingServiceAccounts{}
+				broker.ServiceAccounts = accounts
+				bindServiceAccount(stub, "shared@p.iam.gserviceaccount.com", "key-1")
+
+				for _, id := range []string{"binding-a", "binding-b"} {
+					_, err := broker.Bind(context.Background(), fakeInstanceId, id, stub.BindDetails(), true)
+					failIfErr(t, "binding", err)
+				}
+
+				_, err := broker.Unbind(context.Background(), fakeInstanceId, "binding-a", stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding", err)
+				assertEqual(t, "no accounts should be deleted", 0, len(accounts.Deleted))
+			},
+		},
 		"good-request": {
 			ServiceState: StateBound,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
@@ -493,6 +975,26 @@ func TestGCPServiceBroker_Unbind(t *testing.T) {
 				failIfErr(t, "unbinding", err)
 			},
 		},
+		"dry-run": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				notifier := &recordingNotifier{}
+				broker.Notifier = notifier
+
+				report, err := broker.RevokeCredentials(planning.WithDryRun(context.Background()), revocation.Filter{Service: stub.ServiceId})
+				failIfErr(t, "revoking", err)
+				assertTrue(t, "report should be a dry run", report.DryRun)
+				assertEqual(t, "planned count should match", 1, len(report.Planned))
+				assertEqual(t, "planned binding should match", fakeBindingId, report.Planned[0].BindingId)
+				assertEqual(t, "revoked count should match", 0, len(report.Revoked))
+				assertEqual(t, "unbind calls should match", 0, stub.Provider.UnbindCallCount())
+				assertEqual(t, "notification count should match", 0, len(notifier.Notifications))
+
+				revoked, err := broker.ListRevokedBindings(context.Background())
+				failIfErr(t, "listing revoked bindings", err)
+				assertEqual(t, "revoked binding count should match", 0, len(revoked))
+			},
+		},
 		"good-request-with-credhub": {
 			ServiceState: StateBound,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
@@ -516,83 +1018,938 @@ func TestGCPServiceBroker_Unbind(t *testing.T) {
 	cases.Run(t)
 }
 
-func TestGCPServiceBroker_LastOperation(t *testing.T) {
+// recordingServiceAccounts is a serviceaccounts.Manager that keeps the
+// accounts it's asked to delete.
+type recordingServiceAccounts struct {
+	DeleteErr error
+	Deleted   []string
+}
+
+func (r *recordingServiceAccounts) Exists(ctx context.Context, email string) (bool, error) {
+	return true, nil
+}
+
+func (r *recordingServiceAccounts) Delete(ctx context.Context, email, keyId string) error {
+	if r.DeleteErr != nil {
+		return r.DeleteErr
+	}
+	r.Deleted = append(r.Deleted, email+"/"+keyId)
+	return nil
+}
+
+// bindServiceAccount makes the stub's bindings return a key of the given
+// service account.
+func bindServiceAccount(stub *serviceStub, email, keyId string) {
+	keyFile := fmt.Sprintf(`{"private_key_id":%q,"client_email":%q}`, keyId, email)
+	stub.Provider.BindStub = func(ctx context.Context, vc *varcontext.VarContext) (map[string]interface{}, error) {
+		return map[string]interface{}{"Email": email, "PrivateKeyData": base64.StdEncoding.EncodeToString([]byte(keyFile))}, nil
+	}
+}
+
+// recordingNotifier is a notify.Notifier that keeps every notification.
+type recordingNotifier struct {
+	Notifications []notify.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification notify.Notification) error {
+	n.Notifications = append(n.Notifications, notification)
+	return nil
+}
+
+func TestGCPServiceBroker_RevokeCredentials(t *testing.T) {
 	cases := BrokerEndpointTestSuite{
-		"missing-instance": {
-			ServiceState: StateProvisioned,
-			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.LastOperation(context.Background(), "invalid-instance-id", brokerapi.PollDetails{OperationData: "operationtoken"})
-				assertEqual(t, "errors should match", brokerapi.ErrInstanceDoesNotExist, err)
-			},
-		},
-		"called-on-synchronous-service": {
-			ServiceState: StateProvisioned,
+		"empty-filter": {
+			ServiceState: StateBound,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
-				assertEqual(t, "errors should match", brokerapi.ErrAsyncRequired, err)
+				_, err := broker.RevokeCredentials(context.Background(), revocation.Filter{Reason: "oops"})
+				assertEqual(t, "errors should match", revocation.ErrEmptyFilter, err)
 			},
 		},
-		"called-on-async-service": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+		"no-matches": {
+			ServiceState: StateBound,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
-				failIfErr(t, "shouldn't be called on async service", err)
-
-				assertEqual(t, "PollInstanceCallCount should match", 1, stub.Provider.PollInstanceCallCount())
+				report, err := broker.RevokeCredentials(context.Background(), revocation.Filter{OrganizationGuid: "some-other-org"})
+				failIfErr(t, "revoking", err)
+				assertEqual(t, "revoked count should match", 0, len(report.Revoked))
+				assertEqual(t, "unbind calls should match", 0, stub.Provider.UnbindCallCount())
 			},
 		},
-		"poll-returns-retryable-error": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+		"created-before": {
+			ServiceState: StateBound,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				stub.Provider.PollInstanceReturns(false, "", &googleapi.Error{Code: 503})
-				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
-				failIfErr(t, "checking last operation", err)
-				assertEqual(t, "retryable errors should result in in-progress state", brokerapi.InProgress, status.State)
-				assertEqual(t, "description should be error string", "googleapi: got HTTP response code 503 with body: ", status.Description)
+				cutoff := time.Now().Add(-time.Hour)
+				report, err := broker.RevokeCredentials(context.Background(), revocation.Filter{CreatedBefore: &cutoff})
+				failIfErr(t, "revoking", err)
+				assertEqual(t, "revoked count should match", 0, len(report.Revoked))
 			},
 		},
-		"poll-returns-failure": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+		"good-request": {
+			ServiceState: StateBound,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				stub.Provider.PollInstanceReturns(false, "", errors.New("not-retryable"))
-				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
-				failIfErr(t, "checking last operation", err)
-				assertEqual(t, "non-retryable errors should result in a failure state", brokerapi.Failed, status.State)
-				assertEqual(t, "description should be error string", "not-retryable", status.Description)
+				notifier := &recordingNotifier{}
+				broker.Notifier = notifier
+
+				report, err := broker.RevokeCredentials(context.Background(), revocation.Filter{Service: stub.ServiceDefinition.Name, Plan: stub.PlanId, Reason: "key leaked"})
+				failIfErr(t, "revoking", err)
+				assertEqual(t, "revoked count should match", 1, len(report.Revoked))
+				assertEqual(t, "failed count should match", 0, len(report.Failed))
+				assertEqual(t, "revoked binding should match", fakeBindingId, report.Revoked[0].BindingId)
+				assertEqual(t, "unbind calls should match", 1, stub.Provider.UnbindCallCount())
+				assertEqual(t, "notification count should match", 1, len(notifier.Notifications))
+				assertEqual(t, "notification event should match", revocation.CredentialsRevokedEvent, notifier.Notifications[0].Event)
+
+				revoked, err := broker.ListRevokedBindings(context.Background())
+				failIfErr(t, "listing revoked bindings", err)
+				assertEqual(t, "revoked binding count should match", 1, len(revoked))
+
+				// revoking again is a no-op
+				report, err = broker.RevokeCredentials(context.Background(), revocation.Filter{Service: stub.ServiceId})
+				failIfErr(t, "revoking again", err)
+				assertEqual(t, "revoked count should match", 0, len(report.Revoked))
+
+				// unbinding doesn't try to delete the credentials again
+				_, err = broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding", err)
+				assertEqual(t, "unbind calls should match", 1, stub.Provider.UnbindCallCount())
+
+				revoked, err = broker.ListRevokedBindings(context.Background())
+				failIfErr(t, "listing revoked bindings", err)
+				assertEqual(t, "revoked binding count should match", 0, len(revoked))
 			},
 		},
-		"poll-returns-not-done": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+		"dry-run": {
+			ServiceState: StateBound,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				stub.Provider.PollInstanceReturns(false, "", nil)
-				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
-				failIfErr(t, "checking last operation", err)
-				assertEqual(t, "polls that return no error should result in an in-progress state", brokerapi.InProgress, status.State)
+				notifier := &recordingNotifier{}
+				broker.Notifier = notifier
+
+				report, err := broker.RevokeCredentials(planning.WithDryRun(context.Background()), revocation.Filter{Service: stub.ServiceId})
+				failIfErr(t, "revoking", err)
+				assertTrue(t, "report should be a dry run", report.DryRun)
+				assertEqual(t, "planned count should match", 1, len(report.Planned))
+				assertEqual(t, "planned binding should match", fakeBindingId, report.Planned[0].BindingId)
+				assertEqual(t, "revoked count should match", 0, len(report.Revoked))
+				assertEqual(t, "unbind calls should match", 0, stub.Provider.UnbindCallCount())
+				assertEqual(t, "notification count should match", 0, len(notifier.Notifications))
+
+				revoked, err := broker.ListRevokedBindings(context.Background())
+				failIfErr(t, "listing revoked bindings", err)
+				assertEqual(t, "revoked binding count should match", 0, len(revoked))
 			},
 		},
-		"poll-returns-success": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+		"good-request-with-credhub": {
+			ServiceState: StateBound,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				stub.Provider.PollInstanceReturns(true, "message", nil)
-				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
-				failIfErr(t, "checking last operation", err)
-				assertEqual(t, "polls that return finished should result in a succeeded state", brokerapi.Succeeded, status.State)
-				assertEqual(t, "polls that return finished should have status message", "message", status.Description)
+				_, err := broker.RevokeCredentials(context.Background(), revocation.Filter{Service: stub.ServiceId})
+				failIfErr(t, "revoking", err)
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				assertEqual(t, "Credstore Delete call count should match", 1, fcs.DeleteCallCount())
 			},
+			Credstore: &credstorefakes.FakeCredStore{},
 		},
 	}
 
 	cases.Run(t)
 }
 
-func TestGCPServiceBroker_GetBinding(t *testing.T) {
+func TestGCPServiceBroker_ApproveUpgrades(t *testing.T) {
 	cases := BrokerEndpointTestSuite{
-		"called-on-bound": {
+		"empty-approval": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.ApproveUpgrades(context.Background(), upgrade.Approval{})
+				assertEqual(t, "errors should match", upgrade.ErrEmptyApproval, err)
+			},
+		},
+		"up-to-date": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				setUpgradeConfig(t, stub, "", "manual")
+
+				report, err := broker.ApproveUpgrades(context.Background(), upgrade.Approval{InstanceIds: []string{fakeInstanceId}})
+				failIfErr(t, "approving", err)
+				assertEqual(t, "approved count should match", 0, len(report.Approved))
+				assertEqual(t, "skipped count should match", 1, len(report.Skipped))
+			},
+		},
+		"not-manual": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				setUpgradeConfig(t, stub, "2", "pinned")
+
+				report, err := broker.ApproveUpgrades(context.Background(), upgrade.Approval{Service: stub.ServiceDefinition.Name})
+				failIfErr(t, "approving", err)
+				assertEqual(t, "approved count should match", 0, len(report.Approved))
+				assertEqual(t, "skipped count should match", 1, len(report.Skipped))
+				assertEqual(t, "skipped policy should match", upgrade.PolicyPinned, report.Skipped[0].Policy)
+			},
+		},
+		"by-service": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				setUpgradeConfig(t, stub, "2", "manual")
+
+				report, err := broker.ApproveUpgrades(context.Background(), upgrade.Approval{Service: stub.ServiceId})
+				failIfErr(t, "approving", err)
+				assertEqual(t, "approved count should match", 1, len(report.Approved))
+				assertEqual(t, "approved state should match", upgrade.StateApproved, report.Approved[0].State)
+				assertEqual(t, "available version should match", "2", report.Approved[0].AvailableVersion)
+			},
+		},
+		"dry-run": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				setUpgradeConfig(t, stub, "2", "manual")
+
+				report, err := broker.ApproveUpgrades(planning.WithDryRun(context.Background()), upgrade.Approval{Service: stub.ServiceId})
+				failIfErr(t, "approving", err)
+				assertTrue(t, "report should be a dry run", report.DryRun)
+				assertEqual(t, "planned count should match", 1, len(report.Planned))
+				assertEqual(t, "approved count should match", 0, len(report.Approved))
+
+				upgrades, err := broker.ListUpgrades(context.Background())
+				failIfErr(t, "listing upgrades", err)
+				assertEqual(t, "upgrade count should match", 1, len(upgrades))
+				assertEqual(t, "upgrade state should match", "", upgrades[0].State)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+// importingProvider is a provider whose plans import existing resources.
+type importingProvider struct {
+	*brokerfakes.FakeServiceProvider
+
+	Vars []*varcontext.VarContext
+}
+
+func (p *importingProvider) Import(ctx context.Context, vc *varcontext.VarContext) (models.ServiceInstanceDetails, error) {
+	p.Vars = append(p.Vars, vc)
+	return models.ServiceInstanceDetails{OperationId: "tf:" + fakeInstanceId + ":", OperationType: models.ProvisionOperationType}, nil
+}
+
+func (p *importingProvider) PlanCapabilities(plan broker.ServicePlan, derived broker.Capabilities) broker.Capabilities {
+	derived.Import = true
+	return derived
+}
+
+// enableImports makes the stub's provider import existing resources.
+func enableImports(stub *serviceStub) *importingProvider {
+	provider := &importingProvider{FakeServiceProvider: stub.Provider}
+	stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger, store broker.ProviderStore) broker.ServiceProvider {
+		return provider
+	}
+
+	return provider
+}
+
+func TestGCPServiceBroker_ImportInstance(t *testing.T) {
+	request := func(stub *serviceStub) imports.Request {
+		plan, err := stub.ServiceDefinition.GetPlanById(stub.PlanId)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return imports.Request{
+			InstanceId:       fakeInstanceId,
+			Service:          stub.ServiceDefinition.Name,
+			Plan:             plan.Name,
+			OrganizationGuid: "org-1",
+			SpaceGuid:        "space-1",
+			Parameters:       json.RawMessage(`{"name":"existing-bucket"}`),
+		}
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"imports": {
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provider := enableImports(stub)
+
+				result, err := broker.ImportInstance(context.Background(), request(stub))
+				failIfErr(t, "importing", err)
+				assertEqual(t, "result should match", imports.Result{
+					InstanceId:  fakeInstanceId,
+					ServiceId:   stub.ServiceId,
+					PlanId:      stub.PlanId,
+					Async:       true,
+					OperationId: "tf:" + fakeInstanceId + ":",
+				}, *result)
+
+				assertEqual(t, "import count should match", 1, len(provider.Vars))
+				assertEqual(t, "imported name should match", "existing-bucket", provider.Vars[0].GetString("name"))
+				assertEqual(t, "provision count should match", 0, stub.Provider.ProvisionCallCount())
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "space should match", "space-1", instance.SpaceGuid)
+				assertEqual(t, "operation should match", result.OperationId, instance.OperationId)
+
+				pr, err := db_service.GetProvisionRequestDetailsByInstanceId(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting request details", err)
+				assertTrue(t, "request details should hold the parameters", strings.Contains(pr.RequestDetails, "existing-bucket"))
+			},
+		},
+		"instance-exists": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				enableImports(stub)
+
+				_, err := broker.ImportInstance(context.Background(), request(stub))
+				assertEqual(t, "errors should match", brokerapi.ErrInstanceAlreadyExists, err)
+			},
+		},
+		"not-importable": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.ImportInstance(context.Background(), request(stub))
+				if _, ok := err.(*imports.InvalidRequestError); !ok {
+					t.Fatalf("expected an invalid request error, got %v", err)
+				}
+			},
+		},
+		"unknown-plan": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				enableImports(stub)
+
+				req := request(stub)
+				req.Plan = "no-such-plan"
+				_, err := broker.ImportInstance(context.Background(), req)
+				if _, ok := err.(*imports.InvalidRequestError); !ok {
+					t.Fatalf("expected an invalid request error, got %v", err)
+				}
+			},
+		},
+		"missing-fields": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.ImportInstance(context.Background(), imports.Request{InstanceId: fakeInstanceId})
+				assertEqual(t, "error should match", "missing required fields: service, plan, organization_guid, space_guid", fmt.Sprint(err))
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+// protectPlan configures the stub's plan to be deletion protected for the
+// duration of the test.
+func protectPlan(t *testing.T, stub *serviceStub) {
+	viper.Set(stub.ServiceDefinition.DeletionProtectedPlansProperty(), stub.PlanId)
+	t.Cleanup(func() {
+		viper.Set(stub.ServiceDefinition.DeletionProtectedPlansProperty(), nil)
+	})
+}
+
+func TestGCPServiceBroker_Protection(t *testing.T) {
+	yes, no := true, false
+
+	assertProtected := func(t *testing.T, err error) {
+		failure, ok := err.(*brokerapi.FailureResponse)
+		if !ok {
+			t.Fatalf("expected a failure response, got %v", err)
+		}
+		assertEqual(t, "status should match", http.StatusUnprocessableEntity, failure.ValidatedStatusCode(nil))
+		assertTrue(t, "error should explain how to unprotect", strings.Contains(err.Error(), "/admin/instances/"+fakeInstanceId+"/protection"))
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"unprotected": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				status, err := broker.GetProtection(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting protection", err)
+				assertEqual(t, "status should match", protection.Status{InstanceId: fakeInstanceId}, *status)
+			},
+		},
+		"protected-instance": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				status, err := broker.SetProtection(context.Background(), fakeInstanceId, protection.Change{Protected: &yes, Reason: "production database"})
+				failIfErr(t, "protecting", err)
+				assertTrue(t, "instance should be protected", status.Protected && status.Source == protection.SourceInstance)
+
+				_, err = broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				assertProtected(t, err)
+				assertTrue(t, "error should hold the reason", strings.Contains(err.Error(), "production database"))
+				assertEqual(t, "deprovision calls should match", 0, stub.Provider.DeprovisionCallCount())
+
+				_, err = broker.SetProtection(context.Background(), fakeInstanceId, protection.Change{Protected: &no})
+				failIfErr(t, "unprotecting", err)
+
+				_, err = broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+				assertEqual(t, "deprovision calls should match", 1, stub.Provider.DeprovisionCallCount())
+			},
+		},
+		"protected-plan": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				protectPlan(t, stub)
+
+				status, err := broker.GetProtection(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting protection", err)
+				assertTrue(t, "instance should be protected by its plan", status.Protected && status.Source == protection.SourcePlan)
+
+				_, err = broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				assertProtected(t, err)
+				assertEqual(t, "deprovision calls should match", 0, stub.Provider.DeprovisionCallCount())
+			},
+		},
+		"instance-overrides-plan": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				protectPlan(t, stub)
+
+				status, err := broker.SetProtection(context.Background(), fakeInstanceId, protection.Change{Protected: &no, Reason: "decommissioning"})
+				failIfErr(t, "unprotecting", err)
+				assertTrue(t, "instance should be unprotected", !status.Protected && status.Source == protection.SourceInstance)
+
+				_, err = broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+			},
+		},
+		"unknown-instance": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.GetProtection(context.Background(), fakeInstanceId)
+				assertEqual(t, "get error should match", protection.ErrInstanceNotFound, err)
+
+				_, err = broker.SetProtection(context.Background(), fakeInstanceId, protection.Change{Protected: &yes})
+				assertEqual(t, "set error should match", protection.ErrInstanceNotFound, err)
+			},
+		},
+		"missing-protected": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.SetProtection(context.Background(), fakeInstanceId, protection.Change{Reason: "production"})
+				assertEqual(t, "error should match", protection.ErrMissingProtected, err)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+// pinParameters pins the stub's provision parameters for the duration of the
+// test.
+func pinParameters(t *testing.T, stub *serviceStub, pinned string) {
+	viper.Set(stub.ServiceDefinition.ProvisionPinnedProperty(), pinned)
+	t.Cleanup(func() {
+		viper.Set(stub.ServiceDefinition.ProvisionPinnedProperty(), nil)
+	})
+}
+
+func TestGCPServiceBroker_ProvisionParameters(t *testing.T) {
+	assertPinned := func(t *testing.T, err error) {
+		assertEqual(t, "error code should match", osberror.ParameterPinned, osberror.Code(err))
+		assertTrue(t, "error should name the parameter", strings.Contains(err.Error(), "location"))
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"layered": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Plans[0].ProvisionDefaults = map[string]interface{}{"location": "US", "force_delete": "true"}
+				pinParameters(t, stub, `{"location":"EU"}`)
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"name":"reports","force_delete":"false","location":"EU"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+
+				_, vc := stub.Provider.ProvisionArgsForCall(0)
+				assertEqual(t, "pinned location should be used", "EU", vc.GetString("location"))
+				assertEqual(t, "user value should win over the plan's default", "false", vc.GetString("force_delete"))
+
+				pr, err := db_service.GetProvisionRequestDetailsByInstanceId(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting provision request", err)
+				assertEqual(t, "resolved parameters should be saved", `{"force_delete":"false","location":"EU","name":"reports"}`, pr.RequestDetails)
+			},
+		},
+		"plan-defaults": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Plans[0].ProvisionDefaults = map[string]interface{}{"force_delete": "true"}
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+
+				_, vc := stub.Provider.ProvisionArgsForCall(0)
+				assertEqual(t, "plan default should be used", "true", vc.GetString("force_delete"))
+			},
+		},
+		"default-expressions": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set(stub.ServiceDefinition.ProvisionDefaultOverrideProperty(), `{"name":"${instance_id_short}-${random_string(6)}"}`)
+				defer viper.Set(stub.ServiceDefinition.ProvisionDefaultOverrideProperty(), nil)
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+
+				_, vc := stub.Provider.ProvisionArgsForCall(0)
+				name := vc.GetString("name")
+				assertTrue(t, "name should be evaluated", strings.HasPrefix(name, fakeInstanceId+"-") && len(name) == len(fakeInstanceId)+7)
+
+				pr, err := db_service.GetProvisionRequestDetailsByInstanceId(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting provision request", err)
+				assertEqual(t, "evaluated name should be saved", `{"name":"`+name+`"}`, pr.RequestDetails)
+
+				_, err = broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				failIfErr(t, "updating", err)
+
+				_, vc = stub.Provider.UpdateArgsForCall(0)
+				assertEqual(t, "update should keep the saved name", name, vc.GetString("name"))
+			},
+		},
+		"provision-changes-pinned": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				pinParameters(t, stub, `{"location":"EU"}`)
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"location":"US"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				assertPinned(t, err)
+				assertEqual(t, "provision calls should match", 0, stub.Provider.ProvisionCallCount())
+			},
+		},
+		"update-changes-pinned": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				pinParameters(t, stub, `{"location":"EU"}`)
+
+				req := stub.UpdateDetails()
+				req.RawParameters = json.RawMessage(`{"location":"US"}`)
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				assertPinned(t, err)
+				assertEqual(t, "update calls should match", 0, stub.Provider.UpdateCallCount())
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_PlatformContext(t *testing.T) {
+	provision := func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+		req := stub.ProvisionDetails()
+		req.RawContext = json.RawMessage(`{"platform":"cloudfoundry","organization_name":"eng","space_name":"dev","space_annotations":{}}`)
+		_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+		failIfErr(t, "provisioning", err)
+	}
+
+	assertSpaceName := func(t *testing.T, expected string) {
+		instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+		failIfErr(t, "getting instance", err)
+
+		saved, err := instance.GetPlatformContext()
+		failIfErr(t, "reading instance context", err)
+		assertEqual(t, "saved space name should match", expected, saved.SpaceName)
+
+		labels, err := instance.GetLabels()
+		failIfErr(t, "reading instance labels", err)
+		assertEqual(t, "space name label should match", expected, labels["pcf-space-name"])
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"provision-saves-context": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provision(t, broker, stub)
+				assertSpaceName(t, "dev")
+
+				pr, err := db_service.GetProvisionRequestDetailsByInstanceId(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting provision request", err)
+				assertTrue(t, "raw context should be saved", strings.Contains(pr.Context, `"space_annotations":{}`))
+			},
+		},
+		"context-in-naming-defaults": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set(stub.ServiceDefinition.ProvisionDefaultOverrideProperty(), `{"name":"${space_name}-${instance_id_short}"}`)
+				defer viper.Set(stub.ServiceDefinition.ProvisionDefaultOverrideProperty(), nil)
+
+				provision(t, broker, stub)
+
+				_, vc := stub.Provider.ProvisionArgsForCall(0)
+				assertEqual(t, "name should use the space name", "dev-"+fakeInstanceId, vc.GetString("name"))
+			},
+		},
+		"update-without-context-keeps-it": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provision(t, broker, stub)
+
+				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				failIfErr(t, "updating", err)
+				assertSpaceName(t, "dev")
+			},
+		},
+		"update-with-context-refreshes-it": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provision(t, broker, stub)
+
+				req := stub.UpdateDetails()
+				req.RawContext = json.RawMessage(`{"platform":"cloudfoundry","organization_name":"eng","space_name":"prod"}`)
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "updating", err)
+				assertSpaceName(t, "prod")
+			},
+		},
+		"update-without-previous-values-keeps-labels": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.ProvisionDetails()
+				req.OrganizationGUID = "org-guid"
+				req.SpaceGUID = "space-guid"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+
+				_, err = broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				failIfErr(t, "updating", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				labels, err := instance.GetLabels()
+				failIfErr(t, "reading instance labels", err)
+				assertEqual(t, "organization label should match", "org-guid", labels["pcf-organization-guid"])
+				assertEqual(t, "space label should match", "space-guid", labels["pcf-space-guid"])
+
+				_, vc := stub.Provider.UpdateArgsForCall(0)
+				assertTrue(t, "labels variable should have the organization", strings.Contains(vc.GetString("labels"), `"pcf-organization-guid":"org-guid"`))
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_PlanConstraints(t *testing.T) {
+	constrain := func(t *testing.T, stub *serviceStub) {
+		viper.Set(stub.ServiceDefinition.PlanConstraintsProperty(), `{"standard":{"location":{"enum":["EU","US"]},"force_delete":{"enum":["false"]}}}`)
+		t.Cleanup(func() {
+			viper.Set(stub.ServiceDefinition.PlanConstraintsProperty(), nil)
+		})
+	}
+
+	assertViolated := func(t *testing.T, err error, param string) {
+		assertEqual(t, "error code should match", osberror.PlanConstraint, osberror.Code(err))
+		assertTrue(t, "error should name the parameter", strings.Contains(err.Error(), param+": "))
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"provision-within-constraints": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				constrain(t, stub)
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"location":"EU"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+			},
+		},
+		"provision-violates-constraints": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				constrain(t, stub)
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"location":"asia"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				assertViolated(t, err, "location")
+				assertEqual(t, "provision calls should match", 0, stub.Provider.ProvisionCallCount())
+			},
+		},
+		"update-violates-constraints": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				constrain(t, stub)
+
+				req := stub.UpdateDetails()
+				req.RawParameters = json.RawMessage(`{"force_delete":"true"}`)
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				assertViolated(t, err, "force_delete")
+				assertEqual(t, "update calls should match", 0, stub.Provider.UpdateCallCount())
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+// previewingProvider is a provider that lists the resources it would create.
+type previewingProvider struct {
+	*brokerfakes.FakeServiceProvider
+}
+
+func (p *previewingProvider) PreviewResources(ctx context.Context, vc *varcontext.VarContext) ([]preview.Resource, error) {
+	count := 1
+	return []preview.Resource{{Type: "google_storage_bucket", Name: vc.GetString("name"), Count: &count}}, nil
+}
+
+// enablePreviews makes the stub's provider list the resources it would create.
+func enablePreviews(stub *serviceStub) {
+	provider := &previewingProvider{FakeServiceProvider: stub.Provider}
+	stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger, store broker.ProviderStore) broker.ServiceProvider {
+		return provider
+	}
+}
+
+func TestGCPServiceBroker_PreviewProvision(t *testing.T) {
+	request := func(stub *serviceStub) preview.Request {
+		return preview.Request{
+			Service:          stub.ServiceDefinition.Name,
+			Plan:             stub.PlanId,
+			OrganizationGuid: "org-1",
+			SpaceGuid:        "space-1",
+			Parameters:       json.RawMessage(`{"name":"reports"}`),
+		}
+	}
+
+	checkNames := func(result *preview.Result) []string {
+		var names []string
+		for _, check := range result.Checks {
+			names = append(names, check.Name)
+		}
+		return names
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"valid": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				enablePreviews(stub)
+
+				result, err := broker.PreviewProvision(context.Background(), request(stub))
+				failIfErr(t, "previewing", err)
+				assertTrue(t, "request should be valid", result.Valid)
+				assertTrue(t, "instance ID should be generated", strings.HasPrefix(result.InstanceId, "preview-"))
+				assertEqual(t, "checks should match", []string{"parameters", "maintenance_info", "quota", "naming", "schema", "constraints", "region", "project", "kms_key", "resources"}, checkNames(result))
+				assertEqual(t, "resource name should match", "reports", result.Resources[0].Name)
+				assertEqual(t, "provision calls should match", 0, stub.Provider.ProvisionCallCount())
+
+				exists, err := db_service.ExistsServiceInstanceDetailsById(context.Background(), result.InstanceId)
+				failIfErr(t, "checking instance", err)
+				assertTrue(t, "instance shouldn't be created", !exists)
+			},
+		},
+		"region-not-permitted": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set(stub.ServiceDefinition.AllowedRegionsProperty(), "us,eu")
+				defer viper.Set(stub.ServiceDefinition.AllowedRegionsProperty(), nil)
+
+				req := request(stub)
+				req.Parameters = json.RawMessage(`{"location":"asia"}`)
+				result, err := broker.PreviewProvision(context.Background(), req)
+				failIfErr(t, "previewing", err)
+				assertTrue(t, "request should be invalid", !result.Valid)
+
+				last := result.Checks[len(result.Checks)-1]
+				assertEqual(t, "failed check should match", "region", last.Name)
+				assertEqual(t, "code should match", osberror.RegionNotPermitted, last.Code)
+				assertTrue(t, "no resources should be listed", len(result.Resources) == 0)
+			},
+		},
+		"instance-exists": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := request(stub)
+				req.InstanceId = fakeInstanceId
+				result, err := broker.PreviewProvision(context.Background(), req)
+				failIfErr(t, "previewing", err)
+				assertTrue(t, "request should be invalid", !result.Valid)
+				assertEqual(t, "checks should match", []string{"instance_id"}, checkNames(result))
+			},
+		},
+		"unknown-plan": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := request(stub)
+				req.Plan = "no-such-plan"
+				_, err := broker.PreviewProvision(context.Background(), req)
+				if _, ok := err.(*preview.InvalidRequestError); !ok {
+					t.Fatalf("expected an invalid request error, got %v", err)
+				}
+			},
+		},
+		"missing-fields": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.PreviewProvision(context.Background(), preview.Request{Service: stub.ServiceDefinition.Name})
+				assertEqual(t, "error should match", "missing required fields: plan, organization_guid, space_guid", fmt.Sprint(err))
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_Inventory(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"unknown-service": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.ListInstances(context.Background(), inventory.Filter{Service: "does-not-exist"})
+				assertTrue(t, "error should be returned", err != nil)
+			},
+		},
+		"instances": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				plan, err := stub.ServiceDefinition.GetPlanById(stub.PlanId)
+				failIfErr(t, "getting plan", err)
+
+				instances, err := broker.ListInstances(context.Background(), inventory.Filter{Service: stub.ServiceDefinition.Name, Plan: plan.Name})
+				failIfErr(t, "listing instances", err)
+				assertEqual(t, "instance count should match", 1, len(instances))
+				assertEqual(t, "instance id should match", fakeInstanceId, instances[0].InstanceId)
+				assertEqual(t, "plan name should match", plan.Name, instances[0].PlanName)
+				assertEqual(t, "state should match", inventory.StateSucceeded, instances[0].State)
+
+				instances, err = broker.ListInstances(context.Background(), inventory.Filter{State: inventory.StateFailed})
+				failIfErr(t, "listing instances", err)
+				assertEqual(t, "failed instance count should match", 0, len(instances))
+			},
+		},
+		"bindings": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				bindings, err := broker.ListBindings(context.Background(), inventory.Filter{Plan: stub.PlanId, State: inventory.StateActive})
+				failIfErr(t, "listing bindings", err)
+				assertEqual(t, "binding count should match", 1, len(bindings))
+				assertEqual(t, "binding id should match", fakeBindingId, bindings[0].BindingId)
+				assertEqual(t, "service name should match", stub.ServiceDefinition.Name, bindings[0].ServiceName)
+
+				bindings, err = broker.ListBindings(context.Background(), inventory.Filter{SpaceGuid: "some-other-space"})
+				failIfErr(t, "listing bindings", err)
+				assertEqual(t, "binding count should match", 0, len(bindings))
+			},
+		},
+		"operations": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				for _, deployment := range []models.TerraformDeployment{
+					{ID: "tf:" + fakeInstanceId + ":", LastOperationType: "provision", LastOperationState: "failed", LastOperationMessage: "quota exceeded"},
+					{ID: "tf:deleted-instance:", LastOperationType: "deprovision", LastOperationState: "succeeded"},
+				} {
+					deployment := deployment
+					failIfErr(t, "creating deployment", db_service.CreateTerraformDeployment(context.Background(), &deployment))
+				}
+
+				operations, err := broker.ListOperations(context.Background(), inventory.Filter{})
+				failIfErr(t, "listing operations", err)
+				assertEqual(t, "operation count should match", 2, len(operations))
+
+				operations, err = broker.ListOperations(context.Background(), inventory.Filter{Service: stub.ServiceId})
+				failIfErr(t, "listing operations", err)
+				assertEqual(t, "operation count should match", 1, len(operations))
+				assertEqual(t, "operation instance should match", fakeInstanceId, operations[0].InstanceId)
+				assertEqual(t, "operation message should match", "quota exceeded", operations[0].Message)
+
+				operations, err = broker.ListOperations(context.Background(), inventory.Filter{State: "succeeded", Limit: 1})
+				failIfErr(t, "listing operations", err)
+				assertEqual(t, "operation count should match", 1, len(operations))
+				assertEqual(t, "operation instance should match", "deleted-instance", operations[0].InstanceId)
+
+				instances, err := broker.ListInstances(context.Background(), inventory.Filter{State: inventory.StateFailed})
+				failIfErr(t, "listing instances", err)
+				assertEqual(t, "failed instance count should match", 1, len(instances))
+			},
+		},
+		"stuck-deprovision": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				instances, err := broker.ListInstances(context.Background(), inventory.Filter{Stuck: true})
+				failIfErr(t, "listing instances", err)
+				assertEqual(t, "stuck instance count should match", 0, len(instances))
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				instance.OperationType = models.DeprovisionOperationType
+				instance.OperationId = "delete-operation"
+				failIfErr(t, "saving instance", db_service.SaveServiceInstanceDetails(context.Background(), instance))
+
+				defer func(stuckAfter time.Duration) { deprovision.Default.StuckAfter = stuckAfter }(deprovision.Default.StuckAfter)
+				deprovision.Default.StuckAfter = time.Nanosecond
+				time.Sleep(time.Millisecond)
+
+				instances, err = broker.ListInstances(context.Background(), inventory.Filter{Stuck: true})
+				failIfErr(t, "listing instances", err)
+				assertEqual(t, "stuck instance count should match", 1, len(instances))
+				assertTrue(t, "instance should be flagged as stuck", instances[0].Stuck)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_LastOperation(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"missing-instance": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.LastOperation(context.Background(), "invalid-instance-id", brokerapi.PollDetails{OperationData: "operationtoken"})
+				assertEqual(t, "errors should match", brokerapi.ErrInstanceDoesNotExist, err)
+			},
+		},
+		"called-on-synchronous-service": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				assertEqual(t, "errors should match", brokerapi.ErrAsyncRequired, err)
+			},
+		},
+		"called-on-async-service": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "shouldn't be called on async service", err)
+
+				assertEqual(t, "PollInstanceCallCount should match", 1, stub.Provider.PollInstanceCallCount())
+			},
+		},
+		"poll-returns-retryable-error": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.PollInstanceReturns(false, "", &googleapi.Error{Code: 503})
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "retryable errors should result in in-progress state", brokerapi.InProgress, status.State)
+				assertEqual(t, "description should be error string", "googleapi: got HTTP response code 503 with body: ", status.Description)
+			},
+		},
+		"poll-returns-failure": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.PollInstanceReturns(false, "", errors.New("not-retryable"))
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "non-retryable errors should result in a failure state", brokerapi.Failed, status.State)
+				assertEqual(t, "description should be error string", "not-retryable", status.Description)
+			},
+		},
+		"poll-returns-not-done": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.PollInstanceReturns(false, "", nil)
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "polls that return no error should result in an in-progress state", brokerapi.InProgress, status.State)
+			},
+		},
+		"poll-returns-success": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.PollInstanceReturns(true, "message", nil)
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "polls that return finished should result in a succeeded state", brokerapi.Succeeded, status.State)
+				assertEqual(t, "polls that return finished should have status message", "message", status.Description)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_GetBinding(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"called-on-bound": {
 			ServiceState: StateBound,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
 				_, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
@@ -647,6 +2004,46 @@ func TestGCPServiceBroker_Update(t *testing.T) {
 				failIfErr(t, "update", err)
 			},
 		},
+		"operation-in-progress": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				queuePendingJob(t, "tf:"+fakeInstanceId+":")
+
+				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				assertConcurrencyError(t, err)
+				assertEqual(t, "update calls should match", 0, stub.Provider.UpdateCallCount())
+			},
+		},
+		"concurrent-request": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				started, release := make(chan bool), make(chan bool)
+				stub.Provider.UpdateStub = func(ctx context.Context, vc *varcontext.VarContext) (models.ServiceInstanceDetails, error) {
+					started <- true
+					<-release
+					return models.ServiceInstanceDetails{}, nil
+				}
+
+				done := make(chan error)
+				go func() {
+					_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+					done <- err
+				}()
+				<-started
+
+				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				assertConcurrencyError(t, err)
+
+				close(release)
+				failIfErr(t, "first update", <-done)
+
+				stub.Provider.UpdateStub = nil
+				_, err = broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				failIfErr(t, "update after the first one finished", err)
+			},
+		},
 		"missing-instance": {
 			ServiceState: StateProvisioned,
 			AsyncService: true,
@@ -691,6 +2088,30 @@ func TestGCPServiceBroker_Update(t *testing.T) {
 				assertEqual(t, "errors should match", ErrNonUpdatableParameter, err)
 			},
 		},
+		"project-not-permitted": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set(projects.DefaultProjectProp, "default-project")
+				viper.Set(projects.ProjectsProp, `{"team-a":{}}`)
+				defer viper.Set(projects.DefaultProjectProp, nil)
+				defer viper.Set(projects.ProjectsProp, nil)
+
+				req := stub.UpdateDetails()
+				req.RawParameters = json.RawMessage(`{"project":"someone-elses-project"}`)
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				if ok {
+					assertEqual(t, "status should match", http.StatusBadRequest, failure.ValidatedStatusCode(nil))
+				}
+				assertEqual(t, "update calls should match", 0, stub.Provider.UpdateCallCount())
+
+				req.RawParameters = json.RawMessage(`{"project":"team-a"}`)
+				_, err = broker.Update(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "updating in a permitted project", err)
+			},
+		},
 		"good-request-valid-parameter": {
 			ServiceState: StateProvisioned,
 			AsyncService: true,
@@ -702,7 +2123,352 @@ func TestGCPServiceBroker_Update(t *testing.T) {
 				failIfErr(t, "update", err)
 			},
 		},
+		"stale-maintenance-info": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				setUpgradeConfig(t, stub, "2", "")
+
+				req := stub.UpdateDetails()
+				req.MaintenanceInfo = brokerapi.MaintenanceInfo{Private: "1"}
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", brokerapi.ErrMaintenanceInfoConflict, err)
+			},
+		},
+		"upgrade-automatic": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				setUpgradeConfig(t, stub, "2", "automatic")
+
+				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpgradeDetails("2"), true)
+				failIfErr(t, "upgrading", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "maintenance version should match", "2", instance.MaintenanceVersion)
+			},
+		},
+		"upgrade-pinned": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				setUpgradeConfig(t, stub, "2", "pinned")
+
+				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpgradeDetails("2"), true)
+				assertTrue(t, "pinned upgrade should fail", err != nil && strings.Contains(err.Error(), "pinned"))
+				assertEqual(t, "update calls should match", 0, stub.Provider.UpdateCallCount())
+
+				// updates that aren't upgrades are still allowed
+				_, err = broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				failIfErr(t, "updating", err)
+			},
+		},
+		"upgrade-manual": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				setUpgradeConfig(t, stub, "2", "manual")
+
+				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpgradeDetails("2"), true)
+				assertTrue(t, "unapproved upgrade should fail", err != nil && strings.Contains(err.Error(), "approved by the operator"))
+
+				upgrades, err := broker.ListUpgrades(context.Background())
+				failIfErr(t, "listing upgrades", err)
+				assertEqual(t, "upgrade count should match", 1, len(upgrades))
+				assertEqual(t, "upgrade state should match", upgrade.StatePending, upgrades[0].State)
+
+				report, err := broker.ApproveUpgrades(context.Background(), upgrade.Approval{InstanceIds: []string{fakeInstanceId}})
+				failIfErr(t, "approving", err)
+				assertEqual(t, "approved count should match", 1, len(report.Approved))
+
+				_, err = broker.Update(context.Background(), fakeInstanceId, stub.UpgradeDetails("2"), true)
+				failIfErr(t, "upgrading", err)
+
+				upgrades, err = broker.ListUpgrades(context.Background())
+				failIfErr(t, "listing upgrades", err)
+				assertEqual(t, "upgrade count should match", 0, len(upgrades))
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+func TestGCPServiceBroker_Experiments(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"recorded-with-instance": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				ctx := experiments.WithEnabled(context.Background(), "new-provisioner")
+				_, err := broker.Provision(ctx, fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "recorded experiments should match", "new-provisioner", instance.Experiments)
+
+				// later requests without the header use the recorded experiments
+				_, err = broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+
+				deprovisionCtx, _, _, _ := stub.Provider.DeprovisionArgsForCall(0)
+				assertEqual(t, "deprovision experiments should match", []string{"new-provisioner"}, experiments.FromContext(deprovisionCtx))
+			},
+		},
+		"added-on-update": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				ctx := experiments.WithEnabled(context.Background(), "new-updater")
+				_, err := broker.Update(ctx, fakeInstanceId, stub.UpdateDetails(), true)
+				failIfErr(t, "updating", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "recorded experiments should match", "new-updater", instance.Experiments)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_Events(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"synchronous-lifecycle": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				sink := &recordingSink{}
+				broker.Events = &events.Bus{Sinks: []events.Sink{sink}}
+
+				initService(t, StateDeprovisioned, broker, stub)
+
+				expected := []string{
+					events.InstanceProvisioned + " " + fakeInstanceId,
+					events.Bound + " " + fakeInstanceId + "/" + fakeBindingId,
+					events.Unbound + " " + fakeInstanceId + "/" + fakeBindingId,
+					events.Deprovisioned + " " + fakeInstanceId,
+				}
+				assertEqual(t, "events should match", expected, sink.Summaries(t, broker.Events))
+
+				event := sink.Events[0]
+				assertEqual(t, "service should match", stub.ServiceId, event.ServiceId)
+				assertEqual(t, "service name should match", stub.ServiceDefinition.Name, event.ServiceName)
+				assertEqual(t, "plan should match", stub.PlanId, event.PlanId)
+				assertEqual(t, "organization should match", stub.ProvisionDetails().OrganizationGUID, event.OrganizationGuid)
+				assertEqual(t, "space should match", stub.ProvisionDetails().SpaceGUID, event.SpaceGuid)
+			},
+		},
+		"provision-failed": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				sink := &recordingSink{}
+				broker.Events = &events.Bus{Sinks: []events.Sink{sink}}
+
+				stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{}, errors.New("quota exhausted"))
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				assertTrue(t, "provisioning should fail", err != nil)
+
+				assertEqual(t, "events should match", []string{events.ProvisionFailed + " " + fakeInstanceId}, sink.Summaries(t, broker.Events))
+				assertEqual(t, "error should match", "quota exhausted", sink.Events[0].Error)
+			},
+		},
+		"existing-instance-not-published": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				sink := &recordingSink{}
+				broker.Events = &events.Bus{Sinks: []events.Sink{sink}}
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"name":"other"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", brokerapi.ErrInstanceAlreadyExists, err)
+				assertEqual(t, "event count should match", 0, len(sink.Summaries(t, broker.Events)))
+			},
+		},
+		"asynchronous-provision": {
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				sink := &recordingSink{}
+				broker.Events = &events.Bus{Sinks: []events.Sink{sink}}
+
+				stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{OperationType: models.ProvisionOperationType, OperationId: "op-1"}, nil)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+				assertEqual(t, "in progress provisions shouldn't be published", 0, len(sink.Summaries(t, broker.Events)))
+
+				stub.Provider.PollInstanceReturns(true, "done", nil)
+				_, err = broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "op-1"})
+				failIfErr(t, "polling", err)
+				assertEqual(t, "events should match", []string{events.InstanceProvisioned + " " + fakeInstanceId}, sink.Summaries(t, broker.Events))
+			},
+		},
+		"asynchronous-provision-failed": {
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				sink := &recordingSink{}
+				broker.Events = &events.Bus{Sinks: []events.Sink{sink}}
+
+				stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{OperationType: models.ProvisionOperationType, OperationId: "op-1"}, nil)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+
+				stub.Provider.PollInstanceReturns(false, "", errors.New("instance creation failed"))
+				_, err = broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "op-1"})
+				failIfErr(t, "polling", err)
+				assertEqual(t, "events should match", []string{events.ProvisionFailed + " " + fakeInstanceId}, sink.Summaries(t, broker.Events))
+				assertEqual(t, "error should match", "instance creation failed", sink.Events[0].Error)
+			},
+		},
+		"asynchronous-deprovision": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				sink := &recordingSink{}
+				broker.Events = &events.Bus{Sinks: []events.Sink{sink}}
+
+				operationId := "op-2"
+				stub.Provider.DeprovisionReturns(&operationId, nil)
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+				assertEqual(t, "in progress deprovisions shouldn't be published", 0, len(sink.Summaries(t, broker.Events)))
+
+				stub.Provider.PollInstanceReturns(true, "done", nil)
+				_, err = broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: operationId})
+				failIfErr(t, "polling", err)
+				assertEqual(t, "events should match", []string{events.Deprovisioned + " " + fakeInstanceId}, sink.Summaries(t, broker.Events))
+			},
+		},
 	}
 
 	cases.Run(t)
-}
\ No newline at end of file
+}
+
+// recordingSink is an events.Sink that keeps every event.
+type recordingSink struct {
+	mu     sync.Mutex
+	Events []events.Event
+}
+
+func (s *recordingSink) Name() string {
+	return "recording"
+}
+
+func (s *recordingSink) Send(ctx context.Context, event events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Events = append(s.Events, event)
+	return nil
+}
+
+// Summaries waits for the bus to deliver its events then summarizes them as
+// their type and the instance or binding they're about, in the order they
+// were published.
+func (s *recordingSink) Summaries(t *testing.T, bus *events.Bus) []string {
+	t.Helper()
+	failIfErr(t, "draining events", bus.Drain(context.Background()))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sort.SliceStable(s.Events, func(i, j int) bool {
+		return s.Events[i].Timestamp.Before(s.Events[j].Timestamp)
+	})
+
+	summaries := []string{}
+	for _, event := range s.Events {
+		subject := event.InstanceId
+		if event.BindingId != "" {
+			subject += "/" + event.BindingId
+		}
+		summaries = append(summaries, event.Type+" "+subject)
+	}
+
+	return summaries
+}
+
+func TestNewBroker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "brokers-test")
+	failIfErr(t, "creating a temporary directory", err)
+	defer os.RemoveAll(dir)
+
+	newBroker := func(name string) *ServiceBroker {
+		db, err := db_service.OpenSqlite(filepath.Join(dir, name))
+		failIfErr(t, "opening the database", err)
+		failIfErr(t, "migrating the database", db_service.RunMigrations(db))
+
+		stub := fakeService(t, false)
+		registry := broker.BrokerRegistry{}
+		registry.Register(stub.ServiceDefinition)
+
+		broker, err := NewBroker(db_service.NewSqlDatastore(db), &BrokerConfig{Registry: registry}, utils.NewLogger("brokers-test"))
+		failIfErr(t, "creating the broker", err)
+		return broker
+	}
+
+	// neither broker may use the package-level database
+	previous := db_service.DbConnection
+	db_service.DbConnection = nil
+	defer func() { db_service.DbConnection = previous }()
+
+	first := newBroker("first.db")
+	second := newBroker("second.db")
+
+	stub := fakeService(t, false)
+	_, err = first.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+	failIfErr(t, "provisioning", err)
+
+	instances, err := first.ListInstances(context.Background(), inventory.Filter{})
+	failIfErr(t, "listing the first broker's instances", err)
+	assertEqual(t, "the first broker should have the instance", 1, len(instances))
+
+	instances, err = second.ListInstances(context.Background(), inventory.Filter{})
+	failIfErr(t, "listing the second broker's instances", err)
+	assertEqual(t, "the second broker should not have the instance", 0, len(instances))
+
+	if _, err := NewBroker(nil, &BrokerConfig{}, utils.NewLogger("brokers-test")); err == nil {
+		t.Error("Expected an error creating a broker without a datastore")
+	}
+}
+
+func TestNewBroker_fakeDatastores(t *testing.T) {
+	stub := fakeService(t, false)
+	registry := broker.BrokerRegistry{}
+	registry.Register(stub.ServiceDefinition)
+	ctx := context.Background()
+
+	t.Run("in-memory", func(t *testing.T) {
+		store := fakes.NewInMemoryDatastore()
+		broker, err := NewBroker(store, &BrokerConfig{Registry: registry}, utils.NewLogger("brokers-test"))
+		failIfErr(t, "creating the broker", err)
+
+		_, err = broker.Provision(ctx, fakeInstanceId, stub.ProvisionDetails(), true)
+		failIfErr(t, "provisioning", err)
+		_, err = broker.Bind(ctx, fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+		failIfErr(t, "binding", err)
+
+		exists, err := store.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, fakeInstanceId, fakeBindingId)
+		failIfErr(t, "checking the binding", err)
+		assertTrue(t, "the binding should be stored", exists)
+
+		_, err = broker.Unbind(ctx, fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+		failIfErr(t, "unbinding", err)
+		_, err = broker.Deprovision(ctx, fakeInstanceId, stub.DeprovisionDetails(), true)
+		failIfErr(t, "deprovisioning", err)
+
+		deleted, err := store.ExistsDeletedServiceInstanceDetailsById(ctx, fakeInstanceId)
+		failIfErr(t, "checking the instance", err)
+		assertTrue(t, "the instance should be soft-deleted", deleted)
+	})
+
+	t.Run("recording", func(t *testing.T) {
+		store := &fakes.FakeDatastore{}
+		store.ExistsServiceInstanceDetailsByIdReturns(false, errors.New("database is down"))
+		broker, err := NewBroker(store, &BrokerConfig{Registry: registry}, utils.NewLogger("brokers-test"))
+		failIfErr(t, "creating the broker", err)
+
+		_, err = broker.Provision(ctx, fakeInstanceId, stub.ProvisionDetails(), true)
+		assertTrue(t, "the datastore error should be returned", err != nil && strings.Contains(err.Error(), "database is down"))
+		assertEqual(t, "nothing should be created", 0, store.CreateServiceInstanceDetailsCallCount())
+		_, instanceId := store.ExistsServiceInstanceDetailsByIdArgsForCall(0)
+		assertEqual(t, "the instance should be looked up", fakeInstanceId, instanceId)
+	})
+}
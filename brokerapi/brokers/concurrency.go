@@ -0,0 +1,112 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/pivotal/cloud-service-broker/pkg/osberror"
+)
+
+// instanceLocks tracks the instances with an update or deprovision request
+// in progress so concurrent requests changing the same instance are rejected
+// rather than racing each other.
+type instanceLocks struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+// lock marks the instance as being changed until unlock is called. If it
+// already is, a ConcurrencyError failure is returned.
+func (l *instanceLocks) lock(instanceID string) (unlock func(), err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.held[instanceID] {
+		return nil, concurrencyError(fmt.Errorf("another request is changing instance %s, try again once it's done", instanceID))
+	}
+
+	if l.held == nil {
+		l.held = make(map[string]bool)
+	}
+	l.held[instanceID] = true
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, instanceID)
+	}, nil
+}
+
+// busy returns true if the instance is being changed.
+func (l *instanceLocks) busy(instanceID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.held[instanceID]
+}
+
+// instanceJobTargetPrefix is the start of the targets of the background jobs
+// working on the instance and its bindings. Terraform deployment IDs look
+// like tf:instance-id:binding-id.
+func instanceJobTargetPrefix(instanceID string) string {
+	return fmt.Sprintf("tf:%s:", instanceID)
+}
+
+// checkNoOperationInProgress returns a ConcurrencyError failure if this
+// broker is updating or deprovisioning the instance, or an operation on it
+// is running in the background.
+func (broker *ServiceBroker) checkNoOperationInProgress(ctx context.Context, instanceID string) error {
+	if broker.changing.busy(instanceID) {
+		return concurrencyError(fmt.Errorf("another request is changing instance %s, try again once it's done", instanceID))
+	}
+
+	return broker.checkNoPendingJobs(ctx, instanceID)
+}
+
+// checkNoPendingJobs returns a ConcurrencyError failure if a background job
+// is running an operation on the instance or one of its bindings. Starting
+// another operation would interleave their changes to the instance's
+// resources.
+func (broker *ServiceBroker) checkNoPendingJobs(ctx context.Context, instanceID string) error {
+	pending, err := broker.hasPendingJobs(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	if pending {
+		return concurrencyError(fmt.Errorf("an operation on instance %s is in progress, try again once it's done", instanceID))
+	}
+
+	return nil
+}
+
+// hasPendingJobs returns true if background jobs are queued or running for
+// the instance or one of its bindings.
+func (broker *ServiceBroker) hasPendingJobs(ctx context.Context, instanceID string) (bool, error) {
+	pending, err := broker.store.ListPendingJobs(ctx, instanceJobTargetPrefix(instanceID))
+	if err != nil {
+		return false, fmt.Errorf("Error checking for operations in progress: %s", err)
+	}
+
+	return len(pending) > 0, nil
+}
+
+func concurrencyError(err error) error {
+	return osberror.New(err, http.StatusUnprocessableEntity, "concurrent-instance-access", osberror.ConcurrencyError)
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/osberror"
+	"github.com/spf13/viper"
+)
+
+// DeletedInstanceIdsProp is the viper key of what happens when a provision
+// request reuses the ID of a deprovisioned instance that's still in the
+// database as a soft-deleted row.
+const DeletedInstanceIdsProp = "provision.deleted_instance_ids"
+
+// Values of DeletedInstanceIdsProp.
+const (
+	// DeletedInstanceIdsReject fails the request with a 409 Conflict.
+	DeletedInstanceIdsReject = "reject"
+
+	// DeletedInstanceIdsPurge permanently deletes the old instance's rows and
+	// provisions the new one.
+	DeletedInstanceIdsPurge = "purge"
+)
+
+func init() {
+	config.Register(config.Property{
+		Key:     DeletedInstanceIdsProp,
+		Kind:    config.String,
+		Default: DeletedInstanceIdsReject,
+		Allowed: []string{DeletedInstanceIdsReject, DeletedInstanceIdsPurge},
+	})
+}
+
+// deletedInstanceIdsFromEnv gets the configured handling of deleted instance
+// IDs.
+func deletedInstanceIdsFromEnv() (string, error) {
+	switch value := viper.GetString(DeletedInstanceIdsProp); value {
+	case DeletedInstanceIdsReject, DeletedInstanceIdsPurge:
+		return value, nil
+	default:
+		return "", fmt.Errorf("%s must be %q or %q, got %q", DeletedInstanceIdsProp, DeletedInstanceIdsReject, DeletedInstanceIdsPurge, value)
+	}
+}
+
+// checkDeletedInstanceId rejects or purges the soft-deleted rows of a
+// deprovisioned instance with the ID, depending on DeletedInstanceIds.
+// Without this the old rows break the new instance, e.g. its provision
+// request could be shadowed by the old one.
+func (broker *ServiceBroker) checkDeletedInstanceId(ctx context.Context, instanceID string) error {
+	deleted, err := broker.store.ExistsDeletedServiceInstanceDetailsById(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("Database error checking for deleted instance: %s", err)
+	}
+	if !deleted {
+		return nil
+	}
+
+	if broker.DeletedInstanceIds != DeletedInstanceIdsPurge {
+		return instanceIdReusedError(instanceID)
+	}
+
+	broker.logger(ctx).Info("purging-deleted-instance", lager.Data{"instance_id": instanceID})
+	if err := broker.store.PurgeDeletedServiceInstance(ctx, instanceID); err != nil {
+		return fmt.Errorf("Error purging deleted instance %s: %s", instanceID, err)
+	}
+
+	return nil
+}
+
+// instanceIdReusedError is returned for requests to provision an instance with
+// the ID of a deprovisioned one.
+func instanceIdReusedError(instanceID string) error {
+	err := fmt.Errorf("instance ID %s belonged to a deprovisioned instance, provision with a new ID", instanceID)
+	return osberror.New(err, http.StatusConflict, "instance-id-reused", osberror.InstanceIdReused)
+}
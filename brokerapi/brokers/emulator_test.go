@@ -0,0 +1,117 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	googlestorage "cloud.google.com/go/storage"
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
+	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin/base"
+	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin/base/basefakes"
+	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin/storage"
+	"github.com/spf13/viper"
+)
+
+// TestEmulator_Storage provisions, binds, unbinds and deprovisions a Cloud
+// Storage bucket against an emulator, e.g. fake-gcs-server. It's skipped
+// unless STORAGE_EMULATOR_HOST holds the emulator's host and port. There's no
+// IAM emulator, so bindings use a fake service account manager.
+func TestEmulator_Storage(t *testing.T) {
+	host := os.Getenv("STORAGE_EMULATOR_HOST")
+	if host == "" {
+		t.Skip("STORAGE_EMULATOR_HOST isn't set")
+	}
+
+	viper.Set(gcpclient.EmulatorsProp, fmt.Sprintf(`{"storage": "http://%s/storage/v1/"}`, host))
+	defer viper.Set(gcpclient.EmulatorsProp, nil)
+
+	accounts := &basefakes.FakeServiceAccountManager{}
+	accounts.CreateCredentialsReturns(map[string]interface{}{"Email": "binding@emulator.iam.gserviceaccount.com"}, nil)
+
+	defn := storage.ServiceDefinition()
+	defn.ProviderBuilder = func(logger lager.Logger, store broker.ProviderStore) broker.ServiceProvider {
+		return &storage.StorageBroker{BrokerBase: base.BrokerBase{
+			AccountManager: accounts,
+			ProjectId:      "emulator-project",
+			Logger:         logger,
+		}}
+	}
+	svc, err := defn.CatalogEntry()
+	failIfErr(t, "getting the catalog entry", err)
+
+	registry := broker.BrokerRegistry{}
+	registry.Register(defn)
+	serviceBroker, closer := newStubbedBroker(t, registry, nil)
+	defer closer()
+
+	ctx := context.Background()
+	bucketName := fmt.Sprintf("csb-emulator-%d", time.Now().UnixNano())
+	provisionParams, err := json.Marshal(map[string]interface{}{"name": bucketName, "force_delete": "true"})
+	failIfErr(t, "marshalling parameters", err)
+
+	_, err = serviceBroker.Provision(ctx, fakeInstanceId, brokerapi.ProvisionDetails{
+		ServiceID:     svc.ID,
+		PlanID:        svc.Plans[0].ID,
+		RawParameters: provisionParams,
+	}, true)
+	failIfErr(t, "provisioning", err)
+
+	clients, err := gcpclient.NewFactoryFromEnv()
+	failIfErr(t, "creating clients", err)
+	client, err := googlestorage.NewClient(ctx, clients.Options(ctx, "storage")...)
+	failIfErr(t, "creating the storage client", err)
+
+	bucket := client.Bucket(bucketName)
+	attrs, err := bucket.Attrs(ctx)
+	failIfErr(t, "getting the bucket", err)
+	assertEqual(t, "force delete label should match", "true", attrs.Labels["sb-force-delete"])
+
+	binding, err := serviceBroker.Bind(ctx, fakeInstanceId, fakeBindingId, brokerapi.BindDetails{
+		AppGUID:   "fake-app-guid",
+		ServiceID: svc.ID,
+		PlanID:    svc.Plans[0].ID,
+	}, true)
+	failIfErr(t, "binding", err)
+
+	creds := binding.Credentials.(map[string]interface{})
+	assertEqual(t, "bucket name should match", bucketName, creds["bucket_name"])
+	assertEqual(t, "service account should match", "binding@emulator.iam.gserviceaccount.com", creds["Email"])
+
+	_, err = serviceBroker.Unbind(ctx, fakeInstanceId, fakeBindingId, brokerapi.UnbindDetails{
+		ServiceID: svc.ID,
+		PlanID:    svc.Plans[0].ID,
+	}, true)
+	failIfErr(t, "unbinding", err)
+	assertEqual(t, "delete credentials calls should match", 1, accounts.DeleteCredentialsCallCount())
+
+	_, err = serviceBroker.Deprovision(ctx, fakeInstanceId, brokerapi.DeprovisionDetails{
+		ServiceID: svc.ID,
+		PlanID:    svc.Plans[0].ID,
+	}, true)
+	failIfErr(t, "deprovisioning", err)
+
+	if _, err := bucket.Attrs(ctx); err != googlestorage.ErrBucketNotExist {
+		t.Errorf("expected the bucket to be deleted, got %v", err)
+	}
+}
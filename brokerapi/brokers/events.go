@@ -0,0 +1,54 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/events"
+)
+
+// publishEvent publishes a lifecycle event of the instance, or of one of its
+// bindings if bindingID is set, if events are enabled. cause is why the
+// operation failed, if it did.
+func (broker *ServiceBroker) publishEvent(eventType string, instance models.ServiceInstanceDetails, bindingID string, cause error) {
+	if broker.Events == nil {
+		return
+	}
+
+	event := events.Event{
+		Type:             eventType,
+		InstanceId:       instance.ID,
+		BindingId:        bindingID,
+		ServiceId:        instance.ServiceId,
+		PlanId:           instance.PlanId,
+		OrganizationGuid: instance.OrganizationGuid,
+		SpaceGuid:        instance.SpaceGuid,
+	}
+
+	if cause != nil {
+		event.Error = cause.Error()
+	}
+
+	// names make events readable without the catalog, they're left out if
+	// the service or plan was removed from it
+	if service, err := broker.registry.GetServiceById(instance.ServiceId); err == nil {
+		event.ServiceName = service.Name
+		if plan, err := service.GetPlanById(instance.PlanId); err == nil {
+			event.PlanName = plan.Name
+		}
+	}
+
+	broker.Events.Publish(event)
+}
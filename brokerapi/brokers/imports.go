@@ -0,0 +1,109 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/imports"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+)
+
+var _ imports.Importer = (*ServiceBroker)(nil)
+
+// ImportInstance registers existing resources as a service instance with the
+// request's ID. The request goes through the same checks as a provision, e.g.
+// that the ID isn't in use and the parameters match the schema, then the
+// service's provider adopts the resources instead of creating them. The
+// instance is saved like a provisioned one so it can be bound, updated and
+// deprovisioned.
+func (broker *ServiceBroker) ImportInstance(ctx context.Context, request imports.Request) (*imports.Result, error) {
+	broker.logger(ctx).Info("ImportInstance", lager.Data{"request": request})
+
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	if !isValidOrEmptyJSON(request.Parameters) {
+		return nil, imports.Invalidf("parameters must be valid JSON")
+	}
+
+	svc, err := broker.serviceByNameOrId(request.Service)
+	if err != nil {
+		return nil, imports.Invalidf("%s", err)
+	}
+
+	plan, err := planByNameOrId(svc, request.Plan)
+	if err != nil {
+		return nil, imports.Invalidf("%s", err)
+	}
+
+	if !svc.PlanCapabilities(*plan).Import {
+		return nil, imports.Invalidf("plan %q of service %q can't import existing resources", plan.Name, svc.Name)
+	}
+
+	details := brokerapi.ProvisionDetails{
+		ServiceID:        svc.Id,
+		PlanID:           plan.ID,
+		OrganizationGUID: request.OrganizationGuid,
+		SpaceGUID:        request.SpaceGuid,
+		RawParameters:    request.Parameters,
+	}
+
+	spec, err := broker.provision(ctx, request.InstanceId, details, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &imports.Result{
+		InstanceId:  request.InstanceId,
+		ServiceId:   svc.Id,
+		PlanId:      plan.ID,
+		Async:       spec.IsAsync,
+		OperationId: spec.OperationData,
+	}, nil
+}
+
+// importResources has the provider adopt the existing resources the
+// variables identify.
+func importResources(ctx context.Context, provider broker.ServiceProvider, vars *varcontext.VarContext) (models.ServiceInstanceDetails, error) {
+	importer, ok := provider.(broker.Importer)
+	if !ok {
+		return models.ServiceInstanceDetails{}, imports.Invalidf("the service can't import existing resources")
+	}
+
+	return importer.Import(ctx, vars)
+}
+
+// planByNameOrId returns the plan of the service with the given name or ID.
+func planByNameOrId(svc *broker.ServiceDefinition, nameOrId string) (*broker.ServicePlan, error) {
+	catalogEntry, err := svc.CatalogEntry()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, plan := range catalogEntry.Plans {
+		if plan.Name == nameOrId || plan.ID == nameOrId {
+			return &plan, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Unknown plan %q of service %q", nameOrId, svc.Name)
+}
@@ -0,0 +1,296 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/deprovision"
+	"github.com/pivotal/cloud-service-broker/pkg/experiments"
+	"github.com/pivotal/cloud-service-broker/pkg/inventory"
+)
+
+var _ inventory.Lister = (*ServiceBroker)(nil)
+
+// ListInstances lists the service instances matching the filter. The state of
+// an instance is the state of its last operation. Instances that have been
+// deprovisioning for longer than the deprovision reconciler allows are
+// flagged as stuck.
+func (broker *ServiceBroker) ListInstances(ctx context.Context, filter inventory.Filter) ([]inventory.Instance, error) {
+	conditions, err := broker.inventoryConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := broker.store.ListServiceInstanceDetails(ctx, conditions)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing instances: %s", err)
+	}
+
+	deployments, err := broker.store.ListTerraformDeployments(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing operations: %s", err)
+	}
+
+	states := make(map[string]string)
+	for _, deployment := range deployments {
+		if instanceId, bindingId := parseTfId(deployment.ID); bindingId == "" {
+			states[instanceId] = deployment.LastOperationState
+		}
+	}
+
+	now := time.Now()
+	out := []inventory.Instance{}
+	for i := range instances {
+		instance := &instances[i]
+		if !broker.matchesPlan(filter.Plan, instance) {
+			continue
+		}
+
+		state, ok := states[instance.ID]
+		switch {
+		case ok:
+		case instance.OperationId != "":
+			state = inventory.StateInProgress
+		default:
+			state = inventory.StateSucceeded
+		}
+
+		if filter.State != "" && filter.State != state {
+			continue
+		}
+
+		stuck := state == inventory.StateInProgress &&
+			instance.OperationType == models.DeprovisionOperationType &&
+			deprovision.Default.Stuck(instance.UpdatedAt, now)
+		if filter.Stuck && !stuck {
+			continue
+		}
+
+		serviceName, planName := broker.serviceAndPlanNames(instance)
+		out = append(out, inventory.Instance{
+			InstanceId:         instance.ID,
+			ServiceId:          instance.ServiceId,
+			ServiceName:        serviceName,
+			PlanId:             instance.PlanId,
+			PlanName:           planName,
+			OrganizationGuid:   instance.OrganizationGuid,
+			SpaceGuid:          instance.SpaceGuid,
+			State:              state,
+			OperationType:      instance.OperationType,
+			Stuck:              stuck,
+			MaintenanceVersion: instance.MaintenanceVersion,
+			Experiments:        experiments.Parse(instance.Experiments),
+			CreatedAt:          instance.CreatedAt,
+			UpdatedAt:          instance.UpdatedAt,
+		})
+	}
+
+	return out, nil
+}
+
+// ListBindings lists the bindings matching the filter. Bindings are matched
+// against the plan, organization and space of their instance. The state of a
+// binding is either active or revoked.
+func (broker *ServiceBroker) ListBindings(ctx context.Context, filter inventory.Filter) ([]inventory.Binding, error) {
+	conditions, err := broker.inventoryConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := broker.instancesById(ctx, conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings, err := broker.store.ListServiceBindingCredentials(ctx, models.ServiceBindingCredentials{ServiceId: conditions.ServiceId})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing bindings: %s", err)
+	}
+
+	out := []inventory.Binding{}
+	for _, binding := range bindings {
+		instance, ok := instances[binding.ServiceInstanceId]
+		if !ok || !broker.matchesPlan(filter.Plan, instance) {
+			continue
+		}
+
+		state := inventory.StateActive
+		if binding.RevokedAt != nil {
+			state = inventory.StateRevoked
+		}
+
+		if filter.State != "" && filter.State != state {
+			continue
+		}
+
+		serviceName, _ := broker.serviceAndPlanNames(instance)
+		out = append(out, inventory.Binding{
+			BindingId:        binding.BindingId,
+			InstanceId:       binding.ServiceInstanceId,
+			ServiceId:        binding.ServiceId,
+			ServiceName:      serviceName,
+			PlanId:           instance.PlanId,
+			OrganizationGuid: instance.OrganizationGuid,
+			SpaceGuid:        instance.SpaceGuid,
+			State:            state,
+			CreatedAt:        binding.CreatedAt,
+			RevokedAt:        binding.RevokedAt,
+		})
+	}
+
+	return out, nil
+}
+
+// ListOperations lists the most recent operations on instances and bindings
+// matching the filter, newest first. Operations on deleted instances only
+// match filters that don't select a service, plan, organization or space.
+func (broker *ServiceBroker) ListOperations(ctx context.Context, filter inventory.Filter) ([]inventory.Operation, error) {
+	conditions, err := broker.inventoryConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := broker.instancesById(ctx, conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = inventory.DefaultOperationLimit
+	}
+
+	filtered := filter.Service != "" || filter.Plan != "" || filter.OrganizationGuid != "" || filter.SpaceGuid != "" || filter.State != ""
+	queryLimit := limit
+	if filtered {
+		queryLimit = 0
+	}
+
+	deployments, err := broker.store.ListTerraformDeployments(ctx, queryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing operations: %s", err)
+	}
+
+	out := []inventory.Operation{}
+	for _, deployment := range deployments {
+		if len(out) == limit {
+			break
+		}
+
+		if filter.State != "" && filter.State != deployment.LastOperationState {
+			continue
+		}
+
+		instanceId, bindingId := parseTfId(deployment.ID)
+		operation := inventory.Operation{
+			InstanceId: instanceId,
+			BindingId:  bindingId,
+			Type:       deployment.LastOperationType,
+			State:      deployment.LastOperationState,
+			Message:    deployment.LastOperationMessage,
+			UpdatedAt:  deployment.UpdatedAt,
+		}
+
+		instance, ok := instances[instanceId]
+		switch {
+		case ok && broker.matchesPlan(filter.Plan, instance):
+			operation.ServiceId = instance.ServiceId
+			operation.ServiceName, _ = broker.serviceAndPlanNames(instance)
+			operation.PlanId = instance.PlanId
+		case ok || conditions != (models.ServiceInstanceDetails{}) || filter.Plan != "":
+			continue
+		}
+
+		out = append(out, operation)
+	}
+
+	return out, nil
+}
+
+// inventoryConditions converts the parts of the filter that can be matched by
+// the database into conditions.
+func (broker *ServiceBroker) inventoryConditions(filter inventory.Filter) (models.ServiceInstanceDetails, error) {
+	conditions := models.ServiceInstanceDetails{
+		OrganizationGuid: filter.OrganizationGuid,
+		SpaceGuid:        filter.SpaceGuid,
+	}
+
+	if filter.Service != "" {
+		svc, err := broker.serviceByNameOrId(filter.Service)
+		if err != nil {
+			return conditions, err
+		}
+		conditions.ServiceId = svc.Id
+	}
+
+	return conditions, nil
+}
+
+func (broker *ServiceBroker) instancesById(ctx context.Context, conditions models.ServiceInstanceDetails) (map[string]*models.ServiceInstanceDetails, error) {
+	instances, err := broker.store.ListServiceInstanceDetails(ctx, conditions)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing instances: %s", err)
+	}
+
+	out := make(map[string]*models.ServiceInstanceDetails)
+	for i := range instances {
+		out[instances[i].ID] = &instances[i]
+	}
+
+	return out, nil
+}
+
+// matchesPlan returns true if the plan is empty or the ID or name of the
+// instance's plan.
+func (broker *ServiceBroker) matchesPlan(plan string, instance *models.ServiceInstanceDetails) bool {
+	if plan == "" || plan == instance.PlanId {
+		return true
+	}
+
+	_, planName := broker.serviceAndPlanNames(instance)
+	return planName == plan
+}
+
+// serviceAndPlanNames gets the names of the instance's service and plan, names
+// are empty if they're no longer in the catalog.
+func (broker *ServiceBroker) serviceAndPlanNames(instance *models.ServiceInstanceDetails) (string, string) {
+	svc, err := broker.registry.GetServiceById(instance.ServiceId)
+	if err != nil {
+		return "", ""
+	}
+
+	plan, err := svc.GetPlanById(instance.PlanId)
+	if err != nil {
+		return svc.Name, ""
+	}
+
+	return svc.Name, plan.Name
+}
+
+// parseTfId splits an ID created by the Terraform provider into the instance
+// and binding IDs it was created for.
+func parseTfId(tfId string) (instanceId, bindingId string) {
+	parts := strings.SplitN(tfId, ":", 3)
+	if len(parts) != 3 || parts[0] != "tf" {
+		return tfId, ""
+	}
+
+	return parts[1], parts[2]
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pborman/uuid"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/osberror"
+	"github.com/pivotal/cloud-service-broker/pkg/preview"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+)
+
+var _ preview.Previewer = (*ServiceBroker)(nil)
+
+// PreviewProvision runs the request through the checks a provision does, e.g.
+// the parameter schema, naming, quota, region, project and encryption key
+// policies, then asks the service's provider which resources it would create.
+// Nothing is saved and the cloud isn't called. Requests without an instance
+// ID get a generated one.
+func (broker *ServiceBroker) PreviewProvision(ctx context.Context, request preview.Request) (*preview.Result, error) {
+	broker.logger(ctx).Info("PreviewProvision", lager.Data{"request": request})
+
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	svc, err := broker.serviceByNameOrId(request.Service)
+	if err != nil {
+		return nil, preview.Invalidf("%s", err)
+	}
+
+	plan, err := planByNameOrId(svc, request.Plan)
+	if err != nil {
+		return nil, preview.Invalidf("%s", err)
+	}
+
+	_, provider, err := broker.getDefinitionAndProvider(ctx, svc.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &preview.Result{
+		InstanceId: request.InstanceId,
+		ServiceId:  svc.Id,
+		PlanId:     plan.ID,
+		Async:      provider.ProvisionsAsync(),
+		Checks:     []preview.Check{},
+	}
+	report := func(name string, err error) {
+		check := preview.Check{Name: name, Passed: err == nil}
+		if err != nil {
+			check.Error = err.Error()
+			check.Code = osberror.Code(err)
+		}
+		result.Checks = append(result.Checks, check)
+	}
+
+	if result.InstanceId == "" {
+		result.InstanceId = "preview-" + uuid.New()
+	} else {
+		err := broker.checkInstanceIdAvailable(ctx, result.InstanceId)
+		report("instance_id", err)
+		if err != nil {
+			return result, nil
+		}
+	}
+
+	state := &provisionCheckState{
+		instanceId: result.InstanceId,
+		details: brokerapi.ProvisionDetails{
+			ServiceID:        svc.Id,
+			PlanID:           plan.ID,
+			OrganizationGUID: request.OrganizationGuid,
+			SpaceGUID:        request.SpaceGuid,
+			RawParameters:    request.Parameters,
+		},
+		service: svc,
+		plan:    plan,
+	}
+	if err := broker.checkProvision(ctx, state, report); err != nil {
+		return result, nil
+	}
+	// the variables include the credentials the provider uses
+	result.Variables = config.Redact(state.vars.ToMap())
+
+	resources, err := previewResources(ctx, provider, state.vars)
+	report("resources", err)
+	if err != nil {
+		return result, nil
+	}
+
+	result.Resources = resources
+	result.Valid = true
+	return result, nil
+}
+
+// checkInstanceIdAvailable returns the error a provision with the ID would
+// fail with because it's in use, without purging the records of a deleted
+// instance like provisioning does.
+func (broker *ServiceBroker) checkInstanceIdAvailable(ctx context.Context, instanceID string) error {
+	exists, err := broker.store.ExistsServiceInstanceDetailsById(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return brokerapi.ErrInstanceAlreadyExists
+	}
+
+	deleted, err := broker.store.ExistsDeletedServiceInstanceDetailsById(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	if deleted && broker.DeletedInstanceIds != DeletedInstanceIdsPurge {
+		return instanceIdReusedError(instanceID)
+	}
+
+	return nil
+}
+
+// previewResources lists the resources the provider would create, providers
+// that can't tell list none.
+func previewResources(ctx context.Context, provider broker.ServiceProvider, vars *varcontext.VarContext) ([]preview.Resource, error) {
+	previewer, ok := provider.(broker.ResourcePreviewer)
+	if !ok {
+		return nil, nil
+	}
+
+	return previewer.PreviewResources(ctx, vars)
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/osberror"
+	"github.com/pivotal/cloud-service-broker/pkg/protection"
+)
+
+var _ protection.Protector = (*ServiceBroker)(nil)
+
+// GetProtection returns whether the instance is protected from being
+// deprovisioned. An operator's setting for the instance takes precedence over
+// the deletion_protected_plans of its service.
+func (broker *ServiceBroker) GetProtection(ctx context.Context, instanceId string) (*protection.Status, error) {
+	instance, err := broker.store.GetServiceInstanceDetailsById(ctx, instanceId)
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, protection.ErrInstanceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return broker.protectionStatus(ctx, instance)
+}
+
+// SetProtection protects or unprotects the instance regardless of its plan.
+func (broker *ServiceBroker) SetProtection(ctx context.Context, instanceId string, change protection.Change) (*protection.Status, error) {
+	broker.logger(ctx).Info("SetProtection", lager.Data{"instance_id": instanceId, "change": change})
+
+	if err := change.Validate(); err != nil {
+		return nil, err
+	}
+
+	if exists, err := broker.store.ExistsServiceInstanceDetailsById(ctx, instanceId); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, protection.ErrInstanceNotFound
+	}
+
+	record, err := broker.store.GetInstanceProtection(ctx, instanceId)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		record = &models.InstanceProtection{ServiceInstanceId: instanceId}
+	}
+
+	record.Protected = *change.Protected
+	record.Reason = change.Reason
+	if err := broker.store.SaveInstanceProtection(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return broker.GetProtection(ctx, instanceId)
+}
+
+// protectionStatus works out the instance's protection from the operator's
+// setting for it or else its plan's.
+func (broker *ServiceBroker) protectionStatus(ctx context.Context, instance *models.ServiceInstanceDetails) (*protection.Status, error) {
+	status := &protection.Status{InstanceId: instance.ID}
+
+	record, err := broker.store.GetInstanceProtection(ctx, instance.ID)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		status.Protected = record.Protected
+		status.Source = protection.SourceInstance
+		status.Reason = record.Reason
+		status.UpdatedAt = &record.UpdatedAt
+		return status, nil
+	}
+
+	svc, err := broker.registry.GetServiceById(instance.ServiceId)
+	if err != nil {
+		return nil, err
+	}
+
+	// plans removed from the catalog can't be protected
+	if plan, err := svc.GetPlanById(instance.PlanId); err == nil && svc.IsDeletionProtected(plan) {
+		status.Protected = true
+		status.Source = protection.SourcePlan
+		status.Reason = fmt.Sprintf("instances of plan %q of service %q are protected", plan.Name, svc.Name)
+	}
+
+	return status, nil
+}
+
+// deletionProtectedError explains why the instance can't be deprovisioned and
+// how to lift the protection.
+func deletionProtectedError(status *protection.Status) error {
+	protected := fmt.Sprintf("instance %s is protected from deletion", status.InstanceId)
+	if status.Reason != "" {
+		protected += fmt.Sprintf(" (%s)", status.Reason)
+	}
+
+	err := fmt.Errorf("%s, an operator must unprotect it with PUT /admin/instances/%s/protection before it can be deprovisioned", protected, status.InstanceId)
+	return osberror.New(err, http.StatusUnprocessableEntity, "deletion-protected", osberror.DeletionProtected)
+}
@@ -0,0 +1,240 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/osberror"
+	"github.com/pivotal/cloud-service-broker/pkg/projects"
+	"github.com/pivotal/cloud-service-broker/pkg/quota"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+	"github.com/pivotal/cloud-service-broker/utils"
+)
+
+// provisionCheckState holds what the checks of a provision work out about the
+// request for the steps after them.
+type provisionCheckState struct {
+	instanceId string
+	// details holds the request, its parameters include the generated
+	// resource name once it's been checked
+	details brokerapi.ProvisionDetails
+	service *broker.ServiceDefinition
+	plan    *broker.ServicePlan
+
+	vars *varcontext.VarContext
+	// requestDetails are the parameters saved with the instance, they include
+	// the project it's pinned to
+	requestDetails json.RawMessage
+	project        string
+	kmsKeyName     string
+}
+
+// provisionCheck is a step of validating a provision request. Checks are
+// named so dry runs can report which one a request fails.
+type provisionCheck struct {
+	name  string
+	check func(broker *ServiceBroker, ctx context.Context, state *provisionCheckState) error
+}
+
+// provisionChecks run in order, each may rely on the ones before it.
+var provisionChecks = []provisionCheck{
+	{name: "parameters", check: (*ServiceBroker).checkParameters},
+	{name: "maintenance_info", check: (*ServiceBroker).checkMaintenanceInfo},
+	{name: "quota", check: (*ServiceBroker).checkQuota},
+	{name: "naming", check: (*ServiceBroker).nameResources},
+	{name: "schema", check: (*ServiceBroker).checkSchema},
+	{name: "constraints", check: (*ServiceBroker).checkPlanConstraints},
+	{name: "region", check: (*ServiceBroker).checkRegions},
+	{name: "project", check: (*ServiceBroker).checkProject},
+	{name: "kms_key", check: (*ServiceBroker).checkKmsKey},
+}
+
+// checkProvision runs the provision checks until one fails, calling report,
+// if it's set, with the outcome of each.
+func (broker *ServiceBroker) checkProvision(ctx context.Context, state *provisionCheckState, report func(name string, err error)) error {
+	for _, step := range provisionChecks {
+		err := step.check(broker, ctx, state)
+		if report != nil {
+			report(step.name, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkParameters gives the user a better error message if they give us a
+// bad request, then layers their parameters between the plan's defaults and
+// the operator's pinned parameters so they're saved with the instance along
+// with the results of expressions in the defaults.
+func (broker *ServiceBroker) checkParameters(ctx context.Context, state *provisionCheckState) error {
+	if !isValidOrEmptyJSON(state.details.GetRawParameters()) {
+		return ErrInvalidUserInput
+	}
+
+	if err := state.service.CheckPinnedParameters(state.details.GetRawParameters()); err != nil {
+		return parameterPinnedError(err)
+	}
+
+	resolved, err := state.service.ResolveProvisionParameters(state.instanceId, state.details, *state.plan)
+	if err != nil {
+		return err
+	}
+
+	state.details.RawParameters = resolved
+	state.requestDetails = resolved
+	return nil
+}
+
+// parameterPinnedError turns errors from changing pinned parameters into a
+// bad request.
+func parameterPinnedError(err error) error {
+	if _, ok := err.(*broker.PinnedParameterError); ok {
+		return osberror.New(err, http.StatusBadRequest, "parameter-pinned", osberror.ParameterPinned)
+	}
+
+	return err
+}
+
+// checkMaintenanceInfo makes sure the platform is provisioning the version in
+// the catalog.
+func (broker *ServiceBroker) checkMaintenanceInfo(ctx context.Context, state *provisionCheckState) error {
+	return state.service.ValidateMaintenanceInfo(state.details.MaintenanceInfo)
+}
+
+// checkQuota makes sure the organization and space have room for another
+// instance.
+func (broker *ServiceBroker) checkQuota(ctx context.Context, state *provisionCheckState) error {
+	if broker.Quotas == nil {
+		return nil
+	}
+
+	return quotaError(broker.Quotas.Check(ctx, state.service, state.details.OrganizationGUID, state.details.SpaceGUID))
+}
+
+// reserveQuota checks the quota again, holding it until release is called so
+// concurrent provisions can't take the instance's place before it's saved.
+func (broker *ServiceBroker) reserveQuota(ctx context.Context, state *provisionCheckState) (release func(), err error) {
+	if broker.Quotas == nil {
+		return func() {}, nil
+	}
+
+	release, err = broker.Quotas.Reserve(ctx, state.service, state.details.OrganizationGUID, state.details.SpaceGUID)
+	return release, quotaError(err)
+}
+
+// quotaError converts quota errors to the response telling the platform the
+// quota is exceeded.
+func quotaError(err error) error {
+	if _, ok := err.(*quota.ExceededError); ok {
+		return osberror.New(err, http.StatusForbidden, "quota-exceeded", osberror.QuotaExceeded)
+	}
+
+	return err
+}
+
+// nameResources generates the resource name from the naming template, it's
+// saved with the request so the name doesn't change if the template does.
+func (broker *ServiceBroker) nameResources(ctx context.Context, state *provisionCheckState) error {
+	named, err := state.service.NameResources(state.instanceId, state.details, *state.plan)
+	if err != nil {
+		return err
+	}
+
+	state.details.RawParameters = named
+	state.requestDetails = named
+	return nil
+}
+
+// checkSchema validates parameters meet the service's schema and merges the
+// user vars with the plan's.
+func (broker *ServiceBroker) checkSchema(ctx context.Context, state *provisionCheckState) error {
+	vars, err := state.service.ProvisionVariables(state.instanceId, state.details, *state.plan)
+	if err != nil {
+		return err
+	}
+
+	state.vars = vars
+	return nil
+}
+
+// checkPlanConstraints makes sure the parameters meet the constraints the
+// operator set on the plan.
+func (broker *ServiceBroker) checkPlanConstraints(ctx context.Context, state *provisionCheckState) error {
+	return planConstraintError(state.service.CheckPlanConstraints(state.plan, state.vars.ToMap()))
+}
+
+// planConstraintError turns errors from parameters that don't meet their
+// plan's constraints into a bad request.
+func planConstraintError(err error) error {
+	if _, ok := err.(*broker.ConstraintViolationError); ok {
+		return osberror.New(err, http.StatusBadRequest, "plan-constraint", osberror.PlanConstraint)
+	}
+
+	return err
+}
+
+// checkRegions checks the location before calling out to the provider so
+// users get an actionable error rather than a failure deep in the
+// provisioning calls.
+func (broker *ServiceBroker) checkRegions(ctx context.Context, state *provisionCheckState) error {
+	if err := state.service.ValidateRegions(state.vars); err != nil {
+		return osberror.New(err, http.StatusBadRequest, "region-not-permitted", osberror.RegionNotPermitted)
+	}
+
+	return nil
+}
+
+// checkProject makes sure the instance is going into a project the operator
+// allows and pins it so later operations on the instance target the same
+// project even if the defaults change.
+func (broker *ServiceBroker) checkProject(ctx context.Context, state *provisionCheckState) error {
+	if !state.vars.HasKey("project") {
+		return nil
+	}
+
+	project := state.vars.GetString("project")
+	if err := projects.Validate(project); err != nil {
+		return osberror.New(err, http.StatusBadRequest, "project-not-permitted", osberror.ProjectNotPermitted)
+	}
+
+	requestDetails, err := utils.SetParameter(state.requestDetails, "project", project)
+	if err != nil {
+		return err
+	}
+
+	state.project = project
+	state.requestDetails = requestDetails
+	return nil
+}
+
+// checkKmsKey makes sure the instance is encrypted with a key the operator
+// allows.
+func (broker *ServiceBroker) checkKmsKey(ctx context.Context, state *provisionCheckState) error {
+	kmsKeyName, err := validKmsKey(state.vars)
+	if err != nil {
+		return err
+	}
+
+	state.kmsKeyName = kmsKeyName
+	return nil
+}
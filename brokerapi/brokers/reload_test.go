@@ -0,0 +1,106 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/utils"
+)
+
+// TestGCPServiceBroker_Services_reload reloads the configuration while the
+// catalog is requested, run it with -race to check requests only read
+// configurations that are no longer written.
+func TestGCPServiceBroker_Services_reload(t *testing.T) {
+	stub := fakeService(t, false)
+	registry := broker.BrokerRegistry{}
+	registry.Register(stub.ServiceDefinition)
+
+	serviceBroker, closer := newStubbedBroker(t, registry, nil)
+	defer closer()
+
+	dir, err := ioutil.TempDir("", "reload-test")
+	failIfErr(t, "creating config dir", err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yml")
+	write := func(withPlan bool) {
+		plans := "[]"
+		if withPlan {
+			plans = `[{"id": "reload-plan-id", "name": "reload-plan", "storage_class": "STANDARD"}]`
+		}
+		content := fmt.Sprintf("service:\n  %s:\n    plans: '%s'\n", stub.ServiceDefinition.Name, plans)
+		failIfErr(t, "writing config", ioutil.WriteFile(file, []byte(content), 0600))
+	}
+
+	reloader := config.NewReloader(file, nil)
+	defer config.SetCurrent(nil)
+
+	router := mux.NewRouter()
+	brokerapi.AttachRoutes(router, serviceBroker, utils.NewLogger("reload-test"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 20; j++ {
+				req := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+				req.Header.Set("X-Broker-API-Version", "2.14")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				if w.Code != http.StatusOK {
+					t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+				}
+			}
+		}()
+	}
+
+	for i := 1; i <= 20; i++ {
+		write(i%2 == 0)
+		err := reloader.Reload(func() error {
+			_, err := serviceBroker.Services(context.Background())
+			return err
+		})
+		failIfErr(t, "reloading", err)
+	}
+
+	wg.Wait()
+
+	services, err := serviceBroker.Services(context.Background())
+	failIfErr(t, "getting the catalog", err)
+	for _, svc := range services {
+		for _, plan := range svc.Plans {
+			if plan.ID == "reload-plan-id" {
+				return
+			}
+		}
+	}
+	t.Error("Expected the catalog to have the reloaded plan")
+}
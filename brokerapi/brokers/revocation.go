@@ -0,0 +1,248 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/notify"
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+	"github.com/pivotal/cloud-service-broker/pkg/revocation"
+)
+
+var _ revocation.Revoker = (*ServiceBroker)(nil)
+
+// RevokeCredentials deletes the credentials of every binding matching the
+// filter from the service provider and credential store, marks the bindings as
+// needing rotation and notifies their owners. Failures revoking individual
+// bindings are reported rather than stopping the revocation. If the context is
+// a dry run the matching bindings are only reported.
+func (broker *ServiceBroker) RevokeCredentials(ctx context.Context, filter revocation.Filter) (*revocation.Report, error) {
+	broker.logger(ctx).Info("RevokeCredentials", lager.Data{"filter": filter})
+
+	if filter.IsEmpty() {
+		return nil, revocation.ErrEmptyFilter
+	}
+
+	conditions := models.ServiceBindingCredentials{}
+	if filter.Service != "" {
+		svc, err := broker.serviceByNameOrId(filter.Service)
+		if err != nil {
+			return nil, err
+		}
+		conditions.ServiceId = svc.Id
+	}
+
+	bindings, err := broker.store.ListServiceBindingCredentials(ctx, conditions)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing bindings: %s", err)
+	}
+
+	report := &revocation.Report{Revoked: []revocation.Binding{}, Failed: []revocation.Binding{}}
+	changes := &planning.Plan{}
+	var planned []revocation.Binding
+	var plannedBindings []*models.ServiceBindingCredentials
+	for i := range bindings {
+		binding := &bindings[i]
+		if binding.RevokedAt != nil {
+			continue
+		}
+
+		if filter.CreatedBefore != nil && !binding.CreatedAt.Before(*filter.CreatedBefore) {
+			continue
+		}
+
+		result := revocation.Binding{
+			BindingId:  binding.BindingId,
+			InstanceId: binding.ServiceInstanceId,
+			ServiceId:  binding.ServiceId,
+		}
+
+		instance, err := broker.store.GetServiceInstanceDetailsById(ctx, binding.ServiceInstanceId)
+		if err != nil {
+			result.Error = fmt.Sprintf("Error retrieving service instance details: %s", err)
+			report.Failed = append(report.Failed, result)
+			continue
+		}
+
+		result.PlanId = instance.PlanId
+		result.OrganizationGuid = instance.OrganizationGuid
+		result.SpaceGuid = instance.SpaceGuid
+
+		if !broker.matchesRevocationFilter(filter, instance) {
+			continue
+		}
+
+		details := map[string]interface{}{
+			"instance_id":       result.InstanceId,
+			"service_id":        result.ServiceId,
+			"plan_id":           result.PlanId,
+			"organization_guid": result.OrganizationGuid,
+			"space_guid":        result.SpaceGuid,
+		}
+		changes.Add("revoke-binding", binding.BindingId, details, func(ctx context.Context) error {
+			return broker.revokeBinding(ctx, instance, binding)
+		})
+		planned = append(planned, result)
+		plannedBindings = append(plannedBindings, binding)
+	}
+
+	executed := changes.Execute(ctx)
+	report.DryRun = executed.DryRun
+	for i, outcome := range executed.Outcomes {
+		result := planned[i]
+		switch {
+		case outcome.Error != "":
+			result.Error = outcome.Error
+			report.Failed = append(report.Failed, result)
+		case outcome.Applied:
+			result.RevokedAt = plannedBindings[i].RevokedAt
+			report.Revoked = append(report.Revoked, result)
+		default:
+			report.Planned = append(report.Planned, result)
+		}
+	}
+
+	if report.DryRun {
+		return report, nil
+	}
+
+	report.NotificationErrors = broker.notifyRevocations(ctx, filter.Reason, report.Revoked)
+
+	return report, nil
+}
+
+// ListRevokedBindings lists the bindings whose credentials were revoked and
+// still need to be rotated by their owners.
+func (broker *ServiceBroker) ListRevokedBindings(ctx context.Context) ([]revocation.Binding, error) {
+	bindings, err := broker.store.ListRevokedServiceBindingCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []revocation.Binding{}
+	for _, binding := range bindings {
+		result := revocation.Binding{
+			BindingId:  binding.BindingId,
+			InstanceId: binding.ServiceInstanceId,
+			ServiceId:  binding.ServiceId,
+			RevokedAt:  binding.RevokedAt,
+		}
+
+		if instance, err := broker.store.GetServiceInstanceDetailsById(ctx, binding.ServiceInstanceId); err == nil {
+			result.PlanId = instance.PlanId
+			result.OrganizationGuid = instance.OrganizationGuid
+			result.SpaceGuid = instance.SpaceGuid
+		}
+
+		out = append(out, result)
+	}
+
+	return out, nil
+}
+
+func (broker *ServiceBroker) revokeBinding(ctx context.Context, instance *models.ServiceInstanceDetails, binding *models.ServiceBindingCredentials) error {
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(ctx, binding.ServiceId)
+	if err != nil {
+		return err
+	}
+
+	if err := broker.deleteBindingCredentials(ctx, serviceDefinition, serviceProvider, instance, binding, instance.PlanId); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	binding.RevokedAt = &now
+	if err := broker.store.SaveServiceBindingCredentials(ctx, binding); err != nil {
+		return fmt.Errorf("Error marking binding as revoked: %s. WARNING: the credentials were deleted but the binding will not be reported as needing rotation", err)
+	}
+
+	return nil
+}
+
+// notifyRevocations sends one notification per organization and space listing
+// the bindings that were revoked in it.
+func (broker *ServiceBroker) notifyRevocations(ctx context.Context, reason string, revoked []revocation.Binding) []string {
+	if broker.Notifier == nil {
+		return nil
+	}
+
+	type owner struct{ org, space string }
+	var owners []owner
+	byOwner := make(map[owner][]revocation.Binding)
+	for _, binding := range revoked {
+		o := owner{org: binding.OrganizationGuid, space: binding.SpaceGuid}
+		if _, ok := byOwner[o]; !ok {
+			owners = append(owners, o)
+		}
+		byOwner[o] = append(byOwner[o], binding)
+	}
+
+	var errs []string
+	for _, o := range owners {
+		notification := notify.Notification{
+			Event:            revocation.CredentialsRevokedEvent,
+			OrganizationGuid: o.org,
+			SpaceGuid:        o.space,
+			Message:          fmt.Sprintf("The credentials of %d service binding(s) were revoked by an operator and must be rotated by unbinding and binding again. Reason: %s", len(byOwner[o]), reason),
+			Details:          byOwner[o],
+			Timestamp:        time.Now(),
+		}
+
+		if err := broker.Notifier.Notify(ctx, notification); err != nil {
+			broker.logger(ctx).Error("notifying owners of revoked credentials", err, lager.Data{"organization_guid": o.org, "space_guid": o.space})
+			errs = append(errs, fmt.Sprintf("organization %q space %q: %v", o.org, o.space, err))
+		}
+	}
+
+	return errs
+}
+
+func (broker *ServiceBroker) serviceByNameOrId(nameOrId string) (*broker.ServiceDefinition, error) {
+	for _, svc := range broker.registry.GetAllServices() {
+		if svc.Name == nameOrId || svc.Id == nameOrId {
+			return svc, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Unknown service: %q", nameOrId)
+}
+
+func (broker *ServiceBroker) matchesRevocationFilter(filter revocation.Filter, instance *models.ServiceInstanceDetails) bool {
+	if filter.OrganizationGuid != "" && filter.OrganizationGuid != instance.OrganizationGuid {
+		return false
+	}
+
+	if filter.SpaceGuid != "" && filter.SpaceGuid != instance.SpaceGuid {
+		return false
+	}
+
+	if filter.Plan == "" || filter.Plan == instance.PlanId {
+		return true
+	}
+
+	svc, err := broker.registry.GetServiceById(instance.ServiceId)
+	if err != nil {
+		return false
+	}
+
+	plan, err := svc.GetPlanById(instance.PlanId)
+	return err == nil && plan.Name == filter.Plan
+}
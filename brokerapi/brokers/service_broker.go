@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"code.cloudfoundry.org/lager"
 	"github.com/pivotal-cf/brokerapi"
@@ -29,34 +30,101 @@ import (
 	"github.com/pivotal/cloud-service-broker/db_service"
 	"github.com/pivotal/cloud-service-broker/db_service/models"
 	"github.com/pivotal/cloud-service-broker/pkg/credstore"
+	"github.com/pivotal/cloud-service-broker/pkg/dedupe"
+	"github.com/pivotal/cloud-service-broker/pkg/events"
+	"github.com/pivotal/cloud-service-broker/pkg/experiments"
+	"github.com/pivotal/cloud-service-broker/pkg/failure"
+	"github.com/pivotal/cloud-service-broker/pkg/logging"
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/cmek"
+	"github.com/pivotal/cloud-service-broker/pkg/notify"
+	"github.com/pivotal/cloud-service-broker/pkg/osberror"
+	"github.com/pivotal/cloud-service-broker/pkg/platformcontext"
+	"github.com/pivotal/cloud-service-broker/pkg/projects"
+	"github.com/pivotal/cloud-service-broker/pkg/quota"
+	"github.com/pivotal/cloud-service-broker/pkg/requestdetails"
+	"github.com/pivotal/cloud-service-broker/pkg/serviceaccounts"
+	"github.com/pivotal/cloud-service-broker/pkg/upgrade"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+	"github.com/pivotal/cloud-service-broker/utils"
 )
 
 var (
 	invalidUserInputMsg        = "User supplied paramaters must be in the form of a valid JSON map."
-	ErrInvalidUserInput        = brokerapi.NewFailureResponse(errors.New(invalidUserInputMsg), http.StatusBadRequest, "parsing-user-request")
-	ErrGetInstancesUnsupported = brokerapi.NewFailureResponse(errors.New("the service_instances endpoint is unsupported"), http.StatusBadRequest, "unsupported")
-	ErrGetBindingsUnsupported  = brokerapi.NewFailureResponse(errors.New("the service_bindings endpoint is unsupported"), http.StatusBadRequest, "unsupported")
-	ErrNonUpdatableParameter   = brokerapi.NewFailureResponse(errors.New("attempt to update parameter that may result in service instance re-creation and data loss"), http.StatusBadRequest, "prohibited")
+	ErrInvalidUserInput        = osberror.New(errors.New(invalidUserInputMsg), http.StatusBadRequest, "parsing-user-request", osberror.InvalidParameters)
+	ErrGetInstancesUnsupported = osberror.New(errors.New("the service_instances endpoint is unsupported"), http.StatusBadRequest, "unsupported", osberror.UnsupportedEndpoint)
+	ErrGetBindingsUnsupported  = osberror.New(errors.New("the service_bindings endpoint is unsupported"), http.StatusBadRequest, "unsupported", osberror.UnsupportedEndpoint)
+	ErrNonUpdatableParameter   = osberror.New(errors.New("attempt to update parameter that may result in service instance re-creation and data loss"), http.StatusBadRequest, "prohibited", osberror.NonUpdatableParameter)
 )
 
 const credhubClientIdentifier = "csb"
 
 // ServiceBroker is a brokerapi.ServiceBroker that can be used to generate an OSB compatible service broker.
 type ServiceBroker struct {
+	// store holds the broker's instances, bindings and their operations.
+	store db_service.Datastore
+
 	registry  broker.BrokerRegistry
 	Credstore credstore.CredStore
+	Quotas    *quota.Enforcer
+	Notifier  notify.Notifier
+
+	// Provisions coalesces duplicate provision requests, it's nil if
+	// deduplication is disabled.
+	Provisions *dedupe.Group
+
+	// RequestDetails is what's kept of the parameters instances are
+	// provisioned with.
+	RequestDetails requestdetails.Policy
+
+	// ServiceAccounts deletes the service accounts bindings created if their
+	// service provider leaves them behind, it's nil if no Google Cloud
+	// credentials are configured.
+	ServiceAccounts serviceaccounts.Manager
+
+	// DeletedInstanceIds is how provision requests reusing the ID of a
+	// deprovisioned instance are handled, DeletedInstanceIdsReject if empty.
+	DeletedInstanceIds string
+
+	// Events publishes the lifecycle events of instances and bindings, it's
+	// nil if they aren't published.
+	Events *events.Bus
+
+	// changing holds the instances with an update or deprovision in progress.
+	changing instanceLocks
 
 	Logger lager.Logger
 }
 
-// New creates a ServiceBroker.
+// New creates a ServiceBroker using the database opened by db_service.New,
+// which must be called first.
 // Exactly one of ServiceBroker or error will be nil when returned.
 func New(cfg *BrokerConfig, logger lager.Logger) (*ServiceBroker, error) {
+	return NewBroker(db_service.NewRetryingDatastore(db_service.NewSqlDatastore(db_service.DbConnection)), cfg, logger)
+}
+
+// NewBroker creates a ServiceBroker that keeps its instances and bindings in
+// store, serves the catalog in cfg.Registry and uses the clients in cfg. It
+// doesn't use the package-level database so programs embedding the broker can
+// run several with different databases.
+// Exactly one of ServiceBroker or error will be nil when returned.
+func NewBroker(store db_service.Datastore, cfg *BrokerConfig, logger lager.Logger) (*ServiceBroker, error) {
+	if store == nil {
+		return nil, errors.New("a datastore is required")
+	}
+
 	return &ServiceBroker{
-		registry:  cfg.Registry,
-		Credstore: cfg.Credstore,
-		Logger:    logger,
+		store:              store,
+		registry:           cfg.Registry,
+		Credstore:          cfg.Credstore,
+		Quotas:             &quota.Enforcer{Rules: cfg.QuotaRules, Store: store},
+		Notifier:           cfg.Notifier,
+		Provisions:         cfg.Provisions,
+		RequestDetails:     cfg.RequestDetails,
+		ServiceAccounts:    cfg.ServiceAccounts,
+		DeletedInstanceIds: cfg.DeletedInstanceIds,
+		Events:             cfg.Events,
+		Logger:             logger,
 	}, nil
 }
 
@@ -81,35 +149,112 @@ func (broker *ServiceBroker) Services(ctx context.Context) ([]brokerapi.Service,
 	return svcs, nil
 }
 
-func (broker *ServiceBroker) getDefinitionAndProvider(serviceId string) (*broker.ServiceDefinition, broker.ServiceProvider, error) {
+// getDefinitionAndProvider gets the service and creates its provider with
+// the request's logger so calls made by the provider are correlated with it.
+func (broker *ServiceBroker) getDefinitionAndProvider(ctx context.Context, serviceId string) (*broker.ServiceDefinition, broker.ServiceProvider, error) {
 	defn, err := broker.registry.GetServiceById(serviceId)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	providerBuilder := defn.ProviderBuilder(broker.Logger)
+	providerBuilder := defn.ProviderBuilder(broker.logger(ctx), broker.store)
 	return defn, providerBuilder, nil
 }
 
+// logger returns the request's logger, which includes its correlation ID, or
+// the broker's logger if the context isn't from a request.
+func (broker *ServiceBroker) logger(ctx context.Context) lager.Logger {
+	return logging.FromContext(ctx, broker.Logger)
+}
+
 // Provision creates a new instance of a service.
 // It is bound to the `PUT /v2/service_instances/:instance_id` endpoint and can be called using the `cf create-service` command.
+// Identical requests for the same instance made while one is in-flight, or
+// shortly after it succeeded, share its response rather than racing to create
+// resources.
 func (broker *ServiceBroker) Provision(ctx context.Context, instanceID string, details brokerapi.ProvisionDetails, clientSupportsAsync bool) (brokerapi.ProvisionedServiceSpec, error) {
-	broker.Logger.Info("Provisioning", lager.Data{
+	broker.logger(ctx).Info("Provisioning", lager.Data{
 		"instanceId":         instanceID,
 		"accepts_incomplete": clientSupportsAsync,
 		"details":            details,
 	})
 
+	if broker.Provisions == nil {
+		return broker.provision(ctx, instanceID, details, clientSupportsAsync, false)
+	}
+
+	hash, err := dedupe.Hash(provisionRequest{Details: details, ClientSupportsAsync: clientSupportsAsync, Experiments: experiments.FromContext(ctx)})
+	if err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("Error hashing provision request: %s", err)
+	}
+
+	result, shared, err := broker.Provisions.Do(instanceID, hash, func() (interface{}, error) {
+		return broker.provision(ctx, instanceID, details, clientSupportsAsync, false)
+	})
+	if err == dedupe.ErrConflict {
+		return brokerapi.ProvisionedServiceSpec{}, brokerapi.ErrInstanceAlreadyExists
+	}
+
+	if shared {
+		broker.logger(ctx).Info("coalesced-duplicate-provision", lager.Data{"instanceId": instanceID})
+	}
+
+	spec, _ := result.(brokerapi.ProvisionedServiceSpec)
+	return spec, err
+}
+
+// provisionRequest holds the parts of a provision request that must match for
+// requests to be coalesced.
+type provisionRequest struct {
+	Details             brokerapi.ProvisionDetails
+	ClientSupportsAsync bool
+	Experiments         []string
+}
+
+// provision creates the instance. If importing is true the provider adopts
+// existing resources instead.
+func (broker *ServiceBroker) provision(ctx context.Context, instanceID string, details brokerapi.ProvisionDetails, clientSupportsAsync, importing bool) (_ brokerapi.ProvisionedServiceSpec, err error) {
+	// the parameters the user asked for, before the broker adds its own
+	requestedParameters := details.GetRawParameters()
+	requestHash, err := requestdetails.Hash(details)
+	if err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, ErrInvalidUserInput
+	}
+
 	// make sure that instance hasn't already been provisioned
-	exists, err := db_service.ExistsServiceInstanceDetailsById(ctx, instanceID)
+	exists, err := broker.store.ExistsServiceInstanceDetailsById(ctx, instanceID)
 	if err != nil {
 		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("Database error checking for existing instance: %s", err)
 	}
 	if exists {
-		return brokerapi.ProvisionedServiceSpec{}, brokerapi.ErrInstanceAlreadyExists
+		return broker.existingProvision(ctx, instanceID, requestHash)
+	}
+
+	// e.g. the cleanup of a previous instance with the ID
+	if err := broker.checkNoOperationInProgress(ctx, instanceID); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
-	brokerService, serviceHelper, err := broker.getDefinitionAndProvider(details.ServiceID)
+	if err := broker.checkDeletedInstanceId(ctx, instanceID); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+
+	// the request is for a new instance, so failures from here on are
+	// published
+	defer func() {
+		if err != nil {
+			failed := models.ServiceInstanceDetails{
+				ID:               instanceID,
+				ServiceId:        details.ServiceID,
+				PlanId:           details.PlanID,
+				OrganizationGuid: details.OrganizationGUID,
+				SpaceGuid:        details.SpaceGUID,
+			}
+			broker.publishEvent(events.ProvisionFailed, failed, "", err)
+		}
+	}()
+
+	brokerService, serviceHelper, err := broker.getDefinitionAndProvider(ctx, details.ServiceID)
 	if err != nil {
 		return brokerapi.ProvisionedServiceSpec{}, err
 	}
@@ -126,20 +271,26 @@ func (broker *ServiceBroker) Provision(ctx context.Context, instanceID string, d
 		return brokerapi.ProvisionedServiceSpec{}, brokerapi.ErrAsyncRequired
 	}
 
-	// Give the user a better error message if they give us a bad request
-	if !isValidOrEmptyJSON(details.GetRawParameters()) {
-		return brokerapi.ProvisionedServiceSpec{}, ErrInvalidUserInput
+	state := &provisionCheckState{instanceId: instanceID, details: details, service: brokerService, plan: plan}
+	if err := broker.checkProvision(ctx, state, nil); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
 	}
+	// the checks add the generated resource name to the parameters
+	details, vars := state.details, state.vars
 
-	// validate parameters meet the service's schema and merge the user vars with
-	// the plan's
-	vars, err := brokerService.ProvisionVariables(instanceID, details, *plan)
+	releaseQuota, err := broker.reserveQuota(ctx, state)
 	if err != nil {
 		return brokerapi.ProvisionedServiceSpec{}, err
 	}
+	defer releaseQuota()
 
 	// get instance details
-	instanceDetails, err := serviceHelper.Provision(ctx, vars)
+	var instanceDetails models.ServiceInstanceDetails
+	if importing {
+		instanceDetails, err = importResources(ctx, serviceHelper, vars)
+	} else {
+		instanceDetails, err = serviceHelper.Provision(ctx, vars)
+	}
 	if err != nil {
 		return brokerapi.ProvisionedServiceSpec{}, err
 	}
@@ -150,41 +301,113 @@ func (broker *ServiceBroker) Provision(ctx context.Context, instanceID string, d
 	instanceDetails.PlanId = details.PlanID
 	instanceDetails.SpaceGuid = details.SpaceGUID
 	instanceDetails.OrganizationGuid = details.OrganizationGUID
+	instanceDetails.ProjectId = state.project
+	instanceDetails.KmsKeyName = state.kmsKeyName
+	instanceDetails.MaintenanceVersion = brokerService.MaintenanceVersion()
+	instanceDetails.Experiments = strings.Join(experiments.FromContext(ctx), ",")
+	if err := instanceDetails.SetLabels(utils.ExtractDefaultProvisionLabels(instanceID, details)); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("Error serializing instance labels: %s", err)
+	}
+	if err := instanceDetails.SetPlatformContext(platformcontext.Parse(details.RawContext)); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("Error serializing instance context: %s", err)
+	}
 
-	err = db_service.CreateServiceInstanceDetails(ctx, &instanceDetails)
+	err = broker.store.CreateServiceInstanceDetails(ctx, &instanceDetails)
 	if err != nil {
 		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("Error saving instance details to database: %s. WARNING: this instance cannot be deprovisioned through cf. Contact your operator for cleanup", err)
 	}
 
-	// save provision request details
+	// save provision request details, keeping only what the operator allows
+	keptDetails, err := broker.RequestDetails.Apply(requestedParameters, state.requestDetails)
+	if err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("Error applying request details policy: %s", err)
+	}
 	pr := models.ProvisionRequestDetails{
 		ServiceInstanceId: instanceID,
-		RequestDetails:    string(details.RawParameters),
+		RequestDetails:    string(keptDetails),
+		ParametersHash:    requestHash,
+		Context:           string(details.RawContext),
 	}
-	if err = db_service.CreateProvisionRequestDetails(ctx, &pr); err != nil {
+	if err = broker.store.CreateProvisionRequestDetails(ctx, &pr); err != nil {
 		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("Error saving provision request details to database: %s. Services relying on async provisioning will not be able to complete provisioning", err)
 	}
 
+	// asynchronous provisions are published when LastOperation sees them
+	// finish
+	if !shouldProvisionAsync {
+		broker.publishEvent(events.InstanceProvisioned, instanceDetails, "", nil)
+	}
+
 	return brokerapi.ProvisionedServiceSpec{IsAsync: shouldProvisionAsync, DashboardURL: "", OperationData: instanceDetails.OperationId}, nil
 }
 
+// existingProvision responds to a request to provision an instance that
+// exists. A retry of the request still provisioning it, e.g. one sent to
+// another replica, gets the operation to poll; other requests conflict.
+func (broker *ServiceBroker) existingProvision(ctx context.Context, instanceID, requestHash string) (brokerapi.ProvisionedServiceSpec, error) {
+	pr, err := broker.store.GetProvisionRequestDetailsByInstanceId(ctx, instanceID)
+	if err != nil || pr.ParametersHash == "" || pr.ParametersHash != requestHash {
+		return brokerapi.ProvisionedServiceSpec{}, brokerapi.ErrInstanceAlreadyExists
+	}
+
+	instance, err := broker.store.GetServiceInstanceDetailsById(ctx, instanceID)
+	if err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("Database error checking for existing instance: %s", err)
+	}
+	if instance.OperationType != models.ProvisionOperationType || instance.OperationId == "" {
+		return brokerapi.ProvisionedServiceSpec{}, brokerapi.ErrInstanceAlreadyExists
+	}
+
+	broker.logger(ctx).Info("retried-provision", lager.Data{"instanceId": instanceID})
+	return brokerapi.ProvisionedServiceSpec{IsAsync: true, OperationData: instance.OperationId}, nil
+}
+
 // Deprovision destroys an existing instance of a service.
 // It is bound to the `DELETE /v2/service_instances/:instance_id` endpoint and can be called using the `cf delete-service` command.
 // If a deprovision is asynchronous, the returned DeprovisionServiceSpec will contain the operation ID for tracking its progress.
 func (broker *ServiceBroker) Deprovision(ctx context.Context, instanceID string, details brokerapi.DeprovisionDetails, clientSupportsAsync bool) (response brokerapi.DeprovisionServiceSpec, err error) {
-	broker.Logger.Info("Deprovisioning", lager.Data{
+	broker.logger(ctx).Info("Deprovisioning", lager.Data{
 		"instance_id":        instanceID,
 		"accepts_incomplete": clientSupportsAsync,
 		"details":            details,
 	})
 
+	unlock, err := broker.changing.lock(instanceID)
+	if err != nil {
+		return response, err
+	}
+	defer unlock()
+
 	// make sure that instance actually exists
-	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	instance, err := broker.store.GetServiceInstanceDetailsById(ctx, instanceID)
 	if err != nil {
 		return response, brokerapi.ErrInstanceDoesNotExist
 	}
+	ctx = withInstanceExperiments(ctx, instance)
+
+	// the instance is untouched until the provider is asked to delete it
+	deleting := false
+	defer func() {
+		if err != nil && !deleting {
+			osberror.Record(ctx, osberror.Details{InstanceUsable: osberror.Bool(true)})
+		}
+	}()
+
+	pending, err := broker.hasPendingJobs(ctx, instanceID)
+	if err != nil {
+		return response, err
+	}
+	if pending {
+		// platforms repeating a deprovision that's in progress are told to
+		// keep polling it
+		if instance.OperationType == models.DeprovisionOperationType && instance.OperationId != "" {
+			return brokerapi.DeprovisionServiceSpec{IsAsync: true, OperationData: instance.OperationId}, nil
+		}
 
-	brokerService, serviceProvider, err := broker.getDefinitionAndProvider(instance.ServiceId)
+		return response, concurrencyError(fmt.Errorf("an operation on instance %s is in progress, try again once it's done", instanceID))
+	}
+
+	brokerService, serviceProvider, err := broker.getDefinitionAndProvider(ctx, instance.ServiceId)
 	if err != nil {
 		return response, err
 	}
@@ -195,20 +418,33 @@ func (broker *ServiceBroker) Deprovision(ctx context.Context, instanceID string,
 		return response, err
 	}
 
+	protectionStatus, err := broker.protectionStatus(ctx, instance)
+	if err != nil {
+		return response, err
+	}
+	if protectionStatus.Protected {
+		return response, deletionProtectedError(protectionStatus)
+	}
+
 	// if async deprovisioning isn't allowed but this service needs it, throw an error
 	if serviceProvider.DeprovisionsAsync() && !clientSupportsAsync {
 		return response, brokerapi.ErrAsyncRequired
 	}
 
-	pr, err := db_service.GetProvisionRequestDetailsByInstanceId(ctx, instanceID)
+	pr, err := broker.store.GetProvisionRequestDetailsByInstanceId(ctx, instanceID)
 	if err != nil {
 		return response, fmt.Errorf("updating non-existent instanceid: %v", instanceID)
 	}	
 
+	provisionParameters, err := requestdetails.Restore(json.RawMessage(pr.RequestDetails))
+	if err != nil {
+		return response, err
+	}
+
 	provisionDetails := brokerapi.ProvisionDetails{
 		ServiceID: details.ServiceID,
 		PlanID: details.PlanID,
-		RawParameters: json.RawMessage(pr.RequestDetails),
+		RawParameters: provisionParameters,
 	}
 
 	// validate parameters meet the service's schema and merge the user vars with
@@ -218,6 +454,7 @@ func (broker *ServiceBroker) Deprovision(ctx context.Context, instanceID string,
 		return response, err
 	}	
 
+	deleting = true
 	operationId, err := serviceProvider.Deprovision(ctx, *instance, details, vars)
 	if err != nil {
 		return response, err
@@ -227,9 +464,11 @@ func (broker *ServiceBroker) Deprovision(ctx context.Context, instanceID string,
 		// soft-delete instance details from the db if this is a synchronous operation
 		// if it's an async operation we can't delete from the db until we're sure delete succeeded, so this is
 		// handled internally to LastOperation
-		if err := db_service.DeleteServiceInstanceDetailsById(ctx, instanceID); err != nil {
+		if err := broker.store.DeleteServiceInstanceDetailsAndBindingsById(ctx, instanceID); err != nil {
 			return response, fmt.Errorf("Error deleting instance details from database: %s. WARNING: this instance will remain visible in cf. Contact your operator for cleanup", err)
 		}
+		broker.deleteInstanceShares(ctx, instanceID)
+		broker.publishEvent(events.Deprovisioned, *instance, "", nil)
 		return response, nil
 	} else {
 		response.IsAsync = true
@@ -237,7 +476,7 @@ func (broker *ServiceBroker) Deprovision(ctx context.Context, instanceID string,
 
 		instance.OperationType = models.DeprovisionOperationType
 		instance.OperationId = *operationId
-		if err := db_service.SaveServiceInstanceDetails(ctx, instance); err != nil {
+		if err := broker.store.SaveServiceInstanceDetails(ctx, instance); err != nil {
 			return response, fmt.Errorf("Error saving instance details to database: %s. WARNING: this instance will remain visible in cf. Contact your operator for cleanup.", err)
 		}
 		return response, nil
@@ -247,14 +486,14 @@ func (broker *ServiceBroker) Deprovision(ctx context.Context, instanceID string,
 // Bind creates an account with credentials to access an instance of a service.
 // It is bound to the `PUT /v2/service_instances/:instance_id/service_bindings/:binding_id` endpoint and can be called using the `cf bind-service` command.
 func (broker *ServiceBroker) Bind(ctx context.Context, instanceID, bindingID string, details brokerapi.BindDetails, clientSupportsAsync bool) (brokerapi.Binding, error) {
-	broker.Logger.Info("Binding", lager.Data{
+	broker.logger(ctx).Info("Binding", lager.Data{
 		"instance_id": instanceID,
 		"binding_id":  bindingID,
 		"details":     details,
 	})
 
 	// check for existing binding
-	exists, err := db_service.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, instanceID, bindingID)
+	exists, err := broker.store.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, instanceID, bindingID)
 	if err != nil {
 		return brokerapi.Binding{}, fmt.Errorf("Error checking for existing binding: %s", err)
 	}
@@ -262,13 +501,28 @@ func (broker *ServiceBroker) Bind(ctx context.Context, instanceID, bindingID str
 		return brokerapi.Binding{}, brokerapi.ErrBindingAlreadyExists
 	}
 
+	if err := broker.checkNoOperationInProgress(ctx, instanceID); err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	// credentials in CredHub are only readable by the app they're bound to
+	appGuid := details.AppGUID
+	if appGuid == "" && details.BindResource != nil {
+		appGuid = details.BindResource.AppGuid
+	}
+	if broker.Credstore != nil && appGuid == "" {
+		err := errors.New("bindings store their credentials in CredHub for an app, but the request has no app_guid")
+		return brokerapi.Binding{}, osberror.New(err, http.StatusUnprocessableEntity, "app-guid-not-provided", osberror.RequiresApp)
+	}
+
 	// get existing service instance details
-	instanceRecord, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	instanceRecord, err := broker.store.GetServiceInstanceDetailsById(ctx, instanceID)
 	if err != nil {
-		return brokerapi.Binding{}, fmt.Errorf("Error retrieving service instance details: %s", err)
+		return brokerapi.Binding{}, brokerapi.ErrInstanceDoesNotExist
 	}
+	ctx = withInstanceExperiments(ctx, instanceRecord)
 
-	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(instanceRecord.ServiceId)
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(ctx, instanceRecord.ServiceId)
 	if err != nil {
 		return brokerapi.Binding{}, err
 	}
@@ -284,6 +538,18 @@ func (broker *ServiceBroker) Bind(ctx context.Context, instanceID, bindingID str
 		return brokerapi.Binding{}, ErrInvalidUserInput
 	}
 
+	// apps in other spaces can only bind if the service allows sharing
+	shareOrg, shareSpace, err := sharedWith(serviceDefinition, instanceRecord, details)
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	// operators that opted out of keys don't get them from services that can't
+	// bind without one
+	if err := serviceDefinition.ValidateKeyless(); err != nil {
+		return brokerapi.Binding{}, osberror.New(err, http.StatusBadRequest, "keyless-bind-unsupported", osberror.KeylessUnsupported)
+	}
+
 	// validate parameters meet the service's schema and merge the plan's vars with
 	// the user's
 	vars, err := serviceDefinition.BindVariables(*instanceRecord, bindingID, details, plan)
@@ -291,6 +557,20 @@ func (broker *ServiceBroker) Bind(ctx context.Context, instanceID, bindingID str
 		return brokerapi.Binding{}, err
 	}
 
+	// make sure the operator allows the requested role to be granted
+	if vars.HasKey("role") {
+		if err := serviceDefinition.ValidateRole(vars.GetString("role")); err != nil {
+			return brokerapi.Binding{}, osberror.New(err, http.StatusBadRequest, "role-not-permitted", osberror.RoleNotPermitted)
+		}
+	}
+
+	// make sure the binding isn't created in a project the operator doesn't allow
+	if vars.HasKey("project") {
+		if err := projects.Validate(vars.GetString("project")); err != nil {
+			return brokerapi.Binding{}, osberror.New(err, http.StatusBadRequest, "project-not-permitted", osberror.ProjectNotPermitted)
+		}
+	}
+
 	// create binding
 	credsDetails, err := serviceProvider.Bind(ctx, vars)
 	if err != nil {
@@ -302,19 +582,29 @@ func (broker *ServiceBroker) Bind(ctx context.Context, instanceID, bindingID str
 		return brokerapi.Binding{}, fmt.Errorf("Error serializing credentials: %s. WARNING: these credentials cannot be unbound through cf. Please contact your operator for cleanup", err)
 	}
 
-	// save binding to database
+	// save binding to database, recording the service account it created so
+	// it's deleted on unbind
+	serviceAccountEmail, serviceAccountKeyId := serviceaccounts.FromCredentials(credsDetails)
 	newCreds := models.ServiceBindingCredentials{
-		ServiceInstanceId: instanceID,
-		BindingId:         bindingID,
-		ServiceId:         details.ServiceID,
-		OtherDetails:      string(serializedCreds),
+		ServiceInstanceId:   instanceID,
+		BindingId:           bindingID,
+		ServiceId:           details.ServiceID,
+		OtherDetails:        string(serializedCreds),
+		ServiceAccountEmail: serviceAccountEmail,
+		ServiceAccountKeyId: serviceAccountKeyId,
 	}
 
-	if err := db_service.CreateServiceBindingCredentials(ctx, &newCreds); err != nil {
+	if err := broker.store.CreateServiceBindingCredentials(ctx, &newCreds); err != nil {
 		return brokerapi.Binding{}, fmt.Errorf("Error saving credentials to database: %s. WARNING: these credentials cannot be unbound through cf. Please contact your operator for cleanup",
 			err)
 	}
 
+	if shareSpace != "" {
+		if err := broker.store.RecordInstanceShare(ctx, instanceID, shareOrg, shareSpace); err != nil {
+			broker.logger(ctx).Error("recording instance share", err, lager.Data{"instance_id": instanceID, "space_guid": shareSpace})
+		}
+	}
+
 	binding, err := serviceProvider.BuildInstanceCredentials(ctx, newCreds, *instanceRecord)
 	if err != nil {
 		return brokerapi.Binding{}, err
@@ -328,7 +618,7 @@ func (broker *ServiceBroker) Bind(ctx context.Context, instanceID, bindingID str
 			return brokerapi.Binding{}, fmt.Errorf("Bind failure: unable to put credentials in Credstore: %v", err)
 		}
 
-		_, err = broker.Credstore.AddPermission(credentialName, "mtls-app:"+details.AppGUID, []string{"read"})
+		_, err = broker.Credstore.AddPermission(credentialName, "mtls-app:"+appGuid, []string{"read"})
 		if err != nil {
 			return brokerapi.Binding{}, fmt.Errorf("Bind failure: Unable to add Credstore permissions to app: %v", err)
 		}
@@ -338,6 +628,7 @@ func (broker *ServiceBroker) Bind(ctx context.Context, instanceID, bindingID str
 		}
 	}
 
+	broker.publishEvent(events.Bound, *instanceRecord, bindingID, nil)
 	return *binding, nil
 }
 
@@ -354,7 +645,7 @@ func getCredentialName(serviceName, bindingID string) string {
 //
 // NOTE: This functionality is not implemented.
 func (broker *ServiceBroker) GetBinding(ctx context.Context, instanceID, bindingID string) (brokerapi.GetBindingSpec, error) {
-	broker.Logger.Info("GetBinding", lager.Data{
+	broker.logger(ctx).Info("GetBinding", lager.Data{
 		"instance_id": instanceID,
 		"binding_id":  bindingID,
 	})
@@ -367,7 +658,7 @@ func (broker *ServiceBroker) GetBinding(ctx context.Context, instanceID, binding
 //
 // NOTE: This functionality is not implemented.
 func (broker *ServiceBroker) GetInstance(ctx context.Context, instanceID string) (brokerapi.GetInstanceDetailsSpec, error) {
-	broker.Logger.Info("GetInstance", lager.Data{
+	broker.logger(ctx).Info("GetInstance", lager.Data{
 		"instance_id": instanceID,
 	})
 
@@ -379,7 +670,7 @@ func (broker *ServiceBroker) GetInstance(ctx context.Context, instanceID string)
 //
 // NOTE: This functionality is not implemented.
 func (broker *ServiceBroker) LastBindingOperation(ctx context.Context, instanceID, bindingID string, details brokerapi.PollDetails) (brokerapi.LastOperation, error) {
-	broker.Logger.Info("LastBindingOperation", lager.Data{
+	broker.logger(ctx).Info("LastBindingOperation", lager.Data{
 		"instance_id":    instanceID,
 		"binding_id":     bindingID,
 		"plan_id":        details.PlanID,
@@ -393,97 +684,145 @@ func (broker *ServiceBroker) LastBindingOperation(ctx context.Context, instanceI
 // Unbind destroys an account and credentials with access to an instance of a service.
 // It is bound to the `DELETE /v2/service_instances/:instance_id/service_bindings/:binding_id` endpoint and can be called using the `cf unbind-service` command.
 func (broker *ServiceBroker) Unbind(ctx context.Context, instanceID, bindingID string, details brokerapi.UnbindDetails, asyncSupported bool) (brokerapi.UnbindSpec, error) {
-	broker.Logger.Info("Unbinding", lager.Data{
+	broker.logger(ctx).Info("Unbinding", lager.Data{
 		"instance_id": instanceID,
 		"binding_id":  bindingID,
 		"details":     details,
 	})
 
-	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(details.ServiceID)
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(ctx, details.ServiceID)
 	if err != nil {
 		return brokerapi.UnbindSpec{}, err
 	}
 
 	// validate existence of binding
-	existingBinding, err := db_service.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, instanceID, bindingID)
+	existingBinding, err := broker.store.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, instanceID, bindingID)
 	if err != nil {
 		return brokerapi.UnbindSpec{}, brokerapi.ErrBindingDoesNotExist
 	}
 
 	// get existing service instance details
-	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	instance, err := broker.store.GetServiceInstanceDetailsById(ctx, instanceID)
 	if err != nil {
 		return brokerapi.UnbindSpec{}, fmt.Errorf("Error retrieving service instance details: %s", err)
 	}
+	ctx = withInstanceExperiments(ctx, instance)
+
+	// credentials that were revoked by an operator have already been removed
+	// from the service provider
+	if existingBinding.RevokedAt == nil {
+		if err := broker.deleteBindingCredentials(ctx, serviceDefinition, serviceProvider, instance, existingBinding, details.PlanID); err != nil {
+			return brokerapi.UnbindSpec{}, err
+		}
+	}
+
+	// remove binding from database
+	if err := broker.store.DeleteServiceBindingCredentials(ctx, existingBinding); err != nil {
+		return brokerapi.UnbindSpec{}, fmt.Errorf("Error soft-deleting credentials from database: %s. WARNING: these credentials will remain visible in cf. Contact your operator for cleanup", err)
+	}
 
+	broker.publishEvent(events.Unbound, *instance, bindingID, nil)
+	return brokerapi.UnbindSpec{}, nil
+}
+
+// deleteBindingCredentials removes the credentials of a binding from the
+// service provider and the credential store.
+func (broker *ServiceBroker) deleteBindingCredentials(ctx context.Context, serviceDefinition *broker.ServiceDefinition, serviceProvider broker.ServiceProvider, instance *models.ServiceInstanceDetails, binding *models.ServiceBindingCredentials, planID string) error {
 	// verify the service exists and the plan exists
-	plan, err := serviceDefinition.GetPlanById(details.PlanID)
+	plan, err := serviceDefinition.GetPlanById(planID)
 	if err != nil {
-		return brokerapi.UnbindSpec{}, err
+		return err
 	}
 
-	pr, err := db_service.GetProvisionRequestDetailsByInstanceId(ctx, instanceID)
+	pr, err := broker.store.GetProvisionRequestDetailsByInstanceId(ctx, instance.ID)
 	if err != nil {
-		return brokerapi.UnbindSpec{}, fmt.Errorf("updating non-existent instanceid: %v", instanceID)
-	}	
+		return fmt.Errorf("updating non-existent instanceid: %v", instance.ID)
+	}
+
+	provisionParameters, err := requestdetails.Restore(json.RawMessage(pr.RequestDetails))
+	if err != nil {
+		return err
+	}
 
 	// validate parameters meet the service's schema and merge the plan's vars with
 	// the user's
 	bindDetails := brokerapi.BindDetails{
-		PlanID: details.PlanID,
-		ServiceID: details.ServiceID,
-		RawParameters: json.RawMessage(pr.RequestDetails),
+		PlanID:        planID,
+		ServiceID:     instance.ServiceId,
+		RawParameters: provisionParameters,
 	}
 
-	vars, err := serviceDefinition.BindVariables(*instance, bindingID, bindDetails, plan)
+	vars, err := serviceDefinition.BindVariables(*instance, binding.BindingId, bindDetails, plan)
 	if err != nil {
-		return brokerapi.UnbindSpec{}, err
+		return err
 	}
 
 	// remove binding from service provider
-	if err := serviceProvider.Unbind(ctx, *instance, *existingBinding, vars); err != nil {
-		return brokerapi.UnbindSpec{}, err
+	if err := serviceProvider.Unbind(ctx, *instance, *binding, vars); err != nil {
+		return err
+	}
+
+	if err := broker.deleteServiceAccount(ctx, binding); err != nil {
+		return err
 	}
 
 	if broker.Credstore != nil {
-		credentialName := getCredentialName(broker.getServiceName(serviceDefinition), bindingID)
+		credentialName := getCredentialName(broker.getServiceName(serviceDefinition), binding.BindingId)
 
 		err = broker.Credstore.DeletePermission(credentialName)
 		if err != nil {
-			broker.Logger.Error(fmt.Sprintf("fail to delete permissions on the key %s", credentialName), err)
+			broker.logger(ctx).Error(fmt.Sprintf("fail to delete permissions on the key %s", credentialName), err)
 		}
 
-		err := broker.Credstore.Delete(credentialName)
-		if err != nil {
-			return  brokerapi.UnbindSpec{}, err
+		if err := broker.Credstore.Delete(credentialName); err != nil {
+			return err
 		}
 	}
 
-	// remove binding from database
-	if err := db_service.DeleteServiceBindingCredentials(ctx, existingBinding); err != nil {
-		return brokerapi.UnbindSpec{}, fmt.Errorf("Error soft-deleting credentials from database: %s. WARNING: these credentials will remain visible in cf. Contact your operator for cleanup", err)
+	return nil
+}
+
+// deleteServiceAccount deletes the service account and key the binding
+// created in case the service provider left them behind. Accounts another
+// binding still uses aren't deleted.
+func (broker *ServiceBroker) deleteServiceAccount(ctx context.Context, binding *models.ServiceBindingCredentials) error {
+	if broker.ServiceAccounts == nil || binding.ServiceAccountEmail == "" {
+		return nil
 	}
 
-	return brokerapi.UnbindSpec{}, nil
+	users, err := broker.store.ListServiceBindingCredentials(ctx, models.ServiceBindingCredentials{ServiceAccountEmail: binding.ServiceAccountEmail})
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if user.BindingId != binding.BindingId {
+			broker.logger(ctx).Error("keeping-shared-service-account", fmt.Errorf("service account %s is also used by binding %s", binding.ServiceAccountEmail, user.BindingId))
+			return nil
+		}
+	}
+
+	return broker.ServiceAccounts.Delete(ctx, binding.ServiceAccountEmail, binding.ServiceAccountKeyId)
 }
 
 // LastOperation fetches last operation state for a service instance.
 // It is bound to the `GET /v2/service_instances/:instance_id/last_operation` endpoint.
 // It is called by `cf create-service` or `cf delete-service` if the operation was asynchronous.
 func (broker *ServiceBroker) LastOperation(ctx context.Context, instanceID string, details brokerapi.PollDetails) (brokerapi.LastOperation, error) {
-	broker.Logger.Info("Last Operation", lager.Data{
+	broker.logger(ctx).Info("Last Operation", lager.Data{
 		"instance_id":    instanceID,
 		"plan_id":        details.PlanID,
 		"service_id":     details.ServiceID,
 		"operation_data": details.OperationData,
 	})
 
-	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	instance, err := broker.store.GetServiceInstanceDetailsById(ctx, instanceID)
 	if err != nil {
 		return brokerapi.LastOperation{}, brokerapi.ErrInstanceDoesNotExist
 	}
+	ctx = withInstanceExperiments(ctx, instance)
 
-	_, serviceProvider, err := broker.getDefinitionAndProvider(instance.ServiceId)
+	_, serviceProvider, err := broker.getDefinitionAndProvider(ctx, instance.ServiceId)
 	if err != nil {
 		return brokerapi.LastOperation{}, err
 	}
@@ -506,6 +845,10 @@ func (broker *ServiceBroker) LastOperation(ctx context.Context, instanceID strin
 		}
 
 		// This is not a retryable error. Return fail
+		failure.Record(ctx, failure.Classify(err), instanceUsable(lastOperationType))
+		if lastOperationType == models.ProvisionOperationType {
+			broker.publishEvent(events.ProvisionFailed, *instance, "", err)
+		}
 		return brokerapi.LastOperation{State: brokerapi.Failed, Description: err.Error()}, nil
 	}
 
@@ -516,23 +859,50 @@ func (broker *ServiceBroker) LastOperation(ctx context.Context, instanceID strin
 	// the instance may have been invalidated, so we pass its primary key rather than the
 	// instance directly.
 	updateErr := broker.updateStateOnOperationCompletion(ctx, serviceProvider, lastOperationType, instanceID)
+	if updateErr == nil {
+		switch lastOperationType {
+		case models.ProvisionOperationType:
+			broker.publishEvent(events.InstanceProvisioned, *instance, "", nil)
+		case models.DeprovisionOperationType:
+			broker.publishEvent(events.Deprovisioned, *instance, "", nil)
+		}
+	}
 	return brokerapi.LastOperation{State: brokerapi.Succeeded, Description: message}, updateErr
 }
 
+// instanceUsable reports whether an instance can still be used after its
+// operation of the given type failed, or nil if the OSB spec doesn't define
+// it for the type. A failed update leaves the instance's resources in place,
+// a failed deprovision may have deleted some of them.
+func instanceUsable(operationType string) *bool {
+	var usable bool
+	switch operationType {
+	case models.UpdateOperationType:
+		usable = true
+	case models.DeprovisionOperationType:
+		usable = false
+	default:
+		return nil
+	}
+
+	return &usable
+}
+
 // updateStateOnOperationCompletion handles updating/cleaning-up resources that need to be changed
 // once lastOperation finishes successfully.
 func (broker *ServiceBroker) updateStateOnOperationCompletion(ctx context.Context, service broker.ServiceProvider, lastOperationType, instanceID string) error {
 	if lastOperationType == models.DeprovisionOperationType {
-		if err := db_service.DeleteServiceInstanceDetailsById(ctx, instanceID); err != nil {
+		if err := broker.store.DeleteServiceInstanceDetailsAndBindingsById(ctx, instanceID); err != nil {
 			return fmt.Errorf("Error deleting instance details from database: %s. WARNING: this instance will remain visible in cf. Contact your operator for cleanup", err)
 		}
+		broker.deleteInstanceShares(ctx, instanceID)
 
 		return nil
 	}
 
 	// If the operation was not a delete, clear out the ID and type and update
 	// any changed (or finalized) state like IP addresses, selflinks, etc.
-	details, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	details, err := broker.store.GetServiceInstanceDetailsById(ctx, instanceID)
 	if err != nil {
 		return fmt.Errorf("Error getting instance details from database %v", err)
 	}
@@ -543,7 +913,7 @@ func (broker *ServiceBroker) updateStateOnOperationCompletion(ctx context.Contex
 
 	details.OperationId = ""
 	details.OperationType = models.ClearOperationType
-	if err := db_service.SaveServiceInstanceDetails(ctx, details); err != nil {
+	if err := broker.store.SaveServiceInstanceDetails(ctx, details); err != nil {
 		return fmt.Errorf("Error saving instance details to database %v", err)
 	}
 
@@ -553,19 +923,41 @@ func (broker *ServiceBroker) updateStateOnOperationCompletion(ctx context.Contex
 // Update a service instance plan.
 // This functionality is not implemented and will return an error indicating that plan changes are not supported.
 func (broker *ServiceBroker) Update(ctx context.Context, instanceID string, details brokerapi.UpdateDetails, asyncAllowed bool) (response brokerapi.UpdateServiceSpec, err error) {
-	broker.Logger.Info("Updating", lager.Data{
+	broker.logger(ctx).Info("Updating", lager.Data{
 		"instance_id":        instanceID,
 		"accepts_incomplete": asyncAllowed,
 		"details":            details,
 	})
 
+	unlock, err := broker.changing.lock(instanceID)
+	if err != nil {
+		return response, err
+	}
+	defer unlock()
+
 	// make sure that instance actually exists
-	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	instance, err := broker.store.GetServiceInstanceDetailsById(ctx, instanceID)
 	if err != nil {
 		return response, brokerapi.ErrInstanceDoesNotExist
 	}
+	ctx = withInstanceExperiments(ctx, instance)
+
+	// a failed update leaves the instance usable, repeating it can only
+	// succeed if it wasn't rejected as invalid
+	defer func() {
+		if err != nil {
+			osberror.Record(ctx, osberror.Details{
+				InstanceUsable:   osberror.Bool(true),
+				UpdateRepeatable: osberror.Bool(!isRejected(err)),
+			})
+		}
+	}()
+
+	if err := broker.checkNoPendingJobs(ctx, instanceID); err != nil {
+		return response, err
+	}
 
-	brokerService, serviceHelper, err := broker.getDefinitionAndProvider(instance.ServiceId)
+	brokerService, serviceHelper, err := broker.getDefinitionAndProvider(ctx, instance.ServiceId)
 	if err != nil {
 		return response, err
 	}
@@ -587,6 +979,10 @@ func (broker *ServiceBroker) Update(ctx context.Context, instanceID string, deta
 		return response, ErrInvalidUserInput
 	}
 
+	if err := brokerService.CheckPinnedParameters(details.GetRawParameters()); err != nil {
+		return response, parameterPinnedError(err)
+	}
+
 	allowUpdate, err := brokerService.AllowedUpdate(details); 
 
 	if err != nil {
@@ -597,14 +993,59 @@ func (broker *ServiceBroker) Update(ctx context.Context, instanceID string, deta
 		return response, ErrNonUpdatableParameter
 	}
 
-	pr, err := db_service.GetProvisionRequestDetailsByInstanceId(ctx, instanceID)
+	pr, err := broker.store.GetProvisionRequestDetailsByInstanceId(ctx, instanceID)
 	if err != nil {
 		return response, fmt.Errorf("updating non-existent instanceid: %v", instanceID)
 	}
 	
+	// upgrades to a new maintenance_info version are subject to the service's
+	// upgrade policy
+	upgradeVersion, approvedUpgrade, err := broker.checkUpgrade(ctx, brokerService, instance, details.MaintenanceInfo)
+	if err != nil {
+		return response, err
+	}
+
+	// platforms that don't send the context with updates keep the names from
+	// the last one they sent
+	requestContext := platformcontext.Parse(details.RawContext)
+	if requestContext.IsEmpty() {
+		details.RawContext = json.RawMessage(instance.Context)
+	}
+
+	// and the organization and space the instance was provisioned in, so
+	// neither its labels nor its default project are blanked
+	if details.PreviousValues.OrgID == "" {
+		details.PreviousValues.OrgID = instance.OrganizationGuid
+	}
+	if details.PreviousValues.SpaceID == "" {
+		details.PreviousValues.SpaceID = instance.SpaceGuid
+	}
+
 	// validate parameters meet the service's schema and merge the user vars with
 	// the plan's
-	vars, err := brokerService.UpdateVariables(instanceID, details, json.RawMessage(pr.RequestDetails), *plan)
+	provisionParameters, err := requestdetails.Restore(json.RawMessage(pr.RequestDetails))
+	if err != nil {
+		return response, err
+	}
+
+	vars, err := brokerService.UpdateVariables(instanceID, details, provisionParameters, *plan)
+	if err != nil {
+		return response, err
+	}
+
+	if err := planConstraintError(brokerService.CheckPlanConstraints(plan, vars.ToMap())); err != nil {
+		return response, err
+	}
+
+	// services mark the project as not updatable, this makes sure the update
+	// can't be applied to a project the operator doesn't allow regardless
+	if vars.HasKey("project") {
+		if err := projects.Validate(vars.GetString("project")); err != nil {
+			return response, osberror.New(err, http.StatusBadRequest, "project-not-permitted", osberror.ProjectNotPermitted)
+		}
+	}
+
+	kmsKeyName, err := validKmsKey(vars)
 	if err != nil {
 		return response, err
 	}
@@ -618,18 +1059,44 @@ func (broker *ServiceBroker) Update(ctx context.Context, instanceID string, deta
 	// save instance details
 
 	instance.PlanId = newInstanceDetails.PlanId
+	if kmsKeyName != "" {
+		instance.KmsKeyName = kmsKeyName
+	}
+	if upgradeVersion != "" {
+		instance.MaintenanceVersion = upgradeVersion
+	}
+	instance.Experiments = strings.Join(experiments.FromContext(ctx), ",")
+	recordedLabels, err := instance.GetLabels()
+	if err != nil {
+		return brokerapi.UpdateServiceSpec{}, fmt.Errorf("Error deserializing instance labels: %s", err)
+	}
+	if err := instance.SetLabels(utils.MergeLabels(recordedLabels, utils.ExtractDefaultUpdateLabels(instanceID, details))); err != nil {
+		return brokerapi.UpdateServiceSpec{}, fmt.Errorf("Error serializing instance labels: %s", err)
+	}
+	if !requestContext.IsEmpty() {
+		if err := instance.SetPlatformContext(requestContext); err != nil {
+			return brokerapi.UpdateServiceSpec{}, fmt.Errorf("Error serializing instance context: %s", err)
+		}
+	}
 
-	err = db_service.SaveServiceInstanceDetails(ctx, instance)
+	err = broker.store.SaveServiceInstanceDetails(ctx, instance)
 	if err != nil {
 		return brokerapi.UpdateServiceSpec{}, fmt.Errorf("Error saving instance details to database: %s. WARNING: this instance cannot be deprovisioned through cf. Contact your operator for cleanup", err)
 	}
 
+	if approvedUpgrade != nil {
+		approvedUpgrade.State = upgrade.StateCompleted
+		if err := broker.store.SaveInstanceUpgrade(ctx, approvedUpgrade); err != nil {
+			return brokerapi.UpdateServiceSpec{}, fmt.Errorf("Error saving instance upgrade to database: %s", err)
+		}
+	}
+
 	// save provision request details
 	// pr := models.ProvisionRequestDetails{
 	// 	ServiceInstanceId: instanceID,
 	// 	RequestDetails:    string(details.RawParameters),
 	// }
-	// if err = db_service.SaveProvisionRequestDetails(ctx, &pr); err != nil {
+	// if err = broker.store.SaveProvisionRequestDetails(ctx, &pr); err != nil {
 	// 	return brokerapi.UpdateServiceSpec{}, fmt.Errorf("Error saving provision request details to database: %s. Services relying on async provisioning will not be able to complete provisioning", err)
 	// }
 
@@ -643,3 +1110,58 @@ func (broker *ServiceBroker) Update(ctx context.Context, instanceID string, deta
 func isValidOrEmptyJSON(msg json.RawMessage) bool {
 	return msg == nil || len(msg) == 0 || json.Valid(msg)
 }
+
+// sharedWith returns the organization and space of the binding's consumer if
+// the instance is shared with it, both are empty if the consumer is in the
+// instance's space. It returns an error if the instance is bound from another
+// space but the service doesn't allow sharing.
+func sharedWith(def *broker.ServiceDefinition, instance *models.ServiceInstanceDetails, details brokerapi.BindDetails) (string, string, error) {
+	org, space := broker.BindConsumer(details)
+	if !broker.IsSharedBinding(instance.SpaceGuid, space) {
+		return "", "", nil
+	}
+
+	if !def.Shareable {
+		err := fmt.Errorf("instances of %s can't be shared with other spaces", def.Name)
+		return "", "", osberror.New(err, http.StatusUnprocessableEntity, "instance-not-shareable", osberror.InstanceNotShareable)
+	}
+
+	return org, space, nil
+}
+
+// deleteInstanceShares forgets the spaces a deprovisioned instance was shared
+// with. Failures are logged because the instance is already gone.
+func (broker *ServiceBroker) deleteInstanceShares(ctx context.Context, instanceID string) {
+	if err := broker.store.DeleteInstanceShares(ctx, instanceID); err != nil {
+		broker.logger(ctx).Error("deleting instance shares", err, lager.Data{"instance_id": instanceID})
+	}
+}
+
+// validKmsKey returns the customer-managed encryption key the user or plan
+// chose for the instance, or a bad request error if the operator doesn't allow
+// it.
+func validKmsKey(vars *varcontext.VarContext) (string, error) {
+	if !vars.HasKey(cmek.KeyParameter) {
+		return "", nil
+	}
+
+	key := vars.GetString(cmek.KeyParameter)
+	if err := cmek.Validate(key); err != nil {
+		return "", osberror.New(err, http.StatusBadRequest, "kms-key-not-permitted", osberror.KmsKeyNotPermitted)
+	}
+
+	return key, nil
+}
+
+// withInstanceExperiments enables the experiments recorded with the instance
+// for the rest of the request so every operation on it behaves the same way.
+func withInstanceExperiments(ctx context.Context, instance *models.ServiceInstanceDetails) context.Context {
+	return experiments.WithEnabled(ctx, experiments.Parse(instance.Experiments)...)
+}
+
+// isRejected returns true if the error is a failure response for a request
+// the broker considers invalid rather than one it failed to handle.
+func isRejected(err error) bool {
+	failure, ok := err.(*brokerapi.FailureResponse)
+	return ok && failure.ValidatedStatusCode(nil) < http.StatusInternalServerError
+}
@@ -0,0 +1,255 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/osberror"
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+	"github.com/pivotal/cloud-service-broker/pkg/upgrade"
+)
+
+var _ upgrade.Manager = (*ServiceBroker)(nil)
+
+// ListUpgrades lists the instances that are behind their service's
+// maintenance_info version along with the policy that applies to them.
+func (broker *ServiceBroker) ListUpgrades(ctx context.Context) ([]upgrade.Instance, error) {
+	out := []upgrade.Instance{}
+	for _, svc := range broker.registry.GetAllServices() {
+		target := svc.MaintenanceVersion()
+		if target == "" {
+			continue
+		}
+
+		policy, err := svc.UpgradePolicy()
+		if err != nil {
+			return nil, err
+		}
+
+		instances, err := broker.store.ListServiceInstanceDetails(ctx, models.ServiceInstanceDetails{ServiceId: svc.Id})
+		if err != nil {
+			return nil, fmt.Errorf("Error listing instances: %s", err)
+		}
+
+		for _, instance := range instances {
+			if instance.MaintenanceVersion == target {
+				continue
+			}
+
+			result := upgrade.Instance{
+				InstanceId:       instance.ID,
+				ServiceId:        svc.Id,
+				ServiceName:      svc.Name,
+				PlanId:           instance.PlanId,
+				Policy:           policy,
+				CurrentVersion:   instance.MaintenanceVersion,
+				AvailableVersion: target,
+			}
+
+			record, err := broker.store.GetInstanceUpgrade(ctx, instance.ID, target)
+			if err != nil {
+				return nil, fmt.Errorf("Error retrieving instance upgrade: %s", err)
+			}
+			if record != nil {
+				result.State = record.State
+				result.ApprovedAt = record.ApprovedAt
+			}
+
+			out = append(out, result)
+		}
+	}
+
+	return out, nil
+}
+
+// ApproveUpgrades approves upgrading the outdated instances matching the
+// approval so the platform can upgrade them. Instances whose service doesn't
+// use the manual policy, or that aren't outdated, are reported as skipped. If
+// the context is a dry run the instances are only reported.
+func (broker *ServiceBroker) ApproveUpgrades(ctx context.Context, approval upgrade.Approval) (*upgrade.Report, error) {
+	broker.logger(ctx).Info("ApproveUpgrades", lager.Data{"approval": approval})
+
+	if approval.IsEmpty() {
+		return nil, upgrade.ErrEmptyApproval
+	}
+
+	serviceId := ""
+	if approval.Service != "" {
+		svc, err := broker.serviceByNameOrId(approval.Service)
+		if err != nil {
+			return nil, err
+		}
+		serviceId = svc.Id
+	}
+
+	requested := make(map[string]bool)
+	for _, id := range approval.InstanceIds {
+		requested[id] = false
+	}
+
+	outdated, err := broker.ListUpgrades(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &upgrade.Report{Approved: []upgrade.Instance{}, Skipped: []upgrade.Instance{}}
+	changes := &planning.Plan{}
+	var planned []*upgrade.Instance
+	for i := range outdated {
+		result := &outdated[i]
+		if serviceId != "" && result.ServiceId != serviceId {
+			continue
+		}
+
+		if _, ok := requested[result.InstanceId]; len(requested) > 0 && !ok {
+			continue
+		}
+		requested[result.InstanceId] = true
+
+		if result.Policy != upgrade.PolicyManual {
+			result.Error = fmt.Sprintf("service %q uses the %s upgrade policy", result.ServiceName, result.Policy)
+			report.Skipped = append(report.Skipped, *result)
+			continue
+		}
+
+		details := map[string]interface{}{
+			"service_id":   result.ServiceId,
+			"plan_id":      result.PlanId,
+			"from_version": result.CurrentVersion,
+			"to_version":   result.AvailableVersion,
+		}
+		changes.Add("approve-upgrade", result.InstanceId, details, func(ctx context.Context) error {
+			return broker.approveUpgrade(ctx, result)
+		})
+		planned = append(planned, result)
+	}
+
+	executed := changes.Execute(ctx)
+	report.DryRun = executed.DryRun
+	for i, outcome := range executed.Outcomes {
+		result := *planned[i]
+		switch {
+		case outcome.Error != "":
+			result.Error = outcome.Error
+			report.Skipped = append(report.Skipped, result)
+		case outcome.Applied:
+			report.Approved = append(report.Approved, result)
+		default:
+			report.Planned = append(report.Planned, result)
+		}
+	}
+
+	for _, id := range approval.InstanceIds {
+		if !requested[id] {
+			report.Skipped = append(report.Skipped, upgrade.Instance{InstanceId: id, Error: "no upgrade is available for the instance"})
+		}
+	}
+
+	return report, nil
+}
+
+func (broker *ServiceBroker) approveUpgrade(ctx context.Context, result *upgrade.Instance) error {
+	record, err := broker.store.GetInstanceUpgrade(ctx, result.InstanceId, result.AvailableVersion)
+	if err != nil {
+		return fmt.Errorf("Error retrieving instance upgrade: %s", err)
+	}
+
+	if record == nil {
+		record = &models.InstanceUpgrade{
+			ServiceInstanceId: result.InstanceId,
+			ServiceId:         result.ServiceId,
+			FromVersion:       result.CurrentVersion,
+			ToVersion:         result.AvailableVersion,
+		}
+	}
+
+	if record.State != upgrade.StateApproved {
+		now := time.Now()
+		record.State = upgrade.StateApproved
+		record.ApprovedAt = &now
+		if err := broker.store.SaveInstanceUpgrade(ctx, record); err != nil {
+			return fmt.Errorf("Error saving instance upgrade: %s", err)
+		}
+	}
+
+	result.State = record.State
+	result.ApprovedAt = record.ApprovedAt
+	return nil
+}
+
+// checkUpgrade returns the maintenance_info version an update moves the
+// instance to, or an empty string if the update isn't an upgrade, once the
+// service's upgrade policy allows it. Under the manual policy the approved
+// upgrade record is also returned so it can be completed.
+func (broker *ServiceBroker) checkUpgrade(ctx context.Context, svc *broker.ServiceDefinition, instance *models.ServiceInstanceDetails, requested brokerapi.MaintenanceInfo) (string, *models.InstanceUpgrade, error) {
+	if err := svc.ValidateMaintenanceInfo(requested); err != nil {
+		return "", nil, err
+	}
+
+	target := svc.MaintenanceVersion()
+	if (len(requested.Public) == 0 && requested.Private == "") || instance.MaintenanceVersion == target {
+		return "", nil, nil
+	}
+
+	policy, err := svc.UpgradePolicy()
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch policy {
+	case upgrade.PolicyPinned:
+		err := fmt.Errorf("instances of %s are pinned to their current version by the operator", svc.Name)
+		return "", nil, osberror.New(err, http.StatusUnprocessableEntity, "upgrade-pinned", osberror.UpgradePinned)
+
+	case upgrade.PolicyManual:
+		record, err := broker.store.GetInstanceUpgrade(ctx, instance.ID, target)
+		if err != nil {
+			return "", nil, fmt.Errorf("Error retrieving instance upgrade: %s", err)
+		}
+
+		// record the request so operators can see which upgrades are waiting
+		// for approval
+		if record == nil {
+			record = &models.InstanceUpgrade{
+				ServiceInstanceId: instance.ID,
+				ServiceId:         svc.Id,
+				FromVersion:       instance.MaintenanceVersion,
+				ToVersion:         target,
+				State:             upgrade.StatePending,
+			}
+			if err := broker.store.SaveInstanceUpgrade(ctx, record); err != nil {
+				return "", nil, fmt.Errorf("Error saving instance upgrade: %s", err)
+			}
+		}
+
+		if record.State != upgrade.StateApproved {
+			err := fmt.Errorf("upgrading the instance to version %s must be approved by the operator", target)
+			return "", nil, osberror.New(err, http.StatusUnprocessableEntity, "upgrade-not-approved", osberror.UpgradeNotApproved)
+		}
+
+		return target, record, nil
+
+	default:
+		return target, nil, nil
+	}
+}
@@ -0,0 +1,12 @@
+#!/bin/sh
+
+set -e
+
+echo "Starting the Cloud Storage emulator"
+# outside the broker module so its go.mod is left alone
+(cd /tmp && GO111MODULE=on go get github.com/fsouza/fake-gcs-server@v1.19.0)
+fake-gcs-server -scheme http -port 4443 &
+sleep 5
+
+export STORAGE_EMULATOR_HOST=localhost:4443
+make test-emulators
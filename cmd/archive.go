@@ -0,0 +1,102 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/archive"
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	archiveCmd := &cobra.Command{
+		Use:   "archive",
+		Short: "Move old operation history to blob storage",
+		Long: `Moves cloud operations and completed instance upgrades that haven't been
+updated within archive.max_age to gzipped JSON lines objects in the
+archive.bucket Cloud Storage bucket, then deletes them from the database.
+
+Each object is recorded in the archives table with the table the rows came
+from, their IDs and update times so history can be retrieved later.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var dryRun bool
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Archive rows older than archive.max_age",
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := utils.NewLogger("archive")
+			db_service.New(logger)
+
+			archiver, err := archive.NewArchiverFromEnv(context.Background(), logger)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if archiver == nil {
+				log.Fatalf("set %s to enable archiving", archive.BucketProp)
+			}
+
+			if dryRun {
+				ctx := planning.WithDryRun(context.Background())
+				changes, err := archiver.Plan(ctx)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				utils.PrettyPrintOrExit(changes.Execute(ctx))
+				return
+			}
+
+			created, err := archiver.Run(context.Background())
+			for _, a := range created {
+				fmt.Printf("Archived %d rows from %s to %s\n", a.Records, a.SourceTable, a.Location)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, "report how many rows of each table would be archived without archiving them")
+	archiveCmd.AddCommand(runCmd)
+
+	var table string
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the archives recorded in the database",
+		Run: func(cmd *cobra.Command, args []string) {
+			db_service.New(utils.NewLogger("archive"))
+
+			archives, err := db_service.ListArchives(context.Background(), table)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			utils.PrettyPrintOrExit(archives)
+		},
+	}
+	listCmd.Flags().StringVar(&table, "table", "", "only list archives of the given table")
+	archiveCmd.AddCommand(listCmd)
+
+	rootCmd.AddCommand(archiveCmd)
+}
@@ -15,12 +15,15 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"log"
+	"os"
 
 	"github.com/spf13/cobra"
 
 	"github.com/pivotal/cloud-service-broker/pkg/client"
+	"github.com/pivotal/cloud-service-broker/pkg/conformance"
 	"github.com/pivotal/cloud-service-broker/pkg/server"
 	"github.com/pivotal/cloud-service-broker/utils"
 )
@@ -113,13 +116,26 @@ user-defined plans.
 	})
 
 	runExamplesCmd := &cobra.Command{
-		Use:   "run-examples",
+		Use:   "run-examples [service]",
 		Short: "Run all examples in the use command.",
-		Long: `Run all examples generated by the use command through a
-	provision/bind/unbind/deprovision cycle.
+		Long: `Run all examples generated by the use command, or those of the given
+	service, through a provision/bind/verify/unbind/deprovision cycle.
 
-	Exits with a 0 if all examples were successful, 1 otherwise.`,
+	The verify stage checks the binding's credentials match the service's bind
+	outputs and, for services with a credential check, that they work, e.g. by
+	writing an object to a bucket. Without a check it's reported as skipped.
+
+	Prints a table of the result of each stage of each example. Exits with a 0
+	if all examples were successful, 1 otherwise.`,
+		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 1 {
+				if serviceName != "" && serviceName != args[0] {
+					log.Fatalf("The service %q and --service-name %q don't match.", args[0], serviceName)
+				}
+				serviceName = args[0]
+			}
+
 			apiClient, err := client.NewClientFromEnv()
 			if err != nil {
 				log.Fatalf("Error creating client: %v", err)
@@ -139,7 +155,39 @@ user-defined plans.
 		},
 	}
 
-	clientCmd.AddCommand(clientCatalogCmd, provisionCmd, deprovisionCmd, bindCmd, unbindCmd, lastCmd, runExamplesCmd, updateCmd)
+	conformanceCmd := &cobra.Command{
+		Use:   "conformance",
+		Short: "Check the broker follows the Open Service Broker API.",
+		Long: `Check the broker follows the Open Service Broker API using the given plan.
+
+	The checks fetch the catalog, provision, bind, unbind and deprovision an
+	instance of the plan, polling asynchronous operations, and send requests
+	the broker must reject. Use a plan that's cheap to provision.
+
+	Prints the result of every check and exits with a 0 if none failed, 1
+	otherwise.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			apiClient, err := client.NewClientFromEnv()
+			if err != nil {
+				log.Fatalf("Error creating client: %v", err)
+			}
+
+			suite := &conformance.Suite{
+				Client:    apiClient,
+				ServiceId: serviceId,
+				PlanId:    planId,
+				Params:    json.RawMessage(parametersJson),
+			}
+
+			report := suite.Run(context.Background())
+			utils.PrettyPrintOrExit(report)
+			if !report.Passed {
+				os.Exit(1)
+			}
+		},
+	}
+
+	clientCmd.AddCommand(clientCatalogCmd, provisionCmd, deprovisionCmd, bindCmd, unbindCmd, lastCmd, runExamplesCmd, updateCmd, conformanceCmd)
 
 	bindFlag := func(dest *string, name, description string, commands ...*cobra.Command) {
 		for _, sc := range commands {
@@ -149,11 +197,11 @@ user-defined plans.
 	}
 
 	bindFlag(&instanceId, "instanceid", "id of the service instance to operate on (user defined)", provisionCmd, deprovisionCmd, bindCmd, unbindCmd, lastCmd, updateCmd)
-	bindFlag(&serviceId, "serviceid", "GUID of the service instanceid references (see catalog)", provisionCmd, deprovisionCmd, bindCmd, unbindCmd, updateCmd)
-	bindFlag(&planId, "planid", "GUID of the service instanceid references (see catalog entry for the associated serviceid)", provisionCmd, deprovisionCmd, bindCmd, unbindCmd, updateCmd)
+	bindFlag(&serviceId, "serviceid", "GUID of the service instanceid references (see catalog)", provisionCmd, deprovisionCmd, bindCmd, unbindCmd, updateCmd, conformanceCmd)
+	bindFlag(&planId, "planid", "GUID of the service instanceid references (see catalog entry for the associated serviceid)", provisionCmd, deprovisionCmd, bindCmd, unbindCmd, updateCmd, conformanceCmd)
 	bindFlag(&bindingId, "bindingid", "GUID of the binding to work on (user defined)", bindCmd, unbindCmd)
 
-	for _, sc := range []*cobra.Command{provisionCmd, bindCmd, updateCmd} {
+	for _, sc := range []*cobra.Command{provisionCmd, bindCmd, updateCmd, conformanceCmd} {
 		sc.Flags().StringVarP(&parametersJson, "params", "", "{}", "JSON string of user-defined parameters to pass to the request")
 	}
 
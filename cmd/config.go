@@ -16,9 +16,14 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
+	"strings"
 
+	"github.com/pivotal/cloud-service-broker/pkg/config"
 	"github.com/pivotal/cloud-service-broker/pkg/config/migration"
+	"github.com/pivotal/cloud-service-broker/pkg/config/secrets"
 	"github.com/pivotal/cloud-service-broker/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -63,9 +68,9 @@ You can show the known coonfiguration values using:
 	configCmd.AddCommand(&cobra.Command{
 		Use:   "show",
 		Short: "Show the config",
-		Long:  `Show the current configuration settings.`,
+		Long:  `Show the current configuration settings with sensitive values redacted, like show-config.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			utils.PrettyPrintOrExit(viper.AllSettings())
+			utils.PrettyPrintOrExit(config.Effective())
 		},
 	})
 
@@ -99,6 +104,76 @@ out.toml:
 		},
 	})
 
+	var encryptScheme string
+	encryptCmd := &cobra.Command{
+		Use:   "encrypt [value]",
+		Short: "Encrypt a sensitive configuration value",
+		Long: `Encrypt a sensitive configuration value so it can be safely stored in a
+deployment manifest or configuration file. The broker decrypts values in the
+output format automatically when it starts.
+
+If no value is given, or the value is "-", it's read from stdin so it doesn't
+end up in your shell history.
+
+Schemes:
+
+ * passphrase - AES-256-GCM with a key derived from the passphrase in
+   GSB_CONFIG_ENCRYPTION_PASSPHRASE (config.encryption.passphrase).
+ * kms - Google Cloud KMS using the crypto key in GSB_CONFIG_ENCRYPTION_KMS_KEY
+   (config.encryption.kms_key) and the broker's root service account.
+
+Example:
+
+  GSB_CONFIG_ENCRYPTION_PASSPHRASE=secret cloud-service-broker config encrypt my-db-password
+
+  enc:passphrase:...
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value, err := valueFromArgsOrStdin(args)
+			if err != nil {
+				return err
+			}
+
+			encrypted, err := secrets.Encrypt(encryptScheme, value)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(encrypted)
+			return nil
+		},
+	}
+	encryptCmd.Flags().StringVar(&encryptScheme, "scheme", secrets.PassphraseScheme, "encryption scheme to use, passphrase or kms")
+	configCmd.AddCommand(encryptCmd)
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "decrypt [value]",
+		Short: "Decrypt a configuration value created with config encrypt",
+		Long: `Decrypt a configuration value created with config encrypt using the same
+passphrase or KMS key. If no value is given, or the value is "-", it's read from
+stdin.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value, err := valueFromArgsOrStdin(args)
+			if err != nil {
+				return err
+			}
+
+			if !secrets.IsEncrypted(value) {
+				return fmt.Errorf("value isn't encrypted, expected it to start with %q", secrets.Prefix)
+			}
+
+			decrypted, err := secrets.Decrypt(value)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(decrypted)
+			return nil
+		},
+	})
+
 	configCmd.AddCommand(&cobra.Command{
 		Use:   "migrate-env",
 		Short: "Run migrations on environment variables and print the changes.",
@@ -120,3 +195,16 @@ The original environment variables will not be changed.
 		},
 	})
 }
+
+func valueFromArgsOrStdin(args []string) (string, error) {
+	if len(args) == 1 && args[0] != "-" {
+		return args[0], nil
+	}
+
+	in, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("couldn't read value from stdin: %v", err)
+	}
+
+	return strings.TrimRight(string(in), "\r\n"), nil
+}
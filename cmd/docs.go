@@ -0,0 +1,82 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/brokerpak"
+	"github.com/pivotal/cloud-service-broker/pkg/generator"
+	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin"
+	"github.com/pivotal/cloud-service-broker/pkg/server"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	var format, outputDir string
+
+	docsCmd := &cobra.Command{
+		Use:   "docs",
+		Short: "Generate the documentation of the services and plans",
+		Long: `Generate the user documentation of every service the broker offers with the
+current configuration, including the brokerpaks it loads.
+
+The documentation covers the plans, the provision and bind parameters and
+their JSON schemas, the IAM roles the broker needs and bindings can be
+granted, and example cf and kubectl commands. It's generated from the same
+definitions as the catalog so it can't go stale.
+
+By default a single Markdown document is printed, use --format html to print
+the page served at /docs, or --output-dir to write a Markdown file per
+service along with an index.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			// keep stdout for the documentation, errors are still logged
+			// to stderr
+			viper.Set(utils.LogLevelProp, lager.FATAL.String())
+			logger := utils.NewLogger("docs")
+
+			registry := broker.BrokerRegistry{}
+			builtin.RegisterBuiltinBrokers(registry)
+			if err := brokerpak.RegisterAll(registry); err != nil {
+				logger.Error("loading brokerpaks", err)
+			}
+
+			switch {
+			case outputDir != "":
+				generator.CatalogDocumentationToDir(registry, outputDir)
+			case format == "markdown":
+				fmt.Println(generator.CatalogDocumentation(registry))
+			case format == "html":
+				page, err := server.RenderDocsPage("Service Broker Documents", generator.CatalogDocumentation(registry))
+				if err != nil {
+					log.Fatalf("Error rendering the documentation: %v", err)
+				}
+				fmt.Print(string(page))
+			default:
+				log.Fatalf("Unknown format %q, must be markdown or html", format)
+			}
+		},
+	}
+
+	docsCmd.Flags().StringVar(&format, "format", "markdown", "format of the documentation, markdown or html")
+	docsCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "write a Markdown file per service and an index to this directory instead")
+	rootCmd.AddCommand(docsCmd)
+}
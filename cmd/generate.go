@@ -16,9 +16,17 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
+	"log"
 
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/brokerpak"
 	"github.com/pivotal/cloud-service-broker/pkg/generator"
+	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin"
+	"github.com/pivotal/cloud-service-broker/utils"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func init() {
@@ -40,19 +48,106 @@ func init() {
 		},
 	})
 
-	generateCmd.AddCommand(&cobra.Command{
+	var tileCheck string
+	tileCmd := &cobra.Command{
 		Use:   "tile",
 		Short: "Generate tile.yml file",
+		Long: `Generate the tile.yml file for a PCF tile.
+
+The forms are derived from the broker's registered configuration properties,
+feature flags and services. Use --check to verify a committed tile.yml is up to
+date instead of printing it.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Print(generator.GenerateTile())
+			printOrCheck(generator.GenerateTile(), tileCheck, "tile")
 		},
-	})
+	}
+	tileCmd.Flags().StringVar(&tileCheck, "check", "", "exit with an error if the given file differs from the generated one")
+	generateCmd.AddCommand(tileCmd)
 
-	generateCmd.AddCommand(&cobra.Command{
+	var manifestCheck string
+	manifestCmd := &cobra.Command{
 		Use:   "manifest",
 		Short: "Generate manifest.yml file",
+		Long: `Generate the manifest.yml file to push the broker to Cloud Foundry.
+
+The environment lists the broker's registered configuration properties with
+their defaults. Use --check to verify a committed manifest.yml is up to date
+instead of printing it.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println(generator.GenerateManifest())
+			printOrCheck(generator.GenerateManifest()+"\n", manifestCheck, "manifest")
 		},
-	})
+	}
+	manifestCmd.Flags().StringVar(&manifestCheck, "check", "", "exit with an error if the given file differs from the generated one")
+	generateCmd.AddCommand(manifestCmd)
+
+	k8sOpts := generator.DefaultKubernetesOptions()
+	k8sCmd := &cobra.Command{
+		Use:     "kubernetes",
+		Aliases: []string{"k8s"},
+		Short:   "Generate a Kubernetes manifest",
+		Long: `Generate a Kubernetes manifest with a Secret, Deployment and Service to run
+the broker, e.g. on GKE.
+
+The Deployment's readiness probe uses the /ready endpoint and its liveness
+probe the /live endpoint. The Secret and the container environment list the
+broker's registered configuration properties with their defaults.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Print(generator.GenerateKubernetes(k8sOpts))
+		},
+	}
+	k8sCmd.Flags().StringVar(&k8sOpts.Name, "name", k8sOpts.Name, "name of the Secret, Deployment and Service")
+	k8sCmd.Flags().StringVar(&k8sOpts.Namespace, "namespace", "", "namespace of the resources, kubectl's namespace is used if empty")
+	k8sCmd.Flags().StringVar(&k8sOpts.Image, "image", k8sOpts.Image, "container image of the broker")
+	k8sCmd.Flags().IntVar(&k8sOpts.Replicas, "replicas", k8sOpts.Replicas, "number of broker replicas")
+	generateCmd.AddCommand(k8sCmd)
+
+	svcatOpts := generator.DefaultServiceCatalogOptions()
+	svcatCmd := &cobra.Command{
+		Use:     "service-catalog",
+		Aliases: []string{"svcat"},
+		Short:   "Generate Kubernetes Service Catalog resources",
+		Long: `Generate Kubernetes resources to get started with the broker on the
+Kubernetes Service Catalog: a ClusterServiceBroker that registers the broker
+deployed with "generate kubernetes", and a ServiceInstance and ServiceBinding
+for every plan using the parameters of the plan's example.
+
+Run the broker with compatibility.kubernetes_service_catalog set so the
+catalog's plans have the schemas the Service Catalog expects.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			// keep stdout for the resources, errors are still logged to
+			// stderr
+			viper.Set(utils.LogLevelProp, lager.FATAL.String())
+			logger := utils.NewLogger("generate")
+
+			registry := broker.BrokerRegistry{}
+			builtin.RegisterBuiltinBrokers(registry)
+			if err := brokerpak.RegisterAll(registry); err != nil {
+				logger.Error("loading brokerpaks", err)
+			}
+
+			fmt.Print(generator.GenerateServiceCatalog(registry, svcatOpts))
+		},
+	}
+	svcatCmd.Flags().StringVar(&svcatOpts.Name, "name", svcatOpts.Name, "name of the broker's Kubernetes Service and the ClusterServiceBroker")
+	svcatCmd.Flags().StringVar(&svcatOpts.Namespace, "namespace", svcatOpts.Namespace, "namespace the broker runs in")
+	generateCmd.AddCommand(svcatCmd)
+}
+
+// printOrCheck prints the generated file, or if checkFile is set, fails if
+// its contents differ.
+func printOrCheck(generated, checkFile, subcommand string) {
+	if checkFile == "" {
+		fmt.Print(generated)
+		return
+	}
+
+	existing, err := ioutil.ReadFile(checkFile)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", checkFile, err)
+	}
+
+	if string(existing) != generated {
+		log.Fatalf("%s is out of date, regenerate it with: cloud-service-broker generate %s > %s", checkFile, subcommand, checkFile)
+	}
 }
@@ -0,0 +1,94 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/pivotal/cloud-service-broker/brokerapi/brokers"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/imports"
+	"github.com/pivotal/cloud-service-broker/pkg/jobs"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var request imports.Request
+	var parameters string
+
+	importCmd := &cobra.Command{
+		Use:   "import-instance",
+		Short: "Register an existing resource as a service instance",
+		Long: `Registers a resource that already exists, e.g. a Cloud Storage bucket or a
+CloudSQL instance created by hand or by another broker, as an instance the
+broker manages with the given ID. Bindings, updates and deprovisioning then
+work like they do for instances the broker created; deprovisioning deletes the
+resource.
+
+The parameters identify the resource and configure the instance like the
+provision parameters of a new one, e.g. {"name":"my-bucket"} for buckets or
+{"instance_name":"my-db"} for CloudSQL. Only plans with the import capability
+in the catalog can import resources.
+
+Terraform imports are run by the serving brokers, poll the instance's last
+operation or GET /admin/operations to see when it finishes.
+
+The same import can be done on a running broker by POSTing to /admin/imports
+using the admin API's credentials.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if parameters != "" {
+				if !json.Valid([]byte(parameters)) {
+					log.Fatal("--parameters must be valid JSON")
+				}
+				request.Parameters = json.RawMessage(parameters)
+			}
+
+			logger := utils.NewLogger("import-instance")
+			store := db_service.NewSqlDatastore(db_service.New(logger))
+			// the import is run by a broker's workers from the queue
+			jobs.Default.Database = store
+
+			cfg, err := brokers.NewBrokerConfigFromEnv(logger)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			serviceBroker, err := brokers.NewBroker(db_service.NewRetryingDatastore(store), cfg, logger)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			result, err := serviceBroker.ImportInstance(context.Background(), request)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			utils.PrettyPrintOrExit(result)
+		},
+	}
+
+	importCmd.Flags().StringVar(&request.InstanceId, "instance-id", "", "the GUID of the instance to create")
+	importCmd.Flags().StringVar(&request.Service, "service", "", "the name or ID of the service")
+	importCmd.Flags().StringVar(&request.Plan, "plan", "", "the name or ID of the plan")
+	importCmd.Flags().StringVar(&request.OrganizationGuid, "organization-guid", "", "the GUID of the organization that owns the instance")
+	importCmd.Flags().StringVar(&request.SpaceGuid, "space-guid", "", "the GUID of the space that owns the instance")
+	importCmd.Flags().StringVarP(&parameters, "parameters", "c", "", "JSON provision parameters identifying the existing resource")
+
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/orphans"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	orphansCmd := &cobra.Command{
+		Use:   "orphans",
+		Short: "Find cloud resources of instances the broker doesn't manage",
+		Long: `Finds Cloud Storage buckets, CloudSQL instances, Pub/Sub topics and
+subscriptions, and BigQuery datasets in the broker's project that are labeled
+with the ID of a service instance that isn't in the broker's database.
+
+Orphans are only deleted if orphans.delete is true.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var dryRun bool
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Report orphaned resources and delete them if orphans.delete is set",
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := utils.NewLogger("orphans")
+			db_service.New(logger)
+
+			collector, err := orphans.NewCollectorFromEnv(logger)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if dryRun {
+				collector.Delete = false
+			}
+
+			result, err := collector.Run(context.Background())
+			if result != nil {
+				utils.PrettyPrintOrExit(result)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, "report orphaned resources without deleting them even if orphans.delete is set")
+	orphansCmd.AddCommand(runCmd)
+
+	rootCmd.AddCommand(orphansCmd)
+}
@@ -106,9 +106,13 @@ dependencies, services it provides, and the contents.
 
 			if err := brokerpak.Validate(pakPath); err != nil {
 				log.Fatalf("created: %v, but it failed validity checking: %v\n", pakPath, err)
-			} else {
-				fmt.Printf("created: %v\n", pakPath)
 			}
+
+			if err := printLint(pakPath); err != nil {
+				log.Fatalf("created: %v, but it failed linting: %v\n", pakPath, err)
+			}
+
+			fmt.Printf("created: %v\n", pakPath)
 		},
 	})
 
@@ -136,6 +140,26 @@ dependencies, services it provides, and the contents.
 		},
 	})
 
+	pakCmd.AddCommand(&cobra.Command{
+		Use:   "lint [pack.brokerpak]",
+		Short: "check the service definitions of a brokerpak for risky defaults",
+		Long: `Checks the service definitions of a brokerpak for risky defaults like
+publicly readable buckets, primitive IAM roles in bindings, resources
+without deletion protection and numeric inputs without a maximum.
+
+Each rule's severity can be set to error, warning or off with the
+lint.<rule> property, e.g. GSB_LINT_DELETION_PROTECTION=error. Errors
+fail this command, pak build and broker startup.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := printLint(args[0]); err != nil {
+				log.Fatalf("Error: %v\n", err)
+			}
+
+			log.Println("No lint errors")
+		},
+	})
+
 	pakCmd.AddCommand(&cobra.Command{
 		Use:   "run-examples [pack.brokerpak]",
 		Short: "run the examples from a brokerpak",
@@ -190,3 +214,22 @@ dependencies, services it provides, and the contents.
 		},
 	})
 }
+
+// printLint prints the lint findings of the brokerpak and returns an error if
+// any of them are errors.
+func printLint(pakPath string) error {
+	report, err := brokerpak.Lint(pakPath)
+	if err != nil {
+		return err
+	}
+
+	for _, finding := range report.Findings {
+		fmt.Println(finding)
+	}
+
+	if errs := report.Errors(); len(errs) > 0 {
+		return fmt.Errorf("%d lint error(s)", len(errs))
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/pivotal/cloud-service-broker/brokerapi/brokers"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/preview"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var request preview.Request
+	var parameters string
+
+	previewCmd := &cobra.Command{
+		Use:   "preview-provision",
+		Short: "Dry run a provision request without creating anything",
+		Long: `Runs a hypothetical provision request through the checks the broker does
+before creating an instance: the parameter schema, the naming template, quotas,
+and the region, project and encryption key policies. If they pass, the
+resources the service's template would create are listed. Nothing is saved and
+the cloud isn't called.
+
+The result lists the outcome of each check, stopping at the first failure, and
+the command exits with status 1 if the request would be rejected. The instance
+ID is optional, if it's given the preview also checks it isn't in use.
+
+The same preview can be run on a running broker by POSTing to /admin/previews
+using the admin API's credentials.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if parameters != "" {
+				if !json.Valid([]byte(parameters)) {
+					log.Fatal("--parameters must be valid JSON")
+				}
+				request.Parameters = json.RawMessage(parameters)
+			}
+
+			logger := utils.NewLogger("preview-provision")
+			db_service.New(logger)
+
+			cfg, err := brokers.NewBrokerConfigFromEnv(logger)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			serviceBroker, err := brokers.New(cfg, logger)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			result, err := serviceBroker.PreviewProvision(context.Background(), request)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			utils.PrettyPrintOrExit(result)
+			if !result.Valid {
+				os.Exit(1)
+			}
+		},
+	}
+
+	previewCmd.Flags().StringVar(&request.InstanceId, "instance-id", "", "the GUID the instance would have")
+	previewCmd.Flags().StringVar(&request.Service, "service", "", "the name or ID of the service")
+	previewCmd.Flags().StringVar(&request.Plan, "plan", "", "the name or ID of the plan")
+	previewCmd.Flags().StringVar(&request.OrganizationGuid, "organization-guid", "", "the GUID of the organization that would own the instance")
+	previewCmd.Flags().StringVar(&request.SpaceGuid, "space-guid", "", "the GUID of the space that would own the instance")
+	previewCmd.Flags().StringVarP(&parameters, "parameters", "c", "", "JSON provision parameters")
+
+	rootCmd.AddCommand(previewCmd)
+}
@@ -19,6 +19,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/pivotal/cloud-service-broker/pkg/config/secrets"
 	"github.com/pivotal/cloud-service-broker/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -55,13 +56,15 @@ func init() {
 }
 
 func initConfig() {
-	if cfgFile == "" {
-		return
-	}
+	if cfgFile != "" {
+		viper.SetConfigFile(cfgFile)
 
-	viper.SetConfigFile(cfgFile)
+		if err := viper.ReadInConfig(); err != nil {
+			log.Fatalf("Can't read config: %v\n", err)
+		}
+	}
 
-	if err := viper.ReadInConfig(); err != nil {
-		log.Fatalf("Can't read config: %v\n", err)
+	if err := secrets.DecryptConfig(); err != nil {
+		log.Fatalf("Can't decrypt config: %v\n", err)
 	}
 }
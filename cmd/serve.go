@@ -17,18 +17,53 @@ package cmd
 import (
 	"context"
 	"database/sql"
+	"expvar"
+	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"code.cloudfoundry.org/lager"
 	"github.com/pivotal/cloud-service-broker/brokerapi/brokers"
 	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/alerts"
+	"github.com/pivotal/cloud-service-broker/pkg/apiversion"
+	"github.com/pivotal/cloud-service-broker/pkg/archive"
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/brokerauth"
 	"github.com/pivotal/cloud-service-broker/pkg/brokerpak"
+	"github.com/pivotal/cloud-service-broker/pkg/catalogsync"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/config/secrets"
+	"github.com/pivotal/cloud-service-broker/pkg/deprovision"
+	"github.com/pivotal/cloud-service-broker/pkg/discovery"
+	"github.com/pivotal/cloud-service-broker/pkg/events"
+	"github.com/pivotal/cloud-service-broker/pkg/experiments"
+	"github.com/pivotal/cloud-service-broker/pkg/failure"
+	"github.com/pivotal/cloud-service-broker/pkg/interceptors"
+	"github.com/pivotal/cloud-service-broker/pkg/jobs"
+	"github.com/pivotal/cloud-service-broker/pkg/leader"
+	"github.com/pivotal/cloud-service-broker/pkg/logging"
+	"github.com/pivotal/cloud-service-broker/pkg/maintenance"
+	"github.com/pivotal/cloud-service-broker/pkg/orphans"
+	"github.com/pivotal/cloud-service-broker/pkg/osberror"
+	"github.com/pivotal/cloud-service-broker/pkg/plandrift"
+	"github.com/pivotal/cloud-service-broker/pkg/ratelimit"
+	"github.com/pivotal/cloud-service-broker/pkg/recorder"
+	"github.com/pivotal/cloud-service-broker/pkg/requeststats"
+	"github.com/pivotal/cloud-service-broker/pkg/requestdetails"
 	"github.com/pivotal/cloud-service-broker/pkg/server"
+	"github.com/pivotal/cloud-service-broker/pkg/svcat"
 	"github.com/pivotal/cloud-service-broker/pkg/toggles"
+	"github.com/pivotal/cloud-service-broker/pkg/tracing"
 	"github.com/pivotal/cloud-service-broker/utils"
 	"github.com/gorilla/mux"
+	"github.com/heptiolabs/healthcheck"
 	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/brokerapi/auth"
+	"github.com/pivotal-cf/brokerapi/middlewares/originating_identity_header"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -37,21 +72,48 @@ const (
 	apiUserProp     = "api.user"
 	apiPasswordProp = "api.password"
 	apiPortProp     = "api.port"
+
+	// apiDrainTimeoutProp is how long the broker waits for in-flight
+	// requests and background Terraform jobs to finish when it's stopped.
+	apiDrainTimeoutProp = "api.drain_timeout"
+
+	// The admin API uses separate credentials so operators don't need to
+	// share the platform's broker credentials.
+	adminUserProp     = "admin.user"
+	adminPasswordProp = "admin.password"
 )
 
 var cfCompatibilityToggle = toggles.Features.Toggle("enable-cf-sharing", false, `Set all services to have the Sharable flag so they can be shared
 	across spaces in PCF.`)
 
+var dashboardToggle = toggles.Features.Toggle("enable-dashboard", false, `Serve an HTML dashboard summarizing instances, pending operations and
+	recent failures at /admin/dashboard.`)
+
+var uiToggle = toggles.Features.Toggle("enable-admin-ui", false, `Serve a single page admin UI showing instances, operation timelines,
+	outdated instances and the catalog at /admin/ui.`)
+
 func init() {
-	rootCmd.AddCommand(&cobra.Command{
+	var check bool
+	serveCmd := &cobra.Command{
 		Use:   "serve",
 		Short: "Start the service broker",
 		Long: `Starts the service broker listening on a port defined by the
-	PORT environment variable.`,
+	PORT environment variable.
+
+	With --check, the startup checks are run and reported without migrating
+	the database or listening on the port, e.g. to verify a deployment before
+	it replaces the running broker.`,
 		Run: func(cmd *cobra.Command, args []string) {
+			if check {
+				checkStartup()
+				return
+			}
+
 			serve()
 		},
-	})
+	}
+	serveCmd.Flags().BoolVar(&check, "check", false, "run the startup checks, report the results and exit with a non-zero status if any failed")
+	rootCmd.AddCommand(serveCmd)
 
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "serve-docs",
@@ -61,26 +123,49 @@ func init() {
 		},
 	})
 
-	viper.BindEnv(apiUserProp, "SECURITY_USER_NAME")
-	viper.BindEnv(apiPasswordProp, "SECURITY_USER_PASSWORD")
-	viper.BindEnv(apiPortProp, "PORT")
+	config.Register(
+		config.Property{Key: apiUserProp, Kind: config.String, Env: "SECURITY_USER_NAME"},
+		config.Property{Key: apiPasswordProp, Kind: config.String, Env: "SECURITY_USER_PASSWORD", Sensitive: true},
+		config.Property{Key: apiPortProp, Kind: config.Integer, Env: "PORT"},
+		config.Property{Key: apiDrainTimeoutProp, Kind: config.Duration, Default: "30s"},
+		config.Property{Key: adminUserProp, Kind: config.String},
+		config.Property{Key: adminPasswordProp, Kind: config.String, Sensitive: true},
+	)
 }
 
 func serve() {
 	logger := utils.NewLogger("cloud-service-broker")
+
+	// fail fast with every mistake rather than the first one a component
+	// happens to read
+	if err := config.Validate(); err != nil {
+		logger.Fatal("Invalid configuration", err)
+	}
+
+	shutdownTracing, err := tracing.SetupFromEnv(logger)
+	if err != nil {
+		logger.Fatal("Error initializing tracing", err)
+	}
+	defer shutdownTracing()
+
 	db := db_service.New(logger)
+	// the broker, its Terraform deployments and background jobs share one
+	// datastore
+	store := db_service.NewSqlDatastore(db)
+	jobs.Default.Database = store
 
 	// init broker
 	cfg, err := brokers.NewBrokerConfigFromEnv(logger)
 	if err != nil {
 		logger.Fatal("Error initializing service broker config: %s", err)
 	}
-	var serviceBroker brokerapi.ServiceBroker
-	serviceBroker, err = brokers.New(cfg, logger)
+	gcpBroker, err := brokers.NewBroker(db_service.NewRetryingDatastore(store), cfg, logger)
 	if err != nil {
 		logger.Fatal("Error initializing service broker: %s", err)
 	}
 
+	var serviceBroker brokerapi.ServiceBroker = gcpBroker
+
 	credentials := brokerapi.BrokerCredentials{
 		Username: viper.GetString(apiUserProp),
 		Password: viper.GetString(apiPasswordProp),
@@ -91,15 +176,304 @@ func serve() {
 		serviceBroker = server.NewCfSharingWrapper(serviceBroker)
 	}
 
+	// the catalog is built once and served from memory until it's refreshed
+	catalogCache := server.NewCatalogCache(context.Background(), serviceBroker)
+	serviceBroker = catalogCache
+
 	services, err := serviceBroker.Services(context.Background())
 	if err != nil {
 		logger.Error("creating service catalog", err)
 	}
 	logger.Info("service catalog", lager.Data{"catalog": services})
 
-	brokerAPI := brokerapi.New(serviceBroker, logger, credentials)
+	// a plan whose ID changed orphans the instances created with the old one
+	planDrift, err := plandrift.NewCheckerFromEnv(logger)
+	if err != nil {
+		logger.Fatal("Error configuring plan ID drift detection", err)
+	}
+	if services != nil {
+		if _, err := planDrift.Check(context.Background(), services); err != nil {
+			logger.Fatal("Error checking plan IDs", err)
+		}
+	}
+
+	apiAuth, err := brokerauth.NewChainFromEnv(logger, credentials.Username, credentials.Password)
+	if err != nil {
+		logger.Fatal("Error configuring broker API authentication", err)
+	}
+
+	requestStats, err := requeststats.NewTrackerFromEnv(logger)
+	if err != nil {
+		logger.Fatal("Error configuring request stats", err)
+	}
+
+	// maintenance mode rejects new provisions and bindings while the broker
+	// keeps serving the catalog and polling
+	maintenanceMode, err := maintenance.NewModeFromEnv(logger)
+	if err != nil {
+		logger.Fatal("Error configuring maintenance mode", err)
+	}
+
+	limits, err := ratelimit.NewMiddlewareFromEnv(logger)
+	if err != nil {
+		logger.Fatal("Error configuring rate limits", err)
+	}
+
+	versions, err := apiversion.NewMiddlewareFromEnv(logger)
+	if err != nil {
+		logger.Fatal("Error configuring OSB API version negotiation", err)
+	}
+
+	exchangeRecorder, err := recorder.NewRecorderFromEnv(logger)
+	if err != nil {
+		logger.Fatal("Error configuring request recording", err)
+	}
+
+	for _, interceptor := range interceptors.Registered() {
+		logger.Info("interceptor", lager.Data{"name": interceptor.Name, "stage": interceptor.Stage.String(), "endpoints": interceptor.Endpoints})
+	}
+
+	brokerAPI := mux.NewRouter()
+	brokerapi.AttachRoutes(brokerAPI, serviceBroker, logger)
+	// requests are counted and timed before anything can reject them
+	brokerAPI.Use(requestStats.Wrap)
+	brokerAPI.Use(interceptors.Chain(interceptors.BeforeAuthentication))
+	brokerAPI.Use(requeststats.TimeMiddleware(requeststats.PhaseAuth, apiAuth.Wrap))
+	if exchangeRecorder != nil {
+		// recorded after authentication so the responses are the ones
+		// platforms received from the rest of the middleware
+		brokerAPI.Use(exchangeRecorder.Wrap)
+	}
+	brokerAPI.Use(maintenanceMode.Wrap)
+	brokerAPI.Use(limits.Wrap)
+	// before versions so strict version checks don't reject the bindings
+	// compatibility mode makes synchronous
+	brokerAPI.Use(svcat.Wrap)
+	brokerAPI.Use(versions.Wrap)
+	brokerAPI.Use(catalogCache.Wrap)
+	brokerAPI.Use(experiments.Wrap)
+	brokerAPI.Use(failure.Wrap)
+	brokerAPI.Use(osberror.Wrap)
+	brokerAPI.Use(originating_identity_header.AddToContext)
+	brokerAPI.Use(interceptors.Chain(interceptors.BeforeHandler))
+
+	// platforms fetch the catalog asynchronously so they can be told about
+	// changes while the server starts without blocking on them
+	syncer, err := catalogsync.NewSyncerFromEnv(logger)
+	if err != nil {
+		logger.Fatal("Error initializing catalog sync", err)
+	}
+	if syncer != nil && services != nil {
+		go func() {
+			if _, err := syncer.Sync(context.Background(), services); err != nil {
+				logger.Error("syncing catalog with platforms", err)
+			}
+		}()
+	}
+
+	// a reloaded catalog mustn't orphan instances either
+	catalogCache.Check = func(ctx context.Context, services []brokerapi.Service) error {
+		_, err := planDrift.Check(ctx, services)
+		return err
+	}
+
+	reloader := config.NewReloader(cfgFile, secrets.DecryptViper)
+
+	refreshCatalog := func(ctx context.Context) ([]brokerapi.Service, error) {
+		var services []brokerapi.Service
+		err := reloader.Reload(func() (err error) {
+			services, err = catalogCache.Refresh(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if syncer != nil {
+			if _, err := syncer.Sync(ctx, services); err != nil {
+				logger.Error("syncing catalog with platforms", err)
+			}
+		}
+
+		return services, nil
+	}
+
+	// operators without access to the admin API can reload with a SIGHUP
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go func() {
+		for range hangups {
+			if _, err := refreshCatalog(context.Background()); err != nil {
+				logger.Error("reloading configuration", err)
+				continue
+			}
+
+			logger.Info("reloaded configuration")
+		}
+	}()
+
+	archiver, err := archive.NewArchiverFromEnv(context.Background(), logger)
+	if err != nil {
+		logger.Fatal("Error initializing archiving", err)
+	}
+	if interval := viper.GetDuration(archive.IntervalProp); archiver != nil && interval > 0 {
+		// replicas share the database, so one of them archives for all
+		elector, err := leader.NewElectorFromEnv("archive", logger)
+		if err != nil {
+			logger.Fatal("Error configuring leader election", err)
+		}
+		elector.Start()
+
+		archiver.LeaderContext = elector.LeaderContext
+		go archiver.RunEvery(context.Background(), interval)
+	}
 
-	startServer(cfg.Registry, db.DB(), brokerAPI)
+	purger, err := requestdetails.NewPurgerFromEnv(logger)
+	if err != nil {
+		logger.Fatal("Error configuring request details retention", err)
+	}
+	if interval := viper.GetDuration(requestdetails.PurgeIntervalProp); purger != nil && interval > 0 {
+		elector, err := leader.NewElectorFromEnv("request-details", logger)
+		if err != nil {
+			logger.Fatal("Error configuring leader election", err)
+		}
+		elector.Start()
+
+		purger.LeaderContext = elector.LeaderContext
+		go purger.RunEvery(context.Background(), interval)
+	}
+
+	jobPurger, err := jobs.NewPurgerFromEnv(store, logger)
+	if err != nil {
+		logger.Fatal("Error configuring job retention", err)
+	}
+	if interval := viper.GetDuration(jobs.PurgeIntervalProp); jobPurger != nil && interval > 0 {
+		elector, err := leader.NewElectorFromEnv("jobs", logger)
+		if err != nil {
+			logger.Fatal("Error configuring leader election", err)
+		}
+		elector.Start()
+
+		jobPurger.LeaderContext = elector.LeaderContext
+		go jobPurger.RunEvery(context.Background(), interval)
+	}
+
+	if err := deprovision.Default.ConfigureFromEnv(); err != nil {
+		logger.Fatal("Error configuring deprovision reconciler", err)
+	}
+
+	// the workers run the jobs of every service loaded by the broker,
+	// including ones queued by other instances or before a restart
+	if err := jobs.Default.ConfigureFromEnv(); err != nil {
+		logger.Fatal("Error configuring background jobs", err)
+	}
+	workers := viper.GetInt(jobs.WorkersProp)
+	if workers < 1 {
+		logger.Fatal("Error configuring background jobs", fmt.Errorf("%s must be at least 1, got %d", jobs.WorkersProp, workers))
+	}
+	jobs.Default.Start(workers)
+
+	// discovery calls need Google credentials, brokers for other clouds run
+	// without them
+	discoveryCache, err := discovery.NewGcpCacheFromEnv(logger)
+	if err != nil {
+		logger.Error("initializing discovery cache", err)
+	}
+
+	// orphans are found with the same credentials
+	collector, err := orphans.NewCollectorFromEnv(logger)
+	if err != nil {
+		logger.Error("initializing orphan collector", err)
+	}
+	if interval := viper.GetDuration(orphans.IntervalProp); collector != nil && interval > 0 {
+		elector, err := leader.NewElectorFromEnv("orphans", logger)
+		if err != nil {
+			logger.Fatal("Error configuring leader election", err)
+		}
+		elector.Start()
+
+		collector.LeaderContext = elector.LeaderContext
+		go collector.RunEvery(context.Background(), interval)
+	}
+
+	watcher, err := alerts.NewWatcherFromEnv(gcpBroker, logger)
+	if err != nil {
+		logger.Fatal("Error configuring alerts", err)
+	}
+	if interval := viper.GetDuration(alerts.IntervalProp); watcher != nil && interval > 0 {
+		elector, err := leader.NewElectorFromEnv("alerts", logger)
+		if err != nil {
+			logger.Fatal("Error configuring leader election", err)
+		}
+		elector.Start()
+
+		watcher.LeaderContext = elector.LeaderContext
+		go watcher.RunEvery(context.Background(), interval)
+	}
+
+	readinessChecks := map[string]healthcheck.Check{
+		"migrations": func() error { return db_service.CheckMigrations(db) },
+	}
+	if conf, err := utils.GetAuthedConfig(); err == nil {
+		readinessChecks["gcp-credentials"] = server.TokenSourceCheck(conf.TokenSource(context.Background()))
+	}
+
+	// operators can tell how the broker authenticates without its config
+	healthDetails := map[string]interface{}{}
+	if utils.GcpCredentialsConfigured() {
+		mode, err := utils.GetCredentialMode()
+		if err != nil {
+			logger.Error("finding Google Cloud credentials", err)
+		}
+		logger.Info("gcp credentials", lager.Data{"mode": mode})
+		healthDetails["gcp_credentials"] = mode
+	}
+
+	adminUser, adminPassword := viper.GetString(adminUserProp), viper.GetString(adminPasswordProp)
+	if adminUser == "" || adminPassword == "" {
+		logger.Info("admin API is using the broker credentials, set admin.user and admin.password to use separate ones")
+		adminUser, adminPassword = credentials.Username, credentials.Password
+	}
+
+	startServer(cfg.Registry, db.DB(), brokerAPI, readinessChecks, healthDetails, cfg.Events, func(router *mux.Router) {
+		// brokers authenticating platforms with tokens may have no basic
+		// credentials to fall back to
+		if adminUser == "" || adminPassword == "" {
+			logger.Info("admin API is disabled, set admin.user and admin.password to enable it")
+			return
+		}
+
+		authWrapper := auth.NewWrapper(adminUser, adminPassword)
+		server.AddInventoryHandler(router, gcpBroker, authWrapper.Wrap)
+		server.AddQuotaHandler(router, cfg.Registry, gcpBroker.Quotas, authWrapper.Wrap)
+		server.AddRevocationHandler(router, gcpBroker, authWrapper.Wrap)
+		server.AddImportHandler(router, gcpBroker, authWrapper.Wrap)
+		server.AddPreviewHandler(router, gcpBroker, authWrapper.Wrap)
+		server.AddProtectionHandler(router, gcpBroker, authWrapper.Wrap)
+		server.AddUpgradeHandler(router, gcpBroker, authWrapper.Wrap)
+		server.AddSupportBundleHandler(router, cfg.Registry, authWrapper.Wrap)
+		server.AddUsageHandler(router, cfg.Registry, authWrapper.Wrap)
+		server.AddCatalogHandler(router, refreshCatalog, authWrapper.Wrap)
+		server.AddMaintenanceHandler(router, maintenanceMode, authWrapper.Wrap)
+		server.AddRequestStatsHandler(router, requestStats, authWrapper.Wrap)
+		// metrics include the command line, so they're only for operators
+		router.Handle("/debug/vars", authWrapper.Wrap(expvar.Handler()))
+		if discoveryCache != nil {
+			server.AddDiscoveryHandler(router, discoveryCache, authWrapper.Wrap)
+		}
+		if collector != nil {
+			server.AddOrphanHandler(router, collector, authWrapper.Wrap)
+		}
+		if exchangeRecorder != nil {
+			server.AddExchangeHandler(router, exchangeRecorder, authWrapper.Wrap)
+		}
+		if dashboardToggle.IsActive() {
+			server.AddDashboardHandler(router, gcpBroker, func() error { return db_service.CheckMigrations(db) }, authWrapper.Wrap)
+		}
+		if uiToggle.IsActive() {
+			server.AddUiHandler(router, serviceBroker.Services, authWrapper.Wrap)
+		}
+	})
 }
 
 func serveDocs() {
@@ -110,10 +484,13 @@ func serveDocs() {
 		logger.Error("loading brokerpaks", err)
 	}
 
-	startServer(registry, nil, nil)
+	startServer(registry, nil, nil, nil, nil, nil)
 }
 
-func startServer(registry broker.BrokerRegistry, db *sql.DB, brokerapi http.Handler) {
+// startServer serves the broker, docs and health endpoints. Each extra route
+// function is given the router so it can add admin endpoints. Events still
+// being delivered by the bus are drained on shutdown.
+func startServer(registry broker.BrokerRegistry, db *sql.DB, brokerapi http.Handler, readinessChecks map[string]healthcheck.Check, healthDetails map[string]interface{}, bus *events.Bus, extraRoutes ...func(*mux.Router)) {
 	logger := utils.NewLogger("cloud-service-broker")
 
 	router := mux.NewRouter()
@@ -125,9 +502,91 @@ func startServer(registry broker.BrokerRegistry, db *sql.DB, brokerapi http.Hand
 
 	server.AddDocsHandler(router, registry)
 	router.HandleFunc("/examples", server.NewExampleHandler(registry))
-	server.AddHealthHandler(router, db)
+	server.AddHealthHandler(router, db, readinessChecks, healthDetails)
+
+	for _, addRoutes := range extraRoutes {
+		addRoutes(router)
+	}
+
+	tlsConfig, err := server.NewTlsConfigFromEnv()
+	if err != nil {
+		logger.Fatal("Error configuring TLS", err)
+	}
 
 	port := viper.GetString(apiPortProp)
-	logger.Info("Serving", lager.Data{"port": port})
-	http.ListenAndServe(":"+port, router)
+	httpServer := &http.Server{
+		Addr:      ":" + port,
+		Handler:   tracing.NewHandler(logging.NewHandler(logger, router)),
+		TLSConfig: tlsConfig,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if tlsConfig == nil {
+			logger.Info("Serving", lager.Data{"port": port})
+			serveErr <- httpServer.ListenAndServe()
+			return
+		}
+
+		logger.Info("Serving", lager.Data{"port": port, "tls": true, "client_certificates": tlsConfig.ClientCAs != nil})
+		// the certificate is already in the TLS config
+		serveErr <- httpServer.ListenAndServeTLS("", "")
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+
+	select {
+	case err := <-serveErr:
+		logger.Error("serving", err)
+	case sig := <-signals:
+		logger.Info("shutting down", lager.Data{"signal": sig.String()})
+	}
+
+	shutdown(logger, httpServer, db, bus)
+}
+
+// shutdown stops accepting requests, waits up to the drain timeout for
+// in-flight requests, background jobs and event deliveries to finish, returns
+// jobs that didn't to the queue, hands over background work this instance
+// leads and closes the database connections.
+func shutdown(logger lager.Logger, httpServer *http.Server, db *sql.DB, bus *events.Bus) {
+	drainTimeout, err := time.ParseDuration(viper.GetString(apiDrainTimeoutProp))
+	if err != nil {
+		logger.Error("parsing drain timeout, using 30s", err)
+		drainTimeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(ctx); err != nil {
+		logger.Error("draining requests", err)
+	}
+
+	interrupted, err := jobs.Default.Drain(ctx)
+	if err != nil {
+		logger.Error("draining jobs", err)
+	}
+	if interrupted > 0 {
+		logger.Info("cancelled running jobs", lager.Data{"count": interrupted})
+	}
+
+	if bus != nil {
+		if err := bus.Drain(ctx); err != nil {
+			logger.Error("draining events", err)
+		}
+	}
+
+	if err := leader.ResignAll(ctx); err != nil {
+		logger.Error("releasing leader leases", err)
+	}
+
+	if db != nil {
+		if err := db.Close(); err != nil {
+			logger.Error("closing database", err)
+		}
+	}
+
+	logger.Info("shut down")
 }
@@ -0,0 +1,164 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/brokerapi/brokers"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
+	"github.com/pivotal/cloud-service-broker/pkg/plandrift"
+	"github.com/pivotal/cloud-service-broker/pkg/preflight"
+	"github.com/pivotal/cloud-service-broker/pkg/server"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/viper"
+)
+
+// checkStartup runs the checks serve relies on without migrating the
+// database, recording plan IDs or binding the port, prints the report and
+// exits with a non-zero status if any check failed.
+func checkStartup() {
+	logger := utils.NewLogger("cloud-service-broker")
+	ctx := context.Background()
+
+	var (
+		db       *gorm.DB
+		clients  *gcpclient.Factory
+		services []brokerapi.Service
+	)
+
+	checks := []preflight.Check{
+		{
+			Name: "configuration",
+			Run: func(ctx context.Context) (string, error) {
+				return "", config.Validate()
+			},
+		},
+		{
+			Name: "database",
+			Run: func(ctx context.Context) (out string, err error) {
+				db, err = db_service.Connect(logger)
+				// the plan ID check reads through the package-level database
+				db_service.DbConnection = db
+				return "", err
+			},
+		},
+		{
+			Name:     "migrations",
+			Requires: []string{"database"},
+			Run: func(ctx context.Context) (string, error) {
+				pending, err := db_service.PendingMigrations(db)
+				if err != nil || pending == 0 {
+					return "", err
+				}
+
+				return fmt.Sprintf("%d migration(s) pending, they're run when the broker starts", pending), nil
+			},
+		},
+		{
+			Name: "gcp-credentials",
+			Run: func(ctx context.Context) (out string, err error) {
+				// brokers for other clouds run without Google credentials
+				if !utils.GcpCredentialsConfigured() {
+					return "", preflight.Skip("no Google Cloud credentials are configured")
+				}
+
+				mode, err := utils.GetCredentialMode()
+				if err != nil {
+					return "", err
+				}
+
+				if clients, err = gcpclient.NewFactoryFromEnv(); err != nil {
+					return "", err
+				}
+
+				return mode.String(), server.TokenSourceCheck(clients.Config.TokenSource(ctx))()
+			},
+		},
+		{
+			Name:     "gcp-apis",
+			Requires: []string{"gcp-credentials"},
+			Run: func(ctx context.Context) (string, error) {
+				project, err := utils.GetDefaultProjectId()
+				if err != nil {
+					return "", err
+				}
+
+				return preflight.EnabledApis(project, clients, viper.GetStringSlice(preflight.RequiredApisProp))(ctx)
+			},
+		},
+		{
+			Name:     "catalog",
+			Requires: []string{"configuration"},
+			Run: func(ctx context.Context) (string, error) {
+				cfg, err := brokers.NewBrokerConfigFromEnv(logger)
+				if err != nil {
+					return "", err
+				}
+
+				serviceBroker, err := brokers.New(cfg, logger)
+				if err != nil {
+					return "", err
+				}
+
+				if services, err = serviceBroker.Services(ctx); err != nil {
+					return "", err
+				}
+
+				plans := 0
+				for _, service := range services {
+					plans += len(service.Plans)
+				}
+
+				return fmt.Sprintf("%d service(s) with %d plan(s)", len(services), plans), nil
+			},
+		},
+		{
+			Name:     "plan-ids",
+			Requires: []string{"migrations", "catalog"},
+			Run: func(ctx context.Context) (string, error) {
+				if !db.HasTable(&models.PlanRecord{}) {
+					return "", preflight.Skip("plan IDs are recorded once the broker has migrated the database")
+				}
+
+				checker, err := plandrift.NewCheckerFromEnv(logger)
+				if err != nil {
+					return "", err
+				}
+				checker.DryRun = true
+
+				drifts, err := checker.Check(ctx, services)
+				if err != nil || len(drifts) == 0 {
+					return "", err
+				}
+
+				return fmt.Sprintf("%d plan(s) changed ID, accepted because %s is %q", len(drifts), plandrift.ModeProp, plandrift.ModeWarn), nil
+			},
+		},
+	}
+
+	report := preflight.Run(ctx, checks)
+	utils.PrettyPrintOrExit(report)
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/serviceaccounts"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var dryRun bool
+	cleanupCmd := &cobra.Command{
+		Use:   "cleanup-orphaned-service-accounts",
+		Short: "Delete service accounts of bindings that were unbound",
+		Long: `Deletes the service accounts and keys recorded by bindings that have been
+unbound but whose accounts still exist, e.g. because unbinding failed part
+way. Accounts a current binding also uses are kept.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := utils.NewLogger("service-accounts")
+			db_service.New(logger)
+
+			cleaner, err := serviceaccounts.NewCleanerFromEnv(logger)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			result, err := cleaner.Run(context.Background(), dryRun)
+			if result != nil {
+				utils.PrettyPrintOrExit(result)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	cleanupCmd.Flags().BoolVar(&dryRun, "dry-run", false, "report orphaned service accounts without deleting them")
+
+	rootCmd.AddCommand(cleanupCmd)
+}
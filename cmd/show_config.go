@@ -0,0 +1,51 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "show-config",
+		Short: "Show the effective configuration with secrets redacted",
+		Long: `Shows the configuration the broker runs with, merged from the configuration
+file, the environment and the defaults, with passwords, keys and other
+sensitive values redacted so it can be shared.
+
+The configuration is then validated the same way as when the broker starts.
+Exits with a non-zero status and lists the invalid properties, with the
+environment variables they can be set with, if any are invalid.
+
+Example:
+
+  GSB_JOBS_WORKERS=8 cloud-service-broker --config config.yml show-config
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			utils.PrettyPrintOrExit(config.Effective())
+
+			if err := config.Validate(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	})
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/brokerpak"
+	"github.com/pivotal/cloud-service-broker/pkg/supportbundle"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var outputFile string
+	var skipDatabase bool
+
+	supportBundleCmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "Create a redacted support bundle to attach to issues",
+		Long: `Create a gzipped tarball describing the broker deployment to attach to
+issues. It contains the broker version, registered services and whether
+they're enabled, feature flags, the configuration with secrets redacted,
+the database type, migration level and record counts, the operation backlog
+and recent failed operations.
+
+The same bundle can be downloaded from a running broker at
+/admin/support-bundle using the broker's credentials.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := utils.NewLogger("support-bundle")
+			if !skipDatabase {
+				db_service.New(logger)
+			}
+
+			registry := broker.BrokerRegistry{}
+			if err := brokerpak.RegisterAll(registry); err != nil {
+				logger.Error("loading brokerpaks", err)
+			}
+
+			bundle, err := supportbundle.Collect(context.Background(), registry)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if outputFile == "" {
+				outputFile = fmt.Sprintf("support-bundle-%s.tar.gz", bundle.GeneratedAt.Format("20060102T150405Z"))
+			}
+
+			f, err := os.Create(outputFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+
+			if err := bundle.WriteTarball(f); err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Printf("Wrote support bundle to %s\n", outputFile)
+		},
+	}
+
+	supportBundleCmd.Flags().StringVarP(&outputFile, "output", "o", "", "file to write the bundle to, defaults to support-bundle-<timestamp>.tar.gz")
+	supportBundleCmd.Flags().BoolVar(&skipDatabase, "skip-database", false, "don't connect to the database, e.g. if it's unreachable")
+
+	rootCmd.AddCommand(supportBundleCmd)
+}
@@ -43,8 +43,11 @@ func init() {
 			logger := utils.NewLogger("tf")
 			db = db_service.New(logger)
 
-			jobRunner, err = tf.NewTfJobRunerFromEnv()
-			return err
+			if jobRunner, err = tf.NewTfJobRunerFromEnv(); err != nil {
+				return err
+			}
+			jobRunner.Store = db_service.NewSqlDatastore(db)
+			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.Help()
@@ -57,7 +60,7 @@ func init() {
 		Use:   "dump",
 		Short: "dump a Terraform workspace",
 		Run: func(cmd *cobra.Command, args []string) {
-			deployment, err := db_service.GetTerraformDeploymentById(context.Background(), args[0])
+			deployment, err := jobRunner.Store.GetTerraformDeploymentById(context.Background(), args[0])
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -0,0 +1,92 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"code.cloudfoundry.org/lager"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/brokerpak"
+	"github.com/pivotal/cloud-service-broker/pkg/usage"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	var from, to, groupBy, format string
+
+	usageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Report instance counts and lifetimes for chargeback",
+		Long: `Report how many instances existed, were created and deleted, and for how long,
+grouped by organization, space, service and plan, for chargeback and capacity
+planning. Deleted instances are included until they're purged.
+
+The period defaults to the current month up to now. --from and --to take
+dates, e.g. 2026-09-01, or RFC 3339 timestamps; --to is exclusive.
+
+The same report can be downloaded from a running broker at /admin/usage using
+the admin API's credentials.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			opts, err := usage.ParseOptions(from, to, groupBy, time.Now())
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			// keep stdout for the report, errors are still logged to stderr
+			viper.Set(utils.LogLevelProp, lager.FATAL.String())
+			logger := utils.NewLogger("usage")
+			db_service.New(logger)
+
+			registry := broker.BrokerRegistry{}
+			if err := brokerpak.RegisterAll(registry); err != nil {
+				logger.Error("loading brokerpaks", err)
+			}
+
+			report, err := usage.Generate(context.Background(), registry, opts)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			switch format {
+			case "csv":
+				err = usage.WriteCsv(os.Stdout, report)
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "    ")
+				err = encoder.Encode(report)
+			default:
+				log.Fatalf("Unknown format %q, must be json or csv", format)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	usageCmd.Flags().StringVar(&from, "from", "", "start of the period, defaults to the start of the current month")
+	usageCmd.Flags().StringVar(&to, "to", "", "end of the period, defaults to now")
+	usageCmd.Flags().StringVar(&groupBy, "group-by", "", "comma separated dimensions to group by: organization, space, service and plan (default all)")
+	usageCmd.Flags().StringVar(&format, "format", "csv", "format of the report, csv or json")
+	rootCmd.AddCommand(usageCmd)
+}
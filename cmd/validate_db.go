@@ -0,0 +1,54 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	validateDbCmd := &cobra.Command{
+		Use:   "validate-db",
+		Short: "Check the database schema matches the one the broker expects",
+		Long: `Compares the tables, columns, column types and indexes of the database to
+the ones the broker expects at the current migration level and reports any
+drift, e.g. from manual changes by a DBA. The database isn't migrated or
+modified.
+
+Exits with a non-zero status if the schema has drifted so it can be used to
+gate deployments.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := utils.NewLogger("validate-db")
+			db := db_service.SetupDb(logger)
+
+			drift, err := db_service.ValidateSchema(db)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			utils.PrettyPrintOrExit(drift)
+			if len(drift) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	rootCmd.AddCommand(validateDbCmd)
+}
@@ -0,0 +1,152 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// ArchivableRow is a row read from a table that's being archived, keyed by
+// column name.
+type ArchivableRow map[string]interface{}
+
+// ListArchivableRows lists up to limit rows of the table last updated before
+// the given time, oldest ID first, including soft deleted rows. The condition
+// and its arguments further filter the rows if the condition isn't empty.
+func ListArchivableRows(ctx context.Context, table string, before time.Time, condition string, args []interface{}, limit int) ([]ArchivableRow, error) {
+	return defaultDatastore().ListArchivableRows(ctx, table, before, condition, args, limit)
+}
+
+// ListArchivableRows lists up to limit rows of the table last updated before
+// the given time, oldest ID first, including soft deleted rows. The condition
+// and its arguments further filter the rows if the condition isn't empty.
+func (ds *SqlDatastore) ListArchivableRows(ctx context.Context, table string, before time.Time, condition string, args []interface{}, limit int) ([]ArchivableRow, error) {
+	defer traceOperation(ctx, "ListArchivableRows")()
+	query := ds.db.Table(table).Where("updated_at < ?", before)
+	if condition != "" {
+		query = query.Where(condition, args...)
+	}
+
+	rows, err := query.Order("id asc").Limit(limit).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ArchivableRow
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(ArchivableRow)
+		for i, column := range columns {
+			// drivers return text columns as bytes, store them as strings so
+			// they're readable once serialized
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[column] = values[i]
+		}
+		out = append(out, row)
+	}
+
+	return out, rows.Err()
+}
+
+// CountArchivableRows counts the rows ListArchivableRows would list without a
+// limit.
+func CountArchivableRows(ctx context.Context, table string, before time.Time, condition string, args []interface{}) (int, error) {
+	return defaultDatastore().CountArchivableRows(ctx, table, before, condition, args)
+}
+
+// CountArchivableRows counts the rows ListArchivableRows would list without a
+// limit.
+func (ds *SqlDatastore) CountArchivableRows(ctx context.Context, table string, before time.Time, condition string, args []interface{}) (int, error) {
+	defer traceOperation(ctx, "CountArchivableRows")()
+	query := ds.db.Table(table).Where("updated_at < ?", before)
+	if condition != "" {
+		query = query.Where(condition, args...)
+	}
+
+	count := 0
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// CreateArchive records the archive in the index and permanently deletes the
+// rows it holds from the source table in a single transaction.
+func CreateArchive(ctx context.Context, archive *models.Archive, ids []uint) error {
+	return defaultDatastore().CreateArchive(ctx, archive, ids)
+}
+
+// CreateArchive records the archive in the index and permanently deletes the
+// rows it holds from the source table in a single transaction.
+func (ds *SqlDatastore) CreateArchive(ctx context.Context, archive *models.Archive, ids []uint) error {
+	defer traceOperation(ctx, "CreateArchive")()
+	tx := ds.db.Begin()
+	if err := tx.Create(archive).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	result := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id IN (?)", archive.SourceTable), ids)
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+
+	if result.RowsAffected != int64(len(ids)) {
+		tx.Rollback()
+		return fmt.Errorf("expected to delete %d rows from %s, deleted %d", len(ids), archive.SourceTable, result.RowsAffected)
+	}
+
+	return tx.Commit().Error
+}
+
+// ListArchives lists the archives of the table, or every table if it's empty,
+// oldest first.
+func ListArchives(ctx context.Context, table string) ([]models.Archive, error) {
+	return defaultDatastore().ListArchives(ctx, table)
+}
+
+// ListArchives lists the archives of the table, or every table if it's empty,
+// oldest first.
+func (ds *SqlDatastore) ListArchives(ctx context.Context, table string) ([]models.Archive, error) {
+	defer traceOperation(ctx, "ListArchives")()
+	query := ds.db.Order("id asc")
+	if table != "" {
+		query = query.Where("source_table = ?", table)
+	}
+
+	var archives []models.Archive
+	err := query.Find(&archives).Error
+	return archives, err
+}
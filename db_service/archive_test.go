@@ -0,0 +1,95 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_Archives(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.CloudOperation{})
+	ds.db.CreateTable(models.Archive{})
+
+	now := time.Now().UTC()
+	for i, age := range []time.Duration{72 * time.Hour, 48 * time.Hour, time.Hour} {
+		op := models.CloudOperation{Name: "op", Status: "DONE", ServiceInstanceId: string(rune('a' + i))}
+		if err := ds.db.Create(&op).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := ds.db.Model(&op).UpdateColumn("updated_at", now.Add(-age)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := ds.ListArchivableRows(ctx, "cloud_operations", now.Add(-24*time.Hour), "status = ?", []interface{}{"DONE"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 archivable rows, got %d", len(rows))
+	}
+	if rows[0]["service_instance_id"] != "a" || rows[1]["service_instance_id"] != "b" {
+		t.Errorf("Expected the oldest rows in ID order, got %v", rows)
+	}
+
+	count, err := ds.CountArchivableRows(ctx, "cloud_operations", now.Add(-24*time.Hour), "status = ?", []interface{}{"DONE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 archivable rows to be counted, got %d", count)
+	}
+
+	rows, err = ds.ListArchivableRows(ctx, "cloud_operations", now.Add(-24*time.Hour), "status = ?", []interface{}{"RUNNING"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("Expected the condition to filter rows, got %v", rows)
+	}
+
+	archive := &models.Archive{SourceTable: "cloud_operations", Location: "gs://bucket/ops.jsonl.gz", Records: 2, FirstRecordId: 1, LastRecordId: 2}
+	if err := ds.CreateArchive(ctx, archive, []uint{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	var remaining int
+	ds.db.Unscoped().Model(&models.CloudOperation{}).Count(&remaining)
+	if remaining != 1 {
+		t.Errorf("Expected 1 row left after archiving, got %d", remaining)
+	}
+
+	if err := ds.CreateArchive(ctx, &models.Archive{SourceTable: "cloud_operations"}, []uint{1}); err == nil {
+		t.Error("Expected an error archiving rows that were already deleted")
+	}
+
+	archives, err := ds.ListArchives(ctx, "cloud_operations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archives) != 1 || archives[0].Location != archive.Location {
+		t.Errorf("Expected the archive to be indexed once, got %v", archives)
+	}
+
+	if archives, _ := ds.ListArchives(ctx, "instance_upgrades"); len(archives) != 0 {
+		t.Errorf("Expected no archives of other tables, got %v", archives)
+	}
+}
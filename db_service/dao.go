@@ -1,4 +1,4 @@
-// Copyright 2019 the Service Broker Project Authors.
+// Copyright 2026 the Service Broker Project Authors.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -23,75 +23,38 @@ import (
 	"github.com/jinzhu/gorm"
 )
 
-// CreateServiceInstanceDetails creates a new record in the database and assigns it a primary key.
-func CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error { return defaultDatastore().CreateServiceInstanceDetails(ctx, object) }
-func (ds *SqlDatastore) CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error {
-	return ds.db.Create(object).Error
-}
-
-// SaveServiceInstanceDetails updates an existing record in the database.
-func SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error { return defaultDatastore().SaveServiceInstanceDetails(ctx, object) }
-func (ds *SqlDatastore) SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error {
-	return ds.db.Save(object).Error
-}
-// DeleteServiceInstanceDetailsById soft-deletes the record by its key (id).
-func DeleteServiceInstanceDetailsById(ctx context.Context, id string) error { return defaultDatastore().DeleteServiceInstanceDetailsById(ctx, id) }
-func (ds *SqlDatastore) DeleteServiceInstanceDetailsById(ctx context.Context, id string) error {
-	return ds.db.Where("id = ?", id).Delete(&models.ServiceInstanceDetails{}).Error
-}
-
-
-
-// DeleteServiceInstanceDetails soft-deletes the record.
-func DeleteServiceInstanceDetails(ctx context.Context, record *models.ServiceInstanceDetails) error { return defaultDatastore().DeleteServiceInstanceDetails(ctx, record) }
-func (ds *SqlDatastore) DeleteServiceInstanceDetails(ctx context.Context, record *models.ServiceInstanceDetails) error {
-	return ds.db.Delete(record).Error
-}
-// GetServiceInstanceDetailsById gets an instance of ServiceInstanceDetails by its key (id).
-func GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error) { return defaultDatastore().GetServiceInstanceDetailsById(ctx, id) }
-func (ds *SqlDatastore) GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error) {
-	record := models.ServiceInstanceDetails{}
-	if err := ds.db.Where("id = ?", id).First(&record).Error; err != nil {
-		return nil, err
-	}
-
-	return &record, nil
-}
-
-// ExistsServiceInstanceDetailsById checks to see if an instance of ServiceInstanceDetails exists by its key (id).
-func ExistsServiceInstanceDetailsById(ctx context.Context, id string) (bool, error) { return defaultDatastore().ExistsServiceInstanceDetailsById(ctx, id) }
-func (ds *SqlDatastore) ExistsServiceInstanceDetailsById(ctx context.Context, id string) (bool, error) {
-	return recordToExists(ds.GetServiceInstanceDetailsById(ctx, id))
-}
-
-
-
 // CreateServiceBindingCredentials creates a new record in the database and assigns it a primary key.
+// It returns an error wrapping ErrAlreadyExists if the record has a duplicate key.
 func CreateServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error { return defaultDatastore().CreateServiceBindingCredentials(ctx, object) }
 func (ds *SqlDatastore) CreateServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error {
-	return ds.db.Create(object).Error
+	defer traceOperation(ctx, "CreateServiceBindingCredentials")()
+	return duplicateKeyError(ds.db.Create(object).Error)
 }
 
 // SaveServiceBindingCredentials updates an existing record in the database.
 func SaveServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error { return defaultDatastore().SaveServiceBindingCredentials(ctx, object) }
 func (ds *SqlDatastore) SaveServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error {
+	defer traceOperation(ctx, "SaveServiceBindingCredentials")()
 	return ds.db.Save(object).Error
 }
 // DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId soft-deletes the record by its key (serviceInstanceId, bindingId).
 func DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) error { return defaultDatastore().DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId) }
 func (ds *SqlDatastore) DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) error {
+	defer traceOperation(ctx, "DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId")()
 	return ds.db.Where("service_instance_id = ? AND binding_id = ?", serviceInstanceId, bindingId).Delete(&models.ServiceBindingCredentials{}).Error
 }
 
 // DeleteServiceBindingCredentialsByBindingId soft-deletes the record by its key (bindingId).
 func DeleteServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) error { return defaultDatastore().DeleteServiceBindingCredentialsByBindingId(ctx, bindingId) }
 func (ds *SqlDatastore) DeleteServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) error {
+	defer traceOperation(ctx, "DeleteServiceBindingCredentialsByBindingId")()
 	return ds.db.Where("binding_id = ?", bindingId).Delete(&models.ServiceBindingCredentials{}).Error
 }
 
 // DeleteServiceBindingCredentialsById soft-deletes the record by its key (id).
 func DeleteServiceBindingCredentialsById(ctx context.Context, id uint) error { return defaultDatastore().DeleteServiceBindingCredentialsById(ctx, id) }
 func (ds *SqlDatastore) DeleteServiceBindingCredentialsById(ctx context.Context, id uint) error {
+	defer traceOperation(ctx, "DeleteServiceBindingCredentialsById")()
 	return ds.db.Where("id = ?", id).Delete(&models.ServiceBindingCredentials{}).Error
 }
 
@@ -100,11 +63,13 @@ func (ds *SqlDatastore) DeleteServiceBindingCredentialsById(ctx context.Context,
 // DeleteServiceBindingCredentials soft-deletes the record.
 func DeleteServiceBindingCredentials(ctx context.Context, record *models.ServiceBindingCredentials) error { return defaultDatastore().DeleteServiceBindingCredentials(ctx, record) }
 func (ds *SqlDatastore) DeleteServiceBindingCredentials(ctx context.Context, record *models.ServiceBindingCredentials) error {
+	defer traceOperation(ctx, "DeleteServiceBindingCredentials")()
 	return ds.db.Delete(record).Error
 }
 // GetServiceBindingCredentialsByServiceInstanceIdAndBindingId gets an instance of ServiceBindingCredentials by its key (serviceInstanceId, bindingId).
 func GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (*models.ServiceBindingCredentials, error) { return defaultDatastore().GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId) }
 func (ds *SqlDatastore) GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (*models.ServiceBindingCredentials, error) {
+	defer traceOperation(ctx, "GetServiceBindingCredentialsByServiceInstanceIdAndBindingId")()
 	record := models.ServiceBindingCredentials{}
 	if err := ds.db.Where("service_instance_id = ? AND binding_id = ?", serviceInstanceId, bindingId).First(&record).Error; err != nil {
 		return nil, err
@@ -116,12 +81,14 @@ func (ds *SqlDatastore) GetServiceBindingCredentialsByServiceInstanceIdAndBindin
 // ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId checks to see if an instance of ServiceBindingCredentials exists by its key (serviceInstanceId, bindingId).
 func ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (bool, error) { return defaultDatastore().ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId) }
 func (ds *SqlDatastore) ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (bool, error) {
+	defer traceOperation(ctx, "ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId")()
 	return recordToExists(ds.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId))
 }
 
 // GetServiceBindingCredentialsByBindingId gets an instance of ServiceBindingCredentials by its key (bindingId).
 func GetServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (*models.ServiceBindingCredentials, error) { return defaultDatastore().GetServiceBindingCredentialsByBindingId(ctx, bindingId) }
 func (ds *SqlDatastore) GetServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (*models.ServiceBindingCredentials, error) {
+	defer traceOperation(ctx, "GetServiceBindingCredentialsByBindingId")()
 	record := models.ServiceBindingCredentials{}
 	if err := ds.db.Where("binding_id = ?", bindingId).First(&record).Error; err != nil {
 		return nil, err
@@ -133,12 +100,14 @@ func (ds *SqlDatastore) GetServiceBindingCredentialsByBindingId(ctx context.Cont
 // ExistsServiceBindingCredentialsByBindingId checks to see if an instance of ServiceBindingCredentials exists by its key (bindingId).
 func ExistsServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (bool, error) { return defaultDatastore().ExistsServiceBindingCredentialsByBindingId(ctx, bindingId) }
 func (ds *SqlDatastore) ExistsServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (bool, error) {
+	defer traceOperation(ctx, "ExistsServiceBindingCredentialsByBindingId")()
 	return recordToExists(ds.GetServiceBindingCredentialsByBindingId(ctx, bindingId))
 }
 
 // GetServiceBindingCredentialsById gets an instance of ServiceBindingCredentials by its key (id).
 func GetServiceBindingCredentialsById(ctx context.Context, id uint) (*models.ServiceBindingCredentials, error) { return defaultDatastore().GetServiceBindingCredentialsById(ctx, id) }
 func (ds *SqlDatastore) GetServiceBindingCredentialsById(ctx context.Context, id uint) (*models.ServiceBindingCredentials, error) {
+	defer traceOperation(ctx, "GetServiceBindingCredentialsById")()
 	record := models.ServiceBindingCredentials{}
 	if err := ds.db.Where("id = ?", id).First(&record).Error; err != nil {
 		return nil, err
@@ -150,37 +119,95 @@ func (ds *SqlDatastore) GetServiceBindingCredentialsById(ctx context.Context, id
 // ExistsServiceBindingCredentialsById checks to see if an instance of ServiceBindingCredentials exists by its key (id).
 func ExistsServiceBindingCredentialsById(ctx context.Context, id uint) (bool, error) { return defaultDatastore().ExistsServiceBindingCredentialsById(ctx, id) }
 func (ds *SqlDatastore) ExistsServiceBindingCredentialsById(ctx context.Context, id uint) (bool, error) {
+	defer traceOperation(ctx, "ExistsServiceBindingCredentialsById")()
 	return recordToExists(ds.GetServiceBindingCredentialsById(ctx, id))
 }
 
 
 
+// CreateServiceInstanceDetails creates a new record in the database and assigns it a primary key.
+// It returns an error wrapping ErrAlreadyExists if the record has a duplicate key.
+func CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error { return defaultDatastore().CreateServiceInstanceDetails(ctx, object) }
+func (ds *SqlDatastore) CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error {
+	defer traceOperation(ctx, "CreateServiceInstanceDetails")()
+	return duplicateKeyError(ds.db.Create(object).Error)
+}
+
+// SaveServiceInstanceDetails updates an existing record in the database.
+func SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error { return defaultDatastore().SaveServiceInstanceDetails(ctx, object) }
+func (ds *SqlDatastore) SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error {
+	defer traceOperation(ctx, "SaveServiceInstanceDetails")()
+	return ds.db.Save(object).Error
+}
+// DeleteServiceInstanceDetailsById soft-deletes the record by its key (id).
+func DeleteServiceInstanceDetailsById(ctx context.Context, id string) error { return defaultDatastore().DeleteServiceInstanceDetailsById(ctx, id) }
+func (ds *SqlDatastore) DeleteServiceInstanceDetailsById(ctx context.Context, id string) error {
+	defer traceOperation(ctx, "DeleteServiceInstanceDetailsById")()
+	return ds.db.Where("id = ?", id).Delete(&models.ServiceInstanceDetails{}).Error
+}
+
+
+
+// DeleteServiceInstanceDetails soft-deletes the record.
+func DeleteServiceInstanceDetails(ctx context.Context, record *models.ServiceInstanceDetails) error { return defaultDatastore().DeleteServiceInstanceDetails(ctx, record) }
+func (ds *SqlDatastore) DeleteServiceInstanceDetails(ctx context.Context, record *models.ServiceInstanceDetails) error {
+	defer traceOperation(ctx, "DeleteServiceInstanceDetails")()
+	return ds.db.Delete(record).Error
+}
+// GetServiceInstanceDetailsById gets an instance of ServiceInstanceDetails by its key (id).
+func GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error) { return defaultDatastore().GetServiceInstanceDetailsById(ctx, id) }
+func (ds *SqlDatastore) GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error) {
+	defer traceOperation(ctx, "GetServiceInstanceDetailsById")()
+	record := models.ServiceInstanceDetails{}
+	if err := ds.db.Where("id = ?", id).First(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// ExistsServiceInstanceDetailsById checks to see if an instance of ServiceInstanceDetails exists by its key (id).
+func ExistsServiceInstanceDetailsById(ctx context.Context, id string) (bool, error) { return defaultDatastore().ExistsServiceInstanceDetailsById(ctx, id) }
+func (ds *SqlDatastore) ExistsServiceInstanceDetailsById(ctx context.Context, id string) (bool, error) {
+	defer traceOperation(ctx, "ExistsServiceInstanceDetailsById")()
+	return recordToExists(ds.GetServiceInstanceDetailsById(ctx, id))
+}
+
+
+
 // CreateProvisionRequestDetails creates a new record in the database and assigns it a primary key.
+// It returns an error wrapping ErrAlreadyExists if the record has a duplicate key.
 func CreateProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error { return defaultDatastore().CreateProvisionRequestDetails(ctx, object) }
 func (ds *SqlDatastore) CreateProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error {
-	return ds.db.Create(object).Error
+	defer traceOperation(ctx, "CreateProvisionRequestDetails")()
+	return duplicateKeyError(ds.db.Create(object).Error)
 }
 
 // SaveProvisionRequestDetails updates an existing record in the database.
 func SaveProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error { return defaultDatastore().SaveProvisionRequestDetails(ctx, object) }
 func (ds *SqlDatastore) SaveProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error {
+	defer traceOperation(ctx, "SaveProvisionRequestDetails")()
 	return ds.db.Save(object).Error
 }
-// DeleteProvisionRequestDetailsById soft-deletes the record by its key (id).
+// DeleteProvisionRequestDetailsById deletes the record by its key (id).
 func DeleteProvisionRequestDetailsById(ctx context.Context, id uint) error { return defaultDatastore().DeleteProvisionRequestDetailsById(ctx, id) }
 func (ds *SqlDatastore) DeleteProvisionRequestDetailsById(ctx context.Context, id uint) error {
-	return ds.db.Where("id = ?", id).Delete(&models.ProvisionRequestDetails{}).Error
+	defer traceOperation(ctx, "DeleteProvisionRequestDetailsById")()
+	return ds.db.Unscoped().Where("id = ?", id).Delete(&models.ProvisionRequestDetails{}).Error
 }
 
 
-// DeleteProvisionRequestDetails soft-deletes the record.
+
+// DeleteProvisionRequestDetails deletes the record.
 func DeleteProvisionRequestDetails(ctx context.Context, record *models.ProvisionRequestDetails) error { return defaultDatastore().DeleteProvisionRequestDetails(ctx, record) }
 func (ds *SqlDatastore) DeleteProvisionRequestDetails(ctx context.Context, record *models.ProvisionRequestDetails) error {
-	return ds.db.Delete(record).Error
+	defer traceOperation(ctx, "DeleteProvisionRequestDetails")()
+	return ds.db.Unscoped().Delete(record).Error
 }
 // GetProvisionRequestDetailsById gets an instance of ProvisionRequestDetails by its key (id).
 func GetProvisionRequestDetailsById(ctx context.Context, id uint) (*models.ProvisionRequestDetails, error) { return defaultDatastore().GetProvisionRequestDetailsById(ctx, id) }
 func (ds *SqlDatastore) GetProvisionRequestDetailsById(ctx context.Context, id uint) (*models.ProvisionRequestDetails, error) {
+	defer traceOperation(ctx, "GetProvisionRequestDetailsById")()
 	record := models.ProvisionRequestDetails{}
 	if err := ds.db.Where("id = ?", id).First(&record).Error; err != nil {
 		return nil, err
@@ -189,38 +216,96 @@ func (ds *SqlDatastore) GetProvisionRequestDetailsById(ctx context.Context, id u
 	return &record, nil
 }
 
-func GetProvisionRequestDetailsByInstanceId(ctx context.Context, instanceId string)  (*models.ProvisionRequestDetails, error) { return defaultDatastore().GetProvisionRequestDetailsByInstanceId(ctx, instanceId) } 
-func (ds *SqlDatastore) GetProvisionRequestDetailsByInstanceId(ctx context.Context, instanceId string)  (*models.ProvisionRequestDetails, error) {
-	record := models.ProvisionRequestDetails{}
-	if err := ds.db.Where("service_instance_id = ?", instanceId).First(&record).Error; err != nil {
+// ExistsProvisionRequestDetailsById checks to see if an instance of ProvisionRequestDetails exists by its key (id).
+func ExistsProvisionRequestDetailsById(ctx context.Context, id uint) (bool, error) { return defaultDatastore().ExistsProvisionRequestDetailsById(ctx, id) }
+func (ds *SqlDatastore) ExistsProvisionRequestDetailsById(ctx context.Context, id uint) (bool, error) {
+	defer traceOperation(ctx, "ExistsProvisionRequestDetailsById")()
+	return recordToExists(ds.GetProvisionRequestDetailsById(ctx, id))
+}
+
+
+
+// CreateCloudOperation creates a new record in the database and assigns it a primary key.
+// It returns an error wrapping ErrAlreadyExists if the record has a duplicate key.
+func CreateCloudOperation(ctx context.Context, object *models.CloudOperation) error { return defaultDatastore().CreateCloudOperation(ctx, object) }
+func (ds *SqlDatastore) CreateCloudOperation(ctx context.Context, object *models.CloudOperation) error {
+	defer traceOperation(ctx, "CreateCloudOperation")()
+	return duplicateKeyError(ds.db.Create(object).Error)
+}
+
+// SaveCloudOperation updates an existing record in the database.
+func SaveCloudOperation(ctx context.Context, object *models.CloudOperation) error { return defaultDatastore().SaveCloudOperation(ctx, object) }
+func (ds *SqlDatastore) SaveCloudOperation(ctx context.Context, object *models.CloudOperation) error {
+	defer traceOperation(ctx, "SaveCloudOperation")()
+	return ds.db.Save(object).Error
+}
+// DeleteCloudOperationById deletes the record by its key (id).
+func DeleteCloudOperationById(ctx context.Context, id uint) error { return defaultDatastore().DeleteCloudOperationById(ctx, id) }
+func (ds *SqlDatastore) DeleteCloudOperationById(ctx context.Context, id uint) error {
+	defer traceOperation(ctx, "DeleteCloudOperationById")()
+	return ds.db.Unscoped().Where("id = ?", id).Delete(&models.CloudOperation{}).Error
+}
+
+
+
+// DeleteCloudOperation deletes the record.
+func DeleteCloudOperation(ctx context.Context, record *models.CloudOperation) error { return defaultDatastore().DeleteCloudOperation(ctx, record) }
+func (ds *SqlDatastore) DeleteCloudOperation(ctx context.Context, record *models.CloudOperation) error {
+	defer traceOperation(ctx, "DeleteCloudOperation")()
+	return ds.db.Unscoped().Delete(record).Error
+}
+// GetCloudOperationById gets an instance of CloudOperation by its key (id).
+func GetCloudOperationById(ctx context.Context, id uint) (*models.CloudOperation, error) { return defaultDatastore().GetCloudOperationById(ctx, id) }
+func (ds *SqlDatastore) GetCloudOperationById(ctx context.Context, id uint) (*models.CloudOperation, error) {
+	defer traceOperation(ctx, "GetCloudOperationById")()
+	record := models.CloudOperation{}
+	if err := ds.db.Where("id = ?", id).First(&record).Error; err != nil {
 		return nil, err
 	}
 
 	return &record, nil
 }
 
-// ExistsProvisionRequestDetailsById checks to see if an instance of ProvisionRequestDetails exists by its key (id).
-func ExistsProvisionRequestDetailsById(ctx context.Context, id uint) (bool, error) { return defaultDatastore().ExistsProvisionRequestDetailsById(ctx, id) }
-func (ds *SqlDatastore) ExistsProvisionRequestDetailsById(ctx context.Context, id uint) (bool, error) {
-	return recordToExists(ds.GetProvisionRequestDetailsById(ctx, id))
+// ExistsCloudOperationById checks to see if an instance of CloudOperation exists by its key (id).
+func ExistsCloudOperationById(ctx context.Context, id uint) (bool, error) { return defaultDatastore().ExistsCloudOperationById(ctx, id) }
+func (ds *SqlDatastore) ExistsCloudOperationById(ctx context.Context, id uint) (bool, error) {
+	defer traceOperation(ctx, "ExistsCloudOperationById")()
+	return recordToExists(ds.GetCloudOperationById(ctx, id))
+}
+
+
+// FindAllCloudOperationByServiceInstanceId gets every instance of CloudOperation with the given (serviceInstanceId), oldest first.
+func FindAllCloudOperationByServiceInstanceId(ctx context.Context, serviceInstanceId string) ([]models.CloudOperation, error) { return defaultDatastore().FindAllCloudOperationByServiceInstanceId(ctx, serviceInstanceId) }
+func (ds *SqlDatastore) FindAllCloudOperationByServiceInstanceId(ctx context.Context, serviceInstanceId string) ([]models.CloudOperation, error) {
+	defer traceOperation(ctx, "FindAllCloudOperationByServiceInstanceId")()
+	var records []models.CloudOperation
+	if err := ds.db.Where("service_instance_id = ?", serviceInstanceId).Order("created_at, id").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return records, nil
 }
 
 
 
 // CreateTerraformDeployment creates a new record in the database and assigns it a primary key.
+// It returns an error wrapping ErrAlreadyExists if the record has a duplicate key.
 func CreateTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error { return defaultDatastore().CreateTerraformDeployment(ctx, object) }
 func (ds *SqlDatastore) CreateTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error {
-	return ds.db.Create(object).Error
+	defer traceOperation(ctx, "CreateTerraformDeployment")()
+	return duplicateKeyError(ds.db.Create(object).Error)
 }
 
 // SaveTerraformDeployment updates an existing record in the database.
 func SaveTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error { return defaultDatastore().SaveTerraformDeployment(ctx, object) }
 func (ds *SqlDatastore) SaveTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error {
+	defer traceOperation(ctx, "SaveTerraformDeployment")()
 	return ds.db.Save(object).Error
 }
 // DeleteTerraformDeploymentById soft-deletes the record by its key (id).
 func DeleteTerraformDeploymentById(ctx context.Context, id string) error { return defaultDatastore().DeleteTerraformDeploymentById(ctx, id) }
 func (ds *SqlDatastore) DeleteTerraformDeploymentById(ctx context.Context, id string) error {
+	defer traceOperation(ctx, "DeleteTerraformDeploymentById")()
 	return ds.db.Where("id = ?", id).Delete(&models.TerraformDeployment{}).Error
 }
 
@@ -229,11 +314,13 @@ func (ds *SqlDatastore) DeleteTerraformDeploymentById(ctx context.Context, id st
 // DeleteTerraformDeployment soft-deletes the record.
 func DeleteTerraformDeployment(ctx context.Context, record *models.TerraformDeployment) error { return defaultDatastore().DeleteTerraformDeployment(ctx, record) }
 func (ds *SqlDatastore) DeleteTerraformDeployment(ctx context.Context, record *models.TerraformDeployment) error {
+	defer traceOperation(ctx, "DeleteTerraformDeployment")()
 	return ds.db.Delete(record).Error
 }
 // GetTerraformDeploymentById gets an instance of TerraformDeployment by its key (id).
 func GetTerraformDeploymentById(ctx context.Context, id string) (*models.TerraformDeployment, error) { return defaultDatastore().GetTerraformDeploymentById(ctx, id) }
 func (ds *SqlDatastore) GetTerraformDeploymentById(ctx context.Context, id string) (*models.TerraformDeployment, error) {
+	defer traceOperation(ctx, "GetTerraformDeploymentById")()
 	record := models.TerraformDeployment{}
 	if err := ds.db.Where("id = ?", id).First(&record).Error; err != nil {
 		return nil, err
@@ -245,11 +332,61 @@ func (ds *SqlDatastore) GetTerraformDeploymentById(ctx context.Context, id strin
 // ExistsTerraformDeploymentById checks to see if an instance of TerraformDeployment exists by its key (id).
 func ExistsTerraformDeploymentById(ctx context.Context, id string) (bool, error) { return defaultDatastore().ExistsTerraformDeploymentById(ctx, id) }
 func (ds *SqlDatastore) ExistsTerraformDeploymentById(ctx context.Context, id string) (bool, error) {
+	defer traceOperation(ctx, "ExistsTerraformDeploymentById")()
 	return recordToExists(ds.GetTerraformDeploymentById(ctx, id))
 }
 
 
 
+// DaoDatastore has the methods generated on SqlDatastore. It's regenerated
+// with them so mocks like fakes.MockDaoDatastore always match.
+type DaoDatastore interface {
+	CreateServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error
+	SaveServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error
+	DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) error
+	DeleteServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) error
+	DeleteServiceBindingCredentialsById(ctx context.Context, id uint) error
+	DeleteServiceBindingCredentials(ctx context.Context, record *models.ServiceBindingCredentials) error
+	GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (*models.ServiceBindingCredentials, error)
+	ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (bool, error)
+	GetServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (*models.ServiceBindingCredentials, error)
+	ExistsServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (bool, error)
+	GetServiceBindingCredentialsById(ctx context.Context, id uint) (*models.ServiceBindingCredentials, error)
+	ExistsServiceBindingCredentialsById(ctx context.Context, id uint) (bool, error)
+
+	CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error
+	SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error
+	DeleteServiceInstanceDetailsById(ctx context.Context, id string) error
+	DeleteServiceInstanceDetails(ctx context.Context, record *models.ServiceInstanceDetails) error
+	GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error)
+	ExistsServiceInstanceDetailsById(ctx context.Context, id string) (bool, error)
+
+	CreateProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error
+	SaveProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error
+	DeleteProvisionRequestDetailsById(ctx context.Context, id uint) error
+	DeleteProvisionRequestDetails(ctx context.Context, record *models.ProvisionRequestDetails) error
+	GetProvisionRequestDetailsById(ctx context.Context, id uint) (*models.ProvisionRequestDetails, error)
+	ExistsProvisionRequestDetailsById(ctx context.Context, id uint) (bool, error)
+
+	CreateCloudOperation(ctx context.Context, object *models.CloudOperation) error
+	SaveCloudOperation(ctx context.Context, object *models.CloudOperation) error
+	DeleteCloudOperationById(ctx context.Context, id uint) error
+	DeleteCloudOperation(ctx context.Context, record *models.CloudOperation) error
+	GetCloudOperationById(ctx context.Context, id uint) (*models.CloudOperation, error)
+	ExistsCloudOperationById(ctx context.Context, id uint) (bool, error)
+	FindAllCloudOperationByServiceInstanceId(ctx context.Context, serviceInstanceId string) ([]models.CloudOperation, error)
+
+	CreateTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error
+	SaveTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error
+	DeleteTerraformDeploymentById(ctx context.Context, id string) error
+	DeleteTerraformDeployment(ctx context.Context, record *models.TerraformDeployment) error
+	GetTerraformDeploymentById(ctx context.Context, id string) (*models.TerraformDeployment, error)
+	ExistsTerraformDeploymentById(ctx context.Context, id string) (bool, error)
+
+}
+
+var _ DaoDatastore = (*SqlDatastore)(nil)
+
 func recordToExists(_ interface{}, err error) (bool, error) {
 	if err != nil {
 		if gorm.IsRecordNotFoundError(err) {
@@ -14,86 +14,271 @@
 
 // +build ignore
 
-// This program generates dao.go It can be invoked by running
+// This program generates dao.go, its tests, and the DaoDatastore mock in
+// fakes/dao_datastore.go. It can be invoked by running
 // go generate
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
+	"unicode"
 )
 
 func main() {
-	models := []crudModel{
-		{
-			Type:            "ServiceInstanceDetails",
-			PrimaryKeyType:  "string",
-			PrimaryKeyField: "id",
-			ExampleFields: map[string]interface{}{
-				"Name":             "Hello",
-				"Location":         "loc",
-				"Url":              "https://google.com",
-				"OtherDetails":     `{"some":["json","blob","here"]}`,
-				"ServiceId":        "123-456-7890",
-				"PlanId":           "planid",
-				"SpaceGuid":        "0000-0000-0000",
-				"OrganizationGuid": "1111-1111-1111",
-			},
-		},
-		{
-			Type:            "ServiceBindingCredentials",
-			PrimaryKeyType:  "uint",
-			PrimaryKeyField: "id",
-			Keys: []fieldList{
-				{
-					{Type: "string", Column: "service_instance_id"},
-					{Type: "string", Column: "binding_id"},
-				},
-				{
-					{Type: "string", Column: "binding_id"},
-				},
-			},
-			ExampleFields: map[string]interface{}{
-				"ServiceId":         "1111-1111-1111",
-				"ServiceInstanceId": "2222-2222-2222",
-				"BindingId":         "0000-0000-0000",
-				"OtherDetails":      `{"some":["json","blob","here"]}`,
-			},
-		},
-		{
-			Type:            "ProvisionRequestDetails",
-			PrimaryKeyType:  "uint",
-			PrimaryKeyField: "id",
-			ExampleFields: map[string]interface{}{
-				"ServiceInstanceId": "2222-2222-2222",
-				"RequestDetails":    `{"some":["json","blob","here"]}`,
-			},
-		},
-		{
-			Type:            "TerraformDeployment",
-			PrimaryKeyType:  "string",
-			PrimaryKeyField: "id",
-			Keys:            []fieldList{},
-			ExampleFields: map[string]interface{}{
-				"Workspace":            "{}",
-				"LastOperationType":    "create",
-				"LastOperationState":   `in progress`,
-				"LastOperationMessage": `Started 2018-01-01`,
-			},
-		},
-	}
-
-	for i, model := range models {
-		pk := fieldList{{Type: model.PrimaryKeyType, Column: model.PrimaryKeyField}}
-		models[i].Keys = append(model.Keys, pk)
-	}
+	models, err := loadModels(modelsDir)
+	die(err)
 
 	createDao(models)
 	createDaoTest(models)
+	createDaoMock(models)
+}
+
+// modelsDir holds the package the models are discovered from, relative to
+// db_service where go generate runs.
+const modelsDir = "models"
+
+// loadModels parses the models package and returns a crudModel for every type
+// whose struct has at least one field tagged with `dao`. Models are returned
+// in the order they're declared in db.go.
+//
+// The tag holds semicolon separated options:
+//
+//	key              generates lookups by the field's column
+//	key=a_col,b_col  generates lookups by the listed columns
+//	find_all         generates FindAll lookups returning every record with
+//	                 the field's value, oldest first
+//	find_all=a,b     generates FindAll lookups by the listed columns
+//	example=value    sets the field in the generated tests
+//	hard_delete      deletes records rather than soft-deleting them, it can
+//	                 also be set on an embedded gorm.Model
+//
+// e.g. `dao:"key=service_instance_id,binding_id;example=0000-0000-0000"`.
+// The primary key, ID, is always a key.
+func loadModels(dir string) ([]crudModel, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg, ok := pkgs[filepath.Base(dir)]
+	if !ok {
+		return nil, fmt.Errorf("no package %q in %s", filepath.Base(dir), dir)
+	}
+
+	// Current models are declared in db.go from the historical versions they
+	// were migrated to.
+	var typeNames []string
+	typeExprs := make(map[string]ast.Expr)
+	for filename, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				typeExprs[ts.Name.Name] = ts.Type
+				if filepath.Base(filename) == "db.go" {
+					typeNames = append(typeNames, ts.Name.Name)
+				}
+			}
+		}
+	}
+
+	var models []crudModel
+	for _, name := range typeNames {
+		st := resolveStruct(typeExprs, name)
+		if st == nil || !hasDaoTag(st) {
+			continue
+		}
+
+		model, err := newCrudModel(name, st)
+		if err != nil {
+			return nil, fmt.Errorf("model %s: %v", name, err)
+		}
+		models = append(models, model)
+	}
+
+	return models, nil
+}
+
+// resolveStruct follows type definitions like `type Foo FooV1` to the struct
+// they're defined from.
+func resolveStruct(typeExprs map[string]ast.Expr, name string) *ast.StructType {
+	for i := 0; i < len(typeExprs); i++ {
+		switch expr := typeExprs[name].(type) {
+		case *ast.StructType:
+			return expr
+		case *ast.Ident:
+			name = expr.Name
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func hasDaoTag(st *ast.StructType) bool {
+	for _, field := range st.Fields.List {
+		if _, ok := daoTag(field); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func daoTag(field *ast.Field) (string, bool) {
+	if field.Tag == nil {
+		return "", false
+	}
+
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return "", false
+	}
+
+	return reflect.StructTag(tag).Lookup("dao")
+}
+
+func newCrudModel(name string, st *ast.StructType) (crudModel, error) {
+	model := crudModel{
+		Type:            name,
+		PrimaryKeyField: "id",
+		ExampleFields:   make(map[string]interface{}),
+	}
+
+	columnTypes := make(map[string]string)
+	for _, field := range st.Fields.List {
+		typeName := exprString(field.Type)
+
+		// gorm.Model is embedded for its uint ID and timestamps.
+		if len(field.Names) == 0 && typeName == "gorm.Model" {
+			columnTypes["id"] = "uint"
+		}
+
+		for _, ident := range field.Names {
+			columnTypes[camelToSnake(ident.Name)] = typeName
+		}
+	}
+
+	model.PrimaryKeyType = columnTypes["id"]
+	if model.PrimaryKeyType == "" {
+		return model, fmt.Errorf("no ID field")
+	}
+
+	for _, field := range st.Fields.List {
+		tag, ok := daoTag(field)
+		if !ok {
+			continue
+		}
+		fieldName := exprString(field.Type)
+		if len(field.Names) == 1 {
+			fieldName = field.Names[0].Name
+		}
+
+		for _, option := range strings.Split(tag, ";") {
+			opt, value := option, ""
+			if idx := strings.Index(option, "="); idx >= 0 {
+				opt, value = option[:idx], option[idx+1:]
+			}
+
+			if opt != "hard_delete" && len(field.Names) != 1 {
+				return model, fmt.Errorf("%s can only be set on named fields, not %s", opt, fieldName)
+			}
+
+			switch opt {
+			case "hard_delete":
+				model.HardDelete = true
+
+			case "key", "find_all":
+				if value == "" {
+					value = camelToSnake(fieldName)
+				}
+
+				var key fieldList
+				for _, column := range strings.Split(value, ",") {
+					columnType, ok := columnTypes[column]
+					if !ok {
+						return model, fmt.Errorf("%s column %q on %s doesn't exist", opt, column, fieldName)
+					}
+					key = append(key, crudField{Type: columnType, Column: column})
+				}
+
+				if opt == "key" {
+					model.Keys = append(model.Keys, key)
+				} else {
+					model.FindAllKeys = append(model.FindAllKeys, key)
+				}
+
+			case "example":
+				if exprString(field.Type) != "string" {
+					return model, fmt.Errorf("examples are only supported on string fields, %s is %s", fieldName, exprString(field.Type))
+				}
+				model.ExampleFields[fieldName] = value
+
+			default:
+				return model, fmt.Errorf("unknown dao tag option %q on %s", opt, fieldName)
+			}
+		}
+	}
+
+	pk := fieldList{{Type: model.PrimaryKeyType, Column: model.PrimaryKeyField}}
+	model.Keys = append(model.Keys, pk)
+
+	return model, nil
+}
+
+func exprString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	case *ast.StarExpr:
+		return "*" + exprString(e.X)
+	default:
+		return fmt.Sprintf("%T", expr)
+	}
+}
+
+// camelToSnake converts a field name to its column name the same way gorm
+// does for the simple names used in models e.g. ServiceInstanceId becomes
+// service_instance_id and ID becomes id.
+func camelToSnake(in string) string {
+	out := ""
+	for i, r := range in {
+		upper := unicode.IsUpper(r)
+		if upper && i > 0 {
+			prev := rune(in[i-1])
+			nextLower := i+1 < len(in) && unicode.IsLower(rune(in[i+1]))
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				out += "_"
+			}
+		}
+		out += string(unicode.ToLower(r))
+	}
+
+	return out
 }
 
 func createDao(models []crudModel) {
@@ -124,6 +309,21 @@ func createDaoTest(models []crudModel) {
 	})
 }
 
+func createDaoMock(models []crudModel) {
+	var buf bytes.Buffer
+	die(daoMockTemplate.Execute(&buf, struct {
+		Timestamp time.Time
+		Models    []crudModel
+	}{
+		Timestamp: time.Now(),
+		Models:    models,
+	}))
+
+	formatted, err := format.Source(buf.Bytes())
+	die(err)
+	die(ioutil.WriteFile(filepath.Join("fakes", "dao_datastore.go"), formatted, 0644))
+}
+
 func die(err error) {
 	if err != nil {
 		log.Fatal(err)
@@ -136,6 +336,14 @@ type crudModel struct {
 	PrimaryKeyField string
 	ExampleFields   map[string]interface{}
 	Keys            []fieldList
+
+	// FindAllKeys hold the columns records are listed by, unlike Keys they
+	// needn't be unique.
+	FindAllKeys []fieldList
+
+	// HardDelete is set for models whose records are deleted rather than
+	// soft-deleted because nothing needs them afterwards.
+	HardDelete bool
 }
 
 type fieldList []crudField
@@ -209,6 +417,47 @@ func (fl fieldList) ExampleArgs(parent string) string {
 	return strings.Join(exampleArgs, ", ")
 }
 
+// daoMethod describes one of the methods generated on SqlDatastore, used to
+// write the DaoDatastore interface and its mock.
+type daoMethod struct {
+	Name        string
+	Params      string
+	CallParams  string
+	Results     string
+	ZeroResults string
+}
+
+// Methods lists the methods generated for the model in the order they're
+// written to dao.go.
+func (model crudModel) Methods() []daoMethod {
+	object := "object *models." + model.Type
+	record := "record *models." + model.Type
+
+	methods := []daoMethod{
+		{Name: "Create" + model.Type, Params: object, CallParams: "object", Results: "error", ZeroResults: "nil"},
+		{Name: "Save" + model.Type, Params: object, CallParams: "object", Results: "error", ZeroResults: "nil"},
+	}
+
+	for _, key := range model.Keys {
+		methods = append(methods, daoMethod{Name: "Delete" + model.Type + key.FuncName(), Params: key.Args(), CallParams: key.CallParams(), Results: "error", ZeroResults: "nil"})
+	}
+
+	methods = append(methods, daoMethod{Name: "Delete" + model.Type, Params: record, CallParams: "record", Results: "error", ZeroResults: "nil"})
+
+	for _, key := range model.Keys {
+		methods = append(methods,
+			daoMethod{Name: "Get" + model.Type + key.FuncName(), Params: key.Args(), CallParams: key.CallParams(), Results: "(*models." + model.Type + ", error)", ZeroResults: "nil, nil"},
+			daoMethod{Name: "Exists" + model.Type + key.FuncName(), Params: key.Args(), CallParams: key.CallParams(), Results: "(bool, error)", ZeroResults: "false, nil"},
+		)
+	}
+
+	for _, key := range model.FindAllKeys {
+		methods = append(methods, daoMethod{Name: "FindAll" + model.Type + key.FuncName(), Params: key.Args(), CallParams: key.CallParams(), Results: "([]models." + model.Type + ", error)", ZeroResults: "nil, nil"})
+	}
+
+	return methods
+}
+
 type crudField struct {
 	Type   string
 	Column string
@@ -282,32 +531,37 @@ import (
 {{- $type := .Type}}
 
 // {{funcName "Create" .Type}} creates a new record in the database and assigns it a primary key.
+// It returns an error wrapping ErrAlreadyExists if the record has a duplicate key.
 func {{funcName "Create" .Type}}(ctx context.Context, object *models.{{.Type}}) error { return defaultDatastore().{{funcName "Create" .Type}}(ctx, object) }
 func (ds *SqlDatastore) Create{{.Type}}(ctx context.Context, object *models.{{.Type}}) error {
-	return ds.db.Create(object).Error
+	defer traceOperation(ctx, "Create{{.Type}}")()
+	return duplicateKeyError(ds.db.Create(object).Error)
 }
 
 // {{funcName "Save" .Type}} updates an existing record in the database.
 func {{funcName "Save" .Type}}(ctx context.Context, object *models.{{.Type}}) error { return defaultDatastore().{{funcName "Save" .Type}}(ctx, object) }
 func (ds *SqlDatastore) {{funcName "Save" .Type}}(ctx context.Context, object *models.{{.Type}}) error {
+	defer traceOperation(ctx, "{{funcName "Save" .Type}}")()
 	return ds.db.Save(object).Error
 }
 
-{{- $type := .Type}}
+{{- $type := .Type}}{{ $hard := .HardDelete }}
 {{ range $idx, $key := .Keys -}}
 {{ $fn := (print "Delete" $type $key.FuncName) -}}
-// {{$fn}} soft-deletes the record by its key ({{$key.CallParams}}).
+// {{$fn}} {{if $hard}}deletes{{else}}soft-deletes{{end}} the record by its key ({{$key.CallParams}}).
 func {{$fn}}(ctx context.Context, {{ $key.Args }}) error { return defaultDatastore().{{$fn}}(ctx, {{$key.CallParams}}) }
 func (ds *SqlDatastore) {{$fn}}(ctx context.Context, {{ $key.Args }}) error {
-	return ds.db.{{ $key.WhereClause }}.Delete(&models.{{$type}}{}).Error
+	defer traceOperation(ctx, "{{$fn}}")()
+	return ds.db{{if $hard}}.Unscoped(){{end}}.{{ $key.WhereClause }}.Delete(&models.{{$type}}{}).Error
 }
 
 {{ end }}
 
-// Delete{{.Type}} soft-deletes the record.
+// Delete{{.Type}} {{if .HardDelete}}deletes{{else}}soft-deletes{{end}} the record.
 func {{funcName "Delete" .Type}}(ctx context.Context, record *models.{{.Type}}) error { return defaultDatastore().{{funcName "Delete" .Type}}(ctx, record) }
 func (ds *SqlDatastore) {{funcName "Delete" .Type}}(ctx context.Context, record *models.{{.Type}}) error {
-	return ds.db.Delete(record).Error
+	defer traceOperation(ctx, "{{funcName "Delete" .Type}}")()
+	return ds.db{{if .HardDelete}}.Unscoped(){{end}}.Delete(record).Error
 }
 
 {{- $type := .Type}}
@@ -317,6 +571,7 @@ func (ds *SqlDatastore) {{funcName "Delete" .Type}}(ctx context.Context, record
 // {{$getFn}} gets an instance of {{$type}} by its key ({{$key.CallParams}}).
 func {{$getFn}}(ctx context.Context, {{ $key.Args }}) (*models.{{$type}}, error) { return defaultDatastore().{{$getFn}}(ctx, {{$key.CallParams}}) }
 func (ds *SqlDatastore) {{$getFn}}(ctx context.Context, {{ $key.Args }}) (*models.{{$type}}, error) {
+	defer traceOperation(ctx, "{{$getFn}}")()
 	record := models.{{$type}}{}
 	if err := ds.db.{{ $key.WhereClause }}.First(&record).Error; err != nil {
 		return nil, err
@@ -329,13 +584,41 @@ func (ds *SqlDatastore) {{$getFn}}(ctx context.Context, {{ $key.Args }}) (*model
 // {{$existsFn}} checks to see if an instance of {{$type}} exists by its key ({{$key.CallParams}}).
 func {{$existsFn}}(ctx context.Context, {{ $key.Args }}) (bool, error) { return defaultDatastore().{{$existsFn}}(ctx, {{$key.CallParams}}) }
 func (ds *SqlDatastore) {{$existsFn}}(ctx context.Context, {{ $key.Args }}) (bool, error) {
+	defer traceOperation(ctx, "{{$existsFn}}")()
 	return recordToExists(ds.{{$getFn}}(ctx, {{ $key.CallParams }}))
 }
 
 {{ end }}
 
+{{- range $idx, $key := .FindAllKeys }}
+{{ $fn := (print "FindAll" $type $key.FuncName) -}}
+// {{$fn}} gets every instance of {{$type}} with the given ({{$key.CallParams}}), oldest first.
+func {{$fn}}(ctx context.Context, {{ $key.Args }}) ([]models.{{$type}}, error) { return defaultDatastore().{{$fn}}(ctx, {{$key.CallParams}}) }
+func (ds *SqlDatastore) {{$fn}}(ctx context.Context, {{ $key.Args }}) ([]models.{{$type}}, error) {
+	defer traceOperation(ctx, "{{$fn}}")()
+	var records []models.{{$type}}
+	if err := ds.db.{{ $key.WhereClause }}.Order("created_at, id").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+{{ end }}
+
 {{- end }}
 
+// DaoDatastore has the methods generated on SqlDatastore. It's regenerated
+// with them so mocks like fakes.MockDaoDatastore always match.
+type DaoDatastore interface {
+{{- range .Models}}
+{{range .Methods}}	{{.Name}}(ctx context.Context, {{.Params}}) {{.Results}}
+{{end}}
+{{- end}}
+}
+
+var _ DaoDatastore = (*SqlDatastore)(nil)
+
 func recordToExists(_ interface{}, err error) (bool, error) {
 	if err != nil {
 		if gorm.IsRecordNotFoundError(err) {
@@ -381,7 +664,7 @@ import (
 )
 
 func newInMemoryDatastore(t *testing.T) *SqlDatastore {
-	testDb, err := gorm.Open("sqlite3", ":memory:")
+	testDb, err := OpenSqlite(":memory:")
 	if err != nil {
 		t.Fatalf("Error opening test database %s", err)
 	}
@@ -469,7 +752,7 @@ func TestSqlDatastore_{{.Type}}DAO(t *testing.T) {
 	}
 }
 
-{{- $type := .Type}}{{ $pk := .PrimaryKeyField }}
+{{- $type := .Type}}{{ $pk := .PrimaryKeyField }}{{ $hard := .HardDelete }}
 {{ range $idx, $key := .Keys -}}
 {{ $fn := (print "Get" $type $key.FuncName) -}}
 func TestSqlDatastore_{{$fn}}(t *testing.T) {
@@ -525,9 +808,58 @@ func TestSqlDatastore_{{$fn}}(t *testing.T) {
 		t.Errorf("Expected no error when deleting by pk got: %v", err)
 	}
 
-	// we should be able to see that it was soft-deleted
+{{ if $hard }}	// the record should be gone rather than soft-deleted
+	count := 0
+	if err := ds.db.Unscoped().Model(&models.{{$type}}{}).Count(&count).Error; err != nil {
+		t.Fatalf("Expected no error counting records, got: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected the record to be deleted, found %d", count)
+	}
+
+	exists, err = ds.{{$fn}}(testCtx, {{$key.ExampleArgs "instance"}})
+	ensureExistance(t, false, exists, err)
+{{- else }}	// we should be able to see that it was soft-deleted
 	exists, err = ds.{{$fn}}(testCtx, {{$key.ExampleArgs "instance"}})
 	ensureExistance(t, false, exists, err)
+{{- end }}
+}
+{{ end }}
+
+{{- $pkType := .PrimaryKeyType }}
+{{- range $idx, $key := .FindAllKeys }}
+{{ $fn := (print "FindAll" $type $key.FuncName) -}}
+func TestSqlDatastore_{{$fn}}(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	_, first := create{{$type}}Instance()
+	_, second := create{{$type}}Instance()
+	second.ID = {{$pkType}}(43)
+	testCtx := context.Background()
+
+	records, err := ds.{{$fn}}(testCtx, {{$key.ExampleArgs "first"}})
+	if err != nil {
+		t.Errorf("Expected no error listing records, got: %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Expected no records before creation, got %d", len(records))
+	}
+
+	for _, instance := range []*models.{{$type}}{&first, &second} {
+		if err := ds.{{funcName "Create" $type}}(testCtx, instance); err != nil {
+			t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
+		}
+	}
+
+	records, err = ds.{{$fn}}(testCtx, {{$key.ExampleArgs "first"}})
+	if err != nil {
+		t.Errorf("Expected no error listing records, got: %v", err)
+	}
+	if len(records) != 2 || records[0].ID != first.ID || records[1].ID != second.ID {
+		t.Fatalf("Expected records %v and %v in creation order, got %#v", first.ID, second.ID, records)
+	}
+
+	// Ensure non-gorm fields were deserialized correctly
+	ensure{{$type}}FieldsMatch(t, &first, &records[0])
 }
 {{ end }}
 
@@ -543,3 +875,54 @@ func ensureExistance(t *testing.T, expected, actual bool, err error) {
 	}
 }
 `))
+
+var daoMockTemplate = template.Must(template.New("").Parse(`// Copyright {{ .Timestamp.Year }} the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by go generate; DO NOT EDIT.
+
+package fakes
+
+import (
+	"context"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// MockDaoDatastore is a db_service.DaoDatastore that calls the function set
+// in the field named after each method, e.g. CreateTerraformDeploymentFunc.
+// Methods without a function return zero values.
+type MockDaoDatastore struct {
+{{- range .Models}}
+{{range .Methods}}	{{.Name}}Func func(ctx context.Context, {{.Params}}) {{.Results}}
+{{end}}
+{{- end}}
+}
+
+var _ db_service.DaoDatastore = (*MockDaoDatastore)(nil)
+{{- range .Models}}
+{{- range .Methods}}
+
+// {{.Name}} calls {{.Name}}Func.
+func (m *MockDaoDatastore) {{.Name}}(ctx context.Context, {{.Params}}) {{.Results}} {
+	if m.{{.Name}}Func == nil {
+		return {{.ZeroResults}}
+	}
+
+	return m.{{.Name}}Func(ctx, {{.CallParams}})
+}
+{{- end}}
+{{- end}}
+`))
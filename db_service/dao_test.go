@@ -1,4 +1,4 @@
-// Copyright 2019 the Service Broker Project Authors.
+// Copyright 2026 the Service Broker Project Authors.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -26,95 +26,76 @@ import (
 )
 
 func newInMemoryDatastore(t *testing.T) *SqlDatastore {
-	testDb, err := gorm.Open("sqlite3", ":memory:")
+	testDb, err := OpenSqlite(":memory:")
 	if err != nil {
 		t.Fatalf("Error opening test database %s", err)
 	}
 
-	testDb.CreateTable(models.ServiceInstanceDetails{})
 	testDb.CreateTable(models.ServiceBindingCredentials{})
+	testDb.CreateTable(models.ServiceInstanceDetails{})
 	testDb.CreateTable(models.ProvisionRequestDetails{})
+	testDb.CreateTable(models.CloudOperation{})
 	testDb.CreateTable(models.TerraformDeployment{})
 	
 	return &SqlDatastore{db: testDb}
 }
 
-func createServiceInstanceDetailsInstance() (string, models.ServiceInstanceDetails) {
-	testPk := string(42)
+func createServiceBindingCredentialsInstance() (uint, models.ServiceBindingCredentials) {
+	testPk := uint(42)
 
-	instance := models.ServiceInstanceDetails{}
+	instance := models.ServiceBindingCredentials{}
 	instance.ID = testPk
-	instance.Location = "loc"
-	instance.Name = "Hello"
-	instance.OrganizationGuid = "1111-1111-1111"
+	instance.BindingId = "0000-0000-0000"
 	instance.OtherDetails = "{\"some\":[\"json\",\"blob\",\"here\"]}"
-	instance.PlanId = "planid"
-	instance.ServiceId = "123-456-7890"
-	instance.SpaceGuid = "0000-0000-0000"
-	instance.Url = "https://google.com"
+	instance.ServiceId = "1111-1111-1111"
+	instance.ServiceInstanceId = "2222-2222-2222"
 
 
 	return testPk, instance
 }
 
-func ensureServiceInstanceDetailsFieldsMatch(t *testing.T, expected, actual *models.ServiceInstanceDetails) {
-
-	if expected.Location != actual.Location {
-		t.Errorf("Expected field Location to be %#v, got %#v", expected.Location, actual.Location)
-	}
-
-	if expected.Name != actual.Name {
-		t.Errorf("Expected field Name to be %#v, got %#v", expected.Name, actual.Name)
-	}
+func ensureServiceBindingCredentialsFieldsMatch(t *testing.T, expected, actual *models.ServiceBindingCredentials) {
 
-	if expected.OrganizationGuid != actual.OrganizationGuid {
-		t.Errorf("Expected field OrganizationGuid to be %#v, got %#v", expected.OrganizationGuid, actual.OrganizationGuid)
+	if expected.BindingId != actual.BindingId {
+		t.Errorf("Expected field BindingId to be %#v, got %#v", expected.BindingId, actual.BindingId)
 	}
 
 	if expected.OtherDetails != actual.OtherDetails {
 		t.Errorf("Expected field OtherDetails to be %#v, got %#v", expected.OtherDetails, actual.OtherDetails)
 	}
 
-	if expected.PlanId != actual.PlanId {
-		t.Errorf("Expected field PlanId to be %#v, got %#v", expected.PlanId, actual.PlanId)
-	}
-
 	if expected.ServiceId != actual.ServiceId {
 		t.Errorf("Expected field ServiceId to be %#v, got %#v", expected.ServiceId, actual.ServiceId)
 	}
 
-	if expected.SpaceGuid != actual.SpaceGuid {
-		t.Errorf("Expected field SpaceGuid to be %#v, got %#v", expected.SpaceGuid, actual.SpaceGuid)
-	}
-
-	if expected.Url != actual.Url {
-		t.Errorf("Expected field Url to be %#v, got %#v", expected.Url, actual.Url)
+	if expected.ServiceInstanceId != actual.ServiceInstanceId {
+		t.Errorf("Expected field ServiceInstanceId to be %#v, got %#v", expected.ServiceInstanceId, actual.ServiceInstanceId)
 	}
 
 }
 
-func TestSqlDatastore_ServiceInstanceDetailsDAO(t *testing.T) {
+func TestSqlDatastore_ServiceBindingCredentialsDAO(t *testing.T) {
 	ds := newInMemoryDatastore(t)
-	testPk, instance := createServiceInstanceDetailsInstance()
+	testPk, instance := createServiceBindingCredentialsInstance()
 	testCtx := context.Background()
 
 	// on startup, there should be no objects to find or delete
-	exists, err := ds.ExistsServiceInstanceDetailsById(testCtx, testPk)
+	exists, err := ds.ExistsServiceBindingCredentialsById(testCtx, testPk)
 	ensureExistance(t, false, exists, err)
 
-	if _, err := ds.GetServiceInstanceDetailsById(testCtx, testPk); err != gorm.ErrRecordNotFound {
+	if _, err := ds.GetServiceBindingCredentialsById(testCtx, testPk); err != gorm.ErrRecordNotFound {
 		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing PK got %v", err)
 	}
 
 	// Should be able to create the item
 	beforeCreation := time.Now()
-	if err := ds.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
+	if err := ds.CreateServiceBindingCredentials(testCtx, &instance); err != nil {
 		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
 	}
 	afterCreation := time.Now()
 
 	// after creation we should be able to get the item
-	ret, err := ds.GetServiceInstanceDetailsById(testCtx, testPk)
+	ret, err := ds.GetServiceBindingCredentialsById(testCtx, testPk)
 	if err != nil {
 		t.Errorf("Expected no error trying to get saved item, got: %v", err)
 	}
@@ -128,10 +109,10 @@ func TestSqlDatastore_ServiceInstanceDetailsDAO(t *testing.T) {
 	}
 
 	// Ensure non-gorm fields were deserialized correctly
-	ensureServiceInstanceDetailsFieldsMatch(t, &instance, ret)
+	ensureServiceBindingCredentialsFieldsMatch(t, &instance, ret)
 
 	// we should be able to update the item and it will have a new updated time
-	if err := ds.SaveServiceInstanceDetails(testCtx, ret); err != nil {
+	if err := ds.SaveServiceBindingCredentials(testCtx, ret); err != nil {
 		t.Errorf("Expected no error trying to get update %#v , got: %v", ret, err)
 	}
 
@@ -140,31 +121,31 @@ func TestSqlDatastore_ServiceInstanceDetailsDAO(t *testing.T) {
 	}
 
 	// after deleting the item we should not be able to get it
-	if err := ds.DeleteServiceInstanceDetailsById(testCtx, testPk); err != nil {
+	if err := ds.DeleteServiceBindingCredentialsById(testCtx, testPk); err != nil {
 		t.Errorf("Expected no error when deleting by pk got: %v", err)
 	}
 
-	if _, err := ds.GetServiceInstanceDetailsById(testCtx, testPk); err != gorm.ErrRecordNotFound {
+	if _, err := ds.GetServiceBindingCredentialsById(testCtx, testPk); err != gorm.ErrRecordNotFound {
 		t.Errorf("Expected ErrRecordNotFound after delete but got %v", err)
 	}
 }
-func TestSqlDatastore_GetServiceInstanceDetailsById(t *testing.T) {
+func TestSqlDatastore_GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(t *testing.T) {
 	ds := newInMemoryDatastore(t)
-	_, instance := createServiceInstanceDetailsInstance()
+	_, instance := createServiceBindingCredentialsInstance()
 	testCtx := context.Background()
 
-	if _, err := ds.GetServiceInstanceDetailsById(testCtx, instance.ID); err != gorm.ErrRecordNotFound {
+	if _, err := ds.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(testCtx, instance.ServiceInstanceId, instance.BindingId); err != gorm.ErrRecordNotFound {
 		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing record got %v", err)
 	}
 
 	beforeCreation := time.Now()
-	if err := ds.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
+	if err := ds.CreateServiceBindingCredentials(testCtx, &instance); err != nil {
 		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
 	}
 	afterCreation := time.Now()
 
 	// after creation we should be able to get the item
-	ret, err := ds.GetServiceInstanceDetailsById(testCtx, instance.ID)
+	ret, err := ds.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(testCtx, instance.ServiceInstanceId, instance.BindingId)
 	if err != nil {
 		t.Errorf("Expected no error trying to get saved item, got: %v", err)
 	}
@@ -178,82 +159,41 @@ func TestSqlDatastore_GetServiceInstanceDetailsById(t *testing.T) {
 	}
 
 	// Ensure non-gorm fields were deserialized correctly
-	ensureServiceInstanceDetailsFieldsMatch(t, &instance, ret)
+	ensureServiceBindingCredentialsFieldsMatch(t, &instance, ret)
 }
 
-func TestSqlDatastore_ExistsServiceInstanceDetailsById(t *testing.T) {
+func TestSqlDatastore_ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(t *testing.T) {
 	ds := newInMemoryDatastore(t)
-	_, instance := createServiceInstanceDetailsInstance()
+	_, instance := createServiceBindingCredentialsInstance()
 	testCtx := context.Background()
 
-	exists, err := ds.ExistsServiceInstanceDetailsById(testCtx, instance.ID)
+	exists, err := ds.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(testCtx, instance.ServiceInstanceId, instance.BindingId)
 	ensureExistance(t, false, exists, err)
 
-	if err := ds.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
+	if err := ds.CreateServiceBindingCredentials(testCtx, &instance); err != nil {
 		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
 	}
 
-	exists, err = ds.ExistsServiceInstanceDetailsById(testCtx, instance.ID)
+	exists, err = ds.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(testCtx, instance.ServiceInstanceId, instance.BindingId)
 	ensureExistance(t, true, exists, err)
 
-	if err := ds.DeleteServiceInstanceDetails(testCtx, &instance); err != nil {
+	if err := ds.DeleteServiceBindingCredentials(testCtx, &instance); err != nil {
 		t.Errorf("Expected no error when deleting by pk got: %v", err)
 	}
 
 	// we should be able to see that it was soft-deleted
-	exists, err = ds.ExistsServiceInstanceDetailsById(testCtx, instance.ID)
+	exists, err = ds.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(testCtx, instance.ServiceInstanceId, instance.BindingId)
 	ensureExistance(t, false, exists, err)
 }
-
-
-func createServiceBindingCredentialsInstance() (uint, models.ServiceBindingCredentials) {
-	testPk := uint(42)
-
-	instance := models.ServiceBindingCredentials{}
-	instance.ID = testPk
-	instance.BindingId = "0000-0000-0000"
-	instance.OtherDetails = "{\"some\":[\"json\",\"blob\",\"here\"]}"
-	instance.ServiceId = "1111-1111-1111"
-	instance.ServiceInstanceId = "2222-2222-2222"
-
-
-	return testPk, instance
-}
-
-func ensureServiceBindingCredentialsFieldsMatch(t *testing.T, expected, actual *models.ServiceBindingCredentials) {
-
-	if expected.BindingId != actual.BindingId {
-		t.Errorf("Expected field BindingId to be %#v, got %#v", expected.BindingId, actual.BindingId)
-	}
-
-	if expected.OtherDetails != actual.OtherDetails {
-		t.Errorf("Expected field OtherDetails to be %#v, got %#v", expected.OtherDetails, actual.OtherDetails)
-	}
-
-	if expected.ServiceId != actual.ServiceId {
-		t.Errorf("Expected field ServiceId to be %#v, got %#v", expected.ServiceId, actual.ServiceId)
-	}
-
-	if expected.ServiceInstanceId != actual.ServiceInstanceId {
-		t.Errorf("Expected field ServiceInstanceId to be %#v, got %#v", expected.ServiceInstanceId, actual.ServiceInstanceId)
-	}
-
-}
-
-func TestSqlDatastore_ServiceBindingCredentialsDAO(t *testing.T) {
+func TestSqlDatastore_GetServiceBindingCredentialsByBindingId(t *testing.T) {
 	ds := newInMemoryDatastore(t)
-	testPk, instance := createServiceBindingCredentialsInstance()
+	_, instance := createServiceBindingCredentialsInstance()
 	testCtx := context.Background()
 
-	// on startup, there should be no objects to find or delete
-	exists, err := ds.ExistsServiceBindingCredentialsById(testCtx, testPk)
-	ensureExistance(t, false, exists, err)
-
-	if _, err := ds.GetServiceBindingCredentialsById(testCtx, testPk); err != gorm.ErrRecordNotFound {
-		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing PK got %v", err)
+	if _, err := ds.GetServiceBindingCredentialsByBindingId(testCtx, instance.BindingId); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing record got %v", err)
 	}
 
-	// Should be able to create the item
 	beforeCreation := time.Now()
 	if err := ds.CreateServiceBindingCredentials(testCtx, &instance); err != nil {
 		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
@@ -261,7 +201,7 @@ func TestSqlDatastore_ServiceBindingCredentialsDAO(t *testing.T) {
 	afterCreation := time.Now()
 
 	// after creation we should be able to get the item
-	ret, err := ds.GetServiceBindingCredentialsById(testCtx, testPk)
+	ret, err := ds.GetServiceBindingCredentialsByBindingId(testCtx, instance.BindingId)
 	if err != nil {
 		t.Errorf("Expected no error trying to get saved item, got: %v", err)
 	}
@@ -276,31 +216,37 @@ func TestSqlDatastore_ServiceBindingCredentialsDAO(t *testing.T) {
 
 	// Ensure non-gorm fields were deserialized correctly
 	ensureServiceBindingCredentialsFieldsMatch(t, &instance, ret)
+}
 
-	// we should be able to update the item and it will have a new updated time
-	if err := ds.SaveServiceBindingCredentials(testCtx, ret); err != nil {
-		t.Errorf("Expected no error trying to get update %#v , got: %v", ret, err)
-	}
+func TestSqlDatastore_ExistsServiceBindingCredentialsByBindingId(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	_, instance := createServiceBindingCredentialsInstance()
+	testCtx := context.Background()
 
-	if !ret.UpdatedAt.After(ret.CreatedAt) {
-		t.Errorf("Expected update time to be after create time after update, got update: %#v create: %#v", ret.UpdatedAt, ret.CreatedAt)
+	exists, err := ds.ExistsServiceBindingCredentialsByBindingId(testCtx, instance.BindingId)
+	ensureExistance(t, false, exists, err)
+
+	if err := ds.CreateServiceBindingCredentials(testCtx, &instance); err != nil {
+		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
 	}
 
-	// after deleting the item we should not be able to get it
-	if err := ds.DeleteServiceBindingCredentialsById(testCtx, testPk); err != nil {
+	exists, err = ds.ExistsServiceBindingCredentialsByBindingId(testCtx, instance.BindingId)
+	ensureExistance(t, true, exists, err)
+
+	if err := ds.DeleteServiceBindingCredentials(testCtx, &instance); err != nil {
 		t.Errorf("Expected no error when deleting by pk got: %v", err)
 	}
 
-	if _, err := ds.GetServiceBindingCredentialsById(testCtx, testPk); err != gorm.ErrRecordNotFound {
-		t.Errorf("Expected ErrRecordNotFound after delete but got %v", err)
-	}
+	// we should be able to see that it was soft-deleted
+	exists, err = ds.ExistsServiceBindingCredentialsByBindingId(testCtx, instance.BindingId)
+	ensureExistance(t, false, exists, err)
 }
-func TestSqlDatastore_GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(t *testing.T) {
+func TestSqlDatastore_GetServiceBindingCredentialsById(t *testing.T) {
 	ds := newInMemoryDatastore(t)
 	_, instance := createServiceBindingCredentialsInstance()
 	testCtx := context.Background()
 
-	if _, err := ds.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(testCtx, instance.ServiceInstanceId, instance.BindingId); err != gorm.ErrRecordNotFound {
+	if _, err := ds.GetServiceBindingCredentialsById(testCtx, instance.ID); err != gorm.ErrRecordNotFound {
 		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing record got %v", err)
 	}
 
@@ -311,7 +257,7 @@ func TestSqlDatastore_GetServiceBindingCredentialsByServiceInstanceIdAndBindingI
 	afterCreation := time.Now()
 
 	// after creation we should be able to get the item
-	ret, err := ds.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(testCtx, instance.ServiceInstanceId, instance.BindingId)
+	ret, err := ds.GetServiceBindingCredentialsById(testCtx, instance.ID)
 	if err != nil {
 		t.Errorf("Expected no error trying to get saved item, got: %v", err)
 	}
@@ -328,19 +274,19 @@ func TestSqlDatastore_GetServiceBindingCredentialsByServiceInstanceIdAndBindingI
 	ensureServiceBindingCredentialsFieldsMatch(t, &instance, ret)
 }
 
-func TestSqlDatastore_ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(t *testing.T) {
+func TestSqlDatastore_ExistsServiceBindingCredentialsById(t *testing.T) {
 	ds := newInMemoryDatastore(t)
 	_, instance := createServiceBindingCredentialsInstance()
 	testCtx := context.Background()
 
-	exists, err := ds.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(testCtx, instance.ServiceInstanceId, instance.BindingId)
+	exists, err := ds.ExistsServiceBindingCredentialsById(testCtx, instance.ID)
 	ensureExistance(t, false, exists, err)
 
 	if err := ds.CreateServiceBindingCredentials(testCtx, &instance); err != nil {
 		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
 	}
 
-	exists, err = ds.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(testCtx, instance.ServiceInstanceId, instance.BindingId)
+	exists, err = ds.ExistsServiceBindingCredentialsById(testCtx, instance.ID)
 	ensureExistance(t, true, exists, err)
 
 	if err := ds.DeleteServiceBindingCredentials(testCtx, &instance); err != nil {
@@ -348,26 +294,92 @@ func TestSqlDatastore_ExistsServiceBindingCredentialsByServiceInstanceIdAndBindi
 	}
 
 	// we should be able to see that it was soft-deleted
-	exists, err = ds.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(testCtx, instance.ServiceInstanceId, instance.BindingId)
+	exists, err = ds.ExistsServiceBindingCredentialsById(testCtx, instance.ID)
 	ensureExistance(t, false, exists, err)
 }
-func TestSqlDatastore_GetServiceBindingCredentialsByBindingId(t *testing.T) {
+
+
+func createServiceInstanceDetailsInstance() (string, models.ServiceInstanceDetails) {
+	testPk := string(42)
+
+	instance := models.ServiceInstanceDetails{}
+	instance.ID = testPk
+	instance.Context = "{\"platform\":\"cloudfoundry\",\"space_name\":\"dev\"}"
+	instance.Location = "loc"
+	instance.Name = "Hello"
+	instance.OrganizationGuid = "1111-1111-1111"
+	instance.OtherDetails = "{\"some\":[\"json\",\"blob\",\"here\"]}"
+	instance.PlanId = "planid"
+	instance.ServiceId = "123-456-7890"
+	instance.SpaceGuid = "0000-0000-0000"
+	instance.Url = "https://google.com"
+
+
+	return testPk, instance
+}
+
+func ensureServiceInstanceDetailsFieldsMatch(t *testing.T, expected, actual *models.ServiceInstanceDetails) {
+
+	if expected.Context != actual.Context {
+		t.Errorf("Expected field Context to be %#v, got %#v", expected.Context, actual.Context)
+	}
+
+	if expected.Location != actual.Location {
+		t.Errorf("Expected field Location to be %#v, got %#v", expected.Location, actual.Location)
+	}
+
+	if expected.Name != actual.Name {
+		t.Errorf("Expected field Name to be %#v, got %#v", expected.Name, actual.Name)
+	}
+
+	if expected.OrganizationGuid != actual.OrganizationGuid {
+		t.Errorf("Expected field OrganizationGuid to be %#v, got %#v", expected.OrganizationGuid, actual.OrganizationGuid)
+	}
+
+	if expected.OtherDetails != actual.OtherDetails {
+		t.Errorf("Expected field OtherDetails to be %#v, got %#v", expected.OtherDetails, actual.OtherDetails)
+	}
+
+	if expected.PlanId != actual.PlanId {
+		t.Errorf("Expected field PlanId to be %#v, got %#v", expected.PlanId, actual.PlanId)
+	}
+
+	if expected.ServiceId != actual.ServiceId {
+		t.Errorf("Expected field ServiceId to be %#v, got %#v", expected.ServiceId, actual.ServiceId)
+	}
+
+	if expected.SpaceGuid != actual.SpaceGuid {
+		t.Errorf("Expected field SpaceGuid to be %#v, got %#v", expected.SpaceGuid, actual.SpaceGuid)
+	}
+
+	if expected.Url != actual.Url {
+		t.Errorf("Expected field Url to be %#v, got %#v", expected.Url, actual.Url)
+	}
+
+}
+
+func TestSqlDatastore_ServiceInstanceDetailsDAO(t *testing.T) {
 	ds := newInMemoryDatastore(t)
-	_, instance := createServiceBindingCredentialsInstance()
+	testPk, instance := createServiceInstanceDetailsInstance()
 	testCtx := context.Background()
 
-	if _, err := ds.GetServiceBindingCredentialsByBindingId(testCtx, instance.BindingId); err != gorm.ErrRecordNotFound {
-		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing record got %v", err)
+	// on startup, there should be no objects to find or delete
+	exists, err := ds.ExistsServiceInstanceDetailsById(testCtx, testPk)
+	ensureExistance(t, false, exists, err)
+
+	if _, err := ds.GetServiceInstanceDetailsById(testCtx, testPk); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing PK got %v", err)
 	}
 
+	// Should be able to create the item
 	beforeCreation := time.Now()
-	if err := ds.CreateServiceBindingCredentials(testCtx, &instance); err != nil {
+	if err := ds.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
 		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
 	}
 	afterCreation := time.Now()
 
 	// after creation we should be able to get the item
-	ret, err := ds.GetServiceBindingCredentialsByBindingId(testCtx, instance.BindingId)
+	ret, err := ds.GetServiceInstanceDetailsById(testCtx, testPk)
 	if err != nil {
 		t.Errorf("Expected no error trying to get saved item, got: %v", err)
 	}
@@ -381,49 +393,43 @@ func TestSqlDatastore_GetServiceBindingCredentialsByBindingId(t *testing.T) {
 	}
 
 	// Ensure non-gorm fields were deserialized correctly
-	ensureServiceBindingCredentialsFieldsMatch(t, &instance, ret)
-}
-
-func TestSqlDatastore_ExistsServiceBindingCredentialsByBindingId(t *testing.T) {
-	ds := newInMemoryDatastore(t)
-	_, instance := createServiceBindingCredentialsInstance()
-	testCtx := context.Background()
-
-	exists, err := ds.ExistsServiceBindingCredentialsByBindingId(testCtx, instance.BindingId)
-	ensureExistance(t, false, exists, err)
+	ensureServiceInstanceDetailsFieldsMatch(t, &instance, ret)
 
-	if err := ds.CreateServiceBindingCredentials(testCtx, &instance); err != nil {
-		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	// we should be able to update the item and it will have a new updated time
+	if err := ds.SaveServiceInstanceDetails(testCtx, ret); err != nil {
+		t.Errorf("Expected no error trying to get update %#v , got: %v", ret, err)
 	}
 
-	exists, err = ds.ExistsServiceBindingCredentialsByBindingId(testCtx, instance.BindingId)
-	ensureExistance(t, true, exists, err)
+	if !ret.UpdatedAt.After(ret.CreatedAt) {
+		t.Errorf("Expected update time to be after create time after update, got update: %#v create: %#v", ret.UpdatedAt, ret.CreatedAt)
+	}
 
-	if err := ds.DeleteServiceBindingCredentials(testCtx, &instance); err != nil {
+	// after deleting the item we should not be able to get it
+	if err := ds.DeleteServiceInstanceDetailsById(testCtx, testPk); err != nil {
 		t.Errorf("Expected no error when deleting by pk got: %v", err)
 	}
 
-	// we should be able to see that it was soft-deleted
-	exists, err = ds.ExistsServiceBindingCredentialsByBindingId(testCtx, instance.BindingId)
-	ensureExistance(t, false, exists, err)
+	if _, err := ds.GetServiceInstanceDetailsById(testCtx, testPk); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected ErrRecordNotFound after delete but got %v", err)
+	}
 }
-func TestSqlDatastore_GetServiceBindingCredentialsById(t *testing.T) {
+func TestSqlDatastore_GetServiceInstanceDetailsById(t *testing.T) {
 	ds := newInMemoryDatastore(t)
-	_, instance := createServiceBindingCredentialsInstance()
+	_, instance := createServiceInstanceDetailsInstance()
 	testCtx := context.Background()
 
-	if _, err := ds.GetServiceBindingCredentialsById(testCtx, instance.ID); err != gorm.ErrRecordNotFound {
+	if _, err := ds.GetServiceInstanceDetailsById(testCtx, instance.ID); err != gorm.ErrRecordNotFound {
 		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing record got %v", err)
 	}
 
 	beforeCreation := time.Now()
-	if err := ds.CreateServiceBindingCredentials(testCtx, &instance); err != nil {
+	if err := ds.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
 		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
 	}
 	afterCreation := time.Now()
 
 	// after creation we should be able to get the item
-	ret, err := ds.GetServiceBindingCredentialsById(testCtx, instance.ID)
+	ret, err := ds.GetServiceInstanceDetailsById(testCtx, instance.ID)
 	if err != nil {
 		t.Errorf("Expected no error trying to get saved item, got: %v", err)
 	}
@@ -437,30 +443,30 @@ func TestSqlDatastore_GetServiceBindingCredentialsById(t *testing.T) {
 	}
 
 	// Ensure non-gorm fields were deserialized correctly
-	ensureServiceBindingCredentialsFieldsMatch(t, &instance, ret)
+	ensureServiceInstanceDetailsFieldsMatch(t, &instance, ret)
 }
 
-func TestSqlDatastore_ExistsServiceBindingCredentialsById(t *testing.T) {
+func TestSqlDatastore_ExistsServiceInstanceDetailsById(t *testing.T) {
 	ds := newInMemoryDatastore(t)
-	_, instance := createServiceBindingCredentialsInstance()
+	_, instance := createServiceInstanceDetailsInstance()
 	testCtx := context.Background()
 
-	exists, err := ds.ExistsServiceBindingCredentialsById(testCtx, instance.ID)
+	exists, err := ds.ExistsServiceInstanceDetailsById(testCtx, instance.ID)
 	ensureExistance(t, false, exists, err)
 
-	if err := ds.CreateServiceBindingCredentials(testCtx, &instance); err != nil {
+	if err := ds.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
 		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
 	}
 
-	exists, err = ds.ExistsServiceBindingCredentialsById(testCtx, instance.ID)
+	exists, err = ds.ExistsServiceInstanceDetailsById(testCtx, instance.ID)
 	ensureExistance(t, true, exists, err)
 
-	if err := ds.DeleteServiceBindingCredentials(testCtx, &instance); err != nil {
+	if err := ds.DeleteServiceInstanceDetails(testCtx, &instance); err != nil {
 		t.Errorf("Expected no error when deleting by pk got: %v", err)
 	}
 
 	// we should be able to see that it was soft-deleted
-	exists, err = ds.ExistsServiceBindingCredentialsById(testCtx, instance.ID)
+	exists, err = ds.ExistsServiceInstanceDetailsById(testCtx, instance.ID)
 	ensureExistance(t, false, exists, err)
 }
 
@@ -470,6 +476,8 @@ func createProvisionRequestDetailsInstance() (uint, models.ProvisionRequestDetai
 
 	instance := models.ProvisionRequestDetails{}
 	instance.ID = testPk
+	instance.Context = "{\"platform\":\"kubernetes\"}"
+	instance.ParametersHash = "c0ffee"
 	instance.RequestDetails = "{\"some\":[\"json\",\"blob\",\"here\"]}"
 	instance.ServiceInstanceId = "2222-2222-2222"
 
@@ -479,6 +487,14 @@ func createProvisionRequestDetailsInstance() (uint, models.ProvisionRequestDetai
 
 func ensureProvisionRequestDetailsFieldsMatch(t *testing.T, expected, actual *models.ProvisionRequestDetails) {
 
+	if expected.Context != actual.Context {
+		t.Errorf("Expected field Context to be %#v, got %#v", expected.Context, actual.Context)
+	}
+
+	if expected.ParametersHash != actual.ParametersHash {
+		t.Errorf("Expected field ParametersHash to be %#v, got %#v", expected.ParametersHash, actual.ParametersHash)
+	}
+
 	if expected.RequestDetails != actual.RequestDetails {
 		t.Errorf("Expected field RequestDetails to be %#v, got %#v", expected.RequestDetails, actual.RequestDetails)
 	}
@@ -544,7 +560,6 @@ func TestSqlDatastore_ProvisionRequestDetailsDAO(t *testing.T) {
 		t.Errorf("Expected ErrRecordNotFound after delete but got %v", err)
 	}
 }
-
 func TestSqlDatastore_GetProvisionRequestDetailsById(t *testing.T) {
 	ds := newInMemoryDatastore(t)
 	_, instance := createProvisionRequestDetailsInstance()
@@ -583,18 +598,145 @@ func TestSqlDatastore_ExistsProvisionRequestDetailsById(t *testing.T) {
 	_, instance := createProvisionRequestDetailsInstance()
 	testCtx := context.Background()
 
-	if _, err := ds.GetProvisionRequestDetailsById(testCtx, instance.ID); err != gorm.ErrRecordNotFound {
+	exists, err := ds.ExistsProvisionRequestDetailsById(testCtx, instance.ID)
+	ensureExistance(t, false, exists, err)
+
+	if err := ds.CreateProvisionRequestDetails(testCtx, &instance); err != nil {
+		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	}
+
+	exists, err = ds.ExistsProvisionRequestDetailsById(testCtx, instance.ID)
+	ensureExistance(t, true, exists, err)
+
+	if err := ds.DeleteProvisionRequestDetails(testCtx, &instance); err != nil {
+		t.Errorf("Expected no error when deleting by pk got: %v", err)
+	}
+
+	// the record should be gone rather than soft-deleted
+	count := 0
+	if err := ds.db.Unscoped().Model(&models.ProvisionRequestDetails{}).Count(&count).Error; err != nil {
+		t.Fatalf("Expected no error counting records, got: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected the record to be deleted, found %d", count)
+	}
+
+	exists, err = ds.ExistsProvisionRequestDetailsById(testCtx, instance.ID)
+	ensureExistance(t, false, exists, err)
+}
+
+
+func createCloudOperationInstance() (uint, models.CloudOperation) {
+	testPk := uint(42)
+
+	instance := models.CloudOperation{}
+	instance.ID = testPk
+	instance.Name = "operation-1"
+	instance.OperationType = "insert"
+	instance.ServiceInstanceId = "2222-2222-2222"
+	instance.Status = "RUNNING"
+	instance.TargetId = "1234567890"
+
+
+	return testPk, instance
+}
+
+func ensureCloudOperationFieldsMatch(t *testing.T, expected, actual *models.CloudOperation) {
+
+	if expected.Name != actual.Name {
+		t.Errorf("Expected field Name to be %#v, got %#v", expected.Name, actual.Name)
+	}
+
+	if expected.OperationType != actual.OperationType {
+		t.Errorf("Expected field OperationType to be %#v, got %#v", expected.OperationType, actual.OperationType)
+	}
+
+	if expected.ServiceInstanceId != actual.ServiceInstanceId {
+		t.Errorf("Expected field ServiceInstanceId to be %#v, got %#v", expected.ServiceInstanceId, actual.ServiceInstanceId)
+	}
+
+	if expected.Status != actual.Status {
+		t.Errorf("Expected field Status to be %#v, got %#v", expected.Status, actual.Status)
+	}
+
+	if expected.TargetId != actual.TargetId {
+		t.Errorf("Expected field TargetId to be %#v, got %#v", expected.TargetId, actual.TargetId)
+	}
+
+}
+
+func TestSqlDatastore_CloudOperationDAO(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	testPk, instance := createCloudOperationInstance()
+	testCtx := context.Background()
+
+	// on startup, there should be no objects to find or delete
+	exists, err := ds.ExistsCloudOperationById(testCtx, testPk)
+	ensureExistance(t, false, exists, err)
+
+	if _, err := ds.GetCloudOperationById(testCtx, testPk); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing PK got %v", err)
+	}
+
+	// Should be able to create the item
+	beforeCreation := time.Now()
+	if err := ds.CreateCloudOperation(testCtx, &instance); err != nil {
+		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	}
+	afterCreation := time.Now()
+
+	// after creation we should be able to get the item
+	ret, err := ds.GetCloudOperationById(testCtx, testPk)
+	if err != nil {
+		t.Errorf("Expected no error trying to get saved item, got: %v", err)
+	}
+
+	if ret.CreatedAt.Before(beforeCreation) || ret.CreatedAt.After(afterCreation) {
+		t.Errorf("Expected creation time to be between  %v and %v got %v", beforeCreation, afterCreation, ret.CreatedAt)
+	}
+
+	if !ret.UpdatedAt.Equal(ret.CreatedAt) {
+		t.Errorf("Expected initial update time to equal creation time, but got update: %v, create: %v", ret.UpdatedAt, ret.CreatedAt)
+	}
+
+	// Ensure non-gorm fields were deserialized correctly
+	ensureCloudOperationFieldsMatch(t, &instance, ret)
+
+	// we should be able to update the item and it will have a new updated time
+	if err := ds.SaveCloudOperation(testCtx, ret); err != nil {
+		t.Errorf("Expected no error trying to get update %#v , got: %v", ret, err)
+	}
+
+	if !ret.UpdatedAt.After(ret.CreatedAt) {
+		t.Errorf("Expected update time to be after create time after update, got update: %#v create: %#v", ret.UpdatedAt, ret.CreatedAt)
+	}
+
+	// after deleting the item we should not be able to get it
+	if err := ds.DeleteCloudOperationById(testCtx, testPk); err != nil {
+		t.Errorf("Expected no error when deleting by pk got: %v", err)
+	}
+
+	if _, err := ds.GetCloudOperationById(testCtx, testPk); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected ErrRecordNotFound after delete but got %v", err)
+	}
+}
+func TestSqlDatastore_GetCloudOperationById(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	_, instance := createCloudOperationInstance()
+	testCtx := context.Background()
+
+	if _, err := ds.GetCloudOperationById(testCtx, instance.ID); err != gorm.ErrRecordNotFound {
 		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing record got %v", err)
 	}
 
 	beforeCreation := time.Now()
-	if err := ds.CreateProvisionRequestDetails(testCtx, &instance); err != nil {
+	if err := ds.CreateCloudOperation(testCtx, &instance); err != nil {
 		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
 	}
 	afterCreation := time.Now()
 
 	// after creation we should be able to get the item
-	ret, err := ds.GetProvisionRequestDetailsByInstanceId(testCtx, instance.ServiceInstanceId)
+	ret, err := ds.GetCloudOperationById(testCtx, instance.ID)
 	if err != nil {
 		t.Errorf("Expected no error trying to get saved item, got: %v", err)
 	}
@@ -608,33 +750,75 @@ func TestSqlDatastore_ExistsProvisionRequestDetailsById(t *testing.T) {
 	}
 
 	// Ensure non-gorm fields were deserialized correctly
-	ensureProvisionRequestDetailsFieldsMatch(t, &instance, ret)
+	ensureCloudOperationFieldsMatch(t, &instance, ret)
 }
 
-func TestSqlDatastore_GetsProvisionRequestDetailsByInstanceId(t *testing.T) {
+func TestSqlDatastore_ExistsCloudOperationById(t *testing.T) {
 	ds := newInMemoryDatastore(t)
-	_, instance := createProvisionRequestDetailsInstance()
+	_, instance := createCloudOperationInstance()
 	testCtx := context.Background()
 
-	exists, err := ds.ExistsProvisionRequestDetailsById(testCtx, instance.ID)
+	exists, err := ds.ExistsCloudOperationById(testCtx, instance.ID)
 	ensureExistance(t, false, exists, err)
 
-	if err := ds.CreateProvisionRequestDetails(testCtx, &instance); err != nil {
+	if err := ds.CreateCloudOperation(testCtx, &instance); err != nil {
 		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
 	}
 
-	exists, err = ds.ExistsProvisionRequestDetailsById(testCtx, instance.ID)
+	exists, err = ds.ExistsCloudOperationById(testCtx, instance.ID)
 	ensureExistance(t, true, exists, err)
 
-	if err := ds.DeleteProvisionRequestDetails(testCtx, &instance); err != nil {
+	if err := ds.DeleteCloudOperation(testCtx, &instance); err != nil {
 		t.Errorf("Expected no error when deleting by pk got: %v", err)
 	}
 
-	// we should be able to see that it was soft-deleted
-	exists, err = ds.ExistsProvisionRequestDetailsById(testCtx, instance.ID)
+	// the record should be gone rather than soft-deleted
+	count := 0
+	if err := ds.db.Unscoped().Model(&models.CloudOperation{}).Count(&count).Error; err != nil {
+		t.Fatalf("Expected no error counting records, got: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected the record to be deleted, found %d", count)
+	}
+
+	exists, err = ds.ExistsCloudOperationById(testCtx, instance.ID)
 	ensureExistance(t, false, exists, err)
 }
 
+func TestSqlDatastore_FindAllCloudOperationByServiceInstanceId(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	_, first := createCloudOperationInstance()
+	_, second := createCloudOperationInstance()
+	second.ID = uint(43)
+	testCtx := context.Background()
+
+	records, err := ds.FindAllCloudOperationByServiceInstanceId(testCtx, first.ServiceInstanceId)
+	if err != nil {
+		t.Errorf("Expected no error listing records, got: %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Expected no records before creation, got %d", len(records))
+	}
+
+	for _, instance := range []*models.CloudOperation{&first, &second} {
+		if err := ds.CreateCloudOperation(testCtx, instance); err != nil {
+			t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
+		}
+	}
+
+	records, err = ds.FindAllCloudOperationByServiceInstanceId(testCtx, first.ServiceInstanceId)
+	if err != nil {
+		t.Errorf("Expected no error listing records, got: %v", err)
+	}
+	if len(records) != 2 || records[0].ID != first.ID || records[1].ID != second.ID {
+		t.Fatalf("Expected records %v and %v in creation order, got %#v", first.ID, second.ID, records)
+	}
+
+	// Ensure non-gorm fields were deserialized correctly
+	ensureCloudOperationFieldsMatch(t, &first, &records[0])
+}
+
+
 func createTerraformDeploymentInstance() (string, models.TerraformDeployment) {
 	testPk := string(42)
 
@@ -0,0 +1,209 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/fakes"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// TestDatastore checks the in-memory datastore behaves like the SQL one.
+func TestDatastore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datastore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := db_service.OpenSqlite(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db_service.RunMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]db_service.Datastore{
+		"sql":       db_service.NewSqlDatastore(db),
+		"in-memory": fakes.NewInMemoryDatastore(),
+	}
+
+	results := map[string][]interface{}{}
+	for name, store := range stores {
+		results[name] = exerciseDatastore(t, store)
+	}
+
+	sql, memory := results["sql"], results["in-memory"]
+	for i := range sql {
+		if !reflect.DeepEqual(sql[i], memory[i]) {
+			t.Errorf("Step %d: expected the in-memory datastore to return %#v like the SQL one, got %#v", i, sql[i], memory[i])
+		}
+	}
+}
+
+// exerciseDatastore runs the same operations on a datastore and returns what
+// each one returned with the timestamps and IDs cleared.
+func exerciseDatastore(t *testing.T, store db_service.Datastore) []interface{} {
+	ctx := context.Background()
+	var out []interface{}
+	record := func(values ...interface{}) {
+		for _, value := range values {
+			out = append(out, normalize(value))
+		}
+	}
+	check := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, id := range []string{"instance-b", "instance-a", "instance-c"} {
+		check(store.CreateServiceInstanceDetails(ctx, &models.ServiceInstanceDetails{ID: id, PlanId: "plan", SpaceGuid: "space-" + id}))
+	}
+	record(errors.Is(store.CreateServiceInstanceDetails(ctx, &models.ServiceInstanceDetails{ID: "instance-a"}), db_service.ErrAlreadyExists))
+
+	instance, err := store.GetServiceInstanceDetailsById(ctx, "instance-a")
+	check(err)
+	instance.Name = "renamed"
+	check(store.SaveServiceInstanceDetails(ctx, instance))
+	record(store.GetServiceInstanceDetailsById(ctx, "instance-a"))
+	record(store.ListServiceInstanceDetails(ctx, models.ServiceInstanceDetails{PlanId: "plan"}))
+	record(store.ListServiceInstanceDetails(ctx, models.ServiceInstanceDetails{SpaceGuid: "space-instance-c"}))
+
+	check(store.CreateProvisionRequestDetails(ctx, &models.ProvisionRequestDetails{ServiceInstanceId: "instance-c", RequestDetails: "{}"}))
+	record(store.GetProvisionRequestDetailsByInstanceId(ctx, "instance-c"))
+	record(errors.Is(store.CreateProvisionRequestDetails(ctx, &models.ProvisionRequestDetails{ServiceInstanceId: "instance-c"}), db_service.ErrAlreadyExists))
+	record(store.PurgeDeletedServiceInstance(ctx, "instance-c") != nil)
+	check(store.CreateServiceBindingCredentials(ctx, &models.ServiceBindingCredentials{ServiceInstanceId: "instance-c", BindingId: "binding-c"}))
+	check(store.DeleteServiceInstanceDetailsAndBindingsById(ctx, "instance-c"))
+	record(store.ExistsServiceInstanceDetailsById(ctx, "instance-c"))
+	record(store.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, "instance-c", "binding-c"))
+	record(store.ExistsDeletedServiceInstanceDetailsById(ctx, "instance-c"))
+	_, err = store.GetServiceInstanceDetailsById(ctx, "instance-c")
+	record(gorm.IsRecordNotFoundError(err))
+	check(store.PurgeDeletedServiceInstance(ctx, "instance-c"))
+	record(store.ExistsDeletedServiceInstanceDetailsById(ctx, "instance-c"))
+	_, err = store.GetProvisionRequestDetailsByInstanceId(ctx, "instance-c")
+	record(gorm.IsRecordNotFoundError(err))
+	check(store.CreateServiceBindingCredentials(ctx, &models.ServiceBindingCredentials{ServiceInstanceId: "instance-c", BindingId: "binding-c"}))
+
+	now := time.Now()
+	check(store.CreateServiceBindingCredentials(ctx, &models.ServiceBindingCredentials{ServiceInstanceId: "instance-a", BindingId: "binding-1"}))
+	check(store.CreateServiceBindingCredentials(ctx, &models.ServiceBindingCredentials{ServiceInstanceId: "instance-a", BindingId: "binding-2", RevokedAt: &now}))
+	check(store.CreateServiceBindingCredentials(ctx, &models.ServiceBindingCredentials{ServiceInstanceId: "instance-b", BindingId: "binding-3"}))
+	record(store.ListServiceBindingCredentials(ctx, models.ServiceBindingCredentials{ServiceInstanceId: "instance-a"}))
+	record(store.ListRevokedServiceBindingCredentials(ctx))
+	binding, err := store.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, "instance-a", "binding-1")
+	check(err)
+	binding.OtherDetails = "updated"
+	check(store.SaveServiceBindingCredentials(ctx, binding))
+	record(store.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, "instance-a", "binding-1"))
+	check(store.DeleteServiceBindingCredentials(ctx, binding))
+	record(store.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, "instance-a", "binding-1"))
+	record(store.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, "instance-b", "binding-3"))
+	record(errors.Is(store.CreateServiceBindingCredentials(ctx, &models.ServiceBindingCredentials{ServiceInstanceId: "instance-a", BindingId: "binding-1"}), db_service.ErrAlreadyExists))
+	record(errors.Is(store.CreateServiceBindingCredentials(ctx, &models.ServiceBindingCredentials{ServiceInstanceId: "instance-b", BindingId: "binding-1"}), db_service.ErrAlreadyExists))
+
+	record(store.GetInstanceUpgrade(ctx, "instance-a", "2.0.0"))
+	check(store.SaveInstanceUpgrade(ctx, &models.InstanceUpgrade{ServiceInstanceId: "instance-a", ToVersion: "2.0.0", State: "pending"}))
+	upgrade, err := store.GetInstanceUpgrade(ctx, "instance-a", "2.0.0")
+	check(err)
+	upgrade.State = "approved"
+	check(store.SaveInstanceUpgrade(ctx, upgrade))
+	record(store.GetInstanceUpgrade(ctx, "instance-a", "2.0.0"))
+
+	check(store.RecordInstanceShare(ctx, "instance-a", "org", "space-1"))
+	check(store.RecordInstanceShare(ctx, "instance-a", "org", "space-1"))
+	check(store.DeleteInstanceShares(ctx, "instance-a"))
+
+	record(store.GetInstanceProtection(ctx, "instance-a"))
+	check(store.SaveInstanceProtection(ctx, &models.InstanceProtection{ServiceInstanceId: "instance-a", Protected: true, Reason: "production"}))
+	protection, err := store.GetInstanceProtection(ctx, "instance-a")
+	check(err)
+	protection.Protected = false
+	check(store.SaveInstanceProtection(ctx, protection))
+	record(store.GetInstanceProtection(ctx, "instance-a"))
+
+	record(store.ListPendingJobs(ctx, "tf:instance-a:"))
+	record(store.ListTerraformDeployments(ctx, 0))
+
+	return out
+}
+
+// normalize clears the fields the datastores assign differently.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *models.ServiceInstanceDetails:
+		if v == nil {
+			return v
+		}
+		copied := *v
+		copied.CreatedAt, copied.UpdatedAt = time.Time{}, time.Time{}
+		return copied
+	case []models.ServiceInstanceDetails:
+		var out []interface{}
+		for i := range v {
+			out = append(out, normalize(&v[i]))
+		}
+		return out
+	case *models.ServiceBindingCredentials:
+		if v == nil {
+			return v
+		}
+		return []interface{}{v.ServiceInstanceId, v.BindingId, v.OtherDetails, v.RevokedAt != nil}
+	case []models.ServiceBindingCredentials:
+		var out []interface{}
+		for i := range v {
+			out = append(out, normalize(&v[i]))
+		}
+		return out
+	case *models.ProvisionRequestDetails:
+		if v == nil {
+			return v
+		}
+		return models.ProvisionRequestDetails{ServiceInstanceId: v.ServiceInstanceId, RequestDetails: v.RequestDetails}
+	case *models.InstanceUpgrade:
+		if v == nil {
+			return v
+		}
+		return models.InstanceUpgrade{ServiceInstanceId: v.ServiceInstanceId, ToVersion: v.ToVersion, State: v.State}
+	case *models.InstanceProtection:
+		if v == nil {
+			return v
+		}
+		return models.InstanceProtection{ServiceInstanceId: v.ServiceInstanceId, Protected: v.Protected, Reason: v.Reason}
+	case []models.Job:
+		return len(v)
+	case []models.TerraformDeployment:
+		return len(v)
+	case error:
+		return v.Error()
+	}
+
+	return value
+}
@@ -17,13 +17,14 @@
 package db_service
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"code.cloudfoundry.org/lager"
 	"github.com/jinzhu/gorm"
-
-	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
 )
 
 var DbConnection *gorm.DB
@@ -44,9 +45,81 @@ func New(logger lager.Logger) *gorm.DB {
 // instantiated in New(). In the future, all accesses of DbConnection will be
 // done through SqlDatastore and it will become the globally shared instance.
 func defaultDatastore() *SqlDatastore {
-	return &SqlDatastore{db: DbConnection}
+	return NewSqlDatastore(DbConnection)
 }
 
+// SqlDatastore reads and writes the broker's records in a database. Its
+// methods mirror the package-level functions, which use DbConnection.
 type SqlDatastore struct {
 	db *gorm.DB
 }
+
+//go:generate counterfeiter -o ./fakes/datastore.go . Datastore
+
+// Datastore holds the records the service broker reads and writes while
+// serving the OSB API. SqlDatastore implements it with a database, and the
+// fakes package has implementations for tests.
+type Datastore interface {
+	CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error
+	SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error
+	DeleteServiceInstanceDetailsById(ctx context.Context, id string) error
+	DeleteServiceInstanceDetailsAndBindingsById(ctx context.Context, id string) error
+	GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error)
+	ExistsServiceInstanceDetailsById(ctx context.Context, id string) (bool, error)
+	ListServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) ([]models.ServiceInstanceDetails, error)
+	CountServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) (int, error)
+	ExistsDeletedServiceInstanceDetailsById(ctx context.Context, id string) (bool, error)
+	PurgeDeletedServiceInstance(ctx context.Context, id string) error
+
+	CreateServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error
+	SaveServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error
+	DeleteServiceBindingCredentials(ctx context.Context, record *models.ServiceBindingCredentials) error
+	GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (*models.ServiceBindingCredentials, error)
+	ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (bool, error)
+	ListServiceBindingCredentials(ctx context.Context, conditions models.ServiceBindingCredentials) ([]models.ServiceBindingCredentials, error)
+	ListRevokedServiceBindingCredentials(ctx context.Context) ([]models.ServiceBindingCredentials, error)
+
+	CreateProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error
+	SaveProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error
+	GetProvisionRequestDetailsByInstanceId(ctx context.Context, instanceId string) (*models.ProvisionRequestDetails, error)
+
+	GetInstanceUpgrade(ctx context.Context, instanceId, toVersion string) (*models.InstanceUpgrade, error)
+	SaveInstanceUpgrade(ctx context.Context, upgrade *models.InstanceUpgrade) error
+
+	RecordInstanceShare(ctx context.Context, instanceId, organizationGuid, spaceGuid string) error
+	DeleteInstanceShares(ctx context.Context, instanceId string) error
+
+	GetInstanceProtection(ctx context.Context, instanceId string) (*models.InstanceProtection, error)
+	SaveInstanceProtection(ctx context.Context, protection *models.InstanceProtection) error
+
+	GetTerraformDeploymentById(ctx context.Context, id string) (*models.TerraformDeployment, error)
+	SaveTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error
+	ListTerraformDeployments(ctx context.Context, limit int) ([]models.TerraformDeployment, error)
+	ListJobs(ctx context.Context, target string) ([]models.Job, error)
+	ListPendingJobs(ctx context.Context, targetPrefix string) ([]models.Job, error)
+
+	AcquireLeaderLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLeaderLease(ctx context.Context, name, holder string) error
+}
+
+var _ Datastore = (*SqlDatastore)(nil)
+
+// NewSqlDatastore creates a datastore using db, which should already be
+// migrated with RunMigrations. Programs embedding the broker can use it
+// instead of New to connect to a database of their choosing.
+func NewSqlDatastore(db *gorm.DB) *SqlDatastore {
+	return &SqlDatastore{db: db}
+}
+
+// currentTimePlus is an expression for the database's current time plus the
+// duration, rounded up to a second. Leases and job delays are compared
+// against the database's clock rather than the instances' so they don't have
+// to agree on the time.
+func (ds *SqlDatastore) currentTimePlus(d time.Duration) interface{} {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	if ds.db.Dialect().GetName() == DbTypeMysql {
+		return gorm.Expr("CURRENT_TIMESTAMP + INTERVAL ? SECOND", seconds)
+	}
+
+	return gorm.Expr("datetime(CURRENT_TIMESTAMP, ?)", fmt.Sprintf("%+d seconds", seconds))
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// ExistsDeletedServiceInstanceDetailsById checks if a deprovisioned instance
+// with the ID is still in the database as a soft-deleted row.
+func ExistsDeletedServiceInstanceDetailsById(ctx context.Context, id string) (bool, error) {
+	return defaultDatastore().ExistsDeletedServiceInstanceDetailsById(ctx, id)
+}
+
+// ExistsDeletedServiceInstanceDetailsById checks if a deprovisioned instance
+// with the ID is still in the database as a soft-deleted row.
+func (ds *SqlDatastore) ExistsDeletedServiceInstanceDetailsById(ctx context.Context, id string) (bool, error) {
+	defer traceOperation(ctx, "ExistsDeletedServiceInstanceDetailsById")()
+	count := 0
+	err := ds.db.Unscoped().Model(&models.ServiceInstanceDetails{}).Where("id = ? AND deleted_at IS NOT NULL", id).Count(&count).Error
+	return count > 0, err
+}
+
+// PurgeDeletedServiceInstance permanently deletes the soft-deleted row of a
+// deprovisioned instance and the records referencing it so the ID can be
+// used again. It's an error to purge an instance that hasn't been
+// deprovisioned.
+func PurgeDeletedServiceInstance(ctx context.Context, id string) error {
+	return defaultDatastore().PurgeDeletedServiceInstance(ctx, id)
+}
+
+// PurgeDeletedServiceInstance permanently deletes the soft-deleted row of a
+// deprovisioned instance and the records referencing it so the ID can be
+// used again. It's an error to purge an instance that hasn't been
+// deprovisioned.
+func (ds *SqlDatastore) PurgeDeletedServiceInstance(ctx context.Context, id string) error {
+	defer traceOperation(ctx, "PurgeDeletedServiceInstance")()
+	tx := ds.db.Begin()
+	live := 0
+	if err := tx.Model(&models.ServiceInstanceDetails{}).Where("id = ?", id).Count(&live).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if live > 0 {
+		tx.Rollback()
+		return fmt.Errorf("instance %s hasn't been deprovisioned", id)
+	}
+
+	if err := deleteServiceInstanceDependents(tx, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).Delete(&models.ServiceInstanceDetails{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteServiceInstanceDetailsAndBindingsById soft-deletes a deprovisioned
+// instance along with any bindings it still has so none are left referencing
+// a deleted instance.
+func DeleteServiceInstanceDetailsAndBindingsById(ctx context.Context, id string) error {
+	return defaultDatastore().DeleteServiceInstanceDetailsAndBindingsById(ctx, id)
+}
+
+// DeleteServiceInstanceDetailsAndBindingsById soft-deletes a deprovisioned
+// instance along with any bindings it still has so none are left referencing
+// a deleted instance.
+func (ds *SqlDatastore) DeleteServiceInstanceDetailsAndBindingsById(ctx context.Context, id string) error {
+	defer traceOperation(ctx, "DeleteServiceInstanceDetailsAndBindingsById")()
+	tx := ds.db.Begin()
+	if err := tx.Where("service_instance_id = ?", id).Delete(&models.ServiceBindingCredentials{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("id = ?", id).Delete(&models.ServiceInstanceDetails{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// instanceDependents are the records referencing a service instance by its
+// service_instance_id, in the order they're deleted so the records with
+// foreign keys to the instance go first.
+var instanceDependents = []interface{}{
+	&models.CloudOperation{},
+	&models.ServiceBindingCredentials{},
+	&models.ProvisionRequestDetails{},
+	&models.InstanceShare{},
+	&models.InstanceProtection{},
+}
+
+// deleteServiceInstanceDependents permanently deletes the records referencing
+// the instance.
+func deleteServiceInstanceDependents(tx *gorm.DB, id string) error {
+	for _, dependent := range instanceDependents {
+		if err := tx.Unscoped().Where("service_instance_id = ?", id).Delete(dependent).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_PurgeDeletedServiceInstance(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.InstanceShare{}, models.InstanceProtection{})
+
+	for _, id := range []string{"live", "deleted"} {
+		if err := ds.CreateServiceInstanceDetails(ctx, &models.ServiceInstanceDetails{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+		if err := ds.CreateProvisionRequestDetails(ctx, &models.ProvisionRequestDetails{ServiceInstanceId: id, RequestDetails: "{}"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := ds.CreateServiceBindingCredentials(ctx, &models.ServiceBindingCredentials{ServiceInstanceId: id, BindingId: "binding"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := ds.CreateCloudOperation(ctx, &models.CloudOperation{ServiceInstanceId: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ds.DeleteServiceInstanceDetailsById(ctx, "deleted"); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, expected := range map[string]bool{"live": false, "deleted": true, "unknown": false} {
+		actual, err := ds.ExistsDeletedServiceInstanceDetailsById(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Errorf("Expected %q deleted to be %t, got %t", id, expected, actual)
+		}
+	}
+
+	if err := ds.PurgeDeletedServiceInstance(ctx, "live"); err == nil {
+		t.Error("Expected purging a live instance to fail")
+	}
+	if err := ds.PurgeDeletedServiceInstance(ctx, "deleted"); err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted, _ := ds.ExistsDeletedServiceInstanceDetailsById(ctx, "deleted"); deleted {
+		t.Error("Expected the deleted instance to be purged")
+	}
+	if _, err := ds.GetProvisionRequestDetailsByInstanceId(ctx, "deleted"); err == nil {
+		t.Error("Expected the deleted instance's provision request to be purged")
+	}
+	if count := countDependents(t, ds, &models.ServiceBindingCredentials{}, "deleted"); count != 0 {
+		t.Errorf("Expected the deleted instance's bindings to be purged, found %d", count)
+	}
+	if count := countDependents(t, ds, &models.CloudOperation{}, "deleted"); count != 0 {
+		t.Errorf("Expected the deleted instance's operations to be purged, found %d", count)
+	}
+
+	if exists, _ := ds.ExistsServiceInstanceDetailsById(ctx, "live"); !exists {
+		t.Error("Expected the live instance to be kept")
+	}
+	if _, err := ds.GetProvisionRequestDetailsByInstanceId(ctx, "live"); err != nil {
+		t.Errorf("Expected the live instance's provision request to be kept, got %v", err)
+	}
+	if count := countDependents(t, ds, &models.ServiceBindingCredentials{}, "live"); count != 1 {
+		t.Errorf("Expected the live instance's binding to be kept, found %d", count)
+	}
+
+	// the purged ID can be used again
+	if err := ds.CreateServiceInstanceDetails(ctx, &models.ServiceInstanceDetails{ID: "deleted"}); err != nil {
+		t.Errorf("Expected to reuse the purged ID, got %v", err)
+	}
+}
+
+func TestSqlDatastore_DeleteServiceInstanceDetailsAndBindingsById(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+
+	for _, id := range []string{"kept", "deprovisioned"} {
+		if err := ds.CreateServiceInstanceDetails(ctx, &models.ServiceInstanceDetails{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+		if err := ds.CreateServiceBindingCredentials(ctx, &models.ServiceBindingCredentials{ServiceInstanceId: id, BindingId: "binding"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ds.DeleteServiceInstanceDetailsAndBindingsById(ctx, "deprovisioned"); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, expected := range map[string]bool{"kept": true, "deprovisioned": false} {
+		if exists, _ := ds.ExistsServiceInstanceDetailsById(ctx, id); exists != expected {
+			t.Errorf("Expected instance %q to exist to be %t, got %t", id, expected, exists)
+		}
+		if exists, _ := ds.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, id, "binding"); exists != expected {
+			t.Errorf("Expected the binding of %q to exist to be %t, got %t", id, expected, exists)
+		}
+	}
+
+	// the rows are soft-deleted so they're still available to audit
+	if deleted, _ := ds.ExistsDeletedServiceInstanceDetailsById(ctx, "deprovisioned"); !deleted {
+		t.Error("Expected the instance to be soft-deleted")
+	}
+	if count := countDependents(t, ds, &models.ServiceBindingCredentials{}, "deprovisioned"); count != 1 {
+		t.Errorf("Expected the binding to be soft-deleted, found %d rows", count)
+	}
+}
+
+// countDependents counts the rows of the model referencing the instance,
+// including soft-deleted ones.
+func countDependents(t *testing.T, ds *SqlDatastore, model interface{}, instanceId string) int {
+	t.Helper()
+	count := 0
+	if err := ds.db.Unscoped().Model(model).Where("service_instance_id = ?", instanceId).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
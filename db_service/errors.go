@@ -0,0 +1,50 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlDuplicateEntry is the MySQL error number for unique key violations.
+const mysqlDuplicateEntry = 1062
+
+// ErrAlreadyExists is returned when a record can't be created because another
+// has the same unique key. Errors wrapping it can be checked with errors.Is.
+var ErrAlreadyExists = errors.New("record already exists")
+
+// duplicateKeyError wraps unique key violations reported by the database in
+// ErrAlreadyExists, other errors are returned unchanged.
+func duplicateKeyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return fmt.Errorf("%w: %v", ErrAlreadyExists, err)
+	}
+
+	// Both SQLite drivers only expose the violation through the message.
+	if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return fmt.Errorf("%w: %v", ErrAlreadyExists, err)
+	}
+
+	return err
+}
@@ -0,0 +1,97 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestDuplicateKeyError(t *testing.T) {
+	cases := map[string]struct {
+		err           error
+		alreadyExists bool
+	}{
+		"nil":              {err: nil},
+		"other":            {err: errors.New("connection refused")},
+		"mysql duplicate":  {err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'idx'"}, alreadyExists: true},
+		"mysql other":      {err: &mysql.MySQLError{Number: 1045, Message: "Access denied"}},
+		"sqlite duplicate": {err: errors.New("UNIQUE constraint failed: provision_request_details.service_instance_id"), alreadyExists: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := duplicateKeyError(tc.err)
+			if errors.Is(actual, ErrAlreadyExists) != tc.alreadyExists {
+				t.Errorf("Expected errors.Is(%v, ErrAlreadyExists) to be %t", actual, tc.alreadyExists)
+			}
+
+			if !tc.alreadyExists && actual != tc.err {
+				t.Errorf("Expected other errors to be returned unchanged, got %v", actual)
+			}
+		})
+	}
+}
+
+func TestSqlDatastore_CreateDuplicates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "duplicates-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := OpenSqlite(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := RunMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	ds := NewSqlDatastore(db)
+
+	cases := map[string]func() error{
+		"service instance": func() error {
+			return ds.CreateServiceInstanceDetails(ctx, &models.ServiceInstanceDetails{ID: "instance"})
+		},
+		"binding": func() error {
+			return ds.CreateServiceBindingCredentials(ctx, &models.ServiceBindingCredentials{ServiceInstanceId: "instance", BindingId: "binding"})
+		},
+		"provision request": func() error {
+			return ds.CreateProvisionRequestDetails(ctx, &models.ProvisionRequestDetails{ServiceInstanceId: "instance"})
+		},
+	}
+
+	for tn, create := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if err := create(); err != nil {
+				t.Fatalf("Expected no error creating the first record, got %v", err)
+			}
+
+			if err := create(); !errors.Is(err, ErrAlreadyExists) {
+				t.Errorf("Expected creating a duplicate to fail with ErrAlreadyExists, got %v", err)
+			}
+		})
+	}
+}
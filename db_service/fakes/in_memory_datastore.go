@@ -0,0 +1,640 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+var _ db_service.Datastore = (*InMemoryDatastore)(nil)
+
+// InMemoryDatastore is a Datastore that keeps its records in memory so
+// brokers can be tested without a database. It behaves like SqlDatastore:
+// deletes are soft, records are copied in and out, and getting a missing
+// record returns gorm.ErrRecordNotFound.
+type InMemoryDatastore struct {
+	mu sync.Mutex
+
+	instances         []models.ServiceInstanceDetails
+	bindings          []models.ServiceBindingCredentials
+	provisionRequests []models.ProvisionRequestDetails
+	upgrades          []models.InstanceUpgrade
+	shares            []models.InstanceShare
+	protections       []models.InstanceProtection
+	deployments       []models.TerraformDeployment
+	jobs              []models.Job
+	leases            map[string]inMemoryLease
+
+	lastId uint
+}
+
+// inMemoryLease is the holder of a leader lease and when it expires.
+type inMemoryLease struct {
+	holder  string
+	expires time.Time
+}
+
+// NewInMemoryDatastore creates an empty InMemoryDatastore.
+func NewInMemoryDatastore() *InMemoryDatastore {
+	return &InMemoryDatastore{}
+}
+
+// AddJob adds a job, e.g. a pending operation on an instance, and returns its
+// ID.
+func (ds *InMemoryDatastore) AddJob(job models.Job) uint {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ds.newModel(&job.Model)
+	ds.jobs = append(ds.jobs, job)
+	return job.ID
+}
+
+// AddTerraformDeployment adds the Terraform state of an instance or binding.
+func (ds *InMemoryDatastore) AddTerraformDeployment(deployment models.TerraformDeployment) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := time.Now()
+	setTimestamps(&deployment.CreatedAt, &deployment.UpdatedAt, now)
+	ds.deployments = append(ds.deployments, deployment)
+}
+
+// newModel assigns the model the next ID and sets its timestamps the way
+// gorm does when creating a record.
+func (ds *InMemoryDatastore) newModel(model *gorm.Model) {
+	ds.lastId++
+	model.ID = ds.lastId
+	setTimestamps(&model.CreatedAt, &model.UpdatedAt, time.Now())
+}
+
+func setTimestamps(createdAt, updatedAt *time.Time, now time.Time) {
+	if createdAt.IsZero() {
+		*createdAt = now
+	}
+	*updatedAt = now
+}
+
+func (ds *InMemoryDatastore) CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for _, existing := range ds.instances {
+		if existing.ID == object.ID {
+			return fmt.Errorf("%w: UNIQUE constraint failed: service_instance_details.id %q", db_service.ErrAlreadyExists, object.ID)
+		}
+	}
+
+	setTimestamps(&object.CreatedAt, &object.UpdatedAt, time.Now())
+	ds.instances = append(ds.instances, *object)
+	return nil
+}
+
+func (ds *InMemoryDatastore) SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	setTimestamps(&object.CreatedAt, &object.UpdatedAt, time.Now())
+	for i, existing := range ds.instances {
+		if existing.ID == object.ID {
+			ds.instances[i] = *object
+			return nil
+		}
+	}
+
+	ds.instances = append(ds.instances, *object)
+	return nil
+}
+
+func (ds *InMemoryDatastore) DeleteServiceInstanceDetailsById(ctx context.Context, id string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for i, existing := range ds.instances {
+		if existing.ID == id && existing.DeletedAt == nil {
+			now := time.Now()
+			ds.instances[i].DeletedAt = &now
+		}
+	}
+
+	return nil
+}
+
+func (ds *InMemoryDatastore) DeleteServiceInstanceDetailsAndBindingsById(ctx context.Context, id string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := time.Now()
+	for i, existing := range ds.bindings {
+		if existing.ServiceInstanceId == id && existing.DeletedAt == nil {
+			ds.bindings[i].DeletedAt = &now
+		}
+	}
+
+	for i, existing := range ds.instances {
+		if existing.ID == id && existing.DeletedAt == nil {
+			ds.instances[i].DeletedAt = &now
+		}
+	}
+
+	return nil
+}
+
+func (ds *InMemoryDatastore) GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for _, existing := range ds.instances {
+		if existing.ID == id && existing.DeletedAt == nil {
+			record := existing
+			return &record, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (ds *InMemoryDatastore) ExistsServiceInstanceDetailsById(ctx context.Context, id string) (bool, error) {
+	_, err := ds.GetServiceInstanceDetailsById(ctx, id)
+	return err == nil, nil
+}
+
+func (ds *InMemoryDatastore) ListServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) ([]models.ServiceInstanceDetails, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var out []models.ServiceInstanceDetails
+	for _, existing := range ds.instances {
+		if existing.DeletedAt == nil && matches(existing, conditions) {
+			out = append(out, existing)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (ds *InMemoryDatastore) CountServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) (int, error) {
+	instances, err := ds.ListServiceInstanceDetails(ctx, conditions)
+	return len(instances), err
+}
+
+func (ds *InMemoryDatastore) ExistsDeletedServiceInstanceDetailsById(ctx context.Context, id string) (bool, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for _, existing := range ds.instances {
+		if existing.ID == id && existing.DeletedAt != nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (ds *InMemoryDatastore) PurgeDeletedServiceInstance(ctx context.Context, id string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var instances []models.ServiceInstanceDetails
+	for _, existing := range ds.instances {
+		if existing.ID != id {
+			instances = append(instances, existing)
+			continue
+		}
+
+		if existing.DeletedAt == nil {
+			return fmt.Errorf("instance %s hasn't been deprovisioned", id)
+		}
+	}
+
+	var requests []models.ProvisionRequestDetails
+	for _, existing := range ds.provisionRequests {
+		if existing.ServiceInstanceId != id {
+			requests = append(requests, existing)
+		}
+	}
+
+	var bindings []models.ServiceBindingCredentials
+	for _, existing := range ds.bindings {
+		if existing.ServiceInstanceId != id {
+			bindings = append(bindings, existing)
+		}
+	}
+
+	var shares []models.InstanceShare
+	for _, existing := range ds.shares {
+		if existing.ServiceInstanceId != id {
+			shares = append(shares, existing)
+		}
+	}
+
+	var protections []models.InstanceProtection
+	for _, existing := range ds.protections {
+		if existing.ServiceInstanceId != id {
+			protections = append(protections, existing)
+		}
+	}
+
+	ds.instances = instances
+	ds.provisionRequests = requests
+	ds.bindings = bindings
+	ds.shares = shares
+	ds.protections = protections
+	return nil
+}
+
+func (ds *InMemoryDatastore) CreateServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	// the unique index covers soft-deleted bindings too
+	for _, existing := range ds.bindings {
+		if existing.ServiceInstanceId == object.ServiceInstanceId && existing.BindingId == object.BindingId {
+			return fmt.Errorf("%w: UNIQUE constraint failed: service_binding_credentials.service_instance_id, service_binding_credentials.binding_id", db_service.ErrAlreadyExists)
+		}
+	}
+
+	ds.newModel(&object.Model)
+	ds.bindings = append(ds.bindings, *object)
+	return nil
+}
+
+func (ds *InMemoryDatastore) SaveServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for i, existing := range ds.bindings {
+		if object.ID != 0 && existing.ID == object.ID {
+			setTimestamps(&object.CreatedAt, &object.UpdatedAt, time.Now())
+			ds.bindings[i] = *object
+			return nil
+		}
+	}
+
+	ds.newModel(&object.Model)
+	ds.bindings = append(ds.bindings, *object)
+	return nil
+}
+
+func (ds *InMemoryDatastore) DeleteServiceBindingCredentials(ctx context.Context, record *models.ServiceBindingCredentials) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for i, existing := range ds.bindings {
+		if existing.ID == record.ID && existing.DeletedAt == nil {
+			now := time.Now()
+			ds.bindings[i].DeletedAt = &now
+			record.DeletedAt = &now
+		}
+	}
+
+	return nil
+}
+
+func (ds *InMemoryDatastore) GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (*models.ServiceBindingCredentials, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for _, existing := range ds.bindings {
+		if existing.ServiceInstanceId == serviceInstanceId && existing.BindingId == bindingId && existing.DeletedAt == nil {
+			record := existing
+			return &record, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (ds *InMemoryDatastore) ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (bool, error) {
+	_, err := ds.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId)
+	return err == nil, nil
+}
+
+func (ds *InMemoryDatastore) ListServiceBindingCredentials(ctx context.Context, conditions models.ServiceBindingCredentials) ([]models.ServiceBindingCredentials, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var out []models.ServiceBindingCredentials
+	for _, existing := range ds.bindings {
+		if existing.DeletedAt == nil && matches(existing, conditions) {
+			out = append(out, existing)
+		}
+	}
+
+	return out, nil
+}
+
+func (ds *InMemoryDatastore) ListRevokedServiceBindingCredentials(ctx context.Context) ([]models.ServiceBindingCredentials, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var out []models.ServiceBindingCredentials
+	for _, existing := range ds.bindings {
+		if existing.DeletedAt == nil && existing.RevokedAt != nil {
+			out = append(out, existing)
+		}
+	}
+
+	return out, nil
+}
+
+func (ds *InMemoryDatastore) CreateProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for _, existing := range ds.provisionRequests {
+		if existing.ServiceInstanceId == object.ServiceInstanceId {
+			return fmt.Errorf("%w: UNIQUE constraint failed: provision_request_details.service_instance_id", db_service.ErrAlreadyExists)
+		}
+	}
+
+	ds.newModel(&object.Model)
+	ds.provisionRequests = append(ds.provisionRequests, *object)
+	return nil
+}
+
+func (ds *InMemoryDatastore) SaveProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for i, existing := range ds.provisionRequests {
+		if object.ID != 0 && existing.ID == object.ID {
+			setTimestamps(&object.CreatedAt, &object.UpdatedAt, time.Now())
+			ds.provisionRequests[i] = *object
+			return nil
+		}
+	}
+
+	ds.newModel(&object.Model)
+	ds.provisionRequests = append(ds.provisionRequests, *object)
+	return nil
+}
+
+func (ds *InMemoryDatastore) GetProvisionRequestDetailsByInstanceId(ctx context.Context, instanceId string) (*models.ProvisionRequestDetails, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for _, existing := range ds.provisionRequests {
+		if existing.ServiceInstanceId == instanceId && existing.DeletedAt == nil {
+			record := existing
+			return &record, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (ds *InMemoryDatastore) GetInstanceUpgrade(ctx context.Context, instanceId, toVersion string) (*models.InstanceUpgrade, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for i := len(ds.upgrades) - 1; i >= 0; i-- {
+		existing := ds.upgrades[i]
+		if existing.ServiceInstanceId == instanceId && existing.ToVersion == toVersion && existing.DeletedAt == nil {
+			return &existing, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (ds *InMemoryDatastore) SaveInstanceUpgrade(ctx context.Context, upgrade *models.InstanceUpgrade) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for i, existing := range ds.upgrades {
+		if upgrade.ID != 0 && existing.ID == upgrade.ID {
+			setTimestamps(&upgrade.CreatedAt, &upgrade.UpdatedAt, time.Now())
+			ds.upgrades[i] = *upgrade
+			return nil
+		}
+	}
+
+	ds.newModel(&upgrade.Model)
+	ds.upgrades = append(ds.upgrades, *upgrade)
+	return nil
+}
+
+func (ds *InMemoryDatastore) RecordInstanceShare(ctx context.Context, instanceId, organizationGuid, spaceGuid string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for _, existing := range ds.shares {
+		if existing.ServiceInstanceId == instanceId && existing.SpaceGuid == spaceGuid && existing.DeletedAt == nil {
+			return nil
+		}
+	}
+
+	share := models.InstanceShare{ServiceInstanceId: instanceId, OrganizationGuid: organizationGuid, SpaceGuid: spaceGuid}
+	ds.newModel(&share.Model)
+	ds.shares = append(ds.shares, share)
+	return nil
+}
+
+func (ds *InMemoryDatastore) DeleteInstanceShares(ctx context.Context, instanceId string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := time.Now()
+	for i, existing := range ds.shares {
+		if existing.ServiceInstanceId == instanceId && existing.DeletedAt == nil {
+			ds.shares[i].DeletedAt = &now
+		}
+	}
+
+	return nil
+}
+
+func (ds *InMemoryDatastore) GetInstanceProtection(ctx context.Context, instanceId string) (*models.InstanceProtection, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for i := len(ds.protections) - 1; i >= 0; i-- {
+		existing := ds.protections[i]
+		if existing.ServiceInstanceId == instanceId && existing.DeletedAt == nil {
+			return &existing, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (ds *InMemoryDatastore) SaveInstanceProtection(ctx context.Context, protection *models.InstanceProtection) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for i, existing := range ds.protections {
+		if protection.ID != 0 && existing.ID == protection.ID {
+			setTimestamps(&protection.CreatedAt, &protection.UpdatedAt, time.Now())
+			ds.protections[i] = *protection
+			return nil
+		}
+	}
+
+	ds.newModel(&protection.Model)
+	ds.protections = append(ds.protections, *protection)
+	return nil
+}
+
+func (ds *InMemoryDatastore) GetTerraformDeploymentById(ctx context.Context, id string) (*models.TerraformDeployment, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for _, existing := range ds.deployments {
+		if existing.ID == id && existing.DeletedAt == nil {
+			return &existing, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (ds *InMemoryDatastore) SaveTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	setTimestamps(&object.CreatedAt, &object.UpdatedAt, time.Now())
+	for i, existing := range ds.deployments {
+		if existing.ID == object.ID {
+			ds.deployments[i] = *object
+			return nil
+		}
+	}
+
+	ds.deployments = append(ds.deployments, *object)
+	return nil
+}
+
+func (ds *InMemoryDatastore) ListTerraformDeployments(ctx context.Context, limit int) ([]models.TerraformDeployment, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var out []models.TerraformDeployment
+	for _, existing := range ds.deployments {
+		if existing.DeletedAt == nil {
+			// like SqlDatastore, the workspace isn't loaded
+			existing.Workspace = ""
+			out = append(out, existing)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+
+	return out, nil
+}
+
+func (ds *InMemoryDatastore) ListJobs(ctx context.Context, target string) ([]models.Job, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var out []models.Job
+	for _, existing := range ds.jobs {
+		if existing.DeletedAt == nil && existing.Target == target {
+			out = append(out, existing)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out, nil
+}
+
+func (ds *InMemoryDatastore) ListPendingJobs(ctx context.Context, targetPrefix string) ([]models.Job, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var out []models.Job
+	for _, existing := range ds.jobs {
+		pending := existing.State == models.JobQueued || existing.State == models.JobRunning
+		if existing.DeletedAt == nil && pending && strings.HasPrefix(existing.Target, targetPrefix) {
+			out = append(out, existing)
+		}
+	}
+
+	return out, nil
+}
+
+func (ds *InMemoryDatastore) AcquireLeaderLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := ds.leases[name]; ok && existing.holder != holder && now.Before(existing.expires) {
+		return false, nil
+	}
+
+	if ds.leases == nil {
+		ds.leases = map[string]inMemoryLease{}
+	}
+	ds.leases[name] = inMemoryLease{holder: holder, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (ds *InMemoryDatastore) ReleaseLeaderLease(ctx context.Context, name, holder string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if existing, ok := ds.leases[name]; ok && existing.holder == holder {
+		existing.expires = time.Now()
+		ds.leases[name] = existing
+	}
+
+	return nil
+}
+
+// matches reports whether the record has the value of each of the non-zero
+// fields of conditions, the way gorm queries with a struct.
+func matches(record, conditions interface{}) bool {
+	return matchFields(reflect.ValueOf(record), reflect.ValueOf(conditions))
+}
+
+func matchFields(record, conditions reflect.Value) bool {
+	for i := 0; i < conditions.NumField(); i++ {
+		field := conditions.Field(i)
+		if conditions.Type().Field(i).Anonymous && field.Kind() == reflect.Struct {
+			if !matchFields(record.Field(i), field) {
+				return false
+			}
+			continue
+		}
+
+		if isZero(field) {
+			continue
+		}
+
+		if !reflect.DeepEqual(record.Field(i).Interface(), field.Interface()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isZero(value reflect.Value) bool {
+	return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+}
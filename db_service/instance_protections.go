@@ -0,0 +1,57 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// GetInstanceProtection gets the deletion protection an operator set on the
+// instance or nil if they haven't set any.
+func GetInstanceProtection(ctx context.Context, instanceId string) (*models.InstanceProtection, error) {
+	return defaultDatastore().GetInstanceProtection(ctx, instanceId)
+}
+
+// GetInstanceProtection gets the deletion protection an operator set on the
+// instance or nil if they haven't set any.
+func (ds *SqlDatastore) GetInstanceProtection(ctx context.Context, instanceId string) (*models.InstanceProtection, error) {
+	defer traceOperation(ctx, "GetInstanceProtection")()
+	var protections []models.InstanceProtection
+	err := ds.db.Where("service_instance_id = ?", instanceId).Order("id desc").Limit(1).Find(&protections).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(protections) == 0 {
+		return nil, nil
+	}
+
+	return &protections[0], nil
+}
+
+// SaveInstanceProtection creates or updates the deletion protection of an
+// instance.
+func SaveInstanceProtection(ctx context.Context, protection *models.InstanceProtection) error {
+	return defaultDatastore().SaveInstanceProtection(ctx, protection)
+}
+
+// SaveInstanceProtection creates or updates the deletion protection of an
+// instance.
+func (ds *SqlDatastore) SaveInstanceProtection(ctx context.Context, protection *models.InstanceProtection) error {
+	defer traceOperation(ctx, "SaveInstanceProtection")()
+	return ds.db.Save(protection).Error
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_InstanceProtections(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.InstanceProtection{})
+
+	protection, err := ds.GetInstanceProtection(ctx, "instance-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if protection != nil {
+		t.Errorf("Expected no protection, got %v", protection)
+	}
+
+	protection = &models.InstanceProtection{ServiceInstanceId: "instance-1", Protected: true, Reason: "production"}
+	if err := ds.SaveInstanceProtection(ctx, protection); err != nil {
+		t.Fatal(err)
+	}
+
+	protection.Protected = false
+	if err := ds.SaveInstanceProtection(ctx, protection); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := ds.GetInstanceProtection(ctx, "instance-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual == nil || actual.ID != protection.ID || actual.Protected || actual.Reason != "production" {
+		t.Errorf("Expected the cleared protection, got %v", actual)
+	}
+
+	if other, err := ds.GetInstanceProtection(ctx, "instance-2"); err != nil || other != nil {
+		t.Errorf("Expected no protection for another instance, got %v, %v", other, err)
+	}
+}
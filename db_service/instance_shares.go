@@ -0,0 +1,66 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// RecordInstanceShare records that the instance is shared with the space if
+// it isn't already.
+func RecordInstanceShare(ctx context.Context, instanceId, organizationGuid, spaceGuid string) error {
+	return defaultDatastore().RecordInstanceShare(ctx, instanceId, organizationGuid, spaceGuid)
+}
+
+// RecordInstanceShare records that the instance is shared with the space if
+// it isn't already.
+func (ds *SqlDatastore) RecordInstanceShare(ctx context.Context, instanceId, organizationGuid, spaceGuid string) error {
+	defer traceOperation(ctx, "RecordInstanceShare")()
+	share := models.InstanceShare{}
+	return ds.db.
+		Where("service_instance_id = ? AND space_guid = ?", instanceId, spaceGuid).
+		Attrs(models.InstanceShare{OrganizationGuid: organizationGuid}).
+		FirstOrCreate(&share, models.InstanceShare{ServiceInstanceId: instanceId, SpaceGuid: spaceGuid}).Error
+}
+
+// ListInstanceShares lists the spaces the instance is shared with, oldest
+// first.
+func ListInstanceShares(ctx context.Context, instanceId string) ([]models.InstanceShare, error) {
+	return defaultDatastore().ListInstanceShares(ctx, instanceId)
+}
+
+// ListInstanceShares lists the spaces the instance is shared with, oldest
+// first.
+func (ds *SqlDatastore) ListInstanceShares(ctx context.Context, instanceId string) ([]models.InstanceShare, error) {
+	defer traceOperation(ctx, "ListInstanceShares")()
+	var shares []models.InstanceShare
+	err := ds.db.Where("service_instance_id = ?", instanceId).Order("id asc").Find(&shares).Error
+	return shares, err
+}
+
+// DeleteInstanceShares soft-deletes the shares of the instance, e.g. because
+// it was deprovisioned.
+func DeleteInstanceShares(ctx context.Context, instanceId string) error {
+	return defaultDatastore().DeleteInstanceShares(ctx, instanceId)
+}
+
+// DeleteInstanceShares soft-deletes the shares of the instance, e.g. because
+// it was deprovisioned.
+func (ds *SqlDatastore) DeleteInstanceShares(ctx context.Context, instanceId string) error {
+	defer traceOperation(ctx, "DeleteInstanceShares")()
+	return ds.db.Where("service_instance_id = ?", instanceId).Delete(&models.InstanceShare{}).Error
+}
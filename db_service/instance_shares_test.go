@@ -0,0 +1,68 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_InstanceShares(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.InstanceShare{})
+
+	cases := []struct {
+		InstanceId string
+		Org        string
+		Space      string
+	}{
+		{InstanceId: "instance-1", Org: "org-a", Space: "space-a"},
+		{InstanceId: "instance-1", Org: "org-b", Space: "space-b"},
+		// binding again from a space doesn't record another share
+		{InstanceId: "instance-1", Org: "org-a", Space: "space-a"},
+		{InstanceId: "instance-2", Org: "org-a", Space: "space-a"},
+	}
+
+	for _, tc := range cases {
+		if err := ds.RecordInstanceShare(ctx, tc.InstanceId, tc.Org, tc.Space); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shares, err := ds.ListInstanceShares(ctx, "instance-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spaces []string
+	for _, share := range shares {
+		spaces = append(spaces, share.OrganizationGuid+"/"+share.SpaceGuid)
+	}
+	if len(spaces) != 2 || spaces[0] != "org-a/space-a" || spaces[1] != "org-b/space-b" {
+		t.Errorf("Expected shares [org-a/space-a org-b/space-b], got %v", spaces)
+	}
+
+	if err := ds.DeleteInstanceShares(ctx, "instance-1"); err != nil {
+		t.Fatal(err)
+	}
+	if shares, _ := ds.ListInstanceShares(ctx, "instance-1"); len(shares) != 0 {
+		t.Errorf("Expected no shares after deleting them, got %d", len(shares))
+	}
+	if shares, _ := ds.ListInstanceShares(ctx, "instance-2"); len(shares) != 1 {
+		t.Errorf("Expected other instances' shares to be kept, got %d", len(shares))
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// ErrJobLost is returned when updating a job another worker has taken over,
+// e.g. because its lease expired.
+var ErrJobLost = errors.New("the job was taken over by another worker")
+
+// CreateJob queues a job to run now.
+func CreateJob(ctx context.Context, job *models.Job) error {
+	return defaultDatastore().CreateJob(ctx, job)
+}
+
+// CreateJob queues a job to run now.
+func (ds *SqlDatastore) CreateJob(ctx context.Context, job *models.Job) error {
+	defer traceOperation(ctx, "CreateJob")()
+	tx := ds.db.Begin()
+	if err := tx.Create(job).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// the job is due by the database's clock, like the claims that run it
+	if err := tx.Model(job).UpdateColumn("run_after", gorm.Expr("CURRENT_TIMESTAMP")).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// ClaimJob takes the oldest job of one of the kinds that's due to run, or
+// whose worker's lease expired, for the worker until the lease expires. It
+// returns nil if there are none or another worker claimed it first. Jobs are
+// due and leases expire by the database's clock.
+func ClaimJob(ctx context.Context, kinds []string, worker string, lease time.Duration) (*models.Job, error) {
+	return defaultDatastore().ClaimJob(ctx, kinds, worker, lease)
+}
+
+// ClaimJob takes the oldest job of one of the kinds that's due to run, or
+// whose worker's lease expired, for the worker until the lease expires. It
+// returns nil if there are none or another worker claimed it first. Jobs are
+// due and leases expire by the database's clock.
+func (ds *SqlDatastore) ClaimJob(ctx context.Context, kinds []string, worker string, lease time.Duration) (*models.Job, error) {
+	defer traceOperation(ctx, "ClaimJob")()
+	job := models.Job{}
+	err := ds.db.
+		Where("kind IN (?) AND run_after <= CURRENT_TIMESTAMP", kinds).
+		Where("state = ? OR (state = ? AND lease_expires_at <= CURRENT_TIMESTAMP)", models.JobQueued, models.JobRunning).
+		Order("id asc").
+		First(&job).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// attempts changes on every claim so it guards against two workers
+	// claiming the same job
+	result := ds.db.Model(&models.Job{}).
+		Where("id = ? AND attempts = ?", job.ID, job.Attempts).
+		Updates(map[string]interface{}{
+			"state":            models.JobRunning,
+			"worker":           worker,
+			"lease_expires_at": ds.currentTimePlus(lease),
+			"attempts":         job.Attempts + 1,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+
+	claimed := models.Job{}
+	if err := ds.db.First(&claimed, job.ID).Error; err != nil {
+		return nil, err
+	}
+
+	return &claimed, nil
+}
+
+// RenewJobLease extends the lease of a job the worker is running until the
+// lease duration from now by the database's clock.
+func RenewJobLease(ctx context.Context, job *models.Job, lease time.Duration) error {
+	return defaultDatastore().RenewJobLease(ctx, job, lease)
+}
+
+// RenewJobLease extends the lease of a job the worker is running until the
+// lease duration from now by the database's clock.
+func (ds *SqlDatastore) RenewJobLease(ctx context.Context, job *models.Job, lease time.Duration) error {
+	defer traceOperation(ctx, "RenewJobLease")()
+	return ds.updateClaimedJob(job, map[string]interface{}{"lease_expires_at": ds.currentTimePlus(lease)})
+}
+
+// FinishJob saves the state and error of a job the worker was running,
+// ending its lease. A job queued to run again waits for retryAfter.
+func FinishJob(ctx context.Context, job *models.Job, retryAfter time.Duration) error {
+	return defaultDatastore().FinishJob(ctx, job, retryAfter)
+}
+
+// FinishJob saves the state and error of a job the worker was running,
+// ending its lease. A job queued to run again waits for retryAfter.
+func (ds *SqlDatastore) FinishJob(ctx context.Context, job *models.Job, retryAfter time.Duration) error {
+	defer traceOperation(ctx, "FinishJob")()
+	return ds.updateClaimedJob(job, map[string]interface{}{
+		"state":            job.State,
+		"last_error":       job.LastError,
+		"run_after":        ds.currentTimePlus(retryAfter),
+		"lease_expires_at": nil,
+	})
+}
+
+// ReleaseJob returns a job the worker was running to the queue without
+// counting the attempt, e.g. because the broker is stopping.
+func ReleaseJob(ctx context.Context, job *models.Job) error {
+	return defaultDatastore().ReleaseJob(ctx, job)
+}
+
+// ReleaseJob returns a job the worker was running to the queue without
+// counting the attempt, e.g. because the broker is stopping.
+func (ds *SqlDatastore) ReleaseJob(ctx context.Context, job *models.Job) error {
+	defer traceOperation(ctx, "ReleaseJob")()
+	return ds.updateClaimedJob(job, map[string]interface{}{
+		"state":            models.JobQueued,
+		"worker":           "",
+		"lease_expires_at": nil,
+		"attempts":         job.Attempts - 1,
+	})
+}
+
+// updateClaimedJob updates a job if the worker still holds it.
+func (ds *SqlDatastore) updateClaimedJob(job *models.Job, updates map[string]interface{}) error {
+	result := ds.db.Model(&models.Job{}).
+		Where("id = ? AND worker = ? AND attempts = ? AND state = ?", job.ID, job.Worker, job.Attempts, models.JobRunning).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobLost
+	}
+
+	return nil
+}
+
+// ListJobs lists the jobs working on the target, newest first.
+func ListJobs(ctx context.Context, target string) ([]models.Job, error) {
+	return defaultDatastore().ListJobs(ctx, target)
+}
+
+// ListJobs lists the jobs working on the target, newest first.
+func (ds *SqlDatastore) ListJobs(ctx context.Context, target string) ([]models.Job, error) {
+	defer traceOperation(ctx, "ListJobs")()
+	var jobs []models.Job
+	err := ds.db.Where("target = ?", target).Order("id desc").Find(&jobs).Error
+	return jobs, err
+}
+
+// ListPendingJobs lists the queued and running jobs whose target starts with
+// the prefix, oldest first.
+func ListPendingJobs(ctx context.Context, targetPrefix string) ([]models.Job, error) {
+	return defaultDatastore().ListPendingJobs(ctx, targetPrefix)
+}
+
+// ListPendingJobs lists the queued and running jobs whose target starts with
+// the prefix, oldest first.
+func (ds *SqlDatastore) ListPendingJobs(ctx context.Context, targetPrefix string) ([]models.Job, error) {
+	defer traceOperation(ctx, "ListPendingJobs")()
+	var candidates []models.Job
+	err := ds.db.Where("target LIKE ? AND state IN (?)", targetPrefix+"%", []string{models.JobQueued, models.JobRunning}).
+		Order("id asc").
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// LIKE treats _ and % in the prefix as wildcards
+	var jobs []models.Job
+	for _, job := range candidates {
+		if strings.HasPrefix(job.Target, targetPrefix) {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs, nil
+}
+
+// DeleteFinishedJobsBefore permanently deletes the succeeded and failed jobs
+// last updated before the cutoff and returns how many there were.
+func DeleteFinishedJobsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return defaultDatastore().DeleteFinishedJobsBefore(ctx, cutoff)
+}
+
+// DeleteFinishedJobsBefore permanently deletes the succeeded and failed jobs
+// last updated before the cutoff and returns how many there were.
+func (ds *SqlDatastore) DeleteFinishedJobsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	defer traceOperation(ctx, "DeleteFinishedJobsBefore")()
+	result := ds.db.Unscoped().
+		Where("state IN (?) AND updated_at < ?", []string{models.JobSucceeded, models.JobFailed}, cutoff).
+		Delete(&models.Job{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,211 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_Jobs(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.Job{})
+
+	for _, job := range []models.Job{
+		{Kind: "terraform", Target: "tf:instance-1:", State: models.JobQueued, MaxAttempts: 1},
+		{Kind: "terraform", Target: "tf:instance-2:", State: models.JobQueued, MaxAttempts: 1},
+		{Kind: "other", Target: "other-1", State: models.JobQueued, MaxAttempts: 1},
+	} {
+		if err := ds.CreateJob(ctx, &job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// jobs are due and leases expire by the database's clock, so time is
+	// simulated by moving the columns rather than the clock
+	setTime := func(target, column, offset string) {
+		err := ds.db.Model(&models.Job{}).
+			Where("target = ?", target).
+			UpdateColumn(column, gorm.Expr("datetime(CURRENT_TIMESTAMP, ?)", offset)).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	setTime("tf:instance-1:", "run_after", "-1 minutes")
+	setTime("tf:instance-2:", "run_after", "+1 hours")
+
+	job, err := ds.ClaimJob(ctx, []string{"terraform"}, "worker-1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job == nil || job.Target != "tf:instance-1:" || job.State != models.JobRunning || job.Attempts != 1 || job.Worker != "worker-1" {
+		t.Fatalf("Expected worker-1 to claim the due terraform job, got %+v", job)
+	}
+	if job.LeaseExpiresAt == nil || time.Until(*job.LeaseExpiresAt) <= 0 || time.Until(*job.LeaseExpiresAt) > 2*time.Minute {
+		t.Errorf("Expected the lease to expire in about a minute, got %v", job.LeaseExpiresAt)
+	}
+
+	if other, err := ds.ClaimJob(ctx, []string{"terraform"}, "worker-2", time.Minute); err != nil || other != nil {
+		t.Fatalf("Expected no job to be claimable while the lease holds, got %+v, %v", other, err)
+	}
+
+	// the lease expires as if worker-1 crashed
+	setTime("tf:instance-1:", "lease_expires_at", "-1 seconds")
+	stolen, err := ds.ClaimJob(ctx, []string{"terraform"}, "worker-2", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stolen == nil || stolen.ID != job.ID || stolen.Attempts != 2 || stolen.Worker != "worker-2" {
+		t.Fatalf("Expected worker-2 to take over the expired job, got %+v", stolen)
+	}
+
+	job.State = models.JobSucceeded
+	if err := ds.FinishJob(ctx, job, 0); err != ErrJobLost {
+		t.Errorf("Expected ErrJobLost finishing a job another worker took over, got %v", err)
+	}
+
+	if err := ds.RenewJobLease(ctx, stolen, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.ReleaseJob(ctx, stolen); err != nil {
+		t.Fatal(err)
+	}
+
+	released, err := ds.ClaimJob(ctx, []string{"terraform"}, "worker-3", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released == nil || released.ID != job.ID || released.Attempts != 2 {
+		t.Fatalf("Expected the released job to be claimable without counting the attempt, got %+v", released)
+	}
+
+	// a job queued again waits for its retry delay
+	released.State = models.JobQueued
+	released.LastError = "apply failed"
+	if err := ds.FinishJob(ctx, released, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if retried, err := ds.ClaimJob(ctx, []string{"terraform"}, "worker-3", time.Minute); err != nil || retried != nil {
+		t.Fatalf("Expected the job to wait for its retry delay, got %+v, %v", retried, err)
+	}
+
+	setTime("tf:instance-1:", "run_after", "-1 seconds")
+	retried, err := ds.ClaimJob(ctx, []string{"terraform"}, "worker-3", time.Minute)
+	if err != nil || retried == nil || retried.ID != job.ID {
+		t.Fatalf("Expected the job to be retried once its delay passed, got %+v, %v", retried, err)
+	}
+
+	retried.State = models.JobFailed
+	if err := ds.FinishJob(ctx, retried, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := ds.ListJobs(ctx, "tf:instance-1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].State != models.JobFailed || jobs[0].LastError != "apply failed" || jobs[0].LeaseExpiresAt != nil {
+		t.Errorf("Expected the job to be recorded as failed, got %+v", jobs)
+	}
+}
+
+func TestSqlDatastore_ListPendingJobs(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.Job{})
+
+	for _, job := range []models.Job{
+		{Kind: "terraform", Target: "tf:instance_1:", State: models.JobFailed},
+		{Kind: "terraform", Target: "tf:instance_1:", State: models.JobQueued},
+		{Kind: "terraform", Target: "tf:instance_1:binding-1", State: models.JobRunning},
+		{Kind: "terraform", Target: "tf:instanceX1:", State: models.JobQueued},
+		{Kind: "terraform", Target: "tf:instance_10:", State: models.JobQueued},
+	} {
+		if err := ds.CreateJob(ctx, &job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs, err := ds.ListPendingJobs(ctx, "tf:instance_1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var targets []string
+	for _, job := range jobs {
+		targets = append(targets, job.Target)
+	}
+
+	expected := []string{"tf:instance_1:", "tf:instance_1:binding-1"}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("Expected pending jobs %v, got %v", expected, targets)
+	}
+}
+
+func TestSqlDatastore_DeleteFinishedJobsBefore(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.Job{})
+	now := time.Now()
+
+	cases := map[string]struct {
+		State     string
+		UpdatedAt time.Time
+		Kept      bool
+	}{
+		"succeeded-recently": {State: models.JobSucceeded, UpdatedAt: now.Add(-time.Hour), Kept: true},
+		"succeeded-long-ago": {State: models.JobSucceeded, UpdatedAt: now.Add(-48 * time.Hour), Kept: false},
+		"failed-long-ago":    {State: models.JobFailed, UpdatedAt: now.Add(-48 * time.Hour), Kept: false},
+		"queued-long-ago":    {State: models.JobQueued, UpdatedAt: now.Add(-48 * time.Hour), Kept: true},
+		"running-long-ago":   {State: models.JobRunning, UpdatedAt: now.Add(-48 * time.Hour), Kept: true},
+	}
+
+	for target, tc := range cases {
+		job := models.Job{Kind: "terraform", Target: target, State: tc.State}
+		if err := ds.CreateJob(ctx, &job); err != nil {
+			t.Fatal(err)
+		}
+		if err := ds.db.Model(&job).UpdateColumn("updated_at", tc.UpdatedAt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := ds.DeleteFinishedJobsBefore(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 jobs to be deleted, got %d", deleted)
+	}
+
+	for target, tc := range cases {
+		t.Run(target, func(t *testing.T) {
+			jobs, err := ds.ListJobs(ctx, target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kept := len(jobs) == 1; kept != tc.Kept {
+				t.Errorf("Expected kept to be %v, got %v", tc.Kept, kept)
+			}
+		})
+	}
+}
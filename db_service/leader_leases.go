@@ -0,0 +1,102 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// AcquireLeaderLease makes the holder the leader of the named work until the
+// lease expires, if no other holder has an unexpired lease on it. Holders
+// renew their lease by acquiring it again. It returns whether the holder
+// leads.
+func AcquireLeaderLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	return defaultDatastore().AcquireLeaderLease(ctx, name, holder, ttl)
+}
+
+// AcquireLeaderLease makes the holder the leader of the named work until the
+// lease expires, if no other holder has an unexpired lease on it. Holders
+// renew their lease by acquiring it again. It returns whether the holder
+// leads.
+func (ds *SqlDatastore) AcquireLeaderLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	defer traceOperation(ctx, "AcquireLeaderLease")()
+	expires := ds.currentTimePlus(ttl)
+	result := ds.db.Model(&models.LeaderLease{}).
+		Where("name = ? AND (holder = ? OR expires_at <= CURRENT_TIMESTAMP)", name, holder).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": expires})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	exists, err := ds.leaderLeaseExists(name)
+	if err != nil || exists {
+		return false, err
+	}
+
+	now := time.Now()
+	err = ds.db.Exec("INSERT INTO leader_leases (name, holder, expires_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?)", name, holder, expires, now, now).Error
+	if err != nil {
+		// another instance created the lease first
+		if exists, existsErr := ds.leaderLeaseExists(name); existsErr == nil && exists {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (ds *SqlDatastore) leaderLeaseExists(name string) (bool, error) {
+	var count int
+	err := ds.db.Model(&models.LeaderLease{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+// ReleaseLeaderLease expires the holder's lease on the named work so another
+// instance can take over without waiting, e.g. because the holder is
+// stopping. It does nothing if the holder doesn't hold the lease.
+func ReleaseLeaderLease(ctx context.Context, name, holder string) error {
+	return defaultDatastore().ReleaseLeaderLease(ctx, name, holder)
+}
+
+// ReleaseLeaderLease expires the holder's lease on the named work so another
+// instance can take over without waiting, e.g. because the holder is
+// stopping. It does nothing if the holder doesn't hold the lease.
+func (ds *SqlDatastore) ReleaseLeaderLease(ctx context.Context, name, holder string) error {
+	defer traceOperation(ctx, "ReleaseLeaderLease")()
+	return ds.db.Model(&models.LeaderLease{}).
+		Where("name = ? AND holder = ?", name, holder).
+		Updates(map[string]interface{}{"expires_at": gorm.Expr("CURRENT_TIMESTAMP")}).Error
+}
+
+// ListLeaderLeases lists the leases of all background work.
+func ListLeaderLeases(ctx context.Context) ([]models.LeaderLease, error) {
+	return defaultDatastore().ListLeaderLeases(ctx)
+}
+
+// ListLeaderLeases lists the leases of all background work.
+func (ds *SqlDatastore) ListLeaderLeases(ctx context.Context) ([]models.LeaderLease, error) {
+	defer traceOperation(ctx, "ListLeaderLeases")()
+	var leases []models.LeaderLease
+	err := ds.db.Order("name asc").Find(&leases).Error
+	return leases, err
+}
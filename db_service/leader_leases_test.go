@@ -0,0 +1,93 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_LeaderLeases(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.LeaderLease{})
+
+	// leases expire by the database's clock, so expiry is simulated by
+	// moving the lease back rather than the time forward
+	expire := func() {
+		err := ds.db.Model(&models.LeaderLease{}).
+			Where("name = ?", "archive").
+			UpdateColumn("expires_at", gorm.Expr("datetime(CURRENT_TIMESTAMP, '-1 seconds')")).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		Name     string
+		Holder   string
+		Before   func()
+		Expected bool
+	}{
+		{Name: "first holder creates the lease", Holder: "broker-1", Expected: true},
+		{Name: "other holders wait", Holder: "broker-2", Expected: false},
+		{Name: "the holder renews", Holder: "broker-1", Expected: true},
+		{Name: "others take over once it expires", Holder: "broker-2", Before: expire, Expected: true},
+		{Name: "the old holder lost it", Holder: "broker-1", Expected: false},
+	}
+
+	for _, tc := range cases {
+		if tc.Before != nil {
+			tc.Before()
+		}
+		leads, err := ds.AcquireLeaderLease(ctx, "archive", tc.Holder, 30*time.Second)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.Name, err)
+		}
+		if leads != tc.Expected {
+			t.Errorf("%s: expected leads to be %v, got %v", tc.Name, tc.Expected, leads)
+		}
+	}
+
+	// releasing a lease someone else holds does nothing
+	if err := ds.ReleaseLeaderLease(ctx, "archive", "broker-1"); err != nil {
+		t.Fatal(err)
+	}
+	if leads, _ := ds.AcquireLeaderLease(ctx, "archive", "broker-1", 30*time.Second); leads {
+		t.Error("Expected releasing another holder's lease to have no effect")
+	}
+
+	if err := ds.ReleaseLeaderLease(ctx, "archive", "broker-2"); err != nil {
+		t.Fatal(err)
+	}
+	if leads, _ := ds.AcquireLeaderLease(ctx, "archive", "broker-1", 30*time.Second); !leads {
+		t.Error("Expected a released lease to be taken over immediately")
+	}
+
+	leases, err := ds.ListLeaderLeases(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leases) != 1 || leases[0].Name != "archive" || leases[0].Holder != "broker-1" {
+		t.Errorf("Expected broker-1 to hold the archive lease, got %+v", leases)
+	}
+	if remaining := time.Until(leases[0].ExpiresAt); remaining <= 0 || remaining > time.Minute {
+		t.Errorf("Expected the lease to expire in about 30s, got %s", remaining)
+	}
+}
@@ -22,7 +22,7 @@ import (
 	"github.com/jinzhu/gorm"
 )
 
-const numMigrations = 7
+const numMigrations = 27
 
 // runs schema migrations on the provided service broker database to get it up to date
 func RunMigrations(db *gorm.DB) error {
@@ -80,15 +80,120 @@ func RunMigrations(db *gorm.DB) error {
 		}
 	}
 
-	var lastMigrationNumber = -1
+	migrations[7] = func() error { // v4.2.5
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV3{})
+	}
 
-	// if we've run any migrations before, we should have a migrations table, so find the last one we ran
-	if db.HasTable("migrations") {
-		var storedMigrations []models.Migration
-		if err := db.Order("migration_id desc").Find(&storedMigrations).Error; err != nil {
-			return fmt.Errorf("Error getting last migration id even though migration table exists: %s", err)
+	migrations[8] = func() error { // v4.2.6
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV4{})
+	}
+
+	migrations[9] = func() error { // v4.2.7
+		return autoMigrateTables(db, &models.ServiceBindingCredentialsV2{})
+	}
+
+	migrations[10] = func() error { // v4.2.8
+		return autoMigrateTables(db, &models.CatalogSnapshotV1{})
+	}
+
+	migrations[11] = func() error { // v4.2.9
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV5{}, &models.InstanceUpgradeV1{})
+	}
+
+	migrations[12] = func() error { // v4.2.10
+		return autoMigrateTables(db, &models.DiscoveryCacheEntryV1{})
+	}
+
+	migrations[13] = func() error { // v4.2.11
+		return autoMigrateTables(db, &models.ArchiveV1{})
+	}
+
+	migrations[14] = func() error { // v4.2.12
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV6{})
+	}
+
+	migrations[15] = func() error { // v4.2.13
+		return autoMigrateTables(db, &models.JobV1{})
+	}
+
+	migrations[16] = func() error { // v4.2.14
+		return autoMigrateTables(db, &models.LeaderLeaseV1{})
+	}
+
+	migrations[17] = func() error { // v4.2.15
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV7{})
+	}
+
+	migrations[18] = func() error { // v4.2.16
+		return autoMigrateTables(db, &models.InstanceShareV1{})
+	}
+
+	migrations[19] = func() error { // v4.2.17
+		return autoMigrateTables(db, &models.ServiceBindingCredentialsV3{})
+	}
+
+	migrations[20] = func() error { // v4.2.18
+		return autoMigrateTables(db, &models.PlanRecordV1{})
+	}
+
+	migrations[21] = func() error { // v4.2.19
+		return autoMigrateTables(db, &models.RecordedExchangeV1{})
+	}
+
+	migrations[22] = func() error { // v4.2.20
+		// natural keys the broker already treats as unique, enforced so
+		// concurrent requests can't create duplicates
+		if err := db.Model(&models.ServiceBindingCredentialsV3{}).AddUniqueIndex(bindingInstanceIndex, "service_instance_id", "binding_id").Error; err != nil {
+			return err
+		}
+
+		return db.Model(&models.ProvisionRequestDetailsV2{}).AddUniqueIndex(provisionRequestInstanceIndex, "service_instance_id").Error
+	}
+
+	migrations[23] = func() error { // v4.2.21
+		if db.Dialect().GetName() == "sqlite3" {
+			// sqlite can't add constraints to existing tables
+			return nil
+		}
+
+		dependents := []struct {
+			table string
+			model interface{}
+		}{
+			{table: "service_binding_credentials", model: &models.ServiceBindingCredentialsV3{}},
+			{table: "cloud_operations", model: &models.CloudOperationV1{}},
+		}
+
+		for _, dependent := range dependents {
+			// rows left behind by instances purged before the constraint existed
+			orphans := fmt.Sprintf("DELETE FROM %s WHERE service_instance_id NOT IN (SELECT id FROM service_instance_details)", dependent.table)
+			if err := db.Exec(orphans).Error; err != nil {
+				return err
+			}
+
+			if err := db.Model(dependent.model).AddForeignKey("service_instance_id", "service_instance_details(id)", "RESTRICT", "RESTRICT").Error; err != nil {
+				return err
+			}
 		}
-		lastMigrationNumber = storedMigrations[0].MigrationId
+
+		return nil
+	}
+
+	migrations[24] = func() error { // v4.2.22
+		return autoMigrateTables(db, &models.ProvisionRequestDetailsV3{})
+	}
+
+	migrations[25] = func() error { // v4.2.23
+		return autoMigrateTables(db, &models.InstanceProtectionV1{})
+	}
+
+	migrations[26] = func() error { // v4.2.24
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV8{}, &models.ProvisionRequestDetailsV4{})
+	}
+
+	lastMigrationNumber, err := lastMigration(db)
+	if err != nil {
+		return err
 	}
 
 	if err := ValidateLastMigration(lastMigrationNumber); err != nil {
@@ -119,6 +224,56 @@ func RunMigrations(db *gorm.DB) error {
 	return nil
 }
 
+// lastMigration gets the number of the last migration run on the database or
+// -1 if none have been run.
+func lastMigration(db *gorm.DB) (int, error) {
+	// if we've run any migrations before, we should have a migrations table, so find the last one we ran
+	if !db.HasTable("migrations") {
+		return -1, nil
+	}
+
+	var storedMigrations []models.Migration
+	if err := db.Order("migration_id desc").Find(&storedMigrations).Error; err != nil {
+		return -1, fmt.Errorf("Error getting last migration id even though migration table exists: %s", err)
+	}
+
+	if len(storedMigrations) == 0 {
+		return -1, nil
+	}
+
+	return storedMigrations[0].MigrationId, nil
+}
+
+// CheckMigrations returns an error if the database has migrations this broker
+// hasn't run yet or is newer than this broker supports.
+func CheckMigrations(db *gorm.DB) error {
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	if pending > 0 {
+		return fmt.Errorf("%d migration(s) pending", pending)
+	}
+
+	return nil
+}
+
+// PendingMigrations returns the number of migrations the broker would run on
+// the database, or an error if the database can't be migrated.
+func PendingMigrations(db *gorm.DB) (int, error) {
+	last, err := lastMigration(db)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := ValidateLastMigration(last); err != nil {
+		return 0, err
+	}
+
+	return numMigrations - 1 - last, nil
+}
+
 // ValidateLastMigration returns an error if the database version is newer than
 // this tool supports or is too old to be updated.
 func ValidateLastMigration(lastMigration int) error {
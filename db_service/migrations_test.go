@@ -16,6 +16,7 @@ package db_service
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"os"
 	"reflect"
@@ -96,7 +97,7 @@ func TestRunMigrations_Failures(t *testing.T) {
 
 	for tn, tc := range cases {
 		t.Run(tn, func(t *testing.T) {
-			db, err := gorm.Open("sqlite3", "test.sqlite3")
+			db, err := OpenSqlite("test.sqlite3")
 			defer os.Remove("test.sqlite3")
 			if err != nil {
 				t.Fatal(err)
@@ -157,7 +158,7 @@ func TestRunMigrations(t *testing.T) {
 
 	for tn, tc := range cases {
 		t.Run(tn, func(t *testing.T) {
-			db, err := gorm.Open("sqlite3", "test.sqlite3")
+			db, err := OpenSqlite("test.sqlite3")
 			defer os.Remove("test.sqlite3")
 			if err != nil {
 				t.Fatal(err)
@@ -167,3 +168,59 @@ func TestRunMigrations(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckMigrations(t *testing.T) {
+	cases := map[string]struct {
+		LastMigration int
+		Expected      error
+	}{
+		"up-to-date": {
+			LastMigration: numMigrations - 1,
+			Expected:      nil,
+		},
+		"pending": {
+			LastMigration: numMigrations - 3,
+			Expected:      errors.New("2 migration(s) pending"),
+		},
+		"future": {
+			LastMigration: numMigrations,
+			Expected:      errors.New("The database you're connected to is newer than this tool supports."),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			db, err := OpenSqlite("test.sqlite3")
+			defer os.Remove("test.sqlite3")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := autoMigrateTables(db, &models.MigrationV1{}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := db.Save(&models.Migration{MigrationId: tc.LastMigration}).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			actual := CheckMigrations(db)
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected error %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+
+	t.Run("new-db", func(t *testing.T) {
+		db, err := OpenSqlite("test.sqlite3")
+		defer os.Remove("test.sqlite3")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := fmt.Errorf("%d migration(s) pending", numMigrations)
+		if actual := CheckMigrations(db); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected error %v, got %v", expected, actual)
+		}
+	})
+}
@@ -12,10 +12,15 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package models holds the broker's database models. Models whose fields have
+// `dao` struct tags get CRUD functions generated in db_service/dao.go, see
+// db_service/dao_generator.go for the tag format.
 package models
 
 import (
 	"encoding/json"
+
+	"github.com/pivotal/cloud-service-broker/pkg/platformcontext"
 )
 
 const (
@@ -29,12 +34,20 @@ const (
 	ClearOperationType       = ""
 )
 
+const (
+	// The states of a queued Job.
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
 // ServiceBindingCredentials holds credentials returned to the users after
 // binding to a service.
-type ServiceBindingCredentials ServiceBindingCredentialsV1
+type ServiceBindingCredentials ServiceBindingCredentialsV3
 
 // ServiceInstanceDetails holds information about provisioned services.
-type ServiceInstanceDetails ServiceInstanceDetailsV2
+type ServiceInstanceDetails ServiceInstanceDetailsV8
 
 // SetOtherDetails marshals the value passed in into a JSON string and sets
 // OtherDetails to it if marshalling was successful.
@@ -58,9 +71,59 @@ func (si ServiceInstanceDetails) GetOtherDetails(v interface{}) error {
 	return json.Unmarshal([]byte(si.OtherDetails), v)
 }
 
+// SetLabels marshals the labels applied to the instance's resources into a
+// JSON string and sets Labels to it if marshalling was successful.
+func (si *ServiceInstanceDetails) SetLabels(labels map[string]string) error {
+	out, err := json.Marshal(labels)
+	if err != nil {
+		return err
+	}
+
+	si.Labels = string(out)
+	return nil
+}
+
+// GetLabels returns the labels applied to the instance's resources. An empty
+// Labels field results in an empty map and does not error.
+func (si ServiceInstanceDetails) GetLabels() (map[string]string, error) {
+	labels := map[string]string{}
+	if si.Labels == "" {
+		return labels, nil
+	}
+
+	err := json.Unmarshal([]byte(si.Labels), &labels)
+	return labels, err
+}
+
+// SetPlatformContext marshals the names from the context object the platform
+// sent into a JSON string and sets Context to it.
+func (si *ServiceInstanceDetails) SetPlatformContext(ctx platformcontext.Context) error {
+	out, err := json.Marshal(ctx)
+	if err != nil {
+		return err
+	}
+
+	si.Context = string(out)
+	return nil
+}
+
+// GetPlatformContext returns the names from the context object the platform
+// sent. An empty Context field results in an empty context and does not
+// error.
+func (si ServiceInstanceDetails) GetPlatformContext() (platformcontext.Context, error) {
+	ctx := platformcontext.Context{}
+	if si.Context == "" {
+		return ctx, nil
+	}
+
+	err := json.Unmarshal([]byte(si.Context), &ctx)
+	return ctx, err
+}
+
 // ProvisionRequestDetails holds user-defined properties passed to a call
-// to provision a service.
-type ProvisionRequestDetails ProvisionRequestDetailsV1
+// to provision a service, a hash of them for recognizing retries and the
+// context the platform sent with them.
+type ProvisionRequestDetails ProvisionRequestDetailsV4
 
 // Migration represents the mgirations table. It holds a monotonically
 // increasing number that gets incremented with every database schema revision.
@@ -73,3 +136,33 @@ type CloudOperation CloudOperationV1
 // TerraformDeployment holds Terraform state and plan information for resources
 // that use that execution system.
 type TerraformDeployment TerraformDeploymentV1
+
+// CatalogSnapshot holds the last catalog advertised to platforms.
+type CatalogSnapshot CatalogSnapshotV1
+
+// InstanceUpgrade tracks an operator approved upgrade of an instance.
+type InstanceUpgrade InstanceUpgradeV1
+
+// DiscoveryCacheEntry caches the result of a provider discovery call.
+type DiscoveryCacheEntry DiscoveryCacheEntryV1
+
+// Archive indexes rows moved from the database to blob storage.
+type Archive ArchiveV1
+
+// Job is a unit of background work queued in the database.
+type Job JobV1
+
+// LeaderLease records which broker instance leads a kind of background work.
+type LeaderLease LeaderLeaseV1
+
+// InstanceShare records a space an instance is shared with.
+type InstanceShare InstanceShareV1
+
+// PlanRecord records the last known ID of a plan.
+type PlanRecord PlanRecordV1
+
+// RecordedExchange holds a recorded OSB request and response.
+type RecordedExchange RecordedExchangeV1
+
+// InstanceProtection records whether an instance is protected from deletion.
+type InstanceProtection InstanceProtectionV1
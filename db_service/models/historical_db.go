@@ -46,6 +46,58 @@ func (ServiceBindingCredentialsV1) TableName() string {
 	return "service_binding_credentials"
 }
 
+// ServiceBindingCredentialsV2 holds credentials returned to the users after
+// binding to a service.
+type ServiceBindingCredentialsV2 struct {
+	gorm.Model
+
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId         string
+	ServiceInstanceId string
+	BindingId         string
+
+	// RevokedAt holds when the credentials were revoked by an operator. Revoked
+	// bindings no longer work and need to be rotated by their owner.
+	RevokedAt *time.Time
+}
+
+// TableName returns a consistent table name (`service_binding_credentials`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceBindingCredentialsV2) TableName() string {
+	return "service_binding_credentials"
+}
+
+// ServiceBindingCredentialsV3 holds credentials returned to the users after
+// binding to a service.
+type ServiceBindingCredentialsV3 struct {
+	gorm.Model
+
+	OtherDetails string `gorm:"type:text" dao:"example={\"some\":[\"json\",\"blob\",\"here\"]}"`
+
+	ServiceId         string `dao:"example=1111-1111-1111"`
+	ServiceInstanceId string `dao:"key=service_instance_id,binding_id;example=2222-2222-2222"`
+	BindingId         string `dao:"key;example=0000-0000-0000"`
+
+	// RevokedAt holds when the credentials were revoked by an operator. Revoked
+	// bindings no longer work and need to be rotated by their owner.
+	RevokedAt *time.Time
+
+	// ServiceAccountEmail and ServiceAccountKeyId identify the service
+	// account and key created for the binding, so they can be deleted when
+	// the binding is. They're empty if the binding didn't create one.
+	ServiceAccountEmail string
+	ServiceAccountKeyId string
+}
+
+// TableName returns a consistent table name (`service_binding_credentials`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceBindingCredentialsV3) TableName() string {
+	return "service_binding_credentials"
+}
+
 // ServiceInstanceDetailsV1 holds information about provisioned services.
 type ServiceInstanceDetailsV1 struct {
 	ID        string `gorm:"primary_key;type:varchar(255);not null"`
@@ -107,6 +159,302 @@ func (ServiceInstanceDetailsV2) TableName() string {
 	return "service_instance_details"
 }
 
+// ServiceInstanceDetailsV3 holds information about provisioned services.
+type ServiceInstanceDetailsV3 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// Labels holds a JSON object of the labels the broker applied to the
+	// resources backing the instance.
+	Labels string `gorm:"type:text"`
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV3) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV4 holds information about provisioned services.
+type ServiceInstanceDetailsV4 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// Labels holds a JSON object of the labels the broker applied to the
+	// resources backing the instance.
+	Labels string `gorm:"type:text"`
+
+	// ProjectId holds the GCP project the instance's resources were created in.
+	ProjectId string
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV4) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV5 holds information about provisioned services.
+type ServiceInstanceDetailsV5 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// Labels holds a JSON object of the labels the broker applied to the
+	// resources backing the instance.
+	Labels string `gorm:"type:text"`
+
+	// ProjectId holds the GCP project the instance's resources were created in.
+	ProjectId string
+
+	// MaintenanceVersion holds the maintenance_info version the instance was
+	// last provisioned or upgraded to.
+	MaintenanceVersion string
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV5) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV6 holds information about provisioned services.
+type ServiceInstanceDetailsV6 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// Labels holds a JSON object of the labels the broker applied to the
+	// resources backing the instance.
+	Labels string `gorm:"type:text"`
+
+	// ProjectId holds the GCP project the instance's resources were created in.
+	ProjectId string
+
+	// MaintenanceVersion holds the maintenance_info version the instance was
+	// last provisioned or upgraded to.
+	MaintenanceVersion string
+
+	// Experiments holds a comma delimited list of the experimental behaviors
+	// enabled for the instance when it was provisioned or updated.
+	Experiments string
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV6) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV7 holds information about provisioned services.
+type ServiceInstanceDetailsV7 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string `dao:"example=Hello"`
+	Location     string `dao:"example=loc"`
+	Url          string `dao:"example=https://google.com"`
+	OtherDetails string `gorm:"type:text" dao:"example={\"some\":[\"json\",\"blob\",\"here\"]}"`
+
+	ServiceId        string `dao:"example=123-456-7890"`
+	PlanId           string `dao:"example=planid"`
+	SpaceGuid        string `dao:"example=0000-0000-0000"`
+	OrganizationGuid string `dao:"example=1111-1111-1111"`
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// Labels holds a JSON object of the labels the broker applied to the
+	// resources backing the instance.
+	Labels string `gorm:"type:text"`
+
+	// ProjectId holds the GCP project the instance's resources were created in.
+	ProjectId string
+
+	// MaintenanceVersion holds the maintenance_info version the instance was
+	// last provisioned or upgraded to.
+	MaintenanceVersion string
+
+	// Experiments holds a comma delimited list of the experimental behaviors
+	// enabled for the instance when it was provisioned or updated.
+	Experiments string
+
+	// KmsKeyName holds the Cloud KMS key the user chose to encrypt the
+	// instance's resources, empty if they're encrypted by Google-managed keys.
+	KmsKeyName string
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV7) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV8 holds information about provisioned services.
+type ServiceInstanceDetailsV8 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string `dao:"example=Hello"`
+	Location     string `dao:"example=loc"`
+	Url          string `dao:"example=https://google.com"`
+	OtherDetails string `gorm:"type:text" dao:"example={\"some\":[\"json\",\"blob\",\"here\"]}"`
+
+	ServiceId        string `dao:"example=123-456-7890"`
+	PlanId           string `dao:"example=planid"`
+	SpaceGuid        string `dao:"example=0000-0000-0000"`
+	OrganizationGuid string `dao:"example=1111-1111-1111"`
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// Labels holds a JSON object of the labels the broker applied to the
+	// resources backing the instance.
+	Labels string `gorm:"type:text"`
+
+	// ProjectId holds the GCP project the instance's resources were created in.
+	ProjectId string
+
+	// MaintenanceVersion holds the maintenance_info version the instance was
+	// last provisioned or upgraded to.
+	MaintenanceVersion string
+
+	// Experiments holds a comma delimited list of the experimental behaviors
+	// enabled for the instance when it was provisioned or updated.
+	Experiments string
+
+	// KmsKeyName holds the Cloud KMS key the user chose to encrypt the
+	// instance's resources, empty if they're encrypted by Google-managed keys.
+	KmsKeyName string
+
+	// Context holds a JSON object of the names the platform sent in the
+	// context of the provision request, e.g. its space name or Kubernetes
+	// namespace, updated when an update request sends them.
+	Context string `gorm:"type:text" dao:"example={\"platform\":\"cloudfoundry\",\"space_name\":\"dev\"}"`
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV8) TableName() string {
+	return "service_instance_details"
+}
+
 // ProvisionRequestDetailsV1 holds user-defined properties passed to a call
 // to provision a service.
 type ProvisionRequestDetailsV1 struct {
@@ -142,6 +490,54 @@ func (ProvisionRequestDetailsV2) TableName() string {
 	return "provision_request_details"
 }
 
+// ProvisionRequestDetailsV3 holds user-defined properties passed to a call
+// to provision a service and a hash of them for recognizing retries.
+type ProvisionRequestDetailsV3 struct {
+	gorm.Model `dao:"hard_delete"`
+
+	ServiceInstanceId string `gorm:"uniqueIndex" dao:"example=2222-2222-2222"`
+
+	// is a json.Marshal of models.ProvisionDetails, with any values the
+	// operator doesn't keep removed
+	RequestDetails string `gorm:"type:text" dao:"example={\"some\":[\"json\",\"blob\",\"here\"]}"`
+
+	// is a hash of the provision request the platform made
+	ParametersHash string `dao:"example=c0ffee"`
+}
+
+// TableName returns a consistent table name (`provision_request_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ProvisionRequestDetailsV3) TableName() string {
+	return "provision_request_details"
+}
+
+// ProvisionRequestDetailsV4 holds user-defined properties passed to a call
+// to provision a service, a hash of them for recognizing retries and the
+// context the platform sent with them.
+type ProvisionRequestDetailsV4 struct {
+	gorm.Model `dao:"hard_delete"`
+
+	ServiceInstanceId string `gorm:"uniqueIndex" dao:"example=2222-2222-2222"`
+
+	// is a json.Marshal of models.ProvisionDetails, with any values the
+	// operator doesn't keep removed
+	RequestDetails string `gorm:"type:text" dao:"example={\"some\":[\"json\",\"blob\",\"here\"]}"`
+
+	// is a hash of the provision request the platform made
+	ParametersHash string `dao:"example=c0ffee"`
+
+	// is the context object the platform sent with the provision request
+	Context string `gorm:"type:text" dao:"example={\"platform\":\"kubernetes\"}"`
+}
+
+// TableName returns a consistent table name (`provision_request_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ProvisionRequestDetailsV4) TableName() string {
+	return "provision_request_details"
+}
+
 // MigrationV1 represents the mgirations table. It holds a monotonically
 // increasing number that gets incremented with every database schema revision.
 type MigrationV1 struct {
@@ -161,19 +557,19 @@ func (MigrationV1) TableName() string {
 // long-running operations.
 // As-of version 4.1.0, this table is no longer necessary.
 type CloudOperationV1 struct {
-	gorm.Model
+	gorm.Model `dao:"hard_delete"`
 
-	Name          string
-	Status        string
-	OperationType string
+	Name          string `dao:"example=operation-1"`
+	Status        string `dao:"example=RUNNING"`
+	OperationType string `dao:"example=insert"`
 	ErrorMessage  string `gorm:"type:text"`
 	InsertTime    string
 	StartTime     string
-	TargetId      string
+	TargetId      string `dao:"example=1234567890"`
 	TargetLink    string
 
 	ServiceId         string
-	ServiceInstanceId string
+	ServiceInstanceId string `dao:"find_all;example=2222-2222-2222"`
 }
 
 // TableName returns a consistent table name (`cloud_operations`) for gorm so
@@ -212,17 +608,17 @@ type TerraformDeploymentV1 struct {
 	DeletedAt *time.Time
 
 	// Workspace contains a JSON serialized version of the Terraform workspace.
-	Workspace string `sql:"type:mediumtext"`
+	Workspace string `sql:"type:mediumtext" dao:"example={}"`
 
 	// LastOperationType describes the last operation being performed on the resource.
-	LastOperationType string
+	LastOperationType string `dao:"example=create"`
 
 	// LastOperationState holds one of the following strings "in progress", "succeeded", "failed".
 	// These mirror the OSB API.
-	LastOperationState string
+	LastOperationState string `dao:"example=in progress"`
 
 	// LastOperationMessage is a description that can be passed back to the user.
-	LastOperationMessage string `sql:"type:text"`
+	LastOperationMessage string `sql:"type:text" dao:"example=Started 2018-01-01"`
 }
 
 // TableName returns a consistent table name (`tf_deployment`) for gorm so
@@ -231,3 +627,238 @@ type TerraformDeploymentV1 struct {
 func (TerraformDeploymentV1) TableName() string {
 	return "terraform_deployments"
 }
+
+// CatalogSnapshotV1 records a catalog that was advertised to platforms so the
+// next catalog can be compared against it.
+type CatalogSnapshotV1 struct {
+	gorm.Model
+
+	// Catalog contains the JSON serialized list of services.
+	Catalog string `sql:"type:mediumtext"`
+
+	// Fingerprint is a digest of the catalog used to quickly detect changes.
+	Fingerprint string
+}
+
+// TableName returns a consistent table name (`catalog_snapshots`) for gorm so
+// multiple structs from different versions of the database all operate on the
+// same table.
+func (CatalogSnapshotV1) TableName() string {
+	return "catalog_snapshots"
+}
+
+// InstanceUpgradeV1 tracks an instance's upgrade to a new maintenance_info
+// version for services that need an operator to approve upgrades.
+type InstanceUpgradeV1 struct {
+	gorm.Model
+
+	ServiceInstanceId string
+	ServiceId         string
+	FromVersion       string
+	ToVersion         string
+
+	// State is one of pending, approved or completed.
+	State string
+
+	// ApprovedAt holds the time an operator approved the upgrade.
+	ApprovedAt *time.Time
+}
+
+// TableName returns a consistent table name (`instance_upgrades`) for gorm so
+// multiple structs from different versions of the database all operate on the
+// same table.
+func (InstanceUpgradeV1) TableName() string {
+	return "instance_upgrades"
+}
+
+// DiscoveryCacheEntryV1 caches the result of a slow provider discovery call,
+// like listing CloudSQL tiers, so it's shared by every broker replica.
+type DiscoveryCacheEntryV1 struct {
+	gorm.Model
+
+	CacheKey string `gorm:"unique_index"`
+
+	// Value contains the JSON serialized discovery result.
+	Value string `sql:"type:mediumtext"`
+
+	// FetchedAt holds the time the value was fetched from the provider.
+	FetchedAt time.Time
+}
+
+// TableName returns a consistent table name (`discovery_cache_entries`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (DiscoveryCacheEntryV1) TableName() string {
+	return "discovery_cache_entries"
+}
+
+// ArchiveV1 indexes a compressed object in blob storage holding rows that were
+// moved out of the database to keep it small.
+type ArchiveV1 struct {
+	gorm.Model
+
+	// SourceTable is the name of the table the rows were taken from.
+	SourceTable string
+
+	// Location is the URL of the object, e.g. gs://bucket/path.jsonl.gz
+	Location string
+
+	// Records is the number of rows in the object.
+	Records int
+
+	// FirstRecordId and LastRecordId are the lowest and highest IDs of the rows.
+	FirstRecordId uint
+	LastRecordId  uint
+
+	// OldestRecord and NewestRecord bound the rows' last update times.
+	OldestRecord time.Time
+	NewestRecord time.Time
+}
+
+// TableName returns a consistent table name (`archives`) for gorm so multiple
+// structs from different versions of the database all operate on the same
+// table.
+func (ArchiveV1) TableName() string {
+	return "archives"
+}
+
+// JobV1 is a unit of background work, e.g. running Terraform for an
+// asynchronous provision, queued in the database so it survives restarts and
+// can be run by any broker instance.
+type JobV1 struct {
+	gorm.Model
+
+	// Kind selects the handler that runs the job.
+	Kind string `gorm:"index"`
+
+	// Target identifies what the job works on, e.g. a Terraform deployment
+	// ID.
+	Target string `gorm:"index"`
+
+	// Payload holds the JSON encoded arguments of the handler.
+	Payload string `gorm:"type:text"`
+
+	// State is one of queued, running, succeeded or failed.
+	State       string `gorm:"index"`
+	Attempts    int
+	MaxAttempts int
+	LastError   string `gorm:"type:text"`
+
+	// RunAfter delays the job, e.g. to back off between attempts.
+	RunAfter time.Time
+
+	// Worker identifies the broker instance running the job. Other instances
+	// assume it stopped and run the job again once LeaseExpiresAt passes.
+	Worker         string
+	LeaseExpiresAt *time.Time
+}
+
+// TableName returns a consistent table name (`jobs`) for gorm so multiple
+// structs from different versions of the database all operate on the same
+// table.
+func (JobV1) TableName() string {
+	return "jobs"
+}
+
+// LeaderLeaseV1 records which broker instance leads a kind of background
+// work, e.g. archiving, so it runs on one instance at a time.
+type LeaderLeaseV1 struct {
+	// Name identifies the work being led.
+	Name string `gorm:"primary_key;type:varchar(255);not null"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Holder identifies the leading instance. Other instances take over once
+	// ExpiresAt passes without the holder renewing the lease.
+	Holder    string
+	ExpiresAt time.Time
+}
+
+// TableName returns a consistent table name (`leader_leases`) for gorm so
+// multiple structs from different versions of the database all operate on
+// the same table.
+func (LeaderLeaseV1) TableName() string {
+	return "leader_leases"
+}
+
+// InstanceShareV1 records a space, other than the one it was created in, that
+// an instance is shared with. Shares are recorded the first time an app in the
+// space binds to the instance.
+type InstanceShareV1 struct {
+	gorm.Model
+
+	ServiceInstanceId string `gorm:"index"`
+	OrganizationGuid  string
+	SpaceGuid         string
+}
+
+// TableName returns a consistent table name (`instance_shares`) for gorm so
+// multiple structs from different versions of the database all operate on the
+// same table.
+func (InstanceShareV1) TableName() string {
+	return "instance_shares"
+}
+
+// PlanRecordV1 records the ID a plan had the last time the broker started so
+// plans whose ID changes can be detected.
+type PlanRecordV1 struct {
+	gorm.Model
+
+	ServiceId   string
+	ServiceName string `gorm:"index"`
+	PlanName    string
+	PlanId      string
+}
+
+// TableName returns a consistent table name (`plan_records`) for gorm so
+// multiple structs from different versions of the database all operate on the
+// same table.
+func (PlanRecordV1) TableName() string {
+	return "plan_records"
+}
+
+// RecordedExchangeV1 holds an OSB request and the broker's response, with
+// credentials redacted, so failed operations can be triaged with platform
+// teams.
+type RecordedExchangeV1 struct {
+	gorm.Model
+
+	InstanceId    string `gorm:"index"`
+	BindingId     string
+	CorrelationId string
+
+	Method       string
+	Path         string `gorm:"type:text"`
+	ApiVersion   string
+	RequestBody  string `gorm:"type:text"`
+	Status       int
+	ResponseBody string `gorm:"type:text"`
+
+	// DurationMillis is how long the broker took to respond.
+	DurationMillis int64
+}
+
+// TableName returns a consistent table name (`recorded_exchanges`) for gorm so
+// multiple structs from different versions of the database all operate on the
+// same table.
+func (RecordedExchangeV1) TableName() string {
+	return "recorded_exchanges"
+}
+
+// InstanceProtectionV1 records whether an operator protected an instance from
+// being deprovisioned, overriding its plan's default.
+type InstanceProtectionV1 struct {
+	gorm.Model
+
+	ServiceInstanceId string `gorm:"index"`
+	Protected         bool
+	Reason            string `gorm:"type:text"`
+}
+
+// TableName returns a consistent table name (`instance_protections`) for gorm
+// so multiple structs from different versions of the database all operate on
+// the same table.
+func (InstanceProtectionV1) TableName() string {
+	return "instance_protections"
+}
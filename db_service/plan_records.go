@@ -0,0 +1,45 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// ListPlanRecords lists the last known IDs of the plans.
+func ListPlanRecords(ctx context.Context) ([]models.PlanRecord, error) {
+	return defaultDatastore().ListPlanRecords(ctx)
+}
+
+// ListPlanRecords lists the last known IDs of the plans.
+func (ds *SqlDatastore) ListPlanRecords(ctx context.Context) ([]models.PlanRecord, error) {
+	defer traceOperation(ctx, "ListPlanRecords")()
+	var records []models.PlanRecord
+	err := ds.db.Order("id").Find(&records).Error
+	return records, err
+}
+
+// SavePlanRecord creates or updates the record of a plan's ID.
+func SavePlanRecord(ctx context.Context, record *models.PlanRecord) error {
+	return defaultDatastore().SavePlanRecord(ctx, record)
+}
+
+// SavePlanRecord creates or updates the record of a plan's ID.
+func (ds *SqlDatastore) SavePlanRecord(ctx context.Context, record *models.PlanRecord) error {
+	defer traceOperation(ctx, "SavePlanRecord")()
+	return ds.db.Save(record).Error
+}
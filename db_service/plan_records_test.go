@@ -0,0 +1,56 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_PlanRecords(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.PlanRecord{})
+
+	records := []models.PlanRecord{
+		{ServiceName: "csb-google-mysql", PlanName: "small", PlanId: "plan-1"},
+		{ServiceName: "csb-google-mysql", PlanName: "large", PlanId: "plan-2"},
+	}
+	for i := range records {
+		if err := ds.SavePlanRecord(ctx, &records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records[0].PlanId = "plan-3"
+	if err := ds.SavePlanRecord(ctx, &records[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := ds.ListPlanRecords(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, record := range actual {
+		ids = append(ids, record.PlanName+"="+record.PlanId)
+	}
+	if len(ids) != 2 || ids[0] != "small=plan-3" || ids[1] != "large=plan-2" {
+		t.Errorf("Expected records [small=plan-3 large=plan-2], got %v", ids)
+	}
+}
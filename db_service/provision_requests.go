@@ -0,0 +1,58 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// GetProvisionRequestDetailsByInstanceId gets the details of the request that
+// provisioned the service instance.
+func GetProvisionRequestDetailsByInstanceId(ctx context.Context, instanceId string) (*models.ProvisionRequestDetails, error) {
+	return defaultDatastore().GetProvisionRequestDetailsByInstanceId(ctx, instanceId)
+}
+
+// GetProvisionRequestDetailsByInstanceId gets the details of the request that
+// provisioned the service instance.
+func (ds *SqlDatastore) GetProvisionRequestDetailsByInstanceId(ctx context.Context, instanceId string) (*models.ProvisionRequestDetails, error) {
+	defer traceOperation(ctx, "GetProvisionRequestDetailsByInstanceId")()
+	record := models.ProvisionRequestDetails{}
+	if err := ds.db.Where("service_instance_id = ?", instanceId).First(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// DeleteProvisionRequestDetailsDeletedBefore permanently deletes the request
+// details of service instances deleted before the cutoff and returns how many
+// there were.
+func DeleteProvisionRequestDetailsDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return defaultDatastore().DeleteProvisionRequestDetailsDeletedBefore(ctx, cutoff)
+}
+
+// DeleteProvisionRequestDetailsDeletedBefore permanently deletes the request
+// details of service instances deleted before the cutoff and returns how many
+// there were.
+func (ds *SqlDatastore) DeleteProvisionRequestDetailsDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	defer traceOperation(ctx, "DeleteProvisionRequestDetailsDeletedBefore")()
+	result := ds.db.Unscoped().
+		Where("service_instance_id IN (SELECT id FROM service_instance_details WHERE deleted_at < ?)", cutoff).
+		Delete(&models.ProvisionRequestDetails{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,103 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_GetProvisionRequestDetailsByInstanceId(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+	_, instance := createProvisionRequestDetailsInstance()
+
+	if _, err := ds.GetProvisionRequestDetailsByInstanceId(ctx, instance.ServiceInstanceId); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing record got %v", err)
+	}
+
+	if err := ds.CreateProvisionRequestDetails(ctx, &instance); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := ds.GetProvisionRequestDetailsByInstanceId(ctx, instance.ServiceInstanceId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureProvisionRequestDetailsFieldsMatch(t, &instance, actual)
+
+	if err := ds.DeleteProvisionRequestDetails(ctx, actual); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ds.GetProvisionRequestDetailsByInstanceId(ctx, instance.ServiceInstanceId); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected an ErrRecordNotFound trying to get deleted record got %v", err)
+	}
+}
+
+func TestSqlDatastore_DeleteProvisionRequestDetailsDeletedBefore(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+	now := time.Now()
+
+	cases := map[string]struct {
+		DeletedAt *time.Time
+		Kept      bool
+	}{
+		"live":             {DeletedAt: nil, Kept: true},
+		"deleted-recently": {DeletedAt: timePtr(now.Add(-time.Hour)), Kept: true},
+		"deleted-long-ago": {DeletedAt: timePtr(now.Add(-48 * time.Hour)), Kept: false},
+	}
+
+	for id, tc := range cases {
+		instance := models.ServiceInstanceDetails{ID: id}
+		if err := ds.CreateServiceInstanceDetails(ctx, &instance); err != nil {
+			t.Fatal(err)
+		}
+		if tc.DeletedAt != nil {
+			if err := ds.db.Model(&instance).Unscoped().Update("deleted_at", tc.DeletedAt).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := ds.CreateProvisionRequestDetails(ctx, &models.ProvisionRequestDetails{ServiceInstanceId: id, RequestDetails: "{}"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := ds.DeleteProvisionRequestDetailsDeletedBefore(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 request details to be deleted, got %d", deleted)
+	}
+
+	for id, tc := range cases {
+		t.Run(id, func(t *testing.T) {
+			_, err := ds.GetProvisionRequestDetailsByInstanceId(ctx, id)
+			if kept := err == nil; kept != tc.Kept {
+				t.Errorf("Expected kept to be %v, got error %v", tc.Kept, err)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,259 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// CountServiceInstanceDetails counts the instances that match all non-zero
+// fields of the given conditions.
+func CountServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) (int, error) {
+	return defaultDatastore().CountServiceInstanceDetails(ctx, conditions)
+}
+
+// CountServiceInstanceDetails counts the instances that match all non-zero
+// fields of the given conditions.
+func (ds *SqlDatastore) CountServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) (int, error) {
+	defer traceOperation(ctx, "CountServiceInstanceDetails")()
+	count := 0
+	err := ds.db.Model(&models.ServiceInstanceDetails{}).Where(&conditions).Count(&count).Error
+	return count, err
+}
+
+// ListServiceInstanceHistory lists the instances that existed at any time
+// since the given one, including deleted instances, oldest first.
+func ListServiceInstanceHistory(ctx context.Context, since time.Time) ([]models.ServiceInstanceDetails, error) {
+	return defaultDatastore().ListServiceInstanceHistory(ctx, since)
+}
+
+// ListServiceInstanceHistory lists the instances that existed at any time
+// since the given one, including deleted instances, oldest first.
+func (ds *SqlDatastore) ListServiceInstanceHistory(ctx context.Context, since time.Time) ([]models.ServiceInstanceDetails, error) {
+	defer traceOperation(ctx, "ListServiceInstanceHistory")()
+	var instances []models.ServiceInstanceDetails
+	err := ds.db.Unscoped().Where("deleted_at IS NULL OR deleted_at >= ?", since).Order("created_at, id").Find(&instances).Error
+	return instances, err
+}
+
+// ListServiceBindingCredentials lists the bindings that match all non-zero
+// fields of the given conditions.
+func ListServiceBindingCredentials(ctx context.Context, conditions models.ServiceBindingCredentials) ([]models.ServiceBindingCredentials, error) {
+	return defaultDatastore().ListServiceBindingCredentials(ctx, conditions)
+}
+
+// ListServiceBindingCredentials lists the bindings that match all non-zero
+// fields of the given conditions.
+func (ds *SqlDatastore) ListServiceBindingCredentials(ctx context.Context, conditions models.ServiceBindingCredentials) ([]models.ServiceBindingCredentials, error) {
+	defer traceOperation(ctx, "ListServiceBindingCredentials")()
+	var bindings []models.ServiceBindingCredentials
+	err := ds.db.Where(&conditions).Order("id").Find(&bindings).Error
+	return bindings, err
+}
+
+// ListRevokedServiceBindingCredentials lists the bindings whose credentials
+// were revoked and haven't been unbound yet.
+func ListRevokedServiceBindingCredentials(ctx context.Context) ([]models.ServiceBindingCredentials, error) {
+	return defaultDatastore().ListRevokedServiceBindingCredentials(ctx)
+}
+
+// ListRevokedServiceBindingCredentials lists the bindings whose credentials
+// were revoked and haven't been unbound yet.
+func (ds *SqlDatastore) ListRevokedServiceBindingCredentials(ctx context.Context) ([]models.ServiceBindingCredentials, error) {
+	defer traceOperation(ctx, "ListRevokedServiceBindingCredentials")()
+	var bindings []models.ServiceBindingCredentials
+	err := ds.db.Where("revoked_at IS NOT NULL").Order("id").Find(&bindings).Error
+	return bindings, err
+}
+
+// ListDeletedServiceBindingCredentialsWithServiceAccounts lists the unbound
+// bindings that recorded a service account created for them.
+func ListDeletedServiceBindingCredentialsWithServiceAccounts(ctx context.Context) ([]models.ServiceBindingCredentials, error) {
+	return defaultDatastore().ListDeletedServiceBindingCredentialsWithServiceAccounts(ctx)
+}
+
+// ListDeletedServiceBindingCredentialsWithServiceAccounts lists the unbound
+// bindings that recorded a service account created for them.
+func (ds *SqlDatastore) ListDeletedServiceBindingCredentialsWithServiceAccounts(ctx context.Context) ([]models.ServiceBindingCredentials, error) {
+	defer traceOperation(ctx, "ListDeletedServiceBindingCredentialsWithServiceAccounts")()
+	var bindings []models.ServiceBindingCredentials
+	err := ds.db.Unscoped().Where("deleted_at IS NOT NULL AND service_account_email <> ''").Order("id").Find(&bindings).Error
+	return bindings, err
+}
+
+// GetLatestCatalogSnapshot gets the most recently saved catalog snapshot or
+// nil if no catalog has been saved.
+func GetLatestCatalogSnapshot(ctx context.Context) (*models.CatalogSnapshot, error) {
+	return defaultDatastore().GetLatestCatalogSnapshot(ctx)
+}
+
+// GetLatestCatalogSnapshot gets the most recently saved catalog snapshot or
+// nil if no catalog has been saved.
+func (ds *SqlDatastore) GetLatestCatalogSnapshot(ctx context.Context) (*models.CatalogSnapshot, error) {
+	defer traceOperation(ctx, "GetLatestCatalogSnapshot")()
+	var snapshots []models.CatalogSnapshot
+	if err := ds.db.Order("id desc").Limit(1).Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+
+	return &snapshots[0], nil
+}
+
+// CreateCatalogSnapshot saves a new catalog snapshot.
+func CreateCatalogSnapshot(ctx context.Context, snapshot *models.CatalogSnapshot) error {
+	return defaultDatastore().CreateCatalogSnapshot(ctx, snapshot)
+}
+
+// CreateCatalogSnapshot saves a new catalog snapshot.
+func (ds *SqlDatastore) CreateCatalogSnapshot(ctx context.Context, snapshot *models.CatalogSnapshot) error {
+	defer traceOperation(ctx, "CreateCatalogSnapshot")()
+	return ds.db.Create(snapshot).Error
+}
+
+// ListServiceInstanceDetails lists the instances that match all non-zero
+// fields of the given conditions.
+func ListServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) ([]models.ServiceInstanceDetails, error) {
+	return defaultDatastore().ListServiceInstanceDetails(ctx, conditions)
+}
+
+// ListServiceInstanceDetails lists the instances that match all non-zero
+// fields of the given conditions.
+func (ds *SqlDatastore) ListServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) ([]models.ServiceInstanceDetails, error) {
+	defer traceOperation(ctx, "ListServiceInstanceDetails")()
+	var instances []models.ServiceInstanceDetails
+	err := ds.db.Where(&conditions).Order("id").Find(&instances).Error
+	return instances, err
+}
+
+// GetInstanceUpgrade gets the upgrade of the instance to the given version or
+// nil if there isn't one.
+func GetInstanceUpgrade(ctx context.Context, instanceId, toVersion string) (*models.InstanceUpgrade, error) {
+	return defaultDatastore().GetInstanceUpgrade(ctx, instanceId, toVersion)
+}
+
+// GetInstanceUpgrade gets the upgrade of the instance to the given version or
+// nil if there isn't one.
+func (ds *SqlDatastore) GetInstanceUpgrade(ctx context.Context, instanceId, toVersion string) (*models.InstanceUpgrade, error) {
+	defer traceOperation(ctx, "GetInstanceUpgrade")()
+	var upgrades []models.InstanceUpgrade
+	err := ds.db.Where("service_instance_id = ? AND to_version = ?", instanceId, toVersion).Order("id desc").Limit(1).Find(&upgrades).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(upgrades) == 0 {
+		return nil, nil
+	}
+
+	return &upgrades[0], nil
+}
+
+// SaveInstanceUpgrade creates or updates an instance upgrade.
+func SaveInstanceUpgrade(ctx context.Context, upgrade *models.InstanceUpgrade) error {
+	return defaultDatastore().SaveInstanceUpgrade(ctx, upgrade)
+}
+
+// SaveInstanceUpgrade creates or updates an instance upgrade.
+func (ds *SqlDatastore) SaveInstanceUpgrade(ctx context.Context, upgrade *models.InstanceUpgrade) error {
+	defer traceOperation(ctx, "SaveInstanceUpgrade")()
+	return ds.db.Save(upgrade).Error
+}
+
+// GetDiscoveryCacheEntry gets the cached discovery result with the given key
+// or nil if it isn't cached.
+func GetDiscoveryCacheEntry(ctx context.Context, key string) (*models.DiscoveryCacheEntry, error) {
+	return defaultDatastore().GetDiscoveryCacheEntry(ctx, key)
+}
+
+// GetDiscoveryCacheEntry gets the cached discovery result with the given key
+// or nil if it isn't cached.
+func (ds *SqlDatastore) GetDiscoveryCacheEntry(ctx context.Context, key string) (*models.DiscoveryCacheEntry, error) {
+	defer traceOperation(ctx, "GetDiscoveryCacheEntry")()
+	var entries []models.DiscoveryCacheEntry
+	if err := ds.db.Where("cache_key = ?", key).Limit(1).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	return &entries[0], nil
+}
+
+// ListDiscoveryCacheEntries lists every cached discovery result.
+func ListDiscoveryCacheEntries(ctx context.Context) ([]models.DiscoveryCacheEntry, error) {
+	return defaultDatastore().ListDiscoveryCacheEntries(ctx)
+}
+
+// ListDiscoveryCacheEntries lists every cached discovery result.
+func (ds *SqlDatastore) ListDiscoveryCacheEntries(ctx context.Context) ([]models.DiscoveryCacheEntry, error) {
+	defer traceOperation(ctx, "ListDiscoveryCacheEntries")()
+	var entries []models.DiscoveryCacheEntry
+	err := ds.db.Order("cache_key").Find(&entries).Error
+	return entries, err
+}
+
+// SaveDiscoveryCacheEntry creates or replaces the cached discovery result
+// with the entry's key.
+func SaveDiscoveryCacheEntry(ctx context.Context, entry *models.DiscoveryCacheEntry) error {
+	return defaultDatastore().SaveDiscoveryCacheEntry(ctx, entry)
+}
+
+// SaveDiscoveryCacheEntry creates or replaces the cached discovery result
+// with the entry's key.
+func (ds *SqlDatastore) SaveDiscoveryCacheEntry(ctx context.Context, entry *models.DiscoveryCacheEntry) error {
+	defer traceOperation(ctx, "SaveDiscoveryCacheEntry")()
+	existing, err := ds.GetDiscoveryCacheEntry(ctx, entry.CacheKey)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		entry.ID = existing.ID
+		entry.CreatedAt = existing.CreatedAt
+	}
+
+	return ds.db.Save(entry).Error
+}
+
+// ListTerraformDeployments lists the most recently updated Terraform
+// deployments, newest first, without their workspaces. A limit of zero lists
+// every deployment.
+func ListTerraformDeployments(ctx context.Context, limit int) ([]models.TerraformDeployment, error) {
+	return defaultDatastore().ListTerraformDeployments(ctx, limit)
+}
+
+// ListTerraformDeployments lists the most recently updated Terraform
+// deployments, newest first, without their workspaces. A limit of zero lists
+// every deployment.
+func (ds *SqlDatastore) ListTerraformDeployments(ctx context.Context, limit int) ([]models.TerraformDeployment, error) {
+	defer traceOperation(ctx, "ListTerraformDeployments")()
+	query := ds.db.Select("id, created_at, updated_at, last_operation_type, last_operation_state, last_operation_message").Order("updated_at desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var deployments []models.TerraformDeployment
+	err := query.Find(&deployments).Error
+	return deployments, err
+}
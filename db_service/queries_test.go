@@ -0,0 +1,364 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_CountServiceInstanceDetails(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	ctx := context.Background()
+
+	instances := []models.ServiceInstanceDetails{
+		{ID: "a", ServiceId: "svc-1", OrganizationGuid: "org-1", SpaceGuid: "space-1"},
+		{ID: "b", ServiceId: "svc-1", OrganizationGuid: "org-1", SpaceGuid: "space-2"},
+		{ID: "c", ServiceId: "svc-1", OrganizationGuid: "org-2", SpaceGuid: "space-3"},
+		{ID: "d", ServiceId: "svc-2", OrganizationGuid: "org-1", SpaceGuid: "space-1"},
+		{ID: "deleted", ServiceId: "svc-1", OrganizationGuid: "org-1", SpaceGuid: "space-1"},
+	}
+	for _, instance := range instances {
+		instance := instance
+		if err := ds.CreateServiceInstanceDetails(ctx, &instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ds.DeleteServiceInstanceDetailsById(ctx, "deleted"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		Conditions models.ServiceInstanceDetails
+		Expected   int
+	}{
+		"everything":       {Conditions: models.ServiceInstanceDetails{}, Expected: 4},
+		"by service":       {Conditions: models.ServiceInstanceDetails{ServiceId: "svc-1"}, Expected: 3},
+		"by service + org": {Conditions: models.ServiceInstanceDetails{ServiceId: "svc-1", OrganizationGuid: "org-1"}, Expected: 2},
+		"by space":         {Conditions: models.ServiceInstanceDetails{SpaceGuid: "space-1"}, Expected: 2},
+		"no matches":       {Conditions: models.ServiceInstanceDetails{ServiceId: "svc-3"}, Expected: 0},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := ds.CountServiceInstanceDetails(ctx, tc.Conditions)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != tc.Expected {
+				t.Errorf("Expected count %d, got %d", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestSqlDatastore_ListServiceInstanceHistory(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"active", "deleted-recently", "deleted-long-ago"} {
+		if err := ds.CreateServiceInstanceDetails(ctx, &models.ServiceInstanceDetails{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"deleted-recently", "deleted-long-ago"} {
+		if err := ds.DeleteServiceInstanceDetailsById(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	longAgo := time.Now().Add(-48 * time.Hour)
+	if err := ds.db.Unscoped().Model(&models.ServiceInstanceDetails{}).Where("id = ?", "deleted-long-ago").Update("deleted_at", longAgo).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	instances, err := ds.ListServiceInstanceHistory(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []string
+	for _, instance := range instances {
+		actual = append(actual, instance.ID)
+	}
+
+	expected := []string{"active", "deleted-recently"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected instances %v, got %v", expected, actual)
+	}
+}
+
+func TestSqlDatastore_ListServiceBindingCredentials(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	ctx := context.Background()
+
+	revokedAt := time.Now()
+	bindings := []models.ServiceBindingCredentials{
+		{ServiceId: "svc-1", ServiceInstanceId: "instance-a", BindingId: "binding-1"},
+		{ServiceId: "svc-1", ServiceInstanceId: "instance-b", BindingId: "binding-2", RevokedAt: &revokedAt},
+		{ServiceId: "svc-2", ServiceInstanceId: "instance-c", BindingId: "binding-3"},
+		{ServiceId: "svc-1", ServiceInstanceId: "instance-a", BindingId: "deleted", RevokedAt: &revokedAt},
+		{ServiceId: "svc-2", ServiceInstanceId: "instance-c", BindingId: "deleted-sa", ServiceAccountEmail: "csb-deleted-sa@p.iam.gserviceaccount.com"},
+	}
+	for _, binding := range bindings {
+		binding := binding
+		if err := ds.CreateServiceBindingCredentials(ctx, &binding); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"deleted", "deleted-sa"} {
+		if err := ds.DeleteServiceBindingCredentialsByBindingId(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[string]struct {
+		List     func() ([]models.ServiceBindingCredentials, error)
+		Expected []string
+	}{
+		"everything": {
+			List: func() ([]models.ServiceBindingCredentials, error) {
+				return ds.ListServiceBindingCredentials(ctx, models.ServiceBindingCredentials{})
+			},
+			Expected: []string{"binding-1", "binding-2", "binding-3"},
+		},
+		"by service": {
+			List: func() ([]models.ServiceBindingCredentials, error) {
+				return ds.ListServiceBindingCredentials(ctx, models.ServiceBindingCredentials{ServiceId: "svc-1"})
+			},
+			Expected: []string{"binding-1", "binding-2"},
+		},
+		"revoked": {
+			List: func() ([]models.ServiceBindingCredentials, error) {
+				return ds.ListRevokedServiceBindingCredentials(ctx)
+			},
+			Expected: []string{"binding-2"},
+		},
+		"deleted with service accounts": {
+			List: func() ([]models.ServiceBindingCredentials, error) {
+				return ds.ListDeletedServiceBindingCredentialsWithServiceAccounts(ctx)
+			},
+			Expected: []string{"deleted-sa"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			results, err := tc.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual []string
+			for _, binding := range results {
+				actual = append(actual, binding.BindingId)
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected bindings %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestSqlDatastore_GetLatestCatalogSnapshot(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.CatalogSnapshot{})
+	ctx := context.Background()
+
+	snapshot, err := ds.GetLatestCatalogSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot != nil {
+		t.Errorf("Expected no snapshot, got %v", snapshot)
+	}
+
+	for _, fingerprint := range []string{"first", "second"} {
+		if err := ds.CreateCatalogSnapshot(ctx, &models.CatalogSnapshot{Catalog: "[]", Fingerprint: fingerprint}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot, err = ds.GetLatestCatalogSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot == nil || snapshot.Fingerprint != "second" {
+		t.Errorf("Expected the latest snapshot, got %v", snapshot)
+	}
+}
+
+func TestSqlDatastore_ListServiceInstanceDetails(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	ctx := context.Background()
+
+	for _, instance := range []models.ServiceInstanceDetails{
+		{ID: "b", ServiceId: "svc-1"},
+		{ID: "a", ServiceId: "svc-1"},
+		{ID: "c", ServiceId: "svc-2"},
+	} {
+		instance := instance
+		if err := ds.CreateServiceInstanceDetails(ctx, &instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	instances, err := ds.ListServiceInstanceDetails(ctx, models.ServiceInstanceDetails{ServiceId: "svc-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, instance := range instances {
+		ids = append(ids, instance.ID)
+	}
+
+	if !reflect.DeepEqual(ids, []string{"a", "b"}) {
+		t.Errorf("Expected instances [a b], got %v", ids)
+	}
+}
+
+func TestSqlDatastore_InstanceUpgrades(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.InstanceUpgrade{})
+	ctx := context.Background()
+
+	upgrade, err := ds.GetInstanceUpgrade(ctx, "instance-a", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upgrade != nil {
+		t.Errorf("Expected no upgrade, got %v", upgrade)
+	}
+
+	upgrade = &models.InstanceUpgrade{ServiceInstanceId: "instance-a", FromVersion: "1", ToVersion: "2", State: "pending"}
+	if err := ds.SaveInstanceUpgrade(ctx, upgrade); err != nil {
+		t.Fatal(err)
+	}
+
+	upgrade.State = "approved"
+	if err := ds.SaveInstanceUpgrade(ctx, upgrade); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := ds.GetInstanceUpgrade(ctx, "instance-a", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual == nil || actual.ID != upgrade.ID || actual.State != "approved" {
+		t.Errorf("Expected the approved upgrade, got %v", actual)
+	}
+
+	if other, err := ds.GetInstanceUpgrade(ctx, "instance-a", "3"); err != nil || other != nil {
+		t.Errorf("Expected no upgrade to another version, got %v, %v", other, err)
+	}
+}
+
+func TestSqlDatastore_DiscoveryCacheEntries(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.DiscoveryCacheEntry{})
+	ctx := context.Background()
+
+	entry, err := ds.GetDiscoveryCacheEntry(ctx, "compute.regions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Errorf("Expected no entry, got %v", entry)
+	}
+
+	for _, value := range []string{`["us-central1"]`, `["us-central1","us-east1"]`} {
+		if err := ds.SaveDiscoveryCacheEntry(ctx, &models.DiscoveryCacheEntry{CacheKey: "compute.regions", Value: value, FetchedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ds.SaveDiscoveryCacheEntry(ctx, &models.DiscoveryCacheEntry{CacheKey: "cloudsql.tiers", Value: `[]`}); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err = ds.GetDiscoveryCacheEntry(ctx, "compute.regions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || entry.Value != `["us-central1","us-east1"]` {
+		t.Errorf("Expected the replaced entry, got %v", entry)
+	}
+
+	entries, err := ds.ListDiscoveryCacheEntries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.CacheKey)
+	}
+	if !reflect.DeepEqual(keys, []string{"cloudsql.tiers", "compute.regions"}) {
+		t.Errorf("Expected one entry per key, got %v", keys)
+	}
+}
+
+func TestSqlDatastore_ListTerraformDeployments(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	ctx := context.Background()
+
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"tf:a:", "tf:b:", "tf:a:binding"} {
+		deployment := models.TerraformDeployment{ID: id, Workspace: "{}", LastOperationState: "succeeded"}
+		if err := ds.CreateTerraformDeployment(ctx, &deployment); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ds.db.Model(&deployment).UpdateColumn("updated_at", start.Add(time.Duration(i)*time.Minute)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[string]struct {
+		Limit    int
+		Expected []string
+	}{
+		"all":     {Limit: 0, Expected: []string{"tf:a:binding", "tf:b:", "tf:a:"}},
+		"limited": {Limit: 2, Expected: []string{"tf:a:binding", "tf:b:"}},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			deployments, err := ds.ListTerraformDeployments(ctx, tc.Limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []string
+			for _, deployment := range deployments {
+				ids = append(ids, deployment.ID)
+				if deployment.Workspace != "" {
+					t.Errorf("Expected workspace of %s not to be loaded", deployment.ID)
+				}
+			}
+
+			if !reflect.DeepEqual(ids, tc.Expected) {
+				t.Errorf("Expected deployments %v, got %v", tc.Expected, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// CreateRecordedExchange saves a recorded OSB request and response.
+func CreateRecordedExchange(ctx context.Context, exchange *models.RecordedExchange) error {
+	return defaultDatastore().CreateRecordedExchange(ctx, exchange)
+}
+
+// CreateRecordedExchange saves a recorded OSB request and response.
+func (ds *SqlDatastore) CreateRecordedExchange(ctx context.Context, exchange *models.RecordedExchange) error {
+	defer traceOperation(ctx, "CreateRecordedExchange")()
+	return ds.db.Create(exchange).Error
+}
+
+// ListRecordedExchanges lists up to limit of the exchanges recorded for the
+// instance and its bindings, newest first.
+func ListRecordedExchanges(ctx context.Context, instanceId string, limit int) ([]models.RecordedExchange, error) {
+	return defaultDatastore().ListRecordedExchanges(ctx, instanceId, limit)
+}
+
+// ListRecordedExchanges lists up to limit of the exchanges recorded for the
+// instance and its bindings, newest first.
+func (ds *SqlDatastore) ListRecordedExchanges(ctx context.Context, instanceId string, limit int) ([]models.RecordedExchange, error) {
+	defer traceOperation(ctx, "ListRecordedExchanges")()
+	var exchanges []models.RecordedExchange
+	err := ds.db.Where("instance_id = ?", instanceId).Order("id desc").Limit(limit).Find(&exchanges).Error
+	return exchanges, err
+}
+
+// DeleteRecordedExchangesBefore permanently deletes the exchanges recorded
+// before the cutoff and returns how many there were.
+func DeleteRecordedExchangesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return defaultDatastore().DeleteRecordedExchangesBefore(ctx, cutoff)
+}
+
+// DeleteRecordedExchangesBefore permanently deletes the exchanges recorded
+// before the cutoff and returns how many there were.
+func (ds *SqlDatastore) DeleteRecordedExchangesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	defer traceOperation(ctx, "DeleteRecordedExchangesBefore")()
+	result := ds.db.Unscoped().Where("created_at < ?", cutoff).Delete(&models.RecordedExchange{})
+	return result.RowsAffected, result.Error
+}
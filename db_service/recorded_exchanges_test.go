@@ -0,0 +1,68 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_RecordedExchanges(t *testing.T) {
+	ctx := context.Background()
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.RecordedExchange{})
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, exchange := range []models.RecordedExchange{
+		{InstanceId: "instance-1", Method: "PUT", Status: 202},
+		{InstanceId: "instance-1", BindingId: "binding-1", Method: "PUT", Status: 201},
+		{InstanceId: "instance-2", Method: "PUT", Status: 500},
+		{InstanceId: "instance-1", Method: "GET", Status: 200},
+	} {
+		if err := ds.CreateRecordedExchange(ctx, &exchange); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ds.db.Model(&models.RecordedExchange{}).Where("id = ?", 1).Update("created_at", old)
+
+	exchanges, err := ds.ListRecordedExchanges(ctx, "instance-1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var methods []string
+	for _, exchange := range exchanges {
+		methods = append(methods, exchange.Method+" "+exchange.BindingId)
+	}
+	if expected := []string{"GET ", "PUT binding-1"}; !reflect.DeepEqual(methods, expected) {
+		t.Errorf("Expected the newest exchanges %v, got %v", expected, methods)
+	}
+
+	deleted, err := ds.DeleteRecordedExchangesBefore(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 exchange to be deleted, got %d", deleted)
+	}
+
+	if exchanges, _ := ds.ListRecordedExchanges(ctx, "instance-1", 10); len(exchanges) != 2 {
+		t.Errorf("Expected 2 exchanges left, got %d", len(exchanges))
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/retry"
+)
+
+var datastoreRetryPolicy = retry.Policies.Policy("datastore", "Reading and writing the broker's records once connected.", retry.Defaults{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Retryable:      []retry.ErrorClass{retry.Network},
+})
+
+// RetryingDatastore retries the operations of a Datastore that fail with
+// errors its Policy considers transient, e.g. a dropped connection.
+//
+// Creates aren't retried, a create whose reply was lost may have been written
+// and running it again could add a second record. Their errors are returned
+// so the platform retries the request instead.
+type RetryingDatastore struct {
+	Datastore
+	Policy retry.Policy
+}
+
+var _ Datastore = (*RetryingDatastore)(nil)
+
+// NewRetryingDatastore wraps store with the "datastore" retry policy.
+func NewRetryingDatastore(store Datastore) *RetryingDatastore {
+	return &RetryingDatastore{Datastore: store, Policy: datastoreRetryPolicy}
+}
+
+func (rs *RetryingDatastore) SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.SaveServiceInstanceDetails(ctx, object)
+	})
+}
+
+func (rs *RetryingDatastore) DeleteServiceInstanceDetailsById(ctx context.Context, id string) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.DeleteServiceInstanceDetailsById(ctx, id)
+	})
+}
+
+func (rs *RetryingDatastore) DeleteServiceInstanceDetailsAndBindingsById(ctx context.Context, id string) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.DeleteServiceInstanceDetailsAndBindingsById(ctx, id)
+	})
+}
+
+func (rs *RetryingDatastore) GetServiceInstanceDetailsById(ctx context.Context, id string) (result *models.ServiceInstanceDetails, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.GetServiceInstanceDetailsById(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) ExistsServiceInstanceDetailsById(ctx context.Context, id string) (result bool, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.ExistsServiceInstanceDetailsById(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) ListServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) (result []models.ServiceInstanceDetails, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.ListServiceInstanceDetails(ctx, conditions)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) CountServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) (result int, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.CountServiceInstanceDetails(ctx, conditions)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) ExistsDeletedServiceInstanceDetailsById(ctx context.Context, id string) (result bool, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.ExistsDeletedServiceInstanceDetailsById(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) PurgeDeletedServiceInstance(ctx context.Context, id string) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.PurgeDeletedServiceInstance(ctx, id)
+	})
+}
+
+func (rs *RetryingDatastore) SaveServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.SaveServiceBindingCredentials(ctx, object)
+	})
+}
+
+func (rs *RetryingDatastore) DeleteServiceBindingCredentials(ctx context.Context, record *models.ServiceBindingCredentials) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.DeleteServiceBindingCredentials(ctx, record)
+	})
+}
+
+func (rs *RetryingDatastore) GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (result *models.ServiceBindingCredentials, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (result bool, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) ListServiceBindingCredentials(ctx context.Context, conditions models.ServiceBindingCredentials) (result []models.ServiceBindingCredentials, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.ListServiceBindingCredentials(ctx, conditions)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) ListRevokedServiceBindingCredentials(ctx context.Context) (result []models.ServiceBindingCredentials, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.ListRevokedServiceBindingCredentials(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) SaveProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.SaveProvisionRequestDetails(ctx, object)
+	})
+}
+
+func (rs *RetryingDatastore) GetProvisionRequestDetailsByInstanceId(ctx context.Context, instanceId string) (result *models.ProvisionRequestDetails, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.GetProvisionRequestDetailsByInstanceId(ctx, instanceId)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) GetInstanceUpgrade(ctx context.Context, instanceId, toVersion string) (result *models.InstanceUpgrade, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.GetInstanceUpgrade(ctx, instanceId, toVersion)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) SaveInstanceUpgrade(ctx context.Context, upgrade *models.InstanceUpgrade) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.SaveInstanceUpgrade(ctx, upgrade)
+	})
+}
+
+func (rs *RetryingDatastore) RecordInstanceShare(ctx context.Context, instanceId, organizationGuid, spaceGuid string) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.RecordInstanceShare(ctx, instanceId, organizationGuid, spaceGuid)
+	})
+}
+
+func (rs *RetryingDatastore) DeleteInstanceShares(ctx context.Context, instanceId string) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.DeleteInstanceShares(ctx, instanceId)
+	})
+}
+
+func (rs *RetryingDatastore) GetInstanceProtection(ctx context.Context, instanceId string) (result *models.InstanceProtection, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.GetInstanceProtection(ctx, instanceId)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) SaveInstanceProtection(ctx context.Context, protection *models.InstanceProtection) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.SaveInstanceProtection(ctx, protection)
+	})
+}
+
+func (rs *RetryingDatastore) GetTerraformDeploymentById(ctx context.Context, id string) (result *models.TerraformDeployment, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.GetTerraformDeploymentById(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) SaveTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.SaveTerraformDeployment(ctx, object)
+	})
+}
+
+func (rs *RetryingDatastore) ListTerraformDeployments(ctx context.Context, limit int) (result []models.TerraformDeployment, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.ListTerraformDeployments(ctx, limit)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) ListJobs(ctx context.Context, target string) (result []models.Job, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.ListJobs(ctx, target)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) ListPendingJobs(ctx context.Context, targetPrefix string) (result []models.Job, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.ListPendingJobs(ctx, targetPrefix)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) AcquireLeaderLease(ctx context.Context, name, holder string, ttl time.Duration) (result bool, err error) {
+	err = rs.Policy.Do(ctx, func() error {
+		result, err = rs.Datastore.AcquireLeaderLease(ctx, name, holder, ttl)
+		return err
+	})
+	return result, err
+}
+
+func (rs *RetryingDatastore) ReleaseLeaderLease(ctx context.Context, name, holder string) error {
+	return rs.Policy.Do(ctx, func() error {
+		return rs.Datastore.ReleaseLeaderLease(ctx, name, holder)
+	})
+}
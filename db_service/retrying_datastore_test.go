@@ -0,0 +1,96 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/retry"
+)
+
+// flakyDatastore fails each operation with its errors before succeeding.
+type flakyDatastore struct {
+	Datastore
+
+	Errors []error
+	Calls  int
+}
+
+func (f *flakyDatastore) next() error {
+	f.Calls++
+	if f.Calls <= len(f.Errors) {
+		return f.Errors[f.Calls-1]
+	}
+
+	return nil
+}
+
+func (f *flakyDatastore) GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error) {
+	if err := f.next(); err != nil {
+		return nil, err
+	}
+
+	return &models.ServiceInstanceDetails{ID: id}, nil
+}
+
+func (f *flakyDatastore) CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error {
+	return f.next()
+}
+
+func TestRetryingDatastore(t *testing.T) {
+	policy := retry.NewPolicySet("test-retry.").Policy("datastore", "", retry.Defaults{MaxAttempts: 3, Retryable: []retry.ErrorClass{retry.Network}})
+
+	cases := map[string]struct {
+		Errors        []error
+		Create        bool
+		ExpectedCalls int
+		ExpectErr     bool
+	}{
+		"transient":           {Errors: []error{driver.ErrBadConn}, ExpectedCalls: 2},
+		"not found":           {Errors: []error{gorm.ErrRecordNotFound}, ExpectedCalls: 1, ExpectErr: true},
+		"exhausted":           {Errors: []error{driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn}, ExpectedCalls: 3, ExpectErr: true},
+		"creates not retried": {Errors: []error{driver.ErrBadConn}, Create: true, ExpectedCalls: 1, ExpectErr: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			flaky := &flakyDatastore{Errors: tc.Errors}
+			store := &RetryingDatastore{Datastore: flaky, Policy: policy}
+
+			var err error
+			if tc.Create {
+				err = store.CreateServiceInstanceDetails(context.Background(), &models.ServiceInstanceDetails{ID: "instance-1"})
+			} else {
+				var details *models.ServiceInstanceDetails
+				details, err = store.GetServiceInstanceDetailsById(context.Background(), "instance-1")
+				if err == nil && details.ID != "instance-1" {
+					t.Errorf("Expected instance-1, got %v", details)
+				}
+			}
+
+			if (err != nil) != tc.ExpectErr {
+				t.Errorf("Expected error %v, got %v", tc.ExpectErr, err)
+			}
+
+			if flaky.Calls != tc.ExpectedCalls {
+				t.Errorf("Expected %d calls, got %d", tc.ExpectedCalls, flaky.Calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+const (
+	// bindingInstanceIndex is the unique index on the natural key of bindings.
+	bindingInstanceIndex = "idx_service_binding_credentials_instance_binding"
+
+	// provisionRequestInstanceIndex is the unique index on the instance of
+	// provision requests.
+	provisionRequestInstanceIndex = "idx_provision_request_details_instance"
+)
+
+// schemaModels are the models the broker reads and writes, the tables they
+// map to are expected to have a column for each of their fields.
+var schemaModels = []interface{}{
+	&models.Migration{},
+	&models.ServiceInstanceDetails{},
+	&models.ServiceBindingCredentials{},
+	&models.ProvisionRequestDetails{},
+	&models.CloudOperation{},
+	&models.TerraformDeployment{},
+	&models.CatalogSnapshot{},
+	&models.InstanceUpgrade{},
+	&models.DiscoveryCacheEntry{},
+	&models.Archive{},
+	&models.Job{},
+	&models.LeaderLease{},
+	&models.InstanceShare{},
+	&models.PlanRecord{},
+	&models.RecordedExchange{},
+	&models.InstanceProtection{},
+}
+
+// migratedIndexes are the indexes added by migrations rather than model tags.
+var migratedIndexes = []struct {
+	table string
+	name  string
+}{
+	{table: "service_binding_credentials", name: bindingInstanceIndex},
+	{table: "provision_request_details", name: provisionRequestInstanceIndex},
+}
+
+// migratedForeignKeys are the foreign keys added by migrations, only MySQL
+// databases have them.
+var migratedForeignKeys = []struct {
+	table string
+	field string
+	dest  string
+}{
+	{table: "service_binding_credentials", field: "service_instance_id", dest: "service_instance_details(id)"},
+	{table: "cloud_operations", field: "service_instance_id", dest: "service_instance_details(id)"},
+}
+
+// SchemaDrift is a difference between the database schema and the one the
+// broker expects, e.g. because of a manual change by a DBA.
+type SchemaDrift struct {
+	Table    string `json:"table"`
+	Column   string `json:"column,omitempty"`
+	Index    string `json:"index,omitempty"`
+	Problem  string `json:"problem"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// ValidateSchema compares the tables, columns, column types and indexes of
+// the database to the ones the broker expects at the current migration level
+// and returns the differences. Types are compared by kind, e.g. text or
+// integer, along with the length of varchar columns because databases report
+// the same type in different ways. Nothing is returned for tables or columns
+// the broker doesn't use. If migrations are pending there's nothing to compare
+// to so that's the only drift returned.
+func ValidateSchema(db *gorm.DB) ([]SchemaDrift, error) {
+	if err := CheckMigrations(db); err != nil {
+		return []SchemaDrift{{Table: "migrations", Problem: err.Error()}}, nil
+	}
+
+	drift := []SchemaDrift{}
+	for _, model := range schemaModels {
+		scope := db.NewScope(model)
+		table := scope.TableName()
+		if !db.HasTable(table) {
+			drift = append(drift, SchemaDrift{Table: table, Problem: "missing table"})
+			continue
+		}
+
+		columns, err := liveColumnTypes(db, table)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, field := range scope.GetModelStruct().StructFields {
+			if !field.IsNormal || field.IsIgnored {
+				continue
+			}
+
+			actual, ok := columns[field.DBName]
+			if !ok {
+				drift = append(drift, SchemaDrift{Table: table, Column: field.DBName, Problem: "missing column"})
+				continue
+			}
+
+			expected := scope.Dialect().DataTypeOf(field)
+			if problem := compareColumnTypes(expected, actual); problem != "" {
+				drift = append(drift, SchemaDrift{Table: table, Column: field.DBName, Problem: problem, Expected: expected, Actual: actual})
+			}
+		}
+
+		for _, index := range taggedIndexes(scope) {
+			if !scope.Dialect().HasIndex(table, index) {
+				drift = append(drift, SchemaDrift{Table: table, Index: index, Problem: "missing index"})
+			}
+		}
+	}
+
+	for _, index := range migratedIndexes {
+		if !db.Dialect().HasIndex(index.table, index.name) {
+			drift = append(drift, SchemaDrift{Table: index.table, Index: index.name, Problem: "missing index"})
+		}
+	}
+
+	if db.Dialect().GetName() == DbTypeMysql {
+		for _, fk := range migratedForeignKeys {
+			name := db.Dialect().BuildKeyName(fk.table, fk.field, fk.dest, "foreign")
+			if !db.Dialect().HasForeignKey(fk.table, name) {
+				drift = append(drift, SchemaDrift{Table: fk.table, Column: fk.field, Index: name, Problem: "missing foreign key"})
+			}
+		}
+	}
+
+	return drift, nil
+}
+
+// liveColumnTypes gets the columns of the table and their types as reported
+// by the database.
+func liveColumnTypes(db *gorm.DB, table string) (map[string]string, error) {
+	columns := make(map[string]string)
+
+	switch db.Dialect().GetName() {
+	case DbTypeMysql:
+		rows, err := db.Raw("SELECT column_name, column_type FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?", table).Rows()
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name, columnType string
+			if err := rows.Scan(&name, &columnType); err != nil {
+				return nil, err
+			}
+			columns[name] = columnType
+		}
+
+		return columns, rows.Err()
+
+	default:
+		rows, err := db.Raw(fmt.Sprintf("PRAGMA table_info(%q)", table)).Rows()
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				cid, notNull, pk int
+				name, columnType string
+				defaultValue     interface{}
+			)
+			if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+				return nil, err
+			}
+			columns[name] = columnType
+		}
+
+		return columns, rows.Err()
+	}
+}
+
+// taggedIndexes gets the names of the indexes declared by the model's tags,
+// named the way gorm names them when migrating.
+func taggedIndexes(scope *gorm.Scope) []string {
+	var indexes []string
+	for _, field := range scope.GetModelStruct().StructFields {
+		for _, setting := range []struct{ tag, kind string }{{tag: "INDEX", kind: "idx"}, {tag: "UNIQUE_INDEX", kind: "uix"}} {
+			names, ok := field.TagSettings[setting.tag]
+			if !ok {
+				continue
+			}
+
+			for _, name := range strings.Split(names, ",") {
+				if name == setting.tag || name == "" {
+					name = scope.Dialect().BuildKeyName(setting.kind, scope.TableName(), field.DBName)
+				}
+				indexes = append(indexes, name)
+			}
+		}
+	}
+
+	return indexes
+}
+
+var varcharLength = regexp.MustCompile(`char\((\d+)\)`)
+
+// compareColumnTypes returns a description of the problem if a column of the
+// actual type can't hold the values of the expected type.
+func compareColumnTypes(expected, actual string) string {
+	if typeKind(expected) != typeKind(actual) {
+		return "type mismatch"
+	}
+
+	expectedLength, actualLength := varcharLength.FindStringSubmatch(strings.ToLower(expected)), varcharLength.FindStringSubmatch(strings.ToLower(actual))
+	if expectedLength != nil && actualLength != nil {
+		want, _ := strconv.Atoi(expectedLength[1])
+		got, _ := strconv.Atoi(actualLength[1])
+		if got < want {
+			return "column shorter than expected"
+		}
+	}
+
+	return ""
+}
+
+// typeKind groups the ways databases name column types into the kinds of
+// values they hold.
+func typeKind(columnType string) string {
+	columnType = strings.ToLower(columnType)
+	switch {
+	case strings.HasPrefix(columnType, "bool"), strings.HasPrefix(columnType, "tinyint(1)"):
+		return "boolean"
+	case strings.Contains(columnType, "date"), strings.Contains(columnType, "time"):
+		return "time"
+	case strings.Contains(columnType, "char"), strings.Contains(columnType, "text"), strings.Contains(columnType, "clob"):
+		return "text"
+	case strings.Contains(columnType, "int"):
+		return "integer"
+	case strings.Contains(columnType, "real"), strings.Contains(columnType, "double"), strings.Contains(columnType, "float"), strings.Contains(columnType, "decimal"), strings.Contains(columnType, "numeric"):
+		return "float"
+	case strings.Contains(columnType, "blob"), strings.Contains(columnType, "binary"):
+		return "binary"
+	default:
+		return columnType
+	}
+}
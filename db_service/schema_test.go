@@ -0,0 +1,129 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	cases := map[string]struct {
+		migrate  bool
+		modify   []string
+		expected []SchemaDrift
+	}{
+		"migrated": {
+			migrate:  true,
+			expected: []SchemaDrift{},
+		},
+		"pending migrations": {
+			migrate:  false,
+			expected: []SchemaDrift{{Table: "migrations", Problem: fmt.Sprintf("%d migration(s) pending", numMigrations)}},
+		},
+		"missing table": {
+			migrate:  true,
+			modify:   []string{"DROP TABLE plan_records"},
+			expected: []SchemaDrift{{Table: "plan_records", Problem: "missing table"}},
+		},
+		"missing index": {
+			migrate: true,
+			modify:  []string{"DROP INDEX " + provisionRequestInstanceIndex},
+			expected: []SchemaDrift{
+				{Table: "provision_request_details", Index: provisionRequestInstanceIndex, Problem: "missing index"},
+			},
+		},
+		"changed columns": {
+			migrate: true,
+			modify: []string{
+				"DROP TABLE plan_records",
+				"CREATE TABLE plan_records (id integer primary key autoincrement, created_at datetime, updated_at datetime, deleted_at datetime, service_id varchar(255), service_name varchar(64), plan_id integer)",
+				"CREATE INDEX idx_plan_records_service_name ON plan_records(service_name)",
+			},
+			expected: []SchemaDrift{
+				{Table: "plan_records", Column: "service_name", Problem: "column shorter than expected", Expected: "varchar(255)", Actual: "varchar(64)"},
+				{Table: "plan_records", Column: "plan_name", Problem: "missing column"},
+				{Table: "plan_records", Column: "plan_id", Problem: "type mismatch", Expected: "varchar(255)", Actual: "integer"},
+				{Table: "plan_records", Index: "idx_plan_records_deleted_at", Problem: "missing index"},
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "schema-test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			db, err := OpenSqlite(filepath.Join(dir, "test.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			if tc.migrate {
+				if err := RunMigrations(db); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, statement := range tc.modify {
+				if err := db.Exec(statement).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			actual, err := ValidateSchema(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Errorf("Expected drift %#v, got %#v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCompareColumnTypes(t *testing.T) {
+	cases := map[string]struct {
+		expected string
+		actual   string
+		problem  string
+	}{
+		"same":             {expected: "varchar(255)", actual: "varchar(255)"},
+		"mysql int width":  {expected: "int unsigned AUTO_INCREMENT", actual: "int(10) unsigned"},
+		"mysql boolean":    {expected: "boolean", actual: "tinyint(1)"},
+		"mysql timestamp":  {expected: "timestamp NULL", actual: "timestamp"},
+		"text for varchar": {expected: "varchar(255)", actual: "text"},
+		"longer varchar":   {expected: "varchar(255)", actual: "varchar(1024)"},
+		"shorter varchar":  {expected: "varchar(255)", actual: "varchar(64)", problem: "column shorter than expected"},
+		"integer for text": {expected: "text", actual: "integer", problem: "type mismatch"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := compareColumnTypes(tc.expected, tc.actual); actual != tc.problem {
+				t.Errorf("Expected problem %q, got %q", tc.problem, actual)
+			}
+		})
+	}
+}
@@ -15,15 +15,20 @@
 package db_service
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"code.cloudfoundry.org/lager"
 	"github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/retry"
 	"github.com/spf13/viper"
 )
 
@@ -44,52 +49,68 @@ const (
 	DbTypeSqlite3 = "sqlite3"
 )
 
+var connectRetryPolicy = retry.Policies.Policy("database", "Connecting to the database on startup.", retry.Defaults{
+	MaxAttempts:    5,
+	InitialBackoff: 2 * time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Retryable:      []retry.ErrorClass{retry.Network},
+})
+
 func init() {
-	viper.BindEnv(caCertProp, "CA_CERT")
-	viper.BindEnv(clientCertProp, "CLIENT_CERT")
-	viper.BindEnv(clientKeyProp, "CLIENT_KEY")
-	viper.BindEnv(dbTLS, "DB_TLS")
-	viper.SetDefault(dbTLS, "true")
-	viper.BindEnv(dbHostProp, "DB_HOST")
-	viper.BindEnv(dbUserProp, "DB_USERNAME")
-	viper.BindEnv(dbPassProp, "DB_PASSWORD")
-
-	viper.BindEnv(dbPortProp, "DB_PORT")
-	viper.SetDefault(dbPortProp, "3306")
-	viper.BindEnv(dbNameProp, "DB_NAME")
-	viper.SetDefault(dbNameProp, "servicebroker")
-
-	viper.BindEnv(dbTypeProp, "DB_TYPE")
-	viper.SetDefault(dbTypeProp, DbTypeMysql)
-
-	viper.BindEnv(dbPathProp, "DB_PATH")
+	config.Register(
+		config.Property{Key: caCertProp, Kind: config.String, Env: "CA_CERT", Sensitive: true},
+		config.Property{Key: clientCertProp, Kind: config.String, Env: "CLIENT_CERT", Sensitive: true},
+		config.Property{Key: clientKeyProp, Kind: config.String, Env: "CLIENT_KEY", Sensitive: true},
+		config.Property{Key: dbTLS, Kind: config.String, Env: "DB_TLS", Default: "true"},
+		config.Property{Key: dbHostProp, Kind: config.String, Env: "DB_HOST"},
+		config.Property{Key: dbUserProp, Kind: config.String, Env: "DB_USERNAME"},
+		config.Property{Key: dbPassProp, Kind: config.String, Env: "DB_PASSWORD", Sensitive: true},
+		config.Property{Key: dbPortProp, Kind: config.Integer, Env: "DB_PORT", Default: "3306"},
+		config.Property{Key: dbNameProp, Kind: config.String, Env: "DB_NAME", Default: "servicebroker"},
+		config.Property{Key: dbTypeProp, Kind: config.String, Env: "DB_TYPE", Default: DbTypeMysql, Allowed: []string{DbTypeMysql, DbTypeSqlite3}},
+		config.Property{Key: dbPathProp, Kind: config.String, Env: "DB_PATH"},
+	)
 }
 
 // pulls db credentials from the environment, connects to the db, and returns the db connection
 func SetupDb(logger lager.Logger) *gorm.DB {
+	db, err := Connect(logger)
+	if err != nil {
+		logger.Error("Database Setup", err)
+		os.Exit(1)
+	}
+
+	return db
+}
+
+// Connect connects to the database configured in the environment, retrying
+// network errors, and returns an error rather than exiting if it can't.
+func Connect(logger lager.Logger) (*gorm.DB, error) {
 	dbType := viper.GetString(dbTypeProp)
-	var db *gorm.DB
-	var err error
+	if dbType != DbTypeMysql && dbType != DbTypeSqlite3 {
+		return nil, fmt.Errorf("Invalid database type %q, valid types are: sqlite3 and mysql", dbType)
+	}
+
 	// if provided, use database injected by CF via VCAP_SERVICES environment variable
 	if err := UseVcapServices(); err != nil {
 		logger.Info("Invalid VCAP_SERVICES environment variable - falling back to explicit environment variables")
 	}
-	switch dbType {
-	default:
-		logger.Error("Database Setup", fmt.Errorf("Invalid database type %q, valid types are: sqlite3 and mysql", dbType))
-		os.Exit(1)
-	case DbTypeMysql:
-		db, err = setupMysqlDb(logger)
-	case DbTypeSqlite3:
-		db, err = setupSqlite3Db(logger)
-	}
 
-	if err != nil {
-		logger.Error("Database Setup", err)
-		os.Exit(1)
-	}
+	var db *gorm.DB
+	err := connectRetryPolicy.Do(context.Background(), func() error {
+		var connectErr error
+		switch dbType {
+		case DbTypeMysql:
+			db, connectErr = setupMysqlDb(logger)
+		case DbTypeSqlite3:
+			db, connectErr = setupSqlite3Db(logger)
+		}
 
-	return db
+		return connectErr
+	})
+
+	return db, err
 }
 
 func setupSqlite3Db(logger lager.Logger) (*gorm.DB, error) {
@@ -100,7 +121,7 @@ func setupSqlite3Db(logger lager.Logger) (*gorm.DB, error) {
 
 	logger.Info("WARNING: DO NOT USE SQLITE3 IN PRODUCTION!")
 
-	return gorm.Open(DbTypeSqlite3, dbPath)
+	return OpenSqlite(dbPath)
 }
 
 func setupMysqlDb(logger lager.Logger) (*gorm.DB, error) {
@@ -131,6 +152,13 @@ func setupMysqlDb(logger lager.Logger) (*gorm.DB, error) {
 	return gorm.Open(DbTypeMysql, connStr)
 }
 
+// TLSMode gets the DB_TLS setting the broker connects to its database with:
+// "true", "false" or "skip-verify". Seeds connect to the databases brokerpaks
+// create with it too.
+func TLSMode() string {
+	return strings.ToLower(viper.GetString(dbTLS))
+}
+
 func generateTlsStringFromEnv() (string, error) {
 	caCert := viper.GetString(caCertProp)
 	clientCertStr := viper.GetString(clientCertProp)
@@ -0,0 +1,39 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !cgo || purego
+// +build !cgo purego
+
+package db_service
+
+import (
+	"database/sql"
+
+	"github.com/jinzhu/gorm"
+
+	_ "modernc.org/sqlite"
+)
+
+// OpenSqlite opens the SQLite database at path, ":memory:" for a new
+// in-memory database. Builds with cgo use the cgo driver, builds without it or
+// with the purego tag use a pure-Go one.
+func OpenSqlite(path string) (*gorm.DB, error) {
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// gorm uses the SQLite dialect for connections it's given by name
+	return gorm.Open(DbTypeSqlite3, conn)
+}
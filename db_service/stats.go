@@ -0,0 +1,139 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// failedOperationState mirrors the state the Terraform job runner stores for
+// failed deployments.
+const failedOperationState = "failed"
+
+// inProgressOperationState mirrors the state the Terraform job runner stores
+// for running deployments.
+const inProgressOperationState = "in progress"
+
+// DatastoreStats summarizes the contents of the database for troubleshooting.
+type DatastoreStats struct {
+	// LastMigration is the ID of the last migration run against the database or
+	// -1 if no migrations have been run.
+	LastMigration int `json:"last_migration"`
+	// SupportedMigrations is the number of migrations this version of the
+	// broker knows about.
+	SupportedMigrations int `json:"supported_migrations"`
+
+	ServiceInstances          int `json:"service_instances"`
+	ServiceBindings           int `json:"service_bindings"`
+	ProvisionRequests         int `json:"provision_requests"`
+	TerraformDeployments      int `json:"terraform_deployments"`
+	PendingInstanceOperations int `json:"pending_instance_operations"`
+	InProgressDeployments     int `json:"in_progress_deployments"`
+	FailedDeployments         int `json:"failed_deployments"`
+}
+
+// FailedOperation describes a Terraform deployment whose last operation failed.
+type FailedOperation struct {
+	Id        string    `json:"id"`
+	Operation string    `json:"operation"`
+	Message   string    `json:"message"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetDatastoreStats summarizes the contents of the database.
+func GetDatastoreStats(ctx context.Context) (*DatastoreStats, error) {
+	return defaultDatastore().GetDatastoreStats(ctx)
+}
+
+// GetDatastoreStats summarizes the contents of the database.
+func (ds *SqlDatastore) GetDatastoreStats(ctx context.Context) (*DatastoreStats, error) {
+	defer traceOperation(ctx, "GetDatastoreStats")()
+	stats := &DatastoreStats{LastMigration: -1, SupportedMigrations: numMigrations}
+
+	if ds.db.HasTable("migrations") {
+		var lastMigration models.Migration
+		err := ds.db.Order("migration_id desc").First(&lastMigration).Error
+		switch {
+		case err == nil:
+			stats.LastMigration = lastMigration.MigrationId
+		case !gorm.IsRecordNotFoundError(err):
+			return nil, err
+		}
+	}
+
+	counts := []struct {
+		model interface{}
+		where []interface{}
+		dest  *int
+	}{
+		{model: &models.ServiceInstanceDetails{}, dest: &stats.ServiceInstances},
+		{model: &models.ServiceBindingCredentials{}, dest: &stats.ServiceBindings},
+		{model: &models.ProvisionRequestDetails{}, dest: &stats.ProvisionRequests},
+		{model: &models.TerraformDeployment{}, dest: &stats.TerraformDeployments},
+		{model: &models.ServiceInstanceDetails{}, where: []interface{}{"operation_id <> ''"}, dest: &stats.PendingInstanceOperations},
+		{model: &models.TerraformDeployment{}, where: []interface{}{"last_operation_state = ?", inProgressOperationState}, dest: &stats.InProgressDeployments},
+		{model: &models.TerraformDeployment{}, where: []interface{}{"last_operation_state = ?", failedOperationState}, dest: &stats.FailedDeployments},
+	}
+
+	for _, count := range counts {
+		query := ds.db.Model(count.model)
+		if len(count.where) > 0 {
+			query = query.Where(count.where[0], count.where[1:]...)
+		}
+
+		if err := query.Count(count.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+// ListRecentFailedOperations lists up to limit Terraform deployments whose last
+// operation failed, most recent first.
+func ListRecentFailedOperations(ctx context.Context, limit int) ([]FailedOperation, error) {
+	return defaultDatastore().ListRecentFailedOperations(ctx, limit)
+}
+
+// ListRecentFailedOperations lists up to limit Terraform deployments whose last
+// operation failed, most recent first.
+func (ds *SqlDatastore) ListRecentFailedOperations(ctx context.Context, limit int) ([]FailedOperation, error) {
+	defer traceOperation(ctx, "ListRecentFailedOperations")()
+	var deployments []models.TerraformDeployment
+	err := ds.db.
+		Where("last_operation_state = ?", failedOperationState).
+		Order("updated_at desc").
+		Limit(limit).
+		Find(&deployments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := []FailedOperation{}
+	for _, deployment := range deployments {
+		out = append(out, FailedOperation{
+			Id:        deployment.ID,
+			Operation: deployment.LastOperationType,
+			Message:   deployment.LastOperationMessage,
+			UpdatedAt: deployment.UpdatedAt,
+		})
+	}
+
+	return out, nil
+}
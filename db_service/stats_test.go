@@ -0,0 +1,92 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_GetDatastoreStats(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	ctx := context.Background()
+
+	stats, err := ds.GetDatastoreStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.LastMigration != -1 {
+		t.Errorf("Expected last migration -1 without a migrations table, got %d", stats.LastMigration)
+	}
+
+	ds.db.CreateTable(models.Migration{})
+	for i := 0; i < 3; i++ {
+		if err := ds.db.Save(&models.Migration{MigrationId: i}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	instances := []models.ServiceInstanceDetails{
+		{ID: "a"},
+		{ID: "b", OperationId: "pending-op"},
+	}
+	for _, instance := range instances {
+		instance := instance
+		if err := ds.CreateServiceInstanceDetails(ctx, &instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deployments := []models.TerraformDeployment{
+		{ID: "tf:a:", LastOperationState: inProgressOperationState},
+		{ID: "tf:b:", LastOperationState: failedOperationState, LastOperationType: "provision", LastOperationMessage: "boom"},
+		{ID: "tf:c:", LastOperationState: "succeeded"},
+	}
+	for _, deployment := range deployments {
+		deployment := deployment
+		if err := ds.CreateTerraformDeployment(ctx, &deployment); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err = ds.GetDatastoreStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &DatastoreStats{
+		LastMigration:             2,
+		SupportedMigrations:       numMigrations,
+		ServiceInstances:          2,
+		TerraformDeployments:      3,
+		PendingInstanceOperations: 1,
+		InProgressDeployments:     1,
+		FailedDeployments:         1,
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected stats %#v, got %#v", expected, stats)
+	}
+
+	failed, err := ds.ListRecentFailedOperations(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].Id != "tf:b:" || failed[0].Message != "boom" || failed[0].Operation != "provision" {
+		t.Errorf("Expected the failed deployment to be listed, got %#v", failed)
+	}
+}
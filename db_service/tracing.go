@@ -0,0 +1,40 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/logging"
+	"github.com/pivotal/cloud-service-broker/pkg/requeststats"
+	"github.com/pivotal/cloud-service-broker/pkg/tracing"
+	"go.opencensus.io/trace"
+)
+
+// traceOperation starts a span for a datastore operation that's a child of
+// the request in the context and logs the operation at debug level with the
+// request's correlation ID. Its time counts towards the request's database
+// time. Call the returned function to end it.
+func traceOperation(ctx context.Context, operation string) func() {
+	logging.FromContext(ctx, nil).Debug("db-operation", lager.Data{"operation": operation})
+
+	_, span := tracing.StartSpan(ctx, "db "+operation, trace.StringAttribute("db.operation", operation))
+	stop := requeststats.Start(ctx, requeststats.PhaseDB)
+	return func() {
+		span.End()
+		stop()
+	}
+}
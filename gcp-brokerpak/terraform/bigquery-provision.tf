@@ -3,6 +3,9 @@ variable project  { type = string }
 variable labels { type = map }
 variable region { type = string }
 variable instance_name { type = string }
+variable default_table_expiration_days { type = number }
+variable access { type = any }
+variable kms_key_name { type = string }
 
 provider "google" {
   version = ">=3.17.0"
@@ -30,10 +33,30 @@ resource "google_bigquery_dataset" "csb_dataset" {
   }
   access {
     role          = "READER"
-    special_group = "allAuthenticatedUsers"
+    special_group = "projectReaders"
   }
 
+  dynamic "access" {
+    for_each = var.access
+    content {
+      role           = access.value.role
+      user_by_email  = lookup(access.value, "user_by_email", null)
+      group_by_email = lookup(access.value, "group_by_email", null)
+      domain         = lookup(access.value, "domain", null)
+      special_group  = lookup(access.value, "special_group", null)
+    }
+  }
+
+  default_table_expiration_ms = var.default_table_expiration_days > 0 ? var.default_table_expiration_days * 86400000 : null
+
+  dynamic "default_encryption_configuration" {
+    for_each = var.kms_key_name == "" ? [] : [var.kms_key_name]
+    content {
+      kms_key_name = default_encryption_configuration.value
+    }
+  }
 }
 
 output dataset_id { value =  google_bigquery_dataset.csb_dataset.dataset_id }
-
+output default_table_expiration_days { value = var.default_table_expiration_days }
+output kms_key_name { value = var.kms_key_name }
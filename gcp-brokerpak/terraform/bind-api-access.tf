@@ -0,0 +1,33 @@
+variable name {type = string}
+variable credentials  { type = string }
+variable project  { type = string }
+variable roles { type = list(string) }
+
+provider "google" {
+  version = ">=3.17.0"
+  credentials = var.credentials
+  project     = var.project
+}
+
+resource "google_service_account" "account" {
+  account_id = substr(var.name, 0, 30)
+  display_name = format("%s with roles %s", var.name, join(", ", var.roles))
+}
+
+resource "google_service_account_key" "key" {
+  service_account_id = google_service_account.account.name
+}
+
+resource "google_project_iam_member" "member" {
+  for_each = toset(var.roles)
+  project  = var.project
+  role     = format("roles/%s", each.value)
+  member   = format("serviceAccount:%s", google_service_account.account.email)
+}
+
+output "Name" {value = google_service_account.account.name}
+output "Email" {value = google_service_account.account.email}
+output "UniqueId" {value = google_service_account.account.unique_id}
+output "PrivateKeyData" {value = google_service_account_key.key.private_key}
+output "ProjectId" {value = google_service_account.account.project}
+output "Credentials" { value = base64decode(google_service_account_key.key.private_key) }
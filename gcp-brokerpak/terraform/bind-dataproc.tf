@@ -1,5 +1,15 @@
     variable service_account_name {type = string}
     variable bucket {type = string}
+    variable cluster_name {type = string}
+    variable region {type = string}
+    variable credentials {type = string}
+    variable project {type = string}
+
+    provider "google" {
+      version = ">=3.17.0"
+      credentials = var.credentials
+      project     = var.project
+    }
 
     resource "google_service_account" "account" {
       account_id = var.service_account_name
@@ -16,12 +26,15 @@
       member = "serviceAccount:${google_service_account.account.email}"
     }
 
-    resource "google_project_iam_member" "member" {
-      role   = "roles/dataproc.editor"
-      member = "serviceAccount:${google_service_account.account.email}"
+    resource "google_dataproc_cluster_iam_member" "member" {
+      cluster = var.cluster_name
+      region  = var.region
+      role    = "roles/dataproc.editor"
+      member  = "serviceAccount:${google_service_account.account.email}"
     }
 
     output email {value = google_service_account.account.email}
     output private_key {value = google_service_account_key.key.private_key}
     output project_id {value = google_service_account.account.project}
     output name {value = google_service_account.account.account_id}
+    output api_endpoint {value = format("https://%s-dataproc.googleapis.com", var.region)}
\ No newline at end of file
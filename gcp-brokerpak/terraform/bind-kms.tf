@@ -0,0 +1,38 @@
+variable service_account_name { type = string }
+variable service_account_email { type = string }
+variable kms_key_name { type = string }
+variable role { type = string }
+variable credentials { type = string }
+variable project { type = string }
+
+provider "google" {
+  version = ">=3.17.0"
+  credentials = var.credentials
+  project     = var.project
+}
+
+// a service account is only created if the user didn't supply one
+resource "google_service_account" "account" {
+  count        = var.service_account_email == "" ? 1 : 0
+  account_id   = var.service_account_name
+  display_name = var.service_account_name
+}
+
+resource "google_service_account_key" "key" {
+  count              = length(google_service_account.account)
+  service_account_id = google_service_account.account[count.index].name
+}
+
+locals {
+  email = var.service_account_email == "" ? google_service_account.account[0].email : var.service_account_email
+}
+
+resource "google_kms_crypto_key_iam_member" "member" {
+  crypto_key_id = var.kms_key_name
+  role          = format("roles/%s", var.role)
+  member        = format("serviceAccount:%s", local.email)
+}
+
+output email { value = local.email }
+output private_key { value = join("", google_service_account_key.key[*].private_key) }
+output role { value = var.role }
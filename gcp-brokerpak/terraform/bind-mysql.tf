@@ -17,6 +17,15 @@ variable mysql_hostname { type = string }
 variable mysql_port { type = number }
 variable admin_username { type = string }
 variable admin_password { type = string }
+variable endpoint { type = string }
+variable instance_details { type = any }
+
+locals {
+  // instances created before they had private_ip and public_ip outputs
+  // only have a hostname
+  chosen_ip = lookup(var.instance_details, "${var.endpoint}_ip", "")
+  hostname  = local.chosen_ip != "" ? local.chosen_ip : var.mysql_hostname
+}
 
 provider "mysql" {
   endpoint = format("%s:%d", var.mysql_hostname, var.mysql_port)
@@ -51,19 +60,20 @@ resource "mysql_grant" "newuser" {
   privileges = ["ALL"]
 }
 
+output hostname { value = local.hostname }
 output username { value = mysql_user.newuser.user }
 output password { value = random_password.password.result }
 output uri { 
   value = format("mysql://%s:%s@%s:%d/%s", 
                   random_string.username.result, 
                   random_password.password.result, 
-                  var.mysql_hostname, 
+                  local.hostname, 
                   var.mysql_port,
                   var.mysql_db_name) 
 }
 output jdbcUrl { 
   value = format("jdbc:mysql://%s:%d/%s?user=%s\u0026password=%s\u0026useSSL=false", 
-                  var.mysql_hostname, 
+                  local.hostname, 
                   var.mysql_port,
                   var.mysql_db_name, 
                   mysql_user.newuser.user, 
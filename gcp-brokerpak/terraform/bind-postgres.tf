@@ -17,6 +17,15 @@ variable postgres_hostname { type = string }
 variable postgres_port { type = number }
 variable admin_username { type = string }
 variable admin_password { type = string }
+variable endpoint { type = string }
+variable instance_details { type = any }
+
+locals {
+  // instances created before they had private_ip and public_ip outputs
+  // only have a hostname
+  chosen_ip = lookup(var.instance_details, "${var.endpoint}_ip", "")
+  hostname  = local.chosen_ip != "" ? local.chosen_ip : var.postgres_hostname
+}
 
 locals {
   
@@ -90,19 +99,20 @@ resource "postgresql_default_privileges" "app_sequence" {
 }
 
 
+output hostname { value = local.hostname }
 output username { value = random_string.username.result }
 output password { value = random_password.password.result }
 output uri {
   value = format("postgresql://%s:%s@%s:%d/%s",
                   random_string.username.result,
                   random_password.password.result,
-                  var.postgres_hostname,
+                  local.hostname,
                   var.postgres_port,
                   var.postgres_db_name)
 }
 output jdbcUrl {
   value = format("jdbc:postgresql://%s:%d/%s?user=%s\u0026password=%s\u0026useSSL=false",
-                  var.postgres_hostname,
+                  local.hostname,
                   var.postgres_port,
                   var.postgres_db_name,
                   random_string.username.result,
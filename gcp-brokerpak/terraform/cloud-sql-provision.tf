@@ -14,6 +14,7 @@
 
 variable cores { type = number }
 variable authorized_network { type = string }
+variable connectivity { type = string }
 variable instance_name { type = string }
 variable db_name { type = string }
 
@@ -21,6 +22,15 @@ variable region { type = string }
 variable labels { type = map }
 variable storage_gb { type = number }
 variable database_version { type = string }
+variable availability_type { type = string }
+variable read_replicas { type = number }
+variable backups_enabled { type = bool }
+variable backup_start_time { type = string }
+variable binary_log_enabled { type = bool }
+variable point_in_time_recovery { type = bool }
+variable maintenance_window_day { type = number }
+variable maintenance_window_hour { type = number }
+variable kms_key_name { type = string }
 
 variable credentials  { type = string }
 variable project  { type = string }
@@ -31,6 +41,13 @@ provider "google" {
   project     = var.project
 }
 
+// customer-managed encryption keys are only in the beta provider
+provider "google-beta" {
+  version = ">=3.22.0"
+  credentials = var.credentials
+  project     = var.project
+}
+
 data "google_compute_network" "authorized-network" {
   name = var.authorized_network
 }
@@ -48,21 +65,70 @@ locals {
     0.6   = "db-f1-micro" 
     1.7   = "db-g1-small" 
   }   
+
+  // MySQL failover, read replicas and point-in-time recovery use the
+  // binary log, which needs backups
+  is_mysql   = length(regexall("^MYSQL", var.database_version)) > 0
+  binary_log = local.is_mysql && (var.binary_log_enabled || var.point_in_time_recovery || var.availability_type == "REGIONAL" || var.read_replicas > 0)
+  backups    = var.backups_enabled || local.binary_log
 }
 
 resource "google_sql_database_instance" "instance" {
-  name             = var.instance_name
-  database_version = var.database_version
-  region           = var.region
+  provider            = google-beta
+  name                = var.instance_name
+  database_version    = var.database_version
+  region              = var.region
+  encryption_key_name = var.kms_key_name == "" ? null : var.kms_key_name
 
   settings {
     tier = local.service_tiers[var.cores]
+    availability_type = var.availability_type
     disk_size = var.storage_gb
     user_labels = var.labels
     
     ip_configuration {
-      ipv4_enabled    = false
-      private_network = data.google_compute_network.authorized-network.self_link
+      ipv4_enabled    = var.connectivity != "private"
+      private_network = var.connectivity != "public" ? data.google_compute_network.authorized-network.self_link : null
+    }
+
+    backup_configuration {
+      enabled            = local.backups
+      binary_log_enabled = local.binary_log
+      start_time         = var.backup_start_time == "" ? null : var.backup_start_time
+    }
+
+    dynamic "maintenance_window" {
+      for_each = var.maintenance_window_day > 0 ? [var.maintenance_window_day] : []
+      content {
+        day  = maintenance_window.value
+        hour = var.maintenance_window_hour
+      }
+    }
+  }
+}
+
+// Replicas depend on the instance, so they're destroyed before it.
+resource "google_sql_database_instance" "replica" {
+  provider             = google-beta
+  count                = var.read_replicas
+  name                 = format("%s-replica-%d", var.instance_name, count.index)
+  master_instance_name = google_sql_database_instance.instance.name
+  database_version     = var.database_version
+  region               = var.region
+  encryption_key_name  = var.kms_key_name == "" ? null : var.kms_key_name
+
+  replica_configuration {
+    failover_target = false
+  }
+
+  settings {
+    tier = local.service_tiers[var.cores]
+    disk_size = var.storage_gb
+    user_labels = var.labels
+
+    ip_configuration {
+      ipv4_enabled    = var.connectivity != "private"
+      private_network = var.connectivity != "public" ? data.google_compute_network.authorized-network.self_link : null
     }
   }
 }
@@ -90,8 +156,18 @@ resource "google_sql_user" "admin_user" {
 }
 
 output name { value = google_sql_database.database.name }
-output hostname { value = google_sql_database_instance.instance.first_ip_address }
+// the broker connects to the private IP when there is one
+output hostname { value = coalesce(google_sql_database_instance.instance.private_ip_address, google_sql_database_instance.instance.public_ip_address) }
+output private_ip { value = google_sql_database_instance.instance.private_ip_address }
+output public_ip { value = google_sql_database_instance.instance.public_ip_address }
+output replica_hostnames { value = google_sql_database_instance.replica[*].first_ip_address }
+output backups_enabled { value = local.backups }
+output backup_start_time { value = google_sql_database_instance.instance.settings[0].backup_configuration[0].start_time }
+output binary_log_enabled { value = local.binary_log }
+output maintenance_window_day { value = var.maintenance_window_day }
+output maintenance_window_hour { value = var.maintenance_window_hour }
+output kms_key_name { value = var.kms_key_name }
 
-output port { value = (var.database_version == "POSTGRES_11" ? 5432 : 3306  ) }
+output port { value = (length(regexall("^POSTGRES", var.database_version)) > 0 ? 5432 : 3306  ) }
 output username { value = google_sql_user.admin_user.name }
 output password { value = google_sql_user.admin_user.password }
\ No newline at end of file
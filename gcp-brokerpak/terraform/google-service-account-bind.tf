@@ -14,6 +14,8 @@ variable name {type = string}
 variable credentials  { type = string }
 variable project  { type = string }
 variable role { type = string }
+variable binding_id { type = string }
+variable keyless_principal { type = string }
 
 provider "google" {
   version = ">=3.17.0"
@@ -21,24 +23,43 @@ provider "google" {
   project     = var.project 
 }
 
+locals {
+  // keyless bindings grant the role to the operator's principal instead of
+  // a new service account with a key
+  keyless = var.keyless_principal != ""
+}
+
 resource "google_service_account" "account" {
+  count = local.keyless ? 0 : 1
   account_id = substr(var.name, 0, 30)
   display_name = format("%s with role %s", var.name, var.role)
 }
 
 resource "google_service_account_key" "key" {
-  service_account_id = google_service_account.account.name
+  count = local.keyless ? 0 : 1
+  service_account_id = google_service_account.account[0].name
 }
 
 resource "google_project_iam_member" "member" {
   project = var.project
   role    = format("roles/%s", var.role)
-  member  = format("serviceAccount:%s", google_service_account.account.email)
+  member  = local.keyless ? var.keyless_principal : format("serviceAccount:%s", google_service_account.account[0].email)
+
+  // the principal is shared by every keyless binding, a condition per
+  // binding keeps this grant separate so unbinding doesn't revoke the others
+  dynamic "condition" {
+    for_each = local.keyless ? [var.binding_id] : []
+    content {
+      title       = format("csb-binding-%s", condition.value)
+      description = "Granted by the service broker, removed when the binding is deleted."
+      expression  = "request.time < timestamp(\"9999-12-31T23:59:59Z\")"
+    }
+  }
 }
 
-output "Name" {value = google_service_account.account.name}
-output "Email" {value = google_service_account.account.email}
-output "UniqueId" {value = google_service_account.account.unique_id}
-output "PrivateKeyData" {value = google_service_account_key.key.private_key}
-output "ProjectId" {value = google_service_account.account.project}
-output "Credentials" { value = base64decode(google_service_account_key.key.private_key) }
\ No newline at end of file
+output "Name" {value = join("", google_service_account.account.*.name)}
+output "Email" {value = join("", google_service_account.account.*.email)}
+output "UniqueId" {value = join("", google_service_account.account.*.unique_id)}
+output "PrivateKeyData" {value = join("", google_service_account_key.key.*.private_key)}
+output "ProjectId" {value = var.project}
+output "Credentials" { value = base64decode(join("", google_service_account_key.key.*.private_key)) }
\ No newline at end of file
@@ -2,23 +2,47 @@
     variable service_account_name {type = string}
     variable service_account_display_name {type = string}
     variable bucket {type = string}
+    variable binding_id {type = string}
+    variable keyless_principal {type = string}
+    variable project {type = string}
+
+    locals {
+      // keyless bindings grant the role to the operator's principal instead
+      // of a new service account with a key
+      keyless = var.keyless_principal != ""
+    }
 
     resource "google_service_account" "account" {
+      count = local.keyless ? 0 : 1
       account_id = var.service_account_name
       display_name = var.service_account_display_name
     }
     resource "google_service_account_key" "key" {
-      service_account_id = google_service_account.account.name
+      count = local.keyless ? 0 : 1
+      service_account_id = google_service_account.account[0].name
     }
     resource "google_storage_bucket_iam_member" "member" {
       bucket = var.bucket
       role   = format("roles/%s", var.role)
-      member = format("serviceAccount:%s", google_service_account.account.email)
+      member = local.keyless ? var.keyless_principal : format("serviceAccount:%s", google_service_account.account[0].email)
+
+      // the principal is shared by every keyless binding, a condition per
+      // binding keeps this grant separate so unbinding doesn't revoke the
+      // others. Buckets only accept conditions with uniform bucket-level
+      // access.
+      dynamic "condition" {
+        for_each = local.keyless ? [var.binding_id] : []
+        content {
+          title       = format("csb-binding-%s", condition.value)
+          description = "Granted by the service broker, removed when the binding is deleted."
+          expression  = "request.time < timestamp(\"9999-12-31T23:59:59Z\")"
+        }
+      }
     }
 
-    output Name {value = google_service_account.account.name}
-    output Email {value = google_service_account.account.email}
-    output UniqueId {value = google_service_account.account.unique_id}
-    output PrivateKeyData {value = google_service_account_key.key.private_key}
-    output ProjectId {value = google_service_account.account.project}
-    output Credentials { value = base64decode(google_service_account_key.key.private_key) }
\ No newline at end of file
+    output Name {value = join("", google_service_account.account.*.name)}
+    output Email {value = join("", google_service_account.account.*.email)}
+    output UniqueId {value = join("", google_service_account.account.*.unique_id)}
+    output PrivateKeyData {value = join("", google_service_account_key.key.*.private_key)}
+    output ProjectId {value = var.project}
+    output Credentials { value = base64decode(join("", google_service_account_key.key.*.private_key)) }
\ No newline at end of file
@@ -3,6 +3,14 @@ variable region {type = string}
 variable storage_class {type = string}
 variable labels {type = map}
 variable acl {type = string}
+variable versioning {type = bool}
+variable uniform_bucket_level_access {type = bool}
+variable retention_period_seconds {type = number}
+variable nearline_after_days {type = number}
+variable coldline_after_days {type = number}
+variable delete_after_days {type = number}
+variable noncurrent_versions_kept {type = number}
+variable kms_key_name {type = string}
 
 variable credentials  { type = string }
 variable project  { type = string }
@@ -13,12 +21,83 @@ provider "google" {
   project     = var.project
 }
 
+locals {
+  // lifecycle rules that are enabled, a zero age or count disables one
+  storage_class_rules = [
+    for rule in [
+      {storage_class = "NEARLINE", age = var.nearline_after_days},
+      {storage_class = "COLDLINE", age = var.coldline_after_days},
+    ] : rule if rule.age > 0
+  ]
+}
+
 resource "google_storage_bucket" "bucket" {
     name     = var.name
     location = var.region
     storage_class = var.storage_class
     labels = var.labels
+    uniform_bucket_level_access = var.uniform_bucket_level_access
+
+    versioning {
+      enabled = var.versioning
+    }
+
+    dynamic "encryption" {
+      for_each = var.kms_key_name == "" ? [] : [var.kms_key_name]
+      content {
+        default_kms_key_name = encryption.value
+      }
+    }
+
+    dynamic "retention_policy" {
+      for_each = var.retention_period_seconds > 0 ? [var.retention_period_seconds] : []
+      content {
+        retention_period = retention_policy.value
+      }
+    }
+
+    dynamic "lifecycle_rule" {
+      for_each = local.storage_class_rules
+      content {
+        action {
+          type          = "SetStorageClass"
+          storage_class = lifecycle_rule.value.storage_class
+        }
+        condition {
+          age = lifecycle_rule.value.age
+        }
+      }
+    }
+
+    dynamic "lifecycle_rule" {
+      for_each = var.delete_after_days > 0 ? [var.delete_after_days] : []
+      content {
+        action {
+          type = "Delete"
+        }
+        condition {
+          age = lifecycle_rule.value
+        }
+      }
+    }
+
+    dynamic "lifecycle_rule" {
+      for_each = var.noncurrent_versions_kept > 0 ? [var.noncurrent_versions_kept] : []
+      content {
+        action {
+          type = "Delete"
+        }
+        condition {
+          num_newer_versions = lifecycle_rule.value
+          with_state         = "ARCHIVED"
+        }
+      }
+    }
 }
 
 output id {value = google_storage_bucket.bucket.id}
 output bucket_name {value = var.name}
+output versioning {value = var.versioning}
+output uniform_bucket_level_access {value = var.uniform_bucket_level_access}
+output retention_period_seconds {value = var.retention_period_seconds}
+output kms_key_name {value = var.kms_key_name}
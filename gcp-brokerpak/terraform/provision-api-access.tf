@@ -0,0 +1,20 @@
+variable credentials  { type = string }
+variable project  { type = string }
+variable apis { type = list(string) }
+
+provider "google" {
+  version = ">=3.17.0"
+  credentials = var.credentials
+  project     = var.project
+}
+
+# Other instances and applications in the project may use the same APIs, so
+# deprovisioning leaves them enabled.
+resource "google_project_service" "api" {
+  for_each                   = toset(var.apis)
+  service                    = each.value
+  disable_on_destroy         = false
+  disable_dependent_services = false
+}
+
+output apis { value = sort([for api in google_project_service.api : api.service]) }
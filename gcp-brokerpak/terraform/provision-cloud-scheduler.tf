@@ -0,0 +1,46 @@
+variable credentials  { type = string }
+variable project  { type = string }
+variable job_name { type = string }
+variable schedule { type = string }
+variable time_zone { type = string }
+variable uri { type = string }
+variable http_method { type = string }
+variable body { type = string }
+variable oidc_service_account_email { type = string }
+variable retry_count { type = number }
+variable region { type = string }
+
+provider "google" {
+  version = ">=3.17.0"
+  credentials = var.credentials
+  project     = var.project
+}
+
+resource "google_cloud_scheduler_job" "job" {
+  name      = var.job_name
+  region    = var.region
+  schedule  = var.schedule
+  time_zone = var.time_zone
+
+  retry_config {
+    retry_count = var.retry_count
+  }
+
+  http_target {
+    uri         = var.uri
+    http_method = var.http_method
+    body        = var.body == "" ? null : base64encode(var.body)
+
+    dynamic "oidc_token" {
+      for_each = var.oidc_service_account_email == "" ? [] : [var.oidc_service_account_email]
+      content {
+        service_account_email = oidc_token.value
+      }
+    }
+  }
+}
+
+output job { value = google_cloud_scheduler_job.job.id }
+output job_name { value = google_cloud_scheduler_job.job.name }
+output schedule { value = google_cloud_scheduler_job.job.schedule }
+output time_zone { value = google_cloud_scheduler_job.job.time_zone }
@@ -0,0 +1,36 @@
+variable credentials  { type = string }
+variable project  { type = string }
+variable queue_name { type = string }
+variable location { type = string }
+variable max_dispatches_per_second { type = number }
+variable max_concurrent_dispatches { type = number }
+variable max_attempts { type = number }
+variable min_backoff_seconds { type = number }
+variable max_backoff_seconds { type = number }
+
+provider "google" {
+  version = ">=3.17.0"
+  credentials = var.credentials
+  project     = var.project
+}
+
+# Deleting the queue deletes the tasks in it.
+resource "google_cloud_tasks_queue" "queue" {
+  name     = var.queue_name
+  location = var.location
+
+  rate_limits {
+    max_dispatches_per_second = var.max_dispatches_per_second
+    max_concurrent_dispatches = var.max_concurrent_dispatches
+  }
+
+  retry_config {
+    max_attempts = var.max_attempts
+    min_backoff  = format("%ss", var.min_backoff_seconds)
+    max_backoff  = format("%ss", var.max_backoff_seconds)
+  }
+}
+
+output queue { value = google_cloud_tasks_queue.queue.id }
+output queue_name { value = google_cloud_tasks_queue.queue.name }
+output location { value = google_cloud_tasks_queue.queue.location }
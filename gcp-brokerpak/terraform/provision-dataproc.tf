@@ -2,7 +2,7 @@ variable worker_machine_type {type = string}
 variable master_machine_type {type = string}
 variable worker_count {type = number}
 variable master_count {type = number}
-variable preemptible_count {type = number}
+variable preemptible_ratio {type = number}
 
 variable name {type = string}
 variable region {type = string}
@@ -34,11 +34,12 @@ variable labels {type = map}
         }
 
         preemptible_worker_config {
-          num_instances = var.preemptible_count
+          num_instances = floor(var.worker_count * var.preemptible_ratio)
         }
       }
     }
 
     output bucket_name {value = google_dataproc_cluster.cluster.cluster_config.0.bucket}
     output cluster_name {value = google_dataproc_cluster.cluster.name}
-    output region {value = google_dataproc_cluster.cluster.region}
\ No newline at end of file
+    output region {value = google_dataproc_cluster.cluster.region}
+    output master_hosts {value = google_dataproc_cluster.cluster.cluster_config.0.master_config.0.instance_names}
\ No newline at end of file
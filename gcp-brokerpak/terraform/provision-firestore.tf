@@ -0,0 +1,22 @@
+variable credentials  { type = string }
+variable project  { type = string }
+variable database_type { type = string }
+variable location { type = string }
+
+provider "google" {
+  version = ">=3.17.0"
+  credentials = var.credentials
+  project     = var.project
+}
+
+# Firestore databases belong to the project's App Engine application, which
+# can't be deleted. Destroying the instance only removes it from the state.
+resource "google_app_engine_application" "app" {
+  project       = var.project
+  location_id   = var.location
+  database_type = var.database_type
+}
+
+output project { value = google_app_engine_application.app.project }
+output location { value = google_app_engine_application.app.location_id }
+output database_type { value = google_app_engine_application.app.database_type }
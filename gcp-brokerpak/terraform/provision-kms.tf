@@ -0,0 +1,48 @@
+variable credentials  { type = string }
+variable project  { type = string }
+variable labels { type = map }
+variable key_ring_name { type = string }
+variable key_name { type = string }
+variable location { type = string }
+variable purpose { type = string }
+variable algorithm { type = string }
+variable protection_level { type = string }
+variable rotation_period_days { type = number }
+
+provider "google" {
+  version = ">=3.17.0"
+  credentials = var.credentials
+  project     = var.project
+}
+
+locals {
+  // only symmetric keys are rotated automatically
+  rotation_period_days = var.purpose == "ENCRYPT_DECRYPT" ? var.rotation_period_days : 0
+}
+
+# Key rings and keys can't be deleted. Destroying the instance destroys the
+# key's versions, so nothing can be encrypted or decrypted with it, and
+# removes both from the state.
+resource "google_kms_key_ring" "key_ring" {
+  name     = var.key_ring_name
+  location = var.location
+}
+
+resource "google_kms_crypto_key" "key" {
+  name            = var.key_name
+  key_ring        = google_kms_key_ring.key_ring.id
+  purpose         = var.purpose
+  rotation_period = local.rotation_period_days > 0 ? format("%ds", local.rotation_period_days * 86400) : null
+  labels          = var.labels
+
+  version_template {
+    algorithm        = var.algorithm
+    protection_level = var.protection_level
+  }
+}
+
+output kms_key_name { value = google_kms_crypto_key.key.id }
+output key_ring { value = google_kms_key_ring.key_ring.id }
+output location { value = google_kms_key_ring.key_ring.location }
+output purpose { value = google_kms_crypto_key.key.purpose }
+output rotation_period_days { value = local.rotation_period_days }
@@ -1,5 +1,6 @@
 variable service_tier { type = string }
     variable authorized_network { type = string }
+    variable reserved_ip_range { type = string }
     variable display_name { type = string }
     variable instance_id { type = string }
     variable region { type = string }
@@ -25,6 +26,7 @@ variable service_tier { type = string }
       display_name       = var.display_name
       region             = var.region
       authorized_network = data.google_compute_network.authorized-network.self_link
+      reserved_ip_range  = var.reserved_ip_range == "" ? null : var.reserved_ip_range
       labels             = var.labels
 
       timeouts {
@@ -38,4 +40,5 @@ variable service_tier { type = string }
     output service_tier { value = google_redis_instance.instance.tier }
     output redis_version { value = google_redis_instance.instance.redis_version }
     output host { value = google_redis_instance.instance.host }
-    output port { value = google_redis_instance.instance.port }
\ No newline at end of file
+    output port { value = google_redis_instance.instance.port }
+    output reserved_ip_range { value = google_redis_instance.instance.reserved_ip_range }
\ No newline at end of file
@@ -0,0 +1,38 @@
+variable credentials  { type = string }
+variable project  { type = string }
+variable labels { type = map }
+variable secret_id { type = string }
+variable replication { type = string }
+variable region { type = string }
+
+// Secret Manager is only in the beta provider
+provider "google-beta" {
+  version = ">=3.22.0"
+  credentials = var.credentials
+  project     = var.project
+}
+
+# Apps get values from the secret's versions, which are added by whoever
+# manages the secret rather than the broker, so they're never in the state.
+resource "google_secret_manager_secret" "secret" {
+  provider  = google-beta
+  secret_id = var.secret_id
+  labels    = var.labels
+
+  replication {
+    automatic = var.replication == "automatic" ? true : null
+
+    dynamic "user_managed" {
+      for_each = var.replication == "user_managed" ? [var.region] : []
+      content {
+        replicas {
+          location = user_managed.value
+        }
+      }
+    }
+  }
+}
+
+output secret_name { value = google_secret_manager_secret.secret.name }
+output secret_id { value = google_secret_manager_secret.secret.secret_id }
+output latest_version { value = format("%s/versions/latest", google_secret_manager_secret.secret.name) }
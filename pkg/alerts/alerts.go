@@ -0,0 +1,358 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package alerts tells operators about operations that failed or have been in
+// progress for too long, e.g. in Slack or by email, so they don't have to
+// watch the logs or poll the admin API.
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/inventory"
+	"github.com/spf13/viper"
+)
+
+const (
+	// IntervalProp is the viper key of how often a running broker checks for
+	// failed and stuck operations. Zero disables alerting.
+	IntervalProp = "alerts.interval"
+
+	// StuckAfterProp is the viper key of how long an operation can be in
+	// progress before it's stuck.
+	StuckAfterProp = "alerts.stuck_after"
+
+	// RepeatAfterProp is the viper key of how long an alert isn't sent again
+	// for, e.g. while an operation stays stuck.
+	RepeatAfterProp = "alerts.repeat_after"
+
+	// MaxPerHourProp is the viper key of the most messages sent to each
+	// channel in an hour, alerts past it are counted and reported in the next
+	// message.
+	MaxPerHourProp = "alerts.max_per_hour"
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: IntervalProp, Kind: config.Duration, Default: "1m"},
+		config.Property{Key: StuckAfterProp, Kind: config.Duration, Default: "1h"},
+		config.Property{Key: RepeatAfterProp, Kind: config.Duration, Default: "24h"},
+		config.Property{Key: MaxPerHourProp, Kind: config.Integer, Default: 10},
+	)
+}
+
+// Kinds of alerts.
+const (
+	Failed = "failed"
+	Stuck  = "stuck"
+)
+
+// maxOperations is the most operations looked at in each check.
+const maxOperations = 1000
+
+// Alert is an operation that needs an operator's attention.
+type Alert struct {
+	Kind             string
+	InstanceId       string
+	BindingId        string
+	ServiceName      string
+	PlanName         string
+	OrganizationGuid string
+	SpaceGuid        string
+	OperationType    string
+	Message          string
+	UpdatedAt        time.Time
+}
+
+// key identifies the alert so it isn't sent twice. An operation that makes
+// progress and gets stuck again is alerted again.
+func (a Alert) key() string {
+	return strings.Join([]string{a.Kind, a.InstanceId, a.BindingId, a.OperationType, a.UpdatedAt.UTC().Format(time.RFC3339Nano)}, "|")
+}
+
+// Channel sends alerts to operators.
+type Channel interface {
+	// Name identifies the channel in logs.
+	Name() string
+
+	// Send sends a message about the alerts, suppressed is the number of
+	// alerts that weren't sent since the last message because of throttling.
+	Send(ctx context.Context, alerts []Alert, suppressed int) error
+}
+
+// Watcher checks the operations the broker lists for failures and operations
+// that are stuck in progress and sends alerts about them to every channel.
+// What's been sent is kept in memory, so a restart or a new leader can send an
+// alert about a stuck operation again.
+type Watcher struct {
+	Lister   inventory.Lister
+	Channels []Channel
+
+	StuckAfter  time.Duration
+	RepeatAfter time.Duration
+	MaxPerHour  int
+
+	Logger lager.Logger
+
+	// LeaderContext reports whether this instance should send alerts, with
+	// a context that's cancelled if it stops leading. All instances send
+	// alerts if it's nil.
+	LeaderContext func(ctx context.Context) (context.Context, context.CancelFunc, bool)
+
+	// now is used to get the current time, it can be replaced in tests.
+	now func() time.Time
+
+	mu sync.Mutex
+	// checkedUntil is when the last check started, failures before it have
+	// already been alerted.
+	checkedUntil time.Time
+	sent         map[string]time.Time
+	throttles    map[string]*throttle
+}
+
+// NewWatcherFromEnv creates a Watcher with the channels configured in viper,
+// or returns nil if none are.
+func NewWatcherFromEnv(lister inventory.Lister, logger lager.Logger) (*Watcher, error) {
+	channels, err := channelsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if len(channels) == 0 {
+		return nil, nil
+	}
+
+	maxPerHour := viper.GetInt(MaxPerHourProp)
+	if maxPerHour < 1 {
+		return nil, fmt.Errorf("%s must be at least 1, got %d", MaxPerHourProp, maxPerHour)
+	}
+
+	return &Watcher{
+		Lister:      lister,
+		Channels:    channels,
+		StuckAfter:  viper.GetDuration(StuckAfterProp),
+		RepeatAfter: viper.GetDuration(RepeatAfterProp),
+		MaxPerHour:  maxPerHour,
+		Logger:      logger.Session("alerts"),
+	}, nil
+}
+
+func (w *Watcher) currentTime() time.Time {
+	if w.now != nil {
+		return w.now()
+	}
+
+	return time.Now()
+}
+
+// Check finds the operations that failed since the last check, or since
+// the watcher's first check minus the interval, and the operations stuck in
+// progress, and sends alerts about the ones that weren't recently sent.
+func (w *Watcher) Check(ctx context.Context, interval time.Duration) ([]Alert, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.currentTime()
+	since := w.checkedUntil
+	if since.IsZero() {
+		since = now.Add(-interval)
+	}
+
+	alerts, err := w.find(ctx, since, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// the alerts are left to the instance that leads now
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if w.sent == nil {
+		w.sent = make(map[string]time.Time)
+	}
+	for key, sentAt := range w.sent {
+		if now.Sub(sentAt) >= w.RepeatAfter {
+			delete(w.sent, key)
+		}
+	}
+
+	var fresh []Alert
+	for _, alert := range alerts {
+		if _, ok := w.sent[alert.key()]; !ok {
+			fresh = append(fresh, alert)
+			w.sent[alert.key()] = now
+		}
+	}
+
+	// operations that fail while this check runs are found by the next one
+	w.checkedUntil = now
+
+	if len(fresh) > 0 {
+		w.send(ctx, fresh, now)
+	}
+
+	return fresh, nil
+}
+
+// find lists the operations that failed in the period and the ones stuck at
+// the end of it, with the details of their instances.
+func (w *Watcher) find(ctx context.Context, since, now time.Time) ([]Alert, error) {
+	instances, err := w.Lister.ListInstances(ctx, inventory.Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[string]inventory.Instance)
+	for _, instance := range instances {
+		byId[instance.InstanceId] = instance
+	}
+
+	var alerts []Alert
+	for _, state := range []string{inventory.StateFailed, inventory.StateInProgress} {
+		operations, err := w.Lister.ListOperations(ctx, inventory.Filter{State: state, Limit: maxOperations})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, operation := range operations {
+			var kind string
+			switch {
+			case state == inventory.StateFailed && operation.UpdatedAt.After(since):
+				kind = Failed
+			case state == inventory.StateInProgress && now.Sub(operation.UpdatedAt) >= w.StuckAfter:
+				kind = Stuck
+			default:
+				continue
+			}
+
+			instance := byId[operation.InstanceId]
+			alerts = append(alerts, Alert{
+				Kind:             kind,
+				InstanceId:       operation.InstanceId,
+				BindingId:        operation.BindingId,
+				ServiceName:      operation.ServiceName,
+				PlanName:         instance.PlanName,
+				OrganizationGuid: instance.OrganizationGuid,
+				SpaceGuid:        instance.SpaceGuid,
+				OperationType:    operation.Type,
+				Message:          operation.Message,
+				UpdatedAt:        operation.UpdatedAt,
+			})
+		}
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool {
+		return alerts[i].UpdatedAt.Before(alerts[j].UpdatedAt)
+	})
+
+	return alerts, nil
+}
+
+// send sends the alerts to every channel that hasn't reached its limit,
+// channels that have count them as suppressed.
+func (w *Watcher) send(ctx context.Context, alerts []Alert, now time.Time) {
+	if w.throttles == nil {
+		w.throttles = make(map[string]*throttle)
+	}
+
+	for _, channel := range w.Channels {
+		t, ok := w.throttles[channel.Name()]
+		if !ok {
+			t = &throttle{}
+			w.throttles[channel.Name()] = t
+		}
+
+		data := lager.Data{"channel": channel.Name(), "alerts": len(alerts)}
+		if !t.allow(now, w.MaxPerHour) {
+			t.suppressed += len(alerts)
+			w.Logger.Info("throttled-alerts", data)
+			continue
+		}
+
+		if err := channel.Send(ctx, alerts, t.suppressed); err != nil {
+			// the alerts are lost, they're still in the logs and admin API
+			w.Logger.Error("sending-alerts", err, data)
+			continue
+		}
+
+		t.suppressed = 0
+		w.Logger.Info("sent-alerts", data)
+	}
+}
+
+// RunEvery checks for alerts every interval until the context is done. Checks
+// are skipped while another instance leads if LeaderContext is set.
+func (w *Watcher) RunEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runCtx, cancel, leads := ctx, context.CancelFunc(func() {}), true
+			if w.LeaderContext != nil {
+				runCtx, cancel, leads = w.LeaderContext(ctx)
+			}
+			if !leads {
+				cancel()
+				w.Logger.Debug("skipping-run", lager.Data{"reason": "another instance leads alerting"})
+				// if this instance leads again it only looks back an
+				// interval
+				w.mu.Lock()
+				w.checkedUntil = time.Time{}
+				w.mu.Unlock()
+				continue
+			}
+
+			if _, err := w.Check(runCtx, interval); err != nil {
+				w.Logger.Error("checking-alerts", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// throttle limits the messages sent to a channel in a rolling hour.
+type throttle struct {
+	sentAt     []time.Time
+	suppressed int
+}
+
+// allow records a message sent now and returns true if fewer than max were
+// sent in the last hour.
+func (t *throttle) allow(now time.Time, max int) bool {
+	recent := t.sentAt[:0]
+	for _, sentAt := range t.sentAt {
+		if now.Sub(sentAt) < time.Hour {
+			recent = append(recent, sentAt)
+		}
+	}
+	t.sentAt = recent
+
+	if len(t.sentAt) >= max {
+		return false
+	}
+
+	t.sentAt = append(t.sentAt, now)
+	return true
+}
@@ -0,0 +1,300 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/inventory"
+	"github.com/spf13/viper"
+)
+
+// fakeLister lists fixed instances and operations, filtering operations by
+// state.
+type fakeLister struct {
+	Instances  []inventory.Instance
+	Operations []inventory.Operation
+}
+
+func (l *fakeLister) ListInstances(ctx context.Context, filter inventory.Filter) ([]inventory.Instance, error) {
+	return l.Instances, nil
+}
+
+func (l *fakeLister) ListBindings(ctx context.Context, filter inventory.Filter) ([]inventory.Binding, error) {
+	return nil, nil
+}
+
+func (l *fakeLister) ListOperations(ctx context.Context, filter inventory.Filter) ([]inventory.Operation, error) {
+	var out []inventory.Operation
+	for _, operation := range l.Operations {
+		if filter.State == "" || filter.State == operation.State {
+			out = append(out, operation)
+		}
+	}
+	return out, nil
+}
+
+// recordingChannel keeps what it's sent.
+type recordingChannel struct {
+	Sent       [][]Alert
+	Suppressed []int
+}
+
+func (c *recordingChannel) Name() string {
+	return "recording"
+}
+
+func (c *recordingChannel) Send(ctx context.Context, alerts []Alert, suppressed int) error {
+	c.Sent = append(c.Sent, alerts)
+	c.Suppressed = append(c.Suppressed, suppressed)
+	return nil
+}
+
+func kindsAndInstances(alerts []Alert) []string {
+	out := []string{}
+	for _, alert := range alerts {
+		out = append(out, alert.Kind+" "+alert.InstanceId)
+	}
+	return out
+}
+
+func TestWatcher_Check(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	interval := time.Minute
+
+	lister := &fakeLister{
+		Instances: []inventory.Instance{
+			{InstanceId: "instance-1", PlanName: "small", OrganizationGuid: "org-1", SpaceGuid: "space-1"},
+		},
+		Operations: []inventory.Operation{
+			{InstanceId: "instance-1", ServiceName: "csb-db", Type: "provision", State: inventory.StateFailed, Message: "quota exceeded", UpdatedAt: start.Add(-30 * time.Second)},
+			{InstanceId: "old-failure", Type: "provision", State: inventory.StateFailed, UpdatedAt: start.Add(-time.Hour)},
+			{InstanceId: "stuck", Type: "deprovision", State: inventory.StateInProgress, UpdatedAt: start.Add(-2 * time.Hour)},
+			{InstanceId: "running", Type: "provision", State: inventory.StateInProgress, UpdatedAt: start.Add(-time.Minute)},
+			{InstanceId: "done", Type: "provision", State: inventory.StateSucceeded, UpdatedAt: start.Add(-time.Minute)},
+		},
+	}
+
+	channel := &recordingChannel{}
+	now := start
+	watcher := &Watcher{
+		Lister:      lister,
+		Channels:    []Channel{channel},
+		StuckAfter:  time.Hour,
+		RepeatAfter: 24 * time.Hour,
+		MaxPerHour:  2,
+		Logger:      lager.NewLogger("test"),
+		now:         func() time.Time { return now },
+	}
+
+	check := func(expected ...string) {
+		t.Helper()
+
+		alerts, err := watcher.Check(context.Background(), interval)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := kindsAndInstances(alerts); !reflect.DeepEqual(append([]string{}, expected...), actual) {
+			t.Errorf("Expected alerts %v, got %v", expected, actual)
+		}
+	}
+
+	// the first check looks back an interval
+	check(Stuck+" stuck", Failed+" instance-1")
+	first := channel.Sent[0][1]
+	expected := Alert{
+		Kind:             Failed,
+		InstanceId:       "instance-1",
+		ServiceName:      "csb-db",
+		PlanName:         "small",
+		OrganizationGuid: "org-1",
+		SpaceGuid:        "space-1",
+		OperationType:    "provision",
+		Message:          "quota exceeded",
+		UpdatedAt:        start.Add(-30 * time.Second),
+	}
+	if !reflect.DeepEqual(expected, first) {
+		t.Errorf("Expected %#v, got %#v", expected, first)
+	}
+
+	// alerts aren't repeated
+	now = now.Add(interval)
+	check()
+
+	// new failures are
+	lister.Operations = append(lister.Operations, inventory.Operation{InstanceId: "new-failure", Type: "bind", BindingId: "binding-1", State: inventory.StateFailed, UpdatedAt: now.Add(interval / 2)})
+	now = now.Add(interval)
+	check(Failed + " new-failure")
+
+	// the third message in an hour is throttled
+	lister.Operations = append(lister.Operations, inventory.Operation{InstanceId: "another-failure", Type: "unbind", State: inventory.StateFailed, UpdatedAt: now.Add(interval / 2)})
+	now = now.Add(interval)
+	check(Failed + " another-failure")
+	if len(channel.Sent) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(channel.Sent))
+	}
+
+	// the next message once the hour has passed reports what was throttled,
+	// by then another operation is stuck
+	now = start.Add(time.Hour)
+	lister.Operations = append(lister.Operations, inventory.Operation{InstanceId: "late-failure", Type: "update", State: inventory.StateFailed, UpdatedAt: now.Add(-time.Second)})
+	check(Stuck+" running", Failed+" late-failure")
+	if !reflect.DeepEqual([]int{0, 0, 1}, channel.Suppressed) {
+		t.Errorf("Expected the suppressed counts [0 0 1], got %v", channel.Suppressed)
+	}
+
+	// stuck operations are alerted again after the repeat window
+	now = start.Add(25 * time.Hour)
+	check(Stuck+" stuck", Stuck+" running")
+}
+
+func TestText(t *testing.T) {
+	alerts := []Alert{
+		{Kind: Failed, InstanceId: "instance-1", ServiceName: "csb-db", PlanName: "small", OrganizationGuid: "org-1", SpaceGuid: "space-1", OperationType: "provision", Message: "quota exceeded"},
+		{Kind: Stuck, InstanceId: "instance-2", BindingId: "binding-1", ServiceName: "csb-db", OrganizationGuid: "org-1", SpaceGuid: "space-2", OperationType: "bind", UpdatedAt: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)},
+	}
+
+	expectedSummary := "Service broker: 1 operation failed, 1 operation is stuck"
+	if actual := Summary(alerts); actual != expectedSummary {
+		t.Errorf("Expected summary %q, got %q", expectedSummary, actual)
+	}
+
+	expectedText := `provision of instance instance-1 failed
+  service: csb-db small, organization: org-1, space: space-1
+  quota exceeded
+bind of binding binding-1 of instance instance-2 in progress since 2026-10-01T12:00:00Z
+  service: csb-db, organization: org-1, space: space-2
+3 other alerts weren't sent because of throttling, see the broker's logs or /admin/operations
+`
+	if actual := Text(alerts, 3); actual != expectedText {
+		t.Errorf("Expected text:\n%s\ngot:\n%s", expectedText, actual)
+	}
+}
+
+func TestSlackChannel_Send(t *testing.T) {
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	channel := &SlackChannel{WebhookUrl: server.URL, Client: server.Client()}
+	alerts := []Alert{{Kind: Failed, InstanceId: "instance-1", OperationType: "provision"}}
+	if err := channel.Send(context.Background(), alerts, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(received["text"], "*Service broker: 1 operation failed*\n```\nprovision of instance instance-1 failed\n") {
+		t.Errorf("Expected the alert to be posted, got %q", received["text"])
+	}
+}
+
+func TestEmailChannel_Send(t *testing.T) {
+	var addr, from string
+	var to []string
+	var msg []byte
+	channel := &EmailChannel{
+		Addr: "smtp.example.com:587",
+		From: "broker@example.com",
+		To:   []string{"ops@example.com", "oncall@example.com"},
+		sendMail: func(a string, auth smtp.Auth, f string, t []string, m []byte) error {
+			addr, from, to, msg = a, f, t, m
+			return nil
+		},
+	}
+
+	alerts := []Alert{{Kind: Stuck, InstanceId: "instance-1", OperationType: "deprovision"}}
+	if err := channel.Send(context.Background(), alerts, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if addr != "smtp.example.com:587" || from != "broker@example.com" || !reflect.DeepEqual(channel.To, to) {
+		t.Errorf("Expected the message to be sent through the server to every address, got %s %s %v", addr, from, to)
+	}
+
+	for _, expected := range []string{
+		"To: ops@example.com, oncall@example.com\r\n",
+		"Subject: Service broker: 1 operation is stuck\r\n",
+		"\r\n\r\ndeprovision of instance instance-1 in progress since",
+	} {
+		if !strings.Contains(string(msg), expected) {
+			t.Errorf("Expected the message to contain %q, got %q", expected, msg)
+		}
+	}
+}
+
+func TestNewWatcherFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Config           map[string]interface{}
+		ExpectedChannels []string
+		ExpectErr        bool
+	}{
+		"nothing configured": {},
+		"slack and email": {
+			Config: map[string]interface{}{
+				SlackWebhookUrlProp: "https://hooks.slack.com/services/T0/B0/x",
+				SmtpHostProp:        "smtp.example.com",
+				SmtpFromProp:        "broker@example.com",
+				SmtpToProp:          "ops@example.com, oncall@example.com",
+			},
+			ExpectedChannels: []string{"slack", "email"},
+		},
+		"email without recipients": {
+			Config:    map[string]interface{}{SmtpHostProp: "smtp.example.com", SmtpFromProp: "broker@example.com"},
+			ExpectErr: true,
+		},
+		"no messages allowed": {
+			Config:    map[string]interface{}{SlackWebhookUrlProp: "https://hooks.slack.com/services/T0/B0/x", MaxPerHourProp: 0},
+			ExpectErr: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			for key, value := range tc.Config {
+				viper.Set(key, value)
+				defer viper.Set(key, nil)
+			}
+
+			watcher, err := NewWatcherFromEnv(&fakeLister{}, lager.NewLogger("test"))
+			if hasErr := err != nil; hasErr != tc.ExpectErr {
+				t.Fatalf("Expected error: %v, got %v", tc.ExpectErr, err)
+			}
+
+			var channels []string
+			if watcher != nil {
+				for _, channel := range watcher.Channels {
+					channels = append(channels, channel.Name())
+				}
+			}
+
+			if !reflect.DeepEqual(tc.ExpectedChannels, channels) {
+				t.Errorf("Expected channels %v, got %v", tc.ExpectedChannels, channels)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// SlackWebhookUrlProp is the viper key of the Slack incoming webhook
+	// alerts are posted to.
+	SlackWebhookUrlProp = "alerts.slack.webhook_url"
+
+	// SmtpHostProp is the viper key of the SMTP server alerts are emailed
+	// through.
+	SmtpHostProp = "alerts.smtp.host"
+
+	// SmtpPortProp is the viper key of the SMTP server's port.
+	SmtpPortProp = "alerts.smtp.port"
+
+	// SmtpUsernameProp and SmtpPasswordProp are the viper keys of the
+	// credentials used to authenticate to the SMTP server, if it requires
+	// them.
+	SmtpUsernameProp = "alerts.smtp.username"
+	SmtpPasswordProp = "alerts.smtp.password"
+
+	// SmtpFromProp is the viper key of the address alerts are sent from.
+	SmtpFromProp = "alerts.smtp.from"
+
+	// SmtpToProp is the viper key of the comma delimited addresses alerts are
+	// sent to.
+	SmtpToProp = "alerts.smtp.to"
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: SlackWebhookUrlProp, Kind: config.String, Sensitive: true},
+		config.Property{Key: SmtpHostProp, Kind: config.String},
+		config.Property{Key: SmtpPortProp, Kind: config.Integer, Default: 587},
+		config.Property{Key: SmtpUsernameProp, Kind: config.String},
+		config.Property{Key: SmtpPasswordProp, Kind: config.String, Sensitive: true},
+		config.Property{Key: SmtpFromProp, Kind: config.String},
+		config.Property{Key: SmtpToProp, Kind: config.String},
+	)
+}
+
+// channelsFromEnv creates the channels configured in viper.
+func channelsFromEnv() ([]Channel, error) {
+	var channels []Channel
+	if url := viper.GetString(SlackWebhookUrlProp); url != "" {
+		channels = append(channels, &SlackChannel{WebhookUrl: url, Client: &http.Client{Timeout: 30 * time.Second}})
+	}
+
+	if host := viper.GetString(SmtpHostProp); host != "" {
+		var to []string
+		for _, address := range strings.Split(viper.GetString(SmtpToProp), ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				to = append(to, address)
+			}
+		}
+
+		from := viper.GetString(SmtpFromProp)
+		if from == "" || len(to) == 0 {
+			return nil, fmt.Errorf("%s and %s must be set to email alerts", SmtpFromProp, SmtpToProp)
+		}
+
+		email := &EmailChannel{
+			Addr: net.JoinHostPort(host, strconv.Itoa(viper.GetInt(SmtpPortProp))),
+			From: from,
+			To:   to,
+		}
+		if username := viper.GetString(SmtpUsernameProp); username != "" {
+			email.Auth = smtp.PlainAuth("", username, viper.GetString(SmtpPasswordProp), host)
+		}
+		channels = append(channels, email)
+	}
+
+	return channels, nil
+}
+
+// Summary describes the alerts in a line.
+func Summary(alerts []Alert) string {
+	failed, stuck := 0, 0
+	for _, alert := range alerts {
+		if alert.Kind == Failed {
+			failed++
+		} else {
+			stuck++
+		}
+	}
+
+	var parts []string
+	if failed > 0 {
+		parts = append(parts, plural(failed, "operation failed", "operations failed"))
+	}
+	if stuck > 0 {
+		parts = append(parts, plural(stuck, "operation is stuck", "operations are stuck"))
+	}
+
+	return "Service broker: " + strings.Join(parts, ", ")
+}
+
+func plural(count int, singular, plural string) string {
+	if count == 1 {
+		return "1 " + singular
+	}
+
+	return fmt.Sprintf("%d %s", count, plural)
+}
+
+// Text describes each alert on its own lines.
+func Text(alerts []Alert, suppressed int) string {
+	var b strings.Builder
+	for _, alert := range alerts {
+		subject := "instance " + alert.InstanceId
+		if alert.BindingId != "" {
+			subject = "binding " + alert.BindingId + " of " + subject
+		}
+
+		verb := "failed"
+		if alert.Kind == Stuck {
+			verb = "in progress since " + alert.UpdatedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(&b, "%s of %s %s\n", alert.OperationType, subject, verb)
+
+		service := alert.ServiceName
+		if alert.PlanName != "" {
+			service += " " + alert.PlanName
+		}
+		fmt.Fprintf(&b, "  service: %s, organization: %s, space: %s\n", service, alert.OrganizationGuid, alert.SpaceGuid)
+
+		if alert.Message != "" {
+			fmt.Fprintf(&b, "  %s\n", alert.Message)
+		}
+	}
+
+	if suppressed > 0 {
+		fmt.Fprintf(&b, "%s weren't sent because of throttling, see the broker's logs or /admin/operations\n", plural(suppressed, "other alert", "other alerts"))
+	}
+
+	return b.String()
+}
+
+// SlackChannel posts alerts to a Slack incoming webhook.
+type SlackChannel struct {
+	WebhookUrl string
+	Client     *http.Client
+}
+
+// Name implements Channel.
+func (c *SlackChannel) Name() string {
+	return "slack"
+}
+
+// Send implements Channel.
+func (c *SlackChannel) Send(ctx context.Context, alerts []Alert, suppressed int) error {
+	body, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s*\n```\n%s```", Summary(alerts), Text(alerts, suppressed)),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.WebhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't post to Slack: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("couldn't post to Slack, it responded with: %s %s", resp.Status, message)
+	}
+
+	return nil
+}
+
+// EmailChannel emails alerts through an SMTP server.
+type EmailChannel struct {
+	// Addr is the host and port of the SMTP server.
+	Addr string
+	Auth smtp.Auth
+	From string
+	To   []string
+
+	// sendMail sends the message, it's smtp.SendMail if it's nil.
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// Name implements Channel.
+func (c *EmailChannel) Name() string {
+	return "email"
+}
+
+// Send implements Channel.
+func (c *EmailChannel) Send(ctx context.Context, alerts []Alert, suppressed int) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", c.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(c.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", Summary(alerts))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.Replace(Text(alerts, suppressed), "\n", "\r\n", -1))
+
+	sendMail := c.sendMail
+	if sendMail == nil {
+		sendMail = smtp.SendMail
+	}
+
+	if err := sendMail(c.Addr, c.Auth, c.From, c.To, msg.Bytes()); err != nil {
+		return fmt.Errorf("couldn't email alerts: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apiversion negotiates the OSB API version of each request from its
+// X-Broker-API-Version header, rejects versions the broker doesn't support
+// and turns off features the platform's version doesn't have.
+package apiversion
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/ratelimit"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Header is the header platforms send their OSB API version in.
+	Header = "X-Broker-API-Version"
+
+	// MinVersionProp is the viper key of the oldest OSB API version requests
+	// may use.
+	MinVersionProp = "api.min_version"
+
+	// StrictProp is the viper key of whether requests for versions newer than
+	// Latest, and for endpoints or features their version doesn't have, are
+	// rejected rather than handled the way the version allows.
+	StrictProp = "api.strict_version"
+)
+
+var (
+	// Latest is the newest OSB API version the broker implements.
+	Latest = Version{Major: 2, Minor: 15}
+
+	// AsyncBindings is the version that added asynchronous binding and
+	// unbinding.
+	AsyncBindings = Version{Major: 2, Minor: 14}
+
+	// FetchEndpoints is the version that added the endpoints to get an
+	// instance, a binding and the last operation of a binding.
+	FetchEndpoints = Version{Major: 2, Minor: 14}
+)
+
+// fetchEndpoints are the names of the endpoints added in FetchEndpoints.
+var fetchEndpoints = map[string]bool{
+	"get_instance":           true,
+	"get_binding":            true,
+	"last_binding_operation": true,
+}
+
+// metrics counts requests by version, it's published at /debug/vars.
+var metrics = expvar.NewMap("broker_api_versions")
+
+func init() {
+	config.Register(
+		config.Property{Key: MinVersionProp, Kind: config.String, Default: "2.13"},
+		config.Property{Key: StrictProp, Kind: config.Boolean, Default: false},
+	)
+}
+
+// Version is an OSB API version.
+type Version struct {
+	Major int
+	Minor int
+}
+
+// Parse parses a version like 2.14.
+func Parse(value string) (Version, error) {
+	var v Version
+	if n, err := fmt.Sscanf(value, "%d.%d", &v.Major, &v.Minor); err != nil || n != 2 || v.Major < 0 || v.Minor < 0 {
+		return Version{}, fmt.Errorf("%q isn't a version like 2.14", value)
+	}
+
+	return v, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// AtLeast returns true if v is other or newer.
+func (v Version) AtLeast(other Version) bool {
+	return v.Major > other.Major || (v.Major == other.Major && v.Minor >= other.Minor)
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the negotiated version.
+func NewContext(ctx context.Context, v Version) context.Context {
+	return context.WithValue(ctx, contextKey{}, v)
+}
+
+// FromContext returns the version negotiated for the request the context is
+// from, or false if it's not from one.
+func FromContext(ctx context.Context) (Version, bool) {
+	v, ok := ctx.Value(contextKey{}).(Version)
+	return v, ok
+}
+
+// Supports returns true if the request the context is from negotiated a
+// version with the feature added in the given version. Outside requests
+// every feature is supported.
+func Supports(ctx context.Context, feature Version) bool {
+	v, ok := FromContext(ctx)
+	return !ok || v.AtLeast(feature)
+}
+
+// Middleware negotiates the version of OSB API requests.
+type Middleware struct {
+	Min    Version
+	Strict bool
+	Logger lager.Logger
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// NewMiddlewareFromEnv creates a Middleware from the settings in viper.
+func NewMiddlewareFromEnv(logger lager.Logger) (*Middleware, error) {
+	min, err := Parse(viper.GetString(MinVersionProp))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", MinVersionProp, err)
+	}
+
+	if min.Major != Latest.Major || !Latest.AtLeast(min) {
+		return nil, fmt.Errorf("%s must be between %d.0 and %s, got %s", MinVersionProp, Latest.Major, Latest, min)
+	}
+
+	return &Middleware{
+		Min:    min,
+		Strict: viper.GetBool(StrictProp),
+		Logger: logger.Session("api-version"),
+	}, nil
+}
+
+// Wrap rejects requests with a missing or unsupported version with 412
+// Precondition Failed, as the OSB spec requires, and adds the version to the
+// context of the others. Requests for asynchronous bindings from versions
+// without them are handled synchronously unless the middleware is strict. It
+// must be added to the router the OSB API routes are attached to so the
+// endpoint can be identified.
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		v, err := m.negotiate(req)
+		if err != nil {
+			m.Logger.Info("rejected", lager.Data{"version": req.Header.Get(Header), "path": req.URL.Path, "error": err.Error()})
+			reject(w, err)
+			return
+		}
+
+		m.record(v)
+		next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), v)))
+	})
+}
+
+func (m *Middleware) negotiate(req *http.Request) (Version, error) {
+	header := req.Header.Get(Header)
+	if header == "" {
+		return Version{}, fmt.Errorf("the %s header is required", Header)
+	}
+
+	v, err := Parse(header)
+	if err != nil {
+		return Version{}, fmt.Errorf("the %s header must contain a version: %v", Header, err)
+	}
+
+	switch {
+	case v.Major != m.Min.Major:
+		return v, fmt.Errorf("OSB API version %s isn't supported, the broker requires %d.x", v, m.Min.Major)
+	case !v.AtLeast(m.Min):
+		return v, fmt.Errorf("OSB API version %s isn't supported, the broker requires %s or later", v, m.Min)
+	case m.Strict && !Latest.AtLeast(v):
+		return v, fmt.Errorf("OSB API version %s isn't supported, the broker implements up to %s", v, Latest)
+	}
+
+	endpoint := ratelimit.Endpoint(req)
+	if fetchEndpoints[endpoint] && !v.AtLeast(FetchEndpoints) {
+		return v, fmt.Errorf("the %s endpoint requires OSB API version %s or later", endpoint, FetchEndpoints)
+	}
+
+	async := req.URL.Query().Get("accepts_incomplete") == "true"
+	if async && (endpoint == "bind" || endpoint == "unbind") && !v.AtLeast(AsyncBindings) {
+		if m.Strict {
+			return v, fmt.Errorf("asynchronous bindings require OSB API version %s or later", AsyncBindings)
+		}
+
+		// the broker binds synchronously so the platform gets what it
+		// expects from its version
+		query := req.URL.Query()
+		query.Del("accepts_incomplete")
+		req.URL.RawQuery = query.Encode()
+	}
+
+	return v, nil
+}
+
+// record counts requests by version and logs versions the first time
+// they're seen so operators know which platforms call the broker.
+func (m *Middleware) record(v Version) {
+	metrics.Add(v.String(), 1)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.seen == nil {
+		m.seen = make(map[string]bool)
+	}
+	if !m.seen[v.String()] {
+		m.seen[v.String()] = true
+		m.Logger.Info("new-version", lager.Data{"version": v.String()})
+	}
+}
+
+func reject(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPreconditionFailed)
+	json.NewEncoder(w).Encode(map[string]string{"description": err.Error()})
+}
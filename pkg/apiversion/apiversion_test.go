@@ -0,0 +1,233 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiversion
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]struct {
+		Value     string
+		Expected  Version
+		ExpectErr bool
+	}{
+		"version":  {Value: "2.14", Expected: Version{Major: 2, Minor: 14}},
+		"major":    {Value: "2", ExpectErr: true},
+		"garbage":  {Value: "latest", ExpectErr: true},
+		"empty":    {Value: "", ExpectErr: true},
+		"negative": {Value: "2.-1", ExpectErr: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := Parse(tc.Value)
+			if (err != nil) != tc.ExpectErr {
+				t.Fatalf("expected error: %v, got: %v", tc.ExpectErr, err)
+			}
+
+			if actual != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Wrap(t *testing.T) {
+	cases := map[string]struct {
+		Strict          bool
+		Method          string
+		Path            string
+		Version         string
+		ExpectedStatus  int
+		ExpectedVersion string
+		ExpectedAsync   string
+	}{
+		"supported": {
+			Method:          http.MethodGet,
+			Path:            "/v2/catalog",
+			Version:         "2.14",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedVersion: "2.14",
+		},
+		"missing": {
+			Method:         http.MethodGet,
+			Path:           "/v2/catalog",
+			ExpectedStatus: http.StatusPreconditionFailed,
+		},
+		"malformed": {
+			Method:         http.MethodGet,
+			Path:           "/v2/catalog",
+			Version:        "two",
+			ExpectedStatus: http.StatusPreconditionFailed,
+		},
+		"other major": {
+			Method:         http.MethodGet,
+			Path:           "/v2/catalog",
+			Version:        "3.0",
+			ExpectedStatus: http.StatusPreconditionFailed,
+		},
+		"too old": {
+			Method:         http.MethodGet,
+			Path:           "/v2/catalog",
+			Version:        "2.12",
+			ExpectedStatus: http.StatusPreconditionFailed,
+		},
+		"newer": {
+			Method:          http.MethodGet,
+			Path:            "/v2/catalog",
+			Version:         "2.16",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedVersion: "2.16",
+		},
+		"newer strict": {
+			Strict:         true,
+			Method:         http.MethodGet,
+			Path:           "/v2/catalog",
+			Version:        "2.16",
+			ExpectedStatus: http.StatusPreconditionFailed,
+		},
+		"fetch endpoint": {
+			Method:          http.MethodGet,
+			Path:            "/v2/service_instances/abc",
+			Version:         "2.14",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedVersion: "2.14",
+		},
+		"fetch endpoint too old": {
+			Method:         http.MethodGet,
+			Path:           "/v2/service_instances/abc",
+			Version:        "2.13",
+			ExpectedStatus: http.StatusPreconditionFailed,
+		},
+		"async bind": {
+			Method:          http.MethodPut,
+			Path:            "/v2/service_instances/abc/service_bindings/def?accepts_incomplete=true",
+			Version:         "2.14",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedVersion: "2.14",
+			ExpectedAsync:   "true",
+		},
+		"async bind too old": {
+			Method:          http.MethodPut,
+			Path:            "/v2/service_instances/abc/service_bindings/def?accepts_incomplete=true",
+			Version:         "2.13",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedVersion: "2.13",
+		},
+		"async bind too old strict": {
+			Strict:         true,
+			Method:         http.MethodPut,
+			Path:           "/v2/service_instances/abc/service_bindings/def?accepts_incomplete=true",
+			Version:        "2.13",
+			ExpectedStatus: http.StatusPreconditionFailed,
+		},
+		"async provision": {
+			Method:          http.MethodPut,
+			Path:            "/v2/service_instances/abc?accepts_incomplete=true",
+			Version:         "2.13",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedVersion: "2.13",
+			ExpectedAsync:   "true",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			var version, async string
+			handler := func(w http.ResponseWriter, req *http.Request) {
+				if v, ok := FromContext(req.Context()); ok {
+					version = v.String()
+				}
+				async = req.URL.Query().Get("accepts_incomplete")
+			}
+
+			m := &Middleware{Min: Version{Major: 2, Minor: 13}, Strict: tc.Strict, Logger: lager.NewLogger("test")}
+			router := mux.NewRouter()
+			router.HandleFunc("/v2/catalog", handler).Methods(http.MethodGet)
+			router.HandleFunc("/v2/service_instances/{instance_id}", handler).Methods(http.MethodGet, http.MethodPut)
+			router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", handler).Methods(http.MethodPut)
+			router.Use(m.Wrap)
+
+			req := httptest.NewRequest(tc.Method, tc.Path, nil)
+			if tc.Version != "" {
+				req.Header.Set(Header, tc.Version)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.ExpectedStatus, w.Code, w.Body.String())
+			}
+
+			if version != tc.ExpectedVersion {
+				t.Errorf("expected version %q, got %q", tc.ExpectedVersion, version)
+			}
+
+			if async != tc.ExpectedAsync {
+				t.Errorf("expected accepts_incomplete %q, got %q", tc.ExpectedAsync, async)
+			}
+		})
+	}
+}
+
+func TestSupports(t *testing.T) {
+	if !Supports(context.Background(), AsyncBindings) {
+		t.Error("expected every feature to be supported outside requests")
+	}
+
+	ctx := NewContext(context.Background(), Version{Major: 2, Minor: 13})
+	if Supports(ctx, AsyncBindings) {
+		t.Error("expected 2.13 not to support asynchronous bindings")
+	}
+}
+
+func TestNewMiddlewareFromEnv(t *testing.T) {
+	defer viper.Set(MinVersionProp, nil)
+
+	cases := map[string]struct {
+		Min       string
+		ExpectErr bool
+	}{
+		"default":   {},
+		"older":     {Min: "2.10"},
+		"malformed": {Min: "latest", ExpectErr: true},
+		"too new":   {Min: "2.99", ExpectErr: true},
+		"v1":        {Min: "1.0", ExpectErr: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if tc.Min == "" {
+				viper.Set(MinVersionProp, nil)
+			} else {
+				viper.Set(MinVersionProp, tc.Min)
+			}
+
+			_, err := NewMiddlewareFromEnv(lager.NewLogger("test"))
+			if (err != nil) != tc.ExpectErr {
+				t.Errorf("expected error: %v, got: %v", tc.ExpectErr, err)
+			}
+		})
+	}
+}
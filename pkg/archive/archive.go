@@ -0,0 +1,341 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive moves old operation and upgrade history out of the broker's
+// database into compressed objects in blob storage, keeping an index of the
+// objects in the database so the history can still be found.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+	"github.com/pivotal/cloud-service-broker/pkg/upgrade"
+	"github.com/spf13/viper"
+)
+
+const (
+	// BucketProp is the viper key of the Cloud Storage bucket archives are
+	// written to. Archiving is disabled if it's empty.
+	BucketProp = "archive.bucket"
+
+	// PrefixProp is the viper key of the path archives are written under in
+	// the bucket.
+	PrefixProp = "archive.prefix"
+
+	// MaxAgeProp is the viper key of how long after their last update rows
+	// are archived.
+	MaxAgeProp = "archive.max_age"
+
+	// BatchSizeProp is the viper key of the maximum number of rows in each
+	// archive.
+	BatchSizeProp = "archive.batch_size"
+
+	// IntervalProp is the viper key of how often a running broker archives
+	// rows. Zero disables archiving in the broker so it can be run as a task.
+	IntervalProp = "archive.interval"
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: BucketProp, Kind: config.String},
+		config.Property{Key: PrefixProp, Kind: config.String, Default: "cloud-service-broker/archives"},
+		config.Property{Key: MaxAgeProp, Kind: config.Duration, Default: "2160h"},
+		config.Property{Key: BatchSizeProp, Kind: config.Integer, Default: 1000},
+		config.Property{Key: IntervalProp, Kind: config.Duration, Default: "24h"},
+	)
+}
+
+// Source is a table whose rows are archived. Rows are only archived if they
+// match the condition, if it's set.
+type Source struct {
+	Table     string
+	Condition string
+	Args      []interface{}
+}
+
+// DefaultSources are the tables the broker archives: operations on the
+// legacy Google brokers and completed instance upgrades, which record
+// operator approvals.
+var DefaultSources = []Source{
+	{Table: "cloud_operations"},
+	{Table: "instance_upgrades", Condition: "state = ?", Args: []interface{}{upgrade.StateCompleted}},
+}
+
+// Database reads rows to archive and indexes archives.
+type Database interface {
+	ListArchivableRows(ctx context.Context, table string, before time.Time, condition string, args []interface{}, limit int) ([]db_service.ArchivableRow, error)
+	CountArchivableRows(ctx context.Context, table string, before time.Time, condition string, args []interface{}) (int, error)
+	CreateArchive(ctx context.Context, archive *models.Archive, ids []uint) error
+}
+
+// databaseStore uses the broker's database.
+type databaseStore struct{}
+
+func (databaseStore) ListArchivableRows(ctx context.Context, table string, before time.Time, condition string, args []interface{}, limit int) ([]db_service.ArchivableRow, error) {
+	return db_service.ListArchivableRows(ctx, table, before, condition, args, limit)
+}
+
+func (databaseStore) CountArchivableRows(ctx context.Context, table string, before time.Time, condition string, args []interface{}) (int, error) {
+	return db_service.CountArchivableRows(ctx, table, before, condition, args)
+}
+
+func (databaseStore) CreateArchive(ctx context.Context, archive *models.Archive, ids []uint) error {
+	return db_service.CreateArchive(ctx, archive, ids)
+}
+
+// BlobStore writes archives to blob storage.
+type BlobStore interface {
+	// Put writes the object with the given name and returns its URL.
+	Put(ctx context.Context, name string, data []byte) (string, error)
+}
+
+// Archiver moves rows older than MaxAge from the database to the BlobStore in
+// batches. Each batch is written as a gzipped JSON lines object, recorded in
+// the archives table and deleted from the database.
+//
+// Batches are written before they're deleted, so if the broker stops in
+// between, the next run writes the rows again to a new object rather than
+// losing them.
+type Archiver struct {
+	Sources   []Source
+	Database  Database
+	Store     BlobStore
+	MaxAge    time.Duration
+	BatchSize int
+	Logger    lager.Logger
+
+	// LeaderContext, if set, is called before each run of RunEvery so only
+	// one broker instance runs at a time. Runs are skipped while another
+	// instance leads and stop if this one stops leading.
+	LeaderContext func(ctx context.Context) (context.Context, context.CancelFunc, bool)
+
+	// now is used to get the current time, it can be replaced in tests.
+	now func() time.Time
+}
+
+// NewArchiverFromEnv creates an Archiver for the default sources that writes
+// to the Cloud Storage bucket configured in viper. It returns nil if no bucket
+// is configured.
+func NewArchiverFromEnv(ctx context.Context, logger lager.Logger) (*Archiver, error) {
+	bucket := viper.GetString(BucketProp)
+	if bucket == "" {
+		return nil, nil
+	}
+
+	maxAge, err := time.ParseDuration(viper.GetString(MaxAgeProp))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", MaxAgeProp, err)
+	}
+	if maxAge <= 0 {
+		return nil, fmt.Errorf("%s must be positive, got %s", MaxAgeProp, maxAge)
+	}
+
+	batchSize := viper.GetInt(BatchSizeProp)
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("%s must be positive, got %d", BatchSizeProp, batchSize)
+	}
+
+	store, err := NewGcsStore(ctx, bucket, viper.GetString(PrefixProp))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Archiver{
+		Sources:   DefaultSources,
+		Database:  databaseStore{},
+		Store:     store,
+		MaxAge:    maxAge,
+		BatchSize: batchSize,
+		Logger:    logger.Session("archive"),
+	}, nil
+}
+
+func (a *Archiver) currentTime() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+
+	return time.Now()
+}
+
+// Run archives every row older than MaxAge and returns the archives it
+// created. It stops at the first error. If the context is a dry run nothing is
+// archived, use Plan to see what would be.
+func (a *Archiver) Run(ctx context.Context) ([]models.Archive, error) {
+	var created []models.Archive
+	changes, err := a.plan(ctx, &created)
+	if err != nil {
+		return nil, err
+	}
+
+	return created, changes.Execute(ctx).Err()
+}
+
+// Plan lists how many rows of each source would be archived.
+func (a *Archiver) Plan(ctx context.Context) (*planning.Plan, error) {
+	var created []models.Archive
+	return a.plan(ctx, &created)
+}
+
+// plan creates a change for each source with rows to archive, applying them
+// adds the archives they create to created.
+func (a *Archiver) plan(ctx context.Context, created *[]models.Archive) (*planning.Plan, error) {
+	now := a.currentTime()
+	cutoff := now.Add(-a.MaxAge)
+
+	changes := &planning.Plan{StopOnError: true}
+	for _, source := range a.Sources {
+		source := source
+		count, err := a.Database.CountArchivableRows(ctx, source.Table, cutoff, source.Condition, source.Args)
+		if err != nil {
+			return nil, fmt.Errorf("counting %s: %v", source.Table, err)
+		}
+		if count == 0 {
+			continue
+		}
+
+		details := map[string]interface{}{
+			"records":        count,
+			"updated_before": cutoff,
+		}
+		changes.Add("archive-rows", source.Table, details, func(ctx context.Context) error {
+			for {
+				// stop between batches if another instance took over
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+
+				archive, err := a.archiveBatch(ctx, source, cutoff, now)
+				if err != nil {
+					return err
+				}
+				if archive == nil {
+					return nil
+				}
+
+				a.Logger.Info("archived", lager.Data{"table": archive.SourceTable, "records": archive.Records, "location": archive.Location})
+				*created = append(*created, *archive)
+			}
+		})
+	}
+
+	return changes, nil
+}
+
+// archiveBatch archives the oldest batch of rows from the source, it returns
+// nil if there are none left.
+func (a *Archiver) archiveBatch(ctx context.Context, source Source, cutoff, now time.Time) (*models.Archive, error) {
+	rows, err := a.Database.ListArchivableRows(ctx, source.Table, cutoff, source.Condition, source.Args, a.BatchSize)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	archive := &models.Archive{SourceTable: source.Table, Records: len(rows)}
+	ids := make([]uint, len(rows))
+
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	encoder := json.NewEncoder(zw)
+	for i, row := range rows {
+		if ids[i], err = rowId(row); err != nil {
+			return nil, err
+		}
+
+		if updated, ok := row["updated_at"].(time.Time); ok {
+			if archive.OldestRecord.IsZero() || updated.Before(archive.OldestRecord) {
+				archive.OldestRecord = updated
+			}
+			if updated.After(archive.NewestRecord) {
+				archive.NewestRecord = updated
+			}
+		}
+
+		if err := encoder.Encode(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	// rows are listed in ID order
+	archive.FirstRecordId = ids[0]
+	archive.LastRecordId = ids[len(ids)-1]
+
+	name := fmt.Sprintf("%s/%s/%s-%d-%d.jsonl.gz", source.Table, now.UTC().Format("2006/01/02"), source.Table, archive.FirstRecordId, archive.LastRecordId)
+	if archive.Location, err = a.Store.Put(ctx, name, buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	if err := a.Database.CreateArchive(ctx, archive, ids); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// RunEvery runs the archiver every interval until the context is done. Runs
+// are skipped while another instance leads if LeaderContext is set.
+func (a *Archiver) RunEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runCtx, cancel, leads := ctx, context.CancelFunc(func() {}), true
+			if a.LeaderContext != nil {
+				runCtx, cancel, leads = a.LeaderContext(ctx)
+			}
+			if !leads {
+				cancel()
+				a.Logger.Debug("skipping-run", lager.Data{"reason": "another instance leads archiving"})
+				continue
+			}
+
+			if _, err := a.Run(runCtx); err != nil {
+				a.Logger.Error("archiving", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// rowId gets the ID of a row, drivers return it with different types.
+func rowId(row db_service.ArchivableRow) (uint, error) {
+	switch id := row["id"].(type) {
+	case int64:
+		return uint(id), nil
+	case uint64:
+		return uint(id), nil
+	case string:
+		parsed, err := strconv.ParseUint(id, 10, 64)
+		return uint(parsed), err
+	default:
+		return 0, fmt.Errorf("row has no usable id: %v", row["id"])
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+	"github.com/spf13/viper"
+)
+
+type fakeDatabase struct {
+	Rows     map[string][]db_service.ArchivableRow
+	Archives []models.Archive
+	Before   time.Time
+}
+
+func (f *fakeDatabase) ListArchivableRows(ctx context.Context, table string, before time.Time, condition string, args []interface{}, limit int) ([]db_service.ArchivableRow, error) {
+	f.Before = before
+	rows := f.Rows[table]
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+func (f *fakeDatabase) CountArchivableRows(ctx context.Context, table string, before time.Time, condition string, args []interface{}) (int, error) {
+	return len(f.Rows[table]), nil
+}
+
+func (f *fakeDatabase) CreateArchive(ctx context.Context, archive *models.Archive, ids []uint) error {
+	f.Archives = append(f.Archives, *archive)
+	f.Rows[archive.SourceTable] = f.Rows[archive.SourceTable][len(ids):]
+	return nil
+}
+
+type fakeBlobStore struct {
+	Objects map[string][]byte
+	Err     error
+}
+
+func (f *fakeBlobStore) Put(ctx context.Context, name string, data []byte) (string, error) {
+	if f.Err != nil {
+		return "", f.Err
+	}
+
+	f.Objects[name] = data
+	return "mem://" + name, nil
+}
+
+func readObject(t *testing.T, data []byte) []map[string]interface{} {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []map[string]interface{}
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		row := make(map[string]interface{})
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func TestArchiver_Run(t *testing.T) {
+	now := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	oldest := now.Add(-100 * 24 * time.Hour)
+
+	db := &fakeDatabase{Rows: map[string][]db_service.ArchivableRow{
+		"cloud_operations": {
+			{"id": int64(1), "name": "op-1", "updated_at": oldest},
+			{"id": int64(2), "name": "op-2", "updated_at": oldest.Add(time.Hour)},
+			{"id": "3", "name": "op-3", "updated_at": oldest.Add(2 * time.Hour)},
+		},
+	}}
+	store := &fakeBlobStore{Objects: make(map[string][]byte)}
+
+	archiver := &Archiver{
+		Sources:   DefaultSources,
+		Database:  db,
+		Store:     store,
+		MaxAge:    90 * 24 * time.Hour,
+		BatchSize: 2,
+		Logger:    lager.NewLogger("test"),
+		now:       func() time.Time { return now },
+	}
+
+	created, err := archiver.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := now.Add(-90 * 24 * time.Hour); !db.Before.Equal(expected) {
+		t.Errorf("Expected rows updated before %v to be archived, got %v", expected, db.Before)
+	}
+
+	expected := []models.Archive{
+		{
+			SourceTable:   "cloud_operations",
+			Location:      "mem://cloud_operations/2020/04/01/cloud_operations-1-2.jsonl.gz",
+			Records:       2,
+			FirstRecordId: 1,
+			LastRecordId:  2,
+			OldestRecord:  oldest,
+			NewestRecord:  oldest.Add(time.Hour),
+		},
+		{
+			SourceTable:   "cloud_operations",
+			Location:      "mem://cloud_operations/2020/04/01/cloud_operations-3-3.jsonl.gz",
+			Records:       1,
+			FirstRecordId: 3,
+			LastRecordId:  3,
+			OldestRecord:  oldest.Add(2 * time.Hour),
+			NewestRecord:  oldest.Add(2 * time.Hour),
+		},
+	}
+	if !reflect.DeepEqual(created, expected) {
+		t.Errorf("Expected archives %v, got %v", expected, created)
+	}
+	if !reflect.DeepEqual(db.Archives, expected) {
+		t.Errorf("Expected indexed archives %v, got %v", expected, db.Archives)
+	}
+
+	rows := readObject(t, store.Objects["cloud_operations/2020/04/01/cloud_operations-1-2.jsonl.gz"])
+	if len(rows) != 2 || rows[0]["name"] != "op-1" || rows[1]["name"] != "op-2" {
+		t.Errorf("Expected the first object to hold op-1 and op-2, got %v", rows)
+	}
+}
+
+func TestArchiver_Run_storeFailure(t *testing.T) {
+	db := &fakeDatabase{Rows: map[string][]db_service.ArchivableRow{
+		"cloud_operations": {{"id": int64(1)}},
+	}}
+
+	archiver := &Archiver{
+		Sources:   DefaultSources,
+		Database:  db,
+		Store:     &fakeBlobStore{Err: errors.New("bucket unavailable")},
+		MaxAge:    time.Hour,
+		BatchSize: 10,
+		Logger:    lager.NewLogger("test"),
+	}
+
+	if _, err := archiver.Run(context.Background()); err == nil {
+		t.Fatal("Expected an error when the store fails")
+	}
+
+	if len(db.Archives) != 0 || len(db.Rows["cloud_operations"]) != 1 {
+		t.Errorf("Expected rows to be kept when the store fails, got archives %v rows %v", db.Archives, db.Rows)
+	}
+}
+
+func TestArchiver_Run_dryRun(t *testing.T) {
+	db := &fakeDatabase{Rows: map[string][]db_service.ArchivableRow{
+		"cloud_operations": {{"id": int64(1)}, {"id": int64(2)}},
+	}}
+	store := &fakeBlobStore{Objects: make(map[string][]byte)}
+
+	archiver := &Archiver{
+		Sources:   DefaultSources,
+		Database:  db,
+		Store:     store,
+		MaxAge:    time.Hour,
+		BatchSize: 1,
+		Logger:    lager.NewLogger("test"),
+	}
+
+	ctx := planning.WithDryRun(context.Background())
+	created, err := archiver.Run(ctx)
+	if err != nil || len(created) != 0 {
+		t.Fatalf("Expected a dry run to archive nothing, got %v, %v", created, err)
+	}
+	if len(db.Archives) != 0 || len(db.Rows["cloud_operations"]) != 2 || len(store.Objects) != 0 {
+		t.Errorf("Expected a dry run to keep the rows, got archives %v rows %v objects %v", db.Archives, db.Rows, store.Objects)
+	}
+
+	changes, err := archiver.Plan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planned := changes.Changes()
+	if len(planned) != 1 || planned[0].Target != "cloud_operations" || planned[0].Details["records"] != 2 {
+		t.Errorf("Expected 2 cloud_operations rows to be planned, got %v", planned)
+	}
+}
+
+func TestNewArchiverFromEnv(t *testing.T) {
+	archiver, err := NewArchiverFromEnv(context.Background(), lager.NewLogger("test"))
+	if err != nil || archiver != nil {
+		t.Errorf("Expected archiving to be disabled without a bucket, got %v, %v", archiver, err)
+	}
+
+	cases := map[string]struct {
+		Prop  string
+		Value interface{}
+	}{
+		"bad max age":    {Prop: MaxAgeProp, Value: "ninety days"},
+		"zero max age":   {Prop: MaxAgeProp, Value: "0s"},
+		"bad batch size": {Prop: BatchSizeProp, Value: 0},
+	}
+
+	viper.Set(BucketProp, "archives")
+	defer viper.Set(BucketProp, nil)
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(tc.Prop, tc.Value)
+			defer viper.Set(tc.Prop, nil)
+
+			if _, err := NewArchiverFromEnv(context.Background(), lager.NewLogger("test")); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestArchiver_RunEvery_leader(t *testing.T) {
+	cases := map[string]struct {
+		LeaderContext  func(ctx context.Context) (context.Context, context.CancelFunc, bool)
+		ExpectArchives bool
+	}{
+		"no election": {LeaderContext: nil, ExpectArchives: true},
+		"leader": {
+			LeaderContext: func(ctx context.Context) (context.Context, context.CancelFunc, bool) {
+				ctx, cancel := context.WithCancel(ctx)
+				return ctx, cancel, true
+			},
+			ExpectArchives: true,
+		},
+		"follower": {
+			LeaderContext: func(ctx context.Context) (context.Context, context.CancelFunc, bool) {
+				ctx, cancel := context.WithCancel(ctx)
+				cancel()
+				return ctx, cancel, false
+			},
+			ExpectArchives: false,
+		},
+		"lost lease": {
+			LeaderContext: func(ctx context.Context) (context.Context, context.CancelFunc, bool) {
+				ctx, cancel := context.WithCancel(ctx)
+				cancel()
+				return ctx, cancel, true
+			},
+			ExpectArchives: false,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			db := &fakeDatabase{Rows: map[string][]db_service.ArchivableRow{
+				"cloud_operations": {{"id": int64(1), "name": "op-1", "updated_at": time.Time{}}},
+			}}
+			archiver := &Archiver{
+				Sources:       DefaultSources,
+				Database:      db,
+				Store:         &fakeBlobStore{Objects: make(map[string][]byte)},
+				MaxAge:        time.Hour,
+				BatchSize:     10,
+				Logger:        lager.NewLogger("test"),
+				LeaderContext: tc.LeaderContext,
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			archiver.RunEvery(ctx, time.Millisecond)
+
+			if archived := len(db.Archives) > 0; archived != tc.ExpectArchives {
+				t.Errorf("Expected archived to be %v, got %v", tc.ExpectArchives, archived)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"cloud.google.com/go/storage"
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
+)
+
+// GcsStore writes archives to a Cloud Storage bucket.
+type GcsStore struct {
+	Bucket string
+	Prefix string
+	Client *storage.Client
+}
+
+var _ BlobStore = (*GcsStore)(nil)
+
+// NewGcsStore creates a GcsStore using the broker's credentials.
+func NewGcsStore(ctx context.Context, bucket, prefix string) (*GcsStore, error) {
+	clients, err := gcpclient.NewFactoryFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load credentials for archive bucket: %v", err)
+	}
+
+	client, err := storage.NewClient(ctx, clients.Options(ctx, "storage")...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GcsStore{Bucket: bucket, Prefix: prefix, Client: client}, nil
+}
+
+// Put implements BlobStore. Existing objects are never overwritten.
+func (s *GcsStore) Put(ctx context.Context, name string, data []byte) (string, error) {
+	objectName := path.Join(s.Prefix, name)
+	w := s.Client.Bucket(s.Bucket).Object(objectName).If(storage.Conditions{DoesNotExist: true}).NewWriter(ctx)
+	w.ContentType = "application/gzip"
+
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("gs://%s/%s", s.Bucket, objectName), nil
+}
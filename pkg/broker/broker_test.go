@@ -23,6 +23,7 @@ import (
 	"os"
 
 	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/projects"
 	"github.com/pivotal/cloud-service-broker/pkg/validation"
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
 	"github.com/pivotal-cf/brokerapi"
@@ -408,14 +409,21 @@ func TestServiceDefinition_ProvisionVariables(t *testing.T) {
 				"maybe-missing": "default",
 			},
 		},
-		"operator defaults are not evaluated": {
+		"operator defaults can't reference user variables": {
 			ServiceProperties: map[string]interface{}{},        // 2
 			UserParams:        `{"location":"us"}`,             // 4
 			DefaultOverride:   `{"name":"foo-${location}"}`,    // 5
 			GlobalDefaults:    "{}",
+			ExpectedError:     errors.New(`couldn't evaluate the default for "name", template: "foo-${location}", 1:7: unknown variable accessed: location`),
+		},
+		"operator defaults are evaluated against the instance": {
+			ServiceProperties: map[string]interface{}{},        // 2
+			UserParams:        `{"location":"us"}`,             // 4
+			DefaultOverride:   `{"name":"foo-${instance_id_short}"}`, // 5
+			GlobalDefaults:    "{}",
 			ExpectedContext: map[string]interface{}{
 				"location":      "us",
-				"name":          "foo-${location}",
+				"name":          "foo-instance",
 				"maybe-missing": "default",
 			},
 		},
@@ -485,6 +493,74 @@ func TestServiceDefinition_ProvisionVariables(t *testing.T) {
 	}
 }
 
+func TestServiceDefinition_ProvisionVariablesProject(t *testing.T) {
+	service := ServiceDefinition{
+		Id:   "00000000-0000-0000-0000-000000000000",
+		Name: "left-handed-smoke-sifter",
+		ProvisionInputVariables: []BrokerVariable{
+			{
+				FieldName: "project",
+				Type:      JsonTypeString,
+				Default:   "${request.default_project}",
+			},
+			{
+				FieldName: "credentials",
+				Type:      JsonTypeString,
+				Default:   "${gcp.project_credentials(project)}",
+			},
+		},
+	}
+
+	viper.Set(projects.DefaultProjectProp, "default-project")
+	viper.Set(projects.DefaultCredentialsProp, "default-creds")
+	viper.Set(projects.ProjectsProp, `{"team-a":{"credentials":"team-a-creds"},"team-b":{}}`)
+	viper.Set(projects.MappingProp, `{"organizations":{"org-a":"team-a"}}`)
+	defer viper.Reset()
+
+	cases := map[string]struct {
+		OrgGuid            string
+		UserParams         string
+		ProvisionOverrides map[string]interface{}
+		ExpectedContext    map[string]interface{}
+	}{
+		"default project": {
+			OrgGuid:         "org-z",
+			ExpectedContext: map[string]interface{}{"project": "default-project", "credentials": "default-creds"},
+		},
+		"org mapping": {
+			OrgGuid:         "org-a",
+			ExpectedContext: map[string]interface{}{"project": "team-a", "credentials": "team-a-creds"},
+		},
+		"user selected": {
+			OrgGuid:         "org-a",
+			UserParams:      `{"project":"team-b"}`,
+			ExpectedContext: map[string]interface{}{"project": "team-b", "credentials": "default-creds"},
+		},
+		"plan selected": {
+			OrgGuid:            "org-z",
+			UserParams:         `{"project":"team-b"}`,
+			ProvisionOverrides: map[string]interface{}{"project": "team-a"},
+			ExpectedContext:    map[string]interface{}{"project": "team-a", "credentials": "team-a-creds"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			details := brokerapi.ProvisionDetails{OrganizationGUID: tc.OrgGuid, RawParameters: json.RawMessage(tc.UserParams)}
+			plan := ServicePlan{ProvisionOverrides: tc.ProvisionOverrides}
+
+			vars, err := service.ProvisionVariables("instance-id-here", details, plan)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(vars.ToMap(), tc.ExpectedContext) {
+				t.Errorf("Expected context: %v got %v", tc.ExpectedContext, vars.ToMap())
+			}
+		})
+	}
+}
+
 func TestServiceDefinition_UpdateVariables(t *testing.T) {
 	service := ServiceDefinition{
 		Id:   "00000000-0000-0000-0000-000000000000",
@@ -600,14 +676,21 @@ func TestServiceDefinition_UpdateVariables(t *testing.T) {
 				"maybe-missing": "default",
 			},
 		},
-		"operator defaults are not evaluated": {
+		"operator defaults can't reference user variables": {
 			ServiceProperties: map[string]interface{}{},        // 2
 			UserParams:        `{"location":"us"}`,             // 4
 			DefaultOverride:   `{"name":"foo-${location}"}`,    // 5
 			GlobalDefaults:    "{}",
+			ExpectedError:     errors.New(`couldn't evaluate the default for "name", template: "foo-${location}", 1:7: unknown variable accessed: location`),
+		},
+		"operator defaults are evaluated against the instance": {
+			ServiceProperties: map[string]interface{}{},        // 2
+			UserParams:        `{"location":"us"}`,             // 4
+			DefaultOverride:   `{"name":"foo-${instance_id_short}"}`, // 5
+			GlobalDefaults:    "{}",
 			ExpectedContext: map[string]interface{}{
 				"location":      "us",
-				"name":          "foo-${location}",
+				"name":          "foo-instance",
 				"maybe-missing": "default",
 			},
 		},
@@ -863,6 +946,39 @@ func TestServiceDefinition_BindVariables(t *testing.T) {
 	}
 }
 
+func TestServiceDefinition_BindVariablesKeylessPrincipal(t *testing.T) {
+	service := ServiceDefinition{
+		Id:    "00000000-0000-0000-0000-000000000000",
+		Name:  "left-handed-smoke-sifter",
+		Plans: []ServicePlan{{ServicePlan: brokerapi.ServicePlan{ID: "builtin-plan", Name: "Builtin!"}}},
+		BindComputedVariables: []varcontext.DefaultVariable{
+			{Name: "keyless_principal", Default: "${request.keyless_principal}", Overwrite: true},
+		},
+	}
+
+	cases := map[string]string{
+		"keys":    "",
+		"keyless": "serviceAccount:my-project.svc.id.goog[ns/ksa]",
+	}
+
+	for tn, principal := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(KeylessPrincipalProp, principal)
+			defer viper.Reset()
+
+			instance := models.ServiceInstanceDetails{OtherDetails: "{}"}
+			vars, err := service.BindVariables(instance, "binding-id-here", brokerapi.BindDetails{}, &service.Plans[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual := vars.GetString("keyless_principal"); actual != principal {
+				t.Errorf("Expected keyless principal %q, got %q", principal, actual)
+			}
+		})
+	}
+}
+
 func TestServiceDefinition_createSchemas(t *testing.T) {
 	service := ServiceDefinition{
 		Id:   "00000000-0000-0000-0000-000000000000",
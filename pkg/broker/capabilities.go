@@ -0,0 +1,105 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"code.cloudfoundry.org/lager"
+)
+
+// CapabilitiesMetadataKey is the key of the capabilities in service and plan
+// catalog metadata.
+const CapabilitiesMetadataKey = "capabilities"
+
+// Capabilities describes the operations a service or plan supports so UIs
+// can enable or disable actions rather than guessing.
+type Capabilities struct {
+	Update           bool     `json:"update"`
+	PlanUpdate       bool     `json:"plan_update"`
+	UpdatableFields  []string `json:"updatable_fields"`
+	Bind             bool     `json:"bind"`
+	AsyncProvision   bool     `json:"async_provision"`
+	AsyncDeprovision bool     `json:"async_deprovision"`
+	AsyncBind        bool     `json:"async_bind"`
+	Import           bool     `json:"import"`
+	Snapshots        bool     `json:"snapshots"`
+}
+
+// CapabilityReporter is implemented by ServiceProviders whose capabilities
+// differ from what the ServiceProvider interface and service definition
+// imply, e.g. because they don't support updates or some plans import
+// existing resources.
+type CapabilityReporter interface {
+	// PlanCapabilities adjusts the capabilities derived from the service
+	// definition for the given plan.
+	PlanCapabilities(plan ServicePlan, derived Capabilities) Capabilities
+}
+
+// PlanCapabilities returns the capabilities of the plan. They're derived from
+// the service definition and its provider, which can adjust them by
+// implementing CapabilityReporter.
+func (svc *ServiceDefinition) PlanCapabilities(plan ServicePlan) Capabilities {
+	capabilities := Capabilities{
+		Update:          true,
+		PlanUpdate:      svc.PlanUpdateable,
+		UpdatableFields: []string{},
+		Bind:            svc.Bindable,
+	}
+
+	for _, input := range svc.ProvisionInputVariables {
+		if !input.ProhibitUpdate {
+			capabilities.UpdatableFields = append(capabilities.UpdatableFields, input.FieldName)
+		}
+	}
+
+	if svc.ProviderBuilder == nil {
+		return capabilities
+	}
+
+	provider := svc.ProviderBuilder(lager.NewLogger("capabilities"), nil)
+	capabilities.AsyncProvision = provider.ProvisionsAsync()
+	capabilities.AsyncDeprovision = provider.DeprovisionsAsync()
+
+	if reporter, ok := provider.(CapabilityReporter); ok {
+		capabilities = reporter.PlanCapabilities(plan, capabilities)
+	}
+
+	return capabilities
+}
+
+// ServiceCapabilities combines the capabilities of the plans, a capability is
+// supported by the service if any plan supports it.
+func ServiceCapabilities(plans []Capabilities) Capabilities {
+	combined := Capabilities{UpdatableFields: []string{}}
+	seen := make(map[string]bool)
+	for _, plan := range plans {
+		combined.Update = combined.Update || plan.Update
+		combined.PlanUpdate = combined.PlanUpdate || plan.PlanUpdate
+		combined.Bind = combined.Bind || plan.Bind
+		combined.AsyncProvision = combined.AsyncProvision || plan.AsyncProvision
+		combined.AsyncDeprovision = combined.AsyncDeprovision || plan.AsyncDeprovision
+		combined.AsyncBind = combined.AsyncBind || plan.AsyncBind
+		combined.Import = combined.Import || plan.Import
+		combined.Snapshots = combined.Snapshots || plan.Snapshots
+
+		for _, field := range plan.UpdatableFields {
+			if !seen[field] {
+				seen[field] = true
+				combined.UpdatableFields = append(combined.UpdatableFields, field)
+			}
+		}
+	}
+
+	return combined
+}
@@ -0,0 +1,130 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// capabilityProvider stubs the parts of a ServiceProvider used to derive
+// capabilities, calling any other method panics.
+type capabilityProvider struct {
+	ServiceProvider
+
+	async  bool
+	adjust func(plan ServicePlan, derived Capabilities) Capabilities
+}
+
+func (p *capabilityProvider) ProvisionsAsync() bool   { return p.async }
+func (p *capabilityProvider) DeprovisionsAsync() bool { return p.async }
+
+type reportingProvider struct {
+	capabilityProvider
+}
+
+func (p *reportingProvider) PlanCapabilities(plan ServicePlan, derived Capabilities) Capabilities {
+	return p.adjust(plan, derived)
+}
+
+func TestServiceDefinition_PlanCapabilities(t *testing.T) {
+	inputs := []BrokerVariable{{FieldName: "name"}, {FieldName: "region", ProhibitUpdate: true}}
+
+	cases := map[string]struct {
+		Provider ServiceProvider
+		Expected Capabilities
+	}{
+		"no provider": {
+			Expected: Capabilities{Update: true, PlanUpdate: true, UpdatableFields: []string{"name"}, Bind: true},
+		},
+		"async provider": {
+			Provider: &capabilityProvider{async: true},
+			Expected: Capabilities{Update: true, PlanUpdate: true, UpdatableFields: []string{"name"}, Bind: true, AsyncProvision: true, AsyncDeprovision: true},
+		},
+		"reporting provider": {
+			Provider: &reportingProvider{capabilityProvider{adjust: func(plan ServicePlan, derived Capabilities) Capabilities {
+				derived.Snapshots = plan.Name == "backed-up"
+				return derived
+			}}},
+			Expected: Capabilities{Update: true, PlanUpdate: true, UpdatableFields: []string{"name"}, Bind: true, Snapshots: true},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			svc := ServiceDefinition{Bindable: true, PlanUpdateable: true, ProvisionInputVariables: inputs}
+			if tc.Provider != nil {
+				svc.ProviderBuilder = func(lager.Logger, ProviderStore) ServiceProvider { return tc.Provider }
+			}
+
+			actual := svc.PlanCapabilities(ServicePlan{ServicePlan: brokerapi.ServicePlan{Name: "backed-up"}})
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected: %#v Actual: %#v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceCapabilities(t *testing.T) {
+	actual := ServiceCapabilities([]Capabilities{
+		{Update: true, UpdatableFields: []string{"name", "tier"}},
+		{Import: true, UpdatableFields: []string{"tier", "region"}},
+	})
+
+	expected := Capabilities{Update: true, Import: true, UpdatableFields: []string{"name", "tier", "region"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %#v Actual: %#v", expected, actual)
+	}
+}
+
+func TestServiceDefinition_CatalogEntry_capabilities(t *testing.T) {
+	planMetadata := &brokerapi.ServicePlanMetadata{DisplayName: "Small"}
+	svc := ServiceDefinition{
+		Id:       "svc-id",
+		Name:     "capabilities-test",
+		Bindable: true,
+		Plans: []ServicePlan{
+			{ServicePlan: brokerapi.ServicePlan{ID: "plan-id", Name: "small", Metadata: planMetadata}},
+		},
+	}
+
+	entry, err := svc.CatalogEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serialized, err := json.Marshal(entry.ToPlain())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`"metadata":{"capabilities":{"update":true,"plan_update":false,"updatable_fields":[],"bind":true`,
+		`"metadata":{"capabilities":{"update":true,"plan_update":false,"updatable_fields":[],"bind":true,"async_provision":false,"async_deprovision":false,"async_bind":false,"import":false,"snapshots":false},"displayName":"Small"}`,
+	} {
+		if !strings.Contains(string(serialized), expected) {
+			t.Errorf("Expected catalog to contain %s, got %s", expected, serialized)
+		}
+	}
+
+	if planMetadata.AdditionalMetadata != nil {
+		t.Errorf("Expected the definition's plan metadata not to be modified, got %v", planMetadata.AdditionalMetadata)
+	}
+}
@@ -46,8 +46,13 @@ type ServicePlan struct {
 	brokerapi.ServicePlan
 
 	ServiceProperties  map[string]interface{} `json:"service_properties"`
+	ProvisionDefaults  map[string]interface{} `json:"provision_defaults,omitempty"`
 	ProvisionOverrides map[string]interface{} `json:"provision_overrides,omitempty"`
 	BindOverrides      map[string]interface{} `json:"bind_overrides,omitempty"`
+
+	// Constraints holds JSONSchema constraints, keyed by parameter, that
+	// instances of the plan must meet in addition to the service's.
+	Constraints map[string]map[string]interface{} `json:"constraints,omitempty"`
 }
 
 // GetServiceProperties gets the plan settings variables as a string->interface map.
@@ -0,0 +1,106 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// PlanConstraintsProperty returns the Viper property name for the object
+// operators can set to constrain the parameters of the service's plans. It's
+// keyed by plan name or ID.
+func (svc *ServiceDefinition) PlanConstraintsProperty() string {
+	return fmt.Sprintf("service.%s.plan_constraints", svc.Name)
+}
+
+// PlanConstraints returns the JSONSchema constraints on the parameters of
+// instances of the plan, keyed by parameter. The operator's constraints on a
+// parameter replace the plan's own.
+func (svc *ServiceDefinition) PlanConstraints(plan *ServicePlan) (map[string]map[string]interface{}, error) {
+	constraints := make(map[string]map[string]interface{})
+	for param, constraint := range plan.Constraints {
+		constraints[param] = constraint
+	}
+
+	operatorConstraints := make(map[string]map[string]map[string]interface{})
+	if val := config.Current().GetString(svc.PlanConstraintsProperty()); val != "" {
+		if err := json.Unmarshal([]byte(val), &operatorConstraints); err != nil {
+			return nil, fmt.Errorf("Failed unmarshaling config value %s", svc.PlanConstraintsProperty())
+		}
+	}
+
+	for _, key := range []string{plan.Name, plan.ID} {
+		for param, constraint := range operatorConstraints[key] {
+			constraints[param] = constraint
+		}
+	}
+
+	return constraints, nil
+}
+
+// ConstraintViolationError is returned when parameters don't meet the
+// constraints of their plan.
+type ConstraintViolationError struct {
+	Plan       string
+	Violations []string
+}
+
+func (e *ConstraintViolationError) Error() string {
+	return fmt.Sprintf("the parameters don't meet the constraints of plan %q: %s", e.Plan, strings.Join(e.Violations, "; "))
+}
+
+// CheckPlanConstraints returns a *ConstraintViolationError listing each
+// parameter that doesn't meet the plan's constraints. Parameters that aren't
+// set aren't checked.
+func (svc *ServiceDefinition) CheckPlanConstraints(plan *ServicePlan, parameters map[string]interface{}) error {
+	constraints, err := svc.PlanConstraints(plan)
+	if err != nil || len(constraints) == 0 {
+		return err
+	}
+
+	properties := make(map[string]interface{})
+	for param, constraint := range constraints {
+		properties[param] = constraint
+	}
+
+	schema := map[string]interface{}{
+		"$schema":    "http://json-schema.org/draft-04/schema#",
+		"type":       "object",
+		"properties": properties,
+	}
+
+	result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schema), gojsonschema.NewGoLoader(parameters))
+	if err != nil {
+		return fmt.Errorf("invalid constraints on plan %q: %v", plan.Name, err)
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	var violations []string
+	for _, resultErr := range result.Errors() {
+		violations = append(violations, fmt.Sprintf("%s: %s", resultErr.Field(), resultErr.Description()))
+	}
+	sort.Strings(violations)
+
+	return &ConstraintViolationError{Plan: plan.Name, Violations: violations}
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/spf13/viper"
+)
+
+func TestServiceDefinition_CheckPlanConstraints(t *testing.T) {
+	svcDef := ServiceDefinition{Name: "test-service"}
+	plan := ServicePlan{
+		ServicePlan: brokerapi.ServicePlan{ID: "plan-id", Name: "small"},
+		Constraints: map[string]map[string]interface{}{
+			"disk_size": {"maximum": 100},
+			"tier":      {"enum": []interface{}{"db-f1-micro"}},
+		},
+	}
+
+	cases := map[string]struct {
+		operatorConstraints string
+		parameters          map[string]interface{}
+		expected            []string
+	}{
+		"within constraints": {
+			parameters: map[string]interface{}{"disk_size": 10, "tier": "db-f1-micro"},
+		},
+		"unset parameters": {
+			parameters: map[string]interface{}{"name": "reports"},
+		},
+		"violations": {
+			parameters: map[string]interface{}{"disk_size": 200, "tier": "db-n1-standard-1"},
+			expected: []string{
+				"disk_size: Must be less than or equal to 100",
+				`tier: tier must be one of the following: "db-f1-micro"`,
+			},
+		},
+		"operator constraints by name": {
+			operatorConstraints: `{"small":{"disk_size":{"maximum":50}}}`,
+			parameters:          map[string]interface{}{"disk_size": 75},
+			expected:            []string{"disk_size: Must be less than or equal to 50"},
+		},
+		"operator constraints by id": {
+			operatorConstraints: `{"plan-id":{"disk_size":{"maximum":500}}}`,
+			parameters:          map[string]interface{}{"disk_size": 200},
+		},
+		"operator constraints for other plans": {
+			operatorConstraints: `{"large":{"tier":{"enum":["db-n1-standard-1"]}}}`,
+			parameters:          map[string]interface{}{"tier": "db-f1-micro"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(svcDef.PlanConstraintsProperty(), tc.operatorConstraints)
+			defer viper.Set(svcDef.PlanConstraintsProperty(), nil)
+
+			err := svcDef.CheckPlanConstraints(&plan, tc.parameters)
+			if tc.expected == nil {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				return
+			}
+
+			violationErr, ok := err.(*ConstraintViolationError)
+			if !ok {
+				t.Fatalf("Expected a *ConstraintViolationError, got: %v", err)
+			}
+			if !reflect.DeepEqual(violationErr.Violations, tc.expected) {
+				t.Errorf("Expected violations: %v, got: %v", tc.expected, violationErr.Violations)
+			}
+		})
+	}
+}
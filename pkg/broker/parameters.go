@@ -0,0 +1,203 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/naming"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext/interpolation"
+)
+
+// ProvisionPinnedProperty returns the Viper property name for the object
+// operators can set to pin provision parameters to values users can't change.
+func (svc *ServiceDefinition) ProvisionPinnedProperty() string {
+	return fmt.Sprintf("service.%s.provision.pinned", svc.Name)
+}
+
+// ProvisionPinned returns the deserialized JSON object of the parameters the
+// operator pinned.
+func (svc *ServiceDefinition) ProvisionPinned() (map[string]interface{}, error) {
+	return unmarshalViper(svc.ProvisionPinnedProperty())
+}
+
+// PinnedParameterError is returned when a request sets parameters the
+// operator pinned to other values.
+type PinnedParameterError struct {
+	Parameters []string
+}
+
+func (e *PinnedParameterError) Error() string {
+	return fmt.Sprintf("parameters pinned by the operator can't be changed: %s", strings.Join(e.Parameters, ", "))
+}
+
+// CheckPinnedParameters returns a *PinnedParameterError if the user's
+// parameters change any the operator pinned. Users may repeat a pinned value.
+func (svc *ServiceDefinition) CheckPinnedParameters(rawParameters json.RawMessage) error {
+	pinned, err := svc.ProvisionPinned()
+	if err != nil || len(pinned) == 0 {
+		return err
+	}
+
+	params, err := decodeParameters(rawParameters)
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	for key, value := range params {
+		if pinnedValue, ok := pinned[key]; ok && !reflect.DeepEqual(pinnedValue, value) {
+			changed = append(changed, key)
+		}
+	}
+
+	if len(changed) > 0 {
+		sort.Strings(changed)
+		return &PinnedParameterError{Parameters: changed}
+	}
+
+	return nil
+}
+
+// ResolveProvisionParameters layers the user's provision parameters over the
+// plan's provision_defaults and under the parameters the operator pinned.
+// Expressions in the operator's and plan's defaults are evaluated and saved
+// too. The result is saved with the instance so it keeps its values if the
+// defaults change. The parameters are returned as they are if there's nothing
+// to layer.
+func (svc *ServiceDefinition) ResolveProvisionParameters(instanceId string, details brokerapi.ProvisionDetails, plan ServicePlan) (json.RawMessage, error) {
+	rawParameters := details.GetRawParameters()
+	fields := svc.provisionFields(instanceId, details, plan)
+
+	operatorDefaults, err := svc.operatorDefaults()
+	if err != nil {
+		return nil, err
+	}
+	operatorExpressions, err := evalDefaults(expressionDefaults(operatorDefaults), fields)
+	if err != nil {
+		return nil, err
+	}
+
+	planDefaults, err := evalDefaults(plan.ProvisionDefaults, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	pinned, err := svc.ProvisionPinned()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(operatorExpressions) == 0 && len(planDefaults) == 0 && len(pinned) == 0 {
+		return rawParameters, nil
+	}
+
+	params, err := decodeParameters(rawParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make(map[string]interface{})
+	for _, layer := range []map[string]interface{}{operatorExpressions, planDefaults, params, pinned} {
+		for key, value := range layer {
+			resolved[key] = value
+		}
+	}
+
+	return json.Marshal(resolved)
+}
+
+// operatorDefaults returns the global provision defaults overlaid with the
+// service's.
+func (svc *ServiceDefinition) operatorDefaults() (map[string]interface{}, error) {
+	globalDefaults, err := ProvisionGlobalDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	serviceDefaults, err := svc.ProvisionDefaultOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range serviceDefaults {
+		globalDefaults[key] = value
+	}
+
+	return globalDefaults, nil
+}
+
+// expressionDefaults returns the defaults that are expressions, e.g.
+// ${space_guid_short}.
+func expressionDefaults(defaults map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for key, value := range defaults {
+		if str, ok := value.(string); ok && interpolation.IsHILExpression(str) {
+			out[key] = str
+		}
+	}
+
+	return out
+}
+
+// evalDefaults evaluates the expressions in configured defaults against the
+// fields of the instance, e.g. ${instance_id_short} or ${random_string(8)}.
+// Other values are returned as they are.
+func evalDefaults(defaults map[string]interface{}, fields naming.Fields) (map[string]interface{}, error) {
+	if len(defaults) == 0 {
+		return defaults, nil
+	}
+
+	variables := make(map[string]interface{})
+	for key, value := range fields {
+		variables[key] = value
+	}
+
+	out := make(map[string]interface{})
+	for key, value := range defaults {
+		str, ok := value.(string)
+		if !ok {
+			out[key] = value
+			continue
+		}
+
+		result, err := interpolation.Eval(str, variables)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't evaluate the default for %q, template: %q, %v", key, str, err)
+		}
+		out[key] = result
+	}
+
+	return out, nil
+}
+
+// decodeParameters decodes a JSON object of parameters, which may be empty.
+func decodeParameters(rawParameters json.RawMessage) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
+	if len(rawParameters) == 0 {
+		return params, nil
+	}
+
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return nil, fmt.Errorf("couldn't decode parameters: %v", err)
+	}
+
+	return params, nil
+}
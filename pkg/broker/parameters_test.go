@@ -0,0 +1,139 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/spf13/viper"
+)
+
+func TestServiceDefinition_CheckPinnedParameters(t *testing.T) {
+	svcDef := ServiceDefinition{Name: "test-service"}
+
+	cases := map[string]struct {
+		pinned   string
+		params   string
+		expected []string
+	}{
+		"nothing pinned":   {params: `{"location":"us"}`},
+		"no parameters":    {pinned: `{"location":"eu"}`},
+		"other parameters": {pinned: `{"location":"eu"}`, params: `{"name":"reports"}`},
+		"same value":       {pinned: `{"location":"eu","tier":2}`, params: `{"location":"eu","tier":2}`},
+		"changed":          {pinned: `{"location":"eu","tier":2,"name":"x"}`, params: `{"tier":3,"location":"us","name":"x"}`, expected: []string{"location", "tier"}},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if tc.pinned != "" {
+				viper.Set(svcDef.ProvisionPinnedProperty(), tc.pinned)
+				defer viper.Set(svcDef.ProvisionPinnedProperty(), nil)
+			}
+
+			err := svcDef.CheckPinnedParameters(json.RawMessage(tc.params))
+			if tc.expected == nil {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				return
+			}
+
+			pinnedErr, ok := err.(*PinnedParameterError)
+			if !ok {
+				t.Fatalf("Expected a *PinnedParameterError, got: %v", err)
+			}
+			if !reflect.DeepEqual(pinnedErr.Parameters, tc.expected) {
+				t.Errorf("Expected parameters: %v, got: %v", tc.expected, pinnedErr.Parameters)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_ResolveProvisionParameters(t *testing.T) {
+	svcDef := ServiceDefinition{Name: "test-service"}
+
+	cases := map[string]struct {
+		planDefaults     map[string]interface{}
+		operatorDefaults string
+		pinned           string
+		params           string
+		expected         string
+	}{
+		"nothing to layer": {
+			params:   `{"name": "reports"}`,
+			expected: `{"name": "reports"}`,
+		},
+		"plan defaults": {
+			planDefaults: map[string]interface{}{"location": "us", "storage_class": "STANDARD"},
+			params:       `{"location":"eu"}`,
+			expected:     `{"location":"eu","storage_class":"STANDARD"}`,
+		},
+		"pinned win": {
+			planDefaults: map[string]interface{}{"location": "us"},
+			pinned:       `{"location":"asia"}`,
+			params:       `{"location":"eu","name":"reports"}`,
+			expected:     `{"location":"asia","name":"reports"}`,
+		},
+		"empty parameters": {
+			pinned:   `{"location":"asia"}`,
+			expected: `{"location":"asia"}`,
+		},
+		"plan default expressions": {
+			planDefaults: map[string]interface{}{"name": "reports-${space_guid_short}-${instance_id_short}", "tier": 2},
+			expected:     `{"name":"reports-7c1d9e3f-a4eb5e6c","tier":2}`,
+		},
+		"operator default expressions": {
+			operatorDefaults: `{"name":"${plan_name}-${instance_id_short}","location":"us"}`,
+			params:           `{"location":"eu"}`,
+			expected:         `{"location":"eu","name":"small-a4eb5e6c"}`,
+		},
+		"user wins over expressions": {
+			operatorDefaults: `{"name":"${plan_name}"}`,
+			params:           `{"name":"reports"}`,
+			expected:         `{"name":"reports"}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if tc.pinned != "" {
+				viper.Set(svcDef.ProvisionPinnedProperty(), tc.pinned)
+				defer viper.Set(svcDef.ProvisionPinnedProperty(), nil)
+			}
+
+			if tc.operatorDefaults != "" {
+				viper.Set(svcDef.ProvisionDefaultOverrideProperty(), tc.operatorDefaults)
+				defer viper.Set(svcDef.ProvisionDefaultOverrideProperty(), nil)
+			}
+
+			plan := ServicePlan{ServicePlan: brokerapi.ServicePlan{Name: "small"}, ProvisionDefaults: tc.planDefaults}
+			details := brokerapi.ProvisionDetails{
+				OrganizationGUID: "0f8e2b5a-95a6-4d1c-8a7e-6b3c9d2e1f40",
+				SpaceGUID:        "7c1d9e3f-2a4b-4e6c-8d0f-1a2b3c4d5e6f",
+				RawParameters:    json.RawMessage(tc.params),
+			}
+			actual, err := svcDef.ResolveProvisionParameters("a4eb5e6c-4d8a-4c4b-9b1d-3f0a6d9e2c17", details, plan)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if string(actual) != tc.expected {
+				t.Errorf("Expected parameters: %s, got: %s", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"fmt"
+)
+
+// DeletionProtectedPlansProperty returns the Viper property name for the
+// names or IDs of the service's plans whose instances can't be deprovisioned
+// until an operator lifts their protection.
+func (svc *ServiceDefinition) DeletionProtectedPlansProperty() string {
+	return fmt.Sprintf("service.%s.deletion_protected_plans", svc.Name)
+}
+
+// IsDeletionProtected returns true if the operator protected instances of
+// the plan from being deprovisioned.
+func (svc *ServiceDefinition) IsDeletionProtected(plan *ServicePlan) bool {
+	for _, protected := range viperStringList(svc.DeletionProtectedPlansProperty()) {
+		if protected == plan.Name || protected == plan.ID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,48 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/spf13/viper"
+)
+
+func TestServiceDefinition_IsDeletionProtected(t *testing.T) {
+	svcDef := ServiceDefinition{Name: "test-service"}
+	plan := ServicePlan{ServicePlan: brokerapi.ServicePlan{ID: "plan-id", Name: "production"}}
+
+	cases := map[string]struct {
+		protectedPlans interface{}
+		expected       bool
+	}{
+		"not configured":   {expected: false},
+		"by name":          {protectedPlans: "small,production", expected: true},
+		"by id":            {protectedPlans: []string{"plan-id"}, expected: true},
+		"other plans only": {protectedPlans: "small", expected: false},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(svcDef.DeletionProtectedPlansProperty(), tc.protectedPlans)
+			defer viper.Set(svcDef.DeletionProtectedPlansProperty(), nil)
+
+			if actual := svcDef.IsDeletionProtected(&plan); actual != tc.expected {
+				t.Errorf("Expected protected: %t, got: %t", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+)
+
+// GlobalAllowedRegions viper key for the broker-wide list of regions and
+// locations instances may be provisioned in
+const GlobalAllowedRegions = "provision.allowed_regions"
+
+// GlobalDefaultRegion viper key for the broker-wide region instances are
+// provisioned in if the user doesn't choose one
+const GlobalDefaultRegion = "provision.default_region"
+
+// regionFields are the names of provision variables that select where a
+// service's resources are created.
+var regionFields = []string{"region", "location"}
+
+// AllowedRegionsProperty returns the Viper property name for the list of
+// regions operators allow instances of this service to be provisioned in.
+func (svc *ServiceDefinition) AllowedRegionsProperty() string {
+	return fmt.Sprintf("service.%s.provision.allowed_regions", svc.Name)
+}
+
+// DefaultRegionProperty returns the Viper property name for the region
+// instances of this service are provisioned in by default.
+func (svc *ServiceDefinition) DefaultRegionProperty() string {
+	return fmt.Sprintf("service.%s.provision.default_region", svc.Name)
+}
+
+// AllowedRegions returns the regions instances may be provisioned in. The
+// operator's per-service list takes precedence over the broker-wide list. An
+// empty result means regions are not restricted beyond the provision schema.
+func (svc *ServiceDefinition) AllowedRegions() []string {
+	if regions := viperStringList(svc.AllowedRegionsProperty()); len(regions) > 0 {
+		return regions
+	}
+
+	return viperStringList(GlobalAllowedRegions)
+}
+
+// DefaultRegion returns the operator's default region for the service, the
+// per-service value takes precedence over the broker-wide value.
+func (svc *ServiceDefinition) DefaultRegion() string {
+	if region := config.Current().GetString(svc.DefaultRegionProperty()); region != "" {
+		return region
+	}
+
+	return config.Current().GetString(GlobalDefaultRegion)
+}
+
+// regionDefaults returns operator default values for each of the service's
+// region fields.
+func (svc *ServiceDefinition) regionDefaults() map[string]interface{} {
+	out := make(map[string]interface{})
+
+	region := svc.DefaultRegion()
+	if region == "" {
+		return out
+	}
+
+	for _, v := range svc.ProvisionInputVariables {
+		for _, field := range regionFields {
+			if v.FieldName == field {
+				out[field] = region
+			}
+		}
+	}
+
+	return out
+}
+
+// ValidateRegion returns an error listing the permitted regions if the given
+// region or location isn't in the service's AllowedRegions. Zones are
+// permitted if their region is, e.g. us-central1-a is allowed by us-central1.
+// Comparisons are case insensitive.
+func (svc *ServiceDefinition) ValidateRegion(region string) error {
+	allowed := svc.AllowedRegions()
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	requested := strings.ToLower(region)
+	for _, candidate := range allowed {
+		candidate = strings.ToLower(candidate)
+		if requested == candidate || strings.HasPrefix(requested, candidate+"-") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("region %q is not permitted for service %s, permitted regions are: %s", region, svc.Name, strings.Join(allowed, ", "))
+}
+
+// ValidateRegions checks every region field set in the provision variables
+// against the service's AllowedRegions.
+func (svc *ServiceDefinition) ValidateRegions(vars *varcontext.VarContext) error {
+	for _, field := range regionFields {
+		if !vars.HasKey(field) {
+			continue
+		}
+
+		if err := svc.ValidateRegion(vars.GetString(field)); err != nil {
+			return fmt.Errorf("%s: %v", field, err)
+		}
+	}
+
+	return nil
+}
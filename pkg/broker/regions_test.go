@@ -0,0 +1,141 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/spf13/viper"
+)
+
+func TestServiceDefinition_ValidateRegion(t *testing.T) {
+	svcDef := ServiceDefinition{Name: "test-service"}
+
+	cases := map[string]struct {
+		serviceRegions interface{}
+		globalRegions  interface{}
+		region         string
+		expected       []string
+		expectErr      bool
+	}{
+		"unrestricted": {
+			region: "asia-east1",
+		},
+		"broker-wide list": {
+			globalRegions: "us-central1,us-east1",
+			region:        "us-east1",
+			expected:      []string{"us-central1", "us-east1"},
+		},
+		"service overrides broker-wide": {
+			serviceRegions: []string{"europe-west1"},
+			globalRegions:  "us-central1",
+			region:         "us-central1",
+			expected:       []string{"europe-west1"},
+			expectErr:      true,
+		},
+		"case insensitive": {
+			serviceRegions: "US",
+			region:         "us",
+			expected:       []string{"US"},
+		},
+		"zone in permitted region": {
+			serviceRegions: "us-central1",
+			region:         "us-central1-a",
+			expected:       []string{"us-central1"},
+		},
+		"similar region prefix": {
+			serviceRegions: "us-central1",
+			region:         "us-central10",
+			expected:       []string{"us-central1"},
+			expectErr:      true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(svcDef.AllowedRegionsProperty(), tc.serviceRegions)
+			viper.Set(GlobalAllowedRegions, tc.globalRegions)
+			defer viper.Set(svcDef.AllowedRegionsProperty(), nil)
+			defer viper.Set(GlobalAllowedRegions, nil)
+
+			if actual := svcDef.AllowedRegions(); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Expected allowed regions %v, got %v", tc.expected, actual)
+			}
+
+			if err := svcDef.ValidateRegion(tc.region); (err != nil) != tc.expectErr {
+				t.Errorf("Expected error: %v, got: %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_DefaultRegion(t *testing.T) {
+	svcDef := ServiceDefinition{
+		Name: "test-service",
+		ProvisionInputVariables: []BrokerVariable{
+			{FieldName: "location", Type: JsonTypeString, Default: "us"},
+		},
+		Plans: []ServicePlan{{ServicePlan: brokerapi.ServicePlan{ID: "plan-id", Name: "plan"}}},
+	}
+
+	cases := map[string]struct {
+		serviceDefault string
+		globalDefault  string
+		userParams     string
+		expected       string
+	}{
+		"no default region": {
+			userParams: `{}`,
+			expected:   "us",
+		},
+		"broker-wide default": {
+			globalDefault: "eu",
+			userParams:    `{}`,
+			expected:      "eu",
+		},
+		"service overrides broker-wide": {
+			serviceDefault: "asia",
+			globalDefault:  "eu",
+			userParams:     `{}`,
+			expected:       "asia",
+		},
+		"user overrides default": {
+			globalDefault: "eu",
+			userParams:    `{"location":"us"}`,
+			expected:      "us",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(svcDef.DefaultRegionProperty(), tc.serviceDefault)
+			viper.Set(GlobalDefaultRegion, tc.globalDefault)
+			defer viper.Set(svcDef.DefaultRegionProperty(), nil)
+			defer viper.Set(GlobalDefaultRegion, nil)
+
+			details := brokerapi.ProvisionDetails{RawParameters: []byte(tc.userParams)}
+			vars, err := svcDef.ProvisionVariables("instance-id", details, svcDef.Plans[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual := vars.GetString("location"); actual != tc.expected {
+				t.Errorf("Expected location %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"log"
 	"sort"
 
+	"github.com/pivotal/cloud-service-broker/pkg/config"
 	"github.com/pivotal/cloud-service-broker/pkg/toggles"
 	"github.com/pivotal/cloud-service-broker/utils"
 )
@@ -51,6 +52,13 @@ func (brokerRegistry BrokerRegistry) Register(service *ServiceDefinition) {
 		log.Fatalf("Tried to register multiple instances of: %q", name)
 	}
 
+	config.Register(
+		config.Property{Key: service.UserDefinedPlansProperty(), Kind: config.Json},
+		config.Property{Key: service.ProvisionDefaultOverrideProperty(), Kind: config.Json},
+		config.Property{Key: service.BindDefaultOverrideProperty(), Kind: config.Json},
+		config.Property{Key: service.NamingTemplateProperty(), Kind: config.String},
+	)
+
 	// Test deserializing the user defined plans and service definition
 	if _, err := service.CatalogEntry(); err != nil {
 		log.Fatalf("Error registering service %q, %s", name, err)
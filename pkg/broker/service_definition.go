@@ -19,10 +19,16 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"code.cloudfoundry.org/lager"
 	"github.com/pivotal-cf/brokerapi"
 	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/naming"
+	"github.com/pivotal/cloud-service-broker/pkg/platformcontext"
+	"github.com/pivotal/cloud-service-broker/pkg/projects"
+	"github.com/pivotal/cloud-service-broker/pkg/svcat"
 	"github.com/pivotal/cloud-service-broker/pkg/toggles"
 	"github.com/pivotal/cloud-service-broker/pkg/validation"
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
@@ -35,6 +41,18 @@ var enableCatalogSchemas = toggles.Features.Toggle("enable-catalog-schemas", fal
 // GlobalProvisionDefaults viper key for global provision defaults
 const GlobalProvisionDefaults = "provision.defaults"
 
+// GlobalRoleWhitelist viper key for the broker-wide list of roles that may be
+// granted on bind for services that don't have their own list
+const GlobalRoleWhitelist = "bind.role_whitelist"
+
+// KeylessPrincipalProp holds the IAM member, e.g. a workload identity, that
+// bind templates grant roles to instead of creating service account keys.
+const KeylessPrincipalProp = "bind.keyless.principal"
+
+func init() {
+	config.Register(config.Property{Key: KeylessPrincipalProp, Kind: config.String, Env: "BIND_KEYLESS_PRINCIPAL"})
+}
+
 // ServiceDefinition holds the necessary details to describe an OSB service and
 // provision it.
 type ServiceDefinition struct {
@@ -50,6 +68,14 @@ type ServiceDefinition struct {
 	PlanUpdateable   bool
 	Plans            []ServicePlan
 
+	// Shareable is true if instances can be shared with other spaces, each
+	// of which gets its own bindings.
+	Shareable bool
+
+	// ResourceName, if set, generates the name of the instance's resource
+	// from a naming template operators can override.
+	ResourceName *naming.Rule
+
 	ProvisionInputVariables    []BrokerVariable
 	ProvisionComputedVariables []varcontext.DefaultVariable
 	BindInputVariables         []BrokerVariable
@@ -59,8 +85,19 @@ type ServiceDefinition struct {
 	Examples                   []ServiceExample
 	DefaultRoleWhitelist       []string
 
-	// ProviderBuilder creates a new provider given the project, auth, and logger.
-	ProviderBuilder func(plogger lager.Logger) ServiceProvider
+	// KeylessBind is true if the service's bindings grant roles to the
+	// operator's keyless principal, when one is set, instead of creating
+	// credentials.
+	KeylessBind bool
+
+	// RequiredRoles are the IAM roles the broker's service account needs to
+	// provision and bind the service, they're listed in its documentation.
+	RequiredRoles []string
+
+	// ProviderBuilder creates a new provider given the logger and the
+	// datastore of the broker using it. The datastore is nil when the provider
+	// is only asked about its capabilities.
+	ProviderBuilder func(plogger lager.Logger, store ProviderStore) ServiceProvider
 
 	// IsBuiltin is true if the service is built-in to the platform.
 	IsBuiltin bool
@@ -111,6 +148,17 @@ func (sd *ServiceDefinition) Validate() (errs *validation.FieldError) {
 		errs = errs.Also(v.Validate().ViaFieldIndex("PlanVariables", i))
 	}
 
+	if sd.ResourceName != nil {
+		errs = errs.Also(sd.ResourceName.Validate().ViaField("ResourceName"))
+
+		// catch bad operator templates on startup rather than on provision
+		if override := config.Current().GetString(sd.NamingTemplateProperty()); override != "" {
+			if err := naming.CheckTemplate(override, sd.ResourceName.Product); err != nil {
+				errs = errs.Also(&validation.FieldError{Message: err.Error(), Paths: []string{sd.NamingTemplateProperty()}})
+			}
+		}
+	}
+
 	return errs
 }
 
@@ -126,6 +174,53 @@ func (svc *ServiceDefinition) ProvisionDefaultOverrideProperty() string {
 	return fmt.Sprintf("service.%s.provision.defaults", svc.Name)
 }
 
+// NamingTemplateProperty returns the Viper property name for the template
+// operators can set to override how the service names its resources.
+func (svc *ServiceDefinition) NamingTemplateProperty() string {
+	return fmt.Sprintf("service.%s.naming_template", svc.Name)
+}
+
+// NameResources sets the service's resource name in the provision parameters
+// to one generated from the naming template, unless the user chose a name.
+// Parameters are saved with the instance so the name stays the same if the
+// template changes.
+func (svc *ServiceDefinition) NameResources(instanceId string, details brokerapi.ProvisionDetails, plan ServicePlan) (json.RawMessage, error) {
+	params := details.GetRawParameters()
+	if svc.ResourceName == nil {
+		return params, nil
+	}
+
+	userParams := make(map[string]interface{})
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &userParams); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := userParams[svc.ResourceName.Variable]; ok {
+		return params, nil
+	}
+
+	tmpl := svc.ResourceName.Template
+	if override := config.Current().GetString(svc.NamingTemplateProperty()); override != "" {
+		tmpl = override
+	}
+
+	fields := svc.provisionFields(instanceId, details, plan)
+	name, err := naming.Generate(tmpl, svc.ResourceName.Product, fields)
+	if err != nil {
+		return nil, fmt.Errorf("Error generating %s: %v", svc.ResourceName.Variable, err)
+	}
+
+	return utils.SetParameter(params, svc.ResourceName.Variable, name)
+}
+
+// provisionFields returns the naming fields of an instance being provisioned,
+// including the names from the platform's context.
+func (svc *ServiceDefinition) provisionFields(instanceId string, details brokerapi.ProvisionDetails, plan ServicePlan) naming.Fields {
+	return naming.NewFields(instanceId, details.OrganizationGUID, details.SpaceGUID, svc.Name, plan.Name).
+		WithContext(platformcontext.Parse(details.RawContext))
+}
+
 // ProvisionDefaultOverrides returns the deserialized JSON object for the
 // operator-provided property overrides.
 func (svc *ServiceDefinition) ProvisionDefaultOverrides() (map[string]interface{}, error) {
@@ -138,8 +233,8 @@ func ProvisionGlobalDefaults() (map[string]interface{}, error) {
 
 func unmarshalViper(key string) (map[string]interface{}, error) {
 	vals := make(map[string]interface{})
-	if viper.IsSet(key) {
-		val := viper.GetString(key)
+	if config.Current().IsSet(key) {
+		val := config.Current().GetString(key)
 		if err := json.Unmarshal([]byte(val), &vals); err != nil {
 			return nil, fmt.Errorf("Failed unmarshaling config value %s", key)
 		}
@@ -153,6 +248,87 @@ func (svc *ServiceDefinition) IsRoleWhitelistEnabled() bool {
 	return len(svc.DefaultRoleWhitelist) > 0
 }
 
+// RoleWhitelistProperty returns the Viper property name for the list of roles
+// operators allow to be granted when binding to this service.
+func (svc *ServiceDefinition) RoleWhitelistProperty() string {
+	return fmt.Sprintf("service.%s.bind.role_whitelist", svc.Name)
+}
+
+// RoleWhitelist returns the roles that may be granted on bind. The
+// operator's list for the service takes precedence over the service's
+// DefaultRoleWhitelist, services with neither fall back to the broker-wide
+// list. An empty result means roles are not restricted beyond the bind schema.
+func (svc *ServiceDefinition) RoleWhitelist() []string {
+	if roles := viperStringList(svc.RoleWhitelistProperty()); len(roles) > 0 {
+		return roles
+	}
+
+	if len(svc.DefaultRoleWhitelist) > 0 {
+		return svc.DefaultRoleWhitelist
+	}
+
+	return viperStringList(GlobalRoleWhitelist)
+}
+
+// ValidateRole returns an error listing the permitted roles if the given role
+// is not in the service's RoleWhitelist.
+func (svc *ServiceDefinition) ValidateRole(role string) error {
+	whitelist := svc.RoleWhitelist()
+	if len(whitelist) == 0 || utils.NewStringSet(whitelist...).Contains(role) {
+		return nil
+	}
+
+	return fmt.Errorf("role %q is not permitted for service %s, permitted roles are: %s", role, svc.Name, strings.Join(whitelist, ", "))
+}
+
+// ValidateKeyless returns an error if the operator set a keyless principal
+// but the service's bindings would create credentials anyway.
+func (svc *ServiceDefinition) ValidateKeyless() error {
+	if svc.KeylessBind || viper.GetString(KeylessPrincipalProp) == "" {
+		return nil
+	}
+
+	return fmt.Errorf("service %s can't bind without creating credentials; %s is set so its bindings are refused", svc.Name, KeylessPrincipalProp)
+}
+
+// viperStringList reads a list of strings from Viper, the value may either be
+// a list or a comma delimited string.
+func viperStringList(key string) []string {
+	var out []string
+	for _, item := range config.Current().GetStringSlice(key) {
+		for _, value := range strings.Split(item, ",") {
+			if trimmed := strings.TrimSpace(value); trimmed != "" {
+				out = append(out, trimmed)
+			}
+		}
+	}
+
+	return out
+}
+
+// OperationTimeoutProperty returns the Viper property name for the maximum
+// duration of the given operation, e.g. provision or bind, on this service.
+func (svc *ServiceDefinition) OperationTimeoutProperty(operation string) string {
+	return fmt.Sprintf("service.%s.%s.timeout", svc.Name, operation)
+}
+
+// GlobalOperationTimeoutProperty returns the Viper property name for the
+// broker-wide maximum duration of the given operation.
+func GlobalOperationTimeoutProperty(operation string) string {
+	return fmt.Sprintf("%s.timeout", operation)
+}
+
+// OperationTimeout returns how long the given operation may run before it
+// fails. The operator's per-service timeout takes precedence over the
+// broker-wide one. Zero means the operation can run indefinitely.
+func (svc *ServiceDefinition) OperationTimeout(operation string) time.Duration {
+	if config.Current().IsSet(svc.OperationTimeoutProperty(operation)) {
+		return config.Current().GetDuration(svc.OperationTimeoutProperty(operation))
+	}
+
+	return config.Current().GetDuration(GlobalOperationTimeoutProperty(operation))
+}
+
 // BindDefaultOverrideProperty returns the Viper property name for the
 // object users can set to override the default values on bind.
 func (svc *ServiceDefinition) BindDefaultOverrideProperty() string {
@@ -162,7 +338,7 @@ func (svc *ServiceDefinition) BindDefaultOverrideProperty() string {
 // BindDefaultOverrides returns the deserialized JSON object for the
 // operator-provided property overrides.
 func (svc *ServiceDefinition) BindDefaultOverrides() map[string]interface{} {
-	return viper.GetStringMap(svc.BindDefaultOverrideProperty())
+	return config.Current().GetStringMap(svc.BindDefaultOverrideProperty())
 }
 
 // TileUserDefinedPlansVariable returns the name of the user defined plans
@@ -200,18 +376,64 @@ func (svc *ServiceDefinition) CatalogEntry() (*Service, error) {
 			Bindable:      svc.Bindable,
 			PlanUpdatable: svc.PlanUpdateable,
 		},
-		Plans: append(svc.Plans, userPlans...),
+		// the plans are copied because they're modified below and
+		// definitions are shared by concurrent requests
+		Plans: append(append([]ServicePlan{}, svc.Plans...), userPlans...),
 	}
 
-	if enableCatalogSchemas.IsActive() {
+	if svc.Shareable {
+		shareable := true
+		sd.Metadata.Shareable = &shareable
+	}
+
+	if svcat.Enabled() {
+		for i := range sd.Plans {
+			schemas, err := svc.planSchemas(&sd.Plans[i])
+			if err != nil {
+				return nil, err
+			}
+			sd.Plans[i].Schemas = schemas
+		}
+	} else if enableCatalogSchemas.IsActive() {
 		for i, _ := range sd.Plans {
 			sd.Plans[i].Schemas = svc.createSchemas()
 		}
 	}
 
+	maintenanceInfo := svc.MaintenanceInfo()
+	var planCapabilities []Capabilities
+	for i := range sd.Plans {
+		sd.Plans[i].MaintenanceInfo = maintenanceInfo
+
+		capabilities := svc.PlanCapabilities(sd.Plans[i])
+		planCapabilities = append(planCapabilities, capabilities)
+
+		// plans can share metadata with the definition, so it's copied
+		// rather than modified
+		metadata := brokerapi.ServicePlanMetadata{}
+		if sd.Plans[i].Metadata != nil {
+			metadata = *sd.Plans[i].Metadata
+		}
+		metadata.AdditionalMetadata = withMetadata(metadata.AdditionalMetadata, CapabilitiesMetadataKey, capabilities)
+		sd.Plans[i].Metadata = &metadata
+	}
+
+	sd.Metadata.AdditionalMetadata = withMetadata(nil, CapabilitiesMetadataKey, ServiceCapabilities(planCapabilities))
+
 	return sd, nil
 }
 
+// withMetadata copies the additional metadata and sets the key to the value.
+func withMetadata(additional map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range additional {
+		out[k] = v
+	}
+
+	out[key] = value
+	return out
+}
+
 // createSchemas creates JSONSchemas compatible with the OSB spec for provision and bind.
 // It leaves the instance update schema empty to indicate updates are not supported.
 func (svc *ServiceDefinition) createSchemas() *brokerapi.ServiceSchemas {
@@ -259,7 +481,7 @@ func (svc *ServiceDefinition) UserDefinedPlans() ([]ServicePlan, error) {
 
 	// Unmarshal the plans from the viper configuration which is just a JSON list
 	// of plans
-	if userPlanJSON := viper.GetString(svc.UserDefinedPlansProperty()); userPlanJSON != "" {
+	if userPlanJSON := config.Current().GetString(svc.UserDefinedPlansProperty()); userPlanJSON != "" {
 		if err := json.Unmarshal([]byte(userPlanJSON), &rawPlans); err != nil {
 			return []ServicePlan{}, err
 		}
@@ -355,11 +577,13 @@ func (svc *ServiceDefinition) bindDefaults() []varcontext.DefaultVariable {
 // 1. Variables defined in your `computed_variables` JSON list.
 // 2. Variables defined by the selected service plan in its `service_properties` map.
 // 3. Variables overridden in the plan's `provision_overrides` map.
-// 4. User defined variables (in `update_input_variables`)
-// 5. User defined variables (in `provision_input_variables` or `bind_input_variables`)
-// 6. Operator default variables loaded from the environment.
-// 7. Global operator default variables loaded from the environemnt.
-// 8. Default variables (in `provision_input_variables` or `bind_input_variables`).
+// 4. Operator pinned variables loaded from the environment.
+// 5. User defined variables (in `update_input_variables`)
+// 6. User defined variables (in `provision_input_variables` or `bind_input_variables`)
+// 7. Variables defaulted in the plan's `provision_defaults` map.
+// 8. Operator default variables loaded from the environment.
+// 9. Global operator default variables loaded from the environemnt, including the default region.
+// 10. Default variables (in `provision_input_variables` or `bind_input_variables`).
 //
 // Loading into the map occurs slightly differently.
 // Default variables and computed_variables get executed by interpolation.
@@ -369,6 +593,7 @@ func (svc *ServiceDefinition) bindDefaults() []varcontext.DefaultVariable {
 // Therefore, they get executed conditionally if a user-provided variable does not exist.
 // Computed variables get executed either unconditionally or conditionally for greater flexibility.
 func (svc *ServiceDefinition) variables( constants map[string]interface{}, 
+										 fields naming.Fields,
 										 rawProvisionParameters json.RawMessage, 
 										 rawUpdateParameters json.RawMessage,
 										 plan ServicePlan) (*varcontext.VarContext, error) {
@@ -380,20 +605,42 @@ func (svc *ServiceDefinition) variables( constants map[string]interface{},
 	// 	"request.default_labels": utils.ExtractDefaultProvisionLabels(instanceId, details),
 	// }
 
+	// expressions in defaults are evaluated against the instance's fields,
+	// provisions save their results so they're only evaluated again if the
+	// request details weren't kept
 	globalDefaults, err := ProvisionGlobalDefaults()
 	if err != nil {
 		return nil, err
 	}
+	globalDefaults, err = evalDefaults(globalDefaults, fields)
+	if err != nil {
+		return nil, err
+	}
 	provisionDefaultOverrides, err := svc.ProvisionDefaultOverrides()
 	if err != nil {
 		return nil, err
 	}
+	provisionDefaultOverrides, err = evalDefaults(provisionDefaultOverrides, fields)
+	if err != nil {
+		return nil, err
+	}
+	planDefaults, err := evalDefaults(plan.ProvisionDefaults, fields)
+	if err != nil {
+		return nil, err
+	}
+	provisionPinned, err := svc.ProvisionPinned()
+	if err != nil {
+		return nil, err
+	}
 	builder := varcontext.Builder().
 		SetEvalConstants(constants).
-		MergeMap(globalDefaults).                     // 7
-		MergeMap(provisionDefaultOverrides).          // 6
-		MergeJsonObject(rawProvisionParameters).      // 5 user vars provided during provision call
-		MergeJsonObject(rawUpdateParameters).         // 4 user vars provided during update call
+		MergeMap(globalDefaults).                     // 9
+		MergeMap(svc.regionDefaults()).               // 9 operator default region
+		MergeMap(provisionDefaultOverrides).          // 8
+		MergeMap(planDefaults).                       // 7
+		MergeJsonObject(rawProvisionParameters).      // 6 user vars provided during provision call
+		MergeJsonObject(rawUpdateParameters).         // 5 user vars provided during update call
+		MergeMap(provisionPinned).                    // 4
 		MergeMap(plan.ProvisionOverrides).            // 3
 		MergeDefaults(svc.provisionDefaults()).       // ?
 		MergeMap(plan.GetServiceProperties()).        // 2
@@ -403,24 +650,39 @@ func (svc *ServiceDefinition) variables( constants map[string]interface{},
 }
 
 func (svc *ServiceDefinition) ProvisionVariables(instanceId string, details brokerapi.ProvisionDetails, plan ServicePlan) (*varcontext.VarContext, error) {
+	defaultProject, err := projects.DefaultFor(details.OrganizationGUID, details.SpaceGUID)
+	if err != nil {
+		return nil, err
+	}
+
 	// The namespaces of these values roughly align with the OSB spec.
 	constants := map[string]interface{}{
-		"request.plan_id":        details.PlanID,
-		"request.service_id":     details.ServiceID,
-		"request.instance_id":    instanceId,
-		"request.default_labels": utils.ExtractDefaultProvisionLabels(instanceId, details),
-	}
-	return svc.variables(constants, details.GetRawParameters(), json.RawMessage("{}"), plan)
+		"request.plan_id":         details.PlanID,
+		"request.service_id":      details.ServiceID,
+		"request.instance_id":     instanceId,
+		"request.default_labels":  utils.ExtractDefaultProvisionLabels(instanceId, details),
+		"request.default_project": defaultProject,
+	}
+	fields := svc.provisionFields(instanceId, details, plan)
+	return svc.variables(constants, fields, details.GetRawParameters(), json.RawMessage("{}"), plan)
 }
 
 func (svc *ServiceDefinition) 	UpdateVariables(instanceId string, details brokerapi.UpdateDetails, provisionDetails json.RawMessage, plan ServicePlan) (*varcontext.VarContext, error) {
-	constants := map[string]interface{}{
-		"request.plan_id":        details.PlanID,
-		"request.service_id":     details.ServiceID,
-		"request.instance_id":    instanceId,
-		"request.default_labels": utils.ExtractDefaultUpdateLabels(instanceId, details),
+	defaultProject, err := projects.DefaultFor(details.PreviousValues.OrgID, details.PreviousValues.SpaceID)
+	if err != nil {
+		return nil, err
 	}
-	return svc.variables(constants, provisionDetails, details.GetRawParameters(), plan)
+
+	constants := map[string]interface{}{
+		"request.plan_id":         details.PlanID,
+		"request.service_id":      details.ServiceID,
+		"request.instance_id":     instanceId,
+		"request.default_labels":  utils.ExtractDefaultUpdateLabels(instanceId, details),
+		"request.default_project": defaultProject,
+	}
+	fields := naming.NewFields(instanceId, details.PreviousValues.OrgID, details.PreviousValues.SpaceID, svc.Name, plan.Name).
+		WithContext(platformcontext.Parse(details.RawContext))
+	return svc.variables(constants, fields, provisionDetails, details.GetRawParameters(), plan)
 }
 
 // BindVariables gets the variable resolution context for a bind request.
@@ -439,11 +701,20 @@ func (svc *ServiceDefinition) BindVariables(instance models.ServiceInstanceDetai
 		return nil, err
 	}
 
+	// instances created before projects were recorded live in the default project
+	instanceProject := instance.ProjectId
+	if instanceProject == "" {
+		instanceProject = viper.GetString(projects.DefaultProjectProp)
+	}
+
 	appGuid := ""
 	if details.BindResource != nil {
 		appGuid = details.BindResource.AppGuid
 	}
 
+	// consumers of shared instances may be in other spaces than the instance
+	consumerOrg, consumerSpace := BindConsumer(details)
+
 	// The namespaces of these values roughly align with the OSB spec.
 	constants := map[string]interface{}{
 		// specified in the URL
@@ -454,14 +725,21 @@ func (svc *ServiceDefinition) BindVariables(instance models.ServiceInstanceDetai
 		// Note: the value in instance is considered the official record so values
 		// are pulled from there rather than the request. In a future version of OSB
 		// the duplicate sending of fields is likely to be removed.
-		"request.plan_id":         instance.PlanId,
-		"request.service_id":      instance.ServiceId,
-		"request.app_guid":        appGuid,
-		"request.plan_properties": plan.GetServiceProperties(),
+		"request.plan_id":           instance.PlanId,
+		"request.service_id":        instance.ServiceId,
+		"request.app_guid":          appGuid,
+		"request.organization_guid": consumerOrg,
+		"request.space_guid":        consumerSpace,
+		"request.shared":            IsSharedBinding(instance.SpaceGuid, consumerSpace),
+		"request.plan_properties":   plan.GetServiceProperties(),
+
+		// specified by the operator
+		"request.keyless_principal": viper.GetString(KeylessPrincipalProp),
 
 		// specified by the existing instance
 		"instance.name":    instance.Name,
 		"instance.details": otherDetails,
+		"instance.project": instanceProject,
 	}
 
 	builder := varcontext.Builder().
@@ -16,8 +16,13 @@ package broker
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
+
 	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/naming"
+	"github.com/spf13/viper"
 )
 
 
@@ -74,4 +79,180 @@ func TestServiceDefinition_CheckProhibitUpdate(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestServiceDefinition_RoleWhitelist(t *testing.T) {
+	defaults := []string{"storage.objectViewer", "storage.objectAdmin"}
+
+	cases := map[string]struct {
+		defaultWhitelist []string
+		serviceWhitelist interface{}
+		globalWhitelist  interface{}
+		role             string
+		expected         []string
+		expectErr        bool
+	}{
+		"service default": {
+			defaultWhitelist: defaults,
+			role:             "storage.objectAdmin",
+			expected:         []string{"storage.objectViewer", "storage.objectAdmin"},
+		},
+		"service overrides default": {
+			defaultWhitelist: defaults,
+			serviceWhitelist: []string{"storage.objectViewer"},
+			role:             "storage.objectAdmin",
+			expected:         []string{"storage.objectViewer"},
+			expectErr:        true,
+		},
+		"comma delimited": {
+			defaultWhitelist: defaults,
+			serviceWhitelist: "storage.objectCreator, storage.objectViewer",
+			role:             "storage.objectViewer",
+			expected:         []string{"storage.objectCreator", "storage.objectViewer"},
+		},
+		"broker-wide applies without a service list": {
+			globalWhitelist: "pubsub.subscriber, pubsub.viewer",
+			role:            "pubsub.publisher",
+			expected:        []string{"pubsub.subscriber", "pubsub.viewer"},
+			expectErr:       true,
+		},
+		"broker-wide doesn't override the service default": {
+			defaultWhitelist: defaults,
+			globalWhitelist:  "pubsub.subscriber",
+			role:             "storage.objectAdmin",
+			expected:         []string{"storage.objectViewer", "storage.objectAdmin"},
+		},
+		"service overrides broker-wide": {
+			serviceWhitelist: []string{"pubsub.publisher"},
+			globalWhitelist:  "pubsub.subscriber",
+			role:             "pubsub.publisher",
+			expected:         []string{"pubsub.publisher"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			svcDef := ServiceDefinition{Name: "test-service", DefaultRoleWhitelist: tc.defaultWhitelist}
+			viper.Set(svcDef.RoleWhitelistProperty(), tc.serviceWhitelist)
+			viper.Set(GlobalRoleWhitelist, tc.globalWhitelist)
+			defer viper.Set(svcDef.RoleWhitelistProperty(), nil)
+			defer viper.Set(GlobalRoleWhitelist, nil)
+
+			if actual := svcDef.RoleWhitelist(); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Expected whitelist %v, got %v", tc.expected, actual)
+			}
+
+			if err := svcDef.ValidateRole(tc.role); (err != nil) != tc.expectErr {
+				t.Errorf("Expected error: %v, got: %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_ValidateKeyless(t *testing.T) {
+	cases := map[string]struct {
+		keylessBind bool
+		principal   string
+		expectErr   bool
+	}{
+		"keys allowed":                {keylessBind: false},
+		"keyless service":             {keylessBind: true, principal: "serviceAccount:wi@p.iam.gserviceaccount.com"},
+		"key-only service in keyless": {keylessBind: false, principal: "serviceAccount:wi@p.iam.gserviceaccount.com", expectErr: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			svcDef := ServiceDefinition{Name: "test-service", KeylessBind: tc.keylessBind}
+			viper.Set(KeylessPrincipalProp, tc.principal)
+			defer viper.Set(KeylessPrincipalProp, nil)
+
+			if err := svcDef.ValidateKeyless(); (err != nil) != tc.expectErr {
+				t.Errorf("Expected error: %v, got: %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_OperationTimeout(t *testing.T) {
+	svcDef := ServiceDefinition{Name: "test-service"}
+
+	cases := map[string]struct {
+		serviceTimeout interface{}
+		globalTimeout  interface{}
+		expected       time.Duration
+	}{
+		"unlimited":                     {expected: 0},
+		"broker-wide":                   {globalTimeout: "1h", expected: time.Hour},
+		"service overrides broker-wide": {serviceTimeout: "30m", globalTimeout: "1h", expected: 30 * time.Minute},
+		"service disables broker-wide":  {serviceTimeout: "0", globalTimeout: "1h", expected: 0},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if tc.serviceTimeout != nil {
+				viper.Set(svcDef.OperationTimeoutProperty("provision"), tc.serviceTimeout)
+				defer viper.Set(svcDef.OperationTimeoutProperty("provision"), nil)
+			}
+			if tc.globalTimeout != nil {
+				viper.Set(GlobalOperationTimeoutProperty("provision"), tc.globalTimeout)
+				defer viper.Set(GlobalOperationTimeoutProperty("provision"), nil)
+			}
+
+			if actual := svcDef.OperationTimeout("provision"); actual != tc.expected {
+				t.Errorf("Expected timeout %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_NameResources(t *testing.T) {
+	svcDef := ServiceDefinition{
+		Name:         "test-service",
+		ResourceName: &naming.Rule{Variable: "instance_name", Product: "cloudsql", Template: "csb-{{.instance_id}}"},
+	}
+	plan := ServicePlan{ServicePlan: brokerapi.ServicePlan{Name: "small"}}
+
+	cases := map[string]struct {
+		override  interface{}
+		params    string
+		expected  string
+		expectErr bool
+	}{
+		"default template": {
+			expected: `{"instance_name":"csb-instance-1"}`,
+		},
+		"keeps other parameters": {
+			params:   `{"tier":"db-f1-micro"}`,
+			expected: `{"instance_name":"csb-instance-1","tier":"db-f1-micro"}`,
+		},
+		"operator template": {
+			override: "pcf-{{.space_guid_short}}-{{.plan_name}}-{{.instance_id}}",
+			expected: `{"instance_name":"pcf-space-gu-small-instance-1"}`,
+		},
+		"user chose a name": {
+			override: "pcf-{{.instance_id}}",
+			params:   `{"instance_name":"mine"}`,
+			expected: `{"instance_name":"mine"}`,
+		},
+		"invalid name": {
+			override:  "PCF_{{.instance_id}}",
+			expectErr: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(svcDef.NamingTemplateProperty(), tc.override)
+			defer viper.Set(svcDef.NamingTemplateProperty(), nil)
+
+			details := brokerapi.ProvisionDetails{SpaceGUID: "space-guid-1", RawParameters: json.RawMessage(tc.params)}
+			actual, err := svcDef.NameResources("instance-1", details, plan)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Expected error? %t got %v", tc.expectErr, err)
+			}
+
+			if !tc.expectErr && string(actual) != tc.expected {
+				t.Errorf("Expected parameters %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -18,10 +18,19 @@ import (
 	"context"
 
 	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/preview"
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
 	"github.com/pivotal-cf/brokerapi"
 )
 
+// ProviderStore holds the records providers keep in the datastore of the
+// broker using them, e.g. Terraform state. db_service.Datastore implements it.
+type ProviderStore interface {
+	GetTerraformDeploymentById(ctx context.Context, id string) (*models.TerraformDeployment, error)
+	SaveTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error
+	ListJobs(ctx context.Context, target string) ([]models.Job, error)
+}
+
 //go:generate counterfeiter . ServiceProvider
 
 // ServiceProvider performs the actual provisoning/deprovisioning part of a service broker request.
@@ -59,3 +68,21 @@ type ServiceProvider interface {
 	// Return a nil error if you choose not to implement this function.
 	UpdateInstanceDetails(ctx context.Context, instance *models.ServiceInstanceDetails) error
 }
+
+// Importer is implemented by ServiceProviders that can adopt resources that
+// already exist as service instances rather than creating them. The provision
+// variables identify the resources to adopt, e.g. by their name.
+type Importer interface {
+	// Import brings the existing resources under the broker's management and
+	// returns the instance details like Provision does.
+	Import(ctx context.Context, provisionContext *varcontext.VarContext) (models.ServiceInstanceDetails, error)
+}
+
+// ResourcePreviewer is implemented by ServiceProviders that can list the
+// resources a provision would create without creating them or calling the
+// cloud.
+type ResourcePreviewer interface {
+	// PreviewResources lists the resources Provision would create with the
+	// provision variables.
+	PreviewResources(ctx context.Context, provisionContext *varcontext.VarContext) ([]preview.Resource, error)
+}
@@ -0,0 +1,39 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/platformcontext"
+)
+
+// BindConsumer returns the organization and space of the app or platform
+// resource a binding is for, either may be empty if the platform doesn't send
+// them. Shared instances are bound from spaces other than the one they were
+// created in.
+func BindConsumer(details brokerapi.BindDetails) (organizationGuid, spaceGuid string) {
+	ctx := platformcontext.Parse(details.RawContext)
+	if ctx.SpaceGuid == "" && details.BindResource != nil {
+		ctx.SpaceGuid = details.BindResource.SpaceGuid
+	}
+
+	return ctx.OrganizationGuid, ctx.SpaceGuid
+}
+
+// IsSharedBinding returns true if the binding's consumer is in a different
+// space than the instance. It's false if either space is unknown.
+func IsSharedBinding(instanceSpaceGuid, consumerSpaceGuid string) bool {
+	return instanceSpaceGuid != "" && consumerSpaceGuid != "" && instanceSpaceGuid != consumerSpaceGuid
+}
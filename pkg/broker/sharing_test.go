@@ -0,0 +1,129 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+)
+
+func TestBindConsumer(t *testing.T) {
+	cases := map[string]struct {
+		Details       brokerapi.BindDetails
+		ExpectedOrg   string
+		ExpectedSpace string
+	}{
+		"no context": {
+			Details: brokerapi.BindDetails{},
+		},
+		"cloud foundry context": {
+			Details:       brokerapi.BindDetails{RawContext: json.RawMessage(`{"platform":"cloudfoundry","organization_guid":"org-1","space_guid":"space-1"}`)},
+			ExpectedOrg:   "org-1",
+			ExpectedSpace: "space-1",
+		},
+		"bind resource space": {
+			Details:       brokerapi.BindDetails{BindResource: &brokerapi.BindResource{AppGuid: "app", SpaceGuid: "space-2"}},
+			ExpectedSpace: "space-2",
+		},
+		"context takes precedence": {
+			Details: brokerapi.BindDetails{
+				RawContext:   json.RawMessage(`{"organization_guid":"org-1","space_guid":"space-1"}`),
+				BindResource: &brokerapi.BindResource{SpaceGuid: "space-2"},
+			},
+			ExpectedOrg:   "org-1",
+			ExpectedSpace: "space-1",
+		},
+		"malformed context": {
+			Details:       brokerapi.BindDetails{RawContext: json.RawMessage(`[]`), BindResource: &brokerapi.BindResource{SpaceGuid: "space-2"}},
+			ExpectedSpace: "space-2",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			org, space := BindConsumer(tc.Details)
+			if org != tc.ExpectedOrg || space != tc.ExpectedSpace {
+				t.Errorf("Expected %q/%q, got %q/%q", tc.ExpectedOrg, tc.ExpectedSpace, org, space)
+			}
+		})
+	}
+}
+
+func TestIsSharedBinding(t *testing.T) {
+	cases := map[string]struct {
+		InstanceSpace string
+		ConsumerSpace string
+		Expected      bool
+	}{
+		"same space":             {InstanceSpace: "space-1", ConsumerSpace: "space-1", Expected: false},
+		"other space":            {InstanceSpace: "space-1", ConsumerSpace: "space-2", Expected: true},
+		"unknown consumer space": {InstanceSpace: "space-1", ConsumerSpace: "", Expected: false},
+		"unknown instance space": {InstanceSpace: "", ConsumerSpace: "space-2", Expected: false},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := IsSharedBinding(tc.InstanceSpace, tc.ConsumerSpace); actual != tc.Expected {
+				t.Errorf("Expected %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_BindVariables_consumer(t *testing.T) {
+	service := ServiceDefinition{
+		Id:    "00000000-0000-0000-0000-000000000000",
+		Name:  "shared-service",
+		Plans: []ServicePlan{{ServicePlan: brokerapi.ServicePlan{ID: "plan"}}},
+		BindComputedVariables: []varcontext.DefaultVariable{
+			{Name: "space", Default: "${request.space_guid}", Overwrite: true},
+			{Name: "shared", Default: "${request.shared}", Overwrite: true, Type: "boolean"},
+		},
+	}
+
+	instance := models.ServiceInstanceDetails{SpaceGuid: "space-1"}
+	details := brokerapi.BindDetails{RawContext: json.RawMessage(`{"organization_guid":"org-2","space_guid":"space-2"}`)}
+	vars, err := service.BindVariables(instance, "binding-id", details, &service.Plans[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if space := vars.GetString("space"); space != "space-2" {
+		t.Errorf("Expected the consumer's space, got %q", space)
+	}
+	if !vars.GetBool("shared") {
+		t.Error("Expected the binding to be shared")
+	}
+}
+
+func TestServiceDefinition_CatalogEntry_shareable(t *testing.T) {
+	for _, shareable := range []bool{true, false} {
+		service := ServiceDefinition{Id: "00000000-0000-0000-0000-000000000000", Name: "svc", Shareable: shareable}
+
+		entry, err := service.CatalogEntry()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual := entry.Metadata.Shareable != nil && *entry.Metadata.Shareable
+		if actual != shareable {
+			t.Errorf("Expected shareable metadata to be %v, got %v", shareable, actual)
+		}
+	}
+}
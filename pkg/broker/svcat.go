@@ -0,0 +1,88 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// planSchemas creates the JSONSchemas the Kubernetes Service Catalog expects
+// for the plan. Unlike createSchemas every schema is set, including update,
+// parameters the plan or operator fix are left out and the plan's constraints
+// are merged into the properties so the catalog validates the same things the
+// broker does.
+func (svc *ServiceDefinition) planSchemas(plan *ServicePlan) (*brokerapi.ServiceSchemas, error) {
+	constraints, err := svc.PlanConstraints(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	pinned, err := svc.ProvisionPinned()
+	if err != nil {
+		return nil, err
+	}
+
+	var provisionVars, updateVars []BrokerVariable
+	for _, variable := range svc.ProvisionInputVariables {
+		if _, ok := plan.ProvisionOverrides[variable.FieldName]; ok {
+			continue
+		}
+		if _, ok := pinned[variable.FieldName]; ok {
+			continue
+		}
+
+		provisionVars = append(provisionVars, variable)
+		if !variable.ProhibitUpdate {
+			update := variable
+			update.Required = false
+			updateVars = append(updateVars, update)
+		}
+	}
+
+	var bindVars []BrokerVariable
+	for _, variable := range svc.BindInputVariables {
+		if _, ok := plan.BindOverrides[variable.FieldName]; !ok {
+			bindVars = append(bindVars, variable)
+		}
+	}
+
+	return &brokerapi.ServiceSchemas{
+		Instance: brokerapi.ServiceInstanceSchema{
+			Create: brokerapi.Schema{Parameters: withConstraints(CreateJsonSchema(provisionVars), constraints)},
+			Update: brokerapi.Schema{Parameters: withConstraints(CreateJsonSchema(updateVars), constraints)},
+		},
+		Binding: brokerapi.ServiceBindingSchema{
+			Create: brokerapi.Schema{Parameters: CreateJsonSchema(bindVars)},
+		},
+	}, nil
+}
+
+// withConstraints merges the constraints into the schemas of the properties
+// they apply to, constraints on other parameters are ignored.
+func withConstraints(schema map[string]interface{}, constraints map[string]map[string]interface{}) map[string]interface{} {
+	properties := schema["properties"].(map[string]interface{})
+	for param, constraint := range constraints {
+		property, ok := properties[param].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for key, value := range constraint {
+			property[key] = value
+		}
+	}
+
+	return schema
+}
@@ -0,0 +1,120 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/svcat"
+	"github.com/spf13/viper"
+)
+
+func TestServiceDefinition_CatalogEntry_serviceCatalogCompatibility(t *testing.T) {
+	svc := ServiceDefinition{
+		Id:       "svc-id",
+		Name:     "svcat-test",
+		Bindable: true,
+		Plans: []ServicePlan{
+			{
+				ServicePlan:        brokerapi.ServicePlan{ID: "plan-id", Name: "small"},
+				ProvisionOverrides: map[string]interface{}{"tier": "db-f1-micro"},
+				BindOverrides:      map[string]interface{}{"role": "reader"},
+				Constraints:        map[string]map[string]interface{}{"disk_size": {"maximum": 100}},
+			},
+		},
+		ProvisionInputVariables: []BrokerVariable{
+			{FieldName: "name", Type: JsonTypeString, Required: true, ProhibitUpdate: true},
+			{FieldName: "disk_size", Type: JsonTypeInteger, Required: true},
+			{FieldName: "tier", Type: JsonTypeString},
+			{FieldName: "region", Type: JsonTypeString},
+		},
+		BindInputVariables: []BrokerVariable{
+			{FieldName: "role", Type: JsonTypeString},
+			{FieldName: "prefix", Type: JsonTypeString},
+		},
+	}
+
+	viper.Set(svcat.CompatibilityProp, true)
+	viper.Set(svc.ProvisionPinnedProperty(), `{"region":"us-central1"}`)
+	defer viper.Reset()
+
+	entry, err := svc.CatalogEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schemas, err := json.Marshal(entry.ToPlain().Plans[0].Schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		Schema          interface{}
+		ExpectedProps   []string
+		UnexpectedProps []string
+		Expected        []string
+	}{
+		"instance create": {
+			Schema:          entry.Plans[0].Schemas.Instance.Create.Parameters,
+			ExpectedProps:   []string{"name", "disk_size"},
+			UnexpectedProps: []string{"tier", "region"},
+			Expected:        []string{`"required":["disk_size","name"]`, `"maximum":100`},
+		},
+		"instance update": {
+			Schema:          entry.Plans[0].Schemas.Instance.Update.Parameters,
+			ExpectedProps:   []string{"disk_size"},
+			UnexpectedProps: []string{"name", "tier", "region"},
+			Expected:        []string{`"maximum":100`},
+		},
+		"binding create": {
+			Schema:          entry.Plans[0].Schemas.Binding.Create.Parameters,
+			ExpectedProps:   []string{"prefix"},
+			UnexpectedProps: []string{"role"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			serialized, err := json.Marshal(tc.Schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			properties := tc.Schema.(map[string]interface{})["properties"].(map[string]interface{})
+			for _, prop := range tc.ExpectedProps {
+				if _, ok := properties[prop]; !ok {
+					t.Errorf("Expected property %q in %s", prop, serialized)
+				}
+			}
+			for _, prop := range tc.UnexpectedProps {
+				if _, ok := properties[prop]; ok {
+					t.Errorf("Expected no property %q in %s", prop, serialized)
+				}
+			}
+			for _, expected := range tc.Expected {
+				if !strings.Contains(string(serialized), expected) {
+					t.Errorf("Expected %s in %s", expected, serialized)
+				}
+			}
+		})
+	}
+
+	if strings.Contains(string(schemas), `"parameters":null`) {
+		t.Errorf("Expected every schema to be set, got %s", schemas)
+	}
+}
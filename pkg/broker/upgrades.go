@@ -0,0 +1,93 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"fmt"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/upgrade"
+)
+
+// GlobalUpgradePolicy viper key for the broker-wide policy that controls
+// whether instances are upgraded to new maintenance_info versions
+const GlobalUpgradePolicy = "upgrade.policy"
+
+// UpgradePolicyProperty returns the Viper property name for the policy that
+// controls whether instances of this service are upgraded.
+func (svc *ServiceDefinition) UpgradePolicyProperty() string {
+	return fmt.Sprintf("service.%s.upgrade.policy", svc.Name)
+}
+
+// MaintenanceVersionProperty returns the Viper property name for the
+// maintenance_info version advertised for the service's plans.
+func (svc *ServiceDefinition) MaintenanceVersionProperty() string {
+	return fmt.Sprintf("service.%s.maintenance_info.version", svc.Name)
+}
+
+// UpgradePolicy returns the operator's upgrade policy for the service, the
+// per-service value takes precedence over the broker-wide value.
+func (svc *ServiceDefinition) UpgradePolicy() (upgrade.Policy, error) {
+	if policy := config.Current().GetString(svc.UpgradePolicyProperty()); policy != "" {
+		return upgrade.ParsePolicy(policy)
+	}
+
+	return upgrade.ParsePolicy(config.Current().GetString(GlobalUpgradePolicy))
+}
+
+// MaintenanceVersion returns the maintenance_info version of the service's
+// plans or an empty string if the operator hasn't set one.
+func (svc *ServiceDefinition) MaintenanceVersion() string {
+	return config.Current().GetString(svc.MaintenanceVersionProperty())
+}
+
+// MaintenanceInfo returns the maintenance_info advertised for the service's
+// plans or nil if the operator hasn't set a version.
+func (svc *ServiceDefinition) MaintenanceInfo() *brokerapi.MaintenanceInfo {
+	version := svc.MaintenanceVersion()
+	if version == "" {
+		return nil
+	}
+
+	return &brokerapi.MaintenanceInfo{
+		Public:  map[string]string{"version": version},
+		Private: version,
+	}
+}
+
+// ValidateMaintenanceInfo checks the maintenance_info a platform sent matches
+// the one advertised in the catalog. Platforms that don't send
+// maintenance_info are always allowed.
+func (svc *ServiceDefinition) ValidateMaintenanceInfo(requested brokerapi.MaintenanceInfo) error {
+	if len(requested.Public) == 0 && requested.Private == "" {
+		return nil
+	}
+
+	advertised := svc.MaintenanceInfo()
+	if advertised == nil {
+		return brokerapi.ErrMaintenanceInfoNilConflict
+	}
+
+	if requested.Private != "" && requested.Private != advertised.Private {
+		return brokerapi.ErrMaintenanceInfoConflict
+	}
+
+	if version, ok := requested.Public["version"]; ok && version != svc.MaintenanceVersion() {
+		return brokerapi.ErrMaintenanceInfoConflict
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/upgrade"
+	"github.com/spf13/viper"
+)
+
+func TestServiceDefinition_UpgradePolicy(t *testing.T) {
+	svcDef := ServiceDefinition{Name: "test-service"}
+
+	cases := map[string]struct {
+		servicePolicy interface{}
+		globalPolicy  interface{}
+		expected      upgrade.Policy
+		expectErr     bool
+	}{
+		"default": {
+			expected: upgrade.PolicyAutomatic,
+		},
+		"broker-wide": {
+			globalPolicy: "manual",
+			expected:     upgrade.PolicyManual,
+		},
+		"service overrides broker-wide": {
+			servicePolicy: "pinned",
+			globalPolicy:  "manual",
+			expected:      upgrade.PolicyPinned,
+		},
+		"unknown": {
+			servicePolicy: "sometimes",
+			expectErr:     true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(svcDef.UpgradePolicyProperty(), tc.servicePolicy)
+			viper.Set(GlobalUpgradePolicy, tc.globalPolicy)
+			defer viper.Set(svcDef.UpgradePolicyProperty(), nil)
+			defer viper.Set(GlobalUpgradePolicy, nil)
+
+			actual, err := svcDef.UpgradePolicy()
+			if (err != nil) != tc.expectErr {
+				t.Errorf("Expected error: %v, got: %v", tc.expectErr, err)
+			}
+
+			if actual != tc.expected {
+				t.Errorf("Expected policy %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_ValidateMaintenanceInfo(t *testing.T) {
+	svcDef := ServiceDefinition{Name: "test-service"}
+
+	cases := map[string]struct {
+		version   interface{}
+		requested brokerapi.MaintenanceInfo
+		expected  error
+	}{
+		"not sent": {
+			version: "2",
+		},
+		"not sent or advertised": {},
+		"matches": {
+			version:   "2",
+			requested: brokerapi.MaintenanceInfo{Public: map[string]string{"version": "2"}, Private: "2"},
+		},
+		"stale": {
+			version:   "2",
+			requested: brokerapi.MaintenanceInfo{Private: "1"},
+			expected:  brokerapi.ErrMaintenanceInfoConflict,
+		},
+		"stale public version": {
+			version:   "2",
+			requested: brokerapi.MaintenanceInfo{Public: map[string]string{"version": "1"}},
+			expected:  brokerapi.ErrMaintenanceInfoConflict,
+		},
+		"not advertised": {
+			requested: brokerapi.MaintenanceInfo{Private: "1"},
+			expected:  brokerapi.ErrMaintenanceInfoNilConflict,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(svcDef.MaintenanceVersionProperty(), tc.version)
+			defer viper.Set(svcDef.MaintenanceVersionProperty(), nil)
+
+			if err := svcDef.ValidateMaintenanceInfo(tc.requested); err != tc.expected {
+				t.Errorf("Expected error: %v, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_CatalogEntry_MaintenanceInfo(t *testing.T) {
+	svcDef := ServiceDefinition{
+		Id:    "00000000-0000-0000-0000-000000000000",
+		Name:  "test-service",
+		Plans: []ServicePlan{{ServicePlan: brokerapi.ServicePlan{ID: "plan-1", Name: "small"}}},
+	}
+
+	entry, err := svcDef.CatalogEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Plans[0].MaintenanceInfo != nil {
+		t.Errorf("Expected no maintenance_info, got %v", entry.Plans[0].MaintenanceInfo)
+	}
+
+	viper.Set(svcDef.MaintenanceVersionProperty(), "1.2.0")
+	defer viper.Set(svcDef.MaintenanceVersionProperty(), nil)
+
+	entry, err = svcDef.CatalogEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := entry.Plans[0].MaintenanceInfo
+	if info == nil || info.Private != "1.2.0" || info.Public["version"] != "1.2.0" {
+		t.Errorf("Expected maintenance_info version 1.2.0, got %v", info)
+	}
+}
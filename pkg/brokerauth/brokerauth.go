@@ -0,0 +1,156 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package brokerauth authenticates platform requests to the OSB API using
+// basic auth, static bearer tokens or JWTs issued by an OIDC provider.
+package brokerauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/spf13/viper"
+)
+
+const (
+	// BearerTokensFileProp is the viper key of a file holding the static
+	// bearer tokens platforms may use, one per line.
+	BearerTokensFileProp = "api.auth.bearer_tokens_file"
+
+	// OidcIssuerProp is the viper key of the issuer JWTs must come from. JWT
+	// authentication is disabled if it's empty.
+	OidcIssuerProp = "api.auth.oidc.issuer"
+
+	// OidcAudienceProp is the viper key of the audience JWTs must be issued
+	// for.
+	OidcAudienceProp = "api.auth.oidc.audience"
+
+	// OidcJwksUrlProp is the viper key of the URL of the issuer's signing
+	// keys. It's discovered from the issuer if it's empty.
+	OidcJwksUrlProp = "api.auth.oidc.jwks_url"
+)
+
+const notAuthorized = "Not Authorized"
+
+// Authenticator checks the credentials of a request.
+type Authenticator interface {
+	// Authenticate returns true if the request has valid credentials for
+	// this authenticator.
+	Authenticate(r *http.Request) bool
+}
+
+// Basic authenticates requests with a static username and password.
+type Basic struct {
+	username [sha256.Size]byte
+	password [sha256.Size]byte
+}
+
+// NewBasic creates a Basic authenticator.
+func NewBasic(username, password string) *Basic {
+	return &Basic{username: sha256.Sum256([]byte(username)), password: sha256.Sum256([]byte(password))}
+}
+
+// Authenticate implements Authenticator.
+func (b *Basic) Authenticate(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	u := sha256.Sum256([]byte(username))
+	p := sha256.Sum256([]byte(password))
+
+	// compare both so timing doesn't reveal which one is wrong
+	validUsername := subtle.ConstantTimeCompare(b.username[:], u[:]) == 1
+	validPassword := subtle.ConstantTimeCompare(b.password[:], p[:]) == 1
+	return ok && validUsername && validPassword
+}
+
+// Chain accepts requests any of its authenticators accept.
+type Chain []Authenticator
+
+// Authenticate implements Authenticator.
+func (c Chain) Authenticate(r *http.Request) bool {
+	for _, authenticator := range c {
+		if authenticator.Authenticate(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Wrap rejects requests the chain doesn't accept with 401 Unauthorized.
+func (c Chain) Wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.Authenticate(r) {
+			http.Error(w, notAuthorized, http.StatusUnauthorized)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// NewChainFromEnv creates a Chain from the basic auth credentials and the
+// bearer token and OIDC settings configured in viper. Basic auth is only used
+// if both the username and password are set.
+func NewChainFromEnv(logger lager.Logger, username, password string) (Chain, error) {
+	var chain Chain
+	if username != "" && password != "" {
+		chain = append(chain, NewBasic(username, password))
+	}
+
+	if path := viper.GetString(BearerTokensFileProp); path != "" {
+		tokens := &TokenFile{Path: path, Logger: logger.Session("bearer-tokens")}
+		if err := tokens.reload(); err != nil {
+			return nil, fmt.Errorf("couldn't read %s: %v", BearerTokensFileProp, err)
+		}
+		chain = append(chain, tokens)
+	}
+
+	if issuer := viper.GetString(OidcIssuerProp); issuer != "" {
+		audience := viper.GetString(OidcAudienceProp)
+		if audience == "" {
+			return nil, fmt.Errorf("%s must be set when %s is", OidcAudienceProp, OidcIssuerProp)
+		}
+
+		chain = append(chain, &Oidc{
+			Issuer:   issuer,
+			Audience: audience,
+			JwksUrl:  viper.GetString(OidcJwksUrlProp),
+			Client:   &http.Client{Timeout: 10 * time.Second},
+			Logger:   logger.Session("oidc"),
+		})
+	}
+
+	if len(chain) == 0 {
+		return nil, errors.New("no authentication is configured for the broker API, set a username and password, bearer tokens or an OIDC issuer")
+	}
+
+	return chain, nil
+}
+
+// bearerToken gets the token from the request's Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return strings.TrimSpace(header[len(prefix):]), true
+}
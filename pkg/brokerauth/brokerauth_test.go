@@ -0,0 +1,180 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokerauth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/spf13/viper"
+)
+
+func TestBasic_Authenticate(t *testing.T) {
+	basic := NewBasic("user", "pass")
+
+	cases := map[string]struct {
+		Username string
+		Password string
+		NoAuth   bool
+		Expected bool
+	}{
+		"valid":          {Username: "user", Password: "pass", Expected: true},
+		"wrong password": {Username: "user", Password: "nope"},
+		"wrong username": {Username: "admin", Password: "pass"},
+		"no credentials": {NoAuth: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+			if !tc.NoAuth {
+				req.SetBasicAuth(tc.Username, tc.Password)
+			}
+
+			if actual := basic.Authenticate(req); actual != tc.Expected {
+				t.Errorf("Expected %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestChain_Wrap(t *testing.T) {
+	chain := Chain{NewBasic("user", "pass"), &TokenFile{Path: writeTokens(t, "secret-token"), Logger: lager.NewLogger("test")}}
+	handler := chain.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	cases := map[string]struct {
+		Authorize      func(r *http.Request)
+		ExpectedStatus int
+	}{
+		"basic": {
+			Authorize:      func(r *http.Request) { r.SetBasicAuth("user", "pass") },
+			ExpectedStatus: http.StatusTeapot,
+		},
+		"bearer": {
+			Authorize:      func(r *http.Request) { r.Header.Set("Authorization", "Bearer secret-token") },
+			ExpectedStatus: http.StatusTeapot,
+		},
+		"invalid bearer": {
+			Authorize:      func(r *http.Request) { r.Header.Set("Authorization", "Bearer guess") },
+			ExpectedStatus: http.StatusUnauthorized,
+		},
+		"none": {
+			Authorize:      func(r *http.Request) {},
+			ExpectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+			tc.Authorize(req)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.ExpectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestNewChainFromEnv(t *testing.T) {
+	tokensFile := writeTokens(t, "secret-token")
+
+	cases := map[string]struct {
+		Username       string
+		Password       string
+		Props          map[string]interface{}
+		ExpectedLength int
+		ExpectedErr    string
+	}{
+		"basic": {
+			Username:       "user",
+			Password:       "pass",
+			ExpectedLength: 1,
+		},
+		"every method": {
+			Username:       "user",
+			Password:       "pass",
+			Props:          map[string]interface{}{BearerTokensFileProp: tokensFile, OidcIssuerProp: "https://uaa.example.com/oauth/token", OidcAudienceProp: "broker"},
+			ExpectedLength: 3,
+		},
+		"tokens without basic": {
+			Props:          map[string]interface{}{BearerTokensFileProp: tokensFile},
+			ExpectedLength: 1,
+		},
+		"nothing configured": {
+			ExpectedErr: "no authentication is configured",
+		},
+		"missing tokens file": {
+			Props:       map[string]interface{}{BearerTokensFileProp: filepath.Join(filepath.Dir(tokensFile), "missing")},
+			ExpectedErr: "couldn't read api.auth.bearer_tokens_file",
+		},
+		"issuer without audience": {
+			Props:       map[string]interface{}{OidcIssuerProp: "https://uaa.example.com/oauth/token"},
+			ExpectedErr: "api.auth.oidc.audience must be set",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			for k, v := range tc.Props {
+				viper.Set(k, v)
+				defer viper.Set(k, nil)
+			}
+
+			chain, err := NewChainFromEnv(lager.NewLogger("test"), tc.Username, tc.Password)
+			if tc.ExpectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ExpectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tc.ExpectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(chain) != tc.ExpectedLength {
+				t.Errorf("Expected %d authenticators, got %d", tc.ExpectedLength, len(chain))
+			}
+		})
+	}
+}
+
+// writeTokens writes a token file to a temporary directory that's removed
+// when the test finishes.
+func writeTokens(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", "brokerauth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "tokens")
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
@@ -0,0 +1,309 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokerauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// jwksRefreshInterval is how often signing keys are fetched again so
+	// rotated keys are picked up.
+	jwksRefreshInterval = time.Hour
+
+	// jwksMinRefreshInterval limits how often an unknown key ID causes the
+	// keys to be fetched again.
+	jwksMinRefreshInterval = time.Minute
+
+	// clockSkew is how far the issuer's clock may differ from the broker's.
+	clockSkew = time.Minute
+)
+
+// signingMethods are the algorithms tokens may be signed with.
+var signingMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// Oidc authenticates requests with JWT bearer tokens signed by an OIDC
+// provider. Tokens must come from the issuer, be issued for the audience
+// and not be expired. The provider's signing keys are fetched from its JWKS
+// URL and refreshed periodically so keys can be rotated without restarting
+// the broker.
+type Oidc struct {
+	Issuer   string
+	Audience string
+	// JwksUrl is discovered from the issuer's OpenID configuration if it's
+	// empty.
+	JwksUrl string
+	Client  *http.Client
+	Logger  lager.Logger
+
+	// now is used to get the current time, it can be replaced in tests.
+	now func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// Authenticate implements Authenticator.
+func (o *Oidc) Authenticate(r *http.Request) bool {
+	token, ok := bearerToken(r)
+	if !ok {
+		return false
+	}
+
+	if err := o.Validate(r.Context(), token); err != nil {
+		o.Logger.Info("rejected-token", lager.Data{"error": err.Error()})
+		return false
+	}
+
+	return true
+}
+
+// Validate returns an error if the token isn't a valid JWT for the issuer
+// and audience.
+func (o *Oidc) Validate(ctx context.Context, token string) error {
+	parser := &jwt.Parser{ValidMethods: signingMethods, SkipClaimsValidation: true}
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return o.key(ctx, kid)
+	})
+	if err != nil {
+		return err
+	}
+
+	now := o.currentTime()
+	switch {
+	case !claims.VerifyIssuer(o.Issuer, true):
+		return fmt.Errorf("token wasn't issued by %s", o.Issuer)
+	case !hasAudience(claims, o.Audience):
+		return fmt.Errorf("token wasn't issued for %s", o.Audience)
+	case !claims.VerifyExpiresAt(now.Add(-clockSkew).Unix(), true):
+		return errors.New("token is expired or has no expiry")
+	case !claims.VerifyNotBefore(now.Add(clockSkew).Unix(), false):
+		return errors.New("token isn't valid yet")
+	}
+
+	return nil
+}
+
+func (o *Oidc) currentTime() time.Time {
+	if o.now != nil {
+		return o.now()
+	}
+
+	return time.Now()
+}
+
+// hasAudience checks the aud claim, which may be a string or a list.
+func hasAudience(claims jwt.MapClaims, audience string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// key gets the signing key with the given ID, fetching the keys again if
+// they're old or the ID is unknown. Tokens without a key ID can be used if
+// the issuer has a single key.
+func (o *Oidc) key(ctx context.Context, kid string) (interface{}, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.currentTime()
+	age := now.Sub(o.fetchedAt)
+	_, known := o.keys[kid]
+	if o.keys == nil || age > jwksRefreshInterval || (!known && age > jwksMinRefreshInterval) {
+		keys, err := o.fetchKeys(ctx)
+		switch {
+		case err == nil:
+			o.keys = keys
+			o.fetchedAt = now
+		case o.keys == nil:
+			return nil, err
+		default:
+			// the keys we have are better than failing every request while
+			// the issuer is unavailable
+			o.Logger.Error("refreshing-signing-keys", err)
+		}
+	}
+
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// fetchKeys gets the issuer's signing keys by their IDs.
+func (o *Oidc) fetchKeys(ctx context.Context) (map[string]interface{}, error) {
+	jwksUrl := o.JwksUrl
+	if jwksUrl == "" {
+		var discovery struct {
+			JwksUri string `json:"jwks_uri"`
+		}
+		if err := o.getJson(ctx, strings.TrimSuffix(o.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, err
+		}
+		if discovery.JwksUri == "" {
+			return nil, fmt.Errorf("OpenID configuration of %s has no jwks_uri", o.Issuer)
+		}
+		jwksUrl = discovery.JwksUri
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := o.getJson(ctx, jwksUrl, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			o.Logger.Info("skipping-signing-key", lager.Data{"kid": jwk.Kid, "error": err.Error()})
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s has no usable signing keys", jwksUrl)
+	}
+
+	return keys, nil
+}
+
+func (o *Oidc) getJson(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching %s: unexpected response: %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// jsonWebKey is an RSA or elliptic curve public key in JWK format.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, errors.New("RSA exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(encoded string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,272 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokerauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// fakeIssuer serves OpenID configuration and signing keys.
+type fakeIssuer struct {
+	Server    *httptest.Server
+	Keys      map[string]interface{}
+	JwksCalls int
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	issuer := &fakeIssuer{Keys: make(map[string]interface{})}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"jwks_uri": issuer.Server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		issuer.JwksCalls++
+		var keys []map[string]string
+		for kid, key := range issuer.Keys {
+			switch typed := key.(type) {
+			case *rsa.PrivateKey:
+				keys = append(keys, map[string]string{
+					"kty": "RSA",
+					"kid": kid,
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(typed.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(typed.E)).Bytes()),
+				})
+			case *ecdsa.PrivateKey:
+				keys = append(keys, map[string]string{
+					"kty": "EC",
+					"kid": kid,
+					"crv": "P-256",
+					"x":   base64.RawURLEncoding.EncodeToString(typed.X.Bytes()),
+					"y":   base64.RawURLEncoding.EncodeToString(typed.Y.Bytes()),
+				})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	})
+
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Server.Close)
+	return issuer
+}
+
+func (f *fakeIssuer) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	var method jwt.SigningMethod = jwt.SigningMethodRS256
+	if _, ok := f.Keys[kid].(*ecdsa.PrivateKey); ok {
+		method = jwt.SigningMethodES256
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(f.Keys[kid])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func TestOidc_Validate(t *testing.T) {
+	issuer := newFakeIssuer(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.Keys["rsa"] = rsaKey
+	issuer.Keys["ec"] = ecKey
+
+	now := time.Now()
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": issuer.Server.URL,
+			"aud": []interface{}{"cloud_controller", "broker"},
+			"exp": now.Add(time.Hour).Unix(),
+		}
+	}
+
+	cases := map[string]struct {
+		Token       func() string
+		ExpectedErr string
+	}{
+		"rsa": {
+			Token: func() string { return issuer.sign(t, "rsa", validClaims()) },
+		},
+		"ec with string audience": {
+			Token: func() string {
+				claims := validClaims()
+				claims["aud"] = "broker"
+				return issuer.sign(t, "ec", claims)
+			},
+		},
+		"wrong issuer": {
+			Token: func() string {
+				claims := validClaims()
+				claims["iss"] = "https://attacker.example.com"
+				return issuer.sign(t, "rsa", claims)
+			},
+			ExpectedErr: "wasn't issued by",
+		},
+		"wrong audience": {
+			Token: func() string {
+				claims := validClaims()
+				claims["aud"] = "other"
+				return issuer.sign(t, "rsa", claims)
+			},
+			ExpectedErr: "wasn't issued for broker",
+		},
+		"expired": {
+			Token: func() string {
+				claims := validClaims()
+				claims["exp"] = now.Add(-time.Hour).Unix()
+				return issuer.sign(t, "rsa", claims)
+			},
+			ExpectedErr: "expired",
+		},
+		"no expiry": {
+			Token: func() string {
+				claims := validClaims()
+				delete(claims, "exp")
+				return issuer.sign(t, "rsa", claims)
+			},
+			ExpectedErr: "expired or has no expiry",
+		},
+		"not yet valid": {
+			Token: func() string {
+				claims := validClaims()
+				claims["nbf"] = now.Add(time.Hour).Unix()
+				return issuer.sign(t, "rsa", claims)
+			},
+			ExpectedErr: "isn't valid yet",
+		},
+		"wrong signature": {
+			Token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims())
+				token.Header["kid"] = "rsa"
+				signed, _ := token.SignedString(otherKey)
+				return signed
+			},
+			ExpectedErr: "verification error",
+		},
+		"unsigned": {
+			Token: func() string {
+				signed, _ := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				return signed
+			},
+			ExpectedErr: "signing method none is invalid",
+		},
+		"hmac with public key": {
+			Token: func() string {
+				signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("secret"))
+				return signed
+			},
+			ExpectedErr: "signing method HS256 is invalid",
+		},
+		"unknown key": {
+			Token: func() string {
+				issuer.Keys["rotated"] = otherKey
+				defer delete(issuer.Keys, "rotated")
+				return issuer.sign(t, "rotated", validClaims())
+			},
+			ExpectedErr: `unknown signing key "rotated"`,
+		},
+	}
+
+	oidc := &Oidc{Issuer: issuer.Server.URL, Audience: "broker", Logger: lager.NewLogger("test")}
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			err := oidc.Validate(context.Background(), tc.Token())
+			switch {
+			case tc.ExpectedErr == "" && err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			case tc.ExpectedErr != "" && (err == nil || !strings.Contains(err.Error(), tc.ExpectedErr)):
+				t.Errorf("Expected error containing %q, got %v", tc.ExpectedErr, err)
+			}
+		})
+	}
+}
+
+func TestOidc_keyRotation(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.Keys["old"] = oldKey
+
+	now := time.Now()
+	oidc := &Oidc{
+		Issuer:   issuer.Server.URL,
+		Audience: "broker",
+		JwksUrl:  issuer.Server.URL + "/keys",
+		Logger:   lager.NewLogger("test"),
+		now:      func() time.Time { return now },
+	}
+	claims := jwt.MapClaims{"iss": issuer.Server.URL, "aud": "broker", "exp": now.Add(time.Hour).Unix()}
+
+	if err := oidc.Validate(context.Background(), issuer.sign(t, "old", claims)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new key isn't fetched again immediately so forged key IDs can't be
+	// used to flood the issuer
+	issuer.Keys["new"] = newKey
+	if err := oidc.Validate(context.Background(), issuer.sign(t, "new", claims)); err == nil {
+		t.Error("Expected the new key to be unknown until the minimum refresh interval passes")
+	}
+
+	now = now.Add(2 * jwksMinRefreshInterval)
+	if err := oidc.Validate(context.Background(), issuer.sign(t, "new", claims)); err != nil {
+		t.Errorf("Expected the new key to be fetched, got %v", err)
+	}
+
+	if issuer.JwksCalls != 2 {
+		t.Errorf("Expected keys to be fetched twice, got %d", issuer.JwksCalls)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+	req.Header.Set("Authorization", "Bearer "+issuer.sign(t, "new", claims))
+	if !oidc.Authenticate(req) {
+		t.Error("Expected the request to be authenticated")
+	}
+}
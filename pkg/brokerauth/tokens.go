@@ -0,0 +1,101 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokerauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// TokenFile authenticates requests with static bearer tokens listed one per
+// line in a file. The file is read again when it changes so tokens can be
+// rotated without restarting the broker; list the old and new tokens until
+// every platform uses the new one.
+type TokenFile struct {
+	Path   string
+	Logger lager.Logger
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	hashes  [][sha256.Size]byte
+}
+
+// Authenticate implements Authenticator.
+func (f *TokenFile) Authenticate(r *http.Request) bool {
+	token, ok := bearerToken(r)
+	if !ok || token == "" {
+		return false
+	}
+
+	if err := f.reload(); err != nil {
+		// keep using the tokens that were last read rather than locking
+		// every platform out while the file is replaced
+		f.Logger.Error("reading-bearer-tokens", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	hash := sha256.Sum256([]byte(token))
+	valid := false
+	for _, candidate := range f.hashes {
+		if subtle.ConstantTimeCompare(candidate[:], hash[:]) == 1 {
+			valid = true
+		}
+	}
+
+	return valid
+}
+
+// reload reads the file if it changed since it was last read.
+func (f *TokenFile) reload() error {
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size && f.hashes != nil {
+		return nil
+	}
+
+	contents, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return err
+	}
+
+	hashes := [][sha256.Size]byte{}
+	for _, line := range strings.Split(string(contents), "\n") {
+		if token := strings.TrimSpace(line); token != "" && !strings.HasPrefix(token, "#") {
+			hashes = append(hashes, sha256.Sum256([]byte(token)))
+		}
+	}
+
+	f.hashes = hashes
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokerauth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+func TestTokenFile_Authenticate(t *testing.T) {
+	path := writeTokens(t, "# platform tokens\nold-token\n\n  new-token  \n")
+	tokens := &TokenFile{Path: path, Logger: lager.NewLogger("test")}
+
+	authenticate := func(header string) bool {
+		req := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		return tokens.Authenticate(req)
+	}
+
+	cases := map[string]struct {
+		Header   string
+		Expected bool
+	}{
+		"old token":        {Header: "Bearer old-token", Expected: true},
+		"new token":        {Header: "bearer new-token", Expected: true},
+		"unknown token":    {Header: "Bearer other-token"},
+		"comment":          {Header: "Bearer # platform tokens"},
+		"empty token":      {Header: "Bearer "},
+		"basic auth":       {Header: "Basic b2xkLXRva2Vu"},
+		"no authorization": {},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := authenticate(tc.Header); actual != tc.Expected {
+				t.Errorf("Expected %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+
+	// rotate the old token out, the file's modification time is moved forward
+	// so the change is noticed on filesystems with coarse timestamps
+	if err := ioutil.WriteFile(path, []byte("new-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if authenticate("Bearer old-token") {
+		t.Error("Expected the rotated token to be rejected")
+	}
+	if !authenticate("Bearer new-token") {
+		t.Error("Expected the new token to be accepted")
+	}
+
+	// tokens that were last read keep working if the file goes away
+	os.Remove(path)
+	if !authenticate("Bearer new-token") {
+		t.Error("Expected the last read tokens to be used when the file is missing")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
 	"github.com/pivotal/cloud-service-broker/pkg/client"
 	"github.com/pivotal/cloud-service-broker/pkg/generator"
+	"github.com/pivotal/cloud-service-broker/pkg/lint"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/tf"
 	"github.com/pivotal/cloud-service-broker/pkg/server"
 	"github.com/pivotal/cloud-service-broker/utils/stream"
@@ -161,6 +162,28 @@ func Validate(pack string) error {
 	return brokerPak.Validate()
 }
 
+// Lint checks the brokerpak's service definitions for risky defaults using
+// the rule severities configured in viper.
+func Lint(pack string) (*lint.Report, error) {
+	brokerPak, err := OpenBrokerPak(pack)
+	if err != nil {
+		return nil, err
+	}
+	defer brokerPak.Close()
+
+	services, err := brokerPak.Services()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list services: %v", err)
+	}
+
+	linter, err := lint.NewLinterFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return linter.Lint(services), nil
+}
+
 // RegisterAll fetches all brokerpaks from the settings file and registers them
 // with the given registry.
 func RegisterAll(registry broker.BrokerRegistry) error {
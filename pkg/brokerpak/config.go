@@ -23,6 +23,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/pivotal/cloud-service-broker/pkg/config"
 	"github.com/pivotal/cloud-service-broker/pkg/toggles"
 	"github.com/pivotal/cloud-service-broker/pkg/validation"
 	"github.com/pivotal/cloud-service-broker/utils"
@@ -41,9 +42,11 @@ const (
 var loadBuiltinToggle = toggles.Features.Toggle("enable-builtin-brokerpaks", true, `Load brokerpaks that are built-in to the software.`)
 
 func init() {
-	viper.SetDefault(brokerpakSourcesKey, "{}")
-	viper.SetDefault(brokerpakConfigKey, "{}")
-	viper.SetDefault(brokerpakBuiltinPathKey, BuiltinPakLocation)
+	config.Register(
+		config.Property{Key: brokerpakSourcesKey, Kind: config.Json, Default: "{}"},
+		config.Property{Key: brokerpakConfigKey, Kind: config.Json, Default: "{}", Sensitive: true},
+		config.Property{Key: brokerpakBuiltinPathKey, Kind: config.String, Default: BuiltinPakLocation},
+	)
 }
 
 // BrokerpakSourceConfig represents a single configuration of a brokerpak.
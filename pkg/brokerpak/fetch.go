@@ -25,11 +25,9 @@ import (
 	"time"
 
 	"cloud.google.com/go/storage"
-	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
 	"github.com/pivotal/cloud-service-broker/utils/stream"
 	getter "github.com/hashicorp/go-getter"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/option"
 )
 
 // fetchArchive uses go-getter to download archives. By default go-getter
@@ -121,12 +119,12 @@ func (gsGetter) objectAt(client *storage.Client, u *url.URL) *storage.ObjectHand
 }
 
 func (gsGetter) client(ctx context.Context) (*storage.Client, error) {
-	creds, err := google.CredentialsFromJSON(ctx, []byte(utils.GetServiceAccountJson()), storage.ScopeReadOnly)
+	clients, err := gcpclient.NewFactoryFromEnv()
 	if err != nil {
-		return nil, errors.New("couldn't get JSON credentials from the enviornment")
+		return nil, fmt.Errorf("couldn't get Google Cloud credentials: %v", err)
 	}
 
-	client, err := storage.NewClient(ctx, option.WithCredentials(creds), option.WithUserAgent(utils.CustomUserAgent))
+	client, err := storage.NewClient(ctx, clients.Options(ctx, "storage")...)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't connect to Cloud Storage: %v", err)
 	}
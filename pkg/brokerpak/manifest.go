@@ -165,6 +165,9 @@ func (m *Manifest) packBinaries(tmp string) error {
 func clearRefs(sd *tf.TfServiceDefinitionV1Action) {
 	sd.TemplateRef = ""
 	sd.TemplateRefs = make(map[string]string)
+	for i := range sd.Seeds {
+		sd.Seeds[i].FileRefs = nil
+	}
 }
 
 func (m *Manifest) packDefinitions(tmp, base string) error {
@@ -22,6 +22,8 @@ import (
 
 	"code.cloudfoundry.org/lager"
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/config/secrets"
+	"github.com/pivotal/cloud-service-broker/pkg/lint"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/tf"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/tf/wrapper"
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
@@ -69,6 +71,10 @@ func (r *Registrar) Register(registry broker.BrokerRegistry) error {
 			return err
 		}
 
+		if err := r.lint(services, pak, registerLogger); err != nil {
+			return fmt.Errorf("brokerpak %q: %v", name, err)
+		}
+
 		defns, err := r.toDefinitions(services, pak, executor)
 		if err != nil {
 			return err
@@ -81,6 +87,9 @@ func (r *Registrar) Register(registry broker.BrokerRegistry) error {
 		if manifest, err := brokerPak.Manifest(); err == nil {
 			for env, config := range manifest.EnvConfigMapping {
 				viper.BindEnv(config, env)				
+				if err := secrets.DecryptEnv(env); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -110,6 +119,32 @@ func (Registrar) toDefinitions(services []tf.TfServiceDefinitionV1, config Broke
 	return out, nil
 }
 
+// lint checks the services that will be registered for risky defaults. Error
+// findings stop the broker from starting, warnings are logged.
+func (Registrar) lint(services []tf.TfServiceDefinitionV1, config BrokerpakSourceConfig, logger lager.Logger) error {
+	linter, err := lint.NewLinterFromEnv()
+	if err != nil {
+		return err
+	}
+
+	toIgnore := utils.NewStringSet(config.ExcludedServicesSlice()...)
+	var included []tf.TfServiceDefinitionV1
+	for _, svc := range services {
+		if !toIgnore.Contains(svc.Id) {
+			included = append(included, svc)
+		}
+	}
+
+	report := linter.Lint(included)
+	for _, finding := range report.Findings {
+		if finding.Severity == lint.Warning {
+			logger.Info("lint-warning", lager.Data{"finding": finding.String()})
+		}
+	}
+
+	return report.Err()
+}
+
 func (r *Registrar) createExecutor(brokerPak *BrokerPakReader, vc *varcontext.VarContext) (wrapper.TerraformExecutor, error) {
 	dir, err := ioutil.TempDir("", "brokerpak")
 	if err != nil {
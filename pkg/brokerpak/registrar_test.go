@@ -15,6 +15,7 @@
 package brokerpak
 
 import (
+	"context"
 	"errors"
 	"os"
 	"os/exec"
@@ -22,6 +23,7 @@ import (
 	"reflect"
 	"testing"
 
+	"code.cloudfoundry.org/lager"
 	"github.com/spf13/viper"
 
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
@@ -62,7 +64,7 @@ func TestNewRegistrar(t *testing.T) {
 }
 
 func TestRegistrar_toDefinitions(t *testing.T) {
-	nopExecutor := func(c *exec.Cmd) (wrapper.ExecutionOutput, error) {
+	nopExecutor := func(ctx context.Context, c *exec.Cmd) (wrapper.ExecutionOutput, error) {
 		return wrapper.ExecutionOutput{}, nil
 	}
 
@@ -330,3 +332,44 @@ func TestRegistrar_walk(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistrar_lint(t *testing.T) {
+	publicDefn := func(id string) tf.TfServiceDefinitionV1 {
+		ex := tf.NewExampleTfServiceDefinition()
+		ex.Id = id
+		ex.ProvisionSettings.UserInputs = append(ex.ProvisionSettings.UserInputs, broker.BrokerVariable{
+			FieldName: "acl",
+			Type:      broker.JsonTypeString,
+			Details:   "The bucket ACL.",
+			Default:   "publicRead",
+		})
+		return ex
+	}
+
+	cases := map[string]struct {
+		Services    []tf.TfServiceDefinitionV1
+		Config      BrokerpakSourceConfig
+		ExpectError bool
+	}{
+		"clean": {
+			Services: []tf.TfServiceDefinitionV1{tf.NewExampleTfServiceDefinition()},
+		},
+		"lint error": {
+			Services:    []tf.TfServiceDefinitionV1{publicDefn("public-id")},
+			ExpectError: true,
+		},
+		"excluded service": {
+			Services: []tf.TfServiceDefinitionV1{publicDefn("public-id")},
+			Config:   BrokerpakSourceConfig{ExcludedServices: "public-id"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			err := Registrar{}.lint(tc.Services, tc.Config, lager.NewLogger("test"))
+			if (err != nil) != tc.ExpectError {
+				t.Errorf("Expected error %v, got %v", tc.ExpectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package catalogsync tells the platforms the broker is registered with to
+// fetch its catalog again when the catalog changes.
+package catalogsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+)
+
+// PlatformsProp is the viper key of the JSON list of platforms to notify.
+const PlatformsProp = "catalog_sync.platforms"
+
+func init() {
+	config.Register(config.Property{Key: PlatformsProp, Kind: config.Json, Default: "[]", Sensitive: true})
+}
+
+// SnapshotStore saves the catalog platforms were last told about.
+type SnapshotStore interface {
+	GetLatestCatalogSnapshot(ctx context.Context) (*models.CatalogSnapshot, error)
+	CreateCatalogSnapshot(ctx context.Context, snapshot *models.CatalogSnapshot) error
+}
+
+// databaseStore stores snapshots in the broker's database.
+type databaseStore struct{}
+
+func (databaseStore) GetLatestCatalogSnapshot(ctx context.Context) (*models.CatalogSnapshot, error) {
+	return db_service.GetLatestCatalogSnapshot(ctx)
+}
+
+func (databaseStore) CreateCatalogSnapshot(ctx context.Context, snapshot *models.CatalogSnapshot) error {
+	return db_service.CreateCatalogSnapshot(ctx, snapshot)
+}
+
+// Syncer compares the catalog against the one platforms were last told about
+// and asks them to fetch it again if it changed.
+type Syncer struct {
+	Platforms []Platform
+	Store     SnapshotStore
+	Logger    lager.Logger
+}
+
+// NewSyncerFromEnv creates a Syncer for the platforms configured in viper
+// that stores snapshots in the broker's database. It returns nil if no
+// platforms are configured.
+func NewSyncerFromEnv(logger lager.Logger) (*Syncer, error) {
+	var configs []PlatformConfig
+	if err := json.Unmarshal([]byte(viper.GetString(PlatformsProp)), &configs); err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", PlatformsProp, err)
+	}
+
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	syncer := &Syncer{Store: databaseStore{}, Logger: logger.Session("catalog-sync")}
+	for _, cfg := range configs {
+		platform, err := NewPlatform(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		syncer.Platforms = append(syncer.Platforms, platform)
+	}
+
+	return syncer, nil
+}
+
+// Sync notifies every platform if the catalog changed since the last
+// successful sync. The catalog is only recorded as synced once every platform
+// has been notified so failed platforms are retried on the next sync.
+// The returned Diff is empty if nothing changed.
+func (s *Syncer) Sync(ctx context.Context, catalog []brokerapi.Service) (*Diff, error) {
+	fingerprint, err := Fingerprint(catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &Diff{Fingerprint: fingerprint, Changes: []Change{}}
+
+	previous, err := s.Store.GetLatestCatalogSnapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get the last synced catalog: %v", err)
+	}
+
+	var previousCatalog []brokerapi.Service
+	if previous != nil {
+		if previous.Fingerprint == fingerprint {
+			return diff, nil
+		}
+
+		if err := json.Unmarshal([]byte(previous.Catalog), &previousCatalog); err != nil {
+			return nil, fmt.Errorf("couldn't parse the last synced catalog: %v", err)
+		}
+	}
+
+	diff.Changes = Compare(previousCatalog, catalog)
+	s.Logger.Info("catalog-changed", lager.Data{"fingerprint": fingerprint, "changes": diff.Changes})
+
+	var result *multierror.Error
+	for _, platform := range s.Platforms {
+		if err := platform.Resync(ctx, diff); err != nil {
+			s.Logger.Error("resync-failed", err, lager.Data{"platform": platform.Name()})
+			result = multierror.Append(result, fmt.Errorf("platform %q: %v", platform.Name(), err))
+			continue
+		}
+
+		s.Logger.Info("resync-requested", lager.Data{"platform": platform.Name()})
+	}
+
+	if err := result.ErrorOrNil(); err != nil {
+		return diff, err
+	}
+
+	serialized, err := json.Marshal(catalog)
+	if err != nil {
+		return diff, err
+	}
+
+	if err := s.Store.CreateCatalogSnapshot(ctx, &models.CatalogSnapshot{Catalog: string(serialized), Fingerprint: fingerprint}); err != nil {
+		return diff, fmt.Errorf("couldn't record the synced catalog: %v", err)
+	}
+
+	return diff, nil
+}
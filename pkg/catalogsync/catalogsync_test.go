@@ -0,0 +1,127 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalogsync
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+type memoryStore struct {
+	snapshots []models.CatalogSnapshot
+}
+
+func (m *memoryStore) GetLatestCatalogSnapshot(ctx context.Context) (*models.CatalogSnapshot, error) {
+	if len(m.snapshots) == 0 {
+		return nil, nil
+	}
+
+	return &m.snapshots[len(m.snapshots)-1], nil
+}
+
+func (m *memoryStore) CreateCatalogSnapshot(ctx context.Context, snapshot *models.CatalogSnapshot) error {
+	m.snapshots = append(m.snapshots, *snapshot)
+	return nil
+}
+
+type fakePlatform struct {
+	err   error
+	diffs []*Diff
+}
+
+func (f *fakePlatform) Name() string {
+	return "fake"
+}
+
+func (f *fakePlatform) Resync(ctx context.Context, diff *Diff) error {
+	f.diffs = append(f.diffs, diff)
+	return f.err
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	ctx := context.Background()
+	platform := &fakePlatform{}
+	store := &memoryStore{}
+	syncer := &Syncer{Platforms: []Platform{platform}, Store: store, Logger: lager.NewLogger("test")}
+
+	catalog := []brokerapi.Service{{ID: "svc-1", Name: "storage", Plans: []brokerapi.ServicePlan{{ID: "plan-1", Name: "standard"}}}}
+
+	diff, err := syncer.Sync(ctx, catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Changes) != 1 || diff.Changes[0].Kind != ServiceAdded {
+		t.Errorf("Expected the first sync to add the service, got %v", diff.Changes)
+	}
+
+	diff, err = syncer.Sync(ctx, catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.IsEmpty() || len(platform.diffs) != 1 {
+		t.Errorf("Expected an unchanged catalog not to be synced, got %v", diff.Changes)
+	}
+
+	// failed platforms are retried on the next sync
+	catalog[0].Plans = append(catalog[0].Plans, brokerapi.ServicePlan{ID: "plan-2", Name: "nearline"})
+	platform.err = errors.New("unavailable")
+	if _, err := syncer.Sync(ctx, catalog); err == nil {
+		t.Error("Expected platform errors to be returned")
+	}
+
+	platform.err = nil
+	diff, err = syncer.Sync(ctx, catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Changes) != 1 || diff.Changes[0].Kind != PlanAdded {
+		t.Errorf("Expected the plan to be added, got %v", diff.Changes)
+	}
+
+	if len(platform.diffs) != 3 || len(store.snapshots) != 2 {
+		t.Errorf("Expected 3 resyncs and 2 snapshots, got %d and %d", len(platform.diffs), len(store.snapshots))
+	}
+}
+
+func TestNewSyncerFromEnv(t *testing.T) {
+	logger := lager.NewLogger("test")
+
+	syncer, err := NewSyncerFromEnv(logger)
+	if err != nil || syncer != nil {
+		t.Errorf("Expected no syncer by default, got %v, %v", syncer, err)
+	}
+
+	viper.Set(PlatformsProp, `[{"type":"kubernetes","api_url":"https://k8s.example.com","broker_name":"csb"}]`)
+	defer viper.Set(PlatformsProp, nil)
+
+	syncer, err = NewSyncerFromEnv(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(syncer.Platforms) != 1 {
+		t.Errorf("Expected 1 platform, got %d", len(syncer.Platforms))
+	}
+
+	viper.Set(PlatformsProp, `not json`)
+	if _, err := NewSyncerFromEnv(logger); err == nil {
+		t.Error("Expected an error for malformed configuration")
+	}
+}
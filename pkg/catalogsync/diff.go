@@ -0,0 +1,170 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalogsync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// Kinds of catalog changes.
+const (
+	ServiceAdded   = "service-added"
+	ServiceRemoved = "service-removed"
+	ServiceChanged = "service-changed"
+	PlanAdded      = "plan-added"
+	PlanRemoved    = "plan-removed"
+	PlanChanged    = "plan-changed"
+)
+
+// Change describes a single difference between two catalogs.
+type Change struct {
+	Kind        string `json:"kind"`
+	ServiceId   string `json:"service_id"`
+	ServiceName string `json:"service_name"`
+	PlanId      string `json:"plan_id,omitempty"`
+	PlanName    string `json:"plan_name,omitempty"`
+}
+
+func (c Change) String() string {
+	if c.PlanId == "" {
+		return fmt.Sprintf("%s %s", c.Kind, c.ServiceName)
+	}
+
+	return fmt.Sprintf("%s %s/%s", c.Kind, c.ServiceName, c.PlanName)
+}
+
+// Diff holds the changes between the catalog platforms last saw and the
+// current one.
+type Diff struct {
+	// Fingerprint identifies the current catalog.
+	Fingerprint string `json:"fingerprint"`
+
+	Changes []Change `json:"changes"`
+}
+
+// IsEmpty returns true if the catalogs are the same.
+func (d *Diff) IsEmpty() bool {
+	return len(d.Changes) == 0
+}
+
+// Summary returns a short human readable description of the changes.
+func (d *Diff) Summary() string {
+	var changes []string
+	for _, change := range d.Changes {
+		changes = append(changes, change.String())
+	}
+
+	return strings.Join(changes, ", ")
+}
+
+// Fingerprint computes a digest of the catalog that changes whenever any part
+// of it that platforms display or validate against changes.
+func Fingerprint(catalog []brokerapi.Service) (string, error) {
+	serialized, err := json.Marshal(catalog)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(serialized)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Compare lists the changes needed to go from the old catalog to the new one.
+// Services and plans are matched by ID so renames are reported as changes.
+func Compare(oldCatalog, newCatalog []brokerapi.Service) []Change {
+	changes := []Change{}
+
+	oldServices := make(map[string]brokerapi.Service)
+	for _, svc := range oldCatalog {
+		oldServices[svc.ID] = svc
+	}
+
+	for _, svc := range newCatalog {
+		old, ok := oldServices[svc.ID]
+		delete(oldServices, svc.ID)
+
+		if !ok {
+			changes = append(changes, Change{Kind: ServiceAdded, ServiceId: svc.ID, ServiceName: svc.Name})
+			continue
+		}
+
+		oldWithoutPlans, newWithoutPlans := old, svc
+		oldWithoutPlans.Plans, newWithoutPlans.Plans = nil, nil
+		if !sameJson(oldWithoutPlans, newWithoutPlans) {
+			changes = append(changes, Change{Kind: ServiceChanged, ServiceId: svc.ID, ServiceName: svc.Name})
+		}
+
+		changes = append(changes, comparePlans(svc, old.Plans, svc.Plans)...)
+	}
+
+	// report removals in the order they appeared in the old catalog
+	for _, svc := range oldCatalog {
+		if _, ok := oldServices[svc.ID]; ok {
+			changes = append(changes, Change{Kind: ServiceRemoved, ServiceId: svc.ID, ServiceName: svc.Name})
+		}
+	}
+
+	return changes
+}
+
+func comparePlans(svc brokerapi.Service, oldPlans, newPlans []brokerapi.ServicePlan) []Change {
+	var changes []Change
+
+	oldById := make(map[string]brokerapi.ServicePlan)
+	for _, plan := range oldPlans {
+		oldById[plan.ID] = plan
+	}
+
+	for _, plan := range newPlans {
+		old, ok := oldById[plan.ID]
+		delete(oldById, plan.ID)
+
+		change := Change{ServiceId: svc.ID, ServiceName: svc.Name, PlanId: plan.ID, PlanName: plan.Name}
+		switch {
+		case !ok:
+			change.Kind = PlanAdded
+		case !sameJson(old, plan):
+			change.Kind = PlanChanged
+		default:
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	for _, plan := range oldPlans {
+		if _, ok := oldById[plan.ID]; ok {
+			changes = append(changes, Change{Kind: PlanRemoved, ServiceId: svc.ID, ServiceName: svc.Name, PlanId: plan.ID, PlanName: plan.Name})
+		}
+	}
+
+	return changes
+}
+
+// sameJson compares the serialized forms of the values so catalogs loaded
+// from storage match their in-memory equivalents.
+func sameJson(a, b interface{}) bool {
+	aJson, aErr := json.Marshal(a)
+	bJson, bErr := json.Marshal(b)
+
+	return aErr == nil && bErr == nil && bytes.Equal(aJson, bJson)
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalogsync
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+func TestCompare(t *testing.T) {
+	storage := brokerapi.Service{
+		ID:   "svc-1",
+		Name: "storage",
+		Plans: []brokerapi.ServicePlan{
+			{ID: "plan-1", Name: "standard", Description: "Standard storage"},
+			{ID: "plan-2", Name: "nearline", Description: "Nearline storage"},
+		},
+	}
+
+	withPlans := func(svc brokerapi.Service, plans ...brokerapi.ServicePlan) brokerapi.Service {
+		svc.Plans = plans
+		return svc
+	}
+
+	cases := map[string]struct {
+		Old      []brokerapi.Service
+		New      []brokerapi.Service
+		Expected []Change
+	}{
+		"unchanged": {
+			Old:      []brokerapi.Service{storage},
+			New:      []brokerapi.Service{storage},
+			Expected: []Change{},
+		},
+		"first sync": {
+			Old:      nil,
+			New:      []brokerapi.Service{storage},
+			Expected: []Change{{Kind: ServiceAdded, ServiceId: "svc-1", ServiceName: "storage"}},
+		},
+		"service removed": {
+			Old:      []brokerapi.Service{storage},
+			New:      []brokerapi.Service{},
+			Expected: []Change{{Kind: ServiceRemoved, ServiceId: "svc-1", ServiceName: "storage"}},
+		},
+		"service description changed": {
+			Old: []brokerapi.Service{storage},
+			New: []brokerapi.Service{func() brokerapi.Service {
+				svc := storage
+				svc.Description = "new"
+				return svc
+			}()},
+			Expected: []Change{{Kind: ServiceChanged, ServiceId: "svc-1", ServiceName: "storage"}},
+		},
+		"plan added": {
+			Old:      []brokerapi.Service{storage},
+			New:      []brokerapi.Service{withPlans(storage, storage.Plans[0], storage.Plans[1], brokerapi.ServicePlan{ID: "plan-3", Name: "coldline"})},
+			Expected: []Change{{Kind: PlanAdded, ServiceId: "svc-1", ServiceName: "storage", PlanId: "plan-3", PlanName: "coldline"}},
+		},
+		"plan removed": {
+			Old:      []brokerapi.Service{storage},
+			New:      []brokerapi.Service{withPlans(storage, storage.Plans[0])},
+			Expected: []Change{{Kind: PlanRemoved, ServiceId: "svc-1", ServiceName: "storage", PlanId: "plan-2", PlanName: "nearline"}},
+		},
+		"plan schema changed": {
+			Old: []brokerapi.Service{storage},
+			New: []brokerapi.Service{withPlans(storage, storage.Plans[0], brokerapi.ServicePlan{
+				ID:          "plan-2",
+				Name:        "nearline",
+				Description: "Nearline storage",
+				Schemas: &brokerapi.ServiceSchemas{
+					Instance: brokerapi.ServiceInstanceSchema{Create: brokerapi.Schema{Parameters: map[string]interface{}{"type": "object"}}},
+				},
+			})},
+			Expected: []Change{{Kind: PlanChanged, ServiceId: "svc-1", ServiceName: "storage", PlanId: "plan-2", PlanName: "nearline"}},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := Compare(tc.Old, tc.New)
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected changes %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	catalog := []brokerapi.Service{{ID: "svc-1", Name: "storage"}}
+
+	first, err := Fingerprint(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := Fingerprint([]brokerapi.Service{{ID: "svc-1", Name: "storage"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != second {
+		t.Errorf("Expected identical catalogs to have the same fingerprint, got %q and %q", first, second)
+	}
+
+	catalog[0].Name = "renamed"
+	changed, err := Fingerprint(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed == first {
+		t.Error("Expected the fingerprint to change when the catalog changes")
+	}
+}
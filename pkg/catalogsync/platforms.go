@@ -0,0 +1,246 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalogsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	// CloudFoundryType is the platform type of Cloud Foundry foundations.
+	CloudFoundryType = "cloudfoundry"
+
+	// KubernetesType is the platform type of Kubernetes clusters running the
+	// Service Catalog.
+	KubernetesType = "kubernetes"
+
+	// FingerprintAnnotation is set on Kubernetes brokers to the fingerprint of
+	// the catalog they were last told to fetch.
+	FingerprintAnnotation = "cloud-service-broker/catalog-fingerprint"
+
+	// ChangesAnnotation is set on Kubernetes brokers to a summary of the
+	// catalog changes they were last told to fetch.
+	ChangesAnnotation = "cloud-service-broker/catalog-changes"
+)
+
+// Platform is a marketplace that lists the broker's catalog.
+type Platform interface {
+	// Name identifies the platform in logs and errors.
+	Name() string
+
+	// Resync asks the platform to fetch the broker's catalog again.
+	Resync(ctx context.Context, diff *Diff) error
+}
+
+// PlatformConfig is the operator's configuration of a platform.
+type PlatformConfig struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	ApiUrl string `json:"api_url"`
+
+	// BrokerGuid is the GUID of the broker registered in Cloud Foundry.
+	BrokerGuid string `json:"broker_guid"`
+	// TokenUrl is the UAA token endpoint used to authenticate to Cloud Foundry.
+	TokenUrl     string `json:"token_url"`
+	ClientId     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+
+	// BrokerName is the name of the ClusterServiceBroker in Kubernetes.
+	BrokerName string `json:"broker_name"`
+	// Token is the bearer token used to authenticate to Kubernetes.
+	Token string `json:"token"`
+}
+
+// NewPlatform creates the platform described by the configuration.
+func NewPlatform(cfg PlatformConfig) (Platform, error) {
+	name := cfg.Name
+	if name == "" {
+		name = cfg.ApiUrl
+	}
+
+	type field struct{ name, value string }
+	required := []field{{"api_url", cfg.ApiUrl}}
+
+	switch cfg.Type {
+	case CloudFoundryType:
+		required = append(required, field{"broker_guid", cfg.BrokerGuid}, field{"token_url", cfg.TokenUrl}, field{"client_id", cfg.ClientId})
+	case KubernetesType:
+		required = append(required, field{"broker_name", cfg.BrokerName})
+	default:
+		return nil, fmt.Errorf("platform %q has unknown type %q, must be one of: %s, %s", name, cfg.Type, CloudFoundryType, KubernetesType)
+	}
+
+	var missing []string
+	for _, f := range required {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("platform %q is missing required fields: %s", name, strings.Join(missing, ", "))
+	}
+
+	apiUrl := strings.TrimSuffix(cfg.ApiUrl, "/")
+	if cfg.Type == CloudFoundryType {
+		credentials := clientcredentials.Config{
+			ClientID:     cfg.ClientId,
+			ClientSecret: cfg.ClientSecret,
+			TokenURL:     cfg.TokenUrl,
+		}
+
+		return &CloudFoundry{
+			name:       name,
+			ApiUrl:     apiUrl,
+			BrokerGuid: cfg.BrokerGuid,
+			Client:     credentials.Client(context.Background()),
+		}, nil
+	}
+
+	return &Kubernetes{
+		name:       name,
+		ApiUrl:     apiUrl,
+		BrokerName: cfg.BrokerName,
+		Token:      cfg.Token,
+		Client:     http.DefaultClient,
+	}, nil
+}
+
+// CloudFoundry triggers the equivalent of `cf update-service-broker` through
+// the v3 API.
+type CloudFoundry struct {
+	name       string
+	ApiUrl     string
+	BrokerGuid string
+
+	// Client must add the authorization to requests.
+	Client *http.Client
+}
+
+var _ Platform = (*CloudFoundry)(nil)
+
+// Name implements Platform.
+func (cf *CloudFoundry) Name() string {
+	return cf.name
+}
+
+// Resync implements Platform by updating the broker with no changes, which
+// makes Cloud Controller fetch the catalog again.
+func (cf *CloudFoundry) Resync(ctx context.Context, diff *Diff) error {
+	url := fmt.Sprintf("%s/v3/service_brokers/%s", cf.ApiUrl, cf.BrokerGuid)
+	req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader("{}"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return doRequest(cf.Client, req.WithContext(ctx), nil)
+}
+
+// Kubernetes bumps the relist counter of a ClusterServiceBroker so the
+// Service Catalog fetches the catalog again, and annotates it with the
+// changes.
+type Kubernetes struct {
+	name       string
+	ApiUrl     string
+	BrokerName string
+	Token      string
+	Client     *http.Client
+}
+
+var _ Platform = (*Kubernetes)(nil)
+
+// Name implements Platform.
+func (k *Kubernetes) Name() string {
+	return k.name
+}
+
+type clusterServiceBroker struct {
+	Metadata struct {
+		Annotations map[string]string `json:"annotations,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		RelistRequests int64 `json:"relistRequests"`
+	} `json:"spec"`
+}
+
+// Resync implements Platform.
+func (k *Kubernetes) Resync(ctx context.Context, diff *Diff) error {
+	url := fmt.Sprintf("%s/apis/servicecatalog.k8s.io/v1beta1/clusterservicebrokers/%s", k.ApiUrl, k.BrokerName)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	var current clusterServiceBroker
+	if err := doRequest(k.Client, k.authorize(req.WithContext(ctx)), &current); err != nil {
+		return err
+	}
+
+	var patch clusterServiceBroker
+	patch.Metadata.Annotations = map[string]string{
+		FingerprintAnnotation: diff.Fingerprint,
+		ChangesAnnotation:     diff.Summary(),
+	}
+	patch.Spec.RelistRequests = current.Spec.RelistRequests + 1
+
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	req, err = http.NewRequest(http.MethodPatch, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	return doRequest(k.Client, k.authorize(req.WithContext(ctx)), nil)
+}
+
+func (k *Kubernetes) authorize(req *http.Request) *http.Request {
+	if k.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.Token)
+	}
+
+	return req
+}
+
+// doRequest sends the request and decodes the response into out if it's not
+// nil. Responses other than 2xx are returned as errors.
+func doRequest(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: unexpected response: %s", req.Method, req.URL, resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalogsync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewPlatform(t *testing.T) {
+	cases := map[string]struct {
+		Config    PlatformConfig
+		ExpectErr bool
+	}{
+		"cloud foundry": {
+			Config: PlatformConfig{Type: CloudFoundryType, ApiUrl: "https://api.example.com", BrokerGuid: "guid", TokenUrl: "https://uaa.example.com/oauth/token", ClientId: "client"},
+		},
+		"kubernetes": {
+			Config: PlatformConfig{Type: KubernetesType, ApiUrl: "https://k8s.example.com", BrokerName: "csb"},
+		},
+		"unknown type": {
+			Config:    PlatformConfig{Type: "mainframe", ApiUrl: "https://example.com"},
+			ExpectErr: true,
+		},
+		"missing fields": {
+			Config:    PlatformConfig{Type: CloudFoundryType, ApiUrl: "https://api.example.com"},
+			ExpectErr: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			_, err := NewPlatform(tc.Config)
+			if hasErr := err != nil; hasErr != tc.ExpectErr {
+				t.Errorf("Expected error: %v, got %v", tc.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func TestCloudFoundry_Resync(t *testing.T) {
+	var method, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	cf := &CloudFoundry{ApiUrl: server.URL, BrokerGuid: "broker-guid", Client: server.Client()}
+	if err := cf.Resync(context.Background(), &Diff{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodPatch || path != "/v3/service_brokers/broker-guid" {
+		t.Errorf("Expected PATCH /v3/service_brokers/broker-guid, got %s %s", method, path)
+	}
+}
+
+func TestKubernetes_Resync(t *testing.T) {
+	var patch clusterServiceBroker
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"metadata":{"name":"csb"},"spec":{"relistRequests":4}}`))
+		case http.MethodPatch:
+			if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+				t.Error(err)
+			}
+		}
+	}))
+	defer server.Close()
+
+	k := &Kubernetes{ApiUrl: server.URL, BrokerName: "csb", Token: "token", Client: server.Client()}
+	diff := &Diff{Fingerprint: "abc", Changes: []Change{{Kind: PlanAdded, ServiceName: "storage", PlanId: "plan-1", PlanName: "standard"}}}
+	if err := k.Resync(context.Background(), diff); err != nil {
+		t.Fatal(err)
+	}
+
+	if auth != "Bearer token" {
+		t.Errorf("Expected bearer authorization, got %q", auth)
+	}
+
+	if patch.Spec.RelistRequests != 5 {
+		t.Errorf("Expected relist requests to be incremented to 5, got %d", patch.Spec.RelistRequests)
+	}
+
+	if patch.Metadata.Annotations[FingerprintAnnotation] != "abc" {
+		t.Errorf("Expected the fingerprint annotation to be set, got %v", patch.Metadata.Annotations)
+	}
+
+	if patch.Metadata.Annotations[ChangesAnnotation] != "plan-added storage/standard" {
+		t.Errorf("Expected the changes annotation to be set, got %v", patch.Metadata.Annotations)
+	}
+}
@@ -21,8 +21,10 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/pivotal-cf/brokerapi"
+	retries "github.com/pivotal/cloud-service-broker/pkg/retry"
 	"github.com/spf13/viper"
 )
 
@@ -35,6 +37,18 @@ const (
 	ClientsBrokerApiVersion = "2.13"
 )
 
+var (
+	httpRetryPolicy = retries.Policies.Policy("http", "Idempotent HTTP requests sent by the broker client.", retries.Defaults{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Retryable:      []retries.ErrorClass{retries.Network, retries.RateLimit},
+	})
+
+	httpClient = &http.Client{Transport: retries.NewTransport(httpRetryPolicy, nil)}
+)
+
 // NewClientFromEnv creates a new client from the client configuration properties.
 func NewClientFromEnv() (*Client, error) {
 	user := viper.GetString("api.user")
@@ -121,17 +135,38 @@ func (client *Client) LastOperation(instanceId string) *BrokerResponse {
 	return client.makeRequest(http.MethodGet, url, nil)
 }
 
+// Request sends a request to the given path, e.g. to check how the broker
+// handles malformed ones. The headers replace the default ones, empty values
+// remove them.
+func (client *Client) Request(method, path string, header http.Header, body interface{}) *BrokerResponse {
+	return client.makeRequestWithHeaders(method, path, header, body)
+}
+
 func (client *Client) makeRequest(method, path string, body interface{}) *BrokerResponse {
+	return client.makeRequestWithHeaders(method, path, nil, body)
+}
+
+func (client *Client) makeRequestWithHeaders(method, path string, header http.Header, body interface{}) *BrokerResponse {
 	br := BrokerResponse{}
 
 	req, err := client.newRequest(method, path, body)
+	if err == nil {
+		for key, values := range header {
+			req.Header.Del(key)
+			for _, value := range values {
+				if value != "" {
+					req.Header.Add(key, value)
+				}
+			}
+		}
+	}
 	br.UpdateRequest(req)
 	br.UpdateError(err)
 	if br.InError() {
 		return &br
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpClient.Do(req)
 
 	br.UpdateResponse(resp)
 	br.UpdateError(err)
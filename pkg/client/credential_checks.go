@@ -0,0 +1,188 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"time"
+
+	googlestorage "cloud.google.com/go/storage"
+	"github.com/pivotal/cloud-service-broker/pkg/preflight"
+	"github.com/pivotal/cloud-service-broker/pkg/serviceaccounts"
+	"github.com/pivotal/cloud-service-broker/utils"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+const (
+	// credentialCheckTimeout is how long checks are retried for, the roles
+	// and keys of new service accounts can take a minute to propagate.
+	credentialCheckTimeout = 2 * time.Minute
+	credentialCheckPeriod  = 10 * time.Second
+)
+
+// credentialCheck verifies the credentials of a binding work by using them,
+// e.g. to write an object to a bucket. Checks that don't apply to the
+// binding return an error created with preflight.Skip.
+type credentialCheck func(ctx context.Context, credentials map[string]interface{}) error
+
+// credentialChecks are keyed by the name of the service whose bindings they
+// check.
+var credentialChecks = map[string]credentialCheck{
+	"google-storage":            checkStorageCredentials,
+	"csb-google-storage-bucket": checkStorageCredentials,
+	"csb-google-kms":            checkKmsCredentials,
+}
+
+// checkCredentials runs the check of the service's bindings, retrying until
+// it passes or times out.
+func checkCredentials(ctx context.Context, serviceName string, credentials map[string]interface{}) error {
+	check, ok := credentialChecks[serviceName]
+	if !ok {
+		return preflight.Skip("there's no credential check for %s", serviceName)
+	}
+
+	var lastErr error
+	err := retry(credentialCheckTimeout, credentialCheckPeriod, func() (bool, error) {
+		lastErr = check(ctx, credentials)
+		if lastErr != nil && !preflight.IsSkip(lastErr) {
+			log.Printf("Credential check failed, retrying: %v", lastErr)
+			return true, nil
+		}
+
+		return false, lastErr
+	})
+	if lastErr != nil {
+		return lastErr
+	}
+
+	return err
+}
+
+// bindingKeyFile gets the service account key the binding created.
+func bindingKeyFile(credentials map[string]interface{}) ([]byte, error) {
+	key := serviceaccounts.KeyFile(credentials)
+	if key == nil {
+		return nil, preflight.Skip("the binding has no service account key")
+	}
+
+	return key, nil
+}
+
+// checkStorageCredentials writes, reads and deletes an object in the bucket.
+func checkStorageCredentials(ctx context.Context, credentials map[string]interface{}) error {
+	key, err := bindingKeyFile(credentials)
+	if err != nil {
+		return err
+	}
+
+	bucket, _ := credentials["bucket_name"].(string)
+	if bucket == "" {
+		return fmt.Errorf("the credentials have no bucket_name")
+	}
+
+	client, err := googlestorage.NewClient(ctx, option.WithCredentialsJSON(key), option.WithUserAgent(utils.CustomUserAgent))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	content := []byte("written by run-examples")
+	object := client.Bucket(bucket).Object(fmt.Sprintf("run-examples-%d", rand.Uint32()))
+
+	writer := object.NewWriter(ctx)
+	if _, err := writer.Write(content); err != nil {
+		writer.Close()
+		return fmt.Errorf("writing an object: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("writing an object: %v", err)
+	}
+
+	reader, err := object.NewReader(ctx)
+	if err != nil {
+		return fmt.Errorf("reading the object: %v", err)
+	}
+	read, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("reading the object: %v", err)
+	}
+	if !bytes.Equal(read, content) {
+		return fmt.Errorf("expected to read %q from the object, got %q", content, read)
+	}
+
+	if err := object.Delete(ctx); err != nil {
+		return fmt.Errorf("deleting the object: %v", err)
+	}
+
+	return nil
+}
+
+// checkKmsCredentials encrypts with a symmetric key and decrypts the result
+// if the binding's role allows it.
+func checkKmsCredentials(ctx context.Context, credentials map[string]interface{}) error {
+	if purpose, _ := credentials["purpose"].(string); purpose != "ENCRYPT_DECRYPT" {
+		return preflight.Skip("only symmetric keys are checked, the key's purpose is %q", purpose)
+	}
+
+	role, _ := credentials["role"].(string)
+	decrypts := role == "cloudkms.cryptoKeyEncrypterDecrypter"
+	if !decrypts && role != "cloudkms.cryptoKeyEncrypter" {
+		return preflight.Skip("the role %q can't encrypt", role)
+	}
+
+	key, err := bindingKeyFile(credentials)
+	if err != nil {
+		return err
+	}
+
+	keyName, _ := credentials["kms_key_name"].(string)
+	if keyName == "" {
+		return fmt.Errorf("the credentials have no kms_key_name")
+	}
+
+	service, err := cloudkms.NewService(ctx, option.WithCredentialsJSON(key), option.WithUserAgent(utils.CustomUserAgent))
+	if err != nil {
+		return err
+	}
+	keys := service.Projects.Locations.KeyRings.CryptoKeys
+
+	plaintext := base64.StdEncoding.EncodeToString([]byte("encrypted by run-examples"))
+	encrypted, err := keys.Encrypt(keyName, &cloudkms.EncryptRequest{Plaintext: plaintext}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("encrypting: %v", err)
+	}
+
+	if !decrypts {
+		return nil
+	}
+
+	decrypted, err := keys.Decrypt(keyName, &cloudkms.DecryptRequest{Ciphertext: encrypted.Ciphertext}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("decrypting: %v", err)
+	}
+	if decrypted.Plaintext != plaintext {
+		return fmt.Errorf("expected decrypting to return the encrypted plaintext")
+	}
+
+	return nil
+}
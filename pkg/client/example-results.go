@@ -0,0 +1,122 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pivotal/cloud-service-broker/pkg/preflight"
+)
+
+// Stages of running an example, in order.
+const (
+	StageProvision   = "provision"
+	StageBind        = "bind"
+	StageVerify      = "verify"
+	StageUnbind      = "unbind"
+	StageDeprovision = "deprovision"
+)
+
+var exampleStages = []string{StageProvision, StageBind, StageVerify, StageUnbind, StageDeprovision}
+
+// ExampleResult holds the status of each stage of running an example, one of
+// preflight.Passed, Failed or Skipped. Stages that didn't run are skipped.
+type ExampleResult struct {
+	Name   string            `json:"name"`
+	Stages map[string]string `json:"stages"`
+	Error  string            `json:"error,omitempty"`
+
+	err error
+}
+
+func newExampleResult(serviceExample CompleteServiceExample) ExampleResult {
+	result := ExampleResult{
+		Name:   fmt.Sprintf("%s/%s", serviceExample.ServiceName, serviceExample.ServiceExample.Name),
+		Stages: make(map[string]string),
+	}
+
+	for _, stage := range exampleStages {
+		result.Stages[stage] = preflight.Skipped
+	}
+
+	return result
+}
+
+// record sets the status of the stage from its error and returns true if it
+// passed. The first failure is the example's error.
+func (r *ExampleResult) record(stage string, err error) bool {
+	switch {
+	case err == nil:
+		r.Stages[stage] = preflight.Passed
+		return true
+	case preflight.IsSkip(err):
+		log.Printf("Skipped %s of %s: %v", stage, r.Name, err)
+		r.Stages[stage] = preflight.Skipped
+		return true
+	default:
+		log.Printf("Failed to %s %s: %v", stage, r.Name, err)
+		r.Stages[stage] = preflight.Failed
+		if r.err == nil {
+			r.err = fmt.Errorf("%s of %s failed: %v", stage, r.Name, err)
+			r.Error = r.err.Error()
+		}
+		return false
+	}
+}
+
+// Err returns the first failure of the example, nil if it passed.
+func (r ExampleResult) Err() error {
+	return r.err
+}
+
+// PrintExampleResults writes a table of the status of each stage of each
+// example, sorted by name.
+func PrintExampleResults(out io.Writer, results []ExampleResult) {
+	sorted := append([]ExampleResult(nil), results...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "EXAMPLE\t%s\n", strings.ToUpper(strings.Join(exampleStages, "\t")))
+	for _, result := range sorted {
+		statuses := make([]string, len(exampleStages))
+		for i, stage := range exampleStages {
+			statuses[i] = result.Stages[stage]
+		}
+		fmt.Fprintf(w, "%s\t%s\n", result.Name, strings.Join(statuses, "\t"))
+	}
+	w.Flush()
+}
+
+// examplesError summarizes the failed examples, it's nil if none failed.
+func examplesError(results []ExampleResult) error {
+	var failed []string
+	for _, result := range results {
+		if result.Err() != nil {
+			failed = append(failed, result.Error)
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	sort.Strings(failed)
+	return fmt.Errorf("%d of %d example(s) failed: %s", len(failed), len(results), strings.Join(failed, "; "))
+}
@@ -0,0 +1,51 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPrintExampleResults(t *testing.T) {
+	passed := newExampleResult(CompleteServiceExample{ServiceName: "b-service"})
+	for _, stage := range exampleStages {
+		passed.record(stage, nil)
+	}
+
+	failed := newExampleResult(CompleteServiceExample{ServiceName: "a-service"})
+	failed.record(StageProvision, errors.New("quota exceeded"))
+
+	out := &bytes.Buffer{}
+	PrintExampleResults(out, []ExampleResult{passed, failed})
+
+	expected := `EXAMPLE     PROVISION  BIND     VERIFY   UNBIND   DEPROVISION
+a-service/  failed     skipped  skipped  skipped  skipped
+b-service/  passed     passed   passed   passed   passed
+`
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	err := examplesError([]ExampleResult{passed, failed})
+	if err == nil || err.Error() != "1 of 2 example(s) failed: provision of a-service/ failed: quota exceeded" {
+		t.Errorf("Expected the failure to be summarized, got %v", err)
+	}
+
+	if err := examplesError([]ExampleResult{passed}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
@@ -15,6 +15,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -28,53 +29,57 @@ import (
 	"github.com/pivotal-cf/brokerapi"
 
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/preflight"
 )
 
 // RunExamplesForService runs all the examples for a given service name against
 // the service broker pointed to by client. All examples in the registry get run
 // if serviceName is blank. If exampleName is non-blank then only the example
-// with the given name is run.
+// with the given name is run. The result of each example is printed once
+// they've all run.
 func RunExamplesForService(allExamples []CompleteServiceExample, client *Client, serviceName, exampleName string, jobCount int) error {
 
 	rand.Seed(time.Now().UTC().UnixNano())
 
 	examples := make(chan CompleteServiceExample)
-	errors := make(chan error)
+	results := make(chan ExampleResult)
 	wg := &sync.WaitGroup{}
 
 	go func() {
 		defer close(examples)
 		for _, completeServiceExample := range FilterMatchingServiceExamples(allExamples, serviceName, exampleName) {
-			examples <- completeServiceExample		
+			examples <- completeServiceExample
 		}
 	}()
 
-	for i:=0; i<jobCount; i++ {
+	for i := 0; i < jobCount; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for example := range examples {
-				if err := RunExample(client, example); err != nil {
-					errors <- err
-				}
+				results <- RunExample(client, example)
 			}
 		}()
 	}
 
 	go func() {
 		wg.Wait()
-		close(errors)
+		close(results)
 	}()
 
-	var err error
-	for err = range errors {}
+	var all []ExampleResult
+	for result := range results {
+		all = append(all, result)
+	}
 
-	return err
+	PrintExampleResults(os.Stdout, all)
+	return examplesError(all)
 }
 
 // RunExamplesFromFile reads a json-encoded list of CompleteServiceExamples.
 // All examples in the list get run if serviceName is blank. If exampleName
-// is non-blank then only the example with the given name is run.
+// is non-blank then only the example with the given name is run. The result
+// of each example is printed once they've all run.
 func RunExamplesFromFile(client *Client, fileName, serviceName, exampleName string) error {
 
 	rand.Seed(time.Now().UTC().UnixNano())
@@ -92,13 +97,13 @@ func RunExamplesFromFile(client *Client, fileName, serviceName, exampleName stri
 	byteValue, _ := ioutil.ReadAll(jsonFile)
 	json.Unmarshal(byteValue, &allExamples)
 
+	var results []ExampleResult
 	for _, completeServiceExample := range FilterMatchingServiceExamples(allExamples, serviceName, exampleName) {
-		if err := RunExample(client, completeServiceExample); err != nil {
-			return err
-		}
+		results = append(results, RunExample(client, completeServiceExample))
 	}
 
-	return nil
+	PrintExampleResults(os.Stdout, results)
+	return examplesError(results)
 
 }
 
@@ -152,64 +157,59 @@ func FilterMatchingServiceExamples(allExamples []CompleteServiceExample, service
 }
 
 // RunExample runs a single example against the given service on the broker
-// pointed to by client.
-func RunExample(client *Client, serviceExample CompleteServiceExample) error {
+// pointed to by client. The binding's credentials are verified before it's
+// deleted and the instance is deleted even if binding fails.
+func RunExample(client *Client, serviceExample CompleteServiceExample) ExampleResult {
+	result := newExampleResult(serviceExample)
 
 	executor, err := newExampleExecutor(client, serviceExample)
 	if err != nil {
-		return err
+		result.record(StageProvision, err)
+		return result
 	}
 
 	executor.LogTestInfo()
 
-	// Cleanup the test if it fails partway through
-	defer func() {
+	if !result.record(StageProvision, executor.Provision()) {
 		log.Println("Cleaning up the environment")
-		executor.Unbind()
 		executor.Deprovision()
-	}()
-
-	if err := executor.Provision(); err != nil {
-		log.Printf("Failed to provision %v: %v", serviceExample.ServiceName, err)
-		return err
+		return result
 	}
 
 	bindResponse, bindErr := executor.Bind()
-	if bindErr != nil {
-		if serviceExample.BindCanFail {
-			log.Printf("WARNING: bind failed: %v, but marked 'can fail' so treated as warning.", bindErr)
-		} else {
-			log.Printf("Failed to bind %v: %v", serviceExample.ServiceName, bindErr)
-			return bindErr
-		}
-	} else if err := executor.Unbind(); err != nil {
-		log.Printf("Failed to unbind %v: %v", serviceExample.ServiceName, err)
-		return err
+	if bindErr != nil && serviceExample.BindCanFail {
+		log.Printf("WARNING: bind failed: %v, but marked 'can fail' so treated as warning.", bindErr)
+		result.Stages[StageBind] = preflight.Skipped
+	} else if result.record(StageBind, bindErr) {
+		result.record(StageVerify, verifyBinding(serviceExample, bindResponse))
+		result.record(StageUnbind, executor.Unbind())
 	}
 
-	if err := executor.Deprovision(); err != nil {
-		log.Printf("Failed to deprovision %v: %v", serviceExample.ServiceName, err)
+	result.record(StageDeprovision, executor.Deprovision())
+	return result
+}
+
+// verifyBinding checks the credentials of the binding match the JSON Schema
+// of the service's bind outputs and work, if the service has a credential
+// check.
+func verifyBinding(serviceExample CompleteServiceExample, bindResponse json.RawMessage) error {
+	var binding brokerapi.Binding
+	if err := json.Unmarshal(bindResponse, &binding); err != nil {
 		return err
 	}
 
-	if bindErr == nil {
-		// Check that the binding response has the same fields as expected
-		var binding brokerapi.Binding
-		err = json.Unmarshal(bindResponse, &binding)
-		if err != nil {
-			return err
-		}
-
-		credentialsEntry := binding.Credentials.(map[string]interface{})
+	credentialsEntry, ok := binding.Credentials.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("the binding's credentials aren't an object: %v", binding.Credentials)
+	}
 
-		if err := broker.ValidateVariablesAgainstSchema(credentialsEntry, serviceExample.ExpectedOutput); err != nil {
-			log.Printf("Error: results don't match JSON Schema: %v", err)
-			log.Printf("Schema: %v\n, Actual: %v", serviceExample.ExpectedOutput, credentialsEntry)
-			return err
-		}
+	if err := broker.ValidateVariablesAgainstSchema(credentialsEntry, serviceExample.ExpectedOutput); err != nil {
+		log.Printf("Error: results don't match JSON Schema: %v", err)
+		log.Printf("Schema: %v\n, Actual: %v", serviceExample.ExpectedOutput, credentialsEntry)
+		return err
 	}
 
-	return nil
+	return checkCredentials(context.Background(), serviceExample.ServiceName, credentialsEntry)
 }
 
 func retry(timeout, period time.Duration, function func() (tryAgain bool, err error)) error {
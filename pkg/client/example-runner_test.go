@@ -2,11 +2,16 @@ package client
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/preflight"
 )
 
 func ExampleGetAllCompleteServiceExamples_jsonSpec() {
@@ -374,3 +379,98 @@ func TestFilterMatchingServiceExamples(t *testing.T) {
 		})
 	}
 }
+
+func TestRunExample(t *testing.T) {
+	cases := map[string]struct {
+		BindStatus     int
+		BindCanFail    bool
+		Credentials    string
+		ExpectedStages map[string]string
+		ExpectedError  string
+	}{
+		"passes": {
+			BindStatus:  http.StatusCreated,
+			Credentials: `{"Email": "sa@p.iam.gserviceaccount.com"}`,
+			ExpectedStages: map[string]string{
+				StageProvision:   preflight.Passed,
+				StageBind:        preflight.Passed,
+				StageVerify:      preflight.Skipped,
+				StageUnbind:      preflight.Passed,
+				StageDeprovision: preflight.Passed,
+			},
+		},
+		"bind-fails": {
+			BindStatus: http.StatusInternalServerError,
+			ExpectedStages: map[string]string{
+				StageProvision:   preflight.Passed,
+				StageBind:        preflight.Failed,
+				StageVerify:      preflight.Skipped,
+				StageUnbind:      preflight.Skipped,
+				StageDeprovision: preflight.Passed,
+			},
+			ExpectedError: "bind of fake-service/example failed: Unexpected response code 500",
+		},
+		"bind-can-fail": {
+			BindStatus:  http.StatusInternalServerError,
+			BindCanFail: true,
+			ExpectedStages: map[string]string{
+				StageProvision:   preflight.Passed,
+				StageBind:        preflight.Skipped,
+				StageVerify:      preflight.Skipped,
+				StageUnbind:      preflight.Skipped,
+				StageDeprovision: preflight.Passed,
+			},
+		},
+		"credentials-mismatch-schema": {
+			BindStatus:  http.StatusCreated,
+			Credentials: `{}`,
+			ExpectedStages: map[string]string{
+				StageProvision:   preflight.Passed,
+				StageBind:        preflight.Passed,
+				StageVerify:      preflight.Failed,
+				StageUnbind:      preflight.Passed,
+				StageDeprovision: preflight.Passed,
+			},
+			ExpectedError: "verify of fake-service/example failed",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				binding := strings.Contains(r.URL.Path, "/service_bindings/")
+				switch {
+				case r.Method == http.MethodPut && binding:
+					w.WriteHeader(tc.BindStatus)
+					w.Write([]byte(`{"credentials": ` + tc.Credentials + `}`))
+				case r.Method == http.MethodPut:
+					w.WriteHeader(http.StatusCreated)
+					w.Write([]byte(`{}`))
+				default:
+					w.Write([]byte(`{}`))
+				}
+			}))
+			defer server.Close()
+
+			baseUrl, err := url.Parse(server.URL + "/v2/")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := RunExample(&Client{BaseUrl: baseUrl}, CompleteServiceExample{
+				ServiceExample: broker.ServiceExample{Name: "example", PlanId: "plan", BindCanFail: tc.BindCanFail},
+				ServiceName:    "fake-service",
+				ServiceId:      "service",
+				ExpectedOutput: broker.CreateJsonSchema([]broker.BrokerVariable{{Required: true, FieldName: "Email", Type: "string"}}),
+			})
+
+			if !reflect.DeepEqual(result.Stages, tc.ExpectedStages) {
+				t.Errorf("Expected stages %v, got %v", tc.ExpectedStages, result.Stages)
+			}
+
+			if err := result.Err(); (tc.ExpectedError == "") != (err == nil) || (err != nil && !strings.HasPrefix(err.Error(), tc.ExpectedError)) {
+				t.Errorf("Expected error %q, got %v", tc.ExpectedError, err)
+			}
+		})
+	}
+}
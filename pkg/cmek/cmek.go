@@ -0,0 +1,69 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmek validates the customer-managed encryption keys users choose to
+// encrypt the resources backing their instances.
+package cmek
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// KeyParameter is the provision parameter, or plan property, that services
+	// supporting customer-managed encryption keys read the key from.
+	KeyParameter = "kms_key_name"
+	// AllowedProjectsProp holds a comma delimited list of the projects keys may
+	// be in. Keys in any project are allowed if it's empty.
+	AllowedProjectsProp = "cmek.allowed_projects"
+)
+
+// keyPattern matches Cloud KMS key resource names, capturing the project.
+var keyPattern = regexp.MustCompile(`^projects/([^/]+)/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+$`)
+
+// Validate returns an error if the key isn't the resource name of a Cloud KMS
+// key in a project the operator allows. An empty key, meaning Google-managed
+// encryption, is always valid.
+func Validate(key string) error {
+	if key == "" {
+		return nil
+	}
+
+	matches := keyPattern.FindStringSubmatch(key)
+	if matches == nil {
+		return fmt.Errorf("%s %q must look like projects/PROJECT/locations/LOCATION/keyRings/RING/cryptoKeys/KEY", KeyParameter, key)
+	}
+
+	var allowed []string
+	for _, project := range strings.Split(viper.GetString(AllowedProjectsProp), ",") {
+		if project = strings.TrimSpace(project); project != "" {
+			allowed = append(allowed, project)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	for _, project := range allowed {
+		if project == matches[1] {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("keys in project %q are not permitted, allowed projects are: [%s]", matches[1], strings.Join(allowed, ", "))
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmek
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestValidate(t *testing.T) {
+	key := "projects/team-a/locations/us/keyRings/ring/cryptoKeys/key"
+
+	cases := map[string]struct {
+		Key         string
+		Allowed     string
+		ExpectError bool
+	}{
+		"empty key":              {Key: "", Allowed: "team-b"},
+		"any project":            {Key: key},
+		"allowed project":        {Key: key, Allowed: "team-b, team-a"},
+		"not allowed project":    {Key: key, Allowed: "team-b", ExpectError: true},
+		"key ring":               {Key: "projects/team-a/locations/us/keyRings/ring", ExpectError: true},
+		"key version":            {Key: key + "/cryptoKeyVersions/1", ExpectError: true},
+		"not a key":              {Key: "my-key", ExpectError: true},
+		"blank allowed projects": {Key: key, Allowed: " , "},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(AllowedProjectsProp, tc.Allowed)
+			defer viper.Set(AllowedProjectsProp, nil)
+
+			err := Validate(tc.Key)
+			if tc.ExpectError != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.ExpectError, err)
+			}
+		})
+	}
+}
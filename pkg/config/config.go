@@ -26,8 +26,22 @@ const (
 	credhubSkipSSLValidation = "credhub.skip_ssl_validation"
 	credhubCaCertFile = "credhub.ca_cert_file"
 	credhubStoreBindCredentials = "credhub.store_bind_credentials"
+	credhubDevModeOnly = "credhub.dev_mode_only"
 )
 
+func init() {
+	Register(
+		Property{Key: credhubURL, Kind: String, Env: "CH_CRED_HUB_URL"},
+		Property{Key: credhubUaaURL, Kind: String, Env: "CH_UAA_URL"},
+		Property{Key: credhubUaaClientName, Kind: String, Env: "CH_UAA_CLIENT_NAME"},
+		Property{Key: credhubUaaClientSecret, Kind: String, Env: "CH_UAA_CLIENT_SECRET", Sensitive: true},
+		Property{Key: credhubSkipSSLValidation, Kind: Boolean, Env: "CH_SKIP_SSL_VALIDATION"},
+		Property{Key: credhubCaCertFile, Kind: String, Env: "CH_CA_CERT_FILE"},
+		Property{Key: credhubStoreBindCredentials, Kind: Boolean, Env: "CH_STORE_BIND_CREDENTIALS"},
+		Property{Key: credhubDevModeOnly, Kind: String, Env: "DEV_MODE_ONLY"},
+	)
+}
+
 type CredStoreConfig struct {
 	CredHubURL           string `mapstructure:"url"`
 	UaaURL               string `mapstructure:"uaa_url"`
@@ -36,6 +50,10 @@ type CredStoreConfig struct {
 	SkipSSLValidation    bool   `mapstructure:"skip_ssl_validation"`
 	CaCertFile           string `mapstructure:"ca_cert_file"`
 	StoreBindCredentials bool   `mapstructure:"store_bind_credentials"`
+
+	// DevModeOnly is a directory credentials are written to instead of
+	// CredHub, for local development.
+	DevModeOnly string `mapstructure:"dev_mode_only"`
 }
 
 type Config struct {
@@ -44,14 +62,6 @@ type Config struct {
 
 func Parse() (*Config, error) {
 	c := Config{}
-	viper.BindEnv(credhubURL, "CH_CRED_HUB_URL")
-	viper.BindEnv(credhubUaaURL, "CH_UAA_URL")
-	viper.BindEnv(credhubUaaClientName, "CH_UAA_CLIENT_NAME")
-	viper.BindEnv(credhubUaaClientSecret, "CH_UAA_CLIENT_SECRET")
-	viper.BindEnv(credhubSkipSSLValidation, "CH_SKIP_SSL_VALIDATION")
-	viper.BindEnv(credhubCaCertFile, "CH_CA_CERT_FILE")
-	viper.BindEnv(credhubStoreBindCredentials, "CH_STORE_BIND_CREDENTIALS")
-
 	err := viper.Unmarshal(&c)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,228 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Kind is the type of a configuration property's value.
+type Kind string
+
+const (
+	String   Kind = "string"
+	Boolean  Kind = "boolean"
+	Integer  Kind = "integer"
+	Number   Kind = "number"
+	Duration Kind = "duration"
+	List     Kind = "list"
+	Json     Kind = "JSON"
+)
+
+// envPrefix is the prefix viper looks up properties in the environment with,
+// utils.EnvironmentVarPrefix.
+const envPrefix = "GSB_"
+
+// Property is a configuration property the broker reads. Properties are set
+// in the configuration file or with an environment variable named after the
+// key, e.g. GSB_JOBS_WORKERS for jobs.workers.
+type Property struct {
+	Key  string
+	Kind Kind
+
+	// Default is the value used if the property isn't set, if it's not nil.
+	Default interface{}
+
+	// Env is another environment variable the property can be set with, e.g.
+	// one set by the platform.
+	Env string
+
+	// Allowed holds the values the property can have, any value of its kind
+	// is allowed if it's empty.
+	Allowed []string
+
+	// Sensitive properties are redacted when the configuration is shown.
+	Sensitive bool
+}
+
+// EnvVar gets the environment variable the property can be set with.
+func (p Property) EnvVar() string {
+	return envPrefix + strings.NewReplacer(".", "_", "-", "_").Replace(strings.ToUpper(p.Key))
+}
+
+var (
+	propertiesMu sync.Mutex
+	properties   = map[string]Property{}
+)
+
+// Register declares properties the broker reads, sets their defaults and
+// binds their other environment variables. Packages register their
+// properties in init so the whole configuration can be validated on startup.
+func Register(props ...Property) {
+	propertiesMu.Lock()
+	defer propertiesMu.Unlock()
+
+	for _, p := range props {
+		if p.Env != "" {
+			viper.BindEnv(p.Key, p.Env)
+		}
+
+		if p.Default != nil {
+			viper.SetDefault(p.Key, p.Default)
+		}
+
+		properties[p.Key] = p
+	}
+}
+
+// Properties lists the registered properties sorted by key.
+func Properties() []Property {
+	propertiesMu.Lock()
+	defer propertiesMu.Unlock()
+
+	var out []Property
+	for _, p := range properties {
+		out = append(out, p)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// IsBrokerEnv reports whether the environment variable configures the
+// broker, i.e. it has the GSB_ prefix Viper reads automatically or is bound
+// to a registered property.
+func IsBrokerEnv(name string) bool {
+	if strings.HasPrefix(name, envPrefix) {
+		return true
+	}
+
+	propertiesMu.Lock()
+	defer propertiesMu.Unlock()
+
+	for _, p := range properties {
+		if p.Env == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func lookupProperty(key string) (Property, bool) {
+	propertiesMu.Lock()
+	defer propertiesMu.Unlock()
+
+	p, ok := properties[key]
+	return p, ok
+}
+
+// ValidationError lists the properties whose values are invalid.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Validate checks that the value of every registered property that's set
+// has the property's kind and is allowed. It returns a *ValidationError
+// describing each invalid property and how it can be set.
+func Validate() error {
+	return validate(viper.GetViper())
+}
+
+func validate(v *viper.Viper) error {
+	var problems []string
+	for _, p := range Properties() {
+		value := v.Get(p.Key)
+		if value == nil || value == "" {
+			continue
+		}
+
+		if problem := p.check(value); problem != "" {
+			shown := fmt.Sprintf("%q", fmt.Sprint(value))
+			if p.Sensitive {
+				shown = Redacted
+			}
+
+			names := p.EnvVar()
+			if p.Env != "" {
+				names = p.Env + " or " + names
+			}
+			problems = append(problems, fmt.Sprintf("%s (%s) %s, got %s", names, p.Key, problem, shown))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// check returns what's wrong with the value, or an empty string if it's
+// valid.
+func (p Property) check(value interface{}) string {
+	text := fmt.Sprint(value)
+
+	switch p.Kind {
+	case Boolean:
+		if _, ok := value.(bool); !ok {
+			if _, err := strconv.ParseBool(text); err != nil {
+				return "must be true or false"
+			}
+		}
+	case Integer:
+		if _, err := strconv.ParseInt(text, 10, 64); err != nil {
+			return "must be a whole number"
+		}
+	case Number:
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return "must be a number"
+		}
+	case Duration:
+		if _, ok := value.(time.Duration); !ok {
+			if _, err := time.ParseDuration(text); err != nil {
+				return "must be a duration with a unit, e.g. 30s, 5m or 1h"
+			}
+		}
+	case Json:
+		if s, ok := value.(string); ok && !json.Valid([]byte(s)) {
+			return "must be valid JSON"
+		}
+	}
+
+	if len(p.Allowed) > 0 {
+		for _, allowed := range p.Allowed {
+			if text == allowed {
+				return ""
+			}
+		}
+
+		return fmt.Sprintf("must be one of %s", strings.Join(p.Allowed, ", "))
+	}
+
+	return ""
+}
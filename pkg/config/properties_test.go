@@ -0,0 +1,111 @@
+// Copyright 2020 Pivotal Software, Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+
+	. "github.com/pivotal/cloud-service-broker/pkg/config"
+)
+
+var _ = Describe("Properties", func() {
+	BeforeEach(func() {
+		Register(
+			Property{Key: "test.workers", Kind: Integer, Default: 4},
+			Property{Key: "test.interval", Kind: Duration, Default: "1m"},
+			Property{Key: "test.enabled", Kind: Boolean},
+			Property{Key: "test.ratio", Kind: Number},
+			Property{Key: "test.rules", Kind: Json},
+			Property{Key: "test.mode", Kind: String, Allowed: []string{"fail", "warn"}},
+			Property{Key: "test.dsn", Kind: String, Env: "TEST_DSN", Sensitive: true},
+		)
+	})
+
+	AfterEach(func() {
+		for _, key := range []string{"test.workers", "test.interval", "test.enabled", "test.ratio", "test.rules", "test.mode", "test.dsn"} {
+			viper.Set(key, nil)
+		}
+	})
+
+	It("names the environment variable after the key", func() {
+		Expect(Property{Key: "api.tls.min-version"}.EnvVar()).To(Equal("GSB_API_TLS_MIN_VERSION"))
+	})
+
+	It("sets defaults", func() {
+		Expect(viper.GetInt("test.workers")).To(Equal(4))
+		Expect(viper.GetString("test.interval")).To(Equal("1m"))
+	})
+
+	It("binds other environment variables", func() {
+		os.Setenv("TEST_DSN", "hunter2")
+		defer os.Unsetenv("TEST_DSN")
+
+		Expect(viper.GetString("test.dsn")).To(Equal("hunter2"))
+	})
+
+	It("accepts valid values", func() {
+		viper.Set("test.workers", "8")
+		viper.Set("test.interval", "90s")
+		viper.Set("test.enabled", "true")
+		viper.Set("test.ratio", 0.5)
+		viper.Set("test.rules", `[{"max": 1}]`)
+		viper.Set("test.mode", "warn")
+
+		Expect(Validate()).To(Succeed())
+	})
+
+	It("reports every invalid value with how to set it", func() {
+		viper.Set("test.workers", "four")
+		viper.Set("test.interval", "90")
+		viper.Set("test.enabled", "sometimes")
+		viper.Set("test.ratio", "half")
+		viper.Set("test.rules", `[{"max": 1}`)
+		viper.Set("test.mode", "ignore")
+
+		err := Validate()
+		Expect(err).To(BeAssignableToTypeOf(&ValidationError{}))
+		Expect(err.(*ValidationError).Problems).To(ConsistOf(
+			`GSB_TEST_WORKERS (test.workers) must be a whole number, got "four"`,
+			`GSB_TEST_INTERVAL (test.interval) must be a duration with a unit, e.g. 30s, 5m or 1h, got "90"`,
+			`GSB_TEST_ENABLED (test.enabled) must be true or false, got "sometimes"`,
+			`GSB_TEST_RATIO (test.ratio) must be a number, got "half"`,
+			`GSB_TEST_RULES (test.rules) must be valid JSON, got "[{\"max\": 1}"`,
+			`GSB_TEST_MODE (test.mode) must be one of fail, warn, got "ignore"`,
+		))
+	})
+
+	It("doesn't show invalid sensitive values", func() {
+		Register(Property{Key: "test.dsn", Kind: Integer, Env: "TEST_DSN", Sensitive: true})
+		viper.Set("test.dsn", "hunter2")
+
+		err := Validate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("TEST_DSN or GSB_TEST_DSN (test.dsn) must be a whole number, got <redacted>"))
+		Expect(err.Error()).NotTo(ContainSubstring("hunter2"))
+	})
+
+	It("redacts sensitive values in the effective configuration", func() {
+		viper.Set("test.dsn", "hunter2")
+		viper.Set("test.mode", "fail")
+
+		test := Effective()["test"].(map[string]interface{})
+		Expect(test["dsn"]).To(Equal(Redacted))
+		Expect(test["mode"]).To(Equal("fail"))
+	})
+})
@@ -0,0 +1,92 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Redacted replaces sensitive configuration values.
+const Redacted = "<redacted>"
+
+// sensitiveKeyParts are substrings of configuration keys whose values are
+// redacted.
+var sensitiveKeyParts = []string{"password", "passphrase", "secret", "credentials", "private", "token", "key", "cert"}
+
+// sensitiveValueParts are substrings that mark a value as sensitive no matter
+// its key, e.g. service account keys or platform credentials embedded in JSON
+// configuration.
+var sensitiveValueParts = []string{"private_key", "BEGIN ", "enc:", "client_secret", `"token"`}
+
+// Effective gets the configuration the broker runs with, from the
+// configuration file, the environment and the defaults, with sensitive values
+// redacted.
+func Effective() map[string]interface{} {
+	return Redact(viper.AllSettings())
+}
+
+// Redact returns a copy of the settings with sensitive values replaced:
+// registered sensitive properties, keys that look like they hold credentials
+// and values that look like credentials.
+func Redact(settings map[string]interface{}) map[string]interface{} {
+	return redactMap("", settings)
+}
+
+func redactMap(prefix string, settings map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for k, v := range settings {
+		out[k] = redactValue(prefix+k, k, v)
+	}
+
+	return out
+}
+
+func redactValue(path, key string, value interface{}) interface{} {
+	if p, ok := lookupProperty(path); (ok && p.Sensitive) || isSensitiveKey(key) {
+		return Redacted
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return redactMap(path+".", v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = redactValue("", "", item)
+		}
+		return out
+	case string:
+		for _, part := range sensitiveValueParts {
+			if strings.Contains(v, part) {
+				return Redacted
+			}
+		}
+	}
+
+	return value
+}
+
+func isSensitiveKey(key string) bool {
+	lower := strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,127 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/spf13/viper"
+)
+
+// current holds the *viper.Viper reloadable settings are read from.
+var current atomic.Value
+
+// Current returns the configuration the reloadable settings, those of the
+// services and their plans, are read from. It's the global viper until the
+// first reload. Configurations are never written once they're current, so
+// they can be read while a reload builds the next one.
+func Current() *viper.Viper {
+	if v, ok := current.Load().(*viper.Viper); ok && v != nil {
+		return v
+	}
+
+	return viper.GetViper()
+}
+
+// SetCurrent replaces the configuration Current returns, nil restores the
+// global viper.
+func SetCurrent(v *viper.Viper) {
+	current.Store(v)
+}
+
+// Reloader reads the configuration file again so changes, e.g. new plans,
+// take effect without restarting the broker. The file is read into a new
+// configuration, which only replaces the current one if it's valid.
+//
+// Only settings read through Current are reloaded, the global viper is never
+// written after startup so everything else needs a restart to change.
+type Reloader struct {
+	// File is the configuration file, nothing is read if it's empty.
+	File string
+
+	// Decrypt replaces the encrypted values once the file is read.
+	Decrypt func(*viper.Viper) error
+
+	mu sync.Mutex
+}
+
+// NewReloader creates a Reloader for the configuration file the broker
+// started with.
+func NewReloader(file string, decrypt func(*viper.Viper) error) *Reloader {
+	return &Reloader{File: file, Decrypt: decrypt}
+}
+
+// Reload reads the configuration file, checks the registered properties are
+// valid, makes it current and calls apply, e.g. to rebuild the catalog. If
+// any of them fail the previous configuration is made current again and the
+// error is returned. Reloads run one at a time.
+func (r *Reloader) Reload(apply func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	previous := current.Load()
+	SetCurrent(next)
+	if err := apply(); err != nil {
+		current.Store(previous)
+		return err
+	}
+
+	return nil
+}
+
+// load reads and validates the configuration file into a new viper that
+// looks up the environment and registered properties like the global one.
+func (r *Reloader) load() (*viper.Viper, error) {
+	v := viper.New()
+	v.SetEnvPrefix(strings.TrimSuffix(envPrefix, "_"))
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	v.AutomaticEnv()
+	for _, p := range Properties() {
+		if p.Env != "" {
+			v.BindEnv(p.Key, p.Env)
+		}
+
+		if p.Default != nil {
+			v.SetDefault(p.Key, p.Default)
+		}
+	}
+
+	if r.File != "" {
+		v.SetConfigFile(r.File)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("can't read config: %v", err)
+		}
+	}
+
+	if r.Decrypt != nil {
+		if err := r.Decrypt(v); err != nil {
+			return nil, fmt.Errorf("can't decrypt config: %v", err)
+		}
+	}
+
+	if err := validate(v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 Pivotal Software, Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+
+	. "github.com/pivotal/cloud-service-broker/pkg/config"
+)
+
+var _ = Describe("Reloader", func() {
+	var (
+		dir      string
+		file     string
+		reloader *Reloader
+	)
+
+	write := func(content string) {
+		Expect(ioutil.WriteFile(file, []byte(content), 0600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		Register(Property{Key: "reload.workers", Kind: Integer})
+
+		var err error
+		dir, err = ioutil.TempDir("", "reload-test")
+		Expect(err).NotTo(HaveOccurred())
+		file = filepath.Join(dir, "config.yml")
+		write("reload:\n  workers: 2\n")
+
+		viper.SetConfigFile(file)
+		Expect(viper.ReadInConfig()).To(Succeed())
+
+		reloader = NewReloader(file, nil)
+	})
+
+	AfterEach(func() {
+		SetCurrent(nil)
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(strings.NewReader(""))
+		viper.SetConfigFile("")
+		os.RemoveAll(dir)
+	})
+
+	It("applies a valid configuration", func() {
+		write("reload:\n  workers: 4\n")
+
+		applied := 0
+		Expect(reloader.Reload(func() error {
+			applied = Current().GetInt("reload.workers")
+			return nil
+		})).To(Succeed())
+
+		Expect(applied).To(Equal(4))
+		Expect(Current().GetInt("reload.workers")).To(Equal(4))
+	})
+
+	It("doesn't write the global configuration", func() {
+		write("reload:\n  workers: 4\n")
+		Expect(reloader.Reload(func() error { return nil })).To(Succeed())
+
+		Expect(viper.GetInt("reload.workers")).To(Equal(2))
+	})
+
+	It("reads the environment", func() {
+		os.Setenv("GSB_RELOAD_WORKERS", "6")
+		defer os.Unsetenv("GSB_RELOAD_WORKERS")
+
+		Expect(reloader.Reload(func() error { return nil })).To(Succeed())
+		Expect(Current().GetInt("reload.workers")).To(Equal(6))
+	})
+
+	It("restores the previous configuration if a property is invalid", func() {
+		write("reload:\n  workers: four\n")
+
+		err := reloader.Reload(func() error {
+			Fail("an invalid configuration mustn't be applied")
+			return nil
+		})
+		Expect(err).To(BeAssignableToTypeOf(&ValidationError{}))
+		Expect(Current().GetInt("reload.workers")).To(Equal(2))
+	})
+
+	It("restores the previous configuration if it can't be applied", func() {
+		write("reload:\n  workers: 4\n")
+
+		err := reloader.Reload(func() error { return errors.New("bad plan") })
+		Expect(err).To(MatchError("bad plan"))
+		Expect(Current().GetInt("reload.workers")).To(Equal(2))
+	})
+
+	It("keeps the last configuration applied", func() {
+		write("reload:\n  workers: 4\n")
+		Expect(reloader.Reload(func() error { return nil })).To(Succeed())
+
+		write("reload: [")
+		Expect(reloader.Reload(func() error { return nil })).To(HaveOccurred())
+		Expect(Current().GetInt("reload.workers")).To(Equal(4))
+	})
+})
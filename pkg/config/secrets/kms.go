@@ -0,0 +1,75 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+)
+
+// kmsCipher encrypts data with a Cloud KMS symmetric crypto key.
+type kmsCipher struct {
+	keyName string
+	service *cloudkms.ProjectsLocationsKeyRingsCryptoKeysService
+}
+
+var _ Cipher = (*kmsCipher)(nil)
+
+// NewKmsCipher creates a Cipher backed by the given Cloud KMS crypto key using
+// the broker's root service account.
+func NewKmsCipher(keyName string) (Cipher, error) {
+	clients, err := gcpclient.NewFactoryFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	service, err := cloudkms.NewService(ctx, clients.Options(ctx, "cloudkms")...)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create KMS client: %v", err)
+	}
+
+	return &kmsCipher{
+		keyName: keyName,
+		service: cloudkms.NewProjectsLocationsKeyRingsCryptoKeysService(service),
+	}, nil
+}
+
+// Encrypt implements Cipher.
+func (k *kmsCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	resp, err := k.service.Encrypt(k.keyName, &cloudkms.EncryptRequest{
+		Plaintext: encoding.EncodeToString(plaintext),
+	}).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return encoding.DecodeString(resp.Ciphertext)
+}
+
+// Decrypt implements Cipher.
+func (k *kmsCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	resp, err := k.service.Decrypt(k.keyName, &cloudkms.DecryptRequest{
+		Ciphertext: encoding.EncodeToString(ciphertext),
+	}).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return encoding.DecodeString(resp.Plaintext)
+}
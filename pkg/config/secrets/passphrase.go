@@ -0,0 +1,108 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+var encoding = base64.StdEncoding
+
+// passphraseCipher encrypts data with AES-256-GCM using a key derived from a
+// passphrase with scrypt. Each value gets its own random salt and nonce which
+// are stored alongside the ciphertext.
+type passphraseCipher struct {
+	passphrase []byte
+}
+
+var _ Cipher = (*passphraseCipher)(nil)
+
+// NewPassphraseCipher creates a Cipher that derives its key from the given
+// passphrase.
+func NewPassphraseCipher(passphrase string) Cipher {
+	return &passphraseCipher{passphrase: []byte(passphrase)}
+}
+
+// Encrypt implements Cipher. The output is salt || nonce || ciphertext.
+func (p *passphraseCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	salt := make([]byte, saltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := p.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := append(salt, nonce...)
+	return aead.Seal(out, nonce, plaintext, nil), nil
+}
+
+// Decrypt implements Cipher.
+func (p *passphraseCipher) Decrypt(data []byte) ([]byte, error) {
+	if len(data) < saltLength {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	aead, err := p.aead(data[:saltLength])
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[saltLength:]
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted value")
+	}
+
+	return plaintext, nil
+}
+
+func (p *passphraseCipher) aead(salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(p.passphrase, salt, 1<<15, 8, 1, keyLength)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
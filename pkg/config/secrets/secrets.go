@@ -0,0 +1,283 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package secrets encrypts and decrypts sensitive configuration values so
+deployment manifests don't need to contain plaintext secrets.
+
+Encrypted values take the form:
+
+	enc:<scheme>:<base64 data>
+
+Where scheme is either "passphrase" for values encrypted with AES-256-GCM using
+a key derived from an operator supplied passphrase, or "kms" for values
+encrypted with a Google Cloud KMS key.
+*/
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Prefix is prepended to all encrypted values.
+	Prefix = "enc:"
+
+	// PassphraseProp holds the passphrase used to encrypt and decrypt values
+	// with the passphrase scheme.
+	PassphraseProp = "config.encryption.passphrase"
+	// KmsKeyProp holds the fully qualified name of the Cloud KMS crypto key used
+	// to encrypt and decrypt values with the kms scheme, e.g.
+	// projects/p/locations/global/keyRings/r/cryptoKeys/k
+	KmsKeyProp = "config.encryption.kms_key"
+
+	// PassphraseScheme identifies values encrypted with a passphrase.
+	PassphraseScheme = "passphrase"
+	// KmsScheme identifies values encrypted with Cloud KMS.
+	KmsScheme = "kms"
+)
+
+// Cipher encrypts and decrypts raw data for a single scheme.
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// CipherFactory creates the Cipher for a scheme on demand so credentials are
+// only required if a value actually uses the scheme.
+type CipherFactory func() (Cipher, error)
+
+// DefaultCiphers creates ciphers from the broker's configuration.
+var DefaultCiphers = map[string]CipherFactory{
+	PassphraseScheme: func() (Cipher, error) {
+		passphrase := viper.GetString(PassphraseProp)
+		if passphrase == "" {
+			return nil, fmt.Errorf("%s must be set to use %q encrypted values", PassphraseProp, PassphraseScheme)
+		}
+
+		return NewPassphraseCipher(passphrase), nil
+	},
+
+	KmsScheme: func() (Cipher, error) {
+		keyName := viper.GetString(KmsKeyProp)
+		if keyName == "" {
+			return nil, fmt.Errorf("%s must be set to use %q encrypted values", KmsKeyProp, KmsScheme)
+		}
+
+		return NewKmsCipher(keyName)
+	},
+}
+
+// IsEncrypted returns true if the value is in the encrypted value format.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Encrypt encrypts the plaintext with the given scheme and formats it as an
+// encrypted value.
+func Encrypt(scheme, plaintext string) (string, error) {
+	cipher, err := cipherFor(scheme)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := cipher.Encrypt([]byte(plaintext))
+	if err != nil {
+		return "", fmt.Errorf("couldn't encrypt value: %v", err)
+	}
+
+	return Prefix + scheme + ":" + encoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts a value created by Encrypt. Values that aren't encrypted
+// are returned as-is.
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(value, Prefix), ":", 2)
+	if len(parts) != 2 {
+		return "", errors.New("malformed encrypted value, expected enc:<scheme>:<data>")
+	}
+
+	cipher, err := cipherFor(parts[0])
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %v", err)
+	}
+
+	plaintext, err := cipher.Decrypt(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("couldn't decrypt value: %v", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// DecryptEnv replaces the environment variable with its plaintext if it's
+// encrypted. It's used for variables bound to the configuration after
+// startup, e.g. by a brokerpak's env_config_mapping.
+func DecryptEnv(name string) error {
+	value := os.Getenv(name)
+	if !IsEncrypted(value) {
+		return nil
+	}
+
+	plaintext, err := Decrypt(value)
+	if err != nil {
+		return fmt.Errorf("couldn't decrypt environment variable %q: %v", name, err)
+	}
+
+	return os.Setenv(name, plaintext)
+}
+
+// DecryptConfig decrypts the encrypted environment variables and the
+// encrypted strings in the global Viper configuration, see DecryptViper.
+func DecryptConfig() error {
+	return DecryptViper(viper.GetViper())
+}
+
+// DecryptViper replaces the encrypted environment variables that configure
+// the broker and every encrypted string in v's configuration file with their
+// plaintext. Only environment variables config.IsBrokerEnv reports are
+// decrypted, others are left for the programs they're meant for. They're
+// decrypted in place so values Viper only discovers on lookup are covered
+// too. The file's values are decrypted into the configuration v read rather
+// than set as overrides, so reading the file again replaces them.
+func DecryptViper(v *viper.Viper) error {
+	for _, env := range os.Environ() {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || !config.IsBrokerEnv(parts[0]) {
+			continue
+		}
+
+		if err := DecryptEnv(parts[0]); err != nil {
+			return err
+		}
+	}
+
+	file := v.ConfigFileUsed()
+	if file == "" {
+		return nil
+	}
+
+	// v also holds defaults, overrides and the environment, so the file is
+	// read on its own to find the values it contributed
+	raw := viper.New()
+	raw.SetConfigFile(file)
+	if err := raw.ReadInConfig(); err != nil {
+		return err
+	}
+
+	settings := raw.AllSettings()
+	decrypted, changed, err := decryptValue("", settings)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+
+	content, err := json.Marshal(decrypted)
+	if err != nil {
+		return err
+	}
+
+	v.SetConfigType("json")
+	return v.ReadConfig(bytes.NewReader(content))
+}
+
+// decryptValue returns the value with its encrypted strings decrypted, and
+// whether any were. Maps decoded from YAML are converted to string keyed ones
+// so they can be encoded as JSON.
+func decryptValue(key string, value interface{}) (interface{}, bool, error) {
+	switch value := value.(type) {
+	case string:
+		if !IsEncrypted(value) {
+			return value, false, nil
+		}
+
+		plaintext, err := Decrypt(value)
+		if err != nil {
+			return nil, false, fmt.Errorf("couldn't decrypt configuration value %q: %v", key, err)
+		}
+		return plaintext, true, nil
+
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		changed := false
+		for i, item := range value {
+			decrypted, itemChanged, err := decryptValue(fmt.Sprintf("%s[%d]", key, i), item)
+			if err != nil {
+				return nil, false, err
+			}
+			out[i] = decrypted
+			changed = changed || itemChanged
+		}
+		return out, changed, nil
+
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		changed := false
+		for k, item := range value {
+			decrypted, itemChanged, err := decryptValue(joinKey(key, k), item)
+			if err != nil {
+				return nil, false, err
+			}
+			out[k] = decrypted
+			changed = changed || itemChanged
+		}
+		return out, changed, nil
+
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			converted[fmt.Sprint(k)] = item
+		}
+		return decryptValue(key, converted)
+
+	default:
+		return value, false, nil
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
+
+func cipherFor(scheme string) (Cipher, error) {
+	factory, ok := DefaultCiphers[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption scheme %q", scheme)
+	}
+
+	return factory()
+}
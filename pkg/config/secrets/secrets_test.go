@@ -0,0 +1,198 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	viper.Set(PassphraseProp, "correct horse")
+	defer viper.Set(PassphraseProp, nil)
+
+	encrypted, err := Encrypt(PassphraseScheme, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(encrypted, "enc:passphrase:") {
+		t.Errorf("expected encrypted value to have scheme prefix, got %q", encrypted)
+	}
+
+	if strings.Contains(encrypted, "hunter2") {
+		t.Errorf("expected encrypted value not to contain plaintext, got %q", encrypted)
+	}
+
+	again, err := Encrypt(PassphraseScheme, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again == encrypted {
+		t.Error("expected encrypting the same value twice to produce different outputs")
+	}
+
+	decrypted, err := Decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decrypted != "hunter2" {
+		t.Errorf("expected %q, got %q", "hunter2", decrypted)
+	}
+}
+
+func TestDecrypt_Errors(t *testing.T) {
+	viper.Set(PassphraseProp, "correct horse")
+	encrypted, err := Encrypt(PassphraseScheme, "hunter2")
+	viper.Set(PassphraseProp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		Passphrase    string
+		Value         string
+		ExpectedError string
+	}{
+		"plaintext passes through": {
+			Value: "not-encrypted",
+		},
+		"wrong passphrase": {
+			Passphrase:    "battery staple",
+			Value:         encrypted,
+			ExpectedError: "couldn't decrypt value: wrong passphrase or corrupted value",
+		},
+		"missing passphrase": {
+			Value:         encrypted,
+			ExpectedError: `config.encryption.passphrase must be set to use "passphrase" encrypted values`,
+		},
+		"unknown scheme": {
+			Value:         "enc:rot13:abc",
+			ExpectedError: `unknown encryption scheme "rot13"`,
+		},
+		"malformed": {
+			Value:         "enc:passphrase",
+			ExpectedError: "malformed encrypted value, expected enc:<scheme>:<data>",
+		},
+		"bad encoding": {
+			Passphrase:    "correct horse",
+			Value:         "enc:passphrase:!!!",
+			ExpectedError: "malformed encrypted value: illegal base64 data at input byte 0",
+		},
+		"truncated": {
+			Passphrase:    "correct horse",
+			Value:         "enc:passphrase:YWJj",
+			ExpectedError: "couldn't decrypt value: ciphertext too short",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(PassphraseProp, tc.Passphrase)
+			defer viper.Set(PassphraseProp, nil)
+
+			_, err := Decrypt(tc.Value)
+			actual := ""
+			if err != nil {
+				actual = err.Error()
+			}
+
+			if actual != tc.ExpectedError {
+				t.Errorf("expected error %q, got %q", tc.ExpectedError, actual)
+			}
+		})
+	}
+}
+
+func TestDecryptViper(t *testing.T) {
+	viper.Set(PassphraseProp, "correct horse")
+	defer viper.Set(PassphraseProp, nil)
+
+	encrypted, err := Encrypt(PassphraseScheme, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "secrets-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yml")
+	content := fmt.Sprintf("secrets:\n  test:\n    config: %s\n    plain: plain\n    list: [%s]\n", encrypted, encrypted)
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	config.Register(config.Property{Key: "secrets.test.bound", Kind: config.String, Env: "SECRETS_TEST_BOUND"})
+	for _, env := range []string{"GSB_SECRETS_TEST_ENV", "SECRETS_TEST_BOUND", "SECRETS_TEST_OTHER"} {
+		os.Setenv(env, encrypted)
+		defer os.Unsetenv(env)
+	}
+
+	if err := DecryptViper(v); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := v.GetString("secrets.test.config"); actual != "hunter2" {
+		t.Errorf("expected config value to be decrypted, got %q", actual)
+	}
+
+	if actual := v.GetString("secrets.test.plain"); actual != "plain" {
+		t.Errorf("expected plain config value to be unchanged, got %q", actual)
+	}
+
+	if actual := v.GetStringSlice("secrets.test.list"); len(actual) != 1 || actual[0] != "hunter2" {
+		t.Errorf("expected list values to be decrypted, got %q", actual)
+	}
+
+	if actual := os.Getenv("GSB_SECRETS_TEST_ENV"); actual != "hunter2" {
+		t.Errorf("expected environment variable to be decrypted, got %q", actual)
+	}
+
+	if actual := os.Getenv("SECRETS_TEST_BOUND"); actual != "hunter2" {
+		t.Errorf("expected bound environment variable to be decrypted, got %q", actual)
+	}
+
+	if actual := os.Getenv("SECRETS_TEST_OTHER"); actual != encrypted {
+		t.Errorf("expected unrelated environment variable to be unchanged, got %q", actual)
+	}
+
+	// decrypted values mustn't shadow the file if it's read again
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader("secrets:\n  test:\n    config: changed\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := v.GetString("secrets.test.config"); actual != "changed" {
+		t.Errorf("expected the config read again to replace the decrypted value, got %q", actual)
+	}
+}
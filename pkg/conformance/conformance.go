@@ -0,0 +1,367 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conformance checks a running broker follows the Open Service Broker
+// API: its catalog, provisioning, polling asynchronous operations, binding and
+// the error codes of requests it must reject. It's run with a plan that's
+// cheap to provision, e.g. one backed by an emulator, because it creates and
+// deletes an instance and binding of it.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/client"
+	"github.com/pivotal/cloud-service-broker/pkg/preflight"
+)
+
+// Defaults of the Suite's polling of asynchronous operations.
+const (
+	DefaultPollInterval = 5 * time.Second
+	DefaultTimeout      = 30 * time.Minute
+)
+
+// lastOperationStates are the states the OSB API allows last_operation to
+// respond with.
+var lastOperationStates = []string{"in progress", "succeeded", "failed"}
+
+// Suite checks the broker's API using a plan of one of its services.
+type Suite struct {
+	Client    *client.Client
+	ServiceId string
+	PlanId    string
+
+	// Params are the parameters the instance is provisioned with.
+	Params json.RawMessage
+
+	// PollInterval is how often asynchronous operations are polled and
+	// Timeout how long they may take, they're defaulted if zero.
+	PollInterval time.Duration
+	Timeout      time.Duration
+
+	instanceId string
+	bindingId  string
+	bindable   bool
+	async      bool
+}
+
+// Run runs the checks in order and reports every result. Checks of the
+// instance and binding are skipped if creating them failed, but the instance
+// is deprovisioned even if its binding fails.
+func (s *Suite) Run(ctx context.Context) preflight.Report {
+	s.instanceId = "conformance-" + uuid.New()
+	s.bindingId = "conformance-" + uuid.New()
+
+	return preflight.Run(ctx, []preflight.Check{
+		{Name: "catalog", Run: s.checkCatalog},
+		{Name: "api-version-required", Run: s.checkApiVersionRequired},
+		{Name: "authentication-required", Run: s.checkAuthenticationRequired},
+		{Name: "provision-unknown-plan", Requires: []string{"catalog"}, Run: s.checkProvisionUnknownPlan},
+		{Name: "provision", Requires: []string{"catalog"}, Run: s.checkProvision},
+		{Name: "provision-async-required", Requires: []string{"provision"}, Run: s.checkAsyncRequired},
+		{Name: "provision-conflict", Requires: []string{"provision"}, Run: s.checkProvisionConflict},
+		{Name: "last-operation-unknown-instance", Run: s.checkLastOperationUnknown},
+		{Name: "bind", Requires: []string{"provision"}, Run: s.checkBind},
+		{Name: "bind-conflict", Requires: []string{"bind"}, Run: s.checkBindConflict},
+		{Name: "bind-unknown-instance", Requires: []string{"catalog"}, Run: s.checkBindUnknownInstance},
+		{Name: "unbind", Requires: []string{"bind"}, Run: s.checkUnbind},
+		{Name: "unbind-gone", Requires: []string{"unbind"}, Run: s.checkUnbindGone},
+		{Name: "deprovision", Requires: []string{"provision"}, Run: s.checkDeprovision},
+		{Name: "deprovision-gone", Requires: []string{"deprovision"}, Run: s.checkDeprovisionGone},
+	})
+}
+
+func (s *Suite) checkCatalog(ctx context.Context) (string, error) {
+	resp := s.Client.Catalog()
+	if err := expectStatus(resp, http.StatusOK); err != nil {
+		return "", err
+	}
+
+	var catalog brokerapi.CatalogResponse
+	if err := json.Unmarshal(resp.ResponseBody, &catalog); err != nil {
+		return "", fmt.Errorf("couldn't parse the catalog: %v", err)
+	}
+
+	var problems []string
+	ids := make(map[string]bool)
+	checkId := func(kind, id string) {
+		if id == "" {
+			problems = append(problems, fmt.Sprintf("a %s has no id", kind))
+		} else if ids[id] {
+			problems = append(problems, fmt.Sprintf("%s id %q isn't unique", kind, id))
+		}
+		ids[id] = true
+	}
+
+	found := false
+	for _, service := range catalog.Services {
+		checkId("service", service.ID)
+		if service.Name == "" || service.Description == "" {
+			problems = append(problems, fmt.Sprintf("service %q must have a name and description", service.ID))
+		}
+		if len(service.Plans) == 0 {
+			problems = append(problems, fmt.Sprintf("service %q has no plans", service.Name))
+		}
+
+		for _, plan := range service.Plans {
+			checkId("plan", plan.ID)
+			if plan.Name == "" || plan.Description == "" {
+				problems = append(problems, fmt.Sprintf("plan %q of service %q must have a name and description", plan.ID, service.Name))
+			}
+
+			if service.ID == s.ServiceId && plan.ID == s.PlanId {
+				found = true
+				s.bindable = service.Bindable
+				if plan.Bindable != nil {
+					s.bindable = *plan.Bindable
+				}
+			}
+		}
+	}
+
+	if !found {
+		problems = append(problems, fmt.Sprintf("plan %q of service %q isn't in the catalog", s.PlanId, s.ServiceId))
+	}
+
+	if len(problems) > 0 {
+		return "", fmt.Errorf("invalid catalog: %s", strings.Join(problems, "; "))
+	}
+
+	return fmt.Sprintf("%d service(s)", len(catalog.Services)), nil
+}
+
+func (s *Suite) checkApiVersionRequired(ctx context.Context) (string, error) {
+	resp := s.Client.Request(http.MethodGet, "catalog", http.Header{"X-Broker-Api-Version": {""}}, nil)
+	return "", expectStatus(resp, http.StatusPreconditionFailed)
+}
+
+func (s *Suite) checkAuthenticationRequired(ctx context.Context) (string, error) {
+	anonymousUrl := *s.Client.BaseUrl
+	anonymousUrl.User = nil
+	anonymous := &client.Client{BaseUrl: &anonymousUrl}
+
+	// the OSB API doesn't require the bodies of 401 responses to be JSON
+	resp := anonymous.Catalog()
+	if resp.InError() {
+		return "", resp.Error
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return "", fmt.Errorf("expected status 401, got %s", resp)
+	}
+
+	return "", nil
+}
+
+func (s *Suite) checkProvisionUnknownPlan(ctx context.Context) (string, error) {
+	resp := s.Client.Provision("conformance-"+uuid.New(), s.ServiceId, "conformance-unknown-plan", nil)
+	return "", expectStatus(resp, http.StatusBadRequest)
+}
+
+func (s *Suite) checkProvision(ctx context.Context) (string, error) {
+	resp := s.Client.Provision(s.instanceId, s.ServiceId, s.PlanId, s.Params)
+	if err := expectStatus(resp, http.StatusCreated, http.StatusAccepted); err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode == http.StatusCreated {
+		return fmt.Sprintf("instance %s created synchronously", s.instanceId), nil
+	}
+
+	s.async = true
+	if err := s.poll(ctx, false); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("instance %s created asynchronously", s.instanceId), nil
+}
+
+func (s *Suite) checkAsyncRequired(ctx context.Context) (string, error) {
+	if !s.async {
+		return "", preflight.Skip("the plan provisions synchronously")
+	}
+
+	path := fmt.Sprintf("service_instances/conformance-%s", uuid.New())
+	resp := s.Client.Request(http.MethodPut, path, nil, brokerapi.ProvisionDetails{ServiceID: s.ServiceId, PlanID: s.PlanId, RawParameters: s.Params})
+	if err := expectStatus(resp, http.StatusUnprocessableEntity); err != nil {
+		return "", err
+	}
+
+	return "", expectError(resp, "AsyncRequired")
+}
+
+func (s *Suite) checkProvisionConflict(ctx context.Context) (string, error) {
+	resp := s.Client.Provision(s.instanceId, s.ServiceId, s.PlanId, json.RawMessage(`{"conformance_conflict": true}`))
+	return "", expectStatus(resp, http.StatusConflict)
+}
+
+func (s *Suite) checkLastOperationUnknown(ctx context.Context) (string, error) {
+	resp := s.Client.LastOperation("conformance-" + uuid.New())
+	return "", expectStatus(resp, http.StatusNotFound, http.StatusGone)
+}
+
+func (s *Suite) checkBind(ctx context.Context) (string, error) {
+	if !s.bindable {
+		return "", preflight.Skip("the plan isn't bindable")
+	}
+
+	resp := s.Client.Bind(s.instanceId, s.bindingId, s.ServiceId, s.PlanId, nil)
+	if err := expectStatus(resp, http.StatusCreated); err != nil {
+		return "", err
+	}
+
+	var binding struct {
+		Credentials map[string]interface{} `json:"credentials"`
+	}
+	if err := json.Unmarshal(resp.ResponseBody, &binding); err != nil || binding.Credentials == nil {
+		return "", fmt.Errorf("expected the binding to have a credentials object, got %s", resp)
+	}
+
+	return fmt.Sprintf("binding %s created", s.bindingId), nil
+}
+
+func (s *Suite) checkBindConflict(ctx context.Context) (string, error) {
+	resp := s.Client.Bind(s.instanceId, s.bindingId, s.ServiceId, s.PlanId, json.RawMessage(`{"conformance_conflict": true}`))
+	return "", expectStatus(resp, http.StatusConflict)
+}
+
+func (s *Suite) checkBindUnknownInstance(ctx context.Context) (string, error) {
+	if !s.bindable {
+		return "", preflight.Skip("the plan isn't bindable")
+	}
+
+	resp := s.Client.Bind("conformance-"+uuid.New(), "conformance-"+uuid.New(), s.ServiceId, s.PlanId, nil)
+	return "", expectStatus(resp, http.StatusNotFound)
+}
+
+func (s *Suite) checkUnbind(ctx context.Context) (string, error) {
+	resp := s.Client.Unbind(s.instanceId, s.bindingId, s.ServiceId, s.PlanId)
+	return "", expectStatus(resp, http.StatusOK)
+}
+
+func (s *Suite) checkUnbindGone(ctx context.Context) (string, error) {
+	resp := s.Client.Unbind(s.instanceId, s.bindingId, s.ServiceId, s.PlanId)
+	return "", expectStatus(resp, http.StatusGone)
+}
+
+func (s *Suite) checkDeprovision(ctx context.Context) (string, error) {
+	resp := s.Client.Deprovision(s.instanceId, s.ServiceId, s.PlanId)
+	if err := expectStatus(resp, http.StatusOK, http.StatusAccepted); err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		return fmt.Sprintf("instance %s deleted synchronously", s.instanceId), nil
+	}
+
+	if err := s.poll(ctx, true); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("instance %s deleted asynchronously", s.instanceId), nil
+}
+
+func (s *Suite) checkDeprovisionGone(ctx context.Context) (string, error) {
+	resp := s.Client.Deprovision(s.instanceId, s.ServiceId, s.PlanId)
+	return "", expectStatus(resp, http.StatusGone)
+}
+
+// poll polls the last operation of the instance until it succeeds, fails or
+// times out. If deleting is true the instance may be gone instead.
+func (s *Suite) poll(ctx context.Context, deleting bool) error {
+	interval, timeout := s.PollInterval, s.Timeout
+	if interval == 0 {
+		interval = DefaultPollInterval
+	}
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		resp := s.Client.LastOperation(s.instanceId)
+		if deleting && resp.StatusCode == http.StatusGone {
+			return nil
+		}
+		if err := expectStatus(resp, http.StatusOK); err != nil {
+			return err
+		}
+
+		var op brokerapi.LastOperationResponse
+		if err := json.Unmarshal(resp.ResponseBody, &op); err != nil {
+			return fmt.Errorf("couldn't parse the last operation: %v", err)
+		}
+
+		switch op.State {
+		case brokerapi.Succeeded:
+			return nil
+		case brokerapi.Failed:
+			return fmt.Errorf("the operation failed: %s", op.Description)
+		case brokerapi.InProgress:
+		default:
+			return fmt.Errorf("expected the last operation state to be one of %q, got %q", lastOperationStates, op.State)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the operation didn't finish within %v", timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// expectStatus returns an error if the request failed or the response
+// doesn't have one of the status codes or a JSON object body, which the OSB
+// API requires of every response.
+func expectStatus(resp *client.BrokerResponse, codes ...int) error {
+	if resp.InError() {
+		return resp.Error
+	}
+
+	expected := false
+	for _, code := range codes {
+		expected = expected || resp.StatusCode == code
+	}
+	if !expected {
+		return fmt.Errorf("expected status %v, got %s", codes, resp)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(resp.ResponseBody, &body); err != nil {
+		return fmt.Errorf("expected a JSON object body, got %s", resp)
+	}
+
+	return nil
+}
+
+// expectError returns an error if the response body doesn't have the OSB
+// error code.
+func expectError(resp *client.BrokerResponse, code string) error {
+	var body brokerapi.ErrorResponse
+	if err := json.Unmarshal(resp.ResponseBody, &body); err != nil || body.Error != code {
+		return fmt.Errorf("expected error code %q, got %s", code, resp)
+	}
+
+	return nil
+}
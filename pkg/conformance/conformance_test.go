@@ -0,0 +1,151 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conformance_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/brokerapi/brokers"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/broker/brokerfakes"
+	"github.com/pivotal/cloud-service-broker/pkg/client"
+	"github.com/pivotal/cloud-service-broker/pkg/conformance"
+	"github.com/pivotal/cloud-service-broker/pkg/preflight"
+	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin/storage"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+	"github.com/pivotal/cloud-service-broker/utils"
+)
+
+// TestSuite_Run runs the suite against the broker's API backed by sqlite and a
+// fake service provider.
+func TestSuite_Run(t *testing.T) {
+	cases := map[string]struct {
+		Async bool
+	}{
+		"synchronous":  {Async: false},
+		"asynchronous": {Async: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			suite := newBrokerSuite(t, fakeProvider(tc.Async))
+			report := suite.Run(context.Background())
+
+			for _, result := range report.Checks {
+				t.Logf("%s: %s %s", result.Name, result.Status, result.Detail)
+				if result.Status == preflight.Failed {
+					t.Errorf("expected %s to pass, got %s", result.Name, result.Detail)
+				}
+			}
+
+			skipped := map[string]bool{}
+			for _, result := range report.Checks {
+				if result.Status == preflight.Skipped {
+					skipped[result.Name] = true
+				}
+			}
+			if tc.Async == skipped["provision-async-required"] || len(skipped) > 1 {
+				t.Errorf("expected only provision-async-required to be skipped for synchronous plans, got %v", skipped)
+			}
+		})
+	}
+}
+
+// fakeProvider creates a provider that succeeds at everything. Asynchronous
+// operations finish the first time they're polled.
+func fakeProvider(async bool) *brokerfakes.FakeServiceProvider {
+	operationId := "operation"
+	provider := &brokerfakes.FakeServiceProvider{
+		ProvisionsAsyncStub:   func() bool { return async },
+		DeprovisionsAsyncStub: func() bool { return async },
+		ProvisionStub: func(ctx context.Context, vc *varcontext.VarContext) (models.ServiceInstanceDetails, error) {
+			if async {
+				return models.ServiceInstanceDetails{OperationId: operationId}, nil
+			}
+			return models.ServiceInstanceDetails{}, nil
+		},
+		DeprovisionStub: func(ctx context.Context, instance models.ServiceInstanceDetails, details brokerapi.DeprovisionDetails, vc *varcontext.VarContext) (*string, error) {
+			if async {
+				return &operationId, nil
+			}
+			return nil, nil
+		},
+		BindStub: func(ctx context.Context, vc *varcontext.VarContext) (map[string]interface{}, error) {
+			return map[string]interface{}{"user": "conformance"}, nil
+		},
+		BuildInstanceCredentialsStub: func(ctx context.Context, bc models.ServiceBindingCredentials, id models.ServiceInstanceDetails) (*brokerapi.Binding, error) {
+			return &brokerapi.Binding{Credentials: map[string]interface{}{"user": "conformance"}}, nil
+		},
+	}
+	provider.PollInstanceReturns(true, "done", nil)
+
+	return provider
+}
+
+// newBrokerSuite serves the broker's API with a single service backed by the
+// provider and creates a suite for its first plan.
+func newBrokerSuite(t *testing.T, provider broker.ServiceProvider) *conformance.Suite {
+	db, err := db_service.OpenSqlite(filepath.Join(t.TempDir(), "conformance.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db_service.RunMigrations(db)
+	db_service.DbConnection = db
+
+	defn := storage.ServiceDefinition()
+	defn.ProviderBuilder = func(logger lager.Logger, store broker.ProviderStore) broker.ServiceProvider {
+		return provider
+	}
+	svc, err := defn.CatalogEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := broker.BrokerRegistry{}
+	registry.Register(defn)
+
+	logger := utils.NewLogger("conformance-test")
+	serviceBroker, err := brokers.New(&brokers.BrokerConfig{Registry: registry}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(brokerapi.New(serviceBroker, logger, brokerapi.BrokerCredentials{Username: "user", Password: "pass"}))
+	t.Cleanup(server.Close)
+
+	baseUrl, err := url.Parse(server.URL + "/v2/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseUrl.User = url.UserPassword("user", "pass")
+
+	return &conformance.Suite{
+		Client:       &client.Client{BaseUrl: baseUrl},
+		ServiceId:    svc.ID,
+		PlanId:       svc.Plans[0].ID,
+		PollInterval: 10 * time.Millisecond,
+		Timeout:      time.Second,
+	}
+}
@@ -17,7 +17,6 @@ package credstore
 import (
 	"io/ioutil"
 	"fmt"
-	"os"
 
 	"github.com/pivotal/cloud-service-broker/pkg/config"
 
@@ -46,9 +45,9 @@ type credhubStore struct {
 }
 
 func NewCredhubStore(credStoreConfig *config.CredStoreConfig, logger lager.Logger) (CredStore, error) {
-	if os.Getenv("DEV_MODE_ONLY") != "" {
-		logger.Debug(fmt.Sprintf("DEV_MODE_ONLY [%+v] - Creating Mock Credhub", os.Getenv("DEV_MODE_ONLY")))
-		return &credHubStoreMock{}, nil
+	if credStoreConfig.DevModeOnly != "" {
+		logger.Debug(fmt.Sprintf("DEV_MODE_ONLY [%+v] - Creating Mock Credhub", credStoreConfig.DevModeOnly))
+		return &credHubStoreMock{path: credStoreConfig.DevModeOnly}, nil
 	}
 
 	if !credStoreConfig.HasCredHubConfig() {
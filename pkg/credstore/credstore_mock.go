@@ -24,7 +24,10 @@ import (
 	"code.cloudfoundry.org/credhub-cli/credhub/permissions"
 )
 
-type credHubStoreMock struct{}
+type credHubStoreMock struct {
+	// path is the directory credentials are written to.
+	path string
+}
 
 func (c *credHubStoreMock) Put(key string, credentials interface{}) (interface{}, error) {
 	return nil, nil
@@ -37,7 +40,7 @@ func getFileName(key string) string {
 }
 
 func (c *credHubStoreMock) PutValue(key string, credentials interface{}) (interface{}, error) {
-	credstorePath := os.Getenv("DEV_MODE_ONLY")
+	credstorePath := c.path
 	if _, err := os.Stat(credstorePath); os.IsNotExist(err) {
 		err = os.MkdirAll(credstorePath, 0777)
 		if err != nil {
@@ -63,7 +66,7 @@ func (c *credHubStoreMock) PutValue(key string, credentials interface{}) (interf
 }
 
 func (c *credHubStoreMock) GetValue(key string) (string, error) {
-	credstorePath := os.Getenv("DEV_MODE_ONLY")
+	credstorePath := c.path
 	file, err := os.Open(credstorePath + "/" + getFileName(key))
 	if err != nil {
 		return "", errors.Wrap(err, "Failed to open file for mock credstore")
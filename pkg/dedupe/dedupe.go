@@ -0,0 +1,158 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dedupe coalesces identical requests that arrive in quick succession,
+// like platform retries, onto a single call.
+package dedupe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+)
+
+// ProvisionWindowProp is the viper key of how long identical provision
+// requests are coalesced for after the first one finishes.
+const ProvisionWindowProp = "provision.dedupe_window"
+
+func init() {
+	config.Register(config.Property{Key: ProvisionWindowProp, Kind: config.Duration, Default: "30s"})
+}
+
+// metrics holds per-group counters published at /debug/vars when the default
+// HTTP mux is served.
+var metrics = expvar.NewMap("dedupe")
+
+// ErrConflict is returned when a request with the same key but different
+// contents is made while another is in-flight or within the window.
+var ErrConflict = errors.New("a different request for the same resource is in progress")
+
+// Group coalesces calls with the same key and request hash. Calls that arrive
+// while one is in-flight wait for it and share its result; calls that arrive
+// within the window after it succeeded get the same result without running.
+// Failed calls aren't remembered so they can be retried.
+type Group struct {
+	Name   string
+	Window time.Duration
+
+	// now is used to get the current time, it can be replaced in tests.
+	now func() time.Time
+
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	hash     string
+	done     chan struct{}
+	result   interface{}
+	err      error
+	finished time.Time
+}
+
+// NewProvisionGroupFromEnv creates a Group for provision requests using the
+// window configured in viper. It returns nil if the window is zero, which
+// disables deduplication.
+func NewProvisionGroupFromEnv() (*Group, error) {
+	window, err := time.ParseDuration(viper.GetString(ProvisionWindowProp))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", ProvisionWindowProp, err)
+	}
+
+	if window <= 0 {
+		return nil, nil
+	}
+
+	return &Group{Name: "provision", Window: window}, nil
+}
+
+// Hash creates a stable hash of a JSON serializable request.
+func Hash(request interface{}) (string, error) {
+	serialized, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(serialized)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (g *Group) currentTime() time.Time {
+	if g.now != nil {
+		return g.now()
+	}
+
+	return time.Now()
+}
+
+// Do runs fn unless a call with the same key is in-flight or finished within
+// the window, in which case it returns that call's result and shared is true.
+// If that call had a different hash ErrConflict is returned.
+func (g *Group) Do(key, hash string, fn func() (interface{}, error)) (result interface{}, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	g.expire()
+
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+
+		if c.hash != hash {
+			metrics.Add(g.Name+".conflicts", 1)
+			return nil, false, ErrConflict
+		}
+
+		metrics.Add(g.Name+".hits", 1)
+		<-c.done
+		return c.result, true, c.err
+	}
+
+	c := &call{hash: hash, done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	metrics.Add(g.Name+".misses", 1)
+	c.result, c.err = fn()
+
+	g.mu.Lock()
+	if c.err != nil {
+		delete(g.calls, key)
+	} else {
+		c.finished = g.currentTime()
+	}
+	g.mu.Unlock()
+	close(c.done)
+
+	return c.result, false, c.err
+}
+
+// expire removes calls that finished before the window. It must be called
+// with the lock held.
+func (g *Group) expire() {
+	cutoff := g.currentTime().Add(-g.Window)
+	for key, c := range g.calls {
+		if !c.finished.IsZero() && c.finished.Before(cutoff) {
+			delete(g.calls, key)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedupe
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestGroup_Do_concurrent(t *testing.T) {
+	group := &Group{Name: "test-concurrent", Window: time.Minute}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	calls := 0
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 3)
+	shared := make([]bool, 3)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], shared[i], _ = group.Do("instance", "hash", func() (interface{}, error) {
+				calls++
+				close(started)
+				<-release
+				return "operation-1", nil
+			})
+		}(i)
+
+		// make sure the first call is in-flight before the duplicates arrive
+		if i == 0 {
+			<-started
+		}
+	}
+
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+
+	sharedCount := 0
+	for i, result := range results {
+		if result != "operation-1" {
+			t.Errorf("Expected result %d to be shared, got %v", i, result)
+		}
+		if shared[i] {
+			sharedCount++
+		}
+	}
+
+	if sharedCount != 2 {
+		t.Errorf("Expected 2 shared results, got %d", sharedCount)
+	}
+
+	if hits := metrics.Get("test-concurrent.hits").String(); hits != "2" {
+		t.Errorf("Expected 2 hits to be recorded, got %s", hits)
+	}
+}
+
+func TestGroup_Do(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		FirstErr      error
+		Hash          string
+		Elapsed       time.Duration
+		ExpectedCalls int
+		ExpectedErr   error
+		ExpectShared  bool
+	}{
+		"duplicate within window": {
+			Hash:          "hash",
+			Elapsed:       10 * time.Second,
+			ExpectedCalls: 1,
+			ExpectShared:  true,
+		},
+		"duplicate after window": {
+			Hash:          "hash",
+			Elapsed:       time.Minute + time.Second,
+			ExpectedCalls: 2,
+		},
+		"different request within window": {
+			Hash:          "other",
+			Elapsed:       10 * time.Second,
+			ExpectedCalls: 1,
+			ExpectedErr:   ErrConflict,
+		},
+		"retry after failure": {
+			FirstErr:      errors.New("quota exceeded"),
+			Hash:          "hash",
+			Elapsed:       time.Second,
+			ExpectedCalls: 2,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			now := start
+			group := &Group{Name: "test", Window: time.Minute, now: func() time.Time { return now }}
+
+			calls := 0
+			fn := func(err error) func() (interface{}, error) {
+				return func() (interface{}, error) {
+					calls++
+					return "operation", err
+				}
+			}
+
+			if _, _, err := group.Do("instance", "hash", fn(tc.FirstErr)); err != tc.FirstErr {
+				t.Fatalf("Expected error %v, got %v", tc.FirstErr, err)
+			}
+
+			now = start.Add(tc.Elapsed)
+			_, shared, err := group.Do("instance", tc.Hash, fn(nil))
+			if err != tc.ExpectedErr {
+				t.Errorf("Expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if shared != tc.ExpectShared {
+				t.Errorf("Expected shared: %v, got %v", tc.ExpectShared, shared)
+			}
+
+			if calls != tc.ExpectedCalls {
+				t.Errorf("Expected %d calls, got %d", tc.ExpectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestNewProvisionGroupFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Window         string
+		ExpectedWindow time.Duration
+		ExpectNil      bool
+		ExpectErr      bool
+	}{
+		"default":  {ExpectedWindow: 30 * time.Second},
+		"custom":   {Window: "5s", ExpectedWindow: 5 * time.Second},
+		"disabled": {Window: "0s", ExpectNil: true},
+		"invalid":  {Window: "soon", ExpectNil: true, ExpectErr: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if tc.Window != "" {
+				viper.Set(ProvisionWindowProp, tc.Window)
+				defer viper.Set(ProvisionWindowProp, nil)
+			}
+
+			group, err := NewProvisionGroupFromEnv()
+			if (err != nil) != tc.ExpectErr {
+				t.Errorf("Expected error: %v, got %v", tc.ExpectErr, err)
+			}
+
+			if (group == nil) != tc.ExpectNil {
+				t.Fatalf("Expected nil group: %v, got %v", tc.ExpectNil, group)
+			}
+
+			if group != nil && group.Window != tc.ExpectedWindow {
+				t.Errorf("Expected window %v, got %v", tc.ExpectedWindow, group.Window)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	a, err := Hash(map[string]interface{}{"plan": "small", "region": "us-central1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Hash(map[string]interface{}{"region": "us-central1", "plan": "small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Hash(map[string]interface{}{"plan": "large", "region": "us-central1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a != b {
+		t.Errorf("Expected equal requests to have the same hash, got %q and %q", a, b)
+	}
+
+	if a == c {
+		t.Errorf("Expected different requests to have different hashes, got %q", a)
+	}
+}
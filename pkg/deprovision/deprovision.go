@@ -0,0 +1,185 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package deprovision makes sure the resources of deleted service instances
+// are really gone before the broker forgets the instances. Cloud APIs can
+// report a delete as done while the resource lingers, and deletes can fail
+// for reasons that go away on their own, e.g. rate limits.
+package deprovision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/failure"
+	"github.com/spf13/viper"
+)
+
+const (
+	// VerifyProp is the viper key of whether deletes are checked by looking
+	// for the deleted resources.
+	VerifyProp = "deprovision.verify"
+
+	// VerifyIntervalProp is the viper key of how often the resources of a
+	// delete are looked for.
+	VerifyIntervalProp = "deprovision.verify_interval"
+
+	// VerifyTimeoutProp is the viper key of how long the resources of a
+	// delete are looked for before the delete is tried again.
+	VerifyTimeoutProp = "deprovision.verify_timeout"
+
+	// MaxAttemptsProp is the viper key of how many times a delete that
+	// fails transiently is tried.
+	MaxAttemptsProp = "deprovision.max_attempts"
+
+	// StuckAfterProp is the viper key of how long an instance can be
+	// deleting before it's flagged as stuck.
+	StuckAfterProp = "deprovision.stuck_after"
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: VerifyProp, Kind: config.Boolean, Default: true},
+		config.Property{Key: VerifyIntervalProp, Kind: config.Duration, Default: "15s"},
+		config.Property{Key: VerifyTimeoutProp, Kind: config.Duration, Default: "10m"},
+		config.Property{Key: MaxAttemptsProp, Kind: config.Integer, Default: 3},
+		config.Property{Key: StuckAfterProp, Kind: config.Duration, Default: "1h"},
+	)
+}
+
+// RemainingError is returned when resources still exist after they were
+// deleted.
+type RemainingError struct {
+	Resources []string
+}
+
+func (e *RemainingError) Error() string {
+	return fmt.Sprintf("%d resources still exist after they were deleted: %s", len(e.Resources), strings.Join(e.Resources, ", "))
+}
+
+// Default is the reconciler used by the broker.
+var Default = NewReconciler()
+
+// Reconciler decides when a delete is done, when it's tried again and when
+// it's stuck.
+type Reconciler struct {
+	// Verify enables looking for the resources of deletes.
+	Verify         bool
+	VerifyInterval time.Duration
+	VerifyTimeout  time.Duration
+
+	// MaxAttempts is the number of times a delete that fails transiently is
+	// tried.
+	MaxAttempts int
+
+	// StuckAfter is how long an instance can be deleting before it's stuck.
+	StuckAfter time.Duration
+}
+
+// NewReconciler creates a reconciler with the default settings.
+func NewReconciler() *Reconciler {
+	return &Reconciler{
+		Verify:         true,
+		VerifyInterval: 15 * time.Second,
+		VerifyTimeout:  10 * time.Minute,
+		MaxAttempts:    3,
+		StuckAfter:     time.Hour,
+	}
+}
+
+// ConfigureFromEnv reads the reconciler's settings from viper.
+func (r *Reconciler) ConfigureFromEnv() error {
+	var err error
+	if r.VerifyInterval, err = parsePositiveDuration(VerifyIntervalProp); err != nil {
+		return err
+	}
+	if r.VerifyTimeout, err = parsePositiveDuration(VerifyTimeoutProp); err != nil {
+		return err
+	}
+	if r.StuckAfter, err = parsePositiveDuration(StuckAfterProp); err != nil {
+		return err
+	}
+
+	if r.MaxAttempts = viper.GetInt(MaxAttemptsProp); r.MaxAttempts < 1 {
+		return fmt.Errorf("%s must be at least 1, got %d", MaxAttemptsProp, r.MaxAttempts)
+	}
+
+	r.Verify = viper.GetBool(VerifyProp)
+	return nil
+}
+
+func parsePositiveDuration(prop string) (time.Duration, error) {
+	d, err := time.ParseDuration(viper.GetString(prop))
+	if err != nil {
+		return 0, fmt.Errorf("couldn't parse %s: %v", prop, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got %s", prop, d)
+	}
+
+	return d, nil
+}
+
+// WaitUntilGone calls remaining every VerifyInterval until it returns no
+// resources. If resources remain after VerifyTimeout, or the context is
+// done, a RemainingError listing them is returned, or the last error from
+// remaining if it failed. It returns immediately if Verify is off.
+func (r *Reconciler) WaitUntilGone(ctx context.Context, remaining func(ctx context.Context) ([]string, error)) error {
+	if !r.Verify {
+		return nil
+	}
+
+	deadline := time.Now().Add(r.VerifyTimeout)
+	for {
+		resources, err := remaining(ctx)
+		if err == nil && len(resources) == 0 {
+			return nil
+		}
+		if err == nil {
+			err = &RemainingError{Resources: resources}
+		}
+
+		if !time.Now().Add(r.VerifyInterval).Before(deadline) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(r.VerifyInterval):
+		}
+	}
+}
+
+// Retryable returns true if trying a failed delete again is likely to work.
+// Deletes whose resources remain are tried again, as are deletes that failed
+// with a retryable error in the failure taxonomy.
+func (r *Reconciler) Retryable(err error) bool {
+	var remaining *RemainingError
+	if errors.As(err, &remaining) {
+		return true
+	}
+
+	return failure.Classify(err).Retryable
+}
+
+// Stuck returns true if an instance that started deleting at since is still
+// deleting after StuckAfter.
+func (r *Reconciler) Stuck(since, now time.Time) bool {
+	return now.Sub(since) > r.StuckAfter
+}
@@ -0,0 +1,175 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deprovision
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"google.golang.org/api/googleapi"
+)
+
+func TestReconciler_WaitUntilGone(t *testing.T) {
+	refreshFailed := errors.New("refresh failed")
+
+	cases := map[string]struct {
+		Verify       bool
+		Remaining    [][]string
+		Errors       []error
+		ExpectCalls  int
+		TimesOut     bool
+		ExpectError  error
+		ExpectRemain []string
+	}{
+		"gone": {
+			Verify:      true,
+			Remaining:   [][]string{nil},
+			ExpectCalls: 1,
+		},
+		"gone after polling": {
+			Verify:      true,
+			Remaining:   [][]string{{"google_sql_database_instance.instance"}, {"google_sql_database_instance.instance"}, nil},
+			ExpectCalls: 3,
+		},
+		"remains": {
+			Verify:       true,
+			Remaining:    [][]string{{"google_sql_database_instance.instance"}},
+			TimesOut:     true,
+			ExpectRemain: []string{"google_sql_database_instance.instance"},
+		},
+		"keeps polling after errors": {
+			Verify:      true,
+			Remaining:   [][]string{nil, nil},
+			Errors:      []error{refreshFailed, nil},
+			ExpectCalls: 2,
+		},
+		"returns the last error": {
+			Verify:      true,
+			Remaining:   [][]string{nil},
+			Errors:      []error{refreshFailed},
+			TimesOut:    true,
+			ExpectError: refreshFailed,
+		},
+		"not verified": {
+			Verify:      false,
+			Remaining:   [][]string{{"google_sql_database_instance.instance"}},
+			ExpectCalls: 0,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			r := NewReconciler()
+			r.Verify = tc.Verify
+			r.VerifyInterval = 10 * time.Millisecond
+			r.VerifyTimeout = 55 * time.Millisecond
+
+			calls := 0
+			err := r.WaitUntilGone(context.Background(), func(ctx context.Context) ([]string, error) {
+				i := calls
+				calls++
+				if i >= len(tc.Remaining) {
+					i = len(tc.Remaining) - 1
+				}
+				if i < len(tc.Errors) && tc.Errors[i] != nil {
+					return nil, tc.Errors[i]
+				}
+				return tc.Remaining[i], nil
+			})
+
+			if tc.TimesOut && calls < 2 {
+				t.Errorf("Expected checks until the timeout, got %d", calls)
+			}
+			if !tc.TimesOut && calls != tc.ExpectCalls {
+				t.Errorf("Expected %d checks, got %d", tc.ExpectCalls, calls)
+			}
+
+			var remaining *RemainingError
+			switch {
+			case tc.ExpectRemain != nil:
+				if !errors.As(err, &remaining) || !reflect.DeepEqual(remaining.Resources, tc.ExpectRemain) {
+					t.Errorf("Expected %v to remain, got %v", tc.ExpectRemain, err)
+				}
+			case err != tc.ExpectError:
+				t.Errorf("Expected error %v, got %v", tc.ExpectError, err)
+			}
+		})
+	}
+}
+
+func TestReconciler_Retryable(t *testing.T) {
+	cases := map[string]struct {
+		Err    error
+		Expect bool
+	}{
+		"remaining":  {Err: &RemainingError{Resources: []string{"google_storage_bucket.bucket"}}, Expect: true},
+		"rate limit": {Err: &googleapi.Error{Code: 429}, Expect: true},
+		"in use":     {Err: errors.New("Error deleting network: googleapi: Error 400: The network resource is already being used, resourceInUseByAnotherResource"), Expect: true},
+		"forbidden":  {Err: &googleapi.Error{Code: 403}, Expect: false},
+		"unknown":    {Err: errors.New("exit status 1"), Expect: false},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := NewReconciler().Retryable(tc.Err); actual != tc.Expect {
+				t.Errorf("Expected %v, got %v", tc.Expect, actual)
+			}
+		})
+	}
+}
+
+func TestReconciler_Stuck(t *testing.T) {
+	r := NewReconciler()
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if r.Stuck(since, since.Add(59*time.Minute)) {
+		t.Error("Expected deletes younger than StuckAfter not to be stuck")
+	}
+	if !r.Stuck(since, since.Add(61*time.Minute)) {
+		t.Error("Expected deletes older than StuckAfter to be stuck")
+	}
+}
+
+func TestReconciler_ConfigureFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Prop        string
+		Value       interface{}
+		ExpectError bool
+	}{
+		"defaults":             {},
+		"custom interval":      {Prop: VerifyIntervalProp, Value: "1m"},
+		"invalid timeout":      {Prop: VerifyTimeoutProp, Value: "soon", ExpectError: true},
+		"negative stuck after": {Prop: StuckAfterProp, Value: "-1h", ExpectError: true},
+		"no attempts":          {Prop: MaxAttemptsProp, Value: 0, ExpectError: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if tc.Prop != "" {
+				viper.Set(tc.Prop, tc.Value)
+				defer viper.Set(tc.Prop, nil)
+			}
+
+			err := NewReconciler().ConfigureFromEnv()
+			if (err != nil) != tc.ExpectError {
+				t.Errorf("Expected error %v, got %v", tc.ExpectError, err)
+			}
+		})
+	}
+}
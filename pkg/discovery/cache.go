@@ -0,0 +1,234 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package discovery caches the results of slow provider discovery calls, like
+// listing CloudSQL tiers or compute regions, in the broker's database so
+// they're shared by every broker replica.
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+)
+
+// TtlProp is the viper key of how long discovery results are cached before
+// they're fetched again.
+const TtlProp = "discovery.ttl"
+
+func init() {
+	config.Register(config.Property{Key: TtlProp, Kind: config.Duration, Default: "1h"})
+}
+
+// FetchFunc fetches a discovery result from the provider. The result must be
+// JSON serializable.
+type FetchFunc func(ctx context.Context) (interface{}, error)
+
+// Store saves cached discovery results.
+type Store interface {
+	GetDiscoveryCacheEntry(ctx context.Context, key string) (*models.DiscoveryCacheEntry, error)
+	ListDiscoveryCacheEntries(ctx context.Context) ([]models.DiscoveryCacheEntry, error)
+	SaveDiscoveryCacheEntry(ctx context.Context, entry *models.DiscoveryCacheEntry) error
+}
+
+// databaseStore stores results in the broker's database.
+type databaseStore struct{}
+
+func (databaseStore) GetDiscoveryCacheEntry(ctx context.Context, key string) (*models.DiscoveryCacheEntry, error) {
+	return db_service.GetDiscoveryCacheEntry(ctx, key)
+}
+
+func (databaseStore) ListDiscoveryCacheEntries(ctx context.Context) ([]models.DiscoveryCacheEntry, error) {
+	return db_service.ListDiscoveryCacheEntries(ctx)
+}
+
+func (databaseStore) SaveDiscoveryCacheEntry(ctx context.Context, entry *models.DiscoveryCacheEntry) error {
+	return db_service.SaveDiscoveryCacheEntry(ctx, entry)
+}
+
+// Entry describes a cached discovery result.
+type Entry struct {
+	Key       string          `json:"key"`
+	FetchedAt time.Time       `json:"fetched_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Stale     bool            `json:"stale"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// Cache fetches discovery results from their sources and caches them for the
+// TTL. If a source fails after its result expired, the stale result is used
+// rather than failing the caller.
+type Cache struct {
+	Sources map[string]FetchFunc
+	Store   Store
+	Ttl     time.Duration
+	Logger  lager.Logger
+
+	// now is used to get the current time, it can be replaced in tests.
+	now func() time.Time
+
+	// mu serializes fetches so bursts of requests on one replica only call
+	// the provider once.
+	mu sync.Mutex
+}
+
+// NewCacheFromEnv creates a Cache for the given sources using the TTL
+// configured in viper that stores results in the broker's database.
+func NewCacheFromEnv(logger lager.Logger, sources map[string]FetchFunc) (*Cache, error) {
+	ttl, err := time.ParseDuration(viper.GetString(TtlProp))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", TtlProp, err)
+	}
+
+	return &Cache{
+		Sources: sources,
+		Store:   databaseStore{},
+		Ttl:     ttl,
+		Logger:  logger.Session("discovery"),
+	}, nil
+}
+
+func (c *Cache) currentTime() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+
+	return time.Now()
+}
+
+// Get unmarshals the result of the source with the given key into out,
+// fetching it from the source if it isn't cached or has expired.
+func (c *Cache) Get(ctx context.Context, key string, out interface{}) error {
+	entry, err := c.Store.GetDiscoveryCacheEntry(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if entry == nil || c.isExpired(entry) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		// another request may have fetched it while this one waited
+		if entry, err = c.Store.GetDiscoveryCacheEntry(ctx, key); err != nil {
+			return err
+		}
+
+		if entry == nil || c.isExpired(entry) {
+			fetched, err := c.fetch(ctx, key)
+			switch {
+			case err == nil:
+				entry = fetched
+			case entry != nil:
+				c.Logger.Error("using-stale-discovery-result", err, lager.Data{"key": key})
+			default:
+				return err
+			}
+		}
+	}
+
+	return json.Unmarshal([]byte(entry.Value), out)
+}
+
+// Refresh fetches the results of the given sources, or every source if none
+// are given, regardless of whether they've expired.
+func (c *Cache) Refresh(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		keys = c.keys()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var result *multierror.Error
+	for _, key := range keys {
+		if _, err := c.fetch(ctx, key); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+// Entries lists the cached results.
+func (c *Cache) Entries(ctx context.Context) ([]Entry, error) {
+	entries, err := c.Store.ListDiscoveryCacheEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []Entry{}
+	for _, entry := range entries {
+		out = append(out, Entry{
+			Key:       entry.CacheKey,
+			FetchedAt: entry.FetchedAt,
+			ExpiresAt: entry.FetchedAt.Add(c.Ttl),
+			Stale:     c.isExpired(&entry),
+			Value:     json.RawMessage(entry.Value),
+		})
+	}
+
+	return out, nil
+}
+
+func (c *Cache) isExpired(entry *models.DiscoveryCacheEntry) bool {
+	return !c.currentTime().Before(entry.FetchedAt.Add(c.Ttl))
+}
+
+func (c *Cache) keys() []string {
+	var keys []string
+	for key := range c.Sources {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *Cache) fetch(ctx context.Context, key string) (*models.DiscoveryCacheEntry, error) {
+	source, ok := c.Sources[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown discovery source %q", key)
+	}
+
+	value, err := source(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("discovering %s: %v", key, err)
+	}
+
+	serialized, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("serializing %s: %v", key, err)
+	}
+
+	entry := &models.DiscoveryCacheEntry{
+		CacheKey:  key,
+		Value:     string(serialized),
+		FetchedAt: c.currentTime(),
+	}
+	if err := c.Store.SaveDiscoveryCacheEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
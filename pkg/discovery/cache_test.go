@@ -0,0 +1,189 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+type fakeStore map[string]models.DiscoveryCacheEntry
+
+func (f fakeStore) GetDiscoveryCacheEntry(ctx context.Context, key string) (*models.DiscoveryCacheEntry, error) {
+	entry, ok := f[key]
+	if !ok {
+		return nil, nil
+	}
+
+	return &entry, nil
+}
+
+func (f fakeStore) ListDiscoveryCacheEntries(ctx context.Context) ([]models.DiscoveryCacheEntry, error) {
+	var entries []models.DiscoveryCacheEntry
+	for _, entry := range f {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CacheKey < entries[j].CacheKey })
+	return entries, nil
+}
+
+func (f fakeStore) SaveDiscoveryCacheEntry(ctx context.Context, entry *models.DiscoveryCacheEntry) error {
+	f[entry.CacheKey] = *entry
+	return nil
+}
+
+type fakeSource struct {
+	Calls  int
+	Values []string
+	Err    error
+}
+
+func (f *fakeSource) Fetch(ctx context.Context) (interface{}, error) {
+	f.Calls++
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	return f.Values, nil
+}
+
+func newTestCache(source *fakeSource, now *time.Time) *Cache {
+	return &Cache{
+		Sources: map[string]FetchFunc{"regions": source.Fetch},
+		Store:   fakeStore{},
+		Ttl:     time.Hour,
+		Logger:  lager.NewLogger("test"),
+		now:     func() time.Time { return *now },
+	}
+}
+
+func TestCache_Get(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		Elapsed       time.Duration
+		FailRefetch   bool
+		ExpectedCalls int
+		ExpectedErr   bool
+	}{
+		"fresh": {
+			Elapsed:       30 * time.Minute,
+			ExpectedCalls: 1,
+		},
+		"expired": {
+			Elapsed:       time.Hour,
+			ExpectedCalls: 2,
+		},
+		"expired and source failing uses stale result": {
+			Elapsed:       2 * time.Hour,
+			FailRefetch:   true,
+			ExpectedCalls: 2,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			now := start
+			source := &fakeSource{Values: []string{"us-central1", "europe-west1"}}
+			cache := newTestCache(source, &now)
+
+			var first []string
+			if err := cache.Get(context.Background(), "regions", &first); err != nil {
+				t.Fatal(err)
+			}
+
+			now = start.Add(tc.Elapsed)
+			if tc.FailRefetch {
+				source.Err = errors.New("unavailable")
+			}
+
+			var second []string
+			if err := cache.Get(context.Background(), "regions", &second); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(second, source.Values) {
+				t.Errorf("Expected %v, got %v", source.Values, second)
+			}
+
+			if source.Calls != tc.ExpectedCalls {
+				t.Errorf("Expected %d fetches, got %d", tc.ExpectedCalls, source.Calls)
+			}
+		})
+	}
+}
+
+func TestCache_Get_errors(t *testing.T) {
+	now := time.Now()
+	cache := newTestCache(&fakeSource{Err: errors.New("unavailable")}, &now)
+
+	var out []string
+	if err := cache.Get(context.Background(), "regions", &out); err == nil {
+		t.Error("Expected an error when nothing is cached and the source fails")
+	}
+
+	if err := cache.Get(context.Background(), "tiers", &out); err == nil {
+		t.Error("Expected an error for an unknown source")
+	}
+}
+
+func TestCache_Refresh(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := &fakeSource{Values: []string{"us-central1"}}
+	cache := newTestCache(source, &now)
+
+	if err := cache.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.Refresh(context.Background(), "regions"); err != nil {
+		t.Fatal(err)
+	}
+
+	if source.Calls != 2 {
+		t.Errorf("Expected refresh to fetch every time, got %d fetches", source.Calls)
+	}
+
+	if err := cache.Refresh(context.Background(), "tiers"); err == nil {
+		t.Error("Expected an error refreshing an unknown source")
+	}
+
+	now = now.Add(2 * time.Hour)
+	entries, err := cache.Entries(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %v", entries)
+	}
+
+	entry := entries[0]
+	if entry.Key != "regions" || !entry.Stale || string(entry.Value) != `["us-central1"]` {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+
+	if !entry.ExpiresAt.Equal(entry.FetchedAt.Add(time.Hour)) {
+		t.Errorf("Expected entry to expire after the TTL, got %v", entry.ExpiresAt)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"sort"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
+	"github.com/pivotal/cloud-service-broker/utils"
+	compute "google.golang.org/api/compute/v1"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+// Keys of the Google Cloud discovery sources.
+const (
+	CloudSqlTiersKey    = "cloudsql.tiers"
+	CloudSqlVersionsKey = "cloudsql.database_versions"
+	ComputeRegionsKey   = "compute.regions"
+)
+
+// CloudSqlTier describes a CloudSQL machine tier.
+type CloudSqlTier struct {
+	Tier      string   `json:"tier"`
+	RamBytes  int64    `json:"ram_bytes"`
+	DiskQuota int64    `json:"disk_quota_bytes"`
+	Regions   []string `json:"regions"`
+}
+
+// NewGcpCacheFromEnv creates a Cache of the Google Cloud discovery sources
+// for the broker's service account and default project.
+func NewGcpCacheFromEnv(logger lager.Logger) (*Cache, error) {
+	clients, err := gcpclient.NewFactoryFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := utils.GetDefaultProjectId()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCacheFromEnv(logger, GcpSources(project, clients))
+}
+
+// GcpSources returns the Google Cloud discovery sources for the project. The
+// clients must be authorized to call the CloudSQL Admin and Compute APIs.
+func GcpSources(project string, clients *gcpclient.Factory) map[string]FetchFunc {
+	return map[string]FetchFunc{
+		CloudSqlTiersKey: func(ctx context.Context) (interface{}, error) {
+			return cloudSqlTiers(ctx, project, clients)
+		},
+		CloudSqlVersionsKey: func(ctx context.Context) (interface{}, error) {
+			return cloudSqlVersions(ctx, clients)
+		},
+		ComputeRegionsKey: func(ctx context.Context) (interface{}, error) {
+			return computeRegions(ctx, project, clients)
+		},
+	}
+}
+
+func cloudSqlTiers(ctx context.Context, project string, clients *gcpclient.Factory) ([]CloudSqlTier, error) {
+	service, err := sqladmin.NewService(ctx, clients.Options(ctx, "sqladmin")...)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := service.Tiers.List(project).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	tiers := []CloudSqlTier{}
+	for _, tier := range resp.Items {
+		tiers = append(tiers, CloudSqlTier{Tier: tier.Tier, RamBytes: tier.RAM, DiskQuota: tier.DiskQuota, Regions: tier.Region})
+	}
+
+	return tiers, nil
+}
+
+// cloudSqlVersions lists the database versions CloudSQL supports flags for,
+// the Admin API has no call that lists them directly.
+func cloudSqlVersions(ctx context.Context, clients *gcpclient.Factory) ([]string, error) {
+	service, err := sqladmin.NewService(ctx, clients.Options(ctx, "sqladmin")...)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := service.Flags.List().Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	versions := []string{}
+	for _, flag := range resp.Items {
+		for _, version := range flag.AppliesTo {
+			if !seen[version] {
+				seen[version] = true
+				versions = append(versions, version)
+			}
+		}
+	}
+
+	sort.Strings(versions)
+	return versions, nil
+}
+
+func computeRegions(ctx context.Context, project string, clients *gcpclient.Factory) ([]string, error) {
+	service, err := compute.NewService(ctx, clients.Options(ctx, "compute")...)
+	if err != nil {
+		return nil, err
+	}
+
+	regions := []string{}
+	err = service.Regions.List(project).Pages(ctx, func(page *compute.RegionList) error {
+		for _, region := range page.Items {
+			regions = append(regions, region.Name)
+		}
+		return nil
+	})
+
+	return regions, err
+}
@@ -0,0 +1,262 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package events publishes the lifecycle events of service instances and
+// bindings to sinks operators configure, e.g. a webhook or a Pub/Sub topic, so
+// platform teams can automate on what the broker does.
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pborman/uuid"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
+	"github.com/pivotal/cloud-service-broker/pkg/retry"
+	"github.com/spf13/viper"
+)
+
+const (
+	// WebhookUrlProp is the viper key of the URL events are POSTed to.
+	WebhookUrlProp = "events.webhook_url"
+
+	// WebhookSecretProp is the viper key of the secret webhook requests are
+	// signed with.
+	WebhookSecretProp = "events.webhook_secret"
+
+	// PubSubTopicProp is the viper key of the Pub/Sub topic events are
+	// published to, e.g. projects/my-project/topics/broker-events.
+	PubSubTopicProp = "events.pubsub_topic"
+
+	// TypesProp is the viper key of the comma delimited event types that are
+	// published, every type is if it's empty.
+	TypesProp = "events.types"
+)
+
+// Types of events.
+const (
+	InstanceProvisioned = "instance-provisioned"
+	ProvisionFailed     = "provision-failed"
+	Bound               = "bound"
+	Unbound             = "unbound"
+	Deprovisioned       = "deprovisioned"
+)
+
+// AllTypes holds every type of event.
+var AllTypes = []string{InstanceProvisioned, ProvisionFailed, Bound, Unbound, Deprovisioned}
+
+var deliveryRetryPolicy = retry.Policies.Policy("events", "Delivering lifecycle events to webhooks and Pub/Sub.", retry.Defaults{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Retryable:      []retry.ErrorClass{retry.RateLimit, retry.ServerError, retry.Network},
+})
+
+func init() {
+	config.Register(
+		config.Property{Key: WebhookUrlProp, Kind: config.String},
+		config.Property{Key: WebhookSecretProp, Kind: config.String, Sensitive: true},
+		config.Property{Key: PubSubTopicProp, Kind: config.String},
+		config.Property{Key: TypesProp, Kind: config.String},
+	)
+}
+
+// Event is something that happened to a service instance or binding.
+type Event struct {
+	// Id is unique to the event so sinks can drop duplicate deliveries.
+	Id               string    `json:"id"`
+	Type             string    `json:"type"`
+	Timestamp        time.Time `json:"timestamp"`
+	InstanceId       string    `json:"instance_id"`
+	BindingId        string    `json:"binding_id,omitempty"`
+	ServiceId        string    `json:"service_id"`
+	ServiceName      string    `json:"service_name,omitempty"`
+	PlanId           string    `json:"plan_id"`
+	PlanName         string    `json:"plan_name,omitempty"`
+	OrganizationGuid string    `json:"organization_guid"`
+	SpaceGuid        string    `json:"space_guid"`
+
+	// Error is why the operation failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Sink delivers events to a destination.
+type Sink interface {
+	// Name identifies the sink in logs.
+	Name() string
+	Send(ctx context.Context, event Event) error
+}
+
+// Bus delivers events to every sink in the background so brokering doesn't
+// wait for them. Deliveries that fail are retried using the "events" retry
+// policy, then logged and dropped.
+type Bus struct {
+	Sinks []Sink
+
+	// Types holds the types of events that are delivered, every type is if
+	// it's empty.
+	Types []string
+
+	Logger lager.Logger
+
+	// Timeout bounds each delivery attempt.
+	Timeout time.Duration
+
+	// now is used to get the current time, it can be replaced in tests.
+	now func() time.Time
+
+	pending sync.WaitGroup
+}
+
+// NewBusFromEnv creates a Bus with the sinks configured in viper. A Bus
+// without sinks drops every event.
+func NewBusFromEnv(logger lager.Logger) (*Bus, error) {
+	types, err := parseTypes(viper.GetString(TypesProp))
+	if err != nil {
+		return nil, err
+	}
+
+	bus := &Bus{Types: types, Logger: logger.Session("events"), Timeout: 30 * time.Second}
+
+	if url := viper.GetString(WebhookUrlProp); url != "" {
+		bus.Sinks = append(bus.Sinks, NewWebhookSink(url, viper.GetString(WebhookSecretProp)))
+	}
+
+	if topic := viper.GetString(PubSubTopicProp); topic != "" {
+		if !validTopic(topic) {
+			return nil, fmt.Errorf("%s must be a topic name like projects/PROJECT/topics/TOPIC, got %q", PubSubTopicProp, topic)
+		}
+
+		clients, err := gcpclient.NewFactoryFromEnv()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create a Pub/Sub client: %v", err)
+		}
+
+		bus.Sinks = append(bus.Sinks, &PubSubSink{Topic: topic, Clients: clients})
+	}
+
+	return bus, nil
+}
+
+// parseTypes parses a comma delimited list of event types.
+func parseTypes(text string) ([]string, error) {
+	var types []string
+	for _, eventType := range strings.Split(text, ",") {
+		eventType = strings.TrimSpace(eventType)
+		if eventType == "" {
+			continue
+		}
+
+		if !contains(AllTypes, eventType) {
+			return nil, fmt.Errorf("%s has unknown event type %q, must be one of %s", TypesProp, eventType, strings.Join(AllTypes, ", "))
+		}
+		types = append(types, eventType)
+	}
+
+	return types, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Publish delivers the event to every sink in the background, filling in its
+// ID and timestamp if they aren't set. Events of types that aren't enabled
+// are dropped.
+func (b *Bus) Publish(event Event) {
+	if len(b.Sinks) == 0 || (len(b.Types) > 0 && !contains(b.Types, event.Type)) {
+		return
+	}
+
+	if event.Id == "" {
+		event.Id = uuid.New()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = b.currentTime().UTC()
+	}
+
+	for _, sink := range b.Sinks {
+		b.pending.Add(1)
+		go func(sink Sink) {
+			defer b.pending.Done()
+			b.deliver(sink, event)
+		}(sink)
+	}
+}
+
+// deliver sends the event to the sink, retrying failures.
+func (b *Bus) deliver(sink Sink, event Event) {
+	err := deliveryRetryPolicy.Do(context.Background(), func() error {
+		ctx := context.Background()
+		if b.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, b.Timeout)
+			defer cancel()
+		}
+
+		return sink.Send(ctx, event)
+	})
+
+	data := lager.Data{"sink": sink.Name(), "event_id": event.Id, "type": event.Type, "instance_id": event.InstanceId}
+	if err != nil {
+		b.logger().Error("delivering-event", err, data)
+		return
+	}
+
+	b.logger().Debug("delivered-event", data)
+}
+
+// Drain waits for events that are being delivered until the context is done.
+func (b *Bus) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		b.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("events are still being delivered: %v", ctx.Err())
+	}
+}
+
+func (b *Bus) logger() lager.Logger {
+	if b.Logger == nil {
+		return lager.NewLogger("events")
+	}
+
+	return b.Logger
+}
+
+func (b *Bus) currentTime() time.Time {
+	if b.now == nil {
+		return time.Now()
+	}
+
+	return b.now()
+}
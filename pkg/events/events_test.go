@@ -0,0 +1,296 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/spf13/viper"
+)
+
+// recordingSink keeps the events it's sent, failing the first Failures
+// sends.
+type recordingSink struct {
+	Failures int
+
+	mu     sync.Mutex
+	sends  int
+	events []Event
+}
+
+func (s *recordingSink) Name() string {
+	return "recording"
+}
+
+func (s *recordingSink) Send(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sends++
+	if s.sends <= s.Failures {
+		return &webhookError{status: "503 Service Unavailable", code: http.StatusServiceUnavailable}
+	}
+
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestBus_Publish(t *testing.T) {
+	viper.Set("retry.events.initial_backoff", "1ms")
+	defer viper.Set("retry.events.initial_backoff", nil)
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		Types         []string
+		Failures      int
+		Event         Event
+		ExpectedSends int
+		ExpectedCount int
+	}{
+		"delivered": {
+			Event:         Event{Type: Bound, InstanceId: "instance-1", BindingId: "binding-1"},
+			ExpectedSends: 1,
+			ExpectedCount: 1,
+		},
+		"enabled type": {
+			Types:         []string{Bound, Unbound},
+			Event:         Event{Type: Bound, InstanceId: "instance-1"},
+			ExpectedSends: 1,
+			ExpectedCount: 1,
+		},
+		"disabled type": {
+			Types:         []string{Deprovisioned},
+			Event:         Event{Type: Bound, InstanceId: "instance-1"},
+			ExpectedSends: 0,
+			ExpectedCount: 0,
+		},
+		"retried": {
+			Failures:      2,
+			Event:         Event{Type: ProvisionFailed, InstanceId: "instance-1", Error: "boom"},
+			ExpectedSends: 3,
+			ExpectedCount: 1,
+		},
+		"dropped": {
+			Failures:      10,
+			Event:         Event{Type: InstanceProvisioned, InstanceId: "instance-1"},
+			ExpectedSends: 5,
+			ExpectedCount: 0,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			sink := &recordingSink{Failures: tc.Failures}
+			bus := &Bus{Sinks: []Sink{sink}, Types: tc.Types, now: func() time.Time { return now }}
+
+			bus.Publish(tc.Event)
+			if err := bus.Drain(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if sink.sends != tc.ExpectedSends {
+				t.Errorf("Expected %d sends, got %d", tc.ExpectedSends, sink.sends)
+			}
+
+			if len(sink.events) != tc.ExpectedCount {
+				t.Fatalf("Expected %d events, got %v", tc.ExpectedCount, sink.events)
+			}
+
+			for _, event := range sink.events {
+				if event.Id == "" {
+					t.Error("Expected the event to get an ID")
+				}
+
+				if !event.Timestamp.Equal(now) {
+					t.Errorf("Expected the timestamp %v, got %v", now, event.Timestamp)
+				}
+
+				event.Id, event.Timestamp = "", time.Time{}
+				if !reflect.DeepEqual(tc.Event, event) {
+					t.Errorf("Expected %#v, got %#v", tc.Event, event)
+				}
+			}
+		})
+	}
+}
+
+func TestBus_Drain(t *testing.T) {
+	release := make(chan struct{})
+	bus := &Bus{Sinks: []Sink{blockingSink(release)}}
+	bus.Publish(Event{Type: Unbound})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bus.Drain(ctx); err == nil {
+		t.Error("Expected an error draining while an event is being delivered")
+	}
+
+	close(release)
+	if err := bus.Drain(context.Background()); err != nil {
+		t.Errorf("Expected no error once the event was delivered, got %v", err)
+	}
+}
+
+// blockingSink is a Sink whose sends wait for the channel to be closed.
+type blockingSink chan struct{}
+
+func (s blockingSink) Name() string {
+	return "blocking"
+}
+
+func (s blockingSink) Send(ctx context.Context, event Event) error {
+	<-s
+	return nil
+}
+
+func TestWebhookSink_Send(t *testing.T) {
+	cases := map[string]struct {
+		Secret     string
+		Status     int
+		ExpectErr  bool
+		ExpectCode int
+	}{
+		"signed":   {Secret: "s3cret", Status: http.StatusNoContent},
+		"unsigned": {Status: http.StatusOK},
+		"rejected": {Secret: "s3cret", Status: http.StatusBadGateway, ExpectErr: true, ExpectCode: http.StatusBadGateway},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			var received Event
+			var header http.Header
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header
+				body, _ = ioutil.ReadAll(r.Body)
+				if err := json.Unmarshal(body, &received); err != nil {
+					t.Error(err)
+				}
+				w.WriteHeader(tc.Status)
+			}))
+			defer server.Close()
+
+			sink := &WebhookSink{Url: server.URL, Secret: tc.Secret, Client: server.Client()}
+			event := Event{Id: "event-1", Type: Bound, Timestamp: time.Unix(1790000000, 0).UTC(), InstanceId: "instance-1", BindingId: "binding-1"}
+			err := sink.Send(context.Background(), event)
+
+			if hasErr := err != nil; hasErr != tc.ExpectErr {
+				t.Errorf("Expected error: %v, got %v", tc.ExpectErr, err)
+			}
+
+			var coder interface{ StatusCode() int }
+			if tc.ExpectCode != 0 && (!errors.As(err, &coder) || coder.StatusCode() != tc.ExpectCode) {
+				t.Errorf("Expected an error with status %d, got %v", tc.ExpectCode, err)
+			}
+
+			if !reflect.DeepEqual(event, received) {
+				t.Errorf("Expected %#v to be delivered, got %#v", event, received)
+			}
+
+			if actual := header.Get(EventTypeHeader); actual != Bound {
+				t.Errorf("Expected the event type header %q, got %q", Bound, actual)
+			}
+
+			signature := header.Get(SignatureHeader)
+			if tc.Secret == "" {
+				if signature != "" {
+					t.Errorf("Expected no signature without a secret, got %q", signature)
+				}
+				return
+			}
+
+			if actual := header.Get(TimestampHeader); actual != "1790000000" {
+				t.Errorf("Expected the timestamp header 1790000000, got %q", actual)
+			}
+
+			if expected := "sha256=" + Sign(tc.Secret, "1790000000", body); signature != expected {
+				t.Errorf("Expected the signature %q, got %q", expected, signature)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1790000000.{}' | openssl dgst -sha256 -hmac s3cret
+	expected := "0d84afbca530158326e0549a7a1c3579755f9b8ba4cceba2c143dbe14147b60f"
+	if actual := Sign("s3cret", "1790000000", []byte("{}")); actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+}
+
+func TestNewBusFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Config        map[string]string
+		ExpectedSinks []string
+		ExpectedTypes []string
+		ExpectErr     bool
+	}{
+		"nothing configured": {},
+		"webhook": {
+			Config:        map[string]string{WebhookUrlProp: "https://example.com/hook", TypesProp: "bound, unbound"},
+			ExpectedSinks: []string{"webhook"},
+			ExpectedTypes: []string{Bound, Unbound},
+		},
+		"unknown type": {
+			Config:    map[string]string{WebhookUrlProp: "https://example.com/hook", TypesProp: "bound,created"},
+			ExpectErr: true,
+		},
+		"bad topic": {
+			Config:    map[string]string{PubSubTopicProp: "broker-events"},
+			ExpectErr: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			for key, value := range tc.Config {
+				viper.Set(key, value)
+				defer viper.Set(key, nil)
+			}
+
+			bus, err := NewBusFromEnv(lager.NewLogger("test"))
+			if hasErr := err != nil; hasErr != tc.ExpectErr {
+				t.Fatalf("Expected error: %v, got %v", tc.ExpectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			var sinks []string
+			for _, sink := range bus.Sinks {
+				sinks = append(sinks, sink.Name())
+			}
+
+			if !reflect.DeepEqual(tc.ExpectedSinks, sinks) {
+				t.Errorf("Expected sinks %v, got %v", tc.ExpectedSinks, sinks)
+			}
+
+			if !reflect.DeepEqual(tc.ExpectedTypes, bus.Types) {
+				t.Errorf("Expected types %v, got %v", tc.ExpectedTypes, bus.Types)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
+	"github.com/pivotal/cloud-service-broker/utils"
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+const (
+	// SignatureHeader holds the hex encoded HMAC-SHA256 of the timestamp
+	// header, a period and the body of webhook requests, prefixed with
+	// "sha256=".
+	SignatureHeader = "X-Broker-Signature"
+
+	// TimestampHeader holds the Unix time webhook requests were signed at so
+	// receivers can reject replayed requests.
+	TimestampHeader = "X-Broker-Timestamp"
+
+	// EventTypeHeader holds the type of the event in webhook requests.
+	EventTypeHeader = "X-Broker-Event"
+)
+
+// WebhookSink POSTs events as JSON to a URL, signed with the secret if it's
+// set.
+type WebhookSink struct {
+	Url    string
+	Secret string
+	Client *http.Client
+}
+
+// NewWebhookSink creates a WebhookSink.
+func NewWebhookSink(url, secret string) *WebhookSink {
+	return &WebhookSink{Url: url, Secret: secret, Client: &http.Client{}}
+}
+
+// Name implements Sink.
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+// Send implements Sink. Responses with a status of 300 or more are errors
+// that carry the status so server errors are retried.
+func (s *WebhookSink) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", utils.CustomUserAgent)
+	req.Header.Set(EventTypeHeader, event.Type)
+
+	if s.Secret != "" {
+		timestamp := strconv.FormatInt(event.Timestamp.Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+Sign(s.Secret, timestamp, body))
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't send event: %v", err)
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return &webhookError{status: resp.Status, code: resp.StatusCode}
+	}
+
+	return nil
+}
+
+// Sign computes the hex encoded HMAC-SHA256 of the timestamp, a period and the
+// body with the secret, receivers compare it to the SignatureHeader.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookError is a response with an unsuccessful status, it's a
+// retry.StatusCoder.
+type webhookError struct {
+	status string
+	code   int
+}
+
+func (e *webhookError) Error() string {
+	return fmt.Sprintf("couldn't send event, webhook responded with: %s", e.status)
+}
+
+func (e *webhookError) StatusCode() int {
+	return e.code
+}
+
+// topicPattern matches the full names of Pub/Sub topics.
+var topicPattern = regexp.MustCompile(`^projects/[^/]+/topics/[^/]+$`)
+
+func validTopic(topic string) bool {
+	return topicPattern.MatchString(topic)
+}
+
+// PubSubSink publishes events as JSON messages to a Pub/Sub topic. Messages
+// have the event's ID and type as the id and type attributes so subscriptions
+// can filter on them.
+type PubSubSink struct {
+	// Topic is the full name of the topic, e.g.
+	// projects/my-project/topics/broker-events.
+	Topic   string
+	Clients *gcpclient.Factory
+}
+
+// Name implements Sink.
+func (s *PubSubSink) Name() string {
+	return "pubsub"
+}
+
+// Send implements Sink.
+func (s *PubSubSink) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	service, err := pubsub.NewService(ctx, s.Clients.Options(ctx, "pubsub")...)
+	if err != nil {
+		return err
+	}
+
+	_, err = service.Projects.Topics.Publish(s.Topic, &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{
+			Data:       base64.StdEncoding.EncodeToString(body),
+			Attributes: map[string]string{"id": event.Id, "type": event.Type},
+		}},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("couldn't publish event to %s: %w", s.Topic, err)
+	}
+
+	return nil
+}
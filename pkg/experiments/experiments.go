@@ -0,0 +1,242 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package experiments lets operators canary experimental behaviors, like a
+// new provider implementation, on selected instances before enabling them for
+// every instance.
+//
+// Experiments are registered by the code that implements them. Each one can
+// be enabled globally with a toggle, or for a single request with a header
+// signed using a key only the operator knows. Experiments enabled for a
+// request that creates or updates an instance are recorded with it so later
+// operations on the instance behave the same way.
+package experiments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/pkg/toggles"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Header lists the experiments to enable for a request, comma delimited.
+	Header = "X-Broker-Experiments"
+
+	// SignatureHeader holds the hex encoded HMAC-SHA256 signature of the
+	// request's experiments, see Sign.
+	SignatureHeader = "X-Broker-Experiments-Signature"
+
+	// TimestampHeader holds the Unix time the experiments were signed at.
+	TimestampHeader = "X-Broker-Experiments-Timestamp"
+
+	// MaxSkew is how far the signing time can be from the broker's clock
+	// before a signature is rejected, so captured headers can't be replayed
+	// later.
+	MaxSkew = 5 * time.Minute
+
+	// SigningKeyProp is the viper key of the key experiment headers are
+	// signed with. Headers are rejected if it's empty.
+	SigningKeyProp = "experiments.signing_key"
+)
+
+// Globals holds the toggles that enable experiments for every request.
+var Globals = toggles.NewToggleSet("experiments.")
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]*Experiment)
+)
+
+// Experiment is an experimental behavior that can be enabled per request.
+type Experiment struct {
+	Name        string
+	Description string
+
+	global toggles.Toggle
+}
+
+// Register adds an experiment to the allow-list of experiments requests can
+// enable. It panics if the name is already registered or contains a comma.
+func Register(name, description string) *Experiment {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok || name == "" || strings.ContainsAny(name, ", ") {
+		panic(fmt.Sprintf("invalid or duplicate experiment name %q", name))
+	}
+
+	experiment := &Experiment{
+		Name:        name,
+		Description: description,
+		global:      Globals.Toggle(name, false, description),
+	}
+	registry[name] = experiment
+	return experiment
+}
+
+// Registered lists the registered experiments sorted by name.
+func Registered() []*Experiment {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	var out []*Experiment
+	for _, experiment := range registry {
+		out = append(out, experiment)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func lookup(name string) (*Experiment, bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	experiment, ok := registry[name]
+	return experiment, ok
+}
+
+// Enabled returns true if the experiment is enabled globally or for the
+// request or instance the context belongs to.
+func (e *Experiment) Enabled(ctx context.Context) bool {
+	if e.global.IsActive() {
+		return true
+	}
+
+	for _, name := range FromContext(ctx) {
+		if name == e.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+type contextKey struct{}
+
+// WithEnabled returns a context with the given experiments enabled in
+// addition to any already enabled in ctx.
+func WithEnabled(ctx context.Context, names ...string) context.Context {
+	merged := Normalize(append(FromContext(ctx), names...))
+	if len(merged) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, contextKey{}, merged)
+}
+
+// FromContext lists the experiments enabled in the context, not including
+// globally enabled ones.
+func FromContext(ctx context.Context) []string {
+	names, _ := ctx.Value(contextKey{}).([]string)
+	return names
+}
+
+// Normalize sorts the names and removes blanks and duplicates.
+func Normalize(names []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		out = append(out, name)
+	}
+
+	sort.Strings(out)
+	return out
+}
+
+// Parse splits a comma delimited list of experiments.
+func Parse(list string) []string {
+	return Normalize(strings.Split(list, ","))
+}
+
+// Sign computes the signature of the experiments enabled for a request. The
+// method and path are signed so a header can't be reused for other
+// instances or operations, and the timestamp, the Unix time in the
+// TimestampHeader, so it can't be reused once it's older than MaxSkew.
+func Sign(key, method, path, timestamp string, names []string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s", method, path, timestamp, strings.Join(Normalize(names), ","))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Wrap enables the experiments listed in a request's header after checking
+// its signature and that every experiment is registered. Requests with
+// invalid headers are rejected with 400 Bad Request.
+func Wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(Header)
+		if header == "" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		names, err := verify(r, header)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"description": err.Error()})
+			return
+		}
+
+		handler.ServeHTTP(w, r.WithContext(WithEnabled(r.Context(), names...)))
+	})
+}
+
+func verify(r *http.Request, header string) ([]string, error) {
+	key := viper.GetString(SigningKeyProp)
+	if key == "" {
+		return nil, fmt.Errorf("%s isn't accepted because %s isn't configured", Header, SigningKeyProp)
+	}
+
+	names := Parse(header)
+	for _, name := range names {
+		if _, ok := lookup(name); !ok {
+			return nil, fmt.Errorf("unknown experiment %q", name)
+		}
+	}
+
+	timestamp := r.Header.Get(TimestampHeader)
+	expected := Sign(key, r.Method, r.URL.Path, timestamp, names)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(r.Header.Get(SignatureHeader)))) {
+		return nil, fmt.Errorf("%s doesn't match %s", SignatureHeader, Header)
+	}
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a Unix time", TimestampHeader)
+	}
+	if skew := time.Since(time.Unix(signedAt, 0)); skew > MaxSkew || skew < -MaxSkew {
+		return nil, fmt.Errorf("%s was signed more than %v from the broker's time", Header, MaxSkew)
+	}
+
+	return names, nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package experiments
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var testExperiment = Register("test-provisioner", "Use the test provisioner.")
+
+func TestExperiment_Enabled(t *testing.T) {
+	if testExperiment.Enabled(context.Background()) {
+		t.Error("Expected the experiment to be disabled by default")
+	}
+
+	if !testExperiment.Enabled(WithEnabled(context.Background(), "other", "test-provisioner")) {
+		t.Error("Expected the experiment to be enabled by the context")
+	}
+
+	viper.Set("experiments.test-provisioner", true)
+	defer viper.Set("experiments.test-provisioner", nil)
+	if !testExperiment.Enabled(context.Background()) {
+		t.Error("Expected the experiment to be enabled globally")
+	}
+}
+
+func TestWithEnabled(t *testing.T) {
+	ctx := WithEnabled(context.Background(), "b", " a ")
+	ctx = WithEnabled(ctx, "a", "c", "")
+
+	if actual, expected := FromContext(ctx), []string{"a", "b", "c"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	if actual := FromContext(WithEnabled(context.Background())); actual != nil {
+		t.Errorf("Expected no experiments, got %v", actual)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	for _, name := range []string{"test-provisioner", "", "a,b"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected registering %q to panic", name)
+				}
+			}()
+			Register(name, "")
+		}()
+	}
+
+	found := false
+	for _, experiment := range Registered() {
+		found = found || experiment == testExperiment
+	}
+	if !found {
+		t.Error("Expected the test experiment to be registered")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	const key = "operator-key"
+	const path = "/v2/service_instances/instance-1"
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-MaxSkew-time.Minute).Unix(), 10)
+	signature := Sign(key, http.MethodPut, path, now, []string{"test-provisioner"})
+
+	cases := map[string]struct {
+		Key                 string
+		Method              string
+		Experiments         string
+		Timestamp           string
+		Signature           string
+		ExpectedStatus      int
+		ExpectedExperiments []string
+		ExpectedBody        string
+	}{
+		"no header": {
+			Key:            key,
+			Method:         http.MethodPut,
+			ExpectedStatus: http.StatusOK,
+		},
+		"signed": {
+			Key:                 key,
+			Method:              http.MethodPut,
+			Experiments:         "test-provisioner",
+			Timestamp:           now,
+			Signature:           strings.ToUpper(signature),
+			ExpectedStatus:      http.StatusOK,
+			ExpectedExperiments: []string{"test-provisioner"},
+		},
+		"bad signature": {
+			Key:            key,
+			Method:         http.MethodPut,
+			Experiments:    "test-provisioner",
+			Timestamp:      now,
+			Signature:      Sign("guess", http.MethodPut, path, now, []string{"test-provisioner"}),
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   "doesn't match",
+		},
+		"signed for another method": {
+			Key:            key,
+			Method:         http.MethodDelete,
+			Experiments:    "test-provisioner",
+			Timestamp:      now,
+			Signature:      signature,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   "doesn't match",
+		},
+		"unknown experiment": {
+			Key:            key,
+			Method:         http.MethodPut,
+			Experiments:    "test-provisioner,unregistered",
+			Timestamp:      now,
+			Signature:      Sign(key, http.MethodPut, path, now, []string{"test-provisioner", "unregistered"}),
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `unknown experiment \"unregistered\"`,
+		},
+		"timestamp changed": {
+			Key:            key,
+			Method:         http.MethodPut,
+			Experiments:    "test-provisioner",
+			Timestamp:      stale,
+			Signature:      signature,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   "doesn't match",
+		},
+		"replayed": {
+			Key:            key,
+			Method:         http.MethodPut,
+			Experiments:    "test-provisioner",
+			Timestamp:      stale,
+			Signature:      Sign(key, http.MethodPut, path, stale, []string{"test-provisioner"}),
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   "signed more than 5m0s from the broker's time",
+		},
+		"no timestamp": {
+			Key:            key,
+			Method:         http.MethodPut,
+			Experiments:    "test-provisioner",
+			Signature:      Sign(key, http.MethodPut, path, "", []string{"test-provisioner"}),
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   "X-Broker-Experiments-Timestamp must be a Unix time",
+		},
+		"no signing key": {
+			Method:         http.MethodPut,
+			Experiments:    "test-provisioner",
+			Timestamp:      now,
+			Signature:      signature,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   "experiments.signing_key isn't configured",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(SigningKeyProp, tc.Key)
+			defer viper.Set(SigningKeyProp, nil)
+
+			var actualExperiments []string
+			handler := Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actualExperiments = FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(tc.Method, path, nil)
+			if tc.Experiments != "" {
+				req.Header.Set(Header, tc.Experiments)
+				req.Header.Set(SignatureHeader, tc.Signature)
+				req.Header.Set(TimestampHeader, tc.Timestamp)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if !reflect.DeepEqual(actualExperiments, tc.ExpectedExperiments) {
+				t.Errorf("Expected experiments %v, got %v", tc.ExpectedExperiments, actualExperiments)
+			}
+
+			if !strings.Contains(w.Body.String(), tc.ExpectedBody) {
+				t.Errorf("Expected body to contain %q, got %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
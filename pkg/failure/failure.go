@@ -0,0 +1,194 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package failure classifies the errors that fail asynchronous operations
+// into a small set of codes so platform automation can decide whether to
+// retry an operation, escalate it to an operator, or clean it up.
+package failure
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/pkg/retry"
+	"google.golang.org/api/googleapi"
+)
+
+// Code is the machine readable category of a failure.
+type Code string
+
+const (
+	// RateLimited failures were throttled by the cloud provider.
+	RateLimited Code = "rate_limited"
+	// QuotaExceeded failures need the operator to raise a quota.
+	QuotaExceeded Code = "quota_exceeded"
+	// Conflict failures raced another change to the same resource.
+	Conflict Code = "conflict"
+	// AlreadyExists failures found a resource left behind, e.g. by an earlier
+	// failed operation, that needs cleaning up.
+	AlreadyExists Code = "already_exists"
+	// BackendUnavailable failures are transient errors of the provider.
+	BackendUnavailable Code = "backend_unavailable"
+	// Network failures couldn't reach the provider or the broker's database.
+	Network Code = "network"
+	// Timeout failures gave up waiting for the provider.
+	Timeout Code = "timeout"
+	// PermissionDenied failures need the broker's credentials fixed.
+	PermissionDenied Code = "permission_denied"
+	// InvalidRequest failures were rejected because of the parameters.
+	InvalidRequest Code = "invalid_request"
+	// NotFound failures referenced a resource that doesn't exist.
+	NotFound Code = "not_found"
+	// Unknown failures couldn't be classified.
+	Unknown Code = "unknown"
+)
+
+// Info describes a failure for automation.
+type Info struct {
+	Code Code
+	// Retryable is true if running the same operation again is likely to
+	// succeed without anyone changing anything.
+	Retryable bool
+	// RetryAfter is how long to wait before retrying, if Retryable.
+	RetryAfter time.Duration
+}
+
+// taxonomy holds the retry advice of each code.
+var taxonomy = map[Code]Info{
+	RateLimited:        {Code: RateLimited, Retryable: true, RetryAfter: time.Minute},
+	QuotaExceeded:      {Code: QuotaExceeded},
+	Conflict:           {Code: Conflict, Retryable: true, RetryAfter: 30 * time.Second},
+	AlreadyExists:      {Code: AlreadyExists},
+	BackendUnavailable: {Code: BackendUnavailable, Retryable: true, RetryAfter: 30 * time.Second},
+	Network:            {Code: Network, Retryable: true, RetryAfter: 10 * time.Second},
+	Timeout:            {Code: Timeout, Retryable: true, RetryAfter: time.Minute},
+	PermissionDenied:   {Code: PermissionDenied},
+	InvalidRequest:     {Code: InvalidRequest},
+	NotFound:           {Code: NotFound},
+	Unknown:            {Code: Unknown},
+}
+
+// ForCode returns the retry advice of a code.
+func ForCode(code Code) Info {
+	if info, ok := taxonomy[code]; ok {
+		return info
+	}
+
+	return taxonomy[Unknown]
+}
+
+// messagePatterns classify errors by their text, for failures only known by
+// their message like the output of a failed Terraform run. They're checked
+// in order so more specific patterns come first.
+var messagePatterns = []struct {
+	Code    Code
+	Pattern *regexp.Regexp
+}{
+	{QuotaExceeded, regexp.MustCompile(`(?i)quota.?exceeded|exceeded.*quota|LimitExceeded\b`)},
+	{RateLimited, regexp.MustCompile(`(?i)rate.?limit|Throttl|TooManyRequests|RequestLimitExceeded`)},
+	{AlreadyExists, regexp.MustCompile(`(?i)already.?exists|AlreadyOwnedByYou`)},
+	{Conflict, regexp.MustCompile(`(?i)resourceInUse|is already being used|still in use|DependencyViolation|operation.?in.?progress`)},
+	{PermissionDenied, regexp.MustCompile(`(?i)permission.?denied|AccessDenied|UnauthorizedOperation|AuthorizationFailed|forbidden`)},
+	{Timeout, regexp.MustCompile(`(?i)timeout while waiting|timed out|deadline exceeded`)},
+	{Network, regexp.MustCompile(`(?i)connection refused|connection reset|no such host|i/o timeout|TLS handshake timeout`)},
+	{BackendUnavailable, regexp.MustCompile(`(?i)backendError|ServiceUnavailable|InternalError|internal server error`)},
+	{InvalidRequest, regexp.MustCompile(`(?i)invalid.?(value|parameter|argument)|badRequest`)},
+	{NotFound, regexp.MustCompile(`(?i)notFound\b|does not exist|NoSuch`)},
+}
+
+// statusPattern finds HTTP status codes in provider error messages, e.g.
+// "googleapi: Error 409: ...".
+var statusPattern = regexp.MustCompile(`(?i)(?:Error|status(?: code)?:?|HTTP response code) (\d{3})\b`)
+
+// Classify returns the failure info of an error. Typed provider errors are
+// classified by their status code, other errors by their message.
+func Classify(err error) Info {
+	if err == nil {
+		return taxonomy[Unknown]
+	}
+
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		if code := classifyReasons(gerr); code != "" {
+			return ForCode(code)
+		}
+		return ForCode(classifyStatus(gerr.Code))
+	}
+
+	var coder retry.StatusCoder
+	if errors.As(err, &coder) {
+		return ForCode(classifyStatus(coder.StatusCode()))
+	}
+
+	if retry.Network.Matches(err) {
+		return ForCode(Network)
+	}
+
+	message := err.Error()
+	for _, p := range messagePatterns {
+		if p.Pattern.MatchString(message) {
+			return ForCode(p.Code)
+		}
+	}
+
+	if match := statusPattern.FindStringSubmatch(message); match != nil {
+		status, _ := strconv.Atoi(match[1])
+		return ForCode(classifyStatus(status))
+	}
+
+	return ForCode(Unknown)
+}
+
+// classifyReasons uses the reasons Google APIs give for an error, which
+// tell quota and rate limit errors apart.
+func classifyReasons(gerr *googleapi.Error) Code {
+	for _, item := range gerr.Errors {
+		reason := strings.ToLower(item.Reason)
+		switch {
+		case strings.Contains(reason, "quota"):
+			return QuotaExceeded
+		case strings.Contains(reason, "ratelimit"):
+			return RateLimited
+		case reason == "alreadyexists":
+			return AlreadyExists
+		}
+	}
+
+	return ""
+}
+
+func classifyStatus(status int) Code {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return RateLimited
+	case status == http.StatusConflict:
+		return Conflict
+	case status == http.StatusForbidden || status == http.StatusUnauthorized:
+		return PermissionDenied
+	case status == http.StatusNotFound:
+		return NotFound
+	case status == http.StatusBadRequest:
+		return InvalidRequest
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return Timeout
+	case status >= 500 && status <= 599:
+		return BackendUnavailable
+	default:
+		return Unknown
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package failure
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+type statusError int
+
+func (e statusError) Error() string   { return fmt.Sprintf("status %d", int(e)) }
+func (e statusError) StatusCode() int { return int(e) }
+
+func TestClassify(t *testing.T) {
+	cases := map[string]struct {
+		Err    error
+		Expect Code
+	}{
+		"nil":                {Err: nil, Expect: Unknown},
+		"google quota":       {Err: &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}, Expect: QuotaExceeded},
+		"google rate limit":  {Err: &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}, Expect: RateLimited},
+		"google forbidden":   {Err: &googleapi.Error{Code: 403}, Expect: PermissionDenied},
+		"google unavailable": {Err: &googleapi.Error{Code: 503}, Expect: BackendUnavailable},
+		"wrapped google":     {Err: fmt.Errorf("creating bucket: %w", &googleapi.Error{Code: 409}), Expect: Conflict},
+		"status coder":       {Err: statusError(429), Expect: RateLimited},
+		"network":            {Err: &net.OpError{Op: "dial", Err: errors.New("refused")}, Expect: Network},
+		"terraform quota":    {Err: errors.New("Error creating instance: googleapi: Error 403: Quota 'CPUS' exceeded. Limit: 24.0, quotaExceeded"), Expect: QuotaExceeded},
+		"terraform exists":   {Err: errors.New("Error creating Database: googleapi: Error 409: The database already exists., alreadyExists"), Expect: AlreadyExists},
+		"terraform status":   {Err: errors.New("Error, failed to create instance: googleapi: Error 409: Resource in use"), Expect: Conflict},
+		"terraform in use":   {Err: errors.New("Error deleting network: googleapi: Error 400: The network resource is already being used by 'instance-1', resourceInUseByAnotherResource"), Expect: Conflict},
+		"aws throttling":     {Err: errors.New("Throttling: Rate exceeded\n\tstatus code: 400"), Expect: RateLimited},
+		"aws access denied":  {Err: errors.New("AccessDenied: Access Denied\n\tstatus code: 403"), Expect: PermissionDenied},
+		"terraform timeout":  {Err: errors.New("timeout while waiting for state to become 'RUNNABLE'"), Expect: Timeout},
+		"connection refused": {Err: errors.New("dial tcp 10.0.0.1:3306: connect: connection refused"), Expect: Network},
+		"unclassified":       {Err: errors.New("something broke"), Expect: Unknown},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := Classify(tc.Err); actual.Code != tc.Expect {
+				t.Errorf("Expected %q, got %q", tc.Expect, actual.Code)
+			}
+		})
+	}
+}
+
+func TestForCode(t *testing.T) {
+	if info := ForCode(RateLimited); !info.Retryable || info.RetryAfter != time.Minute {
+		t.Errorf("Expected rate limited failures to be retried after a minute, got %+v", info)
+	}
+
+	if info := ForCode(QuotaExceeded); info.Retryable {
+		t.Errorf("Expected quota failures not to be retryable, got %+v", info)
+	}
+
+	if info := ForCode("made-up"); info.Code != Unknown {
+		t.Errorf("Expected unknown codes to map to %q, got %+v", Unknown, info)
+	}
+}
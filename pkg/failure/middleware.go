@@ -0,0 +1,121 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package failure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type detailsKey struct{}
+
+// details is filled in by the broker while it handles a last_operation
+// request and added to the response by Wrap.
+type details struct {
+	info           *Info
+	instanceUsable *bool
+}
+
+// Record attaches the failure info of an operation to the last_operation
+// response being served with the context. instanceUsable is reported for
+// failed updates and deprovisions, where the OSB spec lets the platform
+// know whether the instance can still be used; pass nil to leave it out.
+// It does nothing outside requests handled by Wrap.
+func Record(ctx context.Context, info Info, instanceUsable *bool) {
+	d, ok := ctx.Value(detailsKey{}).(*details)
+	if !ok {
+		return
+	}
+
+	d.info = &info
+	d.instanceUsable = instanceUsable
+}
+
+// ErrorResponse is the machine readable failure added to failed
+// last_operation responses.
+type ErrorResponse struct {
+	Code              Code `json:"code"`
+	Retryable         bool `json:"retryable"`
+	RetryAfterSeconds int  `json:"retry_after_seconds,omitempty"`
+}
+
+// Wrap adds the failure info recorded by the broker to failed
+// last_operation responses as an `error` object, and `instance_usable` if
+// it was given. Other requests pass through untouched.
+func Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet || !strings.HasSuffix(req.URL.Path, "/last_operation") {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		d := &details{}
+		buffered := &bufferedResponse{header: w.Header(), status: http.StatusOK}
+		next.ServeHTTP(buffered, req.WithContext(context.WithValue(req.Context(), detailsKey{}, d)))
+
+		body := buffered.body.Bytes()
+		if d.info != nil && buffered.status == http.StatusOK {
+			if extended, err := extendResponse(body, d); err == nil {
+				body = extended
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			}
+		}
+
+		w.WriteHeader(buffered.status)
+		w.Write(body)
+	})
+}
+
+func extendResponse(body []byte, d *details) ([]byte, error) {
+	response := make(map[string]interface{})
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	errResponse := ErrorResponse{Code: d.info.Code, Retryable: d.info.Retryable}
+	if d.info.Retryable {
+		errResponse.RetryAfterSeconds = int(math.Ceil(d.info.RetryAfter.Seconds()))
+	}
+	response["error"] = errResponse
+	if d.instanceUsable != nil {
+		response["instance_usable"] = *d.instanceUsable
+	}
+
+	return json.Marshal(response)
+}
+
+// bufferedResponse holds a response so it can be changed before it's sent.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package failure
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	usable := true
+	cases := map[string]struct {
+		Method   string
+		Path     string
+		Record   bool
+		Usable   *bool
+		Expected map[string]interface{}
+	}{
+		"failed operation": {
+			Method: http.MethodGet,
+			Path:   "/v2/service_instances/instance-1/last_operation",
+			Record: true,
+			Expected: map[string]interface{}{
+				"state":       "failed",
+				"description": "quota exceeded",
+				"error":       map[string]interface{}{"code": "rate_limited", "retryable": true, "retry_after_seconds": 60.0},
+			},
+		},
+		"failed update": {
+			Method: http.MethodGet,
+			Path:   "/v2/service_instances/instance-1/last_operation",
+			Record: true,
+			Usable: &usable,
+			Expected: map[string]interface{}{
+				"state":           "failed",
+				"description":     "quota exceeded",
+				"instance_usable": true,
+				"error":           map[string]interface{}{"code": "rate_limited", "retryable": true, "retry_after_seconds": 60.0},
+			},
+		},
+		"nothing recorded": {
+			Method:   http.MethodGet,
+			Path:     "/v2/service_instances/instance-1/last_operation",
+			Expected: map[string]interface{}{"state": "failed", "description": "quota exceeded"},
+		},
+		"other endpoint": {
+			Method:   http.MethodGet,
+			Path:     "/v2/service_instances/instance-1",
+			Record:   true,
+			Expected: map[string]interface{}{"state": "failed", "description": "quota exceeded"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			handler := Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if tc.Record {
+					Record(req.Context(), ForCode(RateLimited), tc.Usable)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"state":"failed","description":"quota exceeded"}`))
+			}))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tc.Method, tc.Path, nil))
+
+			if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Expected the status and headers to be kept, got %d %v", w.Code, w.Header())
+			}
+
+			actual := make(map[string]interface{})
+			if err := json.Unmarshal(w.Body.Bytes(), &actual); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestWrap_errorStatus(t *testing.T) {
+	handler := Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		Record(req.Context(), ForCode(Unknown), nil)
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(`{}`))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/service_instances/instance-1/last_operation", nil))
+	if w.Code != http.StatusGone || w.Body.String() != "{}" {
+		t.Errorf("Expected error responses to pass through, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gcpclient creates the clients the broker calls Google Cloud APIs
+// with, so every API gets the same retries, user agent and tracing, and
+// operators can limit the rate of requests to an API or send them to another
+// endpoint, e.g. for Private Google Access or an emulator.
+package gcpclient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/ratelimit"
+	"github.com/pivotal/cloud-service-broker/pkg/retry"
+	"github.com/pivotal/cloud-service-broker/pkg/tracing"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+	"google.golang.org/api/option"
+)
+
+const (
+	// EndpointsProp is the viper key of a JSON object of endpoints that
+	// replace the default ones of APIs, keyed by API name, e.g. "storage".
+	EndpointsProp = "google.api.endpoints"
+
+	// QpsProp is the viper key of a JSON object of the most requests per
+	// second the broker sends to APIs, keyed by API name.
+	QpsProp = "google.api.qps"
+
+	// EmulatorsProp is the viper key of a JSON object of the endpoints of
+	// local emulators, keyed by API name. Requests to emulators aren't
+	// authorized.
+	EmulatorsProp = "google.api.emulators"
+)
+
+var retryPolicy = retry.Policies.Policy("gcp-api", "Idempotent calls the broker makes to Google Cloud APIs.", retry.Defaults{
+	MaxAttempts:    3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Retryable:      []retry.ErrorClass{retry.RateLimit, retry.ServerError, retry.Network},
+})
+
+func init() {
+	config.Register(
+		config.Property{Key: EndpointsProp, Kind: config.Json},
+		config.Property{Key: QpsProp, Kind: config.Json},
+		config.Property{Key: EmulatorsProp, Kind: config.Json},
+	)
+}
+
+// limiters holds the rate limiter of each API so every client of the API
+// shares it.
+var limiters = struct {
+	sync.Mutex
+	byApi map[string]*ratelimit.Limiter
+}{byApi: make(map[string]*ratelimit.Limiter)}
+
+// limiter gets the shared limiter of the API, replacing it if its rate
+// changed.
+func limiter(api string, qps float64) *ratelimit.Limiter {
+	limiters.Lock()
+	defer limiters.Unlock()
+
+	key := fmt.Sprintf("%s/%v", api, qps)
+	if l, ok := limiters.byApi[key]; ok {
+		return l
+	}
+
+	// a second's worth of requests can be sent at once
+	l := ratelimit.NewLimiter(qps, int(math.Ceil(qps)))
+	limiters.byApi[key] = l
+	return l
+}
+
+// Factory creates clients for Google Cloud APIs authorized with Config. APIs
+// are named like their packages, e.g. "storage", "sqladmin" or "iam".
+type Factory struct {
+	// Config is nil if no credentials are configured, then only emulated
+	// APIs can be called.
+	Config utils.HttpConfig
+
+	// Endpoints replace the default endpoints of the APIs they're keyed by.
+	Endpoints map[string]string
+
+	// Emulators are the endpoints of emulators of the APIs they're keyed by.
+	// They take precedence over Endpoints.
+	Emulators map[string]string
+
+	// Qps limits the requests per second to the APIs it's keyed by. Other
+	// APIs aren't limited.
+	Qps map[string]float64
+
+	// Policy retries idempotent requests.
+	Policy    retry.Policy
+	UserAgent string
+}
+
+// NewFactoryFromEnv creates a Factory using the broker's credentials and the
+// endpoints, emulators and rate limits configured in viper. Credentials are
+// only required if no emulators are configured.
+func NewFactoryFromEnv() (*Factory, error) {
+	endpoints, err := endpointsFromEnv(EndpointsProp)
+	if err != nil {
+		return nil, err
+	}
+
+	emulators, err := endpointsFromEnv(EmulatorsProp)
+	if err != nil {
+		return nil, err
+	}
+
+	qps := make(map[string]float64)
+	if raw := viper.Get(QpsProp); raw != nil && raw != "" {
+		limits, err := cast.ToStringMapE(raw)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse %s: %v", QpsProp, err)
+		}
+
+		for api, limit := range limits {
+			if qps[api], err = cast.ToFloat64E(limit); err != nil || qps[api] <= 0 {
+				return nil, fmt.Errorf("%s of %s must be a positive number, got %v", QpsProp, api, limit)
+			}
+		}
+	}
+
+	var conf utils.HttpConfig
+	if len(emulators) == 0 || utils.GcpCredentialsConfigured() {
+		if conf, err = utils.GetAuthedConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Factory{
+		Config:    conf,
+		Endpoints: endpoints,
+		Emulators: emulators,
+		Qps:       qps,
+		Policy:    retryPolicy,
+		UserAgent: utils.CustomUserAgent,
+	}, nil
+}
+
+// endpointsFromEnv parses the JSON object of endpoints keyed by API name in
+// the given property.
+func endpointsFromEnv(prop string) (map[string]string, error) {
+	raw := viper.Get(prop)
+	if raw == nil || raw == "" {
+		return map[string]string{}, nil
+	}
+
+	endpoints, err := cast.ToStringMapStringE(raw)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", prop, err)
+	}
+
+	return endpoints, nil
+}
+
+// Client creates an HTTP client for requests to the API. Requests wait for
+// the API's rate limit and idempotent ones are retried.
+func (f *Factory) Client(ctx context.Context, api string) *http.Client {
+	var transport http.RoundTripper
+	switch _, emulated := f.Emulators[api]; {
+	case emulated:
+		transport = http.DefaultTransport
+	case f.Config == nil:
+		transport = errorTransport{err: fmt.Errorf("no Google Cloud credentials are configured to call the %s API", api)}
+	default:
+		transport = f.Config.Client(tracing.ClientContext(ctx)).Transport
+	}
+
+	if f.UserAgent != "" {
+		transport = &userAgentTransport{userAgent: f.UserAgent, base: transport}
+	}
+
+	// retries count towards the rate limit too
+	if qps, ok := f.Qps[api]; ok {
+		transport = &limitTransport{limiter: limiter(api, qps), base: transport}
+	}
+
+	return &http.Client{Transport: retry.NewTransport(f.Policy, transport)}
+}
+
+// Options returns the options to create a client of the API with, e.g. with
+// storage.NewService or the Cloud Storage library's storage.NewClient.
+func (f *Factory) Options(ctx context.Context, api string) []option.ClientOption {
+	opts := []option.ClientOption{option.WithHTTPClient(f.Client(ctx, api))}
+	if endpoint, ok := f.Emulators[api]; ok {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	} else if endpoint, ok := f.Endpoints[api]; ok {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+
+	return opts
+}
+
+// userAgentTransport adds the broker's user agent to requests. Options like
+// option.WithUserAgent are ignored for clients created with an HTTP client.
+type userAgentTransport struct {
+	userAgent string
+	base      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	userAgent := t.userAgent
+	if existing := req.Header.Get("User-Agent"); existing != "" {
+		userAgent = existing + " " + userAgent
+	}
+
+	// round trippers mustn't modify the request they're given
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", userAgent)
+	return t.base.RoundTrip(req)
+}
+
+// limitTransport waits for the limiter before sending requests.
+type limitTransport struct {
+	limiter *ratelimit.Limiter
+	base    http.RoundTripper
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// errorTransport fails every request, e.g. to APIs there are no credentials
+// for.
+type errorTransport struct {
+	err error
+}
+
+func (t errorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, t.err
+}
@@ -0,0 +1,196 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/pkg/retry"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+	storage "google.golang.org/api/storage/v1"
+)
+
+// fakeConfig authorizes requests with a static token.
+type fakeConfig struct{}
+
+func (fakeConfig) Client(ctx context.Context) *http.Client {
+	return oauth2.NewClient(ctx, fakeConfig{}.TokenSource(ctx))
+}
+
+func (fakeConfig) TokenSource(ctx context.Context) oauth2.TokenSource {
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"})
+}
+
+var testPolicy = retry.Policy{Name: "test", Defaults: retry.Defaults{
+	MaxAttempts: 3,
+	Retryable:   []retry.ErrorClass{retry.ServerError},
+}}
+
+func TestFactory_Options(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if auth := r.Header.Get("Authorization"); auth != "Bearer token" {
+			t.Errorf("expected the request to be authorized, got %q", auth)
+		}
+
+		if agent := r.Header.Get("User-Agent"); !strings.HasSuffix(agent, " test-broker") {
+			t.Errorf("expected the broker's user agent to be added, got %q", agent)
+		}
+
+		w.Write([]byte(`{"name": "my-bucket"}`))
+	}))
+	defer server.Close()
+
+	factory := &Factory{
+		Config:    fakeConfig{},
+		Endpoints: map[string]string{"storage": server.URL + "/storage/v1/"},
+		Policy:    testPolicy,
+		UserAgent: "test-broker",
+	}
+
+	ctx := context.Background()
+	service, err := storage.NewService(ctx, factory.Options(ctx, "storage")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bucket, err := service.Buckets.Get("my-bucket").Context(ctx).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bucket.Name != "my-bucket" {
+		t.Errorf("expected the bucket from the overridden endpoint, got %v", bucket)
+	}
+
+	if requests != 2 {
+		t.Errorf("expected the unavailable response to be retried, got %d request(s)", requests)
+	}
+}
+
+func TestFactory_Options_emulator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("expected requests to the emulator not to be authorized, got %q", auth)
+		}
+
+		w.Write([]byte(`{"name": "my-bucket"}`))
+	}))
+	defer server.Close()
+
+	factory := &Factory{
+		Endpoints: map[string]string{"storage": "https://storage.invalid/storage/v1/"},
+		Emulators: map[string]string{"storage": server.URL + "/storage/v1/"},
+		Policy:    testPolicy,
+	}
+
+	ctx := context.Background()
+	service, err := storage.NewService(ctx, factory.Options(ctx, "storage")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.Buckets.Get("my-bucket").Context(ctx).Do(); err != nil {
+		t.Fatalf("expected the emulator to be called, got %v", err)
+	}
+
+	// there are no credentials for APIs that aren't emulated
+	_, err = factory.Client(ctx, "iam").Get(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "no Google Cloud credentials are configured to call the iam API") {
+		t.Errorf("expected a missing credentials error, got %v", err)
+	}
+}
+
+func TestFactory_Client_qps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	factory := &Factory{Config: fakeConfig{}, Qps: map[string]float64{"limited": 2}, Policy: testPolicy}
+
+	// both clients share the API's limit, which allows a burst of 2
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := factory.Client(context.Background(), "limited").Get(server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("expected the third request to wait for the limit, took %v", elapsed)
+	}
+
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := factory.Client(context.Background(), "unlimited").Get(server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("expected requests to other APIs not to wait, took %v", elapsed)
+	}
+}
+
+func TestNewFactoryFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Endpoints string
+		Emulators string
+		Qps       string
+		ExpectErr string
+	}{
+		"bad-endpoints": {
+			Endpoints: `["storage"]`,
+			ExpectErr: "couldn't parse google.api.endpoints",
+		},
+		"bad-emulators": {
+			Emulators: `"localhost:4443"`,
+			ExpectErr: "couldn't parse google.api.emulators",
+		},
+		"bad-qps": {
+			Qps:       `{"storage": "fast"}`,
+			ExpectErr: "google.api.qps of storage must be a positive number, got fast",
+		},
+		"negative-qps": {
+			Qps:       `{"storage": -1}`,
+			ExpectErr: "google.api.qps of storage must be a positive number, got -1",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(EndpointsProp, tc.Endpoints)
+			viper.Set(EmulatorsProp, tc.Emulators)
+			viper.Set(QpsProp, tc.Qps)
+			defer viper.Set(EndpointsProp, nil)
+			defer viper.Set(EmulatorsProp, nil)
+			defer viper.Set(QpsProp, nil)
+
+			_, err := NewFactoryFromEnv()
+			if err == nil || !strings.HasPrefix(err.Error(), tc.ExpectErr) {
+				t.Errorf("expected error %q, got %v", tc.ExpectErr, err)
+			}
+		})
+	}
+}
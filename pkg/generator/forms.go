@@ -21,6 +21,8 @@ import (
 	"strings"
 
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin"
 	"github.com/pivotal/cloud-service-broker/pkg/toggles"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -78,7 +80,7 @@ func GenerateFormsString() string {
 func GenerateForms() TileFormsSections {
 	// Add new forms at the bottom of the list because the order is reflected
 	// in the generated UI and we don't want to mix things up on users.
-	return TileFormsSections{
+	sections := TileFormsSections{
 		Forms: []Form{
 			generateServiceAccountForm(),
 			generateDatabaseForm(),
@@ -86,7 +88,131 @@ func GenerateForms() TileFormsSections {
 			generateFeatureFlagForm(),
 		},
 
-		ServicePlanForms: []Form{ brokerpakConfigurationForm() },
+		ServicePlanForms: []Form{brokerpakConfigurationForm()},
+	}
+
+	for _, svc := range builtin.BuiltinBrokerRegistry().GetAllServices() {
+		planForm, err := generateServicePlanForm(svc)
+		if err != nil {
+			log.Fatalf("Error generating plan form for %q: %s", svc.Name, err)
+		}
+
+		sections.ServicePlanForms = append(sections.ServicePlanForms, planForm)
+	}
+
+	// The configuration form gets every property the forms above don't
+	// already set, so it has to be generated last.
+	sections.Forms = append(sections.Forms, generateConfigurationForm(sections))
+
+	return sections
+}
+
+// platformEnv holds the environment variables the platform or the generated
+// artifacts themselves set for the broker, e.g. Cloud Foundry sets the PORT,
+// so they aren't listed as configuration properties.
+var platformEnv = map[string]bool{
+	"PORT":                   true,
+	"SECURITY_USER_NAME":     true,
+	"SECURITY_USER_PASSWORD": true,
+}
+
+// generateConfigurationForm generates a form for the registered configuration
+// properties that aren't set by the other forms, so new properties show up in
+// the tile without having to add them here.
+func generateConfigurationForm(sections TileFormsSections) Form {
+	covered := make(map[string]bool)
+	for _, form := range append(sections.Forms, sections.ServicePlanForms...) {
+		covered[form.Name] = true
+		for _, prop := range form.Properties {
+			covered[prop.Name] = true
+		}
+	}
+
+	var formEntries []FormProperty
+	for _, prop := range config.Properties() {
+		name := strings.ToLower(prop.EnvVar())
+		if covered[name] || platformEnv[prop.Env] || (prop.Env != "" && covered[strings.ToLower(prop.Env)]) {
+			continue
+		}
+
+		formEntries = append(formEntries, configPropertyToFormProperty(prop))
+	}
+
+	return Form{
+		Name:        "broker_configuration",
+		Label:       "Broker Configuration",
+		Description: "Additional configuration properties of the service broker, they're optional and the broker's defaults are used if they're not set.",
+		Properties:  formEntries,
+	}
+}
+
+// configPropertyToFormProperty converts a registered configuration property
+// into a form element setting its environment variable.
+func configPropertyToFormProperty(prop config.Property) FormProperty {
+	formInput := FormProperty{
+		Name:         strings.ToLower(prop.EnvVar()),
+		Type:         "string",
+		Label:        prop.Key,
+		Description:  configPropertyDescription(prop),
+		Configurable: true,
+		Optional:     true,
+	}
+
+	// the tile deals with all values as strings so a default string is acceptable.
+	if value := configPropertyDefault(prop); value != "" {
+		formInput.Default = value
+	}
+
+	switch {
+	case len(prop.Allowed) > 0:
+		formInput.Type = "dropdown_select"
+		for _, value := range prop.Allowed {
+			formInput.Options = append(formInput.Options, FormOption{Name: value, Label: value})
+		}
+	case prop.Sensitive:
+		formInput.Type = "secret"
+	case prop.Kind == config.Boolean:
+		formInput.Type = "boolean"
+	case prop.Kind == config.Integer:
+		formInput.Type = "integer"
+	case prop.Kind == config.Json || prop.Kind == config.List:
+		formInput.Type = "text"
+	}
+
+	return formInput
+}
+
+// configPropertyDefault formats the default value of a property the way it
+// would be set in an environment variable, it's empty if there's none.
+func configPropertyDefault(prop config.Property) string {
+	switch value := prop.Default.(type) {
+	case nil:
+		return ""
+	case []string:
+		return strings.Join(value, ",")
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// configPropertyDescription describes the value a property of the given kind
+// takes.
+func configPropertyDescription(prop config.Property) string {
+	switch prop.Kind {
+	case config.Boolean:
+		return "Either true or false."
+	case config.Integer:
+		return "A whole number."
+	case config.Number:
+		return "A number, e.g. 0.5."
+	case config.Duration:
+		return "A duration, e.g. 30s, 10m or 24h."
+	case config.List:
+		return "A comma separated list of values."
+	case config.Json:
+		return "A JSON value."
+	default:
+		return "A string."
 	}
 }
 
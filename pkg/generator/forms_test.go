@@ -0,0 +1,123 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+)
+
+func TestConfigPropertyToFormProperty(t *testing.T) {
+	cases := map[string]struct {
+		Property config.Property
+		Expected FormProperty
+	}{
+		"string": {
+			Property: config.Property{Key: "tracing.service_name", Kind: config.String, Default: "csb"},
+			Expected: FormProperty{Name: "gsb_tracing_service_name", Type: "string", Default: "csb", Label: "tracing.service_name", Description: "A string.", Configurable: true, Optional: true},
+		},
+		"no default": {
+			Property: config.Property{Key: "archive.bucket", Kind: config.String},
+			Expected: FormProperty{Name: "gsb_archive_bucket", Type: "string", Label: "archive.bucket", Description: "A string.", Configurable: true, Optional: true},
+		},
+		"sensitive": {
+			Property: config.Property{Key: "admin.password", Kind: config.String, Sensitive: true},
+			Expected: FormProperty{Name: "gsb_admin_password", Type: "secret", Label: "admin.password", Description: "A string.", Configurable: true, Optional: true},
+		},
+		"boolean": {
+			Property: config.Property{Key: "orphans.delete", Kind: config.Boolean, Default: false},
+			Expected: FormProperty{Name: "gsb_orphans_delete", Type: "boolean", Default: "false", Label: "orphans.delete", Description: "Either true or false.", Configurable: true, Optional: true},
+		},
+		"integer": {
+			Property: config.Property{Key: "jobs.workers", Kind: config.Integer, Default: 4},
+			Expected: FormProperty{Name: "gsb_jobs_workers", Type: "integer", Default: "4", Label: "jobs.workers", Description: "A whole number.", Configurable: true, Optional: true},
+		},
+		"list": {
+			Property: config.Property{Key: "preflight.required_apis", Kind: config.List, Default: []string{"a", "b"}},
+			Expected: FormProperty{Name: "gsb_preflight_required_apis", Type: "text", Default: "a,b", Label: "preflight.required_apis", Description: "A comma separated list of values.", Configurable: true, Optional: true},
+		},
+		"allowed": {
+			Property: config.Property{Key: "api.tls.client_auth", Kind: config.String, Default: "require", Allowed: []string{"require", "optional"}},
+			Expected: FormProperty{
+				Name:         "gsb_api_tls_client_auth",
+				Type:         "dropdown_select",
+				Default:      "require",
+				Label:        "api.tls.client_auth",
+				Description:  "A string.",
+				Configurable: true,
+				Optional:     true,
+				Options:      []FormOption{{Name: "require", Label: "require"}, {Name: "optional", Label: "optional"}},
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := configPropertyToFormProperty(tc.Property)
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected %#v, got %#v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestGenerateConfigurationForm(t *testing.T) {
+	config.Register(
+		config.Property{Key: "generator.test.covered", Kind: config.String},
+		config.Property{Key: "generator.test.platform", Kind: config.String, Env: "GENERATOR_TEST_PLATFORM"},
+		config.Property{Key: "generator.test.uncovered", Kind: config.String},
+	)
+
+	form := generateConfigurationForm(TileFormsSections{
+		Forms: []Form{{Name: "existing", Properties: []FormProperty{
+			{Name: "gsb_generator_test_covered"},
+			{Name: "generator_test_platform"},
+		}}},
+	})
+
+	var names []string
+	for _, prop := range form.Properties {
+		if strings.HasPrefix(prop.Name, "gsb_generator_test") {
+			names = append(names, prop.Name)
+		}
+	}
+
+	expected := []string{"gsb_generator_test_uncovered"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected properties %v, got %v", expected, names)
+	}
+}
+
+func TestManifestConfigEnv(t *testing.T) {
+	config.Register(
+		config.Property{Key: "generator.manifest.default", Kind: config.Duration, Default: "30s"},
+		config.Property{Key: "generator.manifest.secret", Kind: config.String, Default: "hunter2", Sensitive: true},
+		config.Property{Key: "generator.manifest.port", Kind: config.Integer, Env: "PORT"},
+	)
+
+	env := manifestConfigEnv()
+	for _, line := range []string{`GSB_GENERATOR_MANIFEST_DEFAULT: "30s"`, "GSB_GENERATOR_MANIFEST_SECRET:\n"} {
+		if !strings.Contains(env+"\n", line) {
+			t.Errorf("Expected the env to contain %q, got:\n%s", line, env)
+		}
+	}
+
+	if strings.Contains(env, "hunter2") || strings.Contains(env, "PORT:") {
+		t.Errorf("Expected no secrets or platform variables in the env, got:\n%s", env)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/utils"
+)
+
+const (
+	// containerPort is the port the broker listens on in its container, it's
+	// passed in the PORT environment variable.
+	containerPort = 8080
+
+	// drainTimeoutProp is the property holding how long the broker waits for
+	// requests to finish when it's stopped, see cmd/serve.go.
+	drainTimeoutProp = "api.drain_timeout"
+
+	kubernetesYmlTemplate = copyrightHeader + `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: {{.name}}
+{{- if .namespace}}
+  namespace: {{.namespace}}
+{{- end}}
+  labels:
+    app: {{.name}}
+type: Opaque
+stringData:
+  SECURITY_USER_NAME: ""
+  SECURITY_USER_PASSWORD: ""
+  # Uncomment the sensitive configuration properties you want to set.
+{{.secretEnv}}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{.name}}
+{{- if .namespace}}
+  namespace: {{.namespace}}
+{{- end}}
+  labels:
+    app: {{.name}}
+spec:
+  replicas: {{.replicas}}
+  selector:
+    matchLabels:
+      app: {{.name}}
+  template:
+    metadata:
+      labels:
+        app: {{.name}}
+    spec:
+      # With GKE Workload Identity, run the broker as a Kubernetes service
+      # account bound to a Google service account and set
+      # GSB_GOOGLE_CREDENTIALS_SOURCE to application_default.
+      # serviceAccountName: {{.name}}
+      terminationGracePeriodSeconds: {{.terminationGracePeriod}}
+      containers:
+      - name: {{.name}}
+        image: {{.image}}
+        args: ["serve"]
+        ports:
+        - name: http
+          containerPort: {{.containerPort}}
+        envFrom:
+        - secretRef:
+            name: {{.name}}
+        env:
+        - name: PORT
+          value: "{{.containerPort}}"
+        # Uncomment the configuration properties you want to set, they're
+        # shown with their default values.
+{{.configEnv}}
+        readinessProbe:
+          httpGet:
+            path: /ready
+            port: http
+          periodSeconds: 10
+          failureThreshold: 3
+        livenessProbe:
+          httpGet:
+            path: /live
+            port: http
+          initialDelaySeconds: 10
+          periodSeconds: 10
+          failureThreshold: 3
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{.name}}
+{{- if .namespace}}
+  namespace: {{.namespace}}
+{{- end}}
+  labels:
+    app: {{.name}}
+spec:
+  selector:
+    app: {{.name}}
+  ports:
+  - name: http
+    port: 80
+    targetPort: http
+`
+)
+
+// KubernetesOptions customizes the generated Kubernetes manifest.
+type KubernetesOptions struct {
+	// Name is used for the Secret, Deployment and Service.
+	Name string
+
+	// Namespace is left out if it's empty so kubectl's namespace is used.
+	Namespace string
+
+	Image    string
+	Replicas int
+}
+
+// DefaultKubernetesOptions returns the options used if none are given on the
+// command line.
+func DefaultKubernetesOptions() KubernetesOptions {
+	return KubernetesOptions{
+		Name:     appName,
+		Image:    fmt.Sprintf("gcr.io/PROJECT/%s:%s", appName, utils.Version),
+		Replicas: 1,
+	}
+}
+
+// GenerateKubernetes creates a Kubernetes manifest with a Secret, Deployment
+// and Service for the broker. The readiness and liveness probes use the
+// broker's /ready and /live endpoints.
+func GenerateKubernetes(opts KubernetesOptions) string {
+	vars := map[string]interface{}{
+		"name":                   opts.Name,
+		"namespace":              opts.Namespace,
+		"image":                  opts.Image,
+		"replicas":               opts.Replicas,
+		"containerPort":          containerPort,
+		"terminationGracePeriod": terminationGracePeriod(),
+		"secretEnv":              utils.Indent(kubernetesSecretEnv(), "  # "),
+		"configEnv":              utils.Indent(kubernetesConfigEnv(), "        # "),
+	}
+
+	tmpl, err := template.New("tmpl").Parse(kubernetesYmlTemplate)
+	if err != nil {
+		log.Fatalf("parsing: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		log.Fatalf("execution: %s", err)
+	}
+
+	return buf.String()
+}
+
+// terminationGracePeriod gives the broker time to drain requests before it's
+// killed, plus some time to shut down.
+func terminationGracePeriod() int {
+	drain := 30 * time.Second
+	for _, prop := range config.Properties() {
+		if prop.Key != drainTimeoutProp {
+			continue
+		}
+
+		if parsed, err := time.ParseDuration(configPropertyDefault(prop)); err == nil {
+			drain = parsed
+		}
+	}
+
+	return int((drain + 10*time.Second).Seconds())
+}
+
+// kubernetesEnvName gets the environment variable a property is set with in
+// the manifest, the one set by the platform is preferred.
+func kubernetesEnvName(prop config.Property) string {
+	if prop.Env != "" {
+		return prop.Env
+	}
+
+	return prop.EnvVar()
+}
+
+// kubernetesSecretEnv lists the sensitive configuration properties as Secret
+// data without values.
+func kubernetesSecretEnv() string {
+	var lines []string
+	for _, prop := range config.Properties() {
+		if prop.Sensitive && !platformEnv[prop.Env] {
+			lines = append(lines, kubernetesEnvName(prop)+`: ""`)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// kubernetesConfigEnv lists the other configuration properties as container
+// environment variables with their defaults.
+func kubernetesConfigEnv() string {
+	var lines []string
+	for _, prop := range config.Properties() {
+		if prop.Sensitive || platformEnv[prop.Env] {
+			continue
+		}
+
+		lines = append(lines,
+			"- name: "+kubernetesEnvName(prop),
+			fmt.Sprintf("  value: %q", configPropertyDefault(prop)))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestGenerateKubernetes(t *testing.T) {
+	opts := DefaultKubernetesOptions()
+	opts.Namespace = "brokers"
+	opts.Image = "gcr.io/my-project/cloud-service-broker:test"
+
+	decoder := yaml.NewDecoder(strings.NewReader(GenerateKubernetes(opts)))
+
+	var kinds []string
+	for {
+		var doc struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name      string `yaml:"name"`
+				Namespace string `yaml:"namespace"`
+			} `yaml:"metadata"`
+			Spec struct {
+				Template struct {
+					Spec struct {
+						Containers []struct {
+							Image          string `yaml:"image"`
+							ReadinessProbe struct {
+								HttpGet struct {
+									Path string `yaml:"path"`
+								} `yaml:"httpGet"`
+							} `yaml:"readinessProbe"`
+							LivenessProbe struct {
+								HttpGet struct {
+									Path string `yaml:"path"`
+								} `yaml:"httpGet"`
+							} `yaml:"livenessProbe"`
+						} `yaml:"containers"`
+					} `yaml:"spec"`
+				} `yaml:"template"`
+			} `yaml:"spec"`
+		}
+		if err := decoder.Decode(&doc); err != nil {
+			break
+		}
+
+		kinds = append(kinds, doc.Kind)
+		if doc.Metadata.Name != opts.Name || doc.Metadata.Namespace != opts.Namespace {
+			t.Errorf("Expected %s %s/%s, got %s/%s", doc.Kind, opts.Namespace, opts.Name, doc.Metadata.Namespace, doc.Metadata.Name)
+		}
+
+		if doc.Kind != "Deployment" {
+			continue
+		}
+
+		if len(doc.Spec.Template.Spec.Containers) != 1 {
+			t.Fatalf("Expected one container, got %d", len(doc.Spec.Template.Spec.Containers))
+		}
+
+		container := doc.Spec.Template.Spec.Containers[0]
+		if container.Image != opts.Image {
+			t.Errorf("Expected image %q, got %q", opts.Image, container.Image)
+		}
+
+		if container.ReadinessProbe.HttpGet.Path != "/ready" {
+			t.Errorf("Expected the readiness probe to use /ready, got %q", container.ReadinessProbe.HttpGet.Path)
+		}
+
+		if container.LivenessProbe.HttpGet.Path != "/live" {
+			t.Errorf("Expected the liveness probe to use /live, got %q", container.LivenessProbe.HttpGet.Path)
+		}
+	}
+
+	if strings.Join(kinds, ",") != "Secret,Deployment,Service" {
+		t.Errorf("Expected a Secret, Deployment and Service, got %v", kinds)
+	}
+}
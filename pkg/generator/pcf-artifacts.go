@@ -16,9 +16,12 @@ package generator
 
 import (
 	"bytes"
+	"fmt"
 	"log"
+	"strings"
 	"text/template"
 
+	"github.com/pivotal/cloud-service-broker/pkg/config"
 	"github.com/pivotal/cloud-service-broker/pkg/config/migration"
 	"github.com/pivotal/cloud-service-broker/utils"
 )
@@ -53,12 +56,17 @@ const (
 	manifestYmlTemplate = copyrightHeader + `
 applications:
 - name: {{.appName}}
+  command: {{.appName}} serve
   memory: 1G
   buildpacks: 
   - {{.buildpack}}
   env:
     GOPACKAGENAME: {{.goPackageName}}
-    GOVERSION: {{.goVersion}}`
+    GOVERSION: {{.goVersion}}
+    # Uncomment the configuration properties you want to set, they're shown
+    # with their default values.
+{{.configEnv}}
+  random-route: true`
 	tileYmlTemplate = copyrightHeader + `
 name: {{.appName}}
 icon_file: gcp_logo.png
@@ -80,7 +88,8 @@ packages:
 - name: {{.appName}}
   type: app-broker
   manifest:
-    buildpack: {{.buildpack}}
+    buildpacks: 
+    - {{.buildpack}}
     path: /tmp/cloud-service-broker.zip
     env:
       GOPACKAGENAME: {{.goPackageName}}
@@ -121,6 +130,7 @@ func runPcfTemplate(templateString string) string {
 		"stemcellOs":      stemcellOs,
 		"stemcellVersion": stemcellVersion,
 		"migrationScript": utils.Indent(migrator.TileScript, "  "),
+		"configEnv":       utils.Indent(manifestConfigEnv(), "    # "),
 	}
 
 	tmpl, err := template.New("tmpl").Parse(templateString)
@@ -137,3 +147,31 @@ func runPcfTemplate(templateString string) string {
 
 	return buf.String()
 }
+
+// manifestConfigEnv lists the environment variables of the registered
+// configuration properties with their defaults, except those the platform
+// sets. Sensitive properties are
+// listed without one so secrets don't end up in the manifest.
+func manifestConfigEnv() string {
+	var lines []string
+	for _, prop := range config.Properties() {
+		if platformEnv[prop.Env] {
+			continue
+		}
+
+		name := prop.EnvVar()
+		if prop.Env != "" {
+			name = prop.Env
+		}
+
+		value := configPropertyDefault(prop)
+		if prop.Sensitive || value == "" {
+			lines = append(lines, name+":")
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("%s: %q", name, value))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,175 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"text/template"
+
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+const serviceCatalogYmlTemplate = copyrightHeader + `
+# Register the broker with the Kubernetes Service Catalog. Set the broker's
+# username and password, they're its SECURITY_USER_NAME and
+# SECURITY_USER_PASSWORD.
+apiVersion: v1
+kind: Secret
+metadata:
+  name: {{.name}}-auth
+  namespace: {{.namespace}}
+type: Opaque
+stringData:
+  username: ""
+  password: ""
+---
+apiVersion: servicecatalog.k8s.io/v1beta1
+kind: ClusterServiceBroker
+metadata:
+  name: {{.name}}
+spec:
+  url: http://{{.name}}.{{.namespace}}.svc.cluster.local
+  authInfo:
+    basic:
+      secretRef:
+        name: {{.name}}-auth
+        namespace: {{.namespace}}
+{{- range .instances}}
+---
+# {{.Description}}
+apiVersion: servicecatalog.k8s.io/v1beta1
+kind: ServiceInstance
+metadata:
+  name: {{.Name}}
+spec:
+  clusterServiceClassExternalName: {{.Service}}
+  clusterServicePlanExternalName: {{.Plan}}
+  parameters: {{.ProvisionParams}}
+{{- if .Bindable}}
+---
+apiVersion: servicecatalog.k8s.io/v1beta1
+kind: ServiceBinding
+metadata:
+  name: {{.Name}}-binding
+spec:
+  instanceRef:
+    name: {{.Name}}
+  secretName: {{.Name}}-credentials
+  parameters: {{.BindParams}}
+{{- end}}
+{{- end}}
+`
+
+// ServiceCatalogOptions customizes the generated Service Catalog resources.
+type ServiceCatalogOptions struct {
+	// Name is the name of the broker's Kubernetes Service, it's also used
+	// for the ClusterServiceBroker.
+	Name string
+
+	// Namespace is the namespace the broker runs in.
+	Namespace string
+}
+
+// DefaultServiceCatalogOptions returns the options used if none are given on
+// the command line, they match the defaults of GenerateKubernetes.
+func DefaultServiceCatalogOptions() ServiceCatalogOptions {
+	return ServiceCatalogOptions{
+		Name:      appName,
+		Namespace: "default",
+	}
+}
+
+// serviceCatalogInstance is a ServiceInstance, and its ServiceBinding if the
+// service is bindable, in the generated resources.
+type serviceCatalogInstance struct {
+	Name            string
+	Description     string
+	Service         string
+	Plan            string
+	Bindable        bool
+	ProvisionParams string
+	BindParams      string
+}
+
+// GenerateServiceCatalog creates Kubernetes resources to get started with the
+// broker on the Kubernetes Service Catalog: a ClusterServiceBroker that
+// registers it, and a ServiceInstance and ServiceBinding for every plan of
+// the services in the registry. The parameters come from the service's first
+// example for the plan, or are empty if it has none.
+func GenerateServiceCatalog(registry broker.BrokerRegistry, opts ServiceCatalogOptions) string {
+	var instances []serviceCatalogInstance
+	for _, svc := range registry.GetAllServices() {
+		catalog, err := svc.CatalogEntry()
+		if err != nil {
+			log.Fatalf("Error getting catalog entry for %s: %v", svc.Name, err)
+		}
+
+		for _, plan := range catalog.Plans {
+			example := broker.ServiceExample{Description: fmt.Sprintf("The %s plan of %s.", plan.Name, svc.Name)}
+			for _, candidate := range svc.Examples {
+				if candidate.PlanId == plan.ID {
+					example = candidate
+					break
+				}
+			}
+
+			instances = append(instances, serviceCatalogInstance{
+				Name:            kubernetesName(fmt.Sprintf("%s-%s", svc.Name, plan.Name)),
+				Description:     cleanLines(example.Description),
+				Service:         svc.Name,
+				Plan:            plan.Name,
+				Bindable:        svc.Bindable,
+				ProvisionParams: serviceCatalogParams(example.ProvisionParams),
+				BindParams:      serviceCatalogParams(example.BindParams),
+			})
+		}
+	}
+
+	vars := map[string]interface{}{
+		"name":      opts.Name,
+		"namespace": opts.Namespace,
+		"instances": instances,
+	}
+
+	tmpl, err := template.New("tmpl").Parse(serviceCatalogYmlTemplate)
+	if err != nil {
+		log.Fatalf("parsing: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		log.Fatalf("execution: %s", err)
+	}
+
+	return buf.String()
+}
+
+// serviceCatalogParams formats the parameters as a YAML flow mapping, the
+// Service Catalog requires an object so nil parameters are empty.
+func serviceCatalogParams(params map[string]interface{}) string {
+	if params == nil {
+		return "{}"
+	}
+
+	out, err := json.Marshal(params)
+	if err != nil {
+		log.Fatalf("Error marshaling parameters: %v", err)
+	}
+
+	return string(out)
+}
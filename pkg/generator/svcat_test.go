@@ -0,0 +1,95 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin/storage"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestGenerateServiceCatalog(t *testing.T) {
+	svc := storage.ServiceDefinition()
+	registry := broker.BrokerRegistry{svc.Name: svc}
+
+	opts := DefaultServiceCatalogOptions()
+	opts.Namespace = "brokers"
+
+	decoder := yaml.NewDecoder(strings.NewReader(GenerateServiceCatalog(registry, opts)))
+
+	kinds := make(map[string]int)
+	params := make(map[string]map[string]interface{})
+	for {
+		var doc struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+			Spec struct {
+				Url                             string                 `yaml:"url"`
+				ClusterServiceClassExternalName string                 `yaml:"clusterServiceClassExternalName"`
+				ClusterServicePlanExternalName  string                 `yaml:"clusterServicePlanExternalName"`
+				SecretName                      string                 `yaml:"secretName"`
+				Parameters                      map[string]interface{} `yaml:"parameters"`
+			} `yaml:"spec"`
+		}
+		if err := decoder.Decode(&doc); err != nil {
+			break
+		}
+
+		kinds[doc.Kind]++
+		params[doc.Metadata.Name] = doc.Spec.Parameters
+
+		switch doc.Kind {
+		case "ClusterServiceBroker":
+			if expected := "http://cloud-service-broker.brokers.svc.cluster.local"; doc.Spec.Url != expected {
+				t.Errorf("Expected the broker URL %q, got %q", expected, doc.Spec.Url)
+			}
+		case "ServiceInstance":
+			if doc.Spec.ClusterServiceClassExternalName != svc.Name {
+				t.Errorf("Expected instance %s to use class %q, got %q", doc.Metadata.Name, svc.Name, doc.Spec.ClusterServiceClassExternalName)
+			}
+			if doc.Spec.Parameters == nil {
+				t.Errorf("Expected instance %s to have parameters", doc.Metadata.Name)
+			}
+		case "ServiceBinding":
+			if doc.Spec.SecretName != doc.Metadata.Name[:len(doc.Metadata.Name)-len("-binding")]+"-credentials" {
+				t.Errorf("Unexpected secret name %q of binding %s", doc.Spec.SecretName, doc.Metadata.Name)
+			}
+		}
+	}
+
+	expectedKinds := map[string]int{
+		"Secret":               1,
+		"ClusterServiceBroker": 1,
+		"ServiceInstance":      len(svc.Plans),
+		"ServiceBinding":       len(svc.Plans),
+	}
+	if !reflect.DeepEqual(kinds, expectedKinds) {
+		t.Errorf("Expected resources %v, got %v", expectedKinds, kinds)
+	}
+
+	// plans with an example use its parameters, the others none
+	if expected := map[string]interface{}{"location": "us-west1"}; !reflect.DeepEqual(params["google-storage-regional"], expected) {
+		t.Errorf("Expected the regional plan's parameters to be %v, got %v", expected, params["google-storage-regional"])
+	}
+	if standard, ok := params["google-storage-standard"]; !ok || len(standard) != 0 {
+		t.Errorf("Expected the standard plan to have no parameters, got %v", standard)
+	}
+}
@@ -18,10 +18,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"text/template"
 
@@ -29,6 +31,8 @@ import (
 	"github.com/pivotal/cloud-service-broker/pkg/validation"
 )
 
+var invalidKubernetesNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
 // CatalogDocumentation generates markdown documentation for the service catalog
 // of the given registry. Returns all content in one string.
 func CatalogDocumentation(registry broker.BrokerRegistry) string {
@@ -140,6 +144,10 @@ func generateServiceDocumentation(svc *broker.ServiceDefinition) string {
 		"bindIn":             svc.BindInputVariables,
 		"bindOut":            svc.BindOutputVariables,
 		"provisionInputVars": svc.ProvisionInputVariables,
+		"provisionSchema":    broker.CreateJsonSchema(svc.ProvisionInputVariables),
+		"bindSchema":         broker.CreateJsonSchema(svc.BindInputVariables),
+		"requiredRoles":      svc.RequiredRoles,
+		"bindRoles":          svc.RoleWhitelist(),
 		"examples":           svc.Examples,
 	}
 
@@ -148,6 +156,7 @@ func generateServiceDocumentation(svc *broker.ServiceDefinition) string {
 		"join":          strings.Join,
 		"varNotes":      varNotes,
 		"jsonCodeBlock": jsonCodeBlock,
+		"jsonCode":      jsonCode,
 		"exampleCommands": func(example broker.ServiceExample) string {
 			planName := "unknown-plan"
 			for _, plan := range catalog.Plans {
@@ -169,6 +178,9 @@ func generateServiceDocumentation(svc *broker.ServiceDefinition) string {
 			bind := fmt.Sprintf("$ cf bind-service my-app my-%s-example -c `%s`", catalog.Name, params)
 			return provision + "\n" + bind
 		},
+		"kubernetesExample": func(example broker.ServiceExample) string {
+			return kubernetesExample(catalog, example)
+		},
 	}
 
 	templateText := `
@@ -188,6 +200,11 @@ func generateServiceDocumentation(svc *broker.ServiceDefinition) string {
 {{ if eq (len .provisionInputVars) 0 }}_No parameters supported._{{ end }}
 {{ range $i, $var := .provisionInputVars }} * {{ varNotes $var }}
 {{ end }}
+{{ if .provisionInputVars -}}
+**JSON Schema**
+
+{{ jsonCodeBlock .provisionSchema }}
+{{ end }}
 
 ## Binding
 
@@ -196,10 +213,27 @@ func generateServiceDocumentation(svc *broker.ServiceDefinition) string {
 {{ if eq (len .bindIn) 0 }}_No parameters supported._{{ end }}
 {{ range $i, $var := .bindIn }} * {{ varNotes $var }}
 {{ end }}
+{{ if .bindIn -}}
+**JSON Schema**
+
+{{ jsonCodeBlock .bindSchema }}
+{{ end }}
 **Response Parameters**
 
 {{ range $i, $var := .bindOut }} * {{ varNotes $var }}
 {{ end }}
+## IAM Roles
+
+{{ if eq (len .requiredRoles) 0 }}_The service doesn't list the roles the broker needs._{{ else -}}
+The broker's service account needs the following roles to provision and bind this service:
+
+{{ range .requiredRoles }} * {{ code . }}
+{{ end }}{{ end }}
+{{ if .bindRoles -}}
+Bindings can be granted the following roles:
+
+{{ range .bindRoles }} * {{ code . }}
+{{ end }}{{ end }}
 ## Plans
 
 The following plans are built-in to the Cloud Service Broker and may be overridden
@@ -210,7 +244,11 @@ or disabled by the broker administrator.
 * **{{code $plan.Name }}**
   * Plan ID: {{code $plan.ID}}.
   * Description: {{ $plan.Description }}
-  * This plan {{ if eq (len $plan.ProvisionOverrides) 0 -}} doesn't override {{- else -}} overrides the following {{- end}} user variables on provision.
+{{ if $plan.ProvisionDefaults }}  * This plan defaults the following user variables on provision.
+{{ range $k, $v := $plan.ProvisionDefaults}}    * {{ code $k }} = {{code $v}}
+{{ end }}{{ end }}{{ if $plan.Constraints }}  * This plan constrains the following user variables.
+{{ range $k, $v := $plan.Constraints}}    * {{ code $k }}: {{jsonCode $v}}
+{{ end }}{{ end }}  * This plan {{ if eq (len $plan.ProvisionOverrides) 0 -}} doesn't override {{- else -}} overrides the following {{- end}} user variables on provision.
 {{ range $k, $v := $plan.ProvisionOverrides}}    * {{ code $k }} = {{code $v}}
 {{ end }}  * This plan {{ if eq (len $plan.BindOverrides) 0 -}} doesn't override {{- else -}} overrides the following {{- end}} user variables on bind.
 {{ range $k, $v := $plan.BindOverrides}}    * {{ code $k }} = {{code $v}}
@@ -242,12 +280,70 @@ Uses plan: {{ code $example.PlanId }}.
 {{exampleCommands $example}}
 </pre>
 
+**Kubernetes Example**
+
+<pre>
+{{kubernetesExample $example}}
+</pre>
+
 {{ end }}
 `
 
 	return render(templateText, vars, funcMap)
 }
 
+// kubernetesExample creates the kubectl command to provision and bind the
+// example with the Kubernetes Service Catalog. The command is HTML escaped
+// because it's shown in a pre block.
+func kubernetesExample(catalog *broker.Service, example broker.ServiceExample) string {
+	planName := "unknown-plan"
+	for _, plan := range catalog.Plans {
+		if plan.ID == example.PlanId {
+			planName = plan.Name
+		}
+	}
+
+	provisionParams, err := json.Marshal(example.ProvisionParams)
+	if err != nil {
+		return err.Error()
+	}
+
+	bindParams, err := json.Marshal(example.BindParams)
+	if err != nil {
+		return err.Error()
+	}
+
+	name := kubernetesName(fmt.Sprintf("my-%s-example", catalog.Name))
+	lines := []string{
+		"$ kubectl apply -f - <<EOF",
+		"apiVersion: servicecatalog.k8s.io/v1beta1",
+		"kind: ServiceInstance",
+		"metadata:",
+		"  name: " + name,
+		"spec:",
+		"  clusterServiceClassExternalName: " + catalog.Name,
+		"  clusterServicePlanExternalName: " + planName,
+		"  parameters: " + string(provisionParams),
+		"---",
+		"apiVersion: servicecatalog.k8s.io/v1beta1",
+		"kind: ServiceBinding",
+		"metadata:",
+		"  name: " + name + "-binding",
+		"spec:",
+		"  instanceRef:",
+		"    name: " + name,
+		"  parameters: " + string(bindParams),
+		"EOF",
+	}
+
+	return html.EscapeString(strings.Join(lines, "\n"))
+}
+
+// kubernetesName converts the text into a valid Kubernetes resource name.
+func kubernetesName(text string) string {
+	return strings.Trim(invalidKubernetesNameChars.ReplaceAllString(strings.ToLower(text), "-"), "-")
+}
+
 func render(tmplText string, vars interface{}, funcMap template.FuncMap) string {
 	tmpl, err := template.New("rendered").Funcs(funcMap).Parse(tmplText)
 	if err != nil {
@@ -357,3 +453,8 @@ func jsonCodeBlock(value interface{}) string {
 	block, _ := json.MarshalIndent(value, "", "    ")
 	return fmt.Sprintf("```javascript\n%s\n```", block)
 }
+
+func jsonCode(value interface{}) string {
+	inline, _ := json.Marshal(value)
+	return mdCode(string(inline))
+}
@@ -0,0 +1,56 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin/storage"
+)
+
+func TestGenerateServiceDocumentation(t *testing.T) {
+	docs := generateServiceDocumentation(storage.ServiceDefinition())
+
+	for _, expected := range []string{
+		"**JSON Schema**",
+		`"force_delete": {`,
+		"## IAM Roles",
+		" * `roles/storage.admin`",
+		" * `storage.objectViewer`",
+		"$ cf create-service google-storage nearline my-google-storage-example",
+		"$ kubectl apply -f - &lt;&lt;EOF",
+		"  clusterServicePlanExternalName: nearline",
+		"  name: my-google-storage-example-binding",
+	} {
+		if !strings.Contains(docs, expected) {
+			t.Errorf("Expected the documentation to contain %q, got:\n%s", expected, docs)
+		}
+	}
+}
+
+func TestKubernetesName(t *testing.T) {
+	cases := map[string]string{
+		"my-google-storage-example": "my-google-storage-example",
+		"my-Foo.Bar_baz-example":    "my-foo-bar-baz-example",
+		"--leading and trailing--":  "leading-and-trailing",
+	}
+
+	for text, expected := range cases {
+		if actual := kubernetesName(text); actual != expected {
+			t.Errorf("Expected kubernetesName(%q) to be %q, got %q", text, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package imports holds the types used to register resources that already
+// exist, e.g. ones created by hand or by another broker, as service instances
+// the broker manages.
+package imports
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Request describes the instance an import creates. Parameters are the
+// provision parameters that identify the existing resources, e.g. their name,
+// and configure the instance like they would a new one.
+type Request struct {
+	InstanceId string `json:"instance_id"`
+	// Service holds the name or ID of a service.
+	Service string `json:"service"`
+	// Plan holds the name or ID of a plan.
+	Plan             string          `json:"plan"`
+	OrganizationGuid string          `json:"organization_guid"`
+	SpaceGuid        string          `json:"space_guid"`
+	Parameters       json.RawMessage `json:"parameters,omitempty"`
+}
+
+// InvalidRequestError is returned for requests that can't be imported as
+// they are, as opposed to ones that fail.
+type InvalidRequestError struct {
+	Message string
+}
+
+func (e *InvalidRequestError) Error() string {
+	return e.Message
+}
+
+// Invalidf creates an InvalidRequestError with a formatted message.
+func Invalidf(format string, a ...interface{}) error {
+	return &InvalidRequestError{Message: fmt.Sprintf(format, a...)}
+}
+
+// Validate checks the request sets the fields every import needs.
+func (r *Request) Validate() error {
+	var missing []string
+	for _, field := range []struct{ name, value string }{
+		{"instance_id", r.InstanceId},
+		{"service", r.Service},
+		{"plan", r.Plan},
+		{"organization_guid", r.OrganizationGuid},
+		{"space_guid", r.SpaceGuid},
+	} {
+		if field.value == "" {
+			missing = append(missing, field.name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return Invalidf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// Result describes an imported instance. Asynchronous imports are finished
+// once the operation succeeds, it can be polled like a provision's.
+type Result struct {
+	InstanceId  string `json:"instance_id"`
+	ServiceId   string `json:"service_id"`
+	PlanId      string `json:"plan_id"`
+	Async       bool   `json:"async"`
+	OperationId string `json:"operation_id,omitempty"`
+}
+
+// Importer imports existing resources as service instances.
+type Importer interface {
+	// ImportInstance registers the resources the request identifies as a new
+	// service instance with the request's ID.
+	ImportInstance(ctx context.Context, request Request) (*Result, error)
+}
@@ -0,0 +1,180 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package interceptors lets programs that embed the broker add their own
+// middleware around the OSB API, e.g. custom authentication, request
+// mutation, policy checks or extra logging, without forking the handlers.
+//
+// Interceptors are registered before the broker is started, typically in the
+// embedding program's main function before calling cmd.Execute. Each one runs
+// at a stage of the broker's own middleware chain and can be limited to some
+// of the OSB endpoints.
+package interceptors
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/pivotal/cloud-service-broker/pkg/ratelimit"
+	"github.com/pivotal/cloud-service-broker/utils"
+)
+
+// Stage is where in the broker's middleware chain an interceptor runs.
+type Stage int
+
+const (
+	// BeforeAuthentication interceptors see every OSB request before the
+	// broker authenticates it, e.g. to add an authentication scheme the
+	// broker doesn't support. The broker's authentication still applies to
+	// the requests they pass on.
+	BeforeAuthentication Stage = iota
+
+	// BeforeHandler interceptors run after the broker's own middleware, just
+	// before the request is handled. Requests have been authenticated, rate
+	// limited and their API version negotiated, so this is where policy
+	// checks and request mutation belong.
+	BeforeHandler
+)
+
+func (s Stage) String() string {
+	switch s {
+	case BeforeAuthentication:
+		return "before-authentication"
+	case BeforeHandler:
+		return "before-handler"
+	default:
+		return fmt.Sprintf("stage-%d", int(s))
+	}
+}
+
+// Interceptor wraps OSB API requests.
+type Interceptor struct {
+	// Name identifies the interceptor in logs.
+	Name string
+
+	// Stage is where the interceptor runs.
+	Stage Stage
+
+	// Endpoints limits the interceptor to the named endpoints, see
+	// ratelimit.Endpoints. It applies to every request if it's empty.
+	Endpoints []string
+
+	// Wrap returns a handler that runs the interceptor around next. The
+	// handler may respond itself instead of calling next to reject a request.
+	Wrap func(next http.Handler) http.Handler
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []*Interceptor
+)
+
+// Register adds an interceptor. Interceptors at the same stage run in the
+// order they're registered, the first one outermost. It panics if the name
+// is empty or already registered, the stage or an endpoint is unknown, or
+// Wrap is nil.
+//
+// Interceptors registered after the broker has started aren't used.
+func Register(interceptor Interceptor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if err := validate(interceptor); err != nil {
+		panic(err.Error())
+	}
+
+	registry = append(registry, &interceptor)
+}
+
+func validate(interceptor Interceptor) error {
+	if interceptor.Name == "" {
+		return fmt.Errorf("interceptor names must not be empty")
+	}
+
+	for _, existing := range registry {
+		if existing.Name == interceptor.Name {
+			return fmt.Errorf("duplicate interceptor name %q", interceptor.Name)
+		}
+	}
+
+	if interceptor.Stage != BeforeAuthentication && interceptor.Stage != BeforeHandler {
+		return fmt.Errorf("interceptor %q has unknown stage %s", interceptor.Name, interceptor.Stage)
+	}
+
+	if interceptor.Wrap == nil {
+		return fmt.Errorf("interceptor %q has no Wrap function", interceptor.Name)
+	}
+
+	known := utils.NewStringSet(ratelimit.Endpoints...)
+	for _, endpoint := range interceptor.Endpoints {
+		if !known.Contains(endpoint) {
+			return fmt.Errorf("interceptor %q has unknown endpoint %q, expected one of %v", interceptor.Name, endpoint, ratelimit.Endpoints)
+		}
+	}
+
+	return nil
+}
+
+// Registered lists the registered interceptors in the order they were
+// registered.
+func Registered() []Interceptor {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	var out []Interceptor
+	for _, interceptor := range registry {
+		out = append(out, *interceptor)
+	}
+
+	return out
+}
+
+// Chain returns middleware that runs the interceptors registered for the
+// stage. It must be added to the router the OSB API routes are attached to so
+// requests can be matched to their endpoints.
+func Chain(stage Stage) func(http.Handler) http.Handler {
+	var interceptors []Interceptor
+	for _, interceptor := range Registered() {
+		if interceptor.Stage == stage {
+			interceptors = append(interceptors, interceptor)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			next = scoped(interceptors[i], next)
+		}
+
+		return next
+	}
+}
+
+// scoped runs the interceptor around next for the requests to its endpoints
+// and passes the rest straight to next.
+func scoped(interceptor Interceptor, next http.Handler) http.Handler {
+	wrapped := interceptor.Wrap(next)
+	if len(interceptor.Endpoints) == 0 {
+		return wrapped
+	}
+
+	endpoints := utils.NewStringSet(interceptor.Endpoints...)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if endpoints.Contains(ratelimit.Endpoint(req)) {
+			wrapped.ServeHTTP(w, req)
+		} else {
+			next.ServeHTTP(w, req)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// reset removes every registered interceptor.
+func reset() {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry = nil
+}
+
+// tracing returns an interceptor that appends its name to the X-Trace header
+// of the request before calling next.
+func tracing(name string, stage Stage, endpoints ...string) Interceptor {
+	return Interceptor{
+		Name:      name,
+		Stage:     stage,
+		Endpoints: endpoints,
+		Wrap: func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				req.Header.Add("X-Trace", name)
+				next.ServeHTTP(w, req)
+			})
+		},
+	}
+}
+
+func TestChain(t *testing.T) {
+	rejectAll := Interceptor{
+		Name:      "policy",
+		Stage:     BeforeHandler,
+		Endpoints: []string{"bind"},
+		Wrap: func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				http.Error(w, "denied by policy", http.StatusForbidden)
+			})
+		},
+	}
+
+	cases := map[string]struct {
+		Interceptors []Interceptor
+		Stage        Stage
+		Method       string
+		Path         string
+		ExpectTrace  []string
+		ExpectStatus int
+	}{
+		"none": {
+			Stage:        BeforeHandler,
+			Method:       http.MethodPut,
+			Path:         "/v2/service_instances/instance-1",
+			ExpectStatus: http.StatusOK,
+		},
+		"registration order": {
+			Interceptors: []Interceptor{tracing("first", BeforeHandler), tracing("second", BeforeHandler)},
+			Stage:        BeforeHandler,
+			Method:       http.MethodPut,
+			Path:         "/v2/service_instances/instance-1",
+			ExpectTrace:  []string{"first", "second"},
+			ExpectStatus: http.StatusOK,
+		},
+		"other stage": {
+			Interceptors: []Interceptor{tracing("auth", BeforeAuthentication), tracing("log", BeforeHandler)},
+			Stage:        BeforeAuthentication,
+			Method:       http.MethodPut,
+			Path:         "/v2/service_instances/instance-1",
+			ExpectTrace:  []string{"auth"},
+			ExpectStatus: http.StatusOK,
+		},
+		"matching endpoint": {
+			Interceptors: []Interceptor{tracing("provision-only", BeforeHandler, "provision")},
+			Stage:        BeforeHandler,
+			Method:       http.MethodPut,
+			Path:         "/v2/service_instances/instance-1",
+			ExpectTrace:  []string{"provision-only"},
+			ExpectStatus: http.StatusOK,
+		},
+		"other endpoint": {
+			Interceptors: []Interceptor{tracing("provision-only", BeforeHandler, "provision")},
+			Stage:        BeforeHandler,
+			Method:       http.MethodDelete,
+			Path:         "/v2/service_instances/instance-1",
+			ExpectStatus: http.StatusOK,
+		},
+		"rejected": {
+			Interceptors: []Interceptor{rejectAll},
+			Stage:        BeforeHandler,
+			Method:       http.MethodPut,
+			Path:         "/v2/service_instances/instance-1/service_bindings/binding-1",
+			ExpectStatus: http.StatusForbidden,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			reset()
+			defer reset()
+			for _, interceptor := range tc.Interceptors {
+				Register(interceptor)
+			}
+
+			var trace []string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				trace = req.Header["X-Trace"]
+			})
+
+			router := mux.NewRouter()
+			router.Use(Chain(tc.Stage))
+			router.Handle("/v2/service_instances/{instance_id}", handler).Methods(http.MethodPut, http.MethodDelete)
+			router.Handle("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", handler).Methods(http.MethodPut)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tc.Method, tc.Path, nil))
+
+			if w.Code != tc.ExpectStatus {
+				t.Errorf("Expected status %d, got %d", tc.ExpectStatus, w.Code)
+			}
+
+			if !reflect.DeepEqual(trace, tc.ExpectTrace) {
+				t.Errorf("Expected interceptors %v to run, got %v", tc.ExpectTrace, trace)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	cases := map[string]struct {
+		Interceptor Interceptor
+		ExpectPanic string
+	}{
+		"valid": {
+			Interceptor: tracing("valid", BeforeHandler, "provision", "bind"),
+		},
+		"no name": {
+			Interceptor: tracing("", BeforeHandler),
+			ExpectPanic: "must not be empty",
+		},
+		"duplicate": {
+			Interceptor: tracing("existing", BeforeHandler),
+			ExpectPanic: `duplicate interceptor name "existing"`,
+		},
+		"unknown stage": {
+			Interceptor: tracing("bad-stage", Stage(7)),
+			ExpectPanic: "unknown stage stage-7",
+		},
+		"unknown endpoint": {
+			Interceptor: tracing("bad-endpoint", BeforeHandler, "provison"),
+			ExpectPanic: `unknown endpoint "provison"`,
+		},
+		"no wrap": {
+			Interceptor: Interceptor{Name: "no-wrap"},
+			ExpectPanic: "has no Wrap function",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			reset()
+			defer reset()
+			Register(tracing("existing", BeforeAuthentication))
+
+			defer func() {
+				actual := recover()
+				if tc.ExpectPanic == "" {
+					if actual != nil {
+						t.Errorf("Expected no panic, got %v", actual)
+					}
+					if len(Registered()) != 2 {
+						t.Errorf("Expected the interceptor to be registered, got %v", Registered())
+					}
+					return
+				}
+
+				if actual == nil || !strings.Contains(actual.(string), tc.ExpectPanic) {
+					t.Errorf("Expected a panic containing %q, got %v", tc.ExpectPanic, actual)
+				}
+			}()
+
+			Register(tc.Interceptor)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package inventory holds the types operators use to look up the instances,
+// bindings and operations the broker manages.
+package inventory
+
+import (
+	"context"
+	"time"
+)
+
+// The states of instances and bindings.
+const (
+	StateInProgress = "in progress"
+	StateSucceeded  = "succeeded"
+	StateFailed     = "failed"
+	StateActive     = "active"
+	StateRevoked    = "revoked"
+)
+
+// DefaultOperationLimit is the number of operations listed if the filter
+// doesn't set a limit.
+const DefaultOperationLimit = 50
+
+// Filter selects the instances, bindings or operations to list. Empty fields
+// match everything. Service and Plan match either IDs or names.
+type Filter struct {
+	Service          string
+	Plan             string
+	OrganizationGuid string
+	SpaceGuid        string
+	State            string
+
+	// Stuck selects only instances that have been deleting for too long.
+	Stuck bool
+
+	// Limit is the maximum number of operations to list.
+	Limit int
+}
+
+// Instance describes a service instance.
+type Instance struct {
+	InstanceId         string    `json:"instance_id"`
+	ServiceId          string    `json:"service_id"`
+	ServiceName        string    `json:"service_name"`
+	PlanId             string    `json:"plan_id"`
+	PlanName           string    `json:"plan_name"`
+	OrganizationGuid   string    `json:"organization_guid"`
+	SpaceGuid          string    `json:"space_guid"`
+	State              string    `json:"state"`
+	OperationType      string    `json:"operation_type,omitempty"`
+	Stuck              bool      `json:"stuck,omitempty"`
+	MaintenanceVersion string    `json:"maintenance_version,omitempty"`
+	Experiments        []string  `json:"experiments,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// Binding describes a service binding. Bindings inherit the plan,
+// organization and space of their instance.
+type Binding struct {
+	BindingId        string     `json:"binding_id"`
+	InstanceId       string     `json:"instance_id"`
+	ServiceId        string     `json:"service_id"`
+	ServiceName      string     `json:"service_name"`
+	PlanId           string     `json:"plan_id"`
+	OrganizationGuid string     `json:"organization_guid"`
+	SpaceGuid        string     `json:"space_guid"`
+	State            string     `json:"state"`
+	CreatedAt        time.Time  `json:"created_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Operation describes the last operation run on an instance or binding.
+type Operation struct {
+	InstanceId  string    `json:"instance_id"`
+	BindingId   string    `json:"binding_id,omitempty"`
+	ServiceId   string    `json:"service_id,omitempty"`
+	ServiceName string    `json:"service_name,omitempty"`
+	PlanId      string    `json:"plan_id,omitempty"`
+	Type        string    `json:"type"`
+	State       string    `json:"state"`
+	Message     string    `json:"message,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Lister lists what the broker manages.
+type Lister interface {
+	ListInstances(ctx context.Context, filter Filter) ([]Instance, error)
+	ListBindings(ctx context.Context, filter Filter) ([]Binding, error)
+	ListOperations(ctx context.Context, filter Filter) ([]Operation, error)
+}
@@ -0,0 +1,468 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jobs runs background work from a queue persisted in the database,
+// so work survives broker restarts and can be shared between broker
+// instances. Each instance runs a pool of workers that claim jobs with a
+// lease they renew while the job runs; if an instance stops without
+// finishing a job its lease expires and another worker runs it again.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/viper"
+)
+
+const (
+	// WorkersProp is the viper key of the number of jobs each broker
+	// instance runs at once.
+	WorkersProp = "jobs.workers"
+
+	// PollIntervalProp is the viper key of how often idle workers check for
+	// new jobs.
+	PollIntervalProp = "jobs.poll_interval"
+
+	// LeaseProp is the viper key of how long other instances wait for a
+	// worker to renew its claim on a job before running it themselves.
+	LeaseProp = "jobs.lease"
+
+	// MaxAttemptsProp is the viper key of how many times a failing job is
+	// run before it's marked as failed.
+	MaxAttemptsProp = "jobs.max_attempts"
+
+	// RetryBackoffProp is the viper key of how long to wait before the
+	// second attempt of a failed job, it doubles for each attempt after.
+	RetryBackoffProp = "jobs.retry_backoff"
+
+	// RetentionProp is the viper key of how long succeeded and failed jobs
+	// are kept. Zero keeps them.
+	RetentionProp = "jobs.retention"
+
+	// PurgeIntervalProp is the viper key of how often a running broker
+	// deletes jobs past the retention.
+	PurgeIntervalProp = "jobs.purge_interval"
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: WorkersProp, Kind: config.Integer, Default: 4},
+		config.Property{Key: PollIntervalProp, Kind: config.Duration, Default: "1s"},
+		config.Property{Key: LeaseProp, Kind: config.Duration, Default: "1m"},
+		config.Property{Key: MaxAttemptsProp, Kind: config.Integer, Default: 1},
+		config.Property{Key: RetryBackoffProp, Kind: config.Duration, Default: "30s"},
+		config.Property{Key: RetentionProp, Kind: config.Duration, Default: "168h"},
+		config.Property{Key: PurgeIntervalProp, Kind: config.Duration, Default: "1h"},
+	)
+}
+
+// Handler runs a job. Returning an error fails the attempt. Handlers can
+// compare the job's Attempts to MaxAttempts to tell if it will be retried.
+// The context is cancelled if the worker loses its lease on the job or the
+// queue is drained, handlers should stop and return promptly when it is.
+type Handler func(ctx context.Context, job *models.Job) error
+
+// permanentError is a job failure that won't be fixed by running the job
+// again.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error returned by a Handler as one that won't be fixed
+// by running the job again, so the job fails without using its remaining
+// attempts.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent returns true if the error was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Database stores the queue. db_service.SqlDatastore implements it.
+type Database interface {
+	CreateJob(ctx context.Context, job *models.Job) error
+	ClaimJob(ctx context.Context, kinds []string, worker string, lease time.Duration) (*models.Job, error)
+	RenewJobLease(ctx context.Context, job *models.Job, lease time.Duration) error
+	FinishJob(ctx context.Context, job *models.Job, retryAfter time.Duration) error
+	ReleaseJob(ctx context.Context, job *models.Job) error
+}
+
+// Default is the queue of the broker's background work. Handlers are
+// registered with it as services are loaded; its Database is set to the
+// broker's datastore before jobs are queued or its workers are started.
+var Default = NewQueue(nil)
+
+// Queue enqueues jobs and runs them with registered handlers.
+type Queue struct {
+	Database Database
+	// Worker identifies this broker instance in claimed jobs.
+	Worker       string
+	PollInterval time.Duration
+	Lease        time.Duration
+	MaxAttempts  int
+	RetryBackoff time.Duration
+	Logger       lager.Logger
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	running  map[uint]*runningJob
+	wg       sync.WaitGroup
+	stop     context.CancelFunc
+}
+
+// NewQueue creates a queue stored in the database with the default settings.
+func NewQueue(db Database) *Queue {
+	hostname, _ := os.Hostname()
+	return &Queue{
+		Database:     db,
+		Worker:       fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		PollInterval: time.Second,
+		Lease:        time.Minute,
+		MaxAttempts:  1,
+		RetryBackoff: 30 * time.Second,
+		Logger:       utils.NewLogger("jobs"),
+		handlers:     make(map[string]Handler),
+		running:      make(map[uint]*runningJob),
+	}
+}
+
+// ConfigureFromEnv reads the queue's settings from viper.
+func (q *Queue) ConfigureFromEnv() error {
+	var err error
+	if q.PollInterval, err = parsePositiveDuration(PollIntervalProp); err != nil {
+		return err
+	}
+	if q.Lease, err = parsePositiveDuration(LeaseProp); err != nil {
+		return err
+	}
+	if q.RetryBackoff, err = parsePositiveDuration(RetryBackoffProp); err != nil {
+		return err
+	}
+
+	if q.MaxAttempts = viper.GetInt(MaxAttemptsProp); q.MaxAttempts < 1 {
+		return fmt.Errorf("%s must be at least 1, got %d", MaxAttemptsProp, q.MaxAttempts)
+	}
+
+	return nil
+}
+
+func parsePositiveDuration(prop string) (time.Duration, error) {
+	d, err := time.ParseDuration(viper.GetString(prop))
+	if err != nil {
+		return 0, fmt.Errorf("couldn't parse %s: %v", prop, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got %s", prop, d)
+	}
+
+	return d, nil
+}
+
+// Register sets the handler of a kind of job.
+func (q *Queue) Register(kind string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = handler
+}
+
+func (q *Queue) kinds() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var kinds []string
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func (q *Queue) handler(kind string) Handler {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.handlers[kind]
+}
+
+// Enqueue queues a job of the kind working on the target. The payload is
+// stored as JSON for the handler.
+func (q *Queue) Enqueue(ctx context.Context, kind, target string, payload interface{}) (*models.Job, error) {
+	return q.EnqueueWithAttempts(ctx, kind, target, payload, q.MaxAttempts)
+}
+
+// EnqueueWithAttempts queues a job like Enqueue, but runs it up to
+// maxAttempts times rather than the queue's MaxAttempts.
+func (q *Queue) EnqueueWithAttempts(ctx context.Context, kind, target string, payload interface{}, maxAttempts int) (*models.Job, error) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.Job{
+		Kind:        kind,
+		Target:      target,
+		Payload:     string(encoded),
+		State:       models.JobQueued,
+		MaxAttempts: maxAttempts,
+	}
+	if err := q.Database.CreateJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("Error queueing job: %s", err)
+	}
+
+	return job, nil
+}
+
+// Start runs the given number of workers in the background until Drain is
+// called.
+func (q *Queue) Start(workers int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.mu.Lock()
+	q.stop = cancel
+	q.mu.Unlock()
+
+	q.Logger.Info("starting workers", lager.Data{"workers": workers, "worker": q.Worker, "kinds": q.kinds()})
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			q.work(ctx)
+		}()
+	}
+}
+
+// work runs jobs until the context is done, waiting for the poll interval
+// when there are none.
+func (q *Queue) work(ctx context.Context) {
+	for {
+		ran, err := q.RunOnce(ctx)
+		if err != nil {
+			q.Logger.Error("running job", err)
+		}
+		if ran {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(q.PollInterval):
+		}
+	}
+}
+
+// RunOnce claims a job and runs it. It returns false if there was no job to
+// run.
+func (q *Queue) RunOnce(ctx context.Context) (bool, error) {
+	if ctx.Err() != nil {
+		return false, nil
+	}
+
+	kinds := q.kinds()
+	if len(kinds) == 0 {
+		return false, nil
+	}
+
+	job, err := q.Database.ClaimJob(ctx, kinds, q.Worker, q.Lease)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	// the handler gets its own context so jobs aren't interrupted when the
+	// workers stop, Drain cancels it instead
+	handlerCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	running := &runningJob{job: job, cancel: cancel}
+	q.mu.Lock()
+	q.running[job.ID] = running
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.running, job.ID)
+		q.mu.Unlock()
+	}()
+
+	logger := q.Logger.Session("job", lager.Data{"id": job.ID, "kind": job.Kind, "target": job.Target, "attempt": job.Attempts})
+	logger.Info("starting")
+
+	stopRenewing := q.renewLease(job, cancel, logger)
+	handlerErr := q.runHandler(handlerCtx, job)
+	lost := stopRenewing()
+
+	q.mu.Lock()
+	interrupted := running.interrupted
+	q.mu.Unlock()
+
+	switch {
+	case lost:
+		// another worker runs the job now, its result is theirs to save
+		logger.Info("lost", lager.Data{"error": fmt.Sprint(handlerErr)})
+		return true, nil
+	case interrupted && handlerErr != nil:
+		// the handler has returned so nothing touches the job's resources
+		// once it's back in the queue
+		logger.Info("released", lager.Data{"error": handlerErr.Error()})
+		if err := q.Database.ReleaseJob(context.Background(), job); err != nil {
+			return true, fmt.Errorf("releasing job %d: %v", job.ID, err)
+		}
+		return true, nil
+	}
+
+	var retryAfter time.Duration
+	switch {
+	case handlerErr == nil:
+		job.State = models.JobSucceeded
+		job.LastError = ""
+	case job.Attempts < job.MaxAttempts && !IsPermanent(handlerErr):
+		job.State = models.JobQueued
+		job.LastError = handlerErr.Error()
+		retryAfter = q.RetryBackoff << uint(job.Attempts-1)
+	default:
+		job.State = models.JobFailed
+		job.LastError = handlerErr.Error()
+	}
+
+	logger.Info("finished", lager.Data{"state": job.State, "error": job.LastError})
+	if err := q.Database.FinishJob(context.Background(), job, retryAfter); err != nil {
+		return true, fmt.Errorf("saving job %d: %v", job.ID, err)
+	}
+
+	return true, nil
+}
+
+// runningJob is a job a worker of this instance is running.
+type runningJob struct {
+	job    *models.Job
+	cancel context.CancelFunc
+
+	// interrupted is set by Drain, if the handler fails the job is returned
+	// to the queue rather than the attempt counting.
+	interrupted bool
+}
+
+func (q *Queue) runHandler(ctx context.Context, job *models.Job) (err error) {
+	handler := q.handler(job.Kind)
+	if handler == nil {
+		return fmt.Errorf("no handler is registered for %q jobs", job.Kind)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// renewLease renews the job's lease until the returned function is called,
+// which returns true if the lease was lost. If another worker takes the job
+// over renewing stops and lost is called so the handler stops too.
+func (q *Queue) renewLease(job *models.Job, lost context.CancelFunc, logger lager.Logger) func() bool {
+	done := make(chan struct{})
+	result := make(chan bool, 1)
+	go func() {
+		ticker := time.NewTicker(q.Lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				result <- false
+				return
+			case <-ticker.C:
+				err := q.Database.RenewJobLease(context.Background(), job, q.Lease)
+				if err == db_service.ErrJobLost {
+					logger.Error("lost lease", err)
+					lost()
+					<-done
+					result <- true
+					return
+				}
+				if err != nil {
+					logger.Error("renewing lease", err)
+				}
+			}
+		}
+	}()
+
+	return func() bool {
+		close(done)
+		return <-result
+	}
+}
+
+// Drain stops the workers claiming jobs and waits for running jobs to finish
+// until the context is done. Jobs still running after that are cancelled and,
+// once their handlers return, the ones that didn't finish are returned to the
+// queue so another instance, or this one once it restarts, runs them again.
+// It returns the number of jobs that were cancelled.
+func (q *Queue) Drain(ctx context.Context) (int, error) {
+	q.mu.Lock()
+	if q.stop != nil {
+		q.stop()
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0, nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	interrupted := len(q.running)
+	for _, running := range q.running {
+		running.interrupted = true
+		running.cancel()
+	}
+	q.mu.Unlock()
+
+	q.Logger.Info("cancelled running jobs", lager.Data{"count": interrupted})
+	<-done
+
+	return interrupted, nil
+}
@@ -0,0 +1,341 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// fakeDatabase keeps jobs in memory, due by its own clock.
+type fakeDatabase struct {
+	mu   sync.Mutex
+	now  time.Time
+	jobs []models.Job
+
+	// renewErr is returned when a lease is renewed.
+	renewErr error
+}
+
+func (db *fakeDatabase) CreateJob(ctx context.Context, job *models.Job) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	job.ID = uint(len(db.jobs) + 1)
+	job.RunAfter = db.now
+	db.jobs = append(db.jobs, *job)
+	return nil
+}
+
+func (db *fakeDatabase) ClaimJob(ctx context.Context, kinds []string, worker string, lease time.Duration) (*models.Job, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for i, job := range db.jobs {
+		if job.State != models.JobQueued || job.RunAfter.After(db.now) || !strings.Contains(strings.Join(kinds, ","), job.Kind) {
+			continue
+		}
+
+		expires := db.now.Add(lease)
+		job.State = models.JobRunning
+		job.Worker = worker
+		job.LeaseExpiresAt = &expires
+		job.Attempts++
+		db.jobs[i] = job
+		return &job, nil
+	}
+
+	return nil, nil
+}
+
+func (db *fakeDatabase) RenewJobLease(ctx context.Context, job *models.Job, lease time.Duration) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.renewErr
+}
+
+func (db *fakeDatabase) FinishJob(ctx context.Context, job *models.Job, retryAfter time.Duration) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	finished := *job
+	finished.RunAfter = db.now.Add(retryAfter)
+	db.jobs[job.ID-1] = finished
+	return nil
+}
+
+func (db *fakeDatabase) ReleaseJob(ctx context.Context, job *models.Job) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	released := *job
+	released.State = models.JobQueued
+	released.Worker = ""
+	released.Attempts--
+	db.jobs[job.ID-1] = released
+	return nil
+}
+
+func (db *fakeDatabase) job(id uint) models.Job {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.jobs[id-1]
+}
+
+func newTestQueue(db *fakeDatabase, now time.Time) *Queue {
+	db.now = now
+	q := NewQueue(db)
+	q.Worker = "worker-1"
+	q.Logger = lager.NewLogger("test")
+	return q
+}
+
+func TestQueue_RunOnce(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	failure := errors.New("apply failed")
+
+	cases := map[string]struct {
+		Handler      Handler
+		MaxAttempts  int
+		Runs         int
+		ExpectState  string
+		ExpectError  string
+		ExpectRunAt  time.Time
+		ExpectCalled int
+	}{
+		"succeeds": {
+			Handler:      func(ctx context.Context, job *models.Job) error { return nil },
+			MaxAttempts:  1,
+			Runs:         1,
+			ExpectState:  models.JobSucceeded,
+			ExpectRunAt:  now,
+			ExpectCalled: 1,
+		},
+		"fails": {
+			Handler:      func(ctx context.Context, job *models.Job) error { return failure },
+			MaxAttempts:  1,
+			Runs:         1,
+			ExpectState:  models.JobFailed,
+			ExpectError:  "apply failed",
+			ExpectRunAt:  now,
+			ExpectCalled: 1,
+		},
+		"retries after the backoff": {
+			Handler:      func(ctx context.Context, job *models.Job) error { return failure },
+			MaxAttempts:  3,
+			Runs:         1,
+			ExpectState:  models.JobQueued,
+			ExpectError:  "apply failed",
+			ExpectRunAt:  now.Add(30 * time.Second),
+			ExpectCalled: 1,
+		},
+		"waits for the backoff": {
+			Handler:      func(ctx context.Context, job *models.Job) error { return failure },
+			MaxAttempts:  3,
+			Runs:         2,
+			ExpectState:  models.JobQueued,
+			ExpectError:  "apply failed",
+			ExpectRunAt:  now.Add(30 * time.Second),
+			ExpectCalled: 1,
+		},
+		"fails permanent errors without retrying": {
+			Handler:      func(ctx context.Context, job *models.Job) error { return Permanent(failure) },
+			MaxAttempts:  3,
+			Runs:         1,
+			ExpectState:  models.JobFailed,
+			ExpectError:  "apply failed",
+			ExpectRunAt:  now,
+			ExpectCalled: 1,
+		},
+		"recovers panics": {
+			Handler:      func(ctx context.Context, job *models.Job) error { panic("boom") },
+			MaxAttempts:  1,
+			Runs:         1,
+			ExpectState:  models.JobFailed,
+			ExpectError:  "job panicked: boom",
+			ExpectRunAt:  now,
+			ExpectCalled: 0,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			db := &fakeDatabase{}
+			q := newTestQueue(db, now)
+			q.MaxAttempts = tc.MaxAttempts
+
+			called := 0
+			q.Register("test", func(ctx context.Context, job *models.Job) error {
+				err := tc.Handler(ctx, job)
+				called++
+				return err
+			})
+
+			job, err := q.Enqueue(context.Background(), "test", "target-1", map[string]string{"command": "apply"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Payload != `{"command":"apply"}` {
+				t.Errorf("Expected the payload to be stored as JSON, got %q", job.Payload)
+			}
+
+			for i := 0; i < tc.Runs; i++ {
+				if _, err := q.RunOnce(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			saved := db.job(job.ID)
+			if saved.State != tc.ExpectState {
+				t.Errorf("Expected state %q, got %q", tc.ExpectState, saved.State)
+			}
+			if saved.LastError != tc.ExpectError {
+				t.Errorf("Expected error %q, got %q", tc.ExpectError, saved.LastError)
+			}
+			if !saved.RunAfter.Equal(tc.ExpectRunAt) {
+				t.Errorf("Expected to run after %v, got %v", tc.ExpectRunAt, saved.RunAfter)
+			}
+			if called != tc.ExpectCalled {
+				t.Errorf("Expected the handler to return %d times, got %d", tc.ExpectCalled, called)
+			}
+		})
+	}
+}
+
+func TestQueue_EnqueueWithAttempts(t *testing.T) {
+	db := &fakeDatabase{}
+	q := newTestQueue(db, time.Now())
+	q.MaxAttempts = 1
+	q.Register("test", func(ctx context.Context, job *models.Job) error { return errors.New("destroy failed") })
+
+	job, err := q.EnqueueWithAttempts(context.Background(), "test", "target-1", nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := db.job(job.ID)
+	if saved.MaxAttempts != 3 || saved.State != models.JobQueued {
+		t.Errorf("Expected the job to be retried up to 3 times, got state %q with max attempts %d", saved.State, saved.MaxAttempts)
+	}
+}
+
+func TestQueue_RunOnce_noHandler(t *testing.T) {
+	db := &fakeDatabase{}
+	q := newTestQueue(db, time.Now())
+	q.Register("test", func(ctx context.Context, job *models.Job) error { return nil })
+
+	job, err := q.Enqueue(context.Background(), "other", "target-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ran, err := q.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ran {
+		t.Error("Expected jobs of kinds without a handler not to be claimed")
+	}
+	if state := db.job(job.ID).State; state != models.JobQueued {
+		t.Errorf("Expected the job to stay queued, got %q", state)
+	}
+}
+
+func TestQueue_Drain(t *testing.T) {
+	db := &fakeDatabase{}
+	q := newTestQueue(db, time.Now())
+	q.PollInterval = time.Millisecond
+
+	started := make(chan struct{})
+	q.Register("test", func(ctx context.Context, job *models.Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	job, err := q.Enqueue(context.Background(), "test", "target-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q.Start(2)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	interrupted, err := q.Drain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interrupted != 1 {
+		t.Errorf("Expected 1 interrupted job, got %d", interrupted)
+	}
+
+	saved := db.job(job.ID)
+	if saved.State != models.JobQueued || saved.Worker != "" || saved.Attempts != 0 {
+		t.Errorf("Expected the job to be returned to the queue, got %+v", saved)
+	}
+}
+
+func TestQueue_RunOnce_lostLease(t *testing.T) {
+	db := &fakeDatabase{renewErr: db_service.ErrJobLost}
+	q := newTestQueue(db, time.Now())
+	q.Lease = 3 * time.Millisecond
+
+	q.Register("test", func(ctx context.Context, job *models.Job) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	job, err := q.Enqueue(context.Background(), "test", "target-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ran, err := q.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ran {
+		t.Fatal("Expected the job to run")
+	}
+
+	// the job belongs to whoever took it over so it's left as it was claimed
+	if state := db.job(job.ID).State; state != models.JobRunning {
+		t.Errorf("Expected the job to be left running, got %q", state)
+	}
+}
+
+func TestQueue_Drain_idle(t *testing.T) {
+	q := newTestQueue(&fakeDatabase{}, time.Now())
+	q.PollInterval = time.Millisecond
+	q.Start(2)
+
+	interrupted, err := q.Drain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interrupted != 0 {
+		t.Errorf("Expected no interrupted jobs, got %d", interrupted)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/spf13/viper"
+)
+
+// PurgeStore holds the finished jobs. db_service.SqlDatastore implements it.
+type PurgeStore interface {
+	DeleteFinishedJobsBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// Purger deletes the jobs that succeeded or failed longer ago than the
+// retention.
+type Purger struct {
+	Store     PurgeStore
+	Retention time.Duration
+	Logger    lager.Logger
+
+	// LeaderContext reports whether this instance should purge, with a
+	// context that's cancelled if it stops leading. All instances purge if it's
+	// nil.
+	LeaderContext func(ctx context.Context) (context.Context, context.CancelFunc, bool)
+
+	// now is used to get the current time, it can be replaced in tests.
+	now func() time.Time
+}
+
+// NewPurgerFromEnv creates a Purger deleting jobs from store, or returns nil
+// if finished jobs are kept.
+func NewPurgerFromEnv(store PurgeStore, logger lager.Logger) (*Purger, error) {
+	retention, err := time.ParseDuration(viper.GetString(RetentionProp))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", RetentionProp, err)
+	}
+	if retention < 0 {
+		return nil, fmt.Errorf("%s must not be negative, got %s", RetentionProp, retention)
+	}
+	if retention == 0 {
+		return nil, nil
+	}
+
+	return &Purger{
+		Store:     store,
+		Retention: retention,
+		Logger:    logger.Session("jobs"),
+	}, nil
+}
+
+func (p *Purger) currentTime() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+
+	return time.Now()
+}
+
+// Run deletes the jobs past the retention and returns how many there were.
+func (p *Purger) Run(ctx context.Context) (int64, error) {
+	deleted, err := p.Store.DeleteFinishedJobsBefore(ctx, p.currentTime().Add(-p.Retention))
+	if err != nil {
+		return 0, err
+	}
+
+	if deleted > 0 {
+		p.Logger.Info("purged-jobs", lager.Data{"deleted": deleted})
+	}
+
+	return deleted, nil
+}
+
+// RunEvery runs the purger every interval until the context is done. Runs are
+// skipped while another instance leads if LeaderContext is set.
+func (p *Purger) RunEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runCtx, cancel, leads := ctx, context.CancelFunc(func() {}), true
+			if p.LeaderContext != nil {
+				runCtx, cancel, leads = p.LeaderContext(ctx)
+			}
+			if !leads {
+				cancel()
+				p.Logger.Debug("skipping-run", lager.Data{"reason": "another instance leads purging"})
+				continue
+			}
+
+			if _, err := p.Run(runCtx); err != nil {
+				p.Logger.Error("purging-jobs", err)
+			}
+			cancel()
+		}
+	}
+}
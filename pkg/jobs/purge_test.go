@@ -0,0 +1,92 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/spf13/viper"
+)
+
+type fakePurgeStore struct {
+	Cutoffs []time.Time
+}
+
+func (f *fakePurgeStore) DeleteFinishedJobsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	f.Cutoffs = append(f.Cutoffs, cutoff)
+	return 3, nil
+}
+
+func TestPurger_Run(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	store := &fakePurgeStore{}
+	purger := &Purger{
+		Store:     store,
+		Retention: 48 * time.Hour,
+		Logger:    lager.NewLogger("test"),
+		now:       func() time.Time { return now },
+	}
+
+	deleted, err := purger.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 3 {
+		t.Errorf("Expected 3 deleted, got %d", deleted)
+	}
+
+	expected := []time.Time{time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)}
+	if !reflect.DeepEqual(store.Cutoffs, expected) {
+		t.Errorf("Expected cutoffs %v, got %v", expected, store.Cutoffs)
+	}
+}
+
+func TestNewPurgerFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Retention   string
+		ExpectNil   bool
+		ExpectError bool
+	}{
+		"default purges":  {Retention: ""},
+		"zero keeps jobs": {Retention: "0s", ExpectNil: true},
+		"negative":        {Retention: "-1h", ExpectNil: true, ExpectError: true},
+		"invalid":         {Retention: "forever", ExpectNil: true, ExpectError: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if tc.Retention != "" {
+				viper.Set(RetentionProp, tc.Retention)
+			}
+			defer viper.Set(RetentionProp, nil)
+
+			store := &fakePurgeStore{}
+			purger, err := NewPurgerFromEnv(store, lager.NewLogger("test"))
+			if (err != nil) != tc.ExpectError {
+				t.Errorf("Expected error %v, got %v", tc.ExpectError, err)
+			}
+			if (purger == nil) != tc.ExpectNil {
+				t.Errorf("Expected nil purger %v, got %v", tc.ExpectNil, purger)
+			}
+			if purger != nil && purger.Store != store {
+				t.Error("Expected the purger to use the store")
+			}
+		})
+	}
+}
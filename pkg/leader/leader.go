@@ -0,0 +1,333 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package leader elects one broker instance to run a kind of background
+// work, like archiving, when several replicas share a database. Instances
+// hold a lease on the work in the database that they renew while running;
+// if the leader stops its lease expires and another instance takes over, so
+// no infrastructure beyond the database is needed. Leases expire by the
+// database's clock so instances don't need to agree on the time.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// LeaseTTLProp is the viper key of how long other instances wait for a
+	// leader to renew its lease before taking over.
+	LeaseTTLProp = "leader.lease_ttl"
+
+	// RenewIntervalProp is the viper key of how often instances renew their
+	// lease, or try to take one over.
+	RenewIntervalProp = "leader.renew_interval"
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: LeaseTTLProp, Kind: config.Duration, Default: "30s"},
+		config.Property{Key: RenewIntervalProp, Kind: config.Duration, Default: "10s"},
+	)
+}
+
+// Database stores the leases.
+type Database interface {
+	AcquireLeaderLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLeaderLease(ctx context.Context, name, holder string) error
+}
+
+// databaseStore uses the broker's database.
+type databaseStore struct{}
+
+func (databaseStore) AcquireLeaderLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	return db_service.AcquireLeaderLease(ctx, name, holder, ttl)
+}
+
+func (databaseStore) ReleaseLeaderLease(ctx context.Context, name, holder string) error {
+	return db_service.ReleaseLeaderLease(ctx, name, holder)
+}
+
+// Elector campaigns for the lease on a kind of work.
+type Elector struct {
+	Database Database
+	// Name identifies the work, each name has its own leader so different
+	// kinds of work can be led by different instances.
+	Name string
+	// Holder identifies this broker instance.
+	Holder        string
+	TTL           time.Duration
+	RenewInterval time.Duration
+	Logger        lager.Logger
+
+	mu      sync.Mutex
+	expires time.Time
+	// term is closed when this instance stops leading.
+	term chan struct{}
+	stop context.CancelFunc
+	done chan struct{}
+
+	// now is used to get the current time, it can be replaced in tests.
+	now func() time.Time
+}
+
+// NewElector creates an elector for the named work with the default
+// settings.
+func NewElector(db Database, name string, logger lager.Logger) *Elector {
+	hostname, _ := os.Hostname()
+	return &Elector{
+		Database:      db,
+		Name:          name,
+		Holder:        fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		TTL:           30 * time.Second,
+		RenewInterval: 10 * time.Second,
+		Logger:        logger.Session("leader", lager.Data{"name": name}),
+	}
+}
+
+// NewElectorFromEnv creates an elector for the named work stored in the
+// broker's database with the settings from viper.
+func NewElectorFromEnv(name string, logger lager.Logger) (*Elector, error) {
+	e := NewElector(databaseStore{}, name, logger)
+
+	var err error
+	if e.TTL, err = parsePositiveDuration(LeaseTTLProp); err != nil {
+		return nil, err
+	}
+	if e.RenewInterval, err = parsePositiveDuration(RenewIntervalProp); err != nil {
+		return nil, err
+	}
+	if e.RenewInterval >= e.TTL {
+		return nil, fmt.Errorf("%s must be shorter than %s so leaders renew their lease before it expires", RenewIntervalProp, LeaseTTLProp)
+	}
+
+	return e, nil
+}
+
+func parsePositiveDuration(prop string) (time.Duration, error) {
+	d, err := time.ParseDuration(viper.GetString(prop))
+	if err != nil {
+		return 0, fmt.Errorf("couldn't parse %s: %v", prop, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got %s", prop, d)
+	}
+
+	return d, nil
+}
+
+func (e *Elector) currentTime() time.Time {
+	if e.now != nil {
+		return e.now()
+	}
+
+	return time.Now()
+}
+
+// IsLeader returns whether this instance holds an unexpired lease. It turns
+// false once the lease expires even if renewing it failed, so work doesn't
+// run on two instances when the database can't be reached.
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leads()
+}
+
+// leads is IsLeader for callers holding the lock.
+func (e *Elector) leads() bool {
+	return e.currentTime().Before(e.expires)
+}
+
+// LeaderContext returns a context for work only the leader does, and whether
+// this instance leads. The context is cancelled when this instance stops
+// leading, either because another instance took the lease over, it resigned or
+// the lease expired without being renewed, so the work stops before another
+// instance starts it. The cancel function must be called once the work is
+// done.
+func (e *Elector) LeaderContext(parent context.Context) (context.Context, context.CancelFunc, bool) {
+	ctx, cancel := context.WithCancel(parent)
+
+	e.mu.Lock()
+	leads, term := e.leads(), e.term
+	e.mu.Unlock()
+	if !leads {
+		cancel()
+		return ctx, cancel, false
+	}
+
+	go func() {
+		defer cancel()
+		for {
+			e.mu.Lock()
+			expired, wait := !e.leads(), e.expires.Sub(e.currentTime())
+			e.mu.Unlock()
+			if expired {
+				return
+			}
+
+			// the lease is checked again once it would expire in case it
+			// wasn't renewed
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-term:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+
+	return ctx, cancel, true
+}
+
+// setExpires records when the lease expires, the zero time if this instance
+// doesn't lead, ending the term if it stopped leading. Callers must hold the
+// lock.
+func (e *Elector) setExpires(expires time.Time) {
+	// a lease that expired before it was renewed ends the term too, another
+	// instance could have led in between
+	if e.term != nil && (!e.leads() || !e.currentTime().Before(expires)) {
+		close(e.term)
+		e.term = nil
+	}
+
+	e.expires = expires
+	if e.term == nil && e.leads() {
+		e.term = make(chan struct{})
+	}
+}
+
+// Campaign tries once to acquire or renew the lease and returns whether this
+// instance leads.
+func (e *Elector) Campaign(ctx context.Context) (bool, error) {
+	wasLeader := e.IsLeader()
+
+	// the database expires the lease a TTL after it renews it, so measuring
+	// from before the request means this instance stops leading first
+	started := e.currentTime()
+	leads, err := e.Database.AcquireLeaderLease(ctx, e.Name, e.Holder, e.TTL)
+	if err != nil {
+		return e.IsLeader(), err
+	}
+
+	e.mu.Lock()
+	if leads {
+		e.setExpires(started.Add(e.TTL))
+	} else {
+		e.setExpires(time.Time{})
+	}
+	e.mu.Unlock()
+
+	switch {
+	case leads && !wasLeader:
+		e.Logger.Info("elected", lager.Data{"holder": e.Holder})
+	case !leads && wasLeader:
+		e.Logger.Info("lost-lease", lager.Data{"holder": e.Holder})
+	}
+
+	return leads, nil
+}
+
+// started holds the electors campaigning in the background so they can all
+// resign when the broker stops.
+var started struct {
+	mu       sync.Mutex
+	electors []*Elector
+}
+
+// Start campaigns every renew interval in the background until Resign is
+// called.
+func (e *Elector) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	e.mu.Lock()
+	e.stop = cancel
+	e.done = done
+	e.mu.Unlock()
+
+	started.mu.Lock()
+	started.electors = append(started.electors, e)
+	started.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(e.RenewInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := e.Campaign(ctx); err != nil {
+				e.Logger.Error("campaigning", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Resign stops campaigning and releases the lease if this instance holds it
+// so another instance takes over without waiting for it to expire.
+func (e *Elector) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	stop, done := e.stop, e.done
+	e.mu.Unlock()
+
+	if stop != nil {
+		stop()
+		<-done
+	}
+
+	if !e.IsLeader() {
+		return nil
+	}
+
+	e.mu.Lock()
+	e.setExpires(time.Time{})
+	e.mu.Unlock()
+
+	return e.Database.ReleaseLeaderLease(ctx, e.Name, e.Holder)
+}
+
+// ResignAll resigns every started elector. It returns the first error but
+// tries them all.
+func ResignAll(ctx context.Context) error {
+	started.mu.Lock()
+	electors := started.electors
+	started.electors = nil
+	started.mu.Unlock()
+
+	var firstErr error
+	for _, e := range electors {
+		if err := e.Resign(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
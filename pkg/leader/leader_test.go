@@ -0,0 +1,217 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/spf13/viper"
+)
+
+// fakeDatabase holds one lease per name in memory, expiring them by its own
+// clock.
+type fakeDatabase struct {
+	mu      sync.Mutex
+	now     *time.Time
+	holders map[string]string
+	expires map[string]time.Time
+	err     error
+}
+
+func newFakeDatabase(now *time.Time) *fakeDatabase {
+	return &fakeDatabase{now: now, holders: make(map[string]string), expires: make(map[string]time.Time)}
+}
+
+func (db *fakeDatabase) AcquireLeaderLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.err != nil {
+		return false, db.err
+	}
+
+	now := *db.now
+	if current, ok := db.holders[name]; ok && current != holder && now.Before(db.expires[name]) {
+		return false, nil
+	}
+
+	db.holders[name] = holder
+	db.expires[name] = now.Add(ttl)
+	return true, nil
+}
+
+func (db *fakeDatabase) ReleaseLeaderLease(ctx context.Context, name, holder string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.holders[name] == holder {
+		db.expires[name] = *db.now
+	}
+	return nil
+}
+
+func newTestElector(db Database, holder string, now *time.Time) *Elector {
+	e := NewElector(db, "archive", lager.NewLogger("test"))
+	e.Holder = holder
+	e.now = func() time.Time { return *now }
+	return e
+}
+
+func TestElector_Campaign(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := newFakeDatabase(&now)
+	first := newTestElector(db, "broker-1", &now)
+	second := newTestElector(db, "broker-2", &now)
+
+	if leads, err := first.Campaign(ctx); err != nil || !leads {
+		t.Fatalf("Expected the first instance to be elected, got %v, %v", leads, err)
+	}
+	if leads, err := second.Campaign(ctx); err != nil || leads {
+		t.Fatalf("Expected the second instance to follow, got %v, %v", leads, err)
+	}
+	if !first.IsLeader() || second.IsLeader() {
+		t.Fatal("Expected only the first instance to lead")
+	}
+
+	// the leader can't reach the database so its lease runs out
+	db.err = errors.New("connection refused")
+	now = now.Add(20 * time.Second)
+	if _, err := first.Campaign(ctx); err == nil {
+		t.Fatal("Expected the database error")
+	}
+	if !first.IsLeader() {
+		t.Error("Expected the leader to lead until its lease expires")
+	}
+
+	now = now.Add(20 * time.Second)
+	if first.IsLeader() {
+		t.Error("Expected the leader to stop leading once its lease expired")
+	}
+
+	db.err = nil
+	if leads, _ := second.Campaign(ctx); !leads {
+		t.Fatal("Expected the second instance to take over the expired lease")
+	}
+	if leads, _ := first.Campaign(ctx); leads || first.IsLeader() {
+		t.Error("Expected the first instance to follow after losing the lease")
+	}
+}
+
+func TestElector_LeaderContext(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := newFakeDatabase(&now)
+	first := newTestElector(db, "broker-1", &now)
+	second := newTestElector(db, "broker-2", &now)
+
+	if _, cancel, leads := first.LeaderContext(ctx); leads {
+		cancel()
+		t.Fatal("Expected no leader context before being elected")
+	}
+
+	if leads, _ := first.Campaign(ctx); !leads {
+		t.Fatal("Expected the first instance to be elected")
+	}
+	workCtx, cancel, leads := first.LeaderContext(ctx)
+	defer cancel()
+	if !leads {
+		t.Fatal("Expected a leader context once elected")
+	}
+
+	// renewing the lease keeps the work going
+	now = now.Add(20 * time.Second)
+	if leads, _ := first.Campaign(ctx); !leads {
+		t.Fatal("Expected the first instance to renew its lease")
+	}
+	if workCtx.Err() != nil {
+		t.Fatal("Expected the work to continue while the lease is renewed")
+	}
+
+	// the lease expires and another instance takes over
+	now = now.Add(time.Minute)
+	if leads, _ := second.Campaign(ctx); !leads {
+		t.Fatal("Expected the second instance to take over the expired lease")
+	}
+	if leads, _ := first.Campaign(ctx); leads {
+		t.Fatal("Expected the first instance to follow")
+	}
+
+	select {
+	case <-workCtx.Done():
+	case <-time.After(time.Second):
+		t.Error("Expected the work to be cancelled once the lease was lost")
+	}
+}
+
+func TestElector_Resign(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := newFakeDatabase(&now)
+	first := newTestElector(db, "broker-1", &now)
+	second := newTestElector(db, "broker-2", &now)
+	first.RenewInterval = time.Millisecond
+
+	first.Start()
+	for !first.IsLeader() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := ResignAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if first.IsLeader() {
+		t.Error("Expected a resigned instance not to lead")
+	}
+
+	now = now.Add(time.Second)
+	if leads, _ := second.Campaign(ctx); !leads {
+		t.Error("Expected another instance to take over a released lease without waiting for it to expire")
+	}
+}
+
+func TestNewElectorFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		TTL         string
+		Renew       string
+		ExpectError bool
+	}{
+		"defaults":              {},
+		"custom":                {TTL: "1m", Renew: "20s"},
+		"invalid ttl":           {TTL: "soon", ExpectError: true},
+		"renew longer than ttl": {TTL: "10s", Renew: "10s", ExpectError: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if tc.TTL != "" {
+				viper.Set(LeaseTTLProp, tc.TTL)
+				defer viper.Set(LeaseTTLProp, nil)
+			}
+			if tc.Renew != "" {
+				viper.Set(RenewIntervalProp, tc.Renew)
+				defer viper.Set(RenewIntervalProp, nil)
+			}
+
+			_, err := NewElectorFromEnv("archive", lager.NewLogger("test"))
+			if (err != nil) != tc.ExpectError {
+				t.Errorf("Expected error %v, got %v", tc.ExpectError, err)
+			}
+		})
+	}
+}
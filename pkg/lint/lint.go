@@ -0,0 +1,162 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint inspects brokerpak service definitions for risky defaults,
+// like publicly readable buckets or primitive IAM roles, so they're caught
+// when a pak is built or loaded rather than after users depend on them.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pivotal/cloud-service-broker/pkg/providers/tf"
+	"github.com/spf13/viper"
+)
+
+// Severity is how a rule's findings are treated.
+type Severity string
+
+const (
+	// Error findings fail pak builds and broker startup.
+	Error Severity = "error"
+	// Warning findings are reported but don't fail anything.
+	Warning Severity = "warning"
+	// Off disables a rule.
+	Off Severity = "off"
+)
+
+// SeverityProp is the viper key that overrides the severity of a rule.
+func SeverityProp(rule string) string {
+	return "lint." + rule
+}
+
+// Rule is a check run against each service definition.
+type Rule struct {
+	Name        string
+	Description string
+	Severity    Severity
+	Check       func(svc *tf.TfServiceDefinitionV1) []Problem
+}
+
+// Problem is something a rule found wrong in a service definition.
+type Problem struct {
+	// Location is the path of the offending field, e.g.
+	// provision.templates[main] or bind.user_inputs[0].
+	Location string
+	Message  string
+}
+
+// Finding is a problem found in a service by a rule.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Service  string
+	Problem
+}
+
+// String formats the finding for humans.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: service %q %s: %s (%s)", f.Severity, f.Service, f.Location, f.Message, f.Rule)
+}
+
+// Report holds the findings of a lint run.
+type Report struct {
+	Findings []Finding
+}
+
+// Errors returns the findings with the error severity.
+func (r *Report) Errors() []Finding {
+	var errs []Finding
+	for _, f := range r.Findings {
+		if f.Severity == Error {
+			errs = append(errs, f)
+		}
+	}
+
+	return errs
+}
+
+// Err returns an error listing the error findings, or nil if there are none.
+func (r *Report) Err() error {
+	errs := r.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+
+	var lines []string
+	for _, f := range errs {
+		lines = append(lines, f.String())
+	}
+
+	return fmt.Errorf("%d service definition lint error(s):\n%s", len(errs), strings.Join(lines, "\n"))
+}
+
+// Linter runs rules against service definitions.
+type Linter struct {
+	Rules []Rule
+}
+
+// NewLinterFromEnv creates a linter with the default rules, using the
+// severities configured in viper.
+func NewLinterFromEnv() (*Linter, error) {
+	var rules []Rule
+	for _, rule := range DefaultRules {
+		if value := viper.GetString(SeverityProp(rule.Name)); value != "" {
+			severity := Severity(strings.ToLower(value))
+			if severity != Error && severity != Warning && severity != Off {
+				return nil, fmt.Errorf("%s must be one of error, warning or off, got %q", SeverityProp(rule.Name), value)
+			}
+			rule.Severity = severity
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return &Linter{Rules: rules}, nil
+}
+
+// Lint runs the rules against the services. Findings are sorted by service
+// then location.
+func (l *Linter) Lint(services []tf.TfServiceDefinitionV1) *Report {
+	report := &Report{}
+	for i := range services {
+		svc := &services[i]
+		for _, rule := range l.Rules {
+			if rule.Severity == Off {
+				continue
+			}
+
+			for _, problem := range rule.Check(svc) {
+				report.Findings = append(report.Findings, Finding{
+					Rule:     rule.Name,
+					Severity: rule.Severity,
+					Service:  svc.Name,
+					Problem:  problem,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Location < b.Location
+	})
+
+	return report
+}
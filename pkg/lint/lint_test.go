@@ -0,0 +1,96 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/pkg/providers/tf"
+	"github.com/spf13/viper"
+)
+
+func TestLinter_Lint(t *testing.T) {
+	flagAll := func(svc *tf.TfServiceDefinitionV1) []Problem {
+		return []Problem{{"provision.template", "flagged"}}
+	}
+
+	linter := &Linter{Rules: []Rule{
+		{Name: "always-error", Severity: Error, Check: flagAll},
+		{Name: "always-warning", Severity: Warning, Check: flagAll},
+		{Name: "disabled", Severity: Off, Check: flagAll},
+	}}
+
+	report := linter.Lint([]tf.TfServiceDefinitionV1{{Name: "b"}, {Name: "a"}})
+	if len(report.Findings) != 4 {
+		t.Fatalf("Expected 4 findings, got %v", report.Findings)
+	}
+	if report.Findings[0].Service != "a" || report.Findings[3].Service != "b" {
+		t.Errorf("Expected findings sorted by service, got %v", report.Findings)
+	}
+	if len(report.Errors()) != 2 {
+		t.Errorf("Expected 2 errors, got %v", report.Errors())
+	}
+
+	err := report.Err()
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	expected := `error: service "a" provision.template: flagged (always-error)`
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected the error to contain %q, got %q", expected, err)
+	}
+
+	if err := (&Report{}).Err(); err != nil {
+		t.Errorf("Expected no error without findings, got %v", err)
+	}
+}
+
+func TestNewLinterFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Value       string
+		Expected    Severity
+		ExpectError bool
+	}{
+		"default":  {Value: "", Expected: Warning},
+		"override": {Value: "ERROR", Expected: Error},
+		"off":      {Value: "off", Expected: Off},
+		"invalid":  {Value: "fatal", ExpectError: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(SeverityProp("deletion-protection"), tc.Value)
+			defer viper.Set(SeverityProp("deletion-protection"), nil)
+
+			linter, err := NewLinterFromEnv()
+			if tc.ExpectError {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, rule := range linter.Rules {
+				if rule.Name == "deletion-protection" && rule.Severity != tc.Expected {
+					t.Errorf("Expected severity %q, got %q", tc.Expected, rule.Severity)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
+	"github.com/hashicorp/hcl2/hclparse"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/providers/tf"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DefaultRules are the rules run unless configured otherwise.
+var DefaultRules = []Rule{
+	{
+		Name:        "public-access",
+		Description: "Resources and inputs must not grant access to everyone by default.",
+		Severity:    Error,
+		Check:       checkPublicAccess,
+	},
+	{
+		Name:        "primitive-bind-role",
+		Description: "Bindings must not default to, or allow, the owner, editor or viewer roles.",
+		Severity:    Error,
+		Check:       checkPrimitiveBindRole,
+	},
+	{
+		Name:        "deletion-protection",
+		Description: "Provisioned databases and instances should be protected from accidental deletion.",
+		Severity:    Warning,
+		Check:       checkDeletionProtection,
+	},
+	{
+		Name:        "unbounded-size",
+		Description: "Numeric user inputs should have a maximum so users can't request unlimited capacity.",
+		Severity:    Warning,
+		Check:       checkUnboundedSize,
+	},
+}
+
+// publicValues grant access to anyone, in IAM members and canned ACLs.
+var publicValues = utils.NewStringSet(
+	"allUsers",
+	"allAuthenticatedUsers",
+	"publicRead",
+	"publicReadWrite",
+	"public-read",
+	"public-read-write",
+	"authenticatedRead",
+	"authenticated-read",
+)
+
+// primitiveRoles are the legacy project wide roles.
+var primitiveRoles = utils.NewStringSet(
+	"owner",
+	"editor",
+	"viewer",
+	"roles/owner",
+	"roles/editor",
+	"roles/viewer",
+)
+
+// deletionProtected maps resource types to the attribute that protects them
+// from being deleted.
+var deletionProtected = map[string]string{
+	"google_sql_database_instance": "deletion_protection",
+	"google_bigtable_instance":     "deletion_protection",
+	"google_compute_instance":      "deletion_protection",
+	"aws_db_instance":              "deletion_protection",
+	"aws_rds_cluster":              "deletion_protection",
+}
+
+// forceDestroyed are resource types whose force_destroy attribute deletes
+// their contents along with them.
+var forceDestroyed = utils.NewStringSet(
+	"google_storage_bucket",
+	"aws_s3_bucket",
+)
+
+func checkPublicAccess(svc *tf.TfServiceDefinitionV1) []Problem {
+	var problems []Problem
+	for _, action := range actions(svc) {
+		for _, tmpl := range templates(action.name, action.settings) {
+			for _, res := range resources(tmpl.source) {
+				walkAttributes(res.body, func(name string, expr hcl.Expression) {
+					for _, value := range literalStrings(expr) {
+						if publicValues.Contains(value) {
+							problems = append(problems, Problem{tmpl.location, fmt.Sprintf("resource %s.%s sets %s to %s", res.kind, res.name, name, value)})
+						}
+					}
+				})
+			}
+
+			for _, v := range variables(tmpl.source) {
+				if attr, ok := v.body.Attributes["default"]; ok {
+					for _, value := range literalStrings(attr.Expr) {
+						if publicValues.Contains(value) {
+							problems = append(problems, Problem{tmpl.location, fmt.Sprintf("variable %s defaults to %s", v.name, value)})
+						}
+					}
+				}
+			}
+		}
+
+		for _, input := range inputs(action.name, action.settings) {
+			if value, ok := input.variable.Default.(string); ok && publicValues.Contains(value) {
+				problems = append(problems, Problem{input.location, fmt.Sprintf("%s defaults to %s", input.variable.FieldName, value)})
+			}
+		}
+	}
+
+	return problems
+}
+
+func checkPrimitiveBindRole(svc *tf.TfServiceDefinitionV1) []Problem {
+	var problems []Problem
+	for _, tmpl := range templates("bind", svc.BindSettings) {
+		for _, res := range resources(tmpl.source) {
+			walkAttributes(res.body, func(name string, expr hcl.Expression) {
+				if name != "role" && name != "roles" {
+					return
+				}
+
+				for _, value := range literalStrings(expr) {
+					if primitiveRoles.Contains(value) {
+						problems = append(problems, Problem{tmpl.location, fmt.Sprintf("resource %s.%s grants %s", res.kind, res.name, value)})
+					}
+				}
+			})
+		}
+	}
+
+	for _, input := range inputs("bind", svc.BindSettings) {
+		if value, ok := input.variable.Default.(string); ok && primitiveRoles.Contains(value) {
+			problems = append(problems, Problem{input.location, fmt.Sprintf("%s defaults to %s", input.variable.FieldName, value)})
+		}
+
+		var allowed []string
+		for value := range input.variable.Enum {
+			if s, ok := value.(string); ok && primitiveRoles.Contains(s) {
+				allowed = append(allowed, s)
+			}
+		}
+		sort.Strings(allowed)
+		for _, value := range allowed {
+			problems = append(problems, Problem{input.location, fmt.Sprintf("%s allows %s", input.variable.FieldName, value)})
+		}
+	}
+
+	return problems
+}
+
+func checkDeletionProtection(svc *tf.TfServiceDefinitionV1) []Problem {
+	var problems []Problem
+	for _, tmpl := range templates("provision", svc.ProvisionSettings) {
+		for _, res := range resources(tmpl.source) {
+			if attrName, ok := deletionProtected[res.kind]; ok {
+				attr, set := res.body.Attributes[attrName]
+				if !set {
+					problems = append(problems, Problem{tmpl.location, fmt.Sprintf("resource %s.%s doesn't set %s", res.kind, res.name, attrName)})
+				} else if enabled, ok := literalBool(attr.Expr); ok && !enabled {
+					problems = append(problems, Problem{tmpl.location, fmt.Sprintf("resource %s.%s disables %s", res.kind, res.name, attrName)})
+				}
+			}
+
+			if forceDestroyed.Contains(res.kind) {
+				if attr, set := res.body.Attributes["force_destroy"]; set {
+					if enabled, ok := literalBool(attr.Expr); ok && enabled {
+						problems = append(problems, Problem{tmpl.location, fmt.Sprintf("resource %s.%s sets force_destroy so its contents are deleted with it", res.kind, res.name)})
+					}
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+func checkUnboundedSize(svc *tf.TfServiceDefinitionV1) []Problem {
+	var problems []Problem
+	for _, action := range actions(svc) {
+		for i, input := range action.settings.UserInputs {
+			if input.Type != broker.JsonTypeInteger && input.Type != broker.JsonTypeNumeric {
+				continue
+			}
+			if len(input.Enum) > 0 {
+				continue
+			}
+			if _, ok := input.Constraints[validation.KeyMaximum]; ok {
+				continue
+			}
+			if _, ok := input.Constraints[validation.KeyExclusiveMaximum]; ok {
+				continue
+			}
+
+			location := fmt.Sprintf("%s.user_inputs[%d]", action.name, i)
+			problems = append(problems, Problem{location, fmt.Sprintf("%s has no maximum", input.FieldName)})
+		}
+	}
+
+	return problems
+}
+
+type namedAction struct {
+	name     string
+	settings tf.TfServiceDefinitionV1Action
+}
+
+func actions(svc *tf.TfServiceDefinitionV1) []namedAction {
+	return []namedAction{
+		{"provision", svc.ProvisionSettings},
+		{"bind", svc.BindSettings},
+	}
+}
+
+type template struct {
+	location string
+	source   string
+}
+
+// templates returns the action's Terraform templates. Paks have their
+// template refs inlined when they're built.
+func templates(actionName string, action tf.TfServiceDefinitionV1Action) []template {
+	var out []template
+	if action.Template != "" {
+		out = append(out, template{actionName + ".template", action.Template})
+	}
+
+	var names []string
+	for name := range action.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out = append(out, template{fmt.Sprintf("%s.templates[%s]", actionName, name), action.Templates[name]})
+	}
+
+	return out
+}
+
+type input struct {
+	location string
+	variable broker.BrokerVariable
+}
+
+func inputs(actionName string, action tf.TfServiceDefinitionV1Action) []input {
+	var out []input
+	for i, v := range action.PlanInputs {
+		out = append(out, input{fmt.Sprintf("%s.plan_inputs[%d]", actionName, i), v})
+	}
+	for i, v := range action.UserInputs {
+		out = append(out, input{fmt.Sprintf("%s.user_inputs[%d]", actionName, i), v})
+	}
+
+	return out
+}
+
+type block struct {
+	kind string
+	name string
+	body *hclsyntax.Body
+}
+
+// resources parses the resource blocks of a template. Templates that don't
+// parse are skipped, validation reports them.
+func resources(source string) []block {
+	var out []block
+	for _, b := range topLevelBlocks(source, "resource") {
+		if len(b.Labels) == 2 {
+			out = append(out, block{b.Labels[0], b.Labels[1], b.Body})
+		}
+	}
+
+	return out
+}
+
+func variables(source string) []block {
+	var out []block
+	for _, b := range topLevelBlocks(source, "variable") {
+		if len(b.Labels) == 1 {
+			out = append(out, block{"variable", b.Labels[0], b.Body})
+		}
+	}
+
+	return out
+}
+
+func topLevelBlocks(source, blockType string) []*hclsyntax.Block {
+	f, diags := hclparse.NewParser().ParseHCL([]byte(source), "")
+	if diags.HasErrors() {
+		return nil
+	}
+
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil
+	}
+
+	var out []*hclsyntax.Block
+	for _, b := range body.Blocks {
+		if b.Type == blockType {
+			out = append(out, b)
+		}
+	}
+
+	return out
+}
+
+// walkAttributes calls fn with the attributes of the body and its nested
+// blocks in a stable order.
+func walkAttributes(body *hclsyntax.Body, fn func(name string, expr hcl.Expression)) {
+	var names []string
+	for name := range body.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fn(name, body.Attributes[name].Expr)
+	}
+
+	for _, nested := range body.Blocks {
+		walkAttributes(nested.Body, fn)
+	}
+}
+
+// literalStrings returns the strings of a literal string or list of strings.
+// Expressions that reference variables return nothing.
+func literalStrings(expr hcl.Expression) []string {
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsWhollyKnown() || value.IsNull() {
+		return nil
+	}
+
+	if value.Type() == cty.String {
+		return []string{value.AsString()}
+	}
+
+	if !value.CanIterateElements() {
+		return nil
+	}
+
+	var out []string
+	for it := value.ElementIterator(); it.Next(); {
+		_, elem := it.Element()
+		if !elem.IsNull() && elem.Type() == cty.String {
+			out = append(out, elem.AsString())
+		}
+	}
+
+	return out
+}
+
+// literalBool returns the value of a literal bool, ok is false if the
+// expression isn't one.
+func literalBool(expr hcl.Expression) (value bool, ok bool) {
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || !v.IsWhollyKnown() || v.IsNull() || v.Type() != cty.Bool {
+		return false, false
+	}
+
+	return v.True(), true
+}
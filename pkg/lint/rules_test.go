@@ -0,0 +1,180 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/providers/tf"
+)
+
+func TestDefaultRules(t *testing.T) {
+	cases := map[string]struct {
+		Rule     func(svc *tf.TfServiceDefinitionV1) []Problem
+		Service  tf.TfServiceDefinitionV1
+		Expected []Problem
+	}{
+		"public member": {
+			Rule: checkPublicAccess,
+			Service: tf.TfServiceDefinitionV1{
+				ProvisionSettings: tf.TfServiceDefinitionV1Action{
+					Template: `
+resource "google_storage_bucket_iam_member" "member" {
+  bucket = "b"
+  role   = "roles/storage.objectViewer"
+  member = "allUsers"
+}`,
+				},
+			},
+			Expected: []Problem{{"provision.template", "resource google_storage_bucket_iam_member.member sets member to allUsers"}},
+		},
+		"public nested list": {
+			Rule: checkPublicAccess,
+			Service: tf.TfServiceDefinitionV1{
+				ProvisionSettings: tf.TfServiceDefinitionV1Action{
+					Templates: map[string]string{"main": `
+data "google_iam_policy" "p" {}
+resource "google_pubsub_topic_iam_binding" "b" {
+  topic = "t"
+  binding {
+    members = ["group:ops@example.com", "allAuthenticatedUsers"]
+  }
+}`},
+				},
+			},
+			Expected: []Problem{{"provision.templates[main]", "resource google_pubsub_topic_iam_binding.b sets members to allAuthenticatedUsers"}},
+		},
+		"public variable default": {
+			Rule: checkPublicAccess,
+			Service: tf.TfServiceDefinitionV1{
+				ProvisionSettings: tf.TfServiceDefinitionV1Action{
+					Template: `
+variable acl {
+  type    = string
+  default = "publicRead"
+}
+resource "google_storage_bucket_acl" "acl" { predefined_acl = var.acl }`,
+				},
+			},
+			Expected: []Problem{{"provision.template", "variable acl defaults to publicRead"}},
+		},
+		"public input default": {
+			Rule: checkPublicAccess,
+			Service: tf.TfServiceDefinitionV1{
+				ProvisionSettings: tf.TfServiceDefinitionV1Action{
+					UserInputs: []broker.BrokerVariable{{FieldName: "acl", Type: broker.JsonTypeString, Default: "public-read"}},
+				},
+			},
+			Expected: []Problem{{"provision.user_inputs[0]", "acl defaults to public-read"}},
+		},
+		"private": {
+			Rule: checkPublicAccess,
+			Service: tf.TfServiceDefinitionV1{
+				ProvisionSettings: tf.TfServiceDefinitionV1Action{
+					Template:   `resource "google_storage_bucket_acl" "acl" { predefined_acl = "private" }`,
+					UserInputs: []broker.BrokerVariable{{FieldName: "acl", Type: broker.JsonTypeString, Default: "private"}},
+				},
+			},
+		},
+		"invalid template": {
+			Rule: checkPublicAccess,
+			Service: tf.TfServiceDefinitionV1{
+				ProvisionSettings: tf.TfServiceDefinitionV1Action{Template: `resource "x" {`},
+			},
+		},
+		"primitive role default and enum": {
+			Rule: checkPrimitiveBindRole,
+			Service: tf.TfServiceDefinitionV1{
+				BindSettings: tf.TfServiceDefinitionV1Action{
+					UserInputs: []broker.BrokerVariable{{
+						FieldName: "role",
+						Type:      broker.JsonTypeString,
+						Default:   "roles/editor",
+						Enum:      map[interface{}]string{"roles/editor": "Editor", "owner": "Owner", "roles/spanner.databaseUser": "User"},
+					}},
+				},
+			},
+			Expected: []Problem{
+				{"bind.user_inputs[0]", "role defaults to roles/editor"},
+				{"bind.user_inputs[0]", "role allows owner"},
+				{"bind.user_inputs[0]", "role allows roles/editor"},
+			},
+		},
+		"primitive role in template": {
+			Rule: checkPrimitiveBindRole,
+			Service: tf.TfServiceDefinitionV1{
+				BindSettings: tf.TfServiceDefinitionV1Action{
+					Template: `resource "google_project_iam_member" "m" { role = "roles/owner" }`,
+				},
+			},
+			Expected: []Problem{{"bind.template", "resource google_project_iam_member.m grants roles/owner"}},
+		},
+		"primitive role in provision is ignored": {
+			Rule: checkPrimitiveBindRole,
+			Service: tf.TfServiceDefinitionV1{
+				ProvisionSettings: tf.TfServiceDefinitionV1Action{
+					Template: `resource "google_project_iam_member" "m" { role = "roles/owner" }`,
+				},
+			},
+		},
+		"deletion protection": {
+			Rule: checkDeletionProtection,
+			Service: tf.TfServiceDefinitionV1{
+				ProvisionSettings: tf.TfServiceDefinitionV1Action{
+					Template: `
+resource "google_sql_database_instance" "missing" {}
+resource "google_sql_database_instance" "disabled" { deletion_protection = false }
+resource "google_sql_database_instance" "enabled" { deletion_protection = true }
+resource "aws_db_instance" "variable" { deletion_protection = var.protect }
+resource "google_storage_bucket" "forced" { force_destroy = true }
+resource "google_storage_bucket" "kept" { force_destroy = false }`,
+				},
+			},
+			Expected: []Problem{
+				{"provision.template", "resource google_sql_database_instance.missing doesn't set deletion_protection"},
+				{"provision.template", "resource google_sql_database_instance.disabled disables deletion_protection"},
+				{"provision.template", "resource google_storage_bucket.forced sets force_destroy so its contents are deleted with it"},
+			},
+		},
+		"unbounded size": {
+			Rule: checkUnboundedSize,
+			Service: tf.TfServiceDefinitionV1{
+				ProvisionSettings: tf.TfServiceDefinitionV1Action{
+					UserInputs: []broker.BrokerVariable{
+						{FieldName: "name", Type: broker.JsonTypeString},
+						{FieldName: "disk_gb", Type: broker.JsonTypeInteger},
+						{FieldName: "nodes", Type: broker.JsonTypeInteger, Constraints: map[string]interface{}{"maximum": 10}},
+						{FieldName: "cpus", Type: broker.JsonTypeNumeric, Enum: map[interface{}]string{1: "one", 2: "two"}},
+					},
+				},
+				BindSettings: tf.TfServiceDefinitionV1Action{
+					UserInputs: []broker.BrokerVariable{{FieldName: "ttl", Type: broker.JsonTypeNumeric, Constraints: map[string]interface{}{"exclusiveMaximum": 60}}},
+				},
+			},
+			Expected: []Problem{{"provision.user_inputs[1]", "disk_gb has no maximum"}},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := tc.Rule(&tc.Service)
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
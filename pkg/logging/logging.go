@@ -0,0 +1,111 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logging correlates the log lines written while handling a request
+// so every line for an OSB request can be found by one ID.
+package logging
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pborman/uuid"
+)
+
+const (
+	// RequestIdentityHeader is the OSB header platforms use to identify a
+	// request. It's used as the correlation ID if present.
+	RequestIdentityHeader = "X-Broker-API-Request-Identity"
+
+	// CorrelationIdKey is the log data key holding the correlation ID.
+	CorrelationIdKey = "correlation_id"
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	correlationIdKey
+)
+
+// discard is used when there's no logger to write to.
+var discard = lager.NewLogger("discard")
+
+// NewContext returns a context holding the correlation ID and a logger that
+// adds it to every line.
+func NewContext(ctx context.Context, logger lager.Logger, correlationId string) context.Context {
+	ctx = context.WithValue(ctx, correlationIdKey, correlationId)
+	return context.WithValue(ctx, loggerKey, logger.WithData(lager.Data{CorrelationIdKey: correlationId}))
+}
+
+// FromContext returns the request's logger, or the fallback if the context
+// isn't from a request. Logs are discarded if the fallback is nil.
+func FromContext(ctx context.Context, fallback lager.Logger) lager.Logger {
+	if logger, ok := ctx.Value(loggerKey).(lager.Logger); ok {
+		return logger
+	}
+
+	if fallback != nil {
+		return fallback
+	}
+
+	return discard
+}
+
+// CorrelationId returns the request's correlation ID or an empty string if
+// the context isn't from a request.
+func CorrelationId(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIdKey).(string)
+	return id
+}
+
+// NewHandler wraps an HTTP handler so each request's context holds a
+// correlation ID and a logger that includes it. The ID is taken from the
+// request identity header or generated, echoed back in the same header, and
+// logged along with the outcome of the request.
+func NewHandler(logger lager.Logger, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		correlationId := req.Header.Get(RequestIdentityHeader)
+		if correlationId == "" {
+			correlationId = uuid.New()
+		}
+
+		ctx := NewContext(req.Context(), logger, correlationId)
+		w.Header().Set(RequestIdentityHeader, correlationId)
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, req.WithContext(ctx))
+
+		FromContext(ctx, logger).Info("request", lager.Data{
+			"method":      req.Method,
+			"path":        req.URL.Path,
+			"status":      recorder.status,
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+	})
+}
+
+// statusRecorder remembers the status code written to the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
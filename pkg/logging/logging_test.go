@@ -0,0 +1,100 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+)
+
+func TestNewHandler(t *testing.T) {
+	cases := map[string]struct {
+		RequestIdentity string
+		ExpectGenerated bool
+	}{
+		"from header": {RequestIdentity: "e26cea84-6f7d-4a5a-9f7e-5e4b2f6d2c1a"},
+		"generated":   {ExpectGenerated: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			logger := lagertest.NewTestLogger("test")
+
+			var handlerId string
+			handler := NewHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				handlerId = CorrelationId(req.Context())
+				FromContext(req.Context(), nil).Info("handling")
+				w.WriteHeader(http.StatusTeapot)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+			if tc.RequestIdentity != "" {
+				req.Header.Set(RequestIdentityHeader, tc.RequestIdentity)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if handlerId == "" || (!tc.ExpectGenerated && handlerId != tc.RequestIdentity) {
+				t.Errorf("Expected correlation ID %q, got %q", tc.RequestIdentity, handlerId)
+			}
+
+			if actual := w.Header().Get(RequestIdentityHeader); actual != handlerId {
+				t.Errorf("Expected response header %q, got %q", handlerId, actual)
+			}
+
+			logs := logger.Logs()
+			if len(logs) != 2 {
+				t.Fatalf("Expected 2 log lines, got %d", len(logs))
+			}
+
+			for _, log := range logs {
+				if log.Data[CorrelationIdKey] != handlerId {
+					t.Errorf("Expected %q to have correlation ID %q, got %v", log.Message, handlerId, log.Data[CorrelationIdKey])
+				}
+			}
+
+			if status := logs[1].Data["status"]; status != float64(http.StatusTeapot) {
+				t.Errorf("Expected the request log to have status %d, got %v", http.StatusTeapot, status)
+			}
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	fallback := lagertest.NewTestLogger("fallback")
+
+	if FromContext(context.Background(), fallback) != lager.Logger(fallback) {
+		t.Error("Expected the fallback logger outside of a request")
+	}
+
+	if FromContext(context.Background(), nil) == nil {
+		t.Error("Expected a discarding logger without a fallback")
+	}
+
+	ctx := NewContext(context.Background(), fallback, "abc")
+	FromContext(ctx, nil).Info("correlated")
+	if logs := fallback.Logs(); len(logs) != 1 || logs[0].Data[CorrelationIdKey] != "abc" {
+		t.Errorf("Expected a log with the correlation ID, got %v", logs)
+	}
+
+	if id := CorrelationId(ctx); id != "abc" {
+		t.Errorf("Expected correlation ID abc, got %q", id)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package maintenance lets operators put the broker in maintenance mode, in
+// which it keeps serving the catalog and operation polling but rejects new
+// provisions and bindings, e.g. while its database is upgraded.
+package maintenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/osberror"
+	"github.com/pivotal/cloud-service-broker/pkg/ratelimit"
+	"github.com/spf13/viper"
+)
+
+const (
+	// EnabledProp is the viper key of whether the broker starts in
+	// maintenance mode.
+	EnabledProp = "maintenance.enabled"
+
+	// MessageProp is the viper key of the message added to rejected
+	// requests, e.g. when maintenance is expected to finish.
+	MessageProp = "maintenance.message"
+
+	// RetryAfterProp is the viper key of how long platforms are told to wait
+	// before retrying a rejected request.
+	RetryAfterProp = "maintenance.retry_after"
+)
+
+// RejectedEndpoints are the OSB API endpoints rejected in maintenance mode.
+var RejectedEndpoints = []string{"provision", "bind"}
+
+func init() {
+	config.Register(
+		config.Property{Key: EnabledProp, Kind: config.Boolean, Default: false},
+		config.Property{Key: MessageProp, Kind: config.String, Default: ""},
+		config.Property{Key: RetryAfterProp, Kind: config.Duration, Default: "5m"},
+	)
+}
+
+// Status describes the maintenance mode of a broker instance.
+type Status struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+	// RetryAfter is the duration platforms are told to wait, e.g. 5m0s.
+	RetryAfter string `json:"retry_after"`
+	// Since is when maintenance mode was last turned on.
+	Since *time.Time `json:"since,omitempty"`
+}
+
+// Change turns maintenance mode on or off. The message and retry after keep
+// their values if they're left out.
+type Change struct {
+	Enabled    *bool   `json:"enabled"`
+	Message    *string `json:"message,omitempty"`
+	RetryAfter string  `json:"retry_after,omitempty"`
+}
+
+// InvalidRequestError is returned for changes that can't be made.
+type InvalidRequestError struct {
+	Reason string
+}
+
+func (e *InvalidRequestError) Error() string {
+	return e.Reason
+}
+
+// Invalidf creates an InvalidRequestError with a formatted reason.
+func Invalidf(format string, a ...interface{}) error {
+	return &InvalidRequestError{Reason: fmt.Sprintf(format, a...)}
+}
+
+// Mode is the maintenance mode of a broker instance. It's kept in memory
+// because the database may be what's being maintained. It's safe for
+// concurrent use.
+type Mode struct {
+	Logger lager.Logger
+
+	mu         sync.Mutex
+	enabled    bool
+	message    string
+	retryAfter time.Duration
+	since      time.Time
+}
+
+// NewModeFromEnv creates a Mode from the maintenance settings in viper.
+func NewModeFromEnv(logger lager.Logger) (*Mode, error) {
+	retryAfter, err := parseRetryAfter(viper.GetString(RetryAfterProp))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", RetryAfterProp, err)
+	}
+
+	m := &Mode{
+		Logger:     logger.Session("maintenance"),
+		message:    viper.GetString(MessageProp),
+		retryAfter: retryAfter,
+	}
+
+	if viper.GetBool(EnabledProp) {
+		m.enabled = true
+		m.since = time.Now()
+		m.Logger.Info("enabled", lager.Data{"message": m.message})
+	}
+
+	return m, nil
+}
+
+// Status returns the current maintenance mode.
+func (m *Mode) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := Status{Enabled: m.enabled, Message: m.message, RetryAfter: m.retryAfter.String()}
+	if m.enabled {
+		since := m.since
+		status.Since = &since
+	}
+
+	return status
+}
+
+// Set applies the change and returns the new status.
+func (m *Mode) Set(change Change) (Status, error) {
+	if change.Enabled == nil {
+		return Status{}, Invalidf("enabled is required")
+	}
+
+	var retryAfter time.Duration
+	if change.RetryAfter != "" {
+		var err error
+		if retryAfter, err = parseRetryAfter(change.RetryAfter); err != nil {
+			return Status{}, Invalidf("invalid retry_after: %v", err)
+		}
+	}
+
+	m.mu.Lock()
+	if *change.Enabled && !m.enabled {
+		m.since = time.Now()
+	}
+	m.enabled = *change.Enabled
+	if change.Message != nil {
+		m.message = *change.Message
+	}
+	if retryAfter > 0 {
+		m.retryAfter = retryAfter
+	}
+	m.mu.Unlock()
+
+	status := m.Status()
+	if m.Logger != nil {
+		m.Logger.Info("changed", lager.Data{"enabled": status.Enabled, "message": status.Message, "retry_after": status.RetryAfter})
+	}
+
+	return status, nil
+}
+
+// Wrap rejects requests to the RejectedEndpoints with 503 Service Unavailable
+// and a Retry-After header while maintenance mode is on. It must be added to
+// the router the OSB API routes are attached to so the endpoint can be
+// identified.
+func (m *Mode) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		endpoint := ratelimit.Endpoint(req)
+		if !isRejected(endpoint) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		status := m.Status()
+		if !status.Enabled {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		m.reject(w, endpoint, status)
+	})
+}
+
+func (m *Mode) reject(w http.ResponseWriter, endpoint string, status Status) {
+	retryAfter, _ := time.ParseDuration(status.RetryAfter)
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	description := "the broker is in maintenance mode and isn't accepting new provisions or bindings"
+	if status.Message != "" {
+		description += ": " + status.Message
+	}
+
+	if m.Logger != nil {
+		m.Logger.Info("rejected", lager.Data{"endpoint": endpoint, "retry_after": seconds})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(map[string]string{"error": osberror.MaintenanceMode, "description": description})
+}
+
+func isRejected(endpoint string) bool {
+	for _, rejected := range RejectedEndpoints {
+		if endpoint == rejected {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseRetryAfter(value string) (time.Duration, error) {
+	retryAfter, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if retryAfter <= 0 {
+		return 0, fmt.Errorf("must be positive, got %s", value)
+	}
+
+	return retryAfter, nil
+}
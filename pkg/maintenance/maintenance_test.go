@@ -0,0 +1,143 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/osberror"
+	"github.com/spf13/viper"
+)
+
+func newTestRouter(m *Mode) *mux.Router {
+	handler := func(w http.ResponseWriter, req *http.Request) {}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/catalog", handler).Methods(http.MethodGet)
+	router.HandleFunc("/v2/service_instances/{instance_id}", handler).Methods(http.MethodPut, http.MethodDelete)
+	router.HandleFunc("/v2/service_instances/{instance_id}/last_operation", handler).Methods(http.MethodGet)
+	router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", handler).Methods(http.MethodPut)
+	router.Use(m.Wrap)
+	return router
+}
+
+func TestMode_Wrap(t *testing.T) {
+	cases := map[string]struct {
+		Enabled        bool
+		Method         string
+		Path           string
+		ExpectedStatus int
+	}{
+		"provision":                  {Method: http.MethodPut, Path: "/v2/service_instances/instance-1", ExpectedStatus: http.StatusOK},
+		"provision in maintenance":   {Enabled: true, Method: http.MethodPut, Path: "/v2/service_instances/instance-1", ExpectedStatus: http.StatusServiceUnavailable},
+		"bind in maintenance":        {Enabled: true, Method: http.MethodPut, Path: "/v2/service_instances/instance-1/service_bindings/binding-1", ExpectedStatus: http.StatusServiceUnavailable},
+		"catalog in maintenance":     {Enabled: true, Method: http.MethodGet, Path: "/v2/catalog", ExpectedStatus: http.StatusOK},
+		"polling in maintenance":     {Enabled: true, Method: http.MethodGet, Path: "/v2/service_instances/instance-1/last_operation", ExpectedStatus: http.StatusOK},
+		"deprovision in maintenance": {Enabled: true, Method: http.MethodDelete, Path: "/v2/service_instances/instance-1", ExpectedStatus: http.StatusOK},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			mode := &Mode{}
+			enabled := tc.Enabled
+			message := "database upgrade"
+			if _, err := mode.Set(Change{Enabled: &enabled, Message: &message, RetryAfter: "90s"}); err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			newTestRouter(mode).ServeHTTP(w, httptest.NewRequest(tc.Method, tc.Path, nil))
+
+			if w.Code != tc.ExpectedStatus {
+				t.Fatalf("Expected status %d, got %d", tc.ExpectedStatus, w.Code)
+			}
+			if w.Code != http.StatusServiceUnavailable {
+				return
+			}
+
+			if retryAfter := w.Header().Get("Retry-After"); retryAfter != "90" {
+				t.Errorf("Expected Retry-After 90, got %q", retryAfter)
+			}
+
+			body := map[string]string{}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body["error"] != osberror.MaintenanceMode {
+				t.Errorf("Expected error %q, got %q", osberror.MaintenanceMode, body["error"])
+			}
+			expected := "the broker is in maintenance mode and isn't accepting new provisions or bindings: database upgrade"
+			if body["description"] != expected {
+				t.Errorf("Expected description %q, got %q", expected, body["description"])
+			}
+		})
+	}
+}
+
+func TestMode_Set(t *testing.T) {
+	yes, no := true, false
+	mode := &Mode{}
+
+	status, err := mode.Set(Change{Enabled: &yes, RetryAfter: "1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Enabled || status.Since == nil || status.RetryAfter != "1m0s" {
+		t.Errorf("Expected maintenance mode on since now, got %+v", status)
+	}
+	since := *status.Since
+
+	status, _ = mode.Set(Change{Enabled: &yes})
+	if !status.Since.Equal(since) || status.RetryAfter != "1m0s" {
+		t.Errorf("Expected since and retry after to be kept, got %+v", status)
+	}
+
+	status, _ = mode.Set(Change{Enabled: &no})
+	if status.Enabled || status.Since != nil {
+		t.Errorf("Expected maintenance mode off, got %+v", status)
+	}
+
+	if _, err := mode.Set(Change{}); err == nil {
+		t.Error("Expected an error when enabled isn't set")
+	}
+}
+
+func TestNewModeFromEnv(t *testing.T) {
+	viper.Set(EnabledProp, true)
+	viper.Set(RetryAfterProp, "2m")
+	defer func() {
+		viper.Set(EnabledProp, nil)
+		viper.Set(RetryAfterProp, nil)
+	}()
+
+	mode, err := NewModeFromEnv(lager.NewLogger("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status := mode.Status(); !status.Enabled || status.RetryAfter != "2m0s" {
+		t.Errorf("Expected maintenance mode on with a 2m retry after, got %+v", status)
+	}
+
+	viper.Set(RetryAfterProp, "0s")
+	if _, err := NewModeFromEnv(lager.NewLogger("test")); err == nil {
+		t.Error("Expected an error for a zero retry after")
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package naming generates the names of the cloud resources backing service
+// instances from Go templates operators can override, and checks them against
+// the naming rules of the Google Cloud product they're used for.
+package naming
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pivotal/cloud-service-broker/pkg/platformcontext"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+// shortLength is the number of characters kept of the *_short fields.
+const shortLength = 8
+
+// Product holds the naming rules of a kind of Google Cloud resource.
+type Product struct {
+	Name      string
+	MinLength int
+	MaxLength int
+	Pattern   *regexp.Regexp
+
+	// Description explains Pattern in error messages.
+	Description string
+}
+
+// Validate returns an error if the name breaks the product's rules.
+func (p Product) Validate(name string) error {
+	switch {
+	case len(name) < p.MinLength || len(name) > p.MaxLength:
+		return fmt.Errorf("%s names must be %d to %d characters long, %q is %d", p.Name, p.MinLength, p.MaxLength, name, len(name))
+	case !p.Pattern.MatchString(name):
+		return fmt.Errorf("%s names must %s, got %q", p.Name, p.Description, name)
+	default:
+		return nil
+	}
+}
+
+// Products holds the naming rules of the resources services can name, keyed
+// by the names used in service definitions.
+var Products = map[string]Product{
+	"cloudsql": {
+		Name:        "CloudSQL instance",
+		MinLength:   1,
+		MaxLength:   98,
+		Pattern:     regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`),
+		Description: "start with a letter, end with a letter or number, and contain only lowercase letters, numbers and hyphens",
+	},
+	"spanner": {
+		Name:        "Spanner instance",
+		MinLength:   2,
+		MaxLength:   64,
+		Pattern:     regexp.MustCompile(`^[a-z][-a-z0-9]*[a-z0-9]$`),
+		Description: "start with a letter, end with a letter or number, and contain only lowercase letters, numbers and hyphens",
+	},
+	"bigquery": {
+		Name:        "BigQuery dataset",
+		MinLength:   1,
+		MaxLength:   1024,
+		Pattern:     regexp.MustCompile(`^[a-zA-Z0-9_-]+$`),
+		Description: "contain only letters, numbers, underscores and hyphens",
+	},
+	"storage": {
+		Name:        "Cloud Storage bucket",
+		MinLength:   3,
+		MaxLength:   63,
+		Pattern:     regexp.MustCompile(`^[a-z0-9][-_.a-z0-9]*[a-z0-9]$`),
+		Description: "start and end with a letter or number, and contain only lowercase letters, numbers, hyphens, underscores and dots",
+	},
+	"kms": {
+		Name:        "KMS key ring",
+		MinLength:   1,
+		MaxLength:   63,
+		Pattern:     regexp.MustCompile(`^[a-zA-Z0-9_-]+$`),
+		Description: "contain only letters, numbers, underscores and hyphens",
+	},
+	"secret-manager": {
+		Name:        "Secret Manager secret",
+		MinLength:   1,
+		MaxLength:   255,
+		Pattern:     regexp.MustCompile(`^[a-zA-Z0-9_-]+$`),
+		Description: "contain only letters, numbers, underscores and hyphens",
+	},
+	"cloud-tasks": {
+		Name:        "Cloud Tasks queue",
+		MinLength:   1,
+		MaxLength:   100,
+		Pattern:     regexp.MustCompile(`^[a-zA-Z0-9-]+$`),
+		Description: "contain only letters, numbers and hyphens",
+	},
+	"cloud-scheduler": {
+		Name:        "Cloud Scheduler job",
+		MinLength:   1,
+		MaxLength:   500,
+		Pattern:     regexp.MustCompile(`^[a-zA-Z0-9_-]+$`),
+		Description: "contain only letters, numbers, underscores and hyphens",
+	},
+}
+
+// ProductNames lists the keys of Products in order.
+func ProductNames() []string {
+	var names []string
+	for name := range Products {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Fields holds the values templates can reference, e.g. {{.instance_id}}.
+type Fields map[string]string
+
+// NewFields creates the fields of a service instance. Each GUID also gets a
+// *_short field holding its first 8 characters. The fields from the
+// platform's context are empty until WithContext adds them.
+func NewFields(instanceId, organizationGuid, spaceGuid, serviceName, planName string) Fields {
+	return Fields{
+		"instance_id":             instanceId,
+		"instance_id_short":       short(instanceId),
+		"organization_guid":       organizationGuid,
+		"organization_guid_short": short(organizationGuid),
+		"space_guid":              spaceGuid,
+		"space_guid_short":        short(spaceGuid),
+		"service_name":            serviceName,
+		"plan_name":               planName,
+	}.WithContext(platformcontext.Context{})
+}
+
+// WithContext sets the fields from the context object the platform sent with
+// the request and returns the fields. The organization and space GUIDs are
+// only replaced if the context has them.
+func (f Fields) WithContext(ctx platformcontext.Context) Fields {
+	f["platform"] = ctx.Platform
+	f["instance_name"] = ctx.InstanceName
+	f["organization_name"] = ctx.OrganizationName
+	f["space_name"] = ctx.SpaceName
+	f["namespace"] = ctx.Namespace
+	f["cluster_id"] = ctx.ClusterId
+
+	if ctx.OrganizationGuid != "" {
+		f["organization_guid"] = ctx.OrganizationGuid
+		f["organization_guid_short"] = short(ctx.OrganizationGuid)
+	}
+	if ctx.SpaceGuid != "" {
+		f["space_guid"] = ctx.SpaceGuid
+		f["space_guid_short"] = short(ctx.SpaceGuid)
+	}
+
+	return f
+}
+
+// sampleFields are used to check templates when they're loaded.
+var sampleFields = NewFields(
+	"a4eb5e6c-4d8a-4c4b-9b1d-3f0a6d9e2c17",
+	"0f8e2b5a-95a6-4d1c-8a7e-6b3c9d2e1f40",
+	"7c1d9e3f-2a4b-4e6c-8d0f-1a2b3c4d5e6f",
+	"csb-google-service",
+	"default",
+).WithContext(platformcontext.Context{
+	Platform:         platformcontext.CloudFoundry,
+	InstanceName:     "my-instance",
+	OrganizationName: "my-org",
+	SpaceName:        "my-space",
+	Namespace:        "my-namespace",
+	ClusterId:        "my-cluster",
+})
+
+func short(guid string) string {
+	if len(guid) <= shortLength {
+		return guid
+	}
+
+	return guid[:shortLength]
+}
+
+var funcs = template.FuncMap{
+	"lower":   strings.ToLower,
+	"replace": func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+	"truncate": func(n int, s string) string {
+		if len(s) <= n {
+			return s
+		}
+		return s[:n]
+	},
+}
+
+// Generate renders the template with the fields and checks the result
+// against the product's naming rules.
+func Generate(tmpl, product string, fields Fields) (string, error) {
+	rules, ok := Products[product]
+	if !ok {
+		return "", fmt.Errorf("unknown product %q, must be one of %v", product, ProductNames())
+	}
+
+	parsed, err := template.New("name").Funcs(funcs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("couldn't parse naming template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := parsed.Execute(&buf, map[string]string(fields)); err != nil {
+		return "", fmt.Errorf("couldn't render naming template: %v", err)
+	}
+
+	name := buf.String()
+	if err := rules.Validate(name); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// Rule names a resource of a service.
+type Rule struct {
+	// Variable is the provision input the name is assigned to.
+	Variable string `yaml:"variable"`
+
+	// Product is the key in Products of the kind of resource named.
+	Product string `yaml:"product"`
+
+	// Template is the default naming template, operators can override it.
+	Template string `yaml:"template"`
+}
+
+var _ validation.Validatable = (*Rule)(nil)
+
+// Validate implements validation.Validatable.
+func (rule *Rule) Validate() (errs *validation.FieldError) {
+	errs = errs.Also(
+		validation.ErrIfBlank(rule.Variable, "variable"),
+		validation.ErrIfBlank(rule.Template, "template"),
+	)
+
+	if _, ok := Products[rule.Product]; !ok {
+		return errs.Also(validation.ErrInvalidValue(rule.Product, "product"))
+	}
+
+	if rule.Template == "" {
+		return errs
+	}
+
+	if err := CheckTemplate(rule.Template, rule.Product); err != nil {
+		errs = errs.Also(&validation.FieldError{Message: err.Error(), Paths: []string{"template"}})
+	}
+
+	return errs
+}
+
+// CheckTemplate returns an error if the template can't be rendered or gives
+// a name the product doesn't allow for a sample instance.
+func CheckTemplate(tmpl, product string) error {
+	_, err := Generate(tmpl, product, sampleFields)
+	return err
+}
@@ -0,0 +1,163 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package naming
+
+import (
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/pkg/platformcontext"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+func TestGenerate(t *testing.T) {
+	fields := NewFields("a4eb5e6c-4d8a-4c4b-9b1d-3f0a6d9e2c17", "org-guid", "7c1d9e3f-2a4b-4e6c-8d0f-1a2b3c4d5e6f", "csb-google-mysql", "small")
+
+	cases := map[string]struct {
+		Template  string
+		Product   string
+		Expected  string
+		ExpectErr bool
+	}{
+		"default": {
+			Template: "csb-mysql-{{.instance_id}}",
+			Product:  "cloudsql",
+			Expected: "csb-mysql-a4eb5e6c-4d8a-4c4b-9b1d-3f0a6d9e2c17",
+		},
+		"short fields": {
+			Template: "pcf-{{.space_guid_short}}-{{.instance_id}}",
+			Product:  "cloudsql",
+			Expected: "pcf-7c1d9e3f-a4eb5e6c-4d8a-4c4b-9b1d-3f0a6d9e2c17",
+		},
+		"functions": {
+			Template: `{{.plan_name | lower}}_{{replace "-" "_" .instance_id_short}}`,
+			Product:  "kms",
+			Expected: "small_a4eb5e6c",
+		},
+		"truncated": {
+			Template: `{{truncate 10 .instance_id}}`,
+			Product:  "storage",
+			Expected: "a4eb5e6c-4",
+		},
+		"too long": {
+			Template:  "csb-spanner-{{.instance_id}}-{{.space_guid}}",
+			Product:   "spanner",
+			ExpectErr: true,
+		},
+		"bad characters": {
+			Template:  "CSB_{{.instance_id}}",
+			Product:   "cloudsql",
+			ExpectErr: true,
+		},
+		"unknown field": {
+			Template:  "csb-{{.instance_guid}}",
+			Product:   "cloudsql",
+			ExpectErr: true,
+		},
+		"unparseable": {
+			Template:  "csb-{{.instance_id",
+			Product:   "cloudsql",
+			ExpectErr: true,
+		},
+		"unknown product": {
+			Template:  "csb-{{.instance_id}}",
+			Product:   "pubsub",
+			ExpectErr: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := Generate(tc.Template, tc.Product, fields)
+			if (err != nil) != tc.ExpectErr {
+				t.Fatalf("Expected error? %t got %v", tc.ExpectErr, err)
+			}
+
+			if actual != tc.Expected {
+				t.Errorf("Expected name %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestFields_WithContext(t *testing.T) {
+	cases := map[string]struct {
+		Context  platformcontext.Context
+		Template string
+		Expected string
+	}{
+		"cloud foundry": {
+			Context:  platformcontext.Context{Platform: platformcontext.CloudFoundry, OrganizationName: "Eng", SpaceName: "dev"},
+			Template: `{{lower .organization_name}}-{{.space_name}}-{{.instance_id_short}}`,
+			Expected: "eng-dev-a4eb5e6c",
+		},
+		"kubernetes": {
+			Context:  platformcontext.Context{Platform: platformcontext.Kubernetes, Namespace: "payments", ClusterId: "cluster-1"},
+			Template: `{{.namespace}}-{{.instance_id_short}}`,
+			Expected: "payments-a4eb5e6c",
+		},
+		"context guids": {
+			Context:  platformcontext.Context{SpaceGuid: "0f8e2b5a-95a6-4d1c-8a7e-6b3c9d2e1f40"},
+			Template: `csb-{{.space_guid_short}}-{{.instance_id_short}}`,
+			Expected: "csb-0f8e2b5a-a4eb5e6c",
+		},
+		"no context": {
+			Template: `csb{{.namespace}}-{{.instance_id_short}}`,
+			Expected: "csb-a4eb5e6c",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			fields := NewFields("a4eb5e6c-4d8a-4c4b-9b1d-3f0a6d9e2c17", "org-guid", "7c1d9e3f-2a4b-4e6c-8d0f-1a2b3c4d5e6f", "csb-google-mysql", "small").WithContext(tc.Context)
+
+			actual, err := Generate(tc.Template, "cloudsql", fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != tc.Expected {
+				t.Errorf("Expected %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	cases := map[string]validation.ValidatableTest{
+		"good": {
+			Object: &Rule{Variable: "instance_name", Product: "cloudsql", Template: "csb-{{.instance_id}}"},
+		},
+		"missing variable": {
+			Object: &Rule{Product: "cloudsql", Template: "csb-{{.instance_id}}"},
+			Expect: validation.ErrMissingField("variable"),
+		},
+		"unknown product": {
+			Object: &Rule{Variable: "instance_name", Product: "pubsub", Template: "csb-{{.instance_id}}"},
+			Expect: validation.ErrInvalidValue("pubsub", "product"),
+		},
+		"bad template": {
+			Object: &Rule{Variable: "instance_name", Product: "cloudsql", Template: "CSB"},
+			Expect: &validation.FieldError{
+				Message: `CloudSQL instance names must start with a letter, end with a letter or number, and contain only lowercase letters, numbers and hyphens, got "CSB"`,
+				Paths:   []string{"template"},
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			tc.Assert(t)
+		})
+	}
+}
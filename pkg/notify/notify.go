@@ -0,0 +1,104 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notify tells the owners of service instances about actions an
+// operator took on their behalf.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/spf13/viper"
+)
+
+const (
+	// WebhookUrlProp holds the URL notifications are POSTed to as JSON.
+	WebhookUrlProp = "notifications.webhook_url"
+)
+
+// Notification describes an event that affected resources owned by an
+// organization and space.
+type Notification struct {
+	Event            string      `json:"event"`
+	OrganizationGuid string      `json:"organization_guid"`
+	SpaceGuid        string      `json:"space_guid"`
+	Message          string      `json:"message"`
+	Details          interface{} `json:"details,omitempty"`
+	Timestamp        time.Time   `json:"timestamp"`
+}
+
+// Notifier delivers notifications to resource owners.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// NewNotifierFromEnv creates a WebhookNotifier if a webhook URL is configured,
+// otherwise notifications are only logged.
+func NewNotifierFromEnv(logger lager.Logger) Notifier {
+	if url := viper.GetString(WebhookUrlProp); url != "" {
+		return &WebhookNotifier{Url: url, Client: &http.Client{Timeout: 30 * time.Second}}
+	}
+
+	return &LogNotifier{Logger: logger}
+}
+
+// LogNotifier writes notifications to the broker's log.
+type LogNotifier struct {
+	Logger lager.Logger
+}
+
+// Notify implements Notifier.
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.Logger.Info("notification", lager.Data{"notification": notification})
+	return nil
+}
+
+// WebhookNotifier POSTs notifications as JSON to a URL.
+type WebhookNotifier struct {
+	Url    string
+	Client *http.Client
+}
+
+// Notify implements Notifier.
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't send notification: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("couldn't send notification, webhook responded with: %s", resp.Status)
+	}
+
+	return nil
+}
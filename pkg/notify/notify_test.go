@@ -0,0 +1,75 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/spf13/viper"
+)
+
+func TestNewNotifierFromEnv(t *testing.T) {
+	logger := lager.NewLogger("test")
+
+	if _, ok := NewNotifierFromEnv(logger).(*LogNotifier); !ok {
+		t.Error("Expected a LogNotifier when no webhook is configured")
+	}
+
+	viper.Set(WebhookUrlProp, "https://example.com/hook")
+	defer viper.Set(WebhookUrlProp, nil)
+
+	if _, ok := NewNotifierFromEnv(logger).(*WebhookNotifier); !ok {
+		t.Error("Expected a WebhookNotifier when a webhook is configured")
+	}
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	cases := map[string]struct {
+		Status    int
+		ExpectErr bool
+	}{
+		"accepted": {Status: http.StatusAccepted, ExpectErr: false},
+		"rejected": {Status: http.StatusInternalServerError, ExpectErr: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			var received Notification
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Error(err)
+				}
+				w.WriteHeader(tc.Status)
+			}))
+			defer server.Close()
+
+			notifier := &WebhookNotifier{Url: server.URL, Client: server.Client()}
+			err := notifier.Notify(context.Background(), Notification{Event: "test-event", OrganizationGuid: "org-1"})
+
+			if hasErr := err != nil; hasErr != tc.ExpectErr {
+				t.Errorf("Expected error: %v, got %v", tc.ExpectErr, err)
+			}
+
+			if received.Event != "test-event" || received.OrganizationGuid != "org-1" {
+				t.Errorf("Expected the notification to be delivered, got %v", received)
+			}
+		})
+	}
+}
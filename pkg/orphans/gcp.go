@@ -0,0 +1,194 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orphans
+
+import (
+	"context"
+
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
+	"github.com/pivotal/cloud-service-broker/utils"
+	bigquery "google.golang.org/api/bigquery/v2"
+	pubsub "google.golang.org/api/pubsub/v1"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+	storage "google.golang.org/api/storage/v1"
+)
+
+// Names of the Google Cloud resource kinds.
+const (
+	BucketKind           = "storage.bucket"
+	CloudSqlInstanceKind = "cloudsql.instance"
+	TopicKind            = "pubsub.topic"
+	SubscriptionKind     = "pubsub.subscription"
+	DatasetKind          = "bigquery.dataset"
+)
+
+// GcpKinds returns the Google Cloud resource kinds in the project the broker
+// labels. The clients must be authorized to call the Cloud Storage,
+// CloudSQL Admin, Pub/Sub and BigQuery APIs.
+//
+// Buckets and datasets that still hold objects or tables aren't deleted,
+// their deletion fails and they're left for the operator.
+func GcpKinds(project string, clients *gcpclient.Factory) []Kind {
+	return []Kind{
+		{
+			Name: BucketKind,
+			List: func(ctx context.Context) ([]Resource, error) {
+				service, err := storage.NewService(ctx, clients.Options(ctx, "storage")...)
+				if err != nil {
+					return nil, err
+				}
+
+				var out []Resource
+				err = service.Buckets.List(project).Pages(ctx, func(page *storage.Buckets) error {
+					for _, bucket := range page.Items {
+						out = appendLabeled(out, BucketKind, bucket.Name, bucket.Labels)
+					}
+					return nil
+				})
+				return out, err
+			},
+			Delete: func(ctx context.Context, name string) error {
+				service, err := storage.NewService(ctx, clients.Options(ctx, "storage")...)
+				if err != nil {
+					return err
+				}
+
+				return service.Buckets.Delete(name).Context(ctx).Do()
+			},
+		},
+		{
+			Name: CloudSqlInstanceKind,
+			List: func(ctx context.Context) ([]Resource, error) {
+				service, err := sqladmin.NewService(ctx, clients.Options(ctx, "sqladmin")...)
+				if err != nil {
+					return nil, err
+				}
+
+				var out []Resource
+				err = service.Instances.List(project).Pages(ctx, func(page *sqladmin.InstancesListResponse) error {
+					for _, instance := range page.Items {
+						if instance.Settings != nil {
+							out = appendLabeled(out, CloudSqlInstanceKind, instance.Name, instance.Settings.UserLabels)
+						}
+					}
+					return nil
+				})
+				return out, err
+			},
+			Delete: func(ctx context.Context, name string) error {
+				service, err := sqladmin.NewService(ctx, clients.Options(ctx, "sqladmin")...)
+				if err != nil {
+					return err
+				}
+
+				_, err = service.Instances.Delete(project, name).Context(ctx).Do()
+				return err
+			},
+		},
+		{
+			Name: TopicKind,
+			List: func(ctx context.Context) ([]Resource, error) {
+				service, err := pubsub.NewService(ctx, clients.Options(ctx, "pubsub")...)
+				if err != nil {
+					return nil, err
+				}
+
+				var out []Resource
+				err = service.Projects.Topics.List("projects/"+project).Pages(ctx, func(page *pubsub.ListTopicsResponse) error {
+					for _, topic := range page.Topics {
+						out = appendLabeled(out, TopicKind, topic.Name, topic.Labels)
+					}
+					return nil
+				})
+				return out, err
+			},
+			Delete: func(ctx context.Context, name string) error {
+				service, err := pubsub.NewService(ctx, clients.Options(ctx, "pubsub")...)
+				if err != nil {
+					return err
+				}
+
+				_, err = service.Projects.Topics.Delete(name).Context(ctx).Do()
+				return err
+			},
+		},
+		{
+			Name: SubscriptionKind,
+			List: func(ctx context.Context) ([]Resource, error) {
+				service, err := pubsub.NewService(ctx, clients.Options(ctx, "pubsub")...)
+				if err != nil {
+					return nil, err
+				}
+
+				var out []Resource
+				err = service.Projects.Subscriptions.List("projects/"+project).Pages(ctx, func(page *pubsub.ListSubscriptionsResponse) error {
+					for _, subscription := range page.Subscriptions {
+						out = appendLabeled(out, SubscriptionKind, subscription.Name, subscription.Labels)
+					}
+					return nil
+				})
+				return out, err
+			},
+			Delete: func(ctx context.Context, name string) error {
+				service, err := pubsub.NewService(ctx, clients.Options(ctx, "pubsub")...)
+				if err != nil {
+					return err
+				}
+
+				_, err = service.Projects.Subscriptions.Delete(name).Context(ctx).Do()
+				return err
+			},
+		},
+		{
+			Name: DatasetKind,
+			List: func(ctx context.Context) ([]Resource, error) {
+				service, err := bigquery.NewService(ctx, clients.Options(ctx, "bigquery")...)
+				if err != nil {
+					return nil, err
+				}
+
+				var out []Resource
+				err = service.Datasets.List(project).Pages(ctx, func(page *bigquery.DatasetList) error {
+					for _, dataset := range page.Datasets {
+						if dataset.DatasetReference != nil {
+							out = appendLabeled(out, DatasetKind, dataset.DatasetReference.DatasetId, dataset.Labels)
+						}
+					}
+					return nil
+				})
+				return out, err
+			},
+			Delete: func(ctx context.Context, name string) error {
+				service, err := bigquery.NewService(ctx, clients.Options(ctx, "bigquery")...)
+				if err != nil {
+					return err
+				}
+
+				return service.Datasets.Delete(project, name).Context(ctx).Do()
+			},
+		},
+	}
+}
+
+// appendLabeled appends the resource to out if it's labeled with an instance
+// ID.
+func appendLabeled(out []Resource, kind, name string, labels map[string]string) []Resource {
+	instanceId, ok := labels[utils.InstanceIdLabel]
+	if !ok || instanceId == "" {
+		return out
+	}
+
+	return append(out, Resource{Kind: kind, Name: name, InstanceId: instanceId, Labels: labels})
+}
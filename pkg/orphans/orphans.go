@@ -0,0 +1,322 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package orphans finds cloud resources the broker labeled with the ID of a
+// service instance it no longer manages, e.g. because a deprovision failed
+// part way or the database was restored from an older backup. Orphans are
+// reported and, if the operator opts in, deleted.
+package orphans
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+const (
+	// IntervalProp is the viper key of how often a running broker looks for
+	// orphans. Zero disables collection in the broker so it can be run as a
+	// task.
+	IntervalProp = "orphans.interval"
+
+	// DeleteProp is the viper key of whether orphans are deleted rather than
+	// only reported.
+	DeleteProp = "orphans.delete"
+
+	// MinAgeProp is the viper key of how long after the broker last worked
+	// on an instance its resources can be orphans.
+	MinAgeProp = "orphans.min_age"
+
+	// LabelsProp is the viper key of a JSON object of labels resources must
+	// also have to be orphans. The static labels are used if it's unset.
+	LabelsProp = "orphans.labels"
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: IntervalProp, Kind: config.Duration, Default: "24h"},
+		config.Property{Key: DeleteProp, Kind: config.Boolean, Default: false},
+		config.Property{Key: MinAgeProp, Kind: config.Duration, Default: "24h"},
+		config.Property{Key: LabelsProp, Kind: config.Json},
+	)
+}
+
+// Resource is a cloud resource labeled with the ID of a service instance.
+type Resource struct {
+	Kind       string            `json:"kind"`
+	Name       string            `json:"name"`
+	InstanceId string            `json:"instance_id"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// Kind lists and deletes one kind of cloud resource.
+type Kind struct {
+	Name string
+
+	// List lists the resources of the kind labeled with an instance ID.
+	List func(ctx context.Context) ([]Resource, error)
+
+	// Delete deletes the resource with the given name.
+	Delete func(ctx context.Context, name string) error
+}
+
+// Database lists the instances the broker manages and its recent work.
+type Database interface {
+	ListServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) ([]models.ServiceInstanceDetails, error)
+	ListTerraformDeployments(ctx context.Context, limit int) ([]models.TerraformDeployment, error)
+}
+
+// databaseStore uses the broker's database.
+type databaseStore struct{}
+
+func (databaseStore) ListServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) ([]models.ServiceInstanceDetails, error) {
+	return db_service.ListServiceInstanceDetails(ctx, conditions)
+}
+
+func (databaseStore) ListTerraformDeployments(ctx context.Context, limit int) ([]models.TerraformDeployment, error) {
+	return db_service.ListTerraformDeployments(ctx, limit)
+}
+
+// Collector finds resources labeled with the ID of an instance that isn't in
+// the database. Resources of instances the broker worked on within MinAge
+// aren't orphans, so resources of instances still being provisioned, or
+// whose deprovision is being verified, are left alone.
+type Collector struct {
+	Kinds    []Kind
+	Database Database
+
+	// Labels must all be on a resource for it to be an orphan, so brokers
+	// sharing a project only collect their own resources.
+	Labels map[string]string
+	MinAge time.Duration
+
+	// Delete enables deleting orphans, otherwise they're only reported.
+	Delete bool
+	Logger lager.Logger
+
+	// LeaderContext, if set, is called before each run of RunEvery so only
+	// one broker instance runs at a time. Runs are skipped while another
+	// instance leads and stop if this one stops leading.
+	LeaderContext func(ctx context.Context) (context.Context, context.CancelFunc, bool)
+
+	// now is used to get the current time, it can be replaced in tests.
+	now func() time.Time
+}
+
+// NewCollectorFromEnv creates a Collector of the Google Cloud resource kinds
+// in the broker's default project configured in viper.
+func NewCollectorFromEnv(logger lager.Logger) (*Collector, error) {
+	minAge, err := time.ParseDuration(viper.GetString(MinAgeProp))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", MinAgeProp, err)
+	}
+	if minAge <= 0 {
+		return nil, fmt.Errorf("%s must be positive, got %s", MinAgeProp, minAge)
+	}
+
+	labels := utils.StaticLabels()
+	if raw := viper.Get(LabelsProp); raw != nil && raw != "" {
+		if labels, err = cast.ToStringMapStringE(raw); err != nil {
+			return nil, fmt.Errorf("couldn't parse %s: %v", LabelsProp, err)
+		}
+	}
+
+	// without labels every resource with an instance ID label could be an
+	// orphan, including those of other brokers sharing the project
+	deleteOrphans := viper.GetBool(DeleteProp)
+	if deleteOrphans && len(labels) == 0 {
+		return nil, fmt.Errorf("%s requires %s or the static labels to be set so only the broker's own resources are deleted", DeleteProp, LabelsProp)
+	}
+
+	clients, err := gcpclient.NewFactoryFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := utils.GetDefaultProjectId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Collector{
+		Kinds:    GcpKinds(project, clients),
+		Database: databaseStore{},
+		Labels:   utils.SanitizeLabels(labels),
+		MinAge:   minAge,
+		Delete:   deleteOrphans,
+		Logger:   logger.Session("orphans"),
+	}, nil
+}
+
+func (c *Collector) currentTime() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+
+	return time.Now()
+}
+
+// Run looks for orphans and deletes them if Delete is set, otherwise the
+// result is a dry run listing them. Deleting continues past failures, the
+// first is returned.
+func (c *Collector) Run(ctx context.Context) (*planning.Result, error) {
+	changes, err := c.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.Delete {
+		ctx = planning.WithDryRun(ctx)
+	}
+
+	result := changes.Execute(ctx)
+	for _, outcome := range result.Outcomes {
+		c.Logger.Info("orphan", lager.Data{"resource": outcome.Target, "details": outcome.Details, "deleted": outcome.Applied, "error": outcome.Error})
+	}
+
+	return result, result.Err()
+}
+
+// Plan creates a change deleting each orphan. Kinds that can't be listed,
+// e.g. because their API isn't enabled in the project, are logged and
+// skipped.
+func (c *Collector) Plan(ctx context.Context) (*planning.Plan, error) {
+	live, err := c.liveInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &planning.Plan{}
+	for _, kind := range c.Kinds {
+		kind := kind
+		resources, err := kind.List(ctx)
+		if err != nil {
+			c.Logger.Error("listing", err, lager.Data{"kind": kind.Name})
+			continue
+		}
+
+		sort.Slice(resources, func(i, j int) bool { return resources[i].Name < resources[j].Name })
+		for _, resource := range resources {
+			if live[resource.InstanceId] || !c.hasLabels(resource) {
+				continue
+			}
+
+			name := resource.Name
+			details := map[string]interface{}{
+				"kind":        kind.Name,
+				"instance_id": resource.InstanceId,
+			}
+			changes.Add("delete-orphan", kind.Name+"/"+name, details, func(ctx context.Context) error {
+				return kind.Delete(ctx, name)
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// liveInstances gets the label values of the IDs of the instances in the
+// database and the instances the broker worked on within MinAge.
+func (c *Collector) liveInstances(ctx context.Context) (map[string]bool, error) {
+	instances, err := c.Database.ListServiceInstanceDetails(ctx, models.ServiceInstanceDetails{})
+	if err != nil {
+		return nil, fmt.Errorf("listing instances: %v", err)
+	}
+
+	deployments, err := c.Database.ListTerraformDeployments(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("listing deployments: %v", err)
+	}
+
+	var ids []string
+	for _, instance := range instances {
+		ids = append(ids, instance.ID)
+	}
+
+	cutoff := c.currentTime().Add(-c.MinAge)
+	for _, deployment := range deployments {
+		if deployment.UpdatedAt.After(cutoff) {
+			ids = append(ids, instanceIdOf(deployment.ID))
+		}
+	}
+
+	live := make(map[string]bool)
+	for _, id := range ids {
+		live[utils.SanitizeLabels(map[string]string{utils.InstanceIdLabel: id})[utils.InstanceIdLabel]] = true
+	}
+
+	return live, nil
+}
+
+// instanceIdOf gets the ID of the instance a Terraform deployment was created
+// for, deployment IDs look like tf:instance-id:binding-id.
+func instanceIdOf(deploymentId string) string {
+	parts := strings.SplitN(deploymentId, ":", 3)
+	if len(parts) != 3 || parts[0] != "tf" {
+		return deploymentId
+	}
+
+	return parts[1]
+}
+
+func (c *Collector) hasLabels(resource Resource) bool {
+	for key, value := range c.Labels {
+		if resource.Labels[key] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RunEvery runs the collector every interval until the context is done. Runs
+// are skipped while another instance leads if LeaderContext is set.
+func (c *Collector) RunEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runCtx, cancel, leads := ctx, context.CancelFunc(func() {}), true
+			if c.LeaderContext != nil {
+				runCtx, cancel, leads = c.LeaderContext(ctx)
+			}
+			if !leads {
+				cancel()
+				c.Logger.Debug("skipping-run", lager.Data{"reason": "another instance leads orphan collection"})
+				continue
+			}
+
+			if _, err := c.Run(runCtx); err != nil {
+				c.Logger.Error("collecting", err)
+			}
+			cancel()
+		}
+	}
+}
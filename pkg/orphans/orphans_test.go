@@ -0,0 +1,215 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orphans
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+type fakeDatabase struct {
+	Instances   []models.ServiceInstanceDetails
+	Deployments []models.TerraformDeployment
+}
+
+func (f *fakeDatabase) ListServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) ([]models.ServiceInstanceDetails, error) {
+	return f.Instances, nil
+}
+
+func (f *fakeDatabase) ListTerraformDeployments(ctx context.Context, limit int) ([]models.TerraformDeployment, error) {
+	return f.Deployments, nil
+}
+
+type fakeKind struct {
+	Resources []Resource
+	ListErr   error
+	DeleteErr error
+	Deleted   []string
+}
+
+func (f *fakeKind) Kind(name string) Kind {
+	return Kind{
+		Name: name,
+		List: func(ctx context.Context) ([]Resource, error) {
+			return f.Resources, f.ListErr
+		},
+		Delete: func(ctx context.Context, name string) error {
+			if f.DeleteErr != nil {
+				return f.DeleteErr
+			}
+			f.Deleted = append(f.Deleted, name)
+			return nil
+		},
+	}
+}
+
+func labeled(instanceId string) map[string]string {
+	return map[string]string{"pcf-instance-id": instanceId, "broker": "prod"}
+}
+
+func TestCollector_Run(t *testing.T) {
+	now := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	db := &fakeDatabase{
+		Instances: []models.ServiceInstanceDetails{{ID: "live"}},
+		Deployments: []models.TerraformDeployment{
+			{ID: "tf:provisioning:", UpdatedAt: now.Add(-time.Minute)},
+			{ID: "tf:deleted:", UpdatedAt: now.Add(-48 * time.Hour)},
+		},
+	}
+
+	cases := map[string]struct {
+		Resources     []Resource
+		Delete        bool
+		DeleteErr     error
+		ExpectTargets []string
+		ExpectDeleted []string
+		ExpectError   bool
+	}{
+		"live instances": {
+			Resources: []Resource{
+				{Name: "bucket-1", InstanceId: "live", Labels: labeled("live")},
+				{Name: "bucket-2", InstanceId: "provisioning", Labels: labeled("provisioning")},
+			},
+			ExpectTargets: nil,
+		},
+		"reports orphans": {
+			Resources: []Resource{
+				{Name: "bucket-2", InstanceId: "unknown", Labels: labeled("unknown")},
+				{Name: "bucket-1", InstanceId: "deleted", Labels: labeled("deleted")},
+			},
+			ExpectTargets: []string{"storage.bucket/bucket-1", "storage.bucket/bucket-2"},
+		},
+		"deletes orphans": {
+			Resources:     []Resource{{Name: "bucket-1", InstanceId: "deleted", Labels: labeled("deleted")}},
+			Delete:        true,
+			ExpectTargets: []string{"storage.bucket/bucket-1"},
+			ExpectDeleted: []string{"bucket-1"},
+		},
+		"failed delete": {
+			Resources:     []Resource{{Name: "bucket-1", InstanceId: "deleted", Labels: labeled("deleted")}},
+			Delete:        true,
+			DeleteErr:     errors.New("bucket not empty"),
+			ExpectTargets: []string{"storage.bucket/bucket-1"},
+			ExpectError:   true,
+		},
+		"other brokers": {
+			Resources:     []Resource{{Name: "bucket-1", InstanceId: "deleted", Labels: map[string]string{"pcf-instance-id": "deleted", "broker": "dev"}}},
+			ExpectTargets: nil,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			kind := &fakeKind{Resources: tc.Resources, DeleteErr: tc.DeleteErr}
+			collector := &Collector{
+				Kinds:    []Kind{kind.Kind(BucketKind)},
+				Database: db,
+				Labels:   map[string]string{"broker": "prod"},
+				MinAge:   24 * time.Hour,
+				Delete:   tc.Delete,
+				Logger:   lager.NewLogger("test"),
+				now:      func() time.Time { return now },
+			}
+
+			result, err := collector.Run(context.Background())
+			if (err != nil) != tc.ExpectError {
+				t.Fatalf("Expected error %v, got %v", tc.ExpectError, err)
+			}
+
+			var targets []string
+			for _, outcome := range result.Outcomes {
+				targets = append(targets, outcome.Target)
+			}
+			if !reflect.DeepEqual(targets, tc.ExpectTargets) {
+				t.Errorf("Expected orphans %v, got %v", tc.ExpectTargets, targets)
+			}
+			if result.DryRun == tc.Delete {
+				t.Errorf("Expected dry run %v, got %v", !tc.Delete, result.DryRun)
+			}
+			if !reflect.DeepEqual(kind.Deleted, tc.ExpectDeleted) {
+				t.Errorf("Expected %v to be deleted, got %v", tc.ExpectDeleted, kind.Deleted)
+			}
+		})
+	}
+}
+
+func TestCollector_Plan_listError(t *testing.T) {
+	failing := &fakeKind{ListErr: errors.New("API not enabled")}
+	working := &fakeKind{Resources: []Resource{{Name: "topic-1", InstanceId: "deleted", Labels: labeled("deleted")}}}
+
+	collector := &Collector{
+		Kinds:    []Kind{failing.Kind(DatasetKind), working.Kind(TopicKind)},
+		Database: &fakeDatabase{},
+		MinAge:   24 * time.Hour,
+		Logger:   lager.NewLogger("test"),
+	}
+
+	changes, err := collector.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := len(changes.Changes()); count != 1 {
+		t.Errorf("Expected kinds that can't be listed to be skipped, got %d changes", count)
+	}
+}
+
+func TestNewCollectorFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Prop  string
+		Value interface{}
+	}{
+		"invalid min age":  {Prop: MinAgeProp, Value: "soon"},
+		"negative min age": {Prop: MinAgeProp, Value: "-1h"},
+		"invalid labels":   {Prop: LabelsProp, Value: "[1, 2]"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(tc.Prop, tc.Value)
+			defer viper.Set(tc.Prop, nil)
+
+			if _, err := NewCollectorFromEnv(lager.NewLogger("test")); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestNewCollectorFromEnv_deleteWithoutLabels(t *testing.T) {
+	viper.Set(DeleteProp, true)
+	defer viper.Set(DeleteProp, nil)
+
+	_, err := NewCollectorFromEnv(lager.NewLogger("test"))
+	if err == nil || !strings.Contains(err.Error(), LabelsProp) {
+		t.Fatalf("Expected an error about %s, got %v", LabelsProp, err)
+	}
+
+	// other settings may fail in the test environment, but not the labels
+	viper.Set(LabelsProp, `{"broker":"team-a"}`)
+	defer viper.Set(LabelsProp, nil)
+
+	_, err = NewCollectorFromEnv(lager.NewLogger("test"))
+	if err != nil && strings.Contains(err.Error(), LabelsProp) {
+		t.Errorf("Expected no error about %s with labels set, got %v", LabelsProp, err)
+	}
+}
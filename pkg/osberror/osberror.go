@@ -0,0 +1,175 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package osberror builds the structured error bodies of the OSB spec, with
+// an `error` code platforms can react to programmatically alongside the
+// human readable `description`, and `instance_usable` and
+// `update_repeatable` where the broker knows them.
+package osberror
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// Codes defined by the OSB spec.
+const (
+	// AsyncRequired errors are returned when the operation can only be done
+	// asynchronously but the platform didn't accept incomplete responses.
+	AsyncRequired = "AsyncRequired"
+
+	// ConcurrencyError errors are returned when another request is changing
+	// the same instance.
+	ConcurrencyError = "ConcurrencyError"
+
+	// RequiresApp errors are returned when a binding needs an app but the
+	// request has no app_guid.
+	RequiresApp = "RequiresApp"
+
+	// MaintenanceInfoConflict errors are returned when the maintenance_info
+	// in the request doesn't match the catalog.
+	MaintenanceInfoConflict = "MaintenanceInfoConflict"
+)
+
+// Codes of the broker's own errors.
+const (
+	InvalidParameters     = "InvalidParameters"
+	UnsupportedEndpoint   = "UnsupportedEndpoint"
+	NonUpdatableParameter = "NonUpdatableParameter"
+	QuotaExceeded         = "QuotaExceeded"
+	RegionNotPermitted    = "RegionNotPermitted"
+	ProjectNotPermitted   = "ProjectNotPermitted"
+	RoleNotPermitted      = "RoleNotPermitted"
+	KeylessUnsupported    = "KeylessBindUnsupported"
+	KmsKeyNotPermitted    = "KmsKeyNotPermitted"
+	InstanceNotShareable  = "InstanceNotShareable"
+	InstanceIdReused      = "InstanceIdReused"
+	UpgradePinned         = "UpgradePinned"
+	UpgradeNotApproved    = "UpgradeNotApproved"
+	DeletionProtected     = "DeletionProtected"
+	ParameterPinned       = "ParameterPinned"
+	PlanConstraint        = "PlanConstraint"
+	MaintenanceMode       = "MaintenanceMode"
+)
+
+// New creates a failure response with the given code. The logger action is
+// what the failure is logged as.
+func New(err error, status int, loggerAction, code string) *brokerapi.FailureResponse {
+	return brokerapi.NewFailureResponseBuilder(err, status, loggerAction).WithErrorKey(code).Build()
+}
+
+// Code returns the error code of a failure response, or an empty string if
+// the error doesn't have one.
+func Code(err error) string {
+	failure, ok := err.(*brokerapi.FailureResponse)
+	if !ok {
+		return ""
+	}
+
+	body, ok := failure.ErrorResponse().(brokerapi.ErrorResponse)
+	if !ok {
+		return ""
+	}
+
+	return body.Error
+}
+
+// Details are the optional fields of an error body.
+type Details struct {
+	// InstanceUsable is whether the instance can still be used after a failed
+	// update or deprovision.
+	InstanceUsable *bool
+
+	// UpdateRepeatable is whether repeating a failed update may succeed.
+	UpdateRepeatable *bool
+}
+
+type detailsKey struct{}
+
+// Record attaches details to the error response of the request being served
+// with the context. It does nothing outside requests handled by Wrap.
+func Record(ctx context.Context, details Details) {
+	d, ok := ctx.Value(detailsKey{}).(*Details)
+	if !ok {
+		return
+	}
+
+	*d = details
+}
+
+// Bool returns a pointer to b for the fields of Details.
+func Bool(b bool) *bool {
+	return &b
+}
+
+// Wrap adds the details recorded by the broker to error responses. Other
+// responses pass through untouched.
+func Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		d := &Details{}
+		buffered := &bufferedResponse{header: w.Header(), status: http.StatusOK}
+		next.ServeHTTP(buffered, req.WithContext(context.WithValue(req.Context(), detailsKey{}, d)))
+
+		body := buffered.body.Bytes()
+		if buffered.status >= 400 && (d.InstanceUsable != nil || d.UpdateRepeatable != nil) {
+			if extended, err := extendResponse(body, d); err == nil {
+				body = extended
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			}
+		}
+
+		w.WriteHeader(buffered.status)
+		w.Write(body)
+	})
+}
+
+func extendResponse(body []byte, d *Details) ([]byte, error) {
+	response := make(map[string]interface{})
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	if d.InstanceUsable != nil {
+		response["instance_usable"] = *d.InstanceUsable
+	}
+	if d.UpdateRepeatable != nil {
+		response["update_repeatable"] = *d.UpdateRepeatable
+	}
+
+	return json.Marshal(response)
+}
+
+// bufferedResponse holds a response so it can be changed before it's sent.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
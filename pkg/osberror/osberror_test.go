@@ -0,0 +1,129 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package osberror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+func TestNew(t *testing.T) {
+	failure := New(errors.New("a different request is in progress"), http.StatusUnprocessableEntity, "concurrent-access", ConcurrencyError)
+
+	expected := brokerapi.ErrorResponse{Error: ConcurrencyError, Description: "a different request is in progress"}
+	if actual := failure.ErrorResponse(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected response %v, got %v", expected, actual)
+	}
+
+	if failure.ValidatedStatusCode(nil) != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, failure.ValidatedStatusCode(nil))
+	}
+
+	if failure.LoggerAction() != "concurrent-access" {
+		t.Errorf("expected logger action concurrent-access, got %s", failure.LoggerAction())
+	}
+}
+
+func TestCode(t *testing.T) {
+	cases := map[string]struct {
+		err      error
+		expected string
+	}{
+		"osb error":      {err: New(errors.New("quota exceeded"), http.StatusForbidden, "quota-exceeded", QuotaExceeded), expected: QuotaExceeded},
+		"no error key":   {err: brokerapi.ErrInstanceLimitMet, expected: ""},
+		"empty response": {err: brokerapi.ErrInstanceDoesNotExist, expected: ""},
+		"plain error":    {err: errors.New("database unavailable"), expected: ""},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := Code(tc.err); actual != tc.expected {
+				t.Errorf("expected code %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	cases := map[string]struct {
+		Status   int
+		Details  *Details
+		Expected map[string]interface{}
+	}{
+		"failed update": {
+			Status:  http.StatusBadRequest,
+			Details: &Details{InstanceUsable: Bool(true), UpdateRepeatable: Bool(false)},
+			Expected: map[string]interface{}{
+				"error":             "InvalidParameters",
+				"description":       "bad",
+				"instance_usable":   true,
+				"update_repeatable": false,
+			},
+		},
+		"failed deprovision": {
+			Status:  http.StatusInternalServerError,
+			Details: &Details{InstanceUsable: Bool(true)},
+			Expected: map[string]interface{}{
+				"error":           "InvalidParameters",
+				"description":     "bad",
+				"instance_usable": true,
+			},
+		},
+		"nothing recorded": {
+			Status:   http.StatusBadRequest,
+			Expected: map[string]interface{}{"error": "InvalidParameters", "description": "bad"},
+		},
+		"success": {
+			Status:   http.StatusOK,
+			Details:  &Details{InstanceUsable: Bool(true)},
+			Expected: map[string]interface{}{"error": "InvalidParameters", "description": "bad"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			handler := Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if tc.Details != nil {
+					Record(req.Context(), *tc.Details)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.Status)
+				w.Write([]byte(`{"error":"InvalidParameters","description":"bad"}`))
+			}))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/v2/service_instances/instance-1", nil))
+
+			if w.Code != tc.Status {
+				t.Errorf("expected status %d, got %d", tc.Status, w.Code)
+			}
+
+			actual := make(map[string]interface{})
+			if err := json.Unmarshal(w.Body.Bytes(), &actual); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("expected body %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plandrift detects plans whose ID changed between runs of the
+// broker. Platforms identify plans by ID, so instances created with the old
+// ID are orphaned when it changes, e.g. because a user-defined plan was
+// reconfigured without its id.
+package plandrift
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+)
+
+// ModeProp is the viper key of what the broker does on startup when a plan's
+// ID changed.
+const ModeProp = "catalog.plan_id_drift"
+
+// Values of ModeProp.
+const (
+	// ModeFail refuses to start the broker.
+	ModeFail = "fail"
+
+	// ModeWarn logs the change and records the new ID.
+	ModeWarn = "warn"
+)
+
+func init() {
+	config.Register(config.Property{Key: ModeProp, Kind: config.String, Default: ModeFail, Allowed: []string{ModeFail, ModeWarn}})
+}
+
+// Store holds the recorded plan IDs and the instances using them.
+type Store interface {
+	ListPlanRecords(ctx context.Context) ([]models.PlanRecord, error)
+	SavePlanRecord(ctx context.Context, record *models.PlanRecord) error
+	CountServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) (int, error)
+}
+
+// databaseStore uses the broker's database.
+type databaseStore struct{}
+
+func (databaseStore) ListPlanRecords(ctx context.Context) ([]models.PlanRecord, error) {
+	return db_service.ListPlanRecords(ctx)
+}
+
+func (databaseStore) SavePlanRecord(ctx context.Context, record *models.PlanRecord) error {
+	return db_service.SavePlanRecord(ctx, record)
+}
+
+func (databaseStore) CountServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) (int, error) {
+	return db_service.CountServiceInstanceDetails(ctx, conditions)
+}
+
+// Drift is a plan whose ID changed.
+type Drift struct {
+	ServiceName string `json:"service_name"`
+	PlanName    string `json:"plan_name"`
+	PreviousId  string `json:"previous_id"`
+	CurrentId   string `json:"current_id"`
+
+	// Instances is the number of instances still using the previous ID.
+	Instances int `json:"instances"`
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("plan %q of service %q changed ID from %s to %s, orphaning %d instance(s)", d.PlanName, d.ServiceName, d.PreviousId, d.CurrentId, d.Instances)
+}
+
+// DriftError is returned by Check in ModeFail when plans changed ID.
+type DriftError struct {
+	Drifts []Drift
+}
+
+func (e *DriftError) Error() string {
+	var lines []string
+	for _, drift := range e.Drifts {
+		lines = append(lines, drift.String())
+	}
+
+	return fmt.Sprintf("%s; restore the previous IDs or set %s to %q to accept them", strings.Join(lines, "; "), ModeProp, ModeWarn)
+}
+
+// Checker compares the plans in the catalog with the recorded ones.
+type Checker struct {
+	Store  Store
+	Mode   string
+	Logger lager.Logger
+
+	// DryRun reports drift without recording the catalog's IDs.
+	DryRun bool
+}
+
+// NewCheckerFromEnv creates a Checker using the broker's database and the
+// mode configured in viper.
+func NewCheckerFromEnv(logger lager.Logger) (*Checker, error) {
+	mode := viper.GetString(ModeProp)
+	if mode != ModeFail && mode != ModeWarn {
+		return nil, fmt.Errorf("%s must be %q or %q, got %q", ModeProp, ModeFail, ModeWarn, mode)
+	}
+
+	return &Checker{
+		Store:  databaseStore{},
+		Mode:   mode,
+		Logger: logger.Session("plan-drift"),
+	}, nil
+}
+
+// Check finds the plans in the catalog whose ID differs from the one recorded
+// for the same service and plan name. In ModeFail a DriftError is returned
+// and nothing is recorded. Otherwise the drift is logged and the catalog's
+// IDs are recorded, including those of new plans, unless it's a DryRun.
+func (c *Checker) Check(ctx context.Context, catalog []brokerapi.Service) ([]Drift, error) {
+	records, err := c.Store.ListPlanRecords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list recorded plans: %v", err)
+	}
+
+	recorded := make(map[string]models.PlanRecord)
+	for _, record := range records {
+		recorded[key(record.ServiceName, record.PlanName)] = record
+	}
+
+	var drifts []Drift
+	var changed []models.PlanRecord
+	for _, service := range catalog {
+		for _, plan := range service.Plans {
+			record, ok := recorded[key(service.Name, plan.Name)]
+			if ok && record.PlanId == plan.ID && record.ServiceId == service.ID {
+				continue
+			}
+
+			if ok && record.PlanId != plan.ID {
+				instances, err := c.Store.CountServiceInstanceDetails(ctx, models.ServiceInstanceDetails{PlanId: record.PlanId})
+				if err != nil {
+					return nil, fmt.Errorf("couldn't count instances of plan %s: %v", record.PlanId, err)
+				}
+
+				drifts = append(drifts, Drift{
+					ServiceName: service.Name,
+					PlanName:    plan.Name,
+					PreviousId:  record.PlanId,
+					CurrentId:   plan.ID,
+					Instances:   instances,
+				})
+			}
+
+			record.ServiceId = service.ID
+			record.ServiceName = service.Name
+			record.PlanName = plan.Name
+			record.PlanId = plan.ID
+			changed = append(changed, record)
+		}
+	}
+
+	if len(drifts) > 0 && c.Mode != ModeWarn {
+		return drifts, &DriftError{Drifts: drifts}
+	}
+
+	for _, drift := range drifts {
+		c.Logger.Error("plan-id-changed", fmt.Errorf("%s", drift), lager.Data{"drift": drift})
+	}
+
+	if c.DryRun {
+		return drifts, nil
+	}
+
+	for i := range changed {
+		if err := c.Store.SavePlanRecord(ctx, &changed[i]); err != nil {
+			return drifts, fmt.Errorf("couldn't record plan %s: %v", changed[i].PlanId, err)
+		}
+	}
+
+	return drifts, nil
+}
+
+func key(serviceName, planName string) string {
+	return serviceName + "/" + planName
+}
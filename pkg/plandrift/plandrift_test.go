@@ -0,0 +1,154 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plandrift
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+type fakeStore struct {
+	Records   []models.PlanRecord
+	Instances map[string]int
+	Saved     []models.PlanRecord
+}
+
+func (f *fakeStore) ListPlanRecords(ctx context.Context) ([]models.PlanRecord, error) {
+	return f.Records, nil
+}
+
+func (f *fakeStore) SavePlanRecord(ctx context.Context, record *models.PlanRecord) error {
+	f.Saved = append(f.Saved, *record)
+	return nil
+}
+
+func (f *fakeStore) CountServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) (int, error) {
+	return f.Instances[conditions.PlanId], nil
+}
+
+func TestChecker_Check(t *testing.T) {
+	catalog := []brokerapi.Service{{
+		ID:   "svc-id",
+		Name: "my-service",
+		Plans: []brokerapi.ServicePlan{
+			{ID: "small-id", Name: "small"},
+			{ID: "large-id", Name: "large"},
+		},
+	}}
+
+	small := models.PlanRecord{ServiceId: "svc-id", ServiceName: "my-service", PlanName: "small", PlanId: "small-id"}
+	large := models.PlanRecord{ServiceId: "svc-id", ServiceName: "my-service", PlanName: "large", PlanId: "large-id"}
+	oldLarge := models.PlanRecord{ServiceId: "svc-id", ServiceName: "my-service", PlanName: "large", PlanId: "old-large-id"}
+	largeDrift := Drift{ServiceName: "my-service", PlanName: "large", PreviousId: "old-large-id", CurrentId: "large-id", Instances: 2}
+
+	cases := map[string]struct {
+		Mode        string
+		DryRun      bool
+		Records     []models.PlanRecord
+		ExpectDrift []Drift
+		ExpectSaved []models.PlanRecord
+		ExpectErr   bool
+	}{
+		"first-run": {
+			Mode:        ModeFail,
+			ExpectSaved: []models.PlanRecord{small, large},
+		},
+		"unchanged": {
+			Mode:    ModeFail,
+			Records: []models.PlanRecord{small, large},
+		},
+		"new-plan": {
+			Mode:        ModeFail,
+			Records:     []models.PlanRecord{small},
+			ExpectSaved: []models.PlanRecord{large},
+		},
+		"drift-fails": {
+			Mode:        ModeFail,
+			Records:     []models.PlanRecord{small, oldLarge},
+			ExpectDrift: []Drift{largeDrift},
+			ExpectErr:   true,
+		},
+		"drift-warns": {
+			Mode:        ModeWarn,
+			Records:     []models.PlanRecord{small, oldLarge},
+			ExpectDrift: []Drift{largeDrift},
+			ExpectSaved: []models.PlanRecord{large},
+		},
+		"dry-run": {
+			Mode:        ModeWarn,
+			DryRun:      true,
+			Records:     []models.PlanRecord{oldLarge},
+			ExpectDrift: []Drift{largeDrift},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			store := &fakeStore{Records: tc.Records, Instances: map[string]int{"old-large-id": 2}}
+			checker := &Checker{Store: store, Mode: tc.Mode, DryRun: tc.DryRun, Logger: lager.NewLogger("test")}
+
+			drifts, err := checker.Check(context.Background(), catalog)
+			if (err != nil) != tc.ExpectErr {
+				t.Fatalf("expected error: %v, got: %v", tc.ExpectErr, err)
+			}
+
+			if !reflect.DeepEqual(drifts, tc.ExpectDrift) {
+				t.Errorf("expected drifts %v, got %v", tc.ExpectDrift, drifts)
+			}
+
+			if !reflect.DeepEqual(store.Saved, tc.ExpectSaved) {
+				t.Errorf("expected saved records %v, got %v", tc.ExpectSaved, store.Saved)
+			}
+		})
+	}
+}
+
+func TestNewCheckerFromEnv(t *testing.T) {
+	defer viper.Set(ModeProp, nil)
+
+	cases := map[string]struct {
+		Mode      string
+		ExpectErr bool
+	}{
+		"default": {Mode: ""},
+		"warn":    {Mode: ModeWarn},
+		"bad":     {Mode: "ignore", ExpectErr: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if tc.Mode == "" {
+				viper.Set(ModeProp, nil)
+			} else {
+				viper.Set(ModeProp, tc.Mode)
+			}
+
+			checker, err := NewCheckerFromEnv(lager.NewLogger("test"))
+			if (err != nil) != tc.ExpectErr {
+				t.Fatalf("expected error: %v, got: %v", tc.ExpectErr, err)
+			}
+
+			if err == nil && tc.Mode == "" && checker.Mode != ModeFail {
+				t.Errorf("expected default mode %q, got %q", ModeFail, checker.Mode)
+			}
+		})
+	}
+}
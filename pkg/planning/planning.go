@@ -0,0 +1,137 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package planning separates deciding what a mutating admin operation will
+// change from making the changes, so every operation supports dry runs the
+// same way: the operation builds a Plan listing each change and the function
+// that applies it, then executes the plan, which only reports the changes if
+// the context is a dry run.
+package planning
+
+import (
+	"context"
+)
+
+type dryRunKey struct{}
+
+// WithDryRun returns a context in which plans report their changes without
+// applying them.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun returns true if plans executed with the context shouldn't apply
+// their changes.
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// ApplyFunc makes a planned change.
+type ApplyFunc func(ctx context.Context) error
+
+// Change describes a single change to a row or cloud resource.
+type Change struct {
+	// Action is what will be done, e.g. revoke-binding.
+	Action string `json:"action"`
+	// Target identifies what the action is done to, e.g. a binding ID.
+	Target string `json:"target"`
+	// Details holds anything else needed to review the change.
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Outcome is the result of executing a change.
+type Outcome struct {
+	Change
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Result reports the outcome of every change in a plan, in the order they
+// were added.
+type Result struct {
+	DryRun   bool      `json:"dry_run"`
+	Outcomes []Outcome `json:"outcomes"`
+}
+
+// Plan is an ordered list of changes.
+type Plan struct {
+	// StopOnError skips the remaining changes after one fails rather than
+	// continuing with them.
+	StopOnError bool
+
+	changes []Change
+	apply   []ApplyFunc
+}
+
+// Add appends a change to the plan.
+func (p *Plan) Add(action, target string, details map[string]interface{}, apply ApplyFunc) {
+	p.changes = append(p.changes, Change{Action: action, Target: target, Details: details})
+	p.apply = append(p.apply, apply)
+}
+
+// Changes lists the planned changes.
+func (p *Plan) Changes() []Change {
+	return append([]Change{}, p.changes...)
+}
+
+// Execute applies each change in order, or just reports them if the context
+// is a dry run. Changes skipped because an earlier one failed are reported
+// as not applied without an error, ones skipped because the context is done
+// report its error.
+func (p *Plan) Execute(ctx context.Context) *Result {
+	result := &Result{DryRun: IsDryRun(ctx), Outcomes: []Outcome{}}
+
+	failed := false
+	for i, change := range p.changes {
+		outcome := Outcome{Change: change}
+
+		if !result.DryRun && !(failed && p.StopOnError) {
+			err := ctx.Err()
+			if err == nil {
+				err = p.apply[i](ctx)
+			}
+			if err != nil {
+				outcome.Error = err.Error()
+				failed = true
+			} else {
+				outcome.Applied = true
+			}
+		}
+
+		result.Outcomes = append(result.Outcomes, outcome)
+	}
+
+	return result
+}
+
+// Err returns the first error in the result, if any.
+func (r *Result) Err() error {
+	for _, outcome := range r.Outcomes {
+		if outcome.Error != "" {
+			return &ChangeError{Outcome: outcome}
+		}
+	}
+
+	return nil
+}
+
+// ChangeError is returned by Result.Err for a failed change.
+type ChangeError struct {
+	Outcome Outcome
+}
+
+func (e *ChangeError) Error() string {
+	return e.Outcome.Action + " " + e.Outcome.Target + ": " + e.Outcome.Error
+}
@@ -0,0 +1,95 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planning
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlan_Execute(t *testing.T) {
+	cases := map[string]struct {
+		DryRun      bool
+		StopOnError bool
+		Expected    []Outcome
+		Applied     []string
+	}{
+		"applies every change": {
+			Expected: []Outcome{
+				{Change: Change{Action: "delete", Target: "a"}, Applied: true},
+				{Change: Change{Action: "delete", Target: "b"}, Error: "b is busy"},
+				{Change: Change{Action: "delete", Target: "c"}, Applied: true},
+			},
+			Applied: []string{"a", "b", "c"},
+		},
+		"stops on error": {
+			StopOnError: true,
+			Expected: []Outcome{
+				{Change: Change{Action: "delete", Target: "a"}, Applied: true},
+				{Change: Change{Action: "delete", Target: "b"}, Error: "b is busy"},
+				{Change: Change{Action: "delete", Target: "c"}},
+			},
+			Applied: []string{"a", "b"},
+		},
+		"dry run": {
+			DryRun: true,
+			Expected: []Outcome{
+				{Change: Change{Action: "delete", Target: "a"}},
+				{Change: Change{Action: "delete", Target: "b"}},
+				{Change: Change{Action: "delete", Target: "c"}},
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			var applied []string
+			changes := &Plan{StopOnError: tc.StopOnError}
+			for _, target := range []string{"a", "b", "c"} {
+				target := target
+				changes.Add("delete", target, nil, func(ctx context.Context) error {
+					applied = append(applied, target)
+					if target == "b" {
+						return errors.New("b is busy")
+					}
+					return nil
+				})
+			}
+
+			ctx := context.Background()
+			if tc.DryRun {
+				ctx = WithDryRun(ctx)
+			}
+
+			result := changes.Execute(ctx)
+			if result.DryRun != tc.DryRun {
+				t.Errorf("Expected DryRun %v, got %v", tc.DryRun, result.DryRun)
+			}
+			if !reflect.DeepEqual(result.Outcomes, tc.Expected) {
+				t.Errorf("Expected outcomes %v, got %v", tc.Expected, result.Outcomes)
+			}
+			if !reflect.DeepEqual(applied, tc.Applied) {
+				t.Errorf("Expected %v to be applied, got %v", tc.Applied, applied)
+			}
+
+			err := result.Err()
+			if tc.DryRun != (err == nil) {
+				t.Errorf("Expected an error only when changes were applied, got %v", err)
+			}
+		})
+	}
+}
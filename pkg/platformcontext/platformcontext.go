@@ -0,0 +1,100 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package platformcontext reads the OSB API context object platforms send
+// with provision, update and bind requests to say where the request comes
+// from, e.g. the Cloud Foundry space or Kubernetes namespace.
+package platformcontext
+
+import (
+	"encoding/json"
+)
+
+// The platforms of the OSB API profile.
+const (
+	CloudFoundry = "cloudfoundry"
+	Kubernetes   = "kubernetes"
+)
+
+// Context holds the fields of the context object defined by the OSB API
+// profile: https://github.com/openservicebrokerapi/servicebroker/blob/master/profile.md
+type Context struct {
+	Platform string `json:"platform,omitempty"`
+
+	// InstanceName is the name the user gave the instance on the platform.
+	InstanceName string `json:"instance_name,omitempty"`
+
+	// Cloud Foundry
+	OrganizationGuid string `json:"organization_guid,omitempty"`
+	OrganizationName string `json:"organization_name,omitempty"`
+	SpaceGuid        string `json:"space_guid,omitempty"`
+	SpaceName        string `json:"space_name,omitempty"`
+
+	// Kubernetes
+	Namespace string `json:"namespace,omitempty"`
+	ClusterId string `json:"clusterid,omitempty"`
+}
+
+// Parse reads the fields it knows from the raw context object of a request.
+// The context is informational, so a malformed one is treated as empty.
+func Parse(raw json.RawMessage) Context {
+	ctx := Context{}
+	if len(raw) > 0 {
+		// values of the wrong type are left out rather than failing the
+		// whole object
+		fields := map[string]interface{}{}
+		_ = json.Unmarshal(raw, &fields)
+
+		ctx.Platform = stringField(fields, "platform")
+		ctx.InstanceName = stringField(fields, "instance_name")
+		ctx.OrganizationGuid = stringField(fields, "organization_guid")
+		ctx.OrganizationName = stringField(fields, "organization_name")
+		ctx.SpaceGuid = stringField(fields, "space_guid")
+		ctx.SpaceName = stringField(fields, "space_name")
+		ctx.Namespace = stringField(fields, "namespace")
+		ctx.ClusterId = stringField(fields, "clusterid")
+	}
+
+	return ctx
+}
+
+func stringField(fields map[string]interface{}, key string) string {
+	value, _ := fields[key].(string)
+	return value
+}
+
+// IsEmpty returns true if the platform sent none of the known fields.
+func (c Context) IsEmpty() bool {
+	return c == Context{}
+}
+
+// Labels returns the labels for the resources of an instance created from
+// the context, keyed by label name. Fields the platform didn't send are left
+// out. The values still need to be sanitized for Google Cloud.
+func (c Context) Labels() map[string]string {
+	labels := map[string]string{}
+	add := func(key, value string) {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+
+	add("platform", c.Platform)
+	add("pcf-organization-name", c.OrganizationName)
+	add("pcf-space-name", c.SpaceName)
+	add("k8s-namespace", c.Namespace)
+	add("k8s-cluster-id", c.ClusterId)
+
+	return labels
+}
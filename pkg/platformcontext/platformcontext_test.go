@@ -0,0 +1,91 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platformcontext
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]struct {
+		Raw      string
+		Expected Context
+	}{
+		"cloud foundry": {
+			Raw: `{"platform":"cloudfoundry","organization_guid":"org-guid","organization_name":"eng","space_guid":"space-guid","space_name":"dev","instance_name":"orders-db","organization_annotations":{"owner":"team-a"}}`,
+			Expected: Context{
+				Platform:         CloudFoundry,
+				InstanceName:     "orders-db",
+				OrganizationGuid: "org-guid",
+				OrganizationName: "eng",
+				SpaceGuid:        "space-guid",
+				SpaceName:        "dev",
+			},
+		},
+		"kubernetes": {
+			Raw:      `{"platform":"kubernetes","namespace":"payments","clusterid":"cluster-1","instance_name":"orders-db"}`,
+			Expected: Context{Platform: Kubernetes, InstanceName: "orders-db", Namespace: "payments", ClusterId: "cluster-1"},
+		},
+		"wrong types": {
+			Raw:      `{"platform":"kubernetes","namespace":42}`,
+			Expected: Context{Platform: Kubernetes},
+		},
+		"malformed": {
+			Raw:      `["cloudfoundry"]`,
+			Expected: Context{},
+		},
+		"empty": {
+			Expected: Context{},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := Parse(json.RawMessage(tc.Raw))
+			if actual != tc.Expected {
+				t.Errorf("Expected %+v, got %+v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestContext_Labels(t *testing.T) {
+	cases := map[string]struct {
+		Context  Context
+		Expected map[string]string
+	}{
+		"cloud foundry": {
+			Context:  Context{Platform: CloudFoundry, OrganizationGuid: "org-guid", OrganizationName: "eng", SpaceName: "dev"},
+			Expected: map[string]string{"platform": "cloudfoundry", "pcf-organization-name": "eng", "pcf-space-name": "dev"},
+		},
+		"kubernetes": {
+			Context:  Context{Platform: Kubernetes, Namespace: "payments", ClusterId: "cluster-1"},
+			Expected: map[string]string{"platform": "kubernetes", "k8s-namespace": "payments", "k8s-cluster-id": "cluster-1"},
+		},
+		"empty": {
+			Expected: map[string]string{},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := tc.Context.Labels(); !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
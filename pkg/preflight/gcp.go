@@ -0,0 +1,67 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
+	serviceusage "google.golang.org/api/serviceusage/v1"
+)
+
+// RequiredApisProp is the viper key of the Google Cloud APIs that must be
+// enabled in the broker's default project.
+const RequiredApisProp = "preflight.required_apis"
+
+func init() {
+	config.Register(config.Property{
+		Key:     RequiredApisProp,
+		Kind:    config.List,
+		Default: []string{"iam.googleapis.com", "cloudresourcemanager.googleapis.com"},
+	})
+}
+
+// EnabledApis checks the given APIs are enabled in the project. The clients
+// must be authorized to call the Service Usage API.
+func EnabledApis(project string, clients *gcpclient.Factory, apis []string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		service, err := serviceusage.NewService(ctx, clients.Options(ctx, "serviceusage")...)
+		if err != nil {
+			return "", err
+		}
+
+		var disabled []string
+		for _, api := range apis {
+			name := fmt.Sprintf("projects/%s/services/%s", project, api)
+			state, err := service.Services.Get(name).Context(ctx).Do()
+			if err != nil {
+				return "", fmt.Errorf("couldn't get the state of %s: %v", api, err)
+			}
+
+			if state.State != "ENABLED" {
+				disabled = append(disabled, api)
+			}
+		}
+
+		if len(disabled) > 0 {
+			return "", fmt.Errorf("%s must be enabled in project %s", strings.Join(disabled, ", "), project)
+		}
+
+		return fmt.Sprintf("%s enabled in project %s", strings.Join(apis, ", "), project), nil
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package preflight runs the checks the broker needs to pass to start and
+// reports every result, so a deployment can be verified before it replaces
+// the running broker.
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Statuses of a Result.
+const (
+	Passed  = "passed"
+	Failed  = "failed"
+	Skipped = "skipped"
+)
+
+// Check is a single startup check.
+type Check struct {
+	Name string
+
+	// Requires holds the names of checks that must pass before this one
+	// runs, e.g. because it uses the database they connected to.
+	Requires []string
+
+	// Run performs the check and returns details to report, e.g. what it
+	// found. Errors created with Skip mark it skipped rather than failed.
+	Run func(ctx context.Context) (string, error)
+}
+
+// Result is the outcome of a Check.
+type Result struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report holds the results of every check in the order they ran.
+type Report struct {
+	Passed bool     `json:"passed"`
+	Checks []Result `json:"checks"`
+}
+
+// skipError is returned by checks that don't apply to the configuration.
+type skipError struct {
+	reason string
+}
+
+func (e *skipError) Error() string {
+	return e.reason
+}
+
+// Skip creates an error that marks a check as skipped for the given reason,
+// e.g. because the broker isn't configured to use what it checks.
+func Skip(format string, args ...interface{}) error {
+	return &skipError{reason: fmt.Sprintf(format, args...)}
+}
+
+// IsSkip returns true if the error was created with Skip.
+func IsSkip(err error) bool {
+	_, ok := err.(*skipError)
+	return ok
+}
+
+// Run runs the checks in order. A check is skipped if one it requires didn't
+// pass. The report only passes if no check failed.
+func Run(ctx context.Context, checks []Check) Report {
+	report := Report{Passed: true}
+	statuses := make(map[string]string)
+
+	for _, check := range checks {
+		result := Result{Name: check.Name}
+
+		var missing []string
+		for _, required := range check.Requires {
+			if statuses[required] != Passed {
+				missing = append(missing, required)
+			}
+		}
+
+		if len(missing) > 0 {
+			result.Status = Skipped
+			result.Detail = fmt.Sprintf("requires %s to pass", strings.Join(missing, ", "))
+		} else {
+			detail, err := check.Run(ctx)
+			switch err.(type) {
+			case nil:
+				result.Status, result.Detail = Passed, detail
+			case *skipError:
+				result.Status, result.Detail = Skipped, err.Error()
+			default:
+				result.Status, result.Detail = Failed, err.Error()
+				report.Passed = false
+			}
+		}
+
+		statuses[check.Name] = result.Status
+		report.Checks = append(report.Checks, result)
+	}
+
+	return report
+}
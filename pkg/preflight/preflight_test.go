@@ -0,0 +1,86 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preflight
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	pass := func(detail string) func(context.Context) (string, error) {
+		return func(context.Context) (string, error) { return detail, nil }
+	}
+	fail := func(context.Context) (string, error) { return "", errors.New("connection refused") }
+	skip := func(context.Context) (string, error) { return "", Skip("%s isn't set", "google.account") }
+
+	cases := map[string]struct {
+		Checks       []Check
+		ExpectPassed bool
+		ExpectChecks []Result
+	}{
+		"all-pass": {
+			Checks: []Check{
+				{Name: "database", Run: pass("")},
+				{Name: "migrations", Requires: []string{"database"}, Run: pass("2 migration(s) pending")},
+			},
+			ExpectPassed: true,
+			ExpectChecks: []Result{
+				{Name: "database", Status: Passed},
+				{Name: "migrations", Status: Passed, Detail: "2 migration(s) pending"},
+			},
+		},
+		"failure-skips-dependents": {
+			Checks: []Check{
+				{Name: "database", Run: fail},
+				{Name: "migrations", Requires: []string{"database"}, Run: pass("")},
+				{Name: "catalog", Run: pass("")},
+			},
+			ExpectPassed: false,
+			ExpectChecks: []Result{
+				{Name: "database", Status: Failed, Detail: "connection refused"},
+				{Name: "migrations", Status: Skipped, Detail: "requires database to pass"},
+				{Name: "catalog", Status: Passed},
+			},
+		},
+		"skipped-passes": {
+			Checks: []Check{
+				{Name: "gcp-credentials", Run: skip},
+				{Name: "gcp-apis", Requires: []string{"gcp-credentials"}, Run: fail},
+			},
+			ExpectPassed: true,
+			ExpectChecks: []Result{
+				{Name: "gcp-credentials", Status: Skipped, Detail: "google.account isn't set"},
+				{Name: "gcp-apis", Status: Skipped, Detail: "requires gcp-credentials to pass"},
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			report := Run(context.Background(), tc.Checks)
+
+			if report.Passed != tc.ExpectPassed {
+				t.Errorf("expected passed: %v, got: %v", tc.ExpectPassed, report.Passed)
+			}
+
+			if !reflect.DeepEqual(report.Checks, tc.ExpectChecks) {
+				t.Errorf("expected results %v, got %v", tc.ExpectChecks, report.Checks)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package preview holds the types used to dry run a provision request: the
+// request goes through the checks a provision does and the resources it would
+// create are listed, without creating anything.
+package preview
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Request describes a hypothetical provision. InstanceId is optional, if it's
+// set the preview checks it isn't in use and it's used to name resources.
+type Request struct {
+	InstanceId string `json:"instance_id,omitempty"`
+	// Service holds the name or ID of a service.
+	Service string `json:"service"`
+	// Plan holds the name or ID of a plan.
+	Plan             string          `json:"plan"`
+	OrganizationGuid string          `json:"organization_guid"`
+	SpaceGuid        string          `json:"space_guid"`
+	Parameters       json.RawMessage `json:"parameters,omitempty"`
+}
+
+// InvalidRequestError is returned for requests that can't be previewed, as
+// opposed to ones that would fail to provision.
+type InvalidRequestError struct {
+	Message string
+}
+
+func (e *InvalidRequestError) Error() string {
+	return e.Message
+}
+
+// Invalidf creates an InvalidRequestError with a formatted message.
+func Invalidf(format string, a ...interface{}) error {
+	return &InvalidRequestError{Message: fmt.Sprintf(format, a...)}
+}
+
+// Validate checks the request sets the fields every provision needs.
+func (r *Request) Validate() error {
+	var missing []string
+	for _, field := range []struct{ name, value string }{
+		{"service", r.Service},
+		{"plan", r.Plan},
+		{"organization_guid", r.OrganizationGuid},
+		{"space_guid", r.SpaceGuid},
+	} {
+		if field.value == "" {
+			missing = append(missing, field.name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return Invalidf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// Check is the outcome of one of the checks a provision goes through. Code is
+// the OSB error code the provision would fail with, if it has one.
+type Check struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+	Code   string `json:"code,omitempty"`
+}
+
+// Resource is a cloud resource the provision would create. Count is nil if
+// the number of copies can only be known once the provision runs.
+type Resource struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Count *int   `json:"count,omitempty"`
+}
+
+// Result reports whether the provision would be accepted. Checks stop at the
+// first failure, since later ones depend on its outcome, and resources are
+// only listed for requests that pass every check.
+type Result struct {
+	InstanceId string  `json:"instance_id"`
+	ServiceId  string  `json:"service_id"`
+	PlanId     string  `json:"plan_id"`
+	Valid      bool    `json:"valid"`
+	Async      bool    `json:"async"`
+	Checks     []Check `json:"checks"`
+	// Variables are the provision variables the service would be given,
+	// with sensitive values redacted.
+	Variables map[string]interface{} `json:"variables,omitempty"`
+	Resources []Resource             `json:"resources,omitempty"`
+}
+
+// Previewer dry runs provision requests.
+type Previewer interface {
+	// PreviewProvision runs the request through the checks a provision does
+	// and lists the resources it would create without creating them.
+	PreviewProvision(ctx context.Context, request Request) (*Result, error)
+}
@@ -0,0 +1,161 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package projects resolves which GCP project service instances are created in
+// and the credentials used to manage each project.
+package projects
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultProjectProp holds the project instances are created in when no
+	// other project is selected.
+	DefaultProjectProp = "gcp.project"
+	// DefaultCredentialsProp holds the credentials for the default project and
+	// any additional project that doesn't have its own.
+	DefaultCredentialsProp = "gcp.credentials"
+	// ProjectsProp holds a JSON object of additional projects instances may be
+	// created in, keyed by project ID.
+	ProjectsProp = "gcp.projects"
+	// MappingProp holds a JSON Mapping of organizations and spaces to the
+	// project their instances are created in by default.
+	MappingProp = "gcp.project_mapping"
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: DefaultProjectProp, Kind: config.String},
+		config.Property{Key: DefaultCredentialsProp, Kind: config.String, Sensitive: true},
+		config.Property{Key: ProjectsProp, Kind: config.Json, Default: "{}", Sensitive: true},
+		config.Property{Key: MappingProp, Kind: config.Json, Default: "{}"},
+	)
+}
+
+// Project holds the settings for an additional project.
+type Project struct {
+	// Credentials holds the service account key used to manage the project. If
+	// blank the default credentials are used.
+	Credentials string `json:"credentials,omitempty"`
+}
+
+// Mapping assigns default projects to organizations and spaces. Space mappings
+// take precedence over organization mappings.
+type Mapping struct {
+	Organizations map[string]string `json:"organizations,omitempty"`
+	Spaces        map[string]string `json:"spaces,omitempty"`
+}
+
+// Additional returns the additional projects configured by the operator.
+func Additional() (map[string]Project, error) {
+	projects := make(map[string]Project)
+	if err := unmarshalProp(ProjectsProp, &projects); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// DefaultFor returns the project instances created in the given organization
+// and space go in if the user doesn't select one.
+func DefaultFor(orgGuid, spaceGuid string) (string, error) {
+	mapping := Mapping{}
+	if err := unmarshalProp(MappingProp, &mapping); err != nil {
+		return "", err
+	}
+
+	mapped, ok := mapping.Spaces[spaceGuid]
+	if !ok || spaceGuid == "" {
+		mapped, ok = mapping.Organizations[orgGuid]
+		ok = ok && orgGuid != ""
+	}
+	if !ok {
+		return viper.GetString(DefaultProjectProp), nil
+	}
+
+	// the mapping can't send instances anywhere the operator doesn't allow
+	if err := Validate(mapped); err != nil {
+		return "", fmt.Errorf("%s: %v", MappingProp, err)
+	}
+
+	return mapped, nil
+}
+
+// Validate returns an error if instances may not be created in the project.
+// Only the default project and additional projects are permitted, a blank
+// project never is.
+func Validate(project string) error {
+	if project == "" {
+		return errors.New("project must not be blank, select one or set gcp.project")
+	}
+
+	if project == viper.GetString(DefaultProjectProp) {
+		return nil
+	}
+
+	additional, err := Additional()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := additional[project]; ok {
+		return nil
+	}
+
+	var allowed []string
+	if defaultProject := viper.GetString(DefaultProjectProp); defaultProject != "" {
+		allowed = append(allowed, defaultProject)
+	}
+	for id := range additional {
+		allowed = append(allowed, id)
+	}
+	sort.Strings(allowed)
+
+	return fmt.Errorf("project %q is not permitted, allowed projects are: [%s]", project, strings.Join(allowed, ", "))
+}
+
+// Credentials returns the credentials used to manage the given project.
+func Credentials(project string) (string, error) {
+	additional, err := Additional()
+	if err != nil {
+		return "", err
+	}
+
+	if p, ok := additional[project]; ok && p.Credentials != "" {
+		return p.Credentials, nil
+	}
+
+	return viper.GetString(DefaultCredentialsProp), nil
+}
+
+func unmarshalProp(prop string, v interface{}) error {
+	raw := viper.GetString(prop)
+	if raw == "" {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return fmt.Errorf("couldn't parse %s: %v", prop, err)
+	}
+
+	return nil
+}
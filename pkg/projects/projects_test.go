@@ -0,0 +1,148 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package projects
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func setConfig(t *testing.T, config map[string]interface{}) func() {
+	t.Helper()
+
+	for k, v := range config {
+		viper.Set(k, v)
+	}
+
+	return func() {
+		for k := range config {
+			viper.Set(k, nil)
+		}
+	}
+}
+
+var testConfig = map[string]interface{}{
+	DefaultProjectProp:     "default-project",
+	DefaultCredentialsProp: "default-creds",
+	ProjectsProp:           `{"team-a":{"credentials":"team-a-creds"},"team-b":{}}`,
+	MappingProp:            `{"organizations":{"org-a":"team-a"},"spaces":{"space-b":"team-b"}}`,
+}
+
+func TestDefaultFor(t *testing.T) {
+	defer setConfig(t, testConfig)()
+
+	cases := map[string]struct {
+		OrgGuid   string
+		SpaceGuid string
+		Expected  string
+	}{
+		"unmapped":            {OrgGuid: "org-z", SpaceGuid: "space-z", Expected: "default-project"},
+		"blank":               {OrgGuid: "", SpaceGuid: "", Expected: "default-project"},
+		"org mapped":          {OrgGuid: "org-a", SpaceGuid: "space-z", Expected: "team-a"},
+		"space mapped":        {OrgGuid: "org-z", SpaceGuid: "space-b", Expected: "team-b"},
+		"space overrides org": {OrgGuid: "org-a", SpaceGuid: "space-b", Expected: "team-b"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := DefaultFor(tc.OrgGuid, tc.SpaceGuid)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != tc.Expected {
+				t.Errorf("Expected project %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestDefaultFor_notPermitted(t *testing.T) {
+	defer setConfig(t, testConfig)()
+	defer setConfig(t, map[string]interface{}{
+		MappingProp: `{"organizations":{"org-a":"someone-elses-project"}}`,
+	})()
+
+	expected := errors.New(`gcp.project_mapping: project "someone-elses-project" is not permitted, allowed projects are: [default-project, team-a, team-b]`)
+	if _, err := DefaultFor("org-a", "space-z"); !reflect.DeepEqual(err, expected) {
+		t.Errorf("Expected error %v, got %v", expected, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	defer setConfig(t, testConfig)()
+
+	cases := map[string]struct {
+		Project     string
+		ExpectedErr error
+	}{
+		"default":    {Project: "default-project", ExpectedErr: nil},
+		"additional": {Project: "team-b", ExpectedErr: nil},
+		"blank": {
+			Project:     "",
+			ExpectedErr: errors.New("project must not be blank, select one or set gcp.project"),
+		},
+		"unknown": {
+			Project:     "someone-elses-project",
+			ExpectedErr: errors.New(`project "someone-elses-project" is not permitted, allowed projects are: [default-project, team-a, team-b]`),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			err := Validate(tc.Project)
+			if !reflect.DeepEqual(err, tc.ExpectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.ExpectedErr, err)
+			}
+		})
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	defer setConfig(t, testConfig)()
+
+	cases := map[string]struct {
+		Project  string
+		Expected string
+	}{
+		"default":             {Project: "default-project", Expected: "default-creds"},
+		"own credentials":     {Project: "team-a", Expected: "team-a-creds"},
+		"inherit credentials": {Project: "team-b", Expected: "default-creds"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := Credentials(tc.Project)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != tc.Expected {
+				t.Errorf("Expected credentials %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestAdditional_BadConfig(t *testing.T) {
+	defer setConfig(t, map[string]interface{}{ProjectsProp: "not-json"})()
+
+	if _, err := Additional(); err == nil {
+		t.Error("Expected an error for malformed config, got nil")
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protection holds the types used to protect service instances from
+// being deprovisioned, e.g. production databases, until an operator lifts the
+// protection.
+package protection
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Sources of an instance's protection.
+const (
+	// SourceInstance means an operator set the instance's protection, it
+	// takes precedence over its plan's.
+	SourceInstance = "instance"
+
+	// SourcePlan means the instance's plan is configured to be protected.
+	SourcePlan = "plan"
+)
+
+// ErrInstanceNotFound is returned for instances the broker doesn't manage.
+var ErrInstanceNotFound = errors.New("instance not found")
+
+// ErrMissingProtected is returned for changes that don't say whether the
+// instance is protected.
+var ErrMissingProtected = errors.New("protected must be set")
+
+// Status describes whether an instance can be deprovisioned. Source is empty
+// for unprotected instances that neither an operator nor their plan protected.
+type Status struct {
+	InstanceId string     `json:"instance_id"`
+	Protected  bool       `json:"protected"`
+	Source     string     `json:"source,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// Change protects or unprotects an instance regardless of its plan. Reason is
+// shown to anyone whose deprovision is rejected.
+type Change struct {
+	Protected *bool  `json:"protected"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Validate checks the change says whether the instance is protected.
+func (c *Change) Validate() error {
+	if c.Protected == nil {
+		return ErrMissingProtected
+	}
+
+	return nil
+}
+
+// Protector gets and sets the deletion protection of instances.
+type Protector interface {
+	// GetProtection returns whether the instance is protected and why.
+	GetProtection(ctx context.Context, instanceId string) (*Status, error)
+
+	// SetProtection overrides the instance's protection and returns the new
+	// status.
+	SetProtection(ctx context.Context, instanceId string, change Change) (*Status, error)
+}
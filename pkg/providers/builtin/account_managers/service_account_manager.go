@@ -23,6 +23,8 @@ import (
 	"code.cloudfoundry.org/lager"
 	"github.com/pivotal/cloud-service-broker/db_service/models"
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/retry"
+	"github.com/pivotal/cloud-service-broker/pkg/tracing"
 	"github.com/pivotal/cloud-service-broker/pkg/validation"
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
 
@@ -39,6 +41,16 @@ const (
 	projectResourcePrefix = "projects/"
 )
 
+// iamPolicyRetryPolicy controls retries of read-modify-write cycles on the
+// project IAM policy, which conflict if another writer changes it concurrently.
+var iamPolicyRetryPolicy = retry.Policies.Policy("iam-policy", "Updating the project IAM policy when built-in services bind and unbind.", retry.Defaults{
+	MaxAttempts:    3,
+	InitialBackoff: 5 * time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Retryable:      []retry.ErrorClass{retry.Conflict, retry.RateLimit, retry.ServerError},
+})
+
 type ServiceAccountManager struct {
 	ProjectId  string
 	HttpConfig *jwt.Config
@@ -99,7 +111,7 @@ func (sam *ServiceAccountManager) DeleteCredentials(ctx context.Context, binding
 		return fmt.Errorf("Error unmarshalling credentials: %s", err)
 	}
 
-	iamService, err := iam.New(sam.HttpConfig.Client(ctx))
+	iamService, err := iam.New(sam.HttpConfig.Client(tracing.ClientContext(ctx)))
 	if err != nil {
 		return fmt.Errorf("Error creating IAM service: %s", err)
 	}
@@ -122,7 +134,7 @@ func (sam *ServiceAccountManager) DeleteCredentials(ctx context.Context, binding
 }
 
 func (sam *ServiceAccountManager) createServiceAccount(ctx context.Context, accountId, displayName string) (*iam.ServiceAccount, error) {
-	client := sam.HttpConfig.Client(ctx)
+	client := sam.HttpConfig.Client(tracing.ClientContext(ctx))
 	iamService, err := iam.New(client)
 	if err != nil {
 		return nil, fmt.Errorf("Error creating new IAM service: %s", err)
@@ -142,7 +154,7 @@ func (sam *ServiceAccountManager) createServiceAccount(ctx context.Context, acco
 }
 
 func (sam *ServiceAccountManager) createServiceAccountKey(ctx context.Context, account *iam.ServiceAccount) (*iam.ServiceAccountKey, error) {
-	client := sam.HttpConfig.Client(ctx)
+	client := sam.HttpConfig.Client(tracing.ClientContext(ctx))
 	iamService, err := iam.New(client)
 	if err != nil {
 		return nil, fmt.Errorf("Error creating new IAM service: %s", err)
@@ -153,81 +165,62 @@ func (sam *ServiceAccountManager) createServiceAccountKey(ctx context.Context, a
 }
 
 func (sam *ServiceAccountManager) grantRoleToAccount(ctx context.Context, role string, account *iam.ServiceAccount) error {
-	client := sam.HttpConfig.Client(ctx)
-
-	cloudresService, err := cloudres.New(client)
-	if err != nil {
-		return fmt.Errorf("Error creating new cloud resource management service: %s", err)
-	}
-
-	for attempt := 0; attempt < 3; attempt++ {
-		currPolicy, err := cloudresService.Projects.GetIamPolicy(sam.ProjectId, &cloudres.GetIamPolicyRequest{}).Do()
-		if err != nil {
-			return fmt.Errorf("Error getting current project iam policy: %s", err)
-		}
+	return sam.grantRoleToMember(ctx, role, saResourcePrefix+account.Email)
+}
 
-		currPolicy.Bindings = mergeBindings(append(currPolicy.Bindings, &cloudres.Binding{
-			Members: []string{saResourcePrefix + account.Email},
+// grantRoleToMember adds the given IAM member (e.g. "serviceAccount:foo@...")
+// to the project policy with the given role.
+func (sam *ServiceAccountManager) grantRoleToMember(ctx context.Context, role, member string) error {
+	err := sam.modifyProjectPolicy(ctx, func(bindings []*cloudres.Binding) []*cloudres.Binding {
+		return mergeBindings(append(bindings, &cloudres.Binding{
+			Members: []string{member},
 			Role:    roleResourcePrefix + role,
 		}))
+	})
+	if err != nil {
+		return fmt.Errorf("Error assigning policy to service account: %s", err)
+	}
 
-		newPolicyRequest := cloudres.SetIamPolicyRequest{
-			Policy: currPolicy,
-		}
-		_, err = cloudresService.Projects.SetIamPolicy(sam.ProjectId, &newPolicyRequest).Do()
-		if err == nil {
-			return nil
-		}
+	return nil
+}
 
-		if isConflictError(err) {
-			time.Sleep(5 * time.Second)
-			continue
-		} else {
-			return fmt.Errorf("Error assigning policy to service account: %s", err)
-		}
+func (sam *ServiceAccountManager) revokeRolesFromAccount(ctx context.Context, email string) error {
+	err := sam.modifyProjectPolicy(ctx, func(bindings []*cloudres.Binding) []*cloudres.Binding {
+		return mergeBindings(removeMemberFromBindings(bindings, saResourcePrefix+email))
+	})
+	if err != nil {
+		return fmt.Errorf("Error updating iam policy: %s", err)
 	}
 
-	return err
+	return nil
 }
 
-func (sam *ServiceAccountManager) revokeRolesFromAccount(ctx context.Context, email string) error {
-	client := sam.HttpConfig.Client(ctx)
+// modifyProjectPolicy does a read-modify-write of the project's IAM policy,
+// retrying according to iamPolicyRetryPolicy if another writer changed the
+// policy in the meantime.
+func (sam *ServiceAccountManager) modifyProjectPolicy(ctx context.Context, modify func([]*cloudres.Binding) []*cloudres.Binding) error {
+	client := sam.HttpConfig.Client(tracing.ClientContext(ctx))
 
 	cloudresService, err := cloudres.New(client)
 	if err != nil {
 		return fmt.Errorf("Error creating new cloud resource management service: %s", err)
 	}
 
-	for attempt := 0; attempt < 3; attempt++ {
+	return iamPolicyRetryPolicy.Do(ctx, func() error {
 		currPolicy, err := cloudresService.Projects.GetIamPolicy(sam.ProjectId, &cloudres.GetIamPolicyRequest{}).Do()
 		if err != nil {
-			return fmt.Errorf("Error getting current project iam policy: %s", err)
+			return fmt.Errorf("Error getting current project iam policy: %w", err)
 		}
 
-		currPolicy.Bindings = mergeBindings(removeMemberFromBindings(currPolicy.Bindings, saResourcePrefix+email))
+		currPolicy.Bindings = modify(currPolicy.Bindings)
 
 		newPolicyRequest := cloudres.SetIamPolicyRequest{
 			Policy: currPolicy,
 		}
 
 		_, err = cloudresService.Projects.SetIamPolicy(sam.ProjectId, &newPolicyRequest).Do()
-		if err == nil {
-			return nil
-		}
-
-		if isConflictError(err) {
-			time.Sleep(5 * time.Second)
-			continue
-		} else {
-			return fmt.Errorf("Error updating iam policy: %s", err)
-		}
-	}
-	return err
-}
-
-func isConflictError(err error) bool {
-	gerr, ok := err.(*googleapi.Error)
-	return ok && gerr != nil && gerr.Code == 409
+		return err
+	})
 }
 
 type ServiceAccountInfo struct {
@@ -263,7 +256,7 @@ func ServiceAccountWhitelistWithDefault(whitelist []string, defaultValue string)
 func ServiceAccountBindComputedVariables() []varcontext.DefaultVariable {
 	return []varcontext.DefaultVariable{
 		// XXX names are truncated to 20 characters because of a bug in the IAM service
-		{Name: "service_account_name", Default: `${str.truncate(20, "pcf-binding-${request.binding_id}")}`, Overwrite: true},
+		{Name: "service_account_name", Default: `${str.truncate(30, "pcf-binding-${request.binding_id}")}`, Overwrite: true},
 		{Name: "service_account_display_name", Default: "${service_account_name}", Overwrite: true},
 	}
 }
@@ -19,12 +19,12 @@ import (
 
 	googlestorage "cloud.google.com/go/storage"
 	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin/base"
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
-	"github.com/pivotal/cloud-service-broker/utils"
 	"github.com/pivotal-cf/brokerapi"
 	"golang.org/x/net/context"
-	"google.golang.org/api/option"
 )
 
 // StorageBroker is the service-broker back-end for creating and binding to
@@ -122,10 +122,22 @@ func (b *StorageBroker) Update(ctx context.Context, provisionContext *varcontext
 	return models.ServiceInstanceDetails{}, fmt.Errorf("Update unsupported")
 }
 
+// PlanCapabilities implements broker.CapabilityReporter, buckets can't be
+// updated.
+func (b *StorageBroker) PlanCapabilities(plan broker.ServicePlan, derived broker.Capabilities) broker.Capabilities {
+	derived.Update = false
+	derived.PlanUpdate = false
+	derived.UpdatableFields = []string{}
+	return derived
+}
+
 func (b *StorageBroker) createClient(ctx context.Context) (*googlestorage.Client, error) {
-	co := option.WithUserAgent(utils.CustomUserAgent)
-	//ct := option.WithTokenSource(b.HttpConfig.TokenSource(ctx))
-	storageService, err := googlestorage.NewClient(ctx, co/*, ct*/)
+	clients, err := gcpclient.NewFactoryFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	storageService, err := googlestorage.NewClient(ctx, clients.Options(ctx, "storage")...)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't instantiate Cloud Storage API client: %s", err)
 	}
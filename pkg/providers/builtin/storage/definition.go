@@ -136,6 +136,12 @@ func ServiceDefinition() *broker.ServiceDefinition {
 		ProvisionComputedVariables: []varcontext.DefaultVariable{
 			{Name: "labels", Default: "${json.marshal(request.default_labels)}", Overwrite: true},
 		},
+		RequiredRoles: []string{
+			"roles/storage.admin",
+			"roles/iam.serviceAccountAdmin",
+			"roles/iam.serviceAccountKeyAdmin",
+			"roles/resourcemanager.projectIamAdmin",
+		},
 		DefaultRoleWhitelist: roleWhitelist,
 		BindInputVariables:   accountmanagers.ServiceAccountWhitelistWithDefault(roleWhitelist, "storage.objectAdmin"),
 		BindOutputVariables: append(accountmanagers.ServiceAccountBindOutputVariables(),
@@ -210,7 +216,7 @@ func ServiceDefinition() *broker.ServiceDefinition {
 			},
 		},
 		BindComputedVariables: accountmanagers.ServiceAccountBindComputedVariables(),
-		ProviderBuilder: func(logger lager.Logger) broker.ServiceProvider {
+		ProviderBuilder: func(logger lager.Logger, store broker.ProviderStore) broker.ServiceProvider {
 			bb := base.NewBrokerBase(os.Getenv("GOOGLE_CLOUD_PROJECT"), logger)
 			return &StorageBroker{BrokerBase: bb}
 		},
@@ -18,10 +18,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path"
+	"sort"
 
 	"code.cloudfoundry.org/lager"
 	"github.com/pivotal-cf/brokerapi"
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/config/secrets"
+	"github.com/pivotal/cloud-service-broker/pkg/jobs"
+	"github.com/pivotal/cloud-service-broker/pkg/naming"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/tf/wrapper"
 	"github.com/pivotal/cloud-service-broker/pkg/validation"
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
@@ -45,6 +49,9 @@ type TfServiceDefinitionV1 struct {
 	BindSettings      TfServiceDefinitionV1Action `yaml:"bind"`
 	Examples          []broker.ServiceExample     `yaml:"examples"`
 	PlanUpdateable    bool						  `yaml:"plan_updateable"`
+	Shareable         bool                        `yaml:"shareable,omitempty"`
+	ResourceName      *naming.Rule                `yaml:"resource_name,omitempty"`
+	RequiredRoles     []string                    `yaml:"required_roles,omitempty"`
 
 	// Internal SHOULD be set to true for Google maintained services.
 	Internal bool `yaml:"-"`
@@ -54,15 +61,17 @@ type TfServiceDefinitionV1 struct {
 // TfServiceDefinitionV1Plan represents a service plan in a human-friendly format
 // that can be converted into an OSB compatible plan.
 type TfServiceDefinitionV1Plan struct {
-	Name               string                 `yaml:"name"`
-	Id                 string                 `yaml:"id"`
-	Description        string                 `yaml:"description"`
-	DisplayName        string                 `yaml:"display_name"`
-	Bullets            []string               `yaml:"bullets,omitempty"`
-	Free               bool                   `yaml:"free,omitempty"`
-	Properties         map[string]interface{} `yaml:"properties"`
-	ProvisionOverrides map[string]interface{} `yaml:"provision_overrides,omitempty"`
-	BindOverrides      map[string]interface{} `yaml:"bind_overrides,omitempty"`
+	Name               string                            `yaml:"name"`
+	Id                 string                            `yaml:"id"`
+	Description        string                            `yaml:"description"`
+	DisplayName        string                            `yaml:"display_name"`
+	Bullets            []string                          `yaml:"bullets,omitempty"`
+	Free               bool                              `yaml:"free,omitempty"`
+	Properties         map[string]interface{}            `yaml:"properties"`
+	ProvisionDefaults  map[string]interface{}            `yaml:"provision_defaults,omitempty"`
+	ProvisionOverrides map[string]interface{}            `yaml:"provision_overrides,omitempty"`
+	BindOverrides      map[string]interface{}            `yaml:"bind_overrides,omitempty"`
+	Constraints        map[string]map[string]interface{} `yaml:"constraints,omitempty"`
 }
 
 var _ validation.Validatable = (*TfServiceDefinitionV1Plan)(nil)
@@ -93,8 +102,10 @@ func (plan *TfServiceDefinitionV1Plan) ToPlan() broker.ServicePlan {
 	return broker.ServicePlan{
 		ServicePlan:        masterPlan,
 		ServiceProperties:  plan.Properties,
+		ProvisionDefaults:  plan.ProvisionDefaults,
 		ProvisionOverrides: plan.ProvisionOverrides,
 		BindOverrides:      plan.BindOverrides,
+		Constraints:        plan.Constraints,
 	}
 }
 
@@ -113,6 +124,8 @@ type TfServiceDefinitionV1Action struct {
 	ImportParameterMappings []ImportParameterMapping `yaml:"import_parameter_mappings"`
 	ImportParametersToDelete []string       `yaml:"import_parameters_to_delete"`
 	ImportParametersToAdd []ImportParameterMapping `yaml:"import_parameters_to_add"`
+	AdoptResources []AdoptResource          `yaml:"adopt_resources,omitempty"`
+	Seeds []TfSeed                          `yaml:"seeds,omitempty"`
 }
 
 var _ validation.Validatable = (*TfServiceDefinitionV1Action)(nil)
@@ -127,6 +140,48 @@ func (action *TfServiceDefinitionV1Action) IsTfImport(provisionContext *varconte
 	return false
 }
 
+// roleWhitelist returns the enumerated values of the "role" user input if the
+// action has one.
+func (action *TfServiceDefinitionV1Action) roleWhitelist() []string {
+	var roles []string
+	for _, input := range action.UserInputs {
+		if input.FieldName != "role" {
+			continue
+		}
+
+		for role := range input.Enum {
+			roles = append(roles, fmt.Sprintf("%v", role))
+		}
+	}
+
+	sort.Strings(roles)
+	return roles
+}
+
+// hasUserInput returns true if the action has a user input with the given
+// field name.
+func (action *TfServiceDefinitionV1Action) hasUserInput(fieldName string) bool {
+	for _, input := range action.UserInputs {
+		if input.FieldName == fieldName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasComputedInput returns true if the action has a computed input with the
+// given name.
+func (action *TfServiceDefinitionV1Action) hasComputedInput(name string) bool {
+	for _, input := range action.Computed {
+		if input.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 func loadTemplate(templatePath string) (string, error) {
 	if templatePath == "" {
 		return "", nil
@@ -163,6 +218,12 @@ func (action *TfServiceDefinitionV1Action) LoadTemplate(srcDir string) error {
 		}
 	}
 
+	for i := range action.Seeds {
+		if err := action.Seeds[i].LoadFiles(srcDir); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -194,6 +255,23 @@ func (action *TfServiceDefinitionV1Action) Validate() (errs *validation.FieldErr
 		errs = errs.Also(v.Validate().ViaFieldIndex("outputs", i))
 	}
 
+	inputs := action.inputNames()
+	for i, v := range action.AdoptResources {
+		errs = errs.Also(v.Validate().ViaFieldIndex("adopt_resources", i))
+		if v.FieldName != "" && !inputs.Contains(v.FieldName) {
+			errs = errs.Also(validation.ErrInvalidValue(v.FieldName, fmt.Sprintf("adopt_resources[%d].field_name", i)))
+		}
+	}
+
+	seedNames := utils.NewStringSet()
+	for i, v := range action.Seeds {
+		errs = errs.Also(v.Validate().ViaFieldIndex("seeds", i))
+		if seedNames.Contains(v.Name) {
+			errs = errs.Also(validation.ErrInvalidValue(v.Name, fmt.Sprintf("seeds[%d].name", i)))
+		}
+		seedNames.Add(v.Name)
+	}
+
 	return errs
 }
 
@@ -207,19 +285,7 @@ func (action *TfServiceDefinitionV1Action) ValidateTemplateIO() (errs *validatio
 // validateTemplateInputs checks that all the inputs of the Terraform template
 // are defined by the service.
 func (action *TfServiceDefinitionV1Action) validateTemplateInputs() (errs *validation.FieldError) {
-	inputs := utils.NewStringSet()
-
-	for _, in := range action.PlanInputs {
-		inputs.Add(in.FieldName)
-	}
-
-	for _, in := range action.UserInputs {
-		inputs.Add(in.FieldName)
-	}
-
-	for _, in := range action.Computed {
-		inputs.Add(in.Name)
-	}
+	inputs := action.inputNames()
 
 	tfModule := wrapper.ModuleDefinition{Definition: action.Template, Definitions: action.Templates}
 	tfIn, err := tfModule.Inputs()
@@ -290,6 +356,13 @@ func (tfb *TfServiceDefinitionV1) Validate() (errs *validation.FieldError) {
 	}
 
 	errs = errs.Also(tfb.ProvisionSettings.Validate().ViaField("provision"))
+
+	if tfb.ResourceName != nil {
+		errs = errs.Also(tfb.ResourceName.Validate().ViaField("resource_name"))
+		if !tfb.ProvisionSettings.hasUserInput(tfb.ResourceName.Variable) {
+			errs = errs.Also(validation.ErrInvalidValue(tfb.ResourceName.Variable, "resource_name.variable"))
+		}
+	}
 	errs = errs.Also(tfb.BindSettings.Validate().ViaField("bind"))
 
 	for i, v := range tfb.Examples {
@@ -303,6 +376,9 @@ func (tfb *TfServiceDefinitionV1) resolveEnvVars() (map[string]string, error) {
 	vars := make(map[string]string)
 	for _, v := range tfb.RequiredEnvVars {
 		viper.BindEnv(v, v)
+		if err := secrets.DecryptEnv(v); err != nil {
+			return vars, err
+		}
 		if !viper.IsSet(v) {
 			return vars, fmt.Errorf(fmt.Sprintf("missing required env var %s", v))
 		}
@@ -363,13 +439,22 @@ func (tfb *TfServiceDefinitionV1) ToService(executor wrapper.TerraformExecutor)
 		Overwrite: true,
 	})
 
+	// the service's queued jobs can be picked up by any broker that has it
+	// even if the job wasn't created there
+	jobKind := JobKindPrefix + tfb.Id
+	handler := NewTfJobRunnerForProject(envVars)
+	handler.Executor = executor
+	jobs.Default.Register(jobKind, handler.RunJob)
+
 	constDefn := *tfb
-	return &broker.ServiceDefinition{
+	def := &broker.ServiceDefinition{
 		Id:               tfb.Id,
 		Name:             tfb.Name,
 		Description:      tfb.Description,
 		Bindable:         true,
 		PlanUpdateable:   tfb.PlanUpdateable,
+		Shareable:        tfb.Shareable,
+		ResourceName:     tfb.ResourceName,
 		DisplayName:      tfb.DisplayName,
 		DocumentationUrl: tfb.DocumentationUrl,
 		SupportUrl:       tfb.SupportUrl,
@@ -377,23 +462,32 @@ func (tfb *TfServiceDefinitionV1) ToService(executor wrapper.TerraformExecutor)
 		Tags:             tfb.Tags,
 		Plans:            rawPlans,
 
-		ProvisionInputVariables: tfb.ProvisionSettings.UserInputs,
+		ProvisionInputVariables: tfb.ProvisionSettings.userInputs(),
 		ProvisionComputedVariables: append(tfb.ProvisionSettings.Computed, varcontext.DefaultVariable{
 			Name:      "tf_id",
 			Default:   "tf:${request.instance_id}:",
 			Overwrite: true,
 		}),
-		BindInputVariables:    tfb.BindSettings.UserInputs,
+		BindInputVariables:    tfb.BindSettings.userInputs(),
 		BindComputedVariables: bindComputed,
 		BindOutputVariables:   append(tfb.ProvisionSettings.Outputs, tfb.BindSettings.Outputs...),
 		PlanVariables:         append(tfb.ProvisionSettings.PlanInputs, tfb.BindSettings.PlanInputs...),
 		Examples:              tfb.Examples,
-		ProviderBuilder: func(logger lager.Logger) broker.ServiceProvider {
-			jobRunner := NewTfJobRunnerForProject(envVars)
-			jobRunner.Executor = executor
-			return NewTerraformProvider(jobRunner, logger, constDefn)
-		},
-	}, nil
+		DefaultRoleWhitelist:  tfb.BindSettings.roleWhitelist(),
+		KeylessBind:           tfb.BindSettings.hasComputedInput("keyless_principal"),
+		RequiredRoles:         tfb.RequiredRoles,
+	}
+
+	def.ProviderBuilder = func(logger lager.Logger, store broker.ProviderStore) broker.ServiceProvider {
+		jobRunner := NewTfJobRunnerForProject(envVars)
+		jobRunner.Executor = executor
+		jobRunner.JobKind = jobKind
+		jobRunner.Store = store
+		jobRunner.Timeout = def.OperationTimeout
+		return NewTerraformProvider(jobRunner, logger, constDefn)
+	}
+
+	return def, nil
 }
 
 // generateTfId creates a unique id for a given provision/bind combination that
@@ -510,6 +604,44 @@ func NewExampleTfServiceDefinition() TfServiceDefinitionV1 {
 	}
 }
 
+// inputNames returns the names of the plan, user and computed inputs of the
+// action.
+func (action *TfServiceDefinitionV1Action) inputNames() utils.StringSet {
+	inputs := utils.NewStringSet()
+
+	for _, in := range action.PlanInputs {
+		inputs.Add(in.FieldName)
+	}
+
+	for _, in := range action.UserInputs {
+		inputs.Add(in.FieldName)
+	}
+
+	for _, in := range action.Computed {
+		inputs.Add(in.Name)
+	}
+
+	return inputs
+}
+
+// AdoptResource is a resource of the provision template that can already
+// exist when an instance is imported. It's imported using the value of the
+// named input as its ID instead of being created.
+type AdoptResource struct {
+	TfResource string `yaml:"tf_resource"`
+	FieldName  string `yaml:"field_name"`
+}
+
+var _ validation.Validatable = (*AdoptResource)(nil)
+
+// Validate implements validation.Validatable.
+func (resource *AdoptResource) Validate() (errs *validation.FieldError) {
+	return errs.Also(
+		validation.ErrIfBlank(resource.TfResource, "tf_resource"),
+		validation.ErrIfBlank(resource.FieldName, "field_name"),
+	)
+}
+
 // ImportVariable Variable definition for TF import support
 type ImportVariable struct {
 	Name string			`yaml:"field_name"`
@@ -21,8 +21,10 @@ import (
     "strings"
     "testing"
 
+    "code.cloudfoundry.org/lager"
     "github.com/go-yaml/yaml"
     "github.com/pivotal/cloud-service-broker/pkg/broker"
+    "github.com/pivotal/cloud-service-broker/pkg/naming"
     "github.com/pivotal/cloud-service-broker/pkg/varcontext"
     "github.com/pivotal-cf/brokerapi"
 )
@@ -755,6 +757,7 @@ func TestTfServiceDefinitionV1_ToService(t *testing.T) {
             {Name: "tf_id", Default: "tf:${request.instance_id}:${request.binding_id}", Overwrite: true, Type: ""},
         }, service.BindComputedVariables)
         expectEqual("BindOutputVariables", append(definition.ProvisionSettings.Outputs, definition.BindSettings.Outputs...), service.BindOutputVariables)
+        expectEqual("KeylessBind", false, service.KeylessBind)
     })
 
     t.Run("examples", func(t *testing.T) {
@@ -767,3 +770,120 @@ func TestTfServiceDefinitionV1_ToService(t *testing.T) {
         }
     })
 }
+
+func TestTerraformProvider_PlanCapabilities(t *testing.T) {
+	derived := broker.Capabilities{Update: true, UpdatableFields: []string{"name"}, Bind: true, AsyncProvision: true}
+
+	cases := map[string]struct {
+		Properties map[string]interface{}
+		Expected   broker.Capabilities
+	}{
+		"regular plan": {
+			Properties: map[string]interface{}{"subsume": false},
+			Expected:   derived,
+		},
+		"subsume plan": {
+			Properties: map[string]interface{}{"subsume": true},
+			Expected:   broker.Capabilities{Update: false, UpdatableFields: []string{}, Bind: true, AsyncProvision: true, Import: true},
+		},
+	}
+
+	adopting := NewTerraformProvider(nil, lager.NewLogger("test"), TfServiceDefinitionV1{
+		ProvisionSettings: TfServiceDefinitionV1Action{
+			AdoptResources: []AdoptResource{{TfResource: "google_storage_bucket.bucket", FieldName: "name"}},
+		},
+	})
+	expected := broker.Capabilities{Update: true, UpdatableFields: []string{"name"}, Bind: true, AsyncProvision: true, Import: true}
+	if actual := adopting.(broker.CapabilityReporter).PlanCapabilities(broker.ServicePlan{}, derived); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected plans of services with adopt_resources to import: %#v Actual: %#v", expected, actual)
+	}
+
+	provider := NewTerraformProvider(nil, lager.NewLogger("test"), TfServiceDefinitionV1{})
+	reporter, ok := provider.(broker.CapabilityReporter)
+	if !ok {
+		t.Fatal("Expected the terraform provider to report its capabilities")
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := reporter.PlanCapabilities(broker.ServicePlan{ServiceProperties: tc.Properties}, derived)
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected: %#v Actual: %#v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestTfServiceDefinitionV1Action_ValidateAdoptResources(t *testing.T) {
+	cases := map[string]struct {
+		AdoptResources []AdoptResource
+		ExpectedErr    string
+	}{
+		"valid": {
+			AdoptResources: []AdoptResource{{TfResource: "google_storage_bucket.bucket", FieldName: "name"}},
+		},
+		"undeclared field": {
+			AdoptResources: []AdoptResource{{TfResource: "google_storage_bucket.bucket", FieldName: "bucket_id"}},
+			ExpectedErr:    "invalid value: bucket_id: adopt_resources[0].field_name",
+		},
+		"missing resource": {
+			AdoptResources: []AdoptResource{{FieldName: "name"}},
+			ExpectedErr:    "missing field(s): adopt_resources[0].tf_resource",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			action := TfServiceDefinitionV1Action{
+				UserInputs: []broker.BrokerVariable{{FieldName: "name", Type: broker.JsonTypeString, Details: "name"}},
+				Template: `
+				variable name {type = "string"}
+				`,
+				AdoptResources: tc.AdoptResources,
+			}
+
+			err := action.Validate()
+			switch {
+			case tc.ExpectedErr == "" && err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			case tc.ExpectedErr != "" && (err == nil || !strings.Contains(err.Error(), tc.ExpectedErr)):
+				t.Errorf("Expected error containing %q, got %v", tc.ExpectedErr, err)
+			}
+		})
+	}
+}
+
+func TestTfServiceDefinitionV1_ValidateResourceName(t *testing.T) {
+	cases := map[string]struct {
+		Rule      naming.Rule
+		ExpectErr string
+	}{
+		"valid": {
+			Rule: naming.Rule{Variable: "username", Product: "storage", Template: "csb-{{.instance_id}}"},
+		},
+		"not a user input": {
+			Rule:      naming.Rule{Variable: "domain", Product: "storage", Template: "csb-{{.instance_id}}"},
+			ExpectErr: "invalid value: domain: resource_name.variable",
+		},
+		"bad template": {
+			Rule:      naming.Rule{Variable: "username", Product: "storage", Template: "csb-{{.instance}}"},
+			ExpectErr: "resource_name.template",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			definition := NewExampleTfServiceDefinition()
+			rule := tc.Rule
+			definition.ResourceName = &rule
+
+			err := definition.Validate()
+			switch {
+			case tc.ExpectErr == "" && err != nil:
+				t.Errorf("expected no error, got %v", err)
+			case tc.ExpectErr != "" && (err == nil || !strings.Contains(err.Error(), tc.ExpectErr)):
+				t.Errorf("expected error containing %q, got %v", tc.ExpectErr, err)
+			}
+		})
+	}
+}
@@ -16,21 +16,31 @@ package tf
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"code.cloudfoundry.org/lager"
 	"github.com/pivotal/cloud-service-broker/db_service/models"
-	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/deprovision"
+	"github.com/pivotal/cloud-service-broker/pkg/jobs"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/tf/wrapper"
+	"github.com/pivotal/cloud-service-broker/pkg/tracing"
 	"github.com/pivotal/cloud-service-broker/utils"
+	"go.opencensus.io/trace"
 )
 
 const (
 	InProgress = "in progress"
 	Succeeded  = "succeeded"
 	Failed     = "failed"
+
+	// JobKindPrefix is prepended to a service's ID to get the kind of its
+	// queued Terraform jobs.
+	JobKindPrefix = "terraform:"
 )
 
 // NewTfJobRunerFromEnv creates a new TfJobRunner with default configuration values.
@@ -61,6 +71,62 @@ type TfJobRunner struct {
 	EnvVars map[string]string
 	// Executor holds a custom executor that will be called when commands are run.
 	Executor wrapper.TerraformExecutor
+	// Queue runs the jobs in the background, jobs.Default is used if it's nil.
+	Queue *jobs.Queue
+	// Store holds the deployments, e.g. the broker's datastore. If it's nil
+	// the database of the runner's queue is used, so jobs read the
+	// deployments kept with them.
+	Store broker.ProviderStore
+	// JobKind is the kind of the queued jobs, the RunJob function of a runner
+	// for the same project must be registered as their handler.
+	JobKind string
+	// Reconciler checks and retries destroys, deprovision.Default is used if
+	// it's nil.
+	Reconciler *deprovision.Reconciler
+	// Timeout, if set, gets the maximum duration of an operation, e.g.
+	// provision or bind. Operations that run longer are failed by Status.
+	// Zero means no limit.
+	Timeout func(operation string) time.Duration
+}
+
+// queue gets the queue the runner's jobs are in.
+func (runner *TfJobRunner) queue() *jobs.Queue {
+	if runner.Queue != nil {
+		return runner.Queue
+	}
+
+	return jobs.Default
+}
+
+// store gets the store holding the runner's deployments.
+func (runner *TfJobRunner) store() (broker.ProviderStore, error) {
+	if runner.Store != nil {
+		return runner.Store, nil
+	}
+
+	if store, ok := runner.queue().Database.(broker.ProviderStore); ok {
+		return store, nil
+	}
+
+	return nil, errors.New("the job runner has no store for Terraform deployments")
+}
+
+func (runner *TfJobRunner) getDeployment(ctx context.Context, id string) (*models.TerraformDeployment, error) {
+	store, err := runner.store()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.GetTerraformDeploymentById(ctx, id)
+}
+
+func (runner *TfJobRunner) saveDeployment(ctx context.Context, deployment *models.TerraformDeployment) error {
+	store, err := runner.store()
+	if err != nil {
+		return err
+	}
+
+	return store.SaveTerraformDeployment(ctx, deployment)
 }
 
 // StageJob stages a job to be executed. Before the workspace is saved to the
@@ -73,7 +139,7 @@ func (runner *TfJobRunner) StageJob(ctx context.Context, jobId string, workspace
 		return err
 	}
 
-	if deployment, err := db_service.GetTerraformDeploymentById(ctx, jobId); err == nil {
+	if deployment, err := runner.getDeployment(ctx, jobId); err == nil {
 		// deployment exists, update
 		deployment.Workspace = workspaceString
 		deployment.LastOperationType = "validation"
@@ -94,7 +160,7 @@ func (runner *TfJobRunner) markJobStarted(ctx context.Context, deployment *model
 	deployment.LastOperationState = InProgress
 	deployment.LastOperationMessage = ""
 
-	if err := db_service.SaveTerraformDeployment(ctx, deployment); err != nil {
+	if err := runner.saveDeployment(ctx, deployment); err != nil {
 		return err
 	}
 
@@ -123,10 +189,71 @@ type ImportResource struct {
 	IaaSResource string
 }
 
-// Import runs `terraform import` and `terraform apply` on the given workspace in the background.
+// jobPayload describes the Terraform command a queued job runs on a
+// deployment.
+type jobPayload struct {
+	Command string           `json:"command"`
+	Import  []ImportResource `json:"import,omitempty"`
+
+	// Seed is run against the database the apply created, connecting with
+	// the settings in the workspace's variables and the outputs. The variables
+	// are read from the deployment when the job runs so the job doesn't hold
+	// another copy of them.
+	Seed *TfSeed `json:"seed,omitempty"`
+}
+
+// The Terraform commands jobs run.
+const (
+	importCommand  = "import"
+	adoptCommand   = "adopt"
+	applyCommand   = "apply"
+	destroyCommand = "destroy"
+)
+
+// Import queues `terraform import` and `terraform apply` on the given workspace.
 // The status of the job can be found by polling the Status function.
 func (runner *TfJobRunner) Import(ctx context.Context, id string, importResources []ImportResource) error {
-	deployment, err := db_service.GetTerraformDeploymentById(ctx, id)
+	return runner.enqueue(ctx, id, models.ProvisionOperationType, nil, jobPayload{Command: importCommand, Import: importResources})
+}
+
+// Adopt queues `terraform import` of resources that already exist into the
+// given workspace followed by `terraform apply`, which brings them under the
+// workspace's configuration rather than replacing it with the imported one.
+func (runner *TfJobRunner) Adopt(ctx context.Context, id string, importResources []ImportResource) error {
+	return runner.enqueue(ctx, id, models.ProvisionOperationType, nil, jobPayload{Command: adoptCommand, Import: importResources})
+}
+
+// Create queues `terraform apply` on the given workspace.
+// The status of the job can be found by polling the Status function.
+func (runner *TfJobRunner) Create(ctx context.Context, id string) error {
+	return runner.CreateWithSeed(ctx, id, nil)
+}
+
+// CreateWithSeed queues `terraform apply` on the given workspace then, if the
+// seed isn't nil, runs it against the database that was created before the
+// job is marked as finished. The seed connects using the workspace's
+// variables and the outputs.
+func (runner *TfJobRunner) CreateWithSeed(ctx context.Context, id string, seed *TfSeed) error {
+	return runner.enqueue(ctx, id, models.ProvisionOperationType, nil, jobPayload{Command: applyCommand, Seed: seed})
+}
+
+// Update queues `terraform apply` on the given workspace with the module
+// inputs from templateVars.
+func (runner *TfJobRunner) Update(ctx context.Context, id string, templateVars map[string]interface{}) error {
+	return runner.enqueue(ctx, id, models.UpdateOperationType, templateVars, jobPayload{Command: applyCommand})
+}
+
+// Destroy queues `terraform destroy` on the given workspace.
+// The status of the job can be found by polling the Status function.
+func (runner *TfJobRunner) Destroy(ctx context.Context, id string, templateVars map[string]interface{}) error {
+	return runner.enqueue(ctx, id, models.DeprovisionOperationType, templateVars, jobPayload{Command: destroyCommand})
+}
+
+// enqueue marks the deployment's operation as started and queues a job to
+// run it. If templateVars isn't nil the workspace is configured with the
+// module inputs from them first so the job runs with them.
+func (runner *TfJobRunner) enqueue(ctx context.Context, id, operationType string, templateVars map[string]interface{}, payload jobPayload) error {
+	deployment, err := runner.getDeployment(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -136,141 +263,260 @@ func (runner *TfJobRunner) Import(ctx context.Context, id string, importResource
 		return err
 	}
 
-	if err := runner.markJobStarted(ctx, deployment, models.ProvisionOperationType); err != nil {
-		return err
-	}
-
-	go func() {
-		logger := utils.NewLogger("Import")
-		resources := make(map[string]string)
-		for _, resource := range importResources {
-			resources[fmt.Sprintf("%s", resource.TfResource)] = resource.IaaSResource
+	if templateVars != nil {
+		inputList, err := workspace.Modules[0].Inputs()
+		if err != nil {
+			return err
 		}
-		if err := workspace.Import(resources); err != nil {
-			logger.Error("Import Failed", err)
-			runner.operationFinished(err, workspace, deployment)
-			return
+
+		limitedConfig := make(map[string]interface{})
+		for _, name := range inputList {
+			limitedConfig[name] = templateVars[name]
 		}
-		mainTf, err := workspace.Show()
-		if err == nil {
-			var tf string
-			var parameterVals map[string]string
-			tf, parameterVals, err = workspace.Transformer.ReplaceParametersInTf(workspace.Transformer.AddParametersInTf(workspace.Transformer.CleanTf(mainTf)))
-			if err == nil {
-				for pn, pv := range parameterVals {
-					workspace.Instances[0].Configuration[pn] = pv
-				}
-				workspace.Modules[0].Definitions["main"] = tf
-
-				logger.Info("new workspace", lager.Data{
-					"workspace": workspace,
-					"tf": tf,
-				})
-
-				err = workspace.Apply()
-			}
+
+		workspace.Instances[0].Configuration = limitedConfig
+		if deployment.Workspace, err = workspace.Serialize(); err != nil {
+			return err
 		}
-		runner.operationFinished(err, workspace, deployment)
-	}()
+	}
+
+	if err := runner.markJobStarted(ctx, deployment, operationType); err != nil {
+		return err
+	}
+
+	queue := runner.queue()
+	maxAttempts := queue.MaxAttempts
+	if payload.Command == destroyCommand {
+		maxAttempts = runner.reconciler().MaxAttempts
+	}
+
+	if _, err := queue.EnqueueWithAttempts(ctx, runner.JobKind, id, payload, maxAttempts); err != nil {
+		deployment.LastOperationState = Failed
+		deployment.LastOperationMessage = err.Error()
+		runner.saveDeployment(ctx, deployment)
+		return err
+	}
 
 	return nil
 }
 
-// Create runs `terraform apply` on the given workspace in the background.
-// The status of the job can be found by polling the Status function.
-func (runner *TfJobRunner) Create(ctx context.Context, id string) error {
-	deployment, err := db_service.GetTerraformDeploymentById(ctx, id)
+// RunJob runs a queued job. It's the handler of the runner's JobKind.
+func (runner *TfJobRunner) RunJob(ctx context.Context, job *models.Job) error {
+	payload := jobPayload{}
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+
+	deployment, err := runner.getDeployment(ctx, job.Target)
 	if err != nil {
 		return err
 	}
 
+	// the operation failed while the job was queued, e.g. it timed out
+	if deployment.LastOperationState == Failed {
+		return jobs.Permanent(errors.New(deployment.LastOperationMessage))
+	}
+
 	workspace, err := runner.hydrateWorkspace(ctx, deployment)
 	if err != nil {
 		return err
 	}
 
-	if err := runner.markJobStarted(ctx, deployment, models.ProvisionOperationType); err != nil {
-		return err
+	spanCtx, span := tracing.StartSpan(ctx, "terraform "+payload.Command, trace.StringAttribute("tf_id", job.Target))
+	switch payload.Command {
+	case importCommand:
+		err = runner.runImport(spanCtx, workspace, payload.Import)
+	case adoptCommand:
+		err = runner.runAdopt(spanCtx, workspace, payload.Import)
+	case applyCommand:
+		err = workspace.Apply(spanCtx)
+		if err == nil && payload.Seed != nil {
+			instance := workspace.Instances[0]
+			var outputs map[string]interface{}
+			if outputs, err = workspace.Outputs(instance.InstanceName); err == nil {
+				err = runSeed(spanCtx, utils.NewLogger("job-runner"), payload.Seed, instance.Configuration, outputs)
+			}
+		}
+	case destroyCommand:
+		err = runner.destroy(spanCtx, workspace)
+		if err != nil && !runner.reconciler().Retryable(err) {
+			err = jobs.Permanent(err)
+		}
+	default:
+		err = fmt.Errorf("unknown Terraform command %q", payload.Command)
 	}
+	tracing.EndSpan(span, err)
 
-	go func() {
-		err := workspace.Apply()
-		runner.operationFinished(err, workspace, deployment)
-	}()
+	// the operation failed while the job ran, keep it failed but save the
+	// state Terraform left so the resources can still be destroyed
+	if current, getErr := runner.getDeployment(ctx, job.Target); getErr == nil && current.LastOperationState == Failed {
+		err = jobs.Permanent(errors.New(current.LastOperationMessage))
+	}
 
-	return nil
+	if err != nil && job.Attempts < job.MaxAttempts && !jobs.IsPermanent(err) {
+		// the queue retries the job, keep the operation in progress with the
+		// state Terraform left so the next attempt starts from it
+		runner.operationRetrying(err, job, workspace, deployment)
+		return err
+	}
+
+	runner.operationFinished(err, workspace, deployment)
+	return err
 }
 
-func (runner *TfJobRunner) Update(ctx context.Context, id string, templateVars map[string]interface{}) error {
-	deployment, err := db_service.GetTerraformDeploymentById(ctx, id)
-	if err != nil {
+// runImport imports the resources into the workspace then replaces its
+// module with the imported configuration and applies it.
+func (runner *TfJobRunner) runImport(ctx context.Context, workspace *wrapper.TerraformWorkspace, importResources []ImportResource) error {
+	logger := utils.NewLogger("Import")
+	resources := make(map[string]string)
+	for _, resource := range importResources {
+		resources[fmt.Sprintf("%s", resource.TfResource)] = resource.IaaSResource
+	}
+	if err := workspace.Import(ctx, resources); err != nil {
+		logger.Error("Import Failed", err)
 		return err
 	}
 
-	workspace, err := runner.hydrateWorkspace(ctx, deployment)
+	mainTf, err := workspace.Show(ctx)
 	if err != nil {
 		return err
 	}
 
-	inputList, err := workspace.Modules[0].Inputs()
+	tf, parameterVals, err := workspace.Transformer.ReplaceParametersInTf(workspace.Transformer.AddParametersInTf(workspace.Transformer.CleanTf(mainTf)))
 	if err != nil {
 		return err
 	}
 
-	limitedConfig := make(map[string]interface{})
-	for _, name := range inputList {
-		limitedConfig[name] = templateVars[name]
-	}	
+	for pn, pv := range parameterVals {
+		workspace.Instances[0].Configuration[pn] = pv
+	}
+	workspace.Modules[0].Definitions["main"] = tf
 
-	workspace.Instances[0].Configuration = limitedConfig
+	logger.Info("new workspace", lager.Data{
+		"workspace": workspace,
+		"tf": tf,
+	})
 
-	if err := runner.markJobStarted(ctx, deployment, models.UpdateOperationType); err != nil {
+	return workspace.Apply(ctx)
+}
+
+// runAdopt imports the resources into the workspace then applies it. Resources
+// a previous attempt already imported are skipped so retries don't fail.
+func (runner *TfJobRunner) runAdopt(ctx context.Context, workspace *wrapper.TerraformWorkspace, importResources []ImportResource) error {
+	managed, err := managedResources(workspace.State)
+	if err != nil {
 		return err
 	}
 
-	go func() {
-		err := workspace.Apply()
-		runner.operationFinished(err, workspace, deployment)
-	}()
+	resources := make(map[string]string)
+	for _, resource := range importResources {
+		if !containsResource(managed, resource.TfResource) {
+			resources[resource.TfResource] = resource.IaaSResource
+		}
+	}
 
-	return nil
+	if len(resources) > 0 {
+		if err := workspace.Import(ctx, resources); err != nil {
+			return err
+		}
+	}
+
+	return workspace.Apply(ctx)
 }
 
-// Destroy runs `terraform destroy` on the given workspace in the background.
-// The status of the job can be found by polling the Status function.
-func (runner *TfJobRunner) Destroy(ctx context.Context, id string, templateVars map[string]interface{}) error {
-	deployment, err := db_service.GetTerraformDeploymentById(ctx, id)
-	if err != nil {
-		return err
+// containsResource returns true if one of the managed resource addresses is
+// the resource, possibly within a module.
+func containsResource(managed []string, resource string) bool {
+	for _, address := range managed {
+		for strings.HasPrefix(address, "module.") {
+			parts := strings.SplitN(address, ".", 3)
+			if len(parts) < 3 {
+				break
+			}
+			address = parts[2]
+		}
+
+		if address == resource {
+			return true
+		}
 	}
 
-	workspace, err := runner.hydrateWorkspace(ctx, deployment)
-	if err != nil {
+	return false
+}
+
+func (runner *TfJobRunner) reconciler() *deprovision.Reconciler {
+	if runner.Reconciler == nil {
+		return deprovision.Default
+	}
+
+	return runner.Reconciler
+}
+
+// destroy runs `terraform destroy` on the workspace then refreshes the state
+// from before the destroy until the resources in it are gone. If resources
+// remain, the workspace keeps the refreshed state so the next destroy
+// deletes them.
+func (runner *TfJobRunner) destroy(ctx context.Context, workspace *wrapper.TerraformWorkspace) error {
+	// states Terraform can't refresh, e.g. from older versions, aren't
+	// verified
+	before := workspace.State
+	created, parseErr := managedResources(before)
+
+	if err := workspace.Destroy(ctx); err != nil {
 		return err
 	}
 
-	inputList, err := workspace.Modules[0].Inputs()
+	if parseErr != nil || len(created) == 0 {
+		return nil
+	}
+
+	destroyed := workspace.State
+	err := runner.reconciler().WaitUntilGone(ctx, func(ctx context.Context) ([]string, error) {
+		workspace.State = before
+		if err := workspace.Refresh(ctx); err != nil {
+			return nil, err
+		}
+
+		before = workspace.State
+		return managedResources(before)
+	})
+
 	if err != nil {
+		workspace.State = before
 		return err
 	}
 
-	limitedConfig := make(map[string]interface{})
-	for _, name := range inputList {
-		limitedConfig[name] = templateVars[name]
-	}	
+	workspace.State = destroyed
+	return nil
+}
 
-	workspace.Instances[0].Configuration = limitedConfig	
+// managedResources gets the addresses of the managed resources in a
+// serialized state, an empty state has none.
+func managedResources(state []byte) ([]string, error) {
+	if len(state) == 0 {
+		return nil, nil
+	}
 
-	if err := runner.markJobStarted(ctx, deployment, models.DeprovisionOperationType); err != nil {
-		return err
+	tfstate, err := wrapper.NewTfstate(state)
+	if err != nil {
+		return nil, err
 	}
 
-	go func() {
-		err := workspace.Destroy()
-		runner.operationFinished(err, workspace, deployment)
-	}()
+	return tfstate.GetManagedResources(), nil
+}
 
-	return nil
+// operationRetrying saves the workspace after a failed attempt of a job that
+// will be retried, leaving the operation in progress.
+func (runner *TfJobRunner) operationRetrying(err error, job *models.Job, workspace *wrapper.TerraformWorkspace, deployment *models.TerraformDeployment) error {
+	deployment.LastOperationMessage = fmt.Sprintf("attempt %d of %d failed, retrying: %s", job.Attempts, job.MaxAttempts, err)
+
+	workspaceString, serializeErr := workspace.Serialize()
+	if serializeErr != nil {
+		return serializeErr
+	}
+	deployment.Workspace = workspaceString
+
+	return runner.saveDeployment(context.Background(), deployment)
 }
 
 // operationFinished closes out the state of the background job so clients that
@@ -299,14 +545,14 @@ func (runner *TfJobRunner) operationFinished(err error, workspace *wrapper.Terra
 
 	deployment.Workspace = workspaceString
 
-	return db_service.SaveTerraformDeployment(context.Background(), deployment)
+	return runner.saveDeployment(context.Background(), deployment)
 }
 
 // Status gets the status of the most recent job on the workspace.
 // If isDone is true, then the status of the operation will not change again.
 // if isDone is false, then the operation is ongoing.
 func (runner *TfJobRunner) Status(ctx context.Context, id string) (bool, string, error) {
-	deployment, err := db_service.GetTerraformDeploymentById(ctx, id)
+	deployment, err := runner.getDeployment(ctx, id)
 	if err != nil {
 		return true, "", err
 	}
@@ -317,13 +563,66 @@ func (runner *TfJobRunner) Status(ctx context.Context, id string) (bool, string,
 	case Failed:
 		return true, deployment.LastOperationMessage, errors.New(deployment.LastOperationMessage)
 	default:
+		if err := runner.checkTimeout(ctx, deployment); err != nil {
+			return true, err.Error(), err
+		}
 		return false, deployment.LastOperationMessage, nil
 	}
 }
 
+// checkTimeout fails the deployment's operation if it has run for longer
+// than its Timeout. An operation starts when its latest job is queued.
+func (runner *TfJobRunner) checkTimeout(ctx context.Context, deployment *models.TerraformDeployment) error {
+	if runner.Timeout == nil {
+		return nil
+	}
+
+	operation := operationName(deployment)
+	timeout := runner.Timeout(operation)
+	if timeout <= 0 {
+		return nil
+	}
+
+	store, err := runner.store()
+	if err != nil {
+		return err
+	}
+
+	started := deployment.UpdatedAt
+	if queued, err := store.ListJobs(ctx, deployment.ID); err == nil && len(queued) > 0 {
+		started = queued[0].CreatedAt
+	}
+	if time.Since(started) <= timeout {
+		return nil
+	}
+
+	deployment.LastOperationState = Failed
+	deployment.LastOperationMessage = fmt.Sprintf("%s timed out after %s", operation, timeout)
+	if err := runner.saveDeployment(ctx, deployment); err != nil {
+		return err
+	}
+
+	return errors.New(deployment.LastOperationMessage)
+}
+
+// operationName gets the name of the operation a deployment is running:
+// provision, update or deprovision for instances and bind or unbind for
+// bindings, whose deployment IDs look like tf:instance-id:binding-id.
+func operationName(deployment *models.TerraformDeployment) string {
+	parts := strings.SplitN(deployment.ID, ":", 3)
+	if len(parts) != 3 || parts[2] == "" {
+		return deployment.LastOperationType
+	}
+
+	if deployment.LastOperationType == models.DeprovisionOperationType {
+		return "unbind"
+	}
+	return "bind"
+}
+
 // Outputs gets the output variables for the given module instance in the workspace.
 func (runner *TfJobRunner) Outputs(ctx context.Context, id, instanceName string) (map[string]interface{}, error) {
-	deployment, err := db_service.GetTerraformDeploymentById(ctx, id)
+	deployment, err := runner.getDeployment(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,174 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tf
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/fakes"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/jobs"
+)
+
+func TestOperationName(t *testing.T) {
+	cases := map[string]struct {
+		Id            string
+		OperationType string
+		Expected      string
+	}{
+		"provision":   {Id: "tf:instance:", OperationType: models.ProvisionOperationType, Expected: "provision"},
+		"update":      {Id: "tf:instance:", OperationType: models.UpdateOperationType, Expected: "update"},
+		"deprovision": {Id: "tf:instance:", OperationType: models.DeprovisionOperationType, Expected: "deprovision"},
+		"bind":        {Id: "tf:instance:binding", OperationType: models.ProvisionOperationType, Expected: "bind"},
+		"unbind":      {Id: "tf:instance:binding", OperationType: models.DeprovisionOperationType, Expected: "unbind"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			deployment := &models.TerraformDeployment{ID: tc.Id, LastOperationType: tc.OperationType}
+			if actual := operationName(deployment); actual != tc.Expected {
+				t.Errorf("Expected operation %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestContainsResource(t *testing.T) {
+	managed := []string{"google_storage_bucket.bucket", "module.instance.google_sql_database_instance.instance"}
+
+	cases := map[string]struct {
+		Resource string
+		Expected bool
+	}{
+		"root module":  {Resource: "google_storage_bucket.bucket", Expected: true},
+		"child module": {Resource: "google_sql_database_instance.instance", Expected: true},
+		"not managed":  {Resource: "google_sql_database.database", Expected: false},
+		"same suffix":  {Resource: "bucket", Expected: false},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := containsResource(managed, tc.Resource); actual != tc.Expected {
+				t.Errorf("Expected %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestTfJobRunner_Status_timeout(t *testing.T) {
+	db, err := db_service.OpenSqlite("test.db")
+	if err != nil {
+		t.Fatalf("couldn't create database: %v", err)
+	}
+	defer os.Remove("test.db")
+	defer db.Close()
+	if err := db_service.RunMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	store := db_service.NewSqlDatastore(db)
+
+	cases := map[string]struct {
+		Timeout       time.Duration
+		Age           time.Duration
+		ExpectDone    bool
+		ExpectMessage string
+	}{
+		"no-timeout": {Timeout: 0, Age: time.Hour, ExpectDone: false},
+		"within":     {Timeout: time.Hour, Age: time.Minute, ExpectDone: false},
+		"timed-out":  {Timeout: time.Minute, Age: time.Hour, ExpectDone: true, ExpectMessage: "provision timed out after 1m0s"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			ctx := context.Background()
+			id := generateTfId(tn, "")
+			deployment := &models.TerraformDeployment{
+				ID:                 id,
+				LastOperationType:  models.ProvisionOperationType,
+				LastOperationState: InProgress,
+			}
+			if err := store.CreateTerraformDeployment(ctx, deployment); err != nil {
+				t.Fatal(err)
+			}
+			started := time.Now().Add(-tc.Age)
+			if err := db.Model(deployment).UpdateColumn("updated_at", started).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			runner := &TfJobRunner{Store: store, Timeout: func(operation string) time.Duration {
+				if operation != "provision" {
+					t.Errorf("Expected the provision timeout, got %q", operation)
+				}
+				return tc.Timeout
+			}}
+
+			done, message, err := runner.Status(ctx, id)
+			if done != tc.ExpectDone {
+				t.Errorf("Expected done to be %v, got %v", tc.ExpectDone, done)
+			}
+			if !tc.ExpectDone {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			if message != tc.ExpectMessage || err == nil || !strings.Contains(err.Error(), tc.ExpectMessage) {
+				t.Errorf("Expected message and error %q, got %q, %v", tc.ExpectMessage, message, err)
+			}
+
+			saved, err := store.GetTerraformDeploymentById(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved.LastOperationState != Failed {
+				t.Errorf("Expected the operation to be saved as %q, got %q", Failed, saved.LastOperationState)
+			}
+		})
+	}
+}
+
+func TestTfJobRunner_store(t *testing.T) {
+	own := fakes.NewInMemoryDatastore()
+	queued := db_service.NewSqlDatastore(nil)
+
+	cases := map[string]struct {
+		Runner      *TfJobRunner
+		Expected    broker.ProviderStore
+		ExpectedErr bool
+	}{
+		"own store":      {Runner: &TfJobRunner{Store: own, Queue: jobs.NewQueue(queued)}, Expected: own},
+		"queue database": {Runner: &TfJobRunner{Queue: jobs.NewQueue(queued)}, Expected: queued},
+		"no store":       {Runner: &TfJobRunner{Queue: jobs.NewQueue(nil)}, ExpectedErr: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := tc.Runner.store()
+			if (err != nil) != tc.ExpectedErr {
+				t.Fatalf("Expected error %v, got %v", tc.ExpectedErr, err)
+			}
+			if actual != tc.Expected {
+				t.Errorf("Expected store %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/pivotal-cf/brokerapi"
 	"github.com/pivotal/cloud-service-broker/db_service/models"
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/preview"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin/base"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/tf/wrapper"
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
@@ -44,6 +45,9 @@ type terraformProvider struct {
 	serviceDefinition TfServiceDefinitionV1
 }
 
+var _ broker.Importer = (*terraformProvider)(nil)
+var _ broker.ResourcePreviewer = (*terraformProvider)(nil)
+
 // Provision creates the necessary resources that an instance of this service
 // needs to operate.
 func (provider *terraformProvider) Provision(ctx context.Context, provisionContext *varcontext.VarContext) (models.ServiceInstanceDetails, error) {
@@ -60,7 +64,12 @@ func (provider *terraformProvider) Provision(ctx context.Context, provisionConte
 			return models.ServiceInstanceDetails{}, err
 		}	
 	} else {
-		tfID, err = provider.create(ctx, provisionContext, provider.serviceDefinition.ProvisionSettings)
+		seed, err := provider.serviceDefinition.ProvisionSettings.selectedSeed(provisionContext)
+		if err != nil {
+			return models.ServiceInstanceDetails{}, err
+		}
+
+		tfID, err = provider.create(ctx, provisionContext, provider.serviceDefinition.ProvisionSettings, seed)
 		if err != nil {
 			return models.ServiceInstanceDetails{}, err
 		}
@@ -72,6 +81,81 @@ func (provider *terraformProvider) Provision(ctx context.Context, provisionConte
 	}, nil
 }
 
+// Import implements broker.Importer. Subsume plans import the resources like
+// they do when provisioning; otherwise the service's adopt_resources are
+// imported into the provision template using the IDs in their inputs and the
+// template is applied to them.
+func (provider *terraformProvider) Import(ctx context.Context, provisionContext *varcontext.VarContext) (models.ServiceInstanceDetails, error) {
+	action := provider.serviceDefinition.ProvisionSettings
+	if action.IsTfImport(provisionContext) {
+		return provider.Provision(ctx, provisionContext)
+	}
+
+	if len(action.AdoptResources) == 0 {
+		return models.ServiceInstanceDetails{}, fmt.Errorf("service %s can't import existing resources", provider.serviceDefinition.Name)
+	}
+
+	var resources []ImportResource
+	for _, resource := range action.AdoptResources {
+		id := provisionContext.GetString(resource.FieldName)
+		if id == "" {
+			return models.ServiceInstanceDetails{}, fmt.Errorf("%s must be set to the existing resource to import", resource.FieldName)
+		}
+
+		resources = append(resources, ImportResource{TfResource: resource.TfResource, IaaSResource: id})
+	}
+
+	tfId := provisionContext.GetString("tf_id")
+	if err := provisionContext.Error(); err != nil {
+		return models.ServiceInstanceDetails{}, err
+	}
+
+	provider.logger.Debug("terraform-import", lager.Data{
+		"context":   provisionContext.ToMap(),
+		"resources": resources,
+	})
+
+	workspace, err := wrapper.NewWorkspace(provisionContext.ToMap(), action.Template, action.Templates, []wrapper.ParameterMapping{}, []string{}, []wrapper.ParameterMapping{})
+	if err != nil {
+		return models.ServiceInstanceDetails{}, err
+	}
+
+	if err := provider.jobRunner.StageJob(ctx, tfId, workspace); err != nil {
+		return models.ServiceInstanceDetails{}, err
+	}
+
+	if err := provider.jobRunner.Adopt(ctx, tfId, resources); err != nil {
+		return models.ServiceInstanceDetails{}, err
+	}
+
+	return models.ServiceInstanceDetails{
+		OperationId:   tfId,
+		OperationType: models.ProvisionOperationType,
+	}, nil
+}
+
+// PreviewResources implements broker.ResourcePreviewer by listing the
+// resource blocks of the provision template, Terraform isn't run.
+func (provider *terraformProvider) PreviewResources(ctx context.Context, provisionContext *varcontext.VarContext) ([]preview.Resource, error) {
+	action := provider.serviceDefinition.ProvisionSettings
+	if _, err := action.selectedSeed(provisionContext); err != nil {
+		return nil, err
+	}
+
+	module := wrapper.ModuleDefinition{Name: "brokertemplate", Definition: action.Template, Definitions: action.Templates}
+	resources, err := module.Resources(provisionContext.ToMap())
+	if err != nil {
+		return nil, err
+	}
+
+	var out []preview.Resource
+	for _, resource := range resources {
+		out = append(out, preview.Resource{Type: resource.Type, Name: resource.Name, Count: resource.Count})
+	}
+
+	return out, nil
+}
+
 // Update makes necessary updates to resources so they match new desired configuration
 func (provider *terraformProvider) Update(ctx context.Context, provisionContext *varcontext.VarContext) (models.ServiceInstanceDetails, error) {
 	provider.logger.Debug("update", lager.Data{
@@ -101,7 +185,12 @@ func (provider *terraformProvider) Bind(ctx context.Context, bindContext *varcon
 		"context": bindContext.ToMap(),
 	})
 
-	tfId, err := provider.create(ctx, bindContext, provider.serviceDefinition.BindSettings)
+	seed, err := provider.serviceDefinition.BindSettings.selectedSeed(bindContext)
+	if err != nil {
+		return nil, err
+	}
+
+	tfId, err := provider.create(ctx, bindContext, provider.serviceDefinition.BindSettings, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +199,37 @@ func (provider *terraformProvider) Bind(ctx context.Context, bindContext *varcon
 		return nil, err
 	}
 
-	return provider.jobRunner.Outputs(ctx, tfId, wrapper.DefaultInstanceName)
+	outputs, err := provider.jobRunner.Outputs(ctx, tfId, wrapper.DefaultInstanceName)
+	if err != nil {
+		return nil, err
+	}
+
+	if seed != nil {
+		if err := runSeed(ctx, provider.logger, seed, bindContext.ToMap(), outputs); err != nil {
+			return nil, err
+		}
+	}
+
+	return outputs, nil
+}
+
+// runSeed runs the seed against the database described by the action's
+// variables and outputs.
+func runSeed(ctx context.Context, logger lager.Logger, seed *TfSeed, vars, outputs map[string]interface{}) error {
+	connection := make(map[string]interface{})
+	for k, v := range vars {
+		connection[k] = v
+	}
+	for k, v := range outputs {
+		connection[k] = v
+	}
+
+	logger.Info("running-seed", lager.Data{"seed": seed.Name, "sha256": seed.Checksum})
+	if err := seed.Run(ctx, connection); err != nil {
+		return fmt.Errorf("Error initializing the database: %v", err)
+	}
+
+	return nil
 }
 
 func (provider *terraformProvider) importCreate(ctx context.Context, vars *varcontext.VarContext, action TfServiceDefinitionV1Action) (string, error) {
@@ -167,7 +286,7 @@ func (provider *terraformProvider) importCreate(ctx context.Context, vars *varco
 	return tfId, provider.jobRunner.Import(ctx, tfId, importParams)
 }
 
-func (provider *terraformProvider) create(ctx context.Context, vars *varcontext.VarContext, action TfServiceDefinitionV1Action) (string, error) {
+func (provider *terraformProvider) create(ctx context.Context, vars *varcontext.VarContext, action TfServiceDefinitionV1Action, seed *TfSeed) (string, error) {
 	tfId := vars.GetString("tf_id")
 	if err := vars.Error(); err != nil {
 		return "", err
@@ -187,7 +306,11 @@ func (provider *terraformProvider) create(ctx context.Context, vars *varcontext.
 		return tfId, err
 	}
 
-	return tfId, provider.jobRunner.Create(ctx, tfId)
+	if seed == nil {
+		return tfId, provider.jobRunner.Create(ctx, tfId)
+	}
+
+	return tfId, provider.jobRunner.CreateWithSeed(ctx, tfId, seed)
 }
 
 // Unbind performs a terraform destroy on the binding.
@@ -235,6 +358,23 @@ func (provider *terraformProvider) DeprovisionsAsync() bool {
 	return true
 }
 
+// PlanCapabilities implements broker.CapabilityReporter. Plans with the
+// subsume property import existing resources and can't be updated. Every plan
+// of services with adopt_resources can import existing resources.
+func (provider *terraformProvider) PlanCapabilities(plan broker.ServicePlan, derived broker.Capabilities) broker.Capabilities {
+	if len(provider.serviceDefinition.ProvisionSettings.AdoptResources) > 0 {
+		derived.Import = true
+	}
+
+	if subsume, ok := plan.ServiceProperties["subsume"].(bool); ok && subsume {
+		derived.Import = true
+		derived.Update = false
+		derived.UpdatableFields = []string{}
+	}
+
+	return derived
+}
+
 // UpdateInstanceDetails updates the ServiceInstanceDetails with the most recent state from GCP.
 // This function is optional, but will be called after async provisions, updates, and possibly
 // on broker version changes.
@@ -0,0 +1,289 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tf
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ApprovedSeedsProp is the viper key of the comma delimited list of seed
+	// checksums the operator allows to run.
+	ApprovedSeedsProp = "seeds.approved_checksums"
+
+	// SeedInputName is the name of the user input that selects a seed.
+	SeedInputName = "seed"
+
+	// Supported seed drivers.
+	MySqlDriver    = "mysql"
+	PostgresDriver = "postgres"
+)
+
+// defaultSeedConnection maps connection settings to the variables or outputs
+// that hold them if the seed doesn't say otherwise.
+var defaultSeedConnection = map[string]string{
+	"host":     "hostname",
+	"port":     "port",
+	"username": "username",
+	"password": "password",
+	"database": "name",
+}
+
+// postgresSSLModes maps the broker's database TLS modes to Postgres sslmodes.
+var postgresSSLModes = map[string]string{
+	"true":        "verify-full",
+	"skip-verify": "require",
+	"false":       "disable",
+}
+
+// TfSeed is a bundle of SQL that's run against a database after it's
+// created or bound so users get a ready to use schema.
+type TfSeed struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Driver      string `yaml:"driver"`
+
+	// Checksum is the hex encoded SHA-256 of the files concatenated in order.
+	Checksum string `yaml:"sha256"`
+
+	// FileRefs are paths to SQL files that get packed into Files.
+	FileRefs []string `yaml:"file_refs,omitempty"`
+	Files    []string `yaml:"files"`
+
+	// Connection overrides the variable or output names that hold the
+	// host, port, username, password and database to connect to.
+	Connection map[string]string `yaml:"connection,omitempty"`
+}
+
+var _ validation.Validatable = (*TfSeed)(nil)
+
+// Validate implements validation.Validatable.
+func (seed *TfSeed) Validate() (errs *validation.FieldError) {
+	errs = errs.Also(
+		validation.ErrIfBlank(seed.Name, "name"),
+		validation.ErrIfBlank(seed.Description, "description"),
+		validation.ErrIfBlank(seed.Checksum, "sha256"),
+	)
+
+	if seed.Driver != MySqlDriver && seed.Driver != PostgresDriver {
+		errs = errs.Also(validation.ErrInvalidValue(seed.Driver, "driver"))
+	}
+
+	if len(seed.Files) == 0 {
+		errs = errs.Also(validation.ErrMissingField("files"))
+	} else if seed.Checksum != "" && !seed.checksumMatches() {
+		errs = errs.Also(&validation.FieldError{
+			Message: fmt.Sprintf("checksum of files %s doesn't match", seed.ComputeChecksum()),
+			Paths:   []string{"sha256"},
+		})
+	}
+
+	for key := range seed.Connection {
+		if _, ok := defaultSeedConnection[key]; !ok {
+			errs = errs.Also(validation.ErrInvalidKeyName(key, "connection"))
+		}
+	}
+
+	return errs
+}
+
+// LoadFiles reads the seed's file refs relative to srcDir into Files.
+func (seed *TfSeed) LoadFiles(srcDir string) error {
+	for _, ref := range seed.FileRefs {
+		contents, err := ioutil.ReadFile(path.Join(srcDir, ref))
+		if err != nil {
+			return err
+		}
+
+		seed.Files = append(seed.Files, string(contents))
+	}
+
+	return nil
+}
+
+// ComputeChecksum returns the hex encoded SHA-256 of the seed's files.
+func (seed *TfSeed) ComputeChecksum() string {
+	hash := sha256.New()
+	for _, file := range seed.Files {
+		hash.Write([]byte(file))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (seed *TfSeed) checksumMatches() bool {
+	return strings.EqualFold(seed.Checksum, seed.ComputeChecksum())
+}
+
+// IsApproved returns true if the operator approved the seed's checksum.
+func (seed *TfSeed) IsApproved() bool {
+	if seed.Checksum == "" {
+		return false
+	}
+
+	for _, approved := range strings.Split(viper.GetString(ApprovedSeedsProp), ",") {
+		if strings.EqualFold(strings.TrimSpace(approved), seed.Checksum) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dataSourceName builds the connection string for the seed's driver from the
+// action's variables and outputs.
+func (seed *TfSeed) dataSourceName(vars map[string]interface{}) (string, error) {
+	settings := make(map[string]string)
+	for setting, defaultKey := range defaultSeedConnection {
+		key := defaultKey
+		if override, ok := seed.Connection[setting]; ok {
+			key = override
+		}
+
+		value, ok := vars[key]
+		if !ok {
+			return "", fmt.Errorf("seed %q can't connect, %q isn't set", seed.Name, key)
+		}
+
+		settings[setting] = fmt.Sprintf("%v", value)
+	}
+
+	// seeds use the TLS mode the broker connects to its own database with
+	tlsMode := db_service.TLSMode()
+	address := net.JoinHostPort(settings["host"], settings["port"])
+	if seed.Driver == MySqlDriver {
+		cfg := mysql.NewConfig()
+		cfg.Net = "tcp"
+		cfg.Addr = address
+		cfg.User = settings["username"]
+		cfg.Passwd = settings["password"]
+		cfg.DBName = settings["database"]
+		cfg.MultiStatements = true
+		cfg.TLSConfig = tlsMode
+		return cfg.FormatDSN(), nil
+	}
+
+	sslMode, ok := postgresSSLModes[tlsMode]
+	if !ok {
+		return "", fmt.Errorf("seed %q can't connect, TLS mode %q isn't supported by postgres", seed.Name, tlsMode)
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(settings["username"], settings["password"]),
+		Host:     address,
+		Path:     settings["database"],
+		RawQuery: url.Values{"sslmode": []string{sslMode}}.Encode(),
+	}
+	return dsn.String(), nil
+}
+
+// Run executes the seed's files in order against the database described by
+// vars. The checksum is verified again so packed files can't be changed after
+// the operator approved them.
+func (seed *TfSeed) Run(ctx context.Context, vars map[string]interface{}) error {
+	if !seed.checksumMatches() {
+		return fmt.Errorf("seed %q doesn't match its checksum %s", seed.Name, seed.Checksum)
+	}
+
+	dsn, err := seed.dataSourceName(vars)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open(seed.Driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for i, file := range seed.Files {
+		if _, err := db.ExecContext(ctx, file); err != nil {
+			return fmt.Errorf("seed %q file %d failed: %v", seed.Name, i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// seedInput creates the user input used to choose one of the action's seeds.
+func (action *TfServiceDefinitionV1Action) seedInput() broker.BrokerVariable {
+	enum := make(map[interface{}]string)
+	for _, seed := range action.Seeds {
+		enum[seed.Name] = seed.Description
+	}
+
+	return broker.BrokerVariable{
+		FieldName: SeedInputName,
+		Type:      broker.JsonTypeString,
+		Details:   "Name of an operator approved SQL bundle to initialize the database with.",
+		Enum:      enum,
+	}
+}
+
+// userInputs returns the action's user inputs including the seed selector if
+// the action has seeds.
+func (action *TfServiceDefinitionV1Action) userInputs() []broker.BrokerVariable {
+	if len(action.Seeds) == 0 {
+		return action.UserInputs
+	}
+
+	return append(append([]broker.BrokerVariable{}, action.UserInputs...), action.seedInput())
+}
+
+// selectedSeed returns the seed the user chose or nil if they didn't choose
+// one. It's an error to choose a seed the operator hasn't approved.
+func (action *TfServiceDefinitionV1Action) selectedSeed(vars *varcontext.VarContext) (*TfSeed, error) {
+	if !vars.HasKey(SeedInputName) {
+		return nil, nil
+	}
+
+	name := vars.GetString(SeedInputName)
+	if name == "" {
+		return nil, nil
+	}
+
+	for i := range action.Seeds {
+		seed := &action.Seeds[i]
+		if seed.Name != name {
+			continue
+		}
+
+		if !seed.IsApproved() {
+			return nil, fmt.Errorf("seed %q (sha256 %s) has not been approved by the operator", seed.Name, seed.Checksum)
+		}
+
+		return seed, nil
+	}
+
+	return nil, fmt.Errorf("unknown seed %q", name)
+}
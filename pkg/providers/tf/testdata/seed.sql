@@ -0,0 +1 @@
+CREATE TABLE items (id INT PRIMARY KEY);
@@ -15,12 +15,15 @@
 package wrapper
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 
 	"github.com/pivotal/cloud-service-broker/pkg/validation"
 	"github.com/hashicorp/hcl2/hcl"
 	"github.com/hashicorp/hcl2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
 // ModuleDefinition represents a module in a Terraform workspace.
@@ -60,6 +63,10 @@ func decode(body string)  (hcl.Blocks, error) {
 				Type: "output",
 				LabelNames: []string{"value"},
 			},
+			hcl.BlockHeaderSchema{
+				Type: "resource",
+				LabelNames: []string{"type", "name"},
+			},
 		},
 	}
 	content, _, diags := f.Body.PartialContent(&schema)
@@ -120,3 +127,109 @@ type terraformModuleHcl struct {
 	Inputs  map[string]interface{} //`hcl:"variable"`
 	Outputs map[string]interface{} //`hcl:"output"`
 }
+
+// Resource is a resource block of a module.
+type Resource struct {
+	Type string
+	Name string
+	// Count is how many instances of the resource are created, it's nil if
+	// the count depends on something other than the module's variables, e.g.
+	// another resource, or the resource uses for_each.
+	Count *int
+}
+
+// countSchema holds the meta-arguments that control how many instances of a
+// resource are created.
+var countSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "count"}, {Name: "for_each"}},
+}
+
+// Resources lists the resource blocks of the module, those in Definition
+// first then those in Definitions ordered by name, evaluating their count
+// with the given variables.
+func (module *ModuleDefinition) Resources(variables map[string]interface{}) ([]Resource, error) {
+	evalCtx, err := variablesContext(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	bodies := []string{module.Definition}
+	var names []string
+	for name := range module.Definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		bodies = append(bodies, module.Definitions[name])
+	}
+
+	var resources []Resource
+	for _, body := range bodies {
+		blocks, err := decode(body)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, block := range blocks.OfType("resource") {
+			resources = append(resources, Resource{
+				Type:  block.Labels[0],
+				Name:  block.Labels[1],
+				Count: resourceCount(block, evalCtx),
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+// variablesContext makes the variables available to expressions as var.NAME.
+func variablesContext(variables map[string]interface{}) (*hcl.EvalContext, error) {
+	encoded, err := json.Marshal(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	ty, err := ctyjson.ImpliedType(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := ctyjson.Unmarshal(encoded, ty)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hcl.EvalContext{Variables: map[string]cty.Value{"var": value}}, nil
+}
+
+// resourceCount evaluates the count of the resource block, it's nil if the
+// count can't be known before applying the module.
+func resourceCount(block *hcl.Block, evalCtx *hcl.EvalContext) *int {
+	content, _, diags := block.Body.PartialContent(countSchema)
+	if diags.HasErrors() {
+		return nil
+	}
+
+	if _, ok := content.Attributes["for_each"]; ok {
+		return nil
+	}
+
+	count := 1
+	attr, ok := content.Attributes["count"]
+	if !ok {
+		return &count
+	}
+
+	value, diags := attr.Expr.Value(evalCtx)
+	if diags.HasErrors() || !value.IsWhollyKnown() || value.IsNull() || value.Type() != cty.Number {
+		return nil
+	}
+
+	n, accuracy := value.AsBigFloat().Int64()
+	if accuracy != 0 || n < 0 {
+		return nil
+	}
+
+	count = int(n)
+	return &count
+}
@@ -15,6 +15,7 @@
 package wrapper
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -208,3 +209,58 @@ func TestModuleDefinition_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestModuleDefinition_Resources(t *testing.T) {
+	module := ModuleDefinition{
+		Name: "cloud_sql",
+		Definition: `
+			variable read_replicas {type = number}
+
+			resource "google_sql_database_instance" "instance" {
+			  name = "primary"
+			}
+
+			resource "google_sql_database_instance" "replica" {
+			  count = var.read_replicas
+			  settings {
+			    tier = "db-f1-micro"
+			  }
+			}
+
+			resource "google_sql_user" "users" {
+			  count = length(google_sql_database_instance.replica)
+			}
+		`,
+		Definitions: map[string]string{
+			"b": `resource "random_password" "password" {}`,
+			"a": `resource "google_sql_database" "databases" {
+			  for_each = toset(["a", "b"])
+			}`,
+		},
+	}
+
+	resources, err := module.Resources(map[string]interface{}{"read_replicas": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []string
+	for _, resource := range resources {
+		count := "unknown"
+		if resource.Count != nil {
+			count = fmt.Sprint(*resource.Count)
+		}
+		actual = append(actual, resource.Type+"."+resource.Name+"="+count)
+	}
+
+	expected := []string{
+		"google_sql_database_instance.instance=1",
+		"google_sql_database_instance.replica=2",
+		"google_sql_user.users=unknown",
+		"google_sql_database.databases=unknown",
+		"random_password.password=1",
+	}
+	if !compareStringArrays(actual, expected) {
+		t.Errorf("Expected resources %v, got %v", expected, actual)
+	}
+}
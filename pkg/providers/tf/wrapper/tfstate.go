@@ -44,6 +44,13 @@ type Tfstate struct {
 		Type  string      `json:"type"`
 		Value interface{} `json:"value"`
 	} `json:"outputs"`
+	Resources []struct {
+		Module    string            `json:"module"`
+		Mode      string            `json:"mode"`
+		Type      string            `json:"type"`
+		Name      string            `json:"name"`
+		Instances []json.RawMessage `json:"instances"`
+	} `json:"resources"`
 }
 
 // GetOutputs gets the key/value outputs defined for a module.
@@ -56,3 +63,23 @@ func (module *Tfstate) GetOutputs() map[string]interface{} {
 
 	return out
 }
+
+// GetManagedResources gets the addresses of the managed resources that have
+// at least one instance in the state. Data sources aren't included.
+func (module *Tfstate) GetManagedResources() []string {
+	var out []string
+
+	for _, resource := range module.Resources {
+		if resource.Mode != "managed" || len(resource.Instances) == 0 {
+			continue
+		}
+
+		address := resource.Type + "." + resource.Name
+		if resource.Module != "" {
+			address = resource.Module + "." + address
+		}
+		out = append(out, address)
+	}
+
+	return out
+}
@@ -110,3 +110,41 @@ func ExampleTfstate_GetOutputs() {
 
 	// Output: map[hostname:somehost]
 }
+
+func ExampleTfstate_GetManagedResources() {
+	state := `{
+    "version": 4,
+    "terraform_version": "0.12.20",
+    "serial": 2,
+    "resources": [
+        {
+          "module": "module.instance",
+          "mode": "managed",
+          "type": "google_sql_database",
+          "name": "database",
+          "provider": "provider.google",
+          "instances": []
+        },
+        {
+          "module": "module.instance",
+          "mode": "managed",
+          "type": "google_sql_database_instance",
+          "name": "instance",
+          "provider": "provider.google",
+          "instances": [{"attributes": {"name": "db"}}]
+        },
+        {
+          "mode": "data",
+          "type": "google_compute_network",
+          "name": "network",
+          "provider": "provider.google",
+          "instances": [{"attributes": {"name": "default"}}]
+        }
+    ]
+  }`
+
+	tfstate, _ := NewTfstate([]byte(state))
+	fmt.Printf("%v\n", tfstate.GetManagedResources())
+
+	// Output: [module.instance.google_sql_database_instance.instance]
+}
@@ -15,6 +15,7 @@
 package wrapper
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -45,8 +46,9 @@ type ExecutionOutput struct {
 }
 
 // TerraformExecutor is the function that shells out to Terraform.
-// It can intercept, modify or retry the given command.
-type TerraformExecutor func(*exec.Cmd) (ExecutionOutput, error)
+// It can intercept, modify or retry the given command. Terraform is
+// interrupted if the context is done before it exits.
+type TerraformExecutor func(context.Context, *exec.Cmd) (ExecutionOutput, error)
 
 // NewWorkspace creates a new TerraformWorkspace from a given template and variables to populate an instance of it.
 // The created instance will have the name specified by the DefaultInstanceName constant.
@@ -235,7 +237,7 @@ func (workspace *TerraformWorkspace) initializeFsModules() error {
 }
 
 // initializeFs initializes the filesystem directory necessary to run Terraform.
-func (workspace *TerraformWorkspace) initializeFs() error {
+func (workspace *TerraformWorkspace) initializeFs(ctx context.Context) error {
 	workspace.dirLock.Lock()
 	// create a temp directory
 	if dir, err := ioutil.TempDir("", "gsb"); err == nil {
@@ -272,7 +274,7 @@ func (workspace *TerraformWorkspace) initializeFs() error {
 	}
 
 	// run "terraform init"
-	if _, err := workspace.runTf("init", "-no-color"); err != nil {
+	if _, err := workspace.runTf(ctx, "init", "-no-color"); err != nil {
 		return err
 	}
 
@@ -314,55 +316,69 @@ func (workspace *TerraformWorkspace) Outputs(instance string) (map[string]interf
 
 // Validate runs `terraform Validate` on this workspace.
 // This funciton blocks if another Terraform command is running on this workspace.
-func (workspace *TerraformWorkspace) Validate() error {
-	err := workspace.initializeFs()
+func (workspace *TerraformWorkspace) Validate(ctx context.Context) error {
+	err := workspace.initializeFs(ctx)
 	defer workspace.teardownFs()
 	if err != nil {
 		return err
 	}
 
-	_, err = workspace.runTf("validate", "-no-color")
+	_, err = workspace.runTf(ctx, "validate", "-no-color")
 
 	return err
 }
 
 // Apply runs `terraform apply` on this workspace.
 // This funciton blocks if another Terraform command is running on this workspace.
-func (workspace *TerraformWorkspace) Apply() error {
-	err := workspace.initializeFs()
+func (workspace *TerraformWorkspace) Apply(ctx context.Context) error {
+	err := workspace.initializeFs(ctx)
 	defer workspace.teardownFs()
 	if err != nil {
 		return err
 	}
 
-	_, err = workspace.runTf("apply", "-auto-approve", "-no-color")
+	_, err = workspace.runTf(ctx, "apply", "-auto-approve", "-no-color")
 	return err
 }
 
 // Destroy runs `terraform destroy` on this workspace.
 // This funciton blocks if another Terraform command is running on this workspace.
-func (workspace *TerraformWorkspace) Destroy() error {
-	err := workspace.initializeFs()
+func (workspace *TerraformWorkspace) Destroy(ctx context.Context) error {
+	err := workspace.initializeFs(ctx)
 	defer workspace.teardownFs()
 	if err != nil {
 		return err
 	}
 
-	_, err = workspace.runTf("destroy", "-auto-approve", "-no-color")
+	_, err = workspace.runTf(ctx, "destroy", "-auto-approve", "-no-color")
+	return err
+}
+
+// Refresh runs `terraform refresh` on this workspace, updating the state to
+// match the real resources. Resources that no longer exist are removed.
+// This funciton blocks if another Terraform command is running on this workspace.
+func (workspace *TerraformWorkspace) Refresh(ctx context.Context) error {
+	err := workspace.initializeFs(ctx)
+	defer workspace.teardownFs()
+	if err != nil {
+		return err
+	}
+
+	_, err = workspace.runTf(ctx, "refresh", "-no-color")
 	return err
 }
 
 // Apply runs `terraform import` on this workspace.
 // This funciton blocks if another Terraform command is running on this workspace.
-func (workspace *TerraformWorkspace) Import(resources map[string]string) error {
-	err := workspace.initializeFs()
+func (workspace *TerraformWorkspace) Import(ctx context.Context, resources map[string]string) error {
+	err := workspace.initializeFs(ctx)
 	defer workspace.teardownFs()
 	if err != nil {
 		return err
 	}
 
 	for resource, id := range resources {
-		_, err = workspace.runTf("import", resource, id)
+		_, err = workspace.runTf(ctx, "import", resource, id)
 		if err != nil {
 			return err
 		}
@@ -373,14 +389,14 @@ func (workspace *TerraformWorkspace) Import(resources map[string]string) error {
 
 // Apply runs `terraform show` on this workspace.
 // This funciton blocks if another Terraform command is running on this workspace.
-func (workspace *TerraformWorkspace) Show() (string, error) {
-	err := workspace.initializeFs()
+func (workspace *TerraformWorkspace) Show(ctx context.Context) (string, error) {
+	err := workspace.initializeFs(ctx)
 	defer workspace.teardownFs()
 	if err != nil {
 		return "", err
 	}
 
-	output, err := workspace.runTf("show", "-no-color")
+	output, err := workspace.runTf(ctx, "show", "-no-color")
 
 	return output.StdOut, nil
 }
@@ -389,7 +405,7 @@ func (workspace *TerraformWorkspace) tfStatePath() string {
 	return path.Join(workspace.dir, "terraform.tfstate")
 }
 
-func (workspace *TerraformWorkspace) runTf(subCommand string, args ...string) (ExecutionOutput, error) {
+func (workspace *TerraformWorkspace) runTf(ctx context.Context, subCommand string, args ...string) (ExecutionOutput, error) {
 	sub := []string{subCommand}
 	sub = append(sub, args...)
 
@@ -402,18 +418,18 @@ func (workspace *TerraformWorkspace) runTf(subCommand string, args ...string) (E
 		executor = workspace.Executor
 	}
 
-	return executor(c)
+	return executor(ctx, c)
 }
 
 // CustomEnvironmentExecutor sets custom environment variables on the Terraform
 // execution.
 func CustomEnvironmentExecutor(environment map[string]string, wrapped TerraformExecutor) TerraformExecutor {
-	return func(c *exec.Cmd) (ExecutionOutput, error) {
+	return func(ctx context.Context, c *exec.Cmd) (ExecutionOutput, error) {
 		for k, v := range environment {
 			c.Env = append(c.Env, fmt.Sprintf("%s=%s", k, v))
 		}
 
-		return wrapped(c)
+		return wrapped(ctx, c)
 	}
 }
 
@@ -431,7 +447,7 @@ func updatePath(vars []string, path string) string {
 // from a given plugin directory rather than the Terraform that's on the PATH
 // which will download provider binaries from the web.
 func CustomTerraformExecutor(tfBinaryPath, tfPluginDir string, wrapped TerraformExecutor) TerraformExecutor {
-	return func(c *exec.Cmd) (ExecutionOutput, error) {
+	return func(ctx context.Context, c *exec.Cmd) (ExecutionOutput, error) {
 
 		// Add the -get-plugins=false and -plugin-dir={tfPluginDir} after the
 		// sub-command to force Terraform to use a particular plugin.
@@ -446,13 +462,13 @@ func CustomTerraformExecutor(tfBinaryPath, tfPluginDir string, wrapped Terraform
 		newCmd := exec.Command(tfBinaryPath, allArgs...)
 		newCmd.Dir = c.Dir
 		newCmd.Env = append(c.Env, updatePath(c.Env, tfPluginDir))
-		return wrapped(newCmd)
+		return wrapped(ctx, newCmd)
 	}
 }
 
 // DefaultExecutor is the default executor that shells out to Terraform
 // and logs results to stdout.
-func DefaultExecutor(c *exec.Cmd) (ExecutionOutput, error) {
+func DefaultExecutor(ctx context.Context, c *exec.Cmd) (ExecutionOutput, error) {
 	logger := utils.NewLogger("terraform@" + c.Dir)
 
 	logger.Info("starting process", lager.Data{
@@ -475,6 +491,19 @@ func DefaultExecutor(c *exec.Cmd) (ExecutionOutput, error) {
 		return ExecutionOutput{}, fmt.Errorf("Failed to execute terraform: %v", err)
 	}
 
+	// interrupt rather than kill Terraform so it stops once the operations
+	// in flight are done and saves the state
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			logger.Info("interrupting process", lager.Data{"reason": ctx.Err().Error()})
+			c.Process.Signal(os.Interrupt)
+		case <-exited:
+		}
+	}()
+
 	output, _ := ioutil.ReadAll(stdout)
 	errors, _ := ioutil.ReadAll(stderr)
 
@@ -15,6 +15,7 @@
 package wrapper
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -34,19 +35,22 @@ func TestTerraformWorkspace_Invariants(t *testing.T) {
 		Exec func(ws *TerraformWorkspace)
 	}{
 		"validate": {Exec: func(ws *TerraformWorkspace) {
-			ws.Validate()
+			ws.Validate(context.Background())
 		}},
 		"apply": {Exec: func(ws *TerraformWorkspace) {
-			ws.Apply()
+			ws.Apply(context.Background())
 		}},
 		"destroy": {Exec: func(ws *TerraformWorkspace) {
-			ws.Destroy()
+			ws.Destroy(context.Background())
+		}},
+		"refresh": {Exec: func(ws *TerraformWorkspace) {
+			ws.Refresh(context.Background())
 		}},
 		"import": {Exec: func(ws *TerraformWorkspace) {
-			ws.Import(map[string]string{})
+			ws.Import(context.Background(), map[string]string{})
 		}},
 		"show": {Exec: func(ws *TerraformWorkspace) {
-			ws.Show()
+			ws.Show(context.Background())
 		}},	}
 
 	for tn, tc := range cases {
@@ -62,7 +66,7 @@ func TestTerraformWorkspace_Invariants(t *testing.T) {
 			// "running" tf
 			executorRan := false
 			cmdDir := ""
-			ws.Executor = func(cmd *exec.Cmd) (ExecutionOutput, error) {
+			ws.Executor = func(ctx context.Context, cmd *exec.Cmd) (ExecutionOutput, error) {
 				executorRan = true
 				cmdDir = cmd.Dir
 
@@ -119,19 +123,19 @@ func TestTerraformWorkspace_InvariantsFlat(t *testing.T) {
 		Exec func(ws *TerraformWorkspace)
 	}{
 		"validate": {Exec: func(ws *TerraformWorkspace) {
-			ws.Validate()
+			ws.Validate(context.Background())
 		}},
 		"apply": {Exec: func(ws *TerraformWorkspace) {
-			ws.Apply()
+			ws.Apply(context.Background())
 		}},
 		"destroy": {Exec: func(ws *TerraformWorkspace) {
-			ws.Destroy()
+			ws.Destroy(context.Background())
 		}},
 		"import": {Exec: func(ws *TerraformWorkspace) {
-			ws.Import(map[string]string{})
+			ws.Import(context.Background(), map[string]string{})
 		}},
 		"show": {Exec: func(ws *TerraformWorkspace) {
-			ws.Show()
+			ws.Show(context.Background())
 		}},	}
 
 	for tn, tc := range cases {
@@ -147,7 +151,7 @@ func TestTerraformWorkspace_InvariantsFlat(t *testing.T) {
 			// "running" tf
 			executorRan := false
 			cmdDir := ""
-			ws.Executor = func(cmd *exec.Cmd) (ExecutionOutput, error) {
+			ws.Executor = func(ctx context.Context, cmd *exec.Cmd) (ExecutionOutput, error) {
 				executorRan = true
 				cmdDir = cmd.Dir
 
@@ -235,12 +239,12 @@ func TestCustomTerraformExecutor(t *testing.T) {
 		t.Run(tn, func(t *testing.T) {
 			actual := exec.Command("!actual-never-got-called!")
 
-			executor := CustomTerraformExecutor(customBinary, customPlugins, func(c *exec.Cmd) (ExecutionOutput, error) {
+			executor := CustomTerraformExecutor(customBinary, customPlugins, func(ctx context.Context, c *exec.Cmd) (ExecutionOutput, error) {
 				actual = c
 				return ExecutionOutput{}, nil
 			})
 
-			executor(tc.Input)
+			executor(context.Background(), tc.Input)
 
 			if actual.Path != tc.Expected.Path {
 				t.Errorf("path wasn't updated, expected: %q, actual: %q", tc.Expected.Path, actual.Path)
@@ -262,12 +266,12 @@ func TestCustomEnvironmentExecutor(t *testing.T) {
 	c.Env = []string{"ORIGINAL=value"}
 
 	actual := exec.Command("!actual-never-got-called!")
-	executor := CustomEnvironmentExecutor(map[string]string{"FOO": "bar"}, func(c *exec.Cmd) (ExecutionOutput, error) {
+	executor := CustomEnvironmentExecutor(map[string]string{"FOO": "bar"}, func(ctx context.Context, c *exec.Cmd) (ExecutionOutput, error) {
 		actual = c
 		return ExecutionOutput{}, nil
 	})
 
-	executor(c)
+	executor(context.Background(), c)
 	expected := []string{"ORIGINAL=value", "FOO=bar"}
 
 	if !reflect.DeepEqual(expected, actual.Env) {
@@ -0,0 +1,277 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quota limits the number of service instances organizations and
+// spaces can provision.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/viper"
+)
+
+const (
+	// RulesProp holds a JSON list of Rules.
+	RulesProp = "quota.rules"
+
+	// OrganizationScope limits instances per organization.
+	OrganizationScope = "organization"
+	// SpaceScope limits instances per space.
+	SpaceScope = "space"
+
+	// lockTTL bounds how long a crashed broker's reservation blocks others.
+	lockTTL = 5 * time.Minute
+	// lockWait is how long a reservation waits for others in the same
+	// organization or space to finish.
+	lockWait = 30 * time.Second
+	// lockPollInterval is how often a waiting reservation tries the lock.
+	lockPollInterval = 250 * time.Millisecond
+)
+
+func init() {
+	config.Register(config.Property{Key: RulesProp, Kind: config.Json, Default: "[]"})
+}
+
+// Rule caps the number of instances of a service in an organization or space.
+type Rule struct {
+	// Service holds the name or ID of the service the rule applies to. If blank
+	// the rule applies to every service individually.
+	Service string `json:"service,omitempty"`
+	// Scope is either "organization" or "space".
+	Scope string `json:"scope"`
+	// Guid holds the GUID of the organization or space the rule applies to. If
+	// blank the rule applies to every organization or space individually.
+	Guid string `json:"guid,omitempty"`
+	// Limit is the maximum number of instances allowed.
+	Limit int `json:"limit"`
+}
+
+var _ validation.Validatable = (*Rule)(nil)
+
+// Validate implements validation.Validatable.
+func (r *Rule) Validate() (errs *validation.FieldError) {
+	if r.Scope != OrganizationScope && r.Scope != SpaceScope {
+		errs = errs.Also(validation.ErrInvalidValue(r.Scope, "scope"))
+	}
+
+	if r.Limit < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(r.Limit, "limit"))
+	}
+
+	return errs
+}
+
+// appliesTo returns true if the rule limits the given service in the given
+// organization or space.
+func (r *Rule) appliesTo(svc *broker.ServiceDefinition, scopeGuid string) bool {
+	if scopeGuid == "" {
+		return false
+	}
+
+	if r.Service != "" && r.Service != svc.Id && r.Service != svc.Name {
+		return false
+	}
+
+	return r.Guid == "" || r.Guid == scopeGuid
+}
+
+// lockName gets the name of the lock serializing reservations the rule
+// counts.
+func (r *Rule) lockName(svc *broker.ServiceDefinition, scopeGuid string) string {
+	return fmt.Sprintf("quota/%s/%s/%s", svc.Id, r.Scope, scopeGuid)
+}
+
+// conditions gets the query matching instances the rule counts.
+func (r *Rule) conditions(svc *broker.ServiceDefinition, scopeGuid string) models.ServiceInstanceDetails {
+	conditions := models.ServiceInstanceDetails{ServiceId: svc.Id}
+	if r.Scope == OrganizationScope {
+		conditions.OrganizationGuid = scopeGuid
+	} else {
+		conditions.SpaceGuid = scopeGuid
+	}
+
+	return conditions
+}
+
+// RulesFromEnv loads the quota rules from Viper.
+func RulesFromEnv() ([]Rule, error) {
+	var rules []Rule
+	if err := json.Unmarshal([]byte(viper.GetString(RulesProp)), &rules); err != nil {
+		return nil, fmt.Errorf("couldn't deserialize %s: %v", RulesProp, err)
+	}
+
+	for i, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", RulesProp, err.ViaIndex(i))
+		}
+	}
+
+	return rules, nil
+}
+
+// ExceededError is returned when provisioning an instance would exceed a
+// quota rule.
+type ExceededError struct {
+	Rule        Rule
+	ServiceName string
+	ScopeGuid   string
+	Used        int
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded: %s %q already has %d of %d allowed instances of %s, delete an instance or contact your operator to raise the quota",
+		e.Rule.Scope, e.ScopeGuid, e.Used, e.Rule.Limit, e.ServiceName)
+}
+
+// Usage describes how much of a rule's limit is in use.
+type Usage struct {
+	Rule        Rule   `json:"rule"`
+	ServiceName string `json:"service_name"`
+	ScopeGuid   string `json:"scope_guid"`
+	Used        int    `json:"used"`
+}
+
+// Store holds the instances quotas count. Its leader leases serialize
+// reservations across broker instances. db_service.Datastore implements it.
+type Store interface {
+	// CountServiceInstanceDetails counts instances matching the non-zero
+	// conditions.
+	CountServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) (int, error)
+	AcquireLeaderLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLeaderLease(ctx context.Context, name, holder string) error
+}
+
+// Enforcer checks quota rules against the instances in the database.
+type Enforcer struct {
+	Rules []Rule
+	Store Store
+}
+
+// Usage gets the usage of every rule that applies to instances of the service
+// in the given organization and space.
+func (e *Enforcer) Usage(ctx context.Context, svc *broker.ServiceDefinition, orgGuid, spaceGuid string) ([]Usage, error) {
+	var out []Usage
+	for _, rule := range e.Rules {
+		scopeGuid := orgGuid
+		if rule.Scope == SpaceScope {
+			scopeGuid = spaceGuid
+		}
+
+		if !rule.appliesTo(svc, scopeGuid) {
+			continue
+		}
+
+		used, err := e.Store.CountServiceInstanceDetails(ctx, rule.conditions(svc, scopeGuid))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't count instances for quota: %v", err)
+		}
+
+		out = append(out, Usage{Rule: rule, ServiceName: svc.Name, ScopeGuid: scopeGuid, Used: used})
+	}
+
+	return out, nil
+}
+
+// Check returns an ExceededError if provisioning another instance of the
+// service in the given organization and space would exceed a rule.
+func (e *Enforcer) Check(ctx context.Context, svc *broker.ServiceDefinition, orgGuid, spaceGuid string) error {
+	usages, err := e.Usage(ctx, svc, orgGuid, spaceGuid)
+	if err != nil {
+		return err
+	}
+
+	for _, usage := range usages {
+		if usage.Used >= usage.Rule.Limit {
+			return &ExceededError{Rule: usage.Rule, ServiceName: usage.ServiceName, ScopeGuid: usage.ScopeGuid, Used: usage.Used}
+		}
+	}
+
+	return nil
+}
+
+// Reserve checks the quota like Check, holding a lock on every organization
+// and space a rule limits so concurrent provisions there can't also take the
+// place. Callers call release once the instance is saved or provisioning
+// fails; a lock that isn't released expires after a few minutes.
+func (e *Enforcer) Reserve(ctx context.Context, svc *broker.ServiceDefinition, orgGuid, spaceGuid string) (release func(), err error) {
+	names := utils.NewStringSet()
+	for _, rule := range e.Rules {
+		scopeGuid := orgGuid
+		if rule.Scope == SpaceScope {
+			scopeGuid = spaceGuid
+		}
+
+		if rule.appliesTo(svc, scopeGuid) {
+			names.Add(rule.lockName(svc, scopeGuid))
+		}
+	}
+
+	holder := uuid.New()
+	var held []string
+	release = func() {
+		// the reservation's context may be done, and locks that can't be
+		// released expire
+		for _, name := range held {
+			_ = e.Store.ReleaseLeaderLease(context.Background(), name, holder)
+		}
+	}
+
+	// locks are taken in order so reservations don't wait on each other
+	for _, name := range names.ToSlice() {
+		if err := e.lock(ctx, name, holder); err != nil {
+			release()
+			return nil, err
+		}
+		held = append(held, name)
+	}
+
+	if err := e.Check(ctx, svc, orgGuid, spaceGuid); err != nil {
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// lock waits until the holder acquires the named lock.
+func (e *Enforcer) lock(ctx context.Context, name, holder string) error {
+	ctx, cancel := context.WithTimeout(ctx, lockWait)
+	defer cancel()
+
+	for {
+		acquired, err := e.Store.AcquireLeaderLease(ctx, name, holder, lockTTL)
+		if err != nil {
+			return fmt.Errorf("couldn't lock %s: %v", name, err)
+		}
+		if acquired {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for other provisions to release %s, try again", name)
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
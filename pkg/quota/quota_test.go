@@ -0,0 +1,239 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/fakes"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/spf13/viper"
+)
+
+func TestRulesFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Value         string
+		ExpectedRules []Rule
+		ExpectedErr   string
+	}{
+		"default": {
+			Value:         "[]",
+			ExpectedRules: []Rule{},
+		},
+		"valid": {
+			Value: `[{"service":"google-storage","scope":"space","limit":3},{"scope":"organization","guid":"org-1","limit":0}]`,
+			ExpectedRules: []Rule{
+				{Service: "google-storage", Scope: SpaceScope, Limit: 3},
+				{Scope: OrganizationScope, Guid: "org-1", Limit: 0},
+			},
+		},
+		"bad json": {
+			Value:       `{`,
+			ExpectedErr: "couldn't deserialize quota.rules: unexpected end of JSON input",
+		},
+		"bad scope": {
+			Value:       `[{"scope":"foundation","limit":3}]`,
+			ExpectedErr: "invalid quota.rules: invalid value: foundation: [0].scope",
+		},
+		"negative limit": {
+			Value:       `[{"scope":"space","limit":-1}]`,
+			ExpectedErr: "invalid quota.rules: invalid value: -1: [0].limit",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(RulesProp, tc.Value)
+			defer viper.Set(RulesProp, nil)
+
+			rules, err := RulesFromEnv()
+			if tc.ExpectedErr != "" {
+				if err == nil || err.Error() != tc.ExpectedErr {
+					t.Fatalf("Expected error %q, got %v", tc.ExpectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(rules) != len(tc.ExpectedRules) || (len(rules) > 0 && !reflect.DeepEqual(rules, tc.ExpectedRules)) {
+				t.Errorf("Expected rules %v, got %v", tc.ExpectedRules, rules)
+			}
+		})
+	}
+}
+
+func TestEnforcer_Check(t *testing.T) {
+	svc := &broker.ServiceDefinition{Id: "svc-id", Name: "svc-name"}
+
+	// counts holds the number of existing instances in each org and space.
+	counts := map[string]int{
+		"org-full":   2,
+		"org-room":   1,
+		"space-full": 1,
+	}
+
+	countFn := func(ctx context.Context, conditions models.ServiceInstanceDetails) (int, error) {
+		if conditions.ServiceId != svc.Id {
+			t.Errorf("Expected count to be scoped to service %q, got %q", svc.Id, conditions.ServiceId)
+		}
+
+		return counts[conditions.OrganizationGuid+conditions.SpaceGuid], nil
+	}
+
+	cases := map[string]struct {
+		Rules       []Rule
+		Org         string
+		Space       string
+		ExpectedErr error
+	}{
+		"no rules": {
+			Org: "org-full",
+		},
+		"org rule with room": {
+			Rules: []Rule{{Scope: OrganizationScope, Limit: 2}},
+			Org:   "org-room",
+		},
+		"org rule full": {
+			Rules:       []Rule{{Scope: OrganizationScope, Limit: 2}},
+			Org:         "org-full",
+			ExpectedErr: &ExceededError{Rule: Rule{Scope: OrganizationScope, Limit: 2}, ServiceName: "svc-name", ScopeGuid: "org-full", Used: 2},
+		},
+		"space rule full": {
+			Rules:       []Rule{{Service: "svc-id", Scope: SpaceScope, Limit: 1}},
+			Org:         "org-room",
+			Space:       "space-full",
+			ExpectedErr: &ExceededError{Rule: Rule{Service: "svc-id", Scope: SpaceScope, Limit: 1}, ServiceName: "svc-name", ScopeGuid: "space-full", Used: 1},
+		},
+		"rule for other service": {
+			Rules: []Rule{{Service: "other", Scope: OrganizationScope, Limit: 0}},
+			Org:   "org-full",
+		},
+		"rule for other org": {
+			Rules: []Rule{{Scope: OrganizationScope, Guid: "org-other", Limit: 0}},
+			Org:   "org-full",
+		},
+		"rule by service name and guid": {
+			Rules:       []Rule{{Service: "svc-name", Scope: OrganizationScope, Guid: "org-room", Limit: 0}},
+			Org:         "org-room",
+			ExpectedErr: &ExceededError{Rule: Rule{Service: "svc-name", Scope: OrganizationScope, Guid: "org-room", Limit: 0}, ServiceName: "svc-name", ScopeGuid: "org-room", Used: 1},
+		},
+		"no org in request": {
+			Rules: []Rule{{Scope: OrganizationScope, Limit: 0}},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			enforcer := &Enforcer{Rules: tc.Rules, Store: &countingStore{InMemoryDatastore: fakes.NewInMemoryDatastore(), count: countFn}}
+
+			err := enforcer.Check(context.Background(), svc, tc.Org, tc.Space)
+			if !reflect.DeepEqual(err, tc.ExpectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.ExpectedErr, err)
+			}
+		})
+	}
+}
+
+func TestEnforcer_Check_countError(t *testing.T) {
+	countFn := func(ctx context.Context, conditions models.ServiceInstanceDetails) (int, error) {
+		return 0, errors.New("db down")
+	}
+	enforcer := &Enforcer{
+		Rules: []Rule{{Scope: OrganizationScope, Limit: 1}},
+		Store: &countingStore{InMemoryDatastore: fakes.NewInMemoryDatastore(), count: countFn},
+	}
+
+	err := enforcer.Check(context.Background(), &broker.ServiceDefinition{Id: "svc"}, "org", "space")
+	if err == nil || err.Error() != "couldn't count instances for quota: db down" {
+		t.Errorf("Expected count error, got %v", err)
+	}
+}
+
+func TestEnforcer_Reserve(t *testing.T) {
+	ctx := context.Background()
+	svc := &broker.ServiceDefinition{Id: "svc-id", Name: "svc-name"}
+	store := fakes.NewInMemoryDatastore()
+	enforcer := &Enforcer{Rules: []Rule{{Scope: OrganizationScope, Limit: 1}}, Store: store}
+
+	release, err := enforcer.Reserve(ctx, svc, "org-1", "space-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a released reservation that wasn't used leaves the place free
+	release()
+	release, err = enforcer.Reserve(ctx, svc, "org-1", "space-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// other organizations don't wait
+	otherRelease, err := enforcer.Reserve(ctx, svc, "org-2", "space-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRelease()
+
+	// a concurrent reservation waits for the first to save its instance, then
+	// finds no room
+	concurrent := make(chan error)
+	go func() {
+		_, err := enforcer.Reserve(ctx, svc, "org-1", "space-3")
+		concurrent <- err
+	}()
+
+	select {
+	case err := <-concurrent:
+		t.Fatalf("Expected the concurrent reservation to wait, got %v", err)
+	case <-time.After(2 * lockPollInterval):
+	}
+
+	instance := models.ServiceInstanceDetails{ID: "instance-1", ServiceId: svc.Id, OrganizationGuid: "org-1"}
+	if err := store.CreateServiceInstanceDetails(ctx, &instance); err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	if _, ok := (<-concurrent).(*ExceededError); !ok {
+		t.Error("Expected the concurrent reservation to exceed the quota")
+	}
+}
+
+func TestExceededError_Error(t *testing.T) {
+	err := &ExceededError{Rule: Rule{Scope: SpaceScope, Limit: 2}, ServiceName: "google-storage", ScopeGuid: "space-1", Used: 2}
+	expected := `quota exceeded: space "space-1" already has 2 of 2 allowed instances of google-storage, delete an instance or contact your operator to raise the quota`
+
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+// countingStore is an in-memory store that counts instances with count.
+type countingStore struct {
+	*fakes.InMemoryDatastore
+	count func(ctx context.Context, conditions models.ServiceInstanceDetails) (int, error)
+}
+
+func (s *countingStore) CountServiceInstanceDetails(ctx context.Context, conditions models.ServiceInstanceDetails) (int, error) {
+	return s.count(ctx, conditions)
+}
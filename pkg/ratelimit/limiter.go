@@ -0,0 +1,94 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket that allows rate requests per second on
+// average with bursts of up to burst requests.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	// now is used to get the current time, it can be replaced in tests.
+	now func() time.Time
+}
+
+// NewLimiter creates a Limiter that starts with a full bucket. Burst is
+// raised to 1 if it's lower so requests can ever be allowed.
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+func (l *Limiter) currentTime() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+
+	return time.Now()
+}
+
+// Allow takes a token if one is available. Otherwise it returns false and how
+// long until a token will be.
+func (l *Limiter) Allow() (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.currentTime()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+
+	wait := (1 - l.tokens) / l.rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// Wait blocks until a token is available and takes it, or returns the
+// context's error if it's done first.
+func (l *Limiter) Wait(ctx context.Context) error {
+	for {
+		allowed, wait := l.Allow()
+		if allowed {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := limiter.Allow(); !allowed {
+			t.Fatalf("Expected request %d of the burst to be allowed", i)
+		}
+	}
+
+	allowed, wait := limiter.Allow()
+	if allowed {
+		t.Fatal("Expected the request after the burst to be rejected")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("Expected to wait 500ms, got %v", wait)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if allowed, _ := limiter.Allow(); !allowed {
+		t.Error("Expected a request to be allowed once a token was added")
+	}
+
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if allowed, _ := limiter.Allow(); !allowed {
+			t.Fatalf("Expected request %d to be allowed after the bucket refilled", i)
+		}
+	}
+	if allowed, _ := limiter.Allow(); allowed {
+		t.Error("Expected the bucket to hold no more than the burst")
+	}
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	limiter := NewLimiter(1000, 1)
+
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Expected the first request to be allowed, got %v", err)
+	}
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Expected the second request to wait for a token, got %v", err)
+	}
+
+	slow := NewLimiter(0.001, 1)
+	slow.Allow()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := slow.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected the wait to end with the context, got %v", err)
+	}
+}
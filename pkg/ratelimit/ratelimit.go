@@ -0,0 +1,214 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimit protects the broker's database and cloud API quotas
+// from bursts of platform requests by limiting the rate of each OSB API
+// endpoint and the number of provisions and deprovisions running at once.
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// MaxConcurrentOperationsProp is the viper key of the maximum number of
+	// provisions and deprovisions running at once. Zero is unlimited.
+	MaxConcurrentOperationsProp = "ratelimit.max_concurrent_operations"
+
+	// MaxQueuedOperationsProp is the viper key of the maximum number of
+	// provision and deprovision requests waiting for a running one to finish.
+	MaxQueuedOperationsProp = "ratelimit.max_queued_operations"
+
+	// QueueTimeoutProp is the viper key of how long a queued request waits
+	// before it's rejected.
+	QueueTimeoutProp = "ratelimit.queue_timeout"
+
+	// RetryAfterProp is the viper key of how long platforms are told to wait
+	// before retrying a request rejected because of the operation limit.
+	RetryAfterProp = "ratelimit.retry_after"
+)
+
+// Endpoints are the names of the OSB API endpoints that can be rate limited.
+var Endpoints = []string{
+	"catalog",
+	"get_instance",
+	"provision",
+	"update",
+	"deprovision",
+	"last_operation",
+	"get_binding",
+	"bind",
+	"unbind",
+	"last_binding_operation",
+}
+
+// routes maps the method and path template of each OSB API route to its
+// endpoint name.
+var routes = map[string]string{
+	"GET /v2/catalog":                                                                      "catalog",
+	"GET /v2/service_instances/{instance_id}":                                              "get_instance",
+	"PUT /v2/service_instances/{instance_id}":                                              "provision",
+	"PATCH /v2/service_instances/{instance_id}":                                            "update",
+	"DELETE /v2/service_instances/{instance_id}":                                           "deprovision",
+	"GET /v2/service_instances/{instance_id}/last_operation":                               "last_operation",
+	"GET /v2/service_instances/{instance_id}/service_bindings/{binding_id}":                "get_binding",
+	"PUT /v2/service_instances/{instance_id}/service_bindings/{binding_id}":                "bind",
+	"DELETE /v2/service_instances/{instance_id}/service_bindings/{binding_id}":             "unbind",
+	"GET /v2/service_instances/{instance_id}/service_bindings/{binding_id}/last_operation": "last_binding_operation",
+}
+
+func init() {
+	config.Register(
+		config.Property{Key: MaxConcurrentOperationsProp, Kind: config.Integer, Default: 0},
+		config.Property{Key: MaxQueuedOperationsProp, Kind: config.Integer, Default: 100},
+		config.Property{Key: QueueTimeoutProp, Kind: config.Duration, Default: "10s"},
+		config.Property{Key: RetryAfterProp, Kind: config.Duration, Default: "30s"},
+	)
+}
+
+// RateProp is the viper key of the requests per second allowed to the
+// endpoint. Zero is unlimited.
+func RateProp(endpoint string) string {
+	return fmt.Sprintf("ratelimit.%s.rate", endpoint)
+}
+
+// BurstProp is the viper key of the number of requests to the endpoint
+// allowed at once above its rate. It defaults to the rate.
+func BurstProp(endpoint string) string {
+	return fmt.Sprintf("ratelimit.%s.burst", endpoint)
+}
+
+// Endpoint returns the name of the OSB API endpoint the request was routed
+// to, or an empty string if it's not one.
+func Endpoint(req *http.Request) string {
+	route := mux.CurrentRoute(req)
+	if route == nil {
+		return ""
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+
+	return routes[req.Method+" "+template]
+}
+
+// Middleware rejects requests over the limits with 429 Too Many Requests and
+// a Retry-After header.
+type Middleware struct {
+	// Limits holds the limiter of each rate limited endpoint.
+	Limits map[string]*Limiter
+	// Operations limits concurrent provisions and deprovisions if it's set.
+	Operations *Semaphore
+	// RetryAfter is sent when Operations rejects a request.
+	RetryAfter time.Duration
+	Logger     lager.Logger
+}
+
+// NewMiddlewareFromEnv creates a Middleware from the limits configured in
+// viper.
+func NewMiddlewareFromEnv(logger lager.Logger) (*Middleware, error) {
+	m := &Middleware{Limits: make(map[string]*Limiter), Logger: logger.Session("ratelimit")}
+
+	for _, endpoint := range Endpoints {
+		rate := viper.GetFloat64(RateProp(endpoint))
+		if rate < 0 {
+			return nil, fmt.Errorf("%s must not be negative, got %v", RateProp(endpoint), rate)
+		}
+		if rate == 0 {
+			continue
+		}
+
+		burst := viper.GetInt(BurstProp(endpoint))
+		if burst == 0 {
+			burst = int(math.Ceil(rate))
+		}
+
+		m.Limits[endpoint] = NewLimiter(rate, burst)
+	}
+
+	if size := viper.GetInt(MaxConcurrentOperationsProp); size > 0 {
+		timeout, err := time.ParseDuration(viper.GetString(QueueTimeoutProp))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse %s: %v", QueueTimeoutProp, err)
+		}
+
+		m.Operations = NewSemaphore(size, viper.GetInt(MaxQueuedOperationsProp), timeout)
+	}
+
+	retryAfter, err := time.ParseDuration(viper.GetString(RetryAfterProp))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", RetryAfterProp, err)
+	}
+	m.RetryAfter = retryAfter
+
+	return m, nil
+}
+
+// Wrap limits requests to the handler. It must be added to the router the
+// OSB API routes are attached to so the endpoint can be identified.
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		endpoint := Endpoint(req)
+
+		if limiter, ok := m.Limits[endpoint]; ok {
+			if allowed, wait := limiter.Allow(); !allowed {
+				m.reject(w, endpoint, wait, fmt.Errorf("the %s endpoint is over its rate limit", endpoint))
+				return
+			}
+		}
+
+		if m.Operations == nil || (endpoint != "provision" && endpoint != "deprovision") {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		release, err := m.Operations.Acquire(req.Context())
+		if err != nil {
+			m.reject(w, endpoint, m.RetryAfter, err)
+			return
+		}
+
+		defer release()
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (m *Middleware) reject(w http.ResponseWriter, endpoint string, retryAfter time.Duration, err error) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	if m.Logger != nil {
+		m.Logger.Info("rejected", lager.Data{"endpoint": endpoint, "reason": err.Error(), "retry_after": seconds})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]string{"description": err.Error()})
+}
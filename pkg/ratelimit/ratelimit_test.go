@@ -0,0 +1,163 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+)
+
+func newTestRouter(m *Middleware, handler http.HandlerFunc) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/catalog", handler).Methods(http.MethodGet)
+	router.HandleFunc("/v2/service_instances/{instance_id}", handler).Methods(http.MethodPut, http.MethodDelete)
+	router.Use(m.Wrap)
+	return router
+}
+
+func TestMiddleware_Wrap(t *testing.T) {
+	cases := map[string]struct {
+		Middleware       *Middleware
+		Method           string
+		Path             string
+		Requests         int
+		ExpectedStatuses []int
+		RetryAfter       string
+	}{
+		"unlimited": {
+			Middleware:       &Middleware{},
+			Method:           http.MethodGet,
+			Path:             "/v2/catalog",
+			Requests:         3,
+			ExpectedStatuses: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		"rate limited": {
+			Middleware:       &Middleware{Limits: map[string]*Limiter{"catalog": NewLimiter(0.1, 2)}},
+			Method:           http.MethodGet,
+			Path:             "/v2/catalog",
+			Requests:         3,
+			ExpectedStatuses: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+			RetryAfter:       "10",
+		},
+		"other endpoints aren't rate limited": {
+			Middleware:       &Middleware{Limits: map[string]*Limiter{"provision": NewLimiter(0.1, 1)}},
+			Method:           http.MethodGet,
+			Path:             "/v2/catalog",
+			Requests:         2,
+			ExpectedStatuses: []int{http.StatusOK, http.StatusOK},
+		},
+		"operations are released after synchronous requests": {
+			Middleware:       &Middleware{Operations: NewSemaphore(1, 0, time.Millisecond), RetryAfter: 30 * time.Second},
+			Method:           http.MethodPut,
+			Path:             "/v2/service_instances/instance-1",
+			Requests:         2,
+			ExpectedStatuses: []int{http.StatusOK, http.StatusOK},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := newTestRouter(tc.Middleware, func(w http.ResponseWriter, req *http.Request) {})
+
+			for i := 0; i < tc.Requests; i++ {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(tc.Method, tc.Path, nil))
+
+				if w.Code != tc.ExpectedStatuses[i] {
+					t.Errorf("Expected request %d to get status %d, got %d", i, tc.ExpectedStatuses[i], w.Code)
+				}
+				if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") != tc.RetryAfter {
+					t.Errorf("Expected Retry-After %q, got %q", tc.RetryAfter, w.Header().Get("Retry-After"))
+				}
+			}
+		})
+	}
+}
+
+func TestMiddleware_Wrap_concurrentOperations(t *testing.T) {
+	m := &Middleware{Operations: NewSemaphore(1, 0, time.Millisecond), RetryAfter: 30 * time.Second}
+
+	unblock := make(chan struct{})
+	router := newTestRouter(m, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPut {
+			<-unblock
+		}
+	})
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/v2/service_instances/instance-1", nil))
+		return w
+	}
+
+	provisioned := make(chan int)
+	go func() { provisioned <- serve(http.MethodPut).Code }()
+	for m.Operations.Running() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	w := serve(http.MethodDelete)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected a deprovision to be rejected while the provision runs, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "30" {
+		t.Errorf("Expected Retry-After 30, got %q", w.Header().Get("Retry-After"))
+	}
+
+	close(unblock)
+	if code := <-provisioned; code != http.StatusOK {
+		t.Fatalf("Expected the provision to be accepted, got %d", code)
+	}
+	if w := serve(http.MethodDelete); w.Code != http.StatusOK {
+		t.Errorf("Expected a deprovision to be accepted once the provision finished, got %d", w.Code)
+	}
+}
+
+func TestNewMiddlewareFromEnv(t *testing.T) {
+	viper.Set(RateProp("provision"), 2.5)
+	viper.Set(MaxConcurrentOperationsProp, 4)
+	defer viper.Set(RateProp("provision"), nil)
+	defer viper.Set(MaxConcurrentOperationsProp, nil)
+
+	m, err := NewMiddlewareFromEnv(lager.NewLogger("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.Limits) != 1 || m.Limits["provision"] == nil {
+		t.Fatalf("Expected only provision to be rate limited, got %v", m.Limits)
+	}
+	if m.Limits["provision"].burst != 3 {
+		t.Errorf("Expected the burst to default to the rate rounded up, got %v", m.Limits["provision"].burst)
+	}
+	if m.Operations == nil || cap(m.Operations.slots) != 4 {
+		t.Errorf("Expected 4 operation slots, got %v", m.Operations)
+	}
+	if m.RetryAfter != 30*time.Second {
+		t.Errorf("Expected the default retry after, got %v", m.RetryAfter)
+	}
+
+	viper.Set(RateProp("bind"), -1)
+	defer viper.Set(RateProp("bind"), nil)
+	if _, err := NewMiddlewareFromEnv(lager.NewLogger("test")); err == nil {
+		t.Error("Expected an error for a negative rate")
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned by Semaphore.Acquire if every slot is taken
+	// and the queue is full.
+	ErrQueueFull = errors.New("too many operations are running and queued")
+
+	// ErrQueueTimeout is returned by Semaphore.Acquire if no slot became
+	// free while the caller was queued.
+	ErrQueueTimeout = errors.New("timed out waiting for a running operation to finish")
+)
+
+// Semaphore limits how many operations run at once. Callers that can't get a
+// slot wait in a bounded queue for up to a timeout.
+type Semaphore struct {
+	slots     chan struct{}
+	maxQueued int
+	timeout   time.Duration
+
+	mu     sync.Mutex
+	queued int
+}
+
+// NewSemaphore creates a Semaphore with size slots that queues up to
+// maxQueued callers for at most timeout each.
+func NewSemaphore(size, maxQueued int, timeout time.Duration) *Semaphore {
+	return &Semaphore{slots: make(chan struct{}, size), maxQueued: maxQueued, timeout: timeout}
+}
+
+// Acquire takes a slot, waiting in the queue if none are free. The returned
+// function frees the slot, calling it more than once has no effect.
+func (s *Semaphore) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case s.slots <- struct{}{}:
+		return s.releaser(), nil
+	default:
+	}
+
+	s.mu.Lock()
+	if s.queued >= s.maxQueued {
+		s.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	s.queued++
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.queued--
+		s.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+
+	select {
+	case s.slots <- struct{}{}:
+		return s.releaser(), nil
+	case <-timer.C:
+		return nil, ErrQueueTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Semaphore) releaser() func() {
+	once := sync.Once{}
+	return func() {
+		once.Do(func() { <-s.slots })
+	}
+}
+
+// Running returns the number of slots that are taken.
+func (s *Semaphore) Running() int {
+	return len(s.slots)
+}
+
+// Queued returns the number of callers waiting for a slot.
+func (s *Semaphore) Queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queued
+}
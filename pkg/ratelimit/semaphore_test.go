@@ -0,0 +1,72 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSemaphore_Acquire(t *testing.T) {
+	ctx := context.Background()
+	sem := NewSemaphore(1, 1, time.Second)
+
+	release, err := sem.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sem.Running() != 1 {
+		t.Errorf("Expected 1 running operation, got %d", sem.Running())
+	}
+
+	acquired := make(chan error)
+	go func() {
+		queuedRelease, err := sem.Acquire(ctx)
+		if err == nil {
+			queuedRelease()
+		}
+		acquired <- err
+	}()
+
+	for sem.Queued() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := sem.Acquire(ctx); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull with the queue full, got %v", err)
+	}
+
+	release()
+	release()
+	if err := <-acquired; err != nil {
+		t.Errorf("Expected the queued caller to get the slot, got %v", err)
+	}
+
+	if sem.Running() != 0 || sem.Queued() != 0 {
+		t.Errorf("Expected every slot to be free, got %d running %d queued", sem.Running(), sem.Queued())
+	}
+}
+
+func TestSemaphore_Acquire_timeout(t *testing.T) {
+	sem := NewSemaphore(1, 1, 10*time.Millisecond)
+	if _, err := sem.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sem.Acquire(context.Background()); err != ErrQueueTimeout {
+		t.Errorf("Expected ErrQueueTimeout, got %v", err)
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package recorder records the OSB requests made for each instance and the
+// broker's responses, with credentials redacted, so failed operations can be
+// triaged with platform teams from what was actually exchanged.
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/logging"
+	"github.com/pivotal/cloud-service-broker/pkg/supportbundle"
+	"github.com/spf13/viper"
+)
+
+const (
+	// EnabledProp is the viper key of whether exchanges are recorded.
+	EnabledProp = "recorder.enabled"
+
+	// RetentionProp is the viper key of how long exchanges are kept.
+	RetentionProp = "recorder.retention"
+
+	// maxBodyBytes is the most of each body that's recorded.
+	maxBodyBytes = 64 * 1024
+
+	// pruneInterval is how often exchanges older than the retention are
+	// deleted.
+	pruneInterval = time.Hour
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: EnabledProp, Kind: config.Boolean, Default: false},
+		config.Property{Key: RetentionProp, Kind: config.Duration, Default: "72h"},
+	)
+}
+
+// Store holds the recorded exchanges.
+type Store interface {
+	CreateRecordedExchange(ctx context.Context, exchange *models.RecordedExchange) error
+	ListRecordedExchanges(ctx context.Context, instanceId string, limit int) ([]models.RecordedExchange, error)
+	DeleteRecordedExchangesBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// databaseStore uses the broker's database.
+type databaseStore struct{}
+
+func (databaseStore) CreateRecordedExchange(ctx context.Context, exchange *models.RecordedExchange) error {
+	return db_service.CreateRecordedExchange(ctx, exchange)
+}
+
+func (databaseStore) ListRecordedExchanges(ctx context.Context, instanceId string, limit int) ([]models.RecordedExchange, error) {
+	return db_service.ListRecordedExchanges(ctx, instanceId, limit)
+}
+
+func (databaseStore) DeleteRecordedExchangesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return db_service.DeleteRecordedExchangesBefore(ctx, cutoff)
+}
+
+// Exchange is a recorded request and response.
+type Exchange struct {
+	Time          time.Time       `json:"time"`
+	InstanceId    string          `json:"instance_id"`
+	BindingId     string          `json:"binding_id,omitempty"`
+	CorrelationId string          `json:"correlation_id,omitempty"`
+	Method        string          `json:"method"`
+	Path          string          `json:"path"`
+	ApiVersion    string          `json:"api_version,omitempty"`
+	Request       json.RawMessage `json:"request,omitempty"`
+	Status        int             `json:"status"`
+	Response      json.RawMessage `json:"response,omitempty"`
+	Duration      string          `json:"duration"`
+}
+
+// Recorder records the OSB requests for instances and their bindings.
+type Recorder struct {
+	Store     Store
+	Retention time.Duration
+	Logger    lager.Logger
+
+	// now is used to get the current time, it can be replaced in tests.
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewRecorderFromEnv creates a Recorder using the broker's database, or
+// returns nil if recording isn't enabled.
+func NewRecorderFromEnv(logger lager.Logger) (*Recorder, error) {
+	if !viper.GetBool(EnabledProp) {
+		return nil, nil
+	}
+
+	retention, err := time.ParseDuration(viper.GetString(RetentionProp))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", RetentionProp, err)
+	}
+	if retention <= 0 {
+		return nil, fmt.Errorf("%s must be positive, got %s", RetentionProp, retention)
+	}
+
+	return &Recorder{
+		Store:     databaseStore{},
+		Retention: retention,
+		Logger:    logger.Session("recorder"),
+	}, nil
+}
+
+func (r *Recorder) currentTime() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+
+	return time.Now()
+}
+
+// Wrap records the requests for instances and their bindings, and the
+// responses to them. Failing to record an exchange is logged without
+// affecting the response. It must be added to the router the OSB API routes
+// are attached to so the instance can be identified.
+func (r *Recorder) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		vars := mux.Vars(req)
+		instanceId := vars["instance_id"]
+		if instanceId == "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		var requestBody []byte
+		if req.Body != nil {
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				r.Logger.Error("reading-request", err)
+			}
+			req.Body.Close()
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			requestBody = body
+		}
+
+		start := r.currentTime()
+		recorded := &recordingResponse{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorded, req)
+
+		exchange := &models.RecordedExchange{
+			InstanceId:     instanceId,
+			BindingId:      vars["binding_id"],
+			CorrelationId:  logging.CorrelationId(req.Context()),
+			Method:         req.Method,
+			Path:           req.URL.RequestURI(),
+			ApiVersion:     req.Header.Get("X-Broker-API-Version"),
+			RequestBody:    redact(requestBody),
+			Status:         recorded.status,
+			ResponseBody:   redact(recorded.body.Bytes()),
+			DurationMillis: r.currentTime().Sub(start).Nanoseconds() / int64(time.Millisecond),
+		}
+
+		if err := r.Store.CreateRecordedExchange(req.Context(), exchange); err != nil {
+			r.Logger.Error("recording-exchange", err, lager.Data{"instance_id": instanceId})
+		}
+
+		r.prune(req.Context())
+	})
+}
+
+// prune deletes the exchanges older than the retention at most once per
+// pruneInterval.
+func (r *Recorder) prune(ctx context.Context) {
+	now := r.currentTime()
+
+	r.mu.Lock()
+	due := now.Sub(r.lastPrune) >= pruneInterval
+	if due {
+		r.lastPrune = now
+	}
+	r.mu.Unlock()
+
+	if !due {
+		return
+	}
+
+	deleted, err := r.Store.DeleteRecordedExchangesBefore(ctx, now.Add(-r.Retention))
+	if err != nil {
+		r.Logger.Error("pruning-exchanges", err)
+		return
+	}
+
+	if deleted > 0 {
+		r.Logger.Info("pruned-exchanges", lager.Data{"deleted": deleted})
+	}
+}
+
+// Exchanges lists up to limit of the exchanges recorded for the instance and
+// its bindings, newest first.
+func (r *Recorder) Exchanges(ctx context.Context, instanceId string, limit int) ([]Exchange, error) {
+	recorded, err := r.Store.ListRecordedExchanges(ctx, instanceId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	exchanges := []Exchange{}
+	for _, e := range recorded {
+		exchanges = append(exchanges, Exchange{
+			Time:          e.CreatedAt,
+			InstanceId:    e.InstanceId,
+			BindingId:     e.BindingId,
+			CorrelationId: e.CorrelationId,
+			Method:        e.Method,
+			Path:          e.Path,
+			ApiVersion:    e.ApiVersion,
+			Request:       rawJson(e.RequestBody),
+			Status:        e.Status,
+			Response:      rawJson(e.ResponseBody),
+			Duration:      (time.Duration(e.DurationMillis) * time.Millisecond).String(),
+		})
+	}
+
+	return exchanges, nil
+}
+
+// redact replaces credentials and other sensitive values in a JSON body.
+// Bodies that aren't JSON objects are only kept if they're empty, since
+// their contents can't be checked.
+func redact(body []byte) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ""
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return mustMarshal(supportbundle.Redacted)
+	}
+
+	return mustMarshal(supportbundle.Redact(parsed))
+}
+
+// mustMarshal encodes the value without escaping HTML so recorded bodies read
+// the same as what was sent.
+func mustMarshal(value interface{}) string {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return ""
+	}
+
+	if out.Len() > maxBodyBytes {
+		return mustMarshal(fmt.Sprintf("<%d bytes, truncated>", out.Len()))
+	}
+
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+func rawJson(value string) json.RawMessage {
+	if value == "" {
+		return nil
+	}
+
+	return json.RawMessage(value)
+}
+
+// recordingResponse passes a response through while keeping a copy of its
+// status and body.
+type recordingResponse struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recordingResponse) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recordingResponse) Write(data []byte) (int, error) {
+	if r.body.Len() < maxBodyBytes {
+		r.body.Write(data)
+	}
+
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,296 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recorder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/supportbundle"
+	"github.com/spf13/viper"
+)
+
+type fakeStore struct {
+	Created []models.RecordedExchange
+	Cutoffs []time.Time
+}
+
+func (f *fakeStore) CreateRecordedExchange(ctx context.Context, exchange *models.RecordedExchange) error {
+	f.Created = append(f.Created, *exchange)
+	return nil
+}
+
+func (f *fakeStore) ListRecordedExchanges(ctx context.Context, instanceId string, limit int) ([]models.RecordedExchange, error) {
+	var out []models.RecordedExchange
+	for i := len(f.Created) - 1; i >= 0 && len(out) < limit; i-- {
+		if f.Created[i].InstanceId == instanceId {
+			out = append(out, f.Created[i])
+		}
+	}
+
+	return out, nil
+}
+
+func (f *fakeStore) DeleteRecordedExchangesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	f.Cutoffs = append(f.Cutoffs, cutoff)
+	return 0, nil
+}
+
+func newTestRouter(rec *Recorder) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(rec.Wrap)
+
+	respond := func(status int, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}
+	}
+
+	router.Handle("/v2/catalog", respond(http.StatusOK, `{"services":[]}`)).Methods(http.MethodGet)
+	router.Handle("/v2/service_instances/{instance_id}", respond(http.StatusCreated, `{"dashboard_url":"https://example.com"}`)).Methods(http.MethodPut)
+	router.Handle("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", respond(http.StatusCreated, `{"credentials":{"password":"hunter2"}}`)).Methods(http.MethodPut)
+	return router
+}
+
+func TestRecorder_Wrap(t *testing.T) {
+	cases := map[string]struct {
+		Path           string
+		Body           string
+		ExpectRecorded bool
+		ExpectBinding  string
+		ExpectStatus   int
+		ExpectRequest  string
+		ExpectResponse string
+	}{
+		"catalog": {
+			Path: "/v2/catalog",
+		},
+		"provision": {
+			Path:           "/v2/service_instances/instance-1?accepts_incomplete=true",
+			Body:           `{"plan_id":"plan","parameters":{"name":"db","admin_password":"hunter2"}}`,
+			ExpectRecorded: true,
+			ExpectStatus:   http.StatusCreated,
+			ExpectRequest:  `{"parameters":{"admin_password":"` + supportbundle.Redacted + `","name":"db"},"plan_id":"plan"}`,
+			ExpectResponse: `{"dashboard_url":"https://example.com"}`,
+		},
+		"bind": {
+			Path:           "/v2/service_instances/instance-1/service_bindings/binding-1",
+			Body:           `{"plan_id":"plan"}`,
+			ExpectRecorded: true,
+			ExpectBinding:  "binding-1",
+			ExpectStatus:   http.StatusCreated,
+			ExpectRequest:  `{"plan_id":"plan"}`,
+			ExpectResponse: `{"credentials":"` + supportbundle.Redacted + `"}`,
+		},
+		"not-json": {
+			Path:           "/v2/service_instances/instance-1",
+			Body:           `password=hunter2`,
+			ExpectRecorded: true,
+			ExpectStatus:   http.StatusCreated,
+			ExpectRequest:  `"` + supportbundle.Redacted + `"`,
+			ExpectResponse: `{"dashboard_url":"https://example.com"}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			store := &fakeStore{}
+			rec := &Recorder{Store: store, Retention: time.Hour, Logger: lager.NewLogger("test")}
+			router := newTestRouter(rec)
+
+			method := http.MethodPut
+			if tc.Path == "/v2/catalog" {
+				method = http.MethodGet
+			}
+
+			req := httptest.NewRequest(method, tc.Path, strings.NewReader(tc.Body))
+			req.Header.Set("X-Broker-API-Version", "2.14")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if !tc.ExpectRecorded {
+				if len(store.Created) != 0 {
+					t.Errorf("Expected nothing to be recorded, got %v", store.Created)
+				}
+				return
+			}
+
+			if len(store.Created) != 1 {
+				t.Fatalf("Expected one exchange to be recorded, got %d", len(store.Created))
+			}
+
+			actual := store.Created[0]
+			expected := models.RecordedExchange{
+				InstanceId:   "instance-1",
+				BindingId:    tc.ExpectBinding,
+				Method:       http.MethodPut,
+				Path:         tc.Path,
+				ApiVersion:   "2.14",
+				RequestBody:  tc.ExpectRequest,
+				Status:       tc.ExpectStatus,
+				ResponseBody: tc.ExpectResponse,
+			}
+			actual.DurationMillis = 0
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Expected %+v, got %+v", expected, actual)
+			}
+
+			if w.Code != tc.ExpectStatus {
+				t.Errorf("Expected the response status %d to be passed through, got %d", tc.ExpectStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestRecorder_Wrap_restoresRequestBody(t *testing.T) {
+	rec := &Recorder{Store: &fakeStore{}, Retention: time.Hour, Logger: lager.NewLogger("test")}
+
+	var received string
+	router := mux.NewRouter()
+	router.Use(rec.Wrap)
+	router.HandleFunc("/v2/service_instances/{instance_id}", func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		received, _ = body["plan_id"].(string)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/v2/service_instances/instance-1", strings.NewReader(`{"plan_id":"plan"}`)))
+
+	if received != "plan" {
+		t.Errorf("Expected the handler to read the request body, got plan_id %q", received)
+	}
+}
+
+func TestRecorder_prune(t *testing.T) {
+	store := &fakeStore{}
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	rec := &Recorder{Store: store, Retention: 24 * time.Hour, Logger: lager.NewLogger("test"), now: func() time.Time { return now }}
+	router := newTestRouter(rec)
+
+	provision := func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/v2/service_instances/instance-1", nil))
+	}
+
+	provision()
+	now = now.Add(time.Minute)
+	provision()
+	now = now.Add(time.Hour)
+	provision()
+
+	expected := []time.Time{
+		time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2019, 12, 31, 1, 1, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(store.Cutoffs, expected) {
+		t.Errorf("Expected pruning at most hourly with cutoffs %v, got %v", expected, store.Cutoffs)
+	}
+}
+
+func TestRecorder_Exchanges(t *testing.T) {
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &fakeStore{}
+	for _, id := range []string{"instance-1", "instance-2", "instance-1"} {
+		exchange := models.RecordedExchange{InstanceId: id, Method: http.MethodGet, Path: "/v2/service_instances/" + id, Status: http.StatusOK, DurationMillis: 1500}
+		exchange.CreatedAt = created
+		store.Created = append(store.Created, exchange)
+	}
+	store.Created[2].ResponseBody = `{"state":"succeeded"}`
+
+	rec := &Recorder{Store: store}
+	exchanges, err := rec.Exchanges(context.Background(), "instance-1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Exchange{{
+		Time:       created,
+		InstanceId: "instance-1",
+		Method:     http.MethodGet,
+		Path:       "/v2/service_instances/instance-1",
+		Status:     http.StatusOK,
+		Response:   json.RawMessage(`{"state":"succeeded"}`),
+		Duration:   "1.5s",
+	}}
+	if !reflect.DeepEqual(exchanges, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, exchanges)
+	}
+}
+
+func TestNewRecorderFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Enabled         bool
+		Retention       string
+		ExpectRecorder  bool
+		ExpectRetention time.Duration
+		ExpectErr       bool
+	}{
+		"disabled": {},
+		"defaults": {
+			Enabled:         true,
+			ExpectRecorder:  true,
+			ExpectRetention: 72 * time.Hour,
+		},
+		"custom-retention": {
+			Enabled:         true,
+			Retention:       "6h",
+			ExpectRecorder:  true,
+			ExpectRetention: 6 * time.Hour,
+		},
+		"bad-retention": {
+			Enabled:   true,
+			Retention: "forever",
+			ExpectErr: true,
+		},
+		"negative-retention": {
+			Enabled:   true,
+			Retention: "-1h",
+			ExpectErr: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(EnabledProp, tc.Enabled)
+			if tc.Retention != "" {
+				viper.Set(RetentionProp, tc.Retention)
+			}
+			defer viper.Set(EnabledProp, nil)
+			defer viper.Set(RetentionProp, nil)
+
+			rec, err := NewRecorderFromEnv(lager.NewLogger("test"))
+			if (err != nil) != tc.ExpectErr {
+				t.Fatalf("Expected error: %v, got %v", tc.ExpectErr, err)
+			}
+
+			if (rec != nil) != tc.ExpectRecorder {
+				t.Fatalf("Expected recorder: %v, got %v", tc.ExpectRecorder, rec)
+			}
+
+			if rec != nil && rec.Retention != tc.ExpectRetention {
+				t.Errorf("Expected retention %s, got %s", tc.ExpectRetention, rec.Retention)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package requestdetails decides what the broker keeps of the parameters of
+// each provision request, which it reads again to update, bind and
+// deprovision the instance, and deletes what it kept once the instance has
+// been gone long enough.
+package requestdetails
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/config/secrets"
+	"github.com/pivotal/cloud-service-broker/pkg/dedupe"
+	"github.com/spf13/viper"
+)
+
+const (
+	// RetentionProp is the viper key of how long the request details of a
+	// deprovisioned instance are kept. Zero keeps them.
+	RetentionProp = "provision.request_details.retention"
+
+	// RedactProp is the viper key of the parameters removed from the request
+	// details before they're saved.
+	RedactProp = "provision.request_details.redact"
+
+	// HashOnlyProp is the viper key of whether only the parameters the
+	// broker set are saved, along with a hash of the request.
+	HashOnlyProp = "provision.request_details.hash_only"
+
+	// EncryptionProp is the viper key of the secrets scheme the parameters
+	// that aren't saved in plaintext are encrypted with.
+	EncryptionProp = "provision.request_details.encryption"
+
+	// PurgeIntervalProp is the viper key of how often a running broker
+	// deletes request details past the retention.
+	PurgeIntervalProp = "provision.request_details.purge_interval"
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: RetentionProp, Kind: config.Duration, Default: "0s"},
+		config.Property{Key: RedactProp, Kind: config.List, Default: []string{}},
+		config.Property{Key: HashOnlyProp, Kind: config.Boolean, Default: false},
+		config.Property{Key: EncryptionProp, Kind: config.String, Allowed: []string{secrets.PassphraseScheme, secrets.KmsScheme}},
+		config.Property{Key: PurgeIntervalProp, Kind: config.Duration, Default: "1h"},
+	)
+}
+
+// encryptedKey holds the parameters that aren't kept in plaintext, encrypted
+// together as a JSON object.
+const encryptedKey = "_csb_encrypted"
+
+// Policy is what the broker keeps of the parameters of provision requests.
+// Parameters that aren't kept in plaintext are kept encrypted so updates,
+// binds and deprovisions still get the values the instance was created with.
+type Policy struct {
+	// Redact holds the parameters that aren't kept, nested ones are given as
+	// a path separated by dots, e.g. "credentials.password".
+	Redact []string
+
+	// HashOnly keeps only the parameters the broker set, like generated
+	// resource names and the pinned project, and not the user's.
+	HashOnly bool
+
+	// Scheme is the secrets scheme parameters that aren't kept are encrypted
+	// with. It must be set if any parameters aren't kept.
+	Scheme string
+}
+
+// NewPolicyFromEnv creates a Policy from the configuration in viper.
+func NewPolicyFromEnv() (Policy, error) {
+	policy := Policy{
+		Redact:   viper.GetStringSlice(RedactProp),
+		HashOnly: viper.GetBool(HashOnlyProp),
+		Scheme:   viper.GetString(EncryptionProp),
+	}
+
+	if policy.strips() && policy.Scheme == "" {
+		return Policy{}, fmt.Errorf("%s must be set to use %s or %s", EncryptionProp, RedactProp, HashOnlyProp)
+	}
+
+	return policy, nil
+}
+
+// strips returns true if the policy doesn't keep every parameter in
+// plaintext.
+func (p Policy) strips() bool {
+	return p.HashOnly || len(p.Redact) > 0
+}
+
+// Apply returns what's kept of the parameters the broker provisions an
+// instance with, given the parameters the user requested it with. Use
+// Restore to get the parameters back.
+func (p Policy) Apply(requested, provisioned json.RawMessage) (json.RawMessage, error) {
+	if !p.strips() {
+		return provisioned, nil
+	}
+
+	params, err := decodeObject(provisioned)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := map[string]interface{}{}
+	if p.HashOnly {
+		user, err := decodeObject(requested)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, value := range params {
+			if userValue, ok := user[key]; ok && reflect.DeepEqual(userValue, value) {
+				removed[key] = value
+				delete(params, key)
+			}
+		}
+	}
+
+	for _, path := range p.Redact {
+		redact(params, removed, strings.Split(path, "."))
+	}
+
+	if len(removed) > 0 {
+		plaintext, err := json.Marshal(removed)
+		if err != nil {
+			return nil, err
+		}
+
+		if params[encryptedKey], err = secrets.Encrypt(p.Scheme, string(plaintext)); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(params)
+}
+
+// redact moves the parameter at the path, if there is one, to removed.
+func redact(params, removed map[string]interface{}, path []string) {
+	value, ok := params[path[0]]
+	if !ok {
+		return
+	}
+
+	if len(path) == 1 {
+		removed[path[0]] = value
+		delete(params, path[0])
+		return
+	}
+
+	if nested, ok := value.(map[string]interface{}); ok {
+		removedNested, ok := removed[path[0]].(map[string]interface{})
+		if !ok {
+			removedNested = map[string]interface{}{}
+		}
+
+		redact(nested, removedNested, path[1:])
+		if len(removedNested) > 0 {
+			removed[path[0]] = removedNested
+		}
+	}
+}
+
+// Restore returns the parameters an instance was provisioned with from the
+// request details Apply kept, decrypting the ones that weren't kept in
+// plaintext.
+func Restore(kept json.RawMessage) (json.RawMessage, error) {
+	params, err := decodeObject(kept)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, ok := params[encryptedKey].(string)
+	if !ok {
+		return kept, nil
+	}
+	delete(params, encryptedKey)
+
+	plaintext, err := secrets.Decrypt(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decrypt the request details: %v", err)
+	}
+
+	removed := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(plaintext), &removed); err != nil {
+		return nil, fmt.Errorf("couldn't parse the decrypted request details: %v", err)
+	}
+
+	merge(params, removed)
+	return json.Marshal(params)
+}
+
+// merge puts the removed parameters back into params.
+func merge(params, removed map[string]interface{}) {
+	for key, value := range removed {
+		nestedRemoved, isMap := value.(map[string]interface{})
+		nested, ok := params[key].(map[string]interface{})
+		if isMap && ok {
+			merge(nested, nestedRemoved)
+			continue
+		}
+
+		params[key] = value
+	}
+}
+
+func decodeObject(raw json.RawMessage) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+	if len(raw) == 0 {
+		return params, nil
+	}
+
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, fmt.Errorf("couldn't parse parameters: %v", err)
+	}
+
+	// a request with "null" parameters
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+
+	return params, nil
+}
+
+// Hash gets a hash of what the platform asked to provision, identical
+// requests have the same hash however their parameters are formatted.
+func Hash(details brokerapi.ProvisionDetails) (string, error) {
+	params, err := decodeObject(details.GetRawParameters())
+	if err != nil {
+		return "", err
+	}
+
+	return dedupe.Hash(struct {
+		ServiceId        string
+		PlanId           string
+		OrganizationGuid string
+		SpaceGuid        string
+		Parameters       map[string]interface{}
+	}{
+		ServiceId:        details.ServiceID,
+		PlanId:           details.PlanID,
+		OrganizationGuid: details.OrganizationGUID,
+		SpaceGuid:        details.SpaceGUID,
+		Parameters:       params,
+	})
+}
+
+// Store holds the request details.
+type Store interface {
+	DeleteProvisionRequestDetailsDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// databaseStore uses the broker's database.
+type databaseStore struct{}
+
+func (databaseStore) DeleteProvisionRequestDetailsDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return db_service.DeleteProvisionRequestDetailsDeletedBefore(ctx, cutoff)
+}
+
+// Purger deletes the request details of instances deprovisioned longer ago
+// than the retention.
+type Purger struct {
+	Store     Store
+	Retention time.Duration
+	Logger    lager.Logger
+
+	// LeaderContext reports whether this instance should purge, with a
+	// context that's cancelled if it stops leading. All instances purge if it's
+	// nil.
+	LeaderContext func(ctx context.Context) (context.Context, context.CancelFunc, bool)
+
+	// now is used to get the current time, it can be replaced in tests.
+	now func() time.Time
+}
+
+// NewPurgerFromEnv creates a Purger using the broker's database, or returns
+// nil if request details are kept.
+func NewPurgerFromEnv(logger lager.Logger) (*Purger, error) {
+	retention, err := time.ParseDuration(viper.GetString(RetentionProp))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", RetentionProp, err)
+	}
+	if retention < 0 {
+		return nil, fmt.Errorf("%s must not be negative, got %s", RetentionProp, retention)
+	}
+	if retention == 0 {
+		return nil, nil
+	}
+
+	return &Purger{
+		Store:     databaseStore{},
+		Retention: retention,
+		Logger:    logger.Session("request-details"),
+	}, nil
+}
+
+func (p *Purger) currentTime() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+
+	return time.Now()
+}
+
+// Run deletes the request details past the retention and returns how many
+// there were.
+func (p *Purger) Run(ctx context.Context) (int64, error) {
+	deleted, err := p.Store.DeleteProvisionRequestDetailsDeletedBefore(ctx, p.currentTime().Add(-p.Retention))
+	if err != nil {
+		return 0, err
+	}
+
+	if deleted > 0 {
+		p.Logger.Info("purged-request-details", lager.Data{"deleted": deleted})
+	}
+
+	return deleted, nil
+}
+
+// RunEvery runs the purger every interval until the context is done. Runs are
+// skipped while another instance leads if LeaderContext is set.
+func (p *Purger) RunEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runCtx, cancel, leads := ctx, context.CancelFunc(func() {}), true
+			if p.LeaderContext != nil {
+				runCtx, cancel, leads = p.LeaderContext(ctx)
+			}
+			if !leads {
+				cancel()
+				p.Logger.Debug("skipping-run", lager.Data{"reason": "another instance leads purging"})
+				continue
+			}
+
+			if _, err := p.Run(runCtx); err != nil {
+				p.Logger.Error("purging-request-details", err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requestdetails
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/config/secrets"
+	"github.com/spf13/viper"
+)
+
+func TestPolicy_Apply(t *testing.T) {
+	viper.Set(secrets.PassphraseProp, "request-details-test")
+	defer viper.Set(secrets.PassphraseProp, nil)
+
+	cases := map[string]struct {
+		Policy      Policy
+		Requested   string
+		Provisioned string
+		Expected    string
+	}{
+		"keeps everything by default": {
+			Policy:      Policy{},
+			Requested:   `{"password":"hunter2"}`,
+			Provisioned: `{"password":"hunter2","name":"csb-1234"}`,
+			Expected:    `{"password":"hunter2","name":"csb-1234"}`,
+		},
+		"redacts parameters": {
+			Policy:      Policy{Redact: []string{"password", "missing"}, Scheme: secrets.PassphraseScheme},
+			Requested:   `{"password":"hunter2","tier":"small"}`,
+			Provisioned: `{"password":"hunter2","tier":"small"}`,
+			Expected:    `{"tier":"small"}`,
+		},
+		"redacts nested parameters": {
+			Policy:      Policy{Redact: []string{"admin.password", "tier.size"}, Scheme: secrets.PassphraseScheme},
+			Requested:   `{"admin":{"user":"root","password":"hunter2"},"tier":"small"}`,
+			Provisioned: `{"admin":{"user":"root","password":"hunter2"},"tier":"small"}`,
+			Expected:    `{"admin":{"user":"root"},"tier":"small"}`,
+		},
+		"hash only keeps what the broker set": {
+			Policy:      Policy{HashOnly: true, Scheme: secrets.PassphraseScheme},
+			Requested:   `{"password":"hunter2","project":"user-project"}`,
+			Provisioned: `{"password":"hunter2","project":"pinned-project","name":"csb-1234"}`,
+			Expected:    `{"project":"pinned-project","name":"csb-1234"}`,
+		},
+		"hash only with redaction": {
+			Policy:      Policy{HashOnly: true, Redact: []string{"project"}, Scheme: secrets.PassphraseScheme},
+			Requested:   `{}`,
+			Provisioned: `{"project":"pinned-project","name":"csb-1234"}`,
+			Expected:    `{"name":"csb-1234"}`,
+		},
+		"no parameters": {
+			Policy:      Policy{HashOnly: true, Scheme: secrets.PassphraseScheme},
+			Requested:   ``,
+			Provisioned: `null`,
+			Expected:    `{}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := tc.Policy.Apply(json.RawMessage(tc.Requested), json.RawMessage(tc.Provisioned))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var expected, got map[string]interface{}
+			if err := json.Unmarshal([]byte(tc.Expected), &expected); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(actual, &got); err != nil {
+				t.Fatal(err)
+			}
+			delete(got, encryptedKey)
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("Expected plaintext %s, got %s", tc.Expected, actual)
+			}
+
+			restored, err := Restore(actual)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var provisioned, gotRestored map[string]interface{}
+			if err := json.Unmarshal([]byte(tc.Provisioned), &provisioned); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(restored, &gotRestored); err != nil {
+				t.Fatal(err)
+			}
+			if len(provisioned) > 0 && !reflect.DeepEqual(gotRestored, provisioned) {
+				t.Errorf("Expected restored %s, got %s", tc.Provisioned, restored)
+			}
+		})
+	}
+}
+
+func TestNewPolicyFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Config    map[string]interface{}
+		ExpectErr bool
+	}{
+		"keeps everything":             {Config: map[string]interface{}{}},
+		"redacts without encryption":   {Config: map[string]interface{}{RedactProp: []string{"password"}}, ExpectErr: true},
+		"hash only without encryption": {Config: map[string]interface{}{HashOnlyProp: true}, ExpectErr: true},
+		"redacts with encryption":      {Config: map[string]interface{}{RedactProp: []string{"password"}, EncryptionProp: secrets.KmsScheme}},
+		"hash only with encryption":    {Config: map[string]interface{}{HashOnlyProp: true, EncryptionProp: secrets.PassphraseScheme}},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			for key, value := range tc.Config {
+				viper.Set(key, value)
+				defer viper.Set(key, nil)
+			}
+
+			if _, err := NewPolicyFromEnv(); (err != nil) != tc.ExpectErr {
+				t.Errorf("Expected error: %v, got: %v", tc.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func TestRestore_plaintext(t *testing.T) {
+	kept := json.RawMessage(`{"tier":"small"}`)
+
+	restored, err := Restore(kept)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(restored) != string(kept) {
+		t.Errorf("Expected %s, got %s", kept, restored)
+	}
+}
+
+func TestHash(t *testing.T) {
+	base := brokerapi.ProvisionDetails{
+		ServiceID:        "service",
+		PlanID:           "plan",
+		OrganizationGUID: "org",
+		SpaceGUID:        "space",
+		RawParameters:    json.RawMessage(`{"a":1,"b":2}`),
+	}
+	baseHash, err := Hash(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		Mutate func(details *brokerapi.ProvisionDetails)
+		Same   bool
+	}{
+		"identical":            {Mutate: func(d *brokerapi.ProvisionDetails) {}, Same: true},
+		"reordered parameters": {Mutate: func(d *brokerapi.ProvisionDetails) { d.RawParameters = json.RawMessage(`{ "b": 2, "a": 1 }`) }, Same: true},
+		"different parameters": {Mutate: func(d *brokerapi.ProvisionDetails) { d.RawParameters = json.RawMessage(`{"a":1}`) }, Same: false},
+		"different plan":       {Mutate: func(d *brokerapi.ProvisionDetails) { d.PlanID = "other" }, Same: false},
+		"different space":      {Mutate: func(d *brokerapi.ProvisionDetails) { d.SpaceGUID = "other" }, Same: false},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			details := base
+			tc.Mutate(&details)
+			hash, err := Hash(details)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if same := hash == baseHash; same != tc.Same {
+				t.Errorf("Expected same hash to be %v, got %v", tc.Same, same)
+			}
+		})
+	}
+}
+
+type fakeStore struct {
+	Cutoffs []time.Time
+}
+
+func (f *fakeStore) DeleteProvisionRequestDetailsDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	f.Cutoffs = append(f.Cutoffs, cutoff)
+	return 2, nil
+}
+
+func TestPurger_Run(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	store := &fakeStore{}
+	purger := &Purger{
+		Store:     store,
+		Retention: 72 * time.Hour,
+		Logger:    lager.NewLogger("test"),
+		now:       func() time.Time { return now },
+	}
+
+	deleted, err := purger.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 deleted, got %d", deleted)
+	}
+
+	expected := []time.Time{time.Date(2026, 1, 7, 0, 0, 0, 0, time.UTC)}
+	if !reflect.DeepEqual(store.Cutoffs, expected) {
+		t.Errorf("Expected cutoffs %v, got %v", expected, store.Cutoffs)
+	}
+}
+
+func TestNewPurgerFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Retention   string
+		ExpectNil   bool
+		ExpectError bool
+	}{
+		"default keeps details": {Retention: "", ExpectNil: true},
+		"zero keeps details":    {Retention: "0s", ExpectNil: true},
+		"positive":              {Retention: "720h"},
+		"negative":              {Retention: "-1h", ExpectNil: true, ExpectError: true},
+		"invalid":               {Retention: "forever", ExpectNil: true, ExpectError: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if tc.Retention != "" {
+				viper.Set(RetentionProp, tc.Retention)
+			}
+			defer viper.Set(RetentionProp, nil)
+
+			purger, err := NewPurgerFromEnv(lager.NewLogger("test"))
+			if (err != nil) != tc.ExpectError {
+				t.Errorf("Expected error %v, got %v", tc.ExpectError, err)
+			}
+			if (purger == nil) != tc.ExpectNil {
+				t.Errorf("Expected nil purger %v, got %v", tc.ExpectNil, purger)
+			}
+		})
+	}
+}
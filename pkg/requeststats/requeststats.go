@@ -0,0 +1,228 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package requeststats counts the broker API requests in flight on each
+// endpoint and logs slow requests with a breakdown of where their time went.
+package requeststats
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/logging"
+	"github.com/pivotal/cloud-service-broker/pkg/ratelimit"
+	"github.com/spf13/viper"
+)
+
+const (
+	// SlowThresholdProp is the viper key of how long a request can take
+	// before it's logged as slow, 0 turns the logging off.
+	SlowThresholdProp = "request_stats.slow_threshold"
+
+	// maxRecentSlow is the number of slow requests kept for the admin API.
+	maxRecentSlow = 20
+)
+
+// metrics holds per-endpoint counters, e.g. provision.in_flight.
+var metrics = expvar.NewMap("broker_api_requests")
+
+func init() {
+	config.Register(config.Property{Key: SlowThresholdProp, Kind: config.Duration, Default: "10s"})
+}
+
+// EndpointStats are the request counts of an OSB API endpoint.
+type EndpointStats struct {
+	InFlight     int64 `json:"in_flight"`
+	PeakInFlight int64 `json:"peak_in_flight"`
+	Requests     int64 `json:"requests"`
+	Slow         int64 `json:"slow"`
+}
+
+// SlowRequest describes a request that took longer than the threshold.
+type SlowRequest struct {
+	Endpoint      string           `json:"endpoint"`
+	Method        string           `json:"method"`
+	Path          string           `json:"path"`
+	CorrelationId string           `json:"correlation_id,omitempty"`
+	Start         time.Time        `json:"start"`
+	DurationMs    int64            `json:"duration_ms"`
+	Phases        map[string]Phase `json:"phases"`
+}
+
+// Snapshot is the state of a Tracker.
+type Snapshot struct {
+	// SlowThreshold is the duration requests are logged as slow after,
+	// e.g. 10s, or 0s if they aren't logged.
+	SlowThreshold string                   `json:"slow_threshold"`
+	Endpoints     map[string]EndpointStats `json:"endpoints"`
+	// RecentSlow holds the latest slow requests, newest first.
+	RecentSlow []SlowRequest `json:"recent_slow"`
+}
+
+// Tracker counts requests to each OSB API endpoint and logs the ones slower
+// than SlowThreshold. It's safe for concurrent use.
+type Tracker struct {
+	// SlowThreshold is how long a request can take before it's logged, slow
+	// requests aren't logged if it's 0.
+	SlowThreshold time.Duration
+	Logger        lager.Logger
+
+	mu         sync.Mutex
+	endpoints  map[string]*EndpointStats
+	recentSlow []SlowRequest
+}
+
+// NewTrackerFromEnv creates a Tracker from the settings in viper.
+func NewTrackerFromEnv(logger lager.Logger) (*Tracker, error) {
+	threshold, err := time.ParseDuration(viper.GetString(SlowThresholdProp))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", SlowThresholdProp, err)
+	}
+
+	if threshold < 0 {
+		return nil, fmt.Errorf("%s must not be negative, got %s", SlowThresholdProp, threshold)
+	}
+
+	return &Tracker{SlowThreshold: threshold, Logger: logger.Session("request-stats")}, nil
+}
+
+// Snapshot returns a copy of the tracker's counts and recent slow requests.
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := Snapshot{
+		SlowThreshold: t.SlowThreshold.String(),
+		Endpoints:     make(map[string]EndpointStats),
+		RecentSlow:    append([]SlowRequest{}, t.recentSlow...),
+	}
+
+	for endpoint, stats := range t.endpoints {
+		snapshot.Endpoints[endpoint] = *stats
+	}
+
+	return snapshot
+}
+
+// Wrap counts requests while they're in flight and times them. It must be
+// added to the router the OSB API routes are attached to, before any other
+// middleware, so the endpoint can be identified and the whole request is
+// timed.
+func (t *Tracker) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		endpoint := ratelimit.Endpoint(req)
+		if endpoint == "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		t.begin(endpoint)
+		start := time.Now()
+		timings := &Timings{}
+		ctx := NewContext(req.Context(), timings)
+
+		defer func() {
+			elapsed := time.Since(start)
+			slow := t.SlowThreshold > 0 && elapsed >= t.SlowThreshold
+			t.end(endpoint, slow)
+
+			if slow {
+				t.slow(SlowRequest{
+					Endpoint:      endpoint,
+					Method:        req.Method,
+					Path:          req.URL.Path,
+					CorrelationId: logging.CorrelationId(ctx),
+					Start:         start,
+					DurationMs:    elapsed.Milliseconds(),
+					Phases:        timings.Phases(),
+				})
+			}
+		}()
+
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+func (t *Tracker) begin(endpoint string) {
+	metrics.Add(endpoint+".in_flight", 1)
+	metrics.Add(endpoint+".requests", 1)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.endpoints == nil {
+		t.endpoints = make(map[string]*EndpointStats)
+	}
+
+	stats, ok := t.endpoints[endpoint]
+	if !ok {
+		stats = &EndpointStats{}
+		t.endpoints[endpoint] = stats
+	}
+
+	stats.InFlight++
+	stats.Requests++
+	if stats.InFlight > stats.PeakInFlight {
+		stats.PeakInFlight = stats.InFlight
+	}
+}
+
+func (t *Tracker) end(endpoint string, slow bool) {
+	metrics.Add(endpoint+".in_flight", -1)
+	if slow {
+		metrics.Add(endpoint+".slow", 1)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := t.endpoints[endpoint]
+	stats.InFlight--
+	if slow {
+		stats.Slow++
+	}
+}
+
+func (t *Tracker) slow(request SlowRequest) {
+	t.mu.Lock()
+	t.recentSlow = append([]SlowRequest{request}, t.recentSlow...)
+	if len(t.recentSlow) > maxRecentSlow {
+		t.recentSlow = t.recentSlow[:maxRecentSlow]
+	}
+	t.mu.Unlock()
+
+	if t.Logger == nil {
+		return
+	}
+
+	data := lager.Data{
+		"endpoint":       request.Endpoint,
+		"method":         request.Method,
+		"path":           request.Path,
+		"correlation_id": request.CorrelationId,
+		"duration_ms":    request.DurationMs,
+		"threshold_ms":   t.SlowThreshold.Milliseconds(),
+	}
+	for name, phase := range request.Phases {
+		data[name+"_ms"] = phase.DurationMs
+		data[name+"_calls"] = phase.Calls
+	}
+
+	t.Logger.Info("slow-request", data)
+}
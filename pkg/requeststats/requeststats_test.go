@@ -0,0 +1,182 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requeststats
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func newTestRouter(tracker *Tracker, handler http.HandlerFunc, middleware ...mux.MiddlewareFunc) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/catalog", handler).Methods(http.MethodGet)
+	router.HandleFunc("/v2/service_instances/{instance_id}", handler).Methods(http.MethodPut)
+	router.Use(tracker.Wrap)
+	router.Use(middleware...)
+	return router
+}
+
+func TestTracker_Wrap(t *testing.T) {
+	cases := map[string]struct {
+		Threshold     time.Duration
+		Sleep         time.Duration
+		Path          string
+		Method        string
+		ExpectedStats map[string]EndpointStats
+		ExpectedSlow  int
+	}{
+		"counted": {
+			Method:        http.MethodGet,
+			Path:          "/v2/catalog",
+			ExpectedStats: map[string]EndpointStats{"catalog": {PeakInFlight: 1, Requests: 1}},
+		},
+		"slow": {
+			Threshold:     time.Millisecond,
+			Sleep:         5 * time.Millisecond,
+			Method:        http.MethodPut,
+			Path:          "/v2/service_instances/instance-1",
+			ExpectedStats: map[string]EndpointStats{"provision": {PeakInFlight: 1, Requests: 1, Slow: 1}},
+			ExpectedSlow:  1,
+		},
+		"fast": {
+			Threshold:     time.Hour,
+			Method:        http.MethodPut,
+			Path:          "/v2/service_instances/instance-1",
+			ExpectedStats: map[string]EndpointStats{"provision": {PeakInFlight: 1, Requests: 1}},
+		},
+		"not an endpoint": {
+			Method:        http.MethodGet,
+			Path:          "/v2/unknown",
+			ExpectedStats: map[string]EndpointStats{},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			tracker := &Tracker{SlowThreshold: tc.Threshold}
+			router := newTestRouter(tracker, func(w http.ResponseWriter, req *http.Request) {
+				time.Sleep(tc.Sleep)
+			})
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.Method, tc.Path, nil))
+
+			snapshot := tracker.Snapshot()
+			if len(snapshot.Endpoints) != len(tc.ExpectedStats) {
+				t.Fatalf("Expected stats %v, got %v", tc.ExpectedStats, snapshot.Endpoints)
+			}
+			for endpoint, expected := range tc.ExpectedStats {
+				if actual := snapshot.Endpoints[endpoint]; actual != expected {
+					t.Errorf("Expected %s stats %+v, got %+v", endpoint, expected, actual)
+				}
+			}
+
+			if len(snapshot.RecentSlow) != tc.ExpectedSlow {
+				t.Fatalf("Expected %d slow requests, got %v", tc.ExpectedSlow, snapshot.RecentSlow)
+			}
+		})
+	}
+}
+
+func TestTracker_Wrap_inFlight(t *testing.T) {
+	tracker := &Tracker{}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	router := newTestRouter(tracker, func(w http.ResponseWriter, req *http.Request) {
+		started <- struct{}{}
+		<-release
+	})
+
+	done := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		go func() {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/v2/service_instances/instance-1", nil))
+			done <- struct{}{}
+		}()
+		<-started
+	}
+
+	if actual := tracker.Snapshot().Endpoints["provision"]; actual.InFlight != 3 {
+		t.Errorf("Expected 3 requests in flight, got %+v", actual)
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+
+	expected := EndpointStats{PeakInFlight: 3, Requests: 3}
+	if actual := tracker.Snapshot().Endpoints["provision"]; actual != expected {
+		t.Errorf("Expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestTracker_Wrap_phases(t *testing.T) {
+	tracker := &Tracker{SlowThreshold: time.Nanosecond}
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			time.Sleep(5 * time.Millisecond)
+			next.ServeHTTP(w, req)
+		})
+	}
+	router := newTestRouter(tracker, func(w http.ResponseWriter, req *http.Request) {
+		stop := Start(req.Context(), PhaseDB)
+		time.Sleep(5 * time.Millisecond)
+		stop()
+		Start(req.Context(), PhaseDB)()
+	}, TimeMiddleware(PhaseAuth, auth))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v2/catalog", nil))
+
+	slow := tracker.Snapshot().RecentSlow
+	if len(slow) != 1 {
+		t.Fatalf("Expected a slow request, got %v", slow)
+	}
+
+	phases := slow[0].Phases
+	if phases[PhaseAuth].Calls != 1 || phases[PhaseAuth].DurationMs < 5 {
+		t.Errorf("Expected the auth time, got %+v", phases[PhaseAuth])
+	}
+	if phases[PhaseDB].Calls != 2 || phases[PhaseDB].DurationMs < 5 {
+		t.Errorf("Expected the database time, got %+v", phases[PhaseDB])
+	}
+	if phases[PhaseGCP] != (Phase{}) {
+		t.Errorf("Expected no GCP time, got %+v", phases[PhaseGCP])
+	}
+	if phases[PhaseAuth].DurationMs >= slow[0].DurationMs {
+		t.Errorf("Expected the auth time to exclude the handler, got %+v of %dms", phases[PhaseAuth], slow[0].DurationMs)
+	}
+}
+
+func TestTracker_Wrap_recentSlow(t *testing.T) {
+	tracker := &Tracker{SlowThreshold: time.Nanosecond}
+	router := newTestRouter(tracker, func(w http.ResponseWriter, req *http.Request) {})
+
+	for i := 0; i < maxRecentSlow+5; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v2/catalog", nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/v2/service_instances/instance-1", nil))
+
+	slow := tracker.Snapshot().RecentSlow
+	if len(slow) != maxRecentSlow {
+		t.Fatalf("Expected %d slow requests, got %d", maxRecentSlow, len(slow))
+	}
+	if slow[0].Endpoint != "provision" {
+		t.Errorf("Expected the newest request first, got %+v", slow[0])
+	}
+}
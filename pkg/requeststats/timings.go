@@ -0,0 +1,199 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requeststats
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The phases of a request that are timed. Slow requests always report them,
+// even if no time was spent in them.
+const (
+	PhaseAuth = "auth"
+	PhaseDB   = "db"
+	PhaseGCP  = "gcp"
+)
+
+var phases = []string{PhaseAuth, PhaseDB, PhaseGCP}
+
+type contextKey struct{}
+
+// Phase is the time a request spent in one kind of work. Calls made
+// concurrently each count in full so it can add up to more than the request.
+type Phase struct {
+	DurationMs int64 `json:"duration_ms"`
+	Calls      int   `json:"calls"`
+}
+
+// Timings collects how long a request spent in each phase. A nil Timings
+// ignores everything so callers needn't check whether they're serving a
+// request. It's safe for concurrent use.
+type Timings struct {
+	mu        sync.Mutex
+	durations map[string]time.Duration
+	calls     map[string]int
+	open      map[string]time.Time
+}
+
+// NewContext returns a context holding the timings.
+func NewContext(ctx context.Context, timings *Timings) context.Context {
+	return context.WithValue(ctx, contextKey{}, timings)
+}
+
+// FromContext returns the timings of the request in the context or nil if
+// there's no request.
+func FromContext(ctx context.Context) *Timings {
+	timings, _ := ctx.Value(contextKey{}).(*Timings)
+	return timings
+}
+
+// Start times a call in the phase of the request in the context. Call the
+// returned function when it's done.
+func Start(ctx context.Context, phase string) func() {
+	timings := FromContext(ctx)
+	if timings == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		timings.Add(phase, time.Since(start))
+	}
+}
+
+// Add records a call in the phase that took the duration.
+func (t *Timings) Add(phase string, duration time.Duration) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.durations == nil {
+		t.durations = make(map[string]time.Duration)
+		t.calls = make(map[string]int)
+	}
+
+	t.durations[phase] += duration
+	t.calls[phase]++
+}
+
+// Phases returns the time spent in each phase so far.
+func (t *Timings) Phases() map[string]Phase {
+	out := make(map[string]Phase)
+	for _, phase := range phases {
+		out[phase] = Phase{}
+	}
+
+	if t == nil {
+		return out
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for phase, duration := range t.durations {
+		out[phase] = Phase{DurationMs: duration.Milliseconds(), Calls: t.calls[phase]}
+	}
+
+	return out
+}
+
+func (t *Timings) begin(phase string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.open == nil {
+		t.open = make(map[string]time.Time)
+	}
+	t.open[phase] = time.Now()
+}
+
+// finish records the phase started with begin, if it hasn't been already.
+func (t *Timings) finish(phase string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	start, ok := t.open[phase]
+	delete(t.open, phase)
+	t.mu.Unlock()
+
+	if ok {
+		t.Add(phase, time.Since(start))
+	}
+}
+
+// TimeMiddleware records the time spent in the middleware as the phase, from
+// when a request reaches it until it passes the request on or responds
+// itself, e.g. to time authentication.
+func TimeMiddleware(phase string, middleware func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			FromContext(req.Context()).finish(phase)
+			next.ServeHTTP(w, req)
+		}))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			timings := FromContext(req.Context())
+			timings.begin(phase)
+			wrapped.ServeHTTP(w, req)
+			timings.finish(phase)
+		})
+	}
+}
+
+// NewTransport creates a transport that records the time of each round trip
+// as a call in the phase of the request in the context. The context is
+// captured because clients are often created per request while the calls
+// they make don't carry its context. A nil base uses http.DefaultTransport.
+func NewTransport(ctx context.Context, phase string, base http.RoundTripper) http.RoundTripper {
+	timings := FromContext(ctx)
+	if timings == nil {
+		if base == nil {
+			return http.DefaultTransport
+		}
+		return base
+	}
+
+	return &timingTransport{timings: timings, phase: phase, base: base}
+}
+
+type timingTransport struct {
+	timings *Timings
+	phase   string
+	base    http.RoundTripper
+}
+
+func (t *timingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	start := time.Now()
+	defer func() { t.timings.Add(t.phase, time.Since(start)) }()
+
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,96 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requeststats
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTimeMiddleware(t *testing.T) {
+	cases := map[string]struct {
+		Reject        bool
+		ExpectHandled bool
+	}{
+		"passed on": {ExpectHandled: true},
+		"rejected":  {Reject: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			middleware := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					if tc.Reject {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					next.ServeHTTP(w, req)
+				})
+			}
+
+			timings := &Timings{}
+			handled := false
+			handler := TimeMiddleware(PhaseAuth, middleware)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				handled = true
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+			handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(NewContext(req.Context(), timings)))
+
+			if handled != tc.ExpectHandled {
+				t.Errorf("Expected handled %v, got %v", tc.ExpectHandled, handled)
+			}
+			if calls := timings.Phases()[PhaseAuth].Calls; calls != 1 {
+				t.Errorf("Expected the phase to be recorded once, got %d", calls)
+			}
+		})
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	timings := &Timings{}
+	client := &http.Client{Transport: NewTransport(NewContext(context.Background(), timings), PhaseGCP, nil)}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if calls := timings.Phases()[PhaseGCP].Calls; calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+
+	if transport := NewTransport(context.Background(), PhaseGCP, nil); transport != http.DefaultTransport {
+		t.Errorf("Expected the base transport outside of requests, got %T", transport)
+	}
+}
+
+func TestTimings_outsideRequests(t *testing.T) {
+	// outside of requests there's nothing to record to
+	Start(context.Background(), PhaseDB)()
+
+	var timings *Timings
+	timings.Add(PhaseDB, 1)
+	if phases := timings.Phases(); len(phases) != 3 {
+		t.Errorf("Expected every phase to be reported, got %v", phases)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+)
+
+// ErrorClass is a broad category of errors that a policy can choose to retry.
+type ErrorClass string
+
+const (
+	// Conflict errors are caused by concurrent modification, e.g. HTTP 409.
+	Conflict ErrorClass = "conflict"
+	// RateLimit errors are caused by quota or rate limiting, e.g. HTTP 429.
+	RateLimit ErrorClass = "rate_limit"
+	// ServerError errors are transient failures of the remote, e.g. HTTP 5xx.
+	ServerError ErrorClass = "server"
+	// Network errors are failures to reach or talk to the remote.
+	Network ErrorClass = "network"
+	// Any matches all errors.
+	Any ErrorClass = "any"
+)
+
+// StatusCoder is implemented by errors that carry an HTTP status code.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Matches returns true if the error belongs to the class.
+func (class ErrorClass) Matches(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch class {
+	case Any:
+		return true
+	case Network:
+		return isNetworkError(err)
+	}
+
+	code, ok := statusCode(err)
+	if !ok {
+		return false
+	}
+
+	switch class {
+	case Conflict:
+		return code == http.StatusConflict
+	case RateLimit:
+		return code == http.StatusTooManyRequests
+	case ServerError:
+		return code >= 500 && code <= 599
+	default:
+		return false
+	}
+}
+
+func statusCode(err error) (int, bool) {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		return gerr.Code, true
+	}
+
+	var coder StatusCoder
+	if errors.As(err, &coder) {
+		return coder.StatusCode(), true
+	}
+
+	return 0, false
+}
+
+func isNetworkError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var nerr net.Error
+	return errors.As(err, &nerr)
+}
@@ -0,0 +1,227 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package retry defines a standard way to configure and apply retry policies
+across the database, provider, and outbound HTTP layers of the service broker.
+
+Each component registers a named Policy with its own defaults. Operators can
+then change the behavior of every policy at once using the global properties
+(e.g. `retry.max_attempts`) or a single component using its own properties
+(e.g. `retry.database.max_attempts`). Component properties take precedence
+over global ones, which take precedence over the registered defaults.
+*/
+package retry
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+const (
+	maxAttemptsKey    = "max_attempts"
+	initialBackoffKey = "initial_backoff"
+	maxBackoffKey     = "max_backoff"
+	multiplierKey     = "multiplier"
+	retryableKey      = "retryable"
+)
+
+// Policies is the default set of retry policies used by the broker.
+var Policies = NewPolicySet("retry.")
+
+// Defaults holds the values a policy uses if the operator hasn't overridden
+// them.
+type Defaults struct {
+	// MaxAttempts is the total number of times the operation is tried,
+	// including the first.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
+	// Multiplier is applied to the delay after each retry.
+	Multiplier float64
+	// Retryable holds the error classes that will be retried.
+	Retryable []ErrorClass
+}
+
+// Policy represents a single named retry policy.
+type Policy struct {
+	Name        string
+	Description string
+	Defaults    Defaults
+
+	propertyPrefix string
+}
+
+// EnvironmentVariable gets the environment variable used to control the given
+// setting (e.g. "max_attempts") for this policy.
+func (p Policy) EnvironmentVariable(setting string) string {
+	return utils.PropertyToEnv(p.componentProperty(setting))
+}
+
+func (p Policy) componentProperty(setting string) string {
+	return p.propertyPrefix + p.Name + "." + setting
+}
+
+func (p Policy) globalProperty(setting string) string {
+	return p.propertyPrefix + setting
+}
+
+// lookup finds the most specific value set for the given setting, or nil if
+// the operator hasn't set one.
+func (p Policy) lookup(setting string) interface{} {
+	for _, key := range []string{p.componentProperty(setting), p.globalProperty(setting)} {
+		if val := viper.Get(key); val != nil && val != "" {
+			return val
+		}
+	}
+
+	return nil
+}
+
+// MaxAttempts gets the total number of times an operation will be tried.
+// It's always at least 1.
+func (p Policy) MaxAttempts() int {
+	attempts := p.Defaults.MaxAttempts
+	if val := p.lookup(maxAttemptsKey); val != nil {
+		attempts = cast.ToInt(val)
+	}
+
+	if attempts < 1 {
+		return 1
+	}
+
+	return attempts
+}
+
+// Backoff gets the delay before the given retry, starting at 1 for the delay
+// between the first and second attempts.
+func (p Policy) Backoff(retry int) time.Duration {
+	initial := p.duration(initialBackoffKey, p.Defaults.InitialBackoff)
+	max := p.duration(maxBackoffKey, p.Defaults.MaxBackoff)
+
+	multiplier := p.Defaults.Multiplier
+	if val := p.lookup(multiplierKey); val != nil {
+		multiplier = cast.ToFloat64(val)
+	}
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(initial)
+	for i := 1; i < retry; i++ {
+		delay *= multiplier
+		if max > 0 && delay > float64(max) {
+			break
+		}
+	}
+
+	if max > 0 && delay > float64(max) {
+		return max
+	}
+
+	return time.Duration(delay)
+}
+
+func (p Policy) duration(setting string, defaultValue time.Duration) time.Duration {
+	if val := p.lookup(setting); val != nil {
+		return cast.ToDuration(val)
+	}
+
+	return defaultValue
+}
+
+// RetryableClasses gets the error classes that will be retried.
+func (p Policy) RetryableClasses() []ErrorClass {
+	val := p.lookup(retryableKey)
+	if val == nil {
+		return p.Defaults.Retryable
+	}
+
+	var out []ErrorClass
+	for _, class := range cast.ToStringSlice(val) {
+		for _, part := range strings.Split(class, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, ErrorClass(part))
+			}
+		}
+	}
+
+	return out
+}
+
+// IsRetryable returns true if the error belongs to one of the policy's
+// retryable error classes.
+func (p Policy) IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	for _, class := range p.RetryableClasses() {
+		if class.Matches(err) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// A PolicySet represents a set of defined retry policies.
+type PolicySet struct {
+	policies       []Policy
+	propertyPrefix string
+}
+
+// Policies returns a list of all registered policies sorted lexicographically
+// by name.
+func (set *PolicySet) Policies() []Policy {
+	var copy []Policy
+
+	for _, p := range set.policies {
+		copy = append(copy, p)
+	}
+
+	sort.Slice(copy, func(i, j int) bool { return copy[i].Name < copy[j].Name })
+	return copy
+}
+
+// Policy creates a new policy with the given name, description and defaults
+// and adds it to the set.
+func (set *PolicySet) Policy(name, description string, defaults Defaults) Policy {
+	policy := Policy{
+		Name:           name,
+		Description:    description,
+		Defaults:       defaults,
+		propertyPrefix: set.propertyPrefix,
+	}
+
+	set.policies = append(set.policies, policy)
+
+	return policy
+}
+
+// NewPolicySet returns a new, empty policy set with the specified property
+// prefix. You MUST specify a trailing period if you want your properties to be
+// namespaced.
+func NewPolicySet(propertyPrefix string) *PolicySet {
+	return &PolicySet{
+		propertyPrefix: propertyPrefix,
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"time"
+)
+
+var (
+	// metrics holds per-policy counters published at /debug/vars when the
+	// default HTTP mux is served.
+	metrics = expvar.NewMap("retry")
+
+	// sleep waits for the given duration or until the context is done. It's a
+	// variable so tests can skip waiting.
+	sleep = func(ctx context.Context, d time.Duration) error {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	}
+)
+
+// ExhaustedError is returned when an operation still fails after the policy's
+// maximum number of attempts.
+type ExhaustedError struct {
+	Policy   string
+	Attempts int
+	Err      error
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("giving up after %d attempts (retry policy %q): %v", e.Attempts, e.Policy, e.Err)
+}
+
+// Unwrap returns the last error the operation returned.
+func (e *ExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// Do runs the function until it succeeds, returns an error the policy doesn't
+// consider retryable, the policy runs out of attempts, or the context is done.
+// Errors that aren't retried are returned unchanged. If the context is done
+// the returned error wraps the context's error, so errors.Is matches
+// context.Canceled or context.DeadlineExceeded.
+func (p Policy) Do(ctx context.Context, fn func() error) error {
+	maxAttempts := p.MaxAttempts()
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		metrics.Add(p.Name+".attempts", 1)
+
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return p.stopped(ctx, err)
+		}
+
+		if !p.IsRetryable(err) {
+			return err
+		}
+
+		if attempt == maxAttempts {
+			break
+		}
+
+		metrics.Add(p.Name+".retries", 1)
+		if serr := sleep(ctx, p.Backoff(attempt)); serr != nil {
+			return p.stopped(ctx, err)
+		}
+	}
+
+	metrics.Add(p.Name+".exhausted", 1)
+	return &ExhaustedError{Policy: p.Name, Attempts: maxAttempts, Err: err}
+}
+
+// stopped gets the error returned when the context is done before the
+// operation succeeds; it wraps the context's error and keeps the last error
+// of the operation in its message.
+func (p Policy) stopped(ctx context.Context, err error) error {
+	metrics.Add(p.Name+".canceled", 1)
+	return fmt.Errorf("stopped retrying (retry policy %q): %w, last error: %v", p.Name, ctx.Err(), err)
+}
@@ -0,0 +1,311 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"google.golang.org/api/googleapi"
+)
+
+func init() {
+	sleep = func(ctx context.Context, d time.Duration) error { return ctx.Err() }
+}
+
+func ExamplePolicy_EnvironmentVariable() {
+	ps := NewPolicySet("retry.")
+	policy := ps.Policy("database", "connecting to the database", Defaults{})
+
+	fmt.Println(policy.EnvironmentVariable("max_attempts"))
+
+	// Output: GSB_RETRY_DATABASE_MAX_ATTEMPTS
+}
+
+func TestPolicy_Settings(t *testing.T) {
+	defaults := Defaults{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Retryable:      []ErrorClass{Conflict},
+	}
+
+	cases := map[string]struct {
+		Config            map[string]interface{}
+		ExpectedAttempts  int
+		ExpectedBackoffs  []time.Duration
+		ExpectedRetryable []ErrorClass
+	}{
+		"defaults": {
+			Config:            map[string]interface{}{},
+			ExpectedAttempts:  3,
+			ExpectedBackoffs:  []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second},
+			ExpectedRetryable: []ErrorClass{Conflict},
+		},
+		"global overrides": {
+			Config: map[string]interface{}{
+				"retry.max_attempts":    "7",
+				"retry.initial_backoff": "10ms",
+				"retry.multiplier":      "1",
+				"retry.retryable":       "network,server",
+			},
+			ExpectedAttempts:  7,
+			ExpectedBackoffs:  []time.Duration{10 * time.Millisecond, 10 * time.Millisecond},
+			ExpectedRetryable: []ErrorClass{Network, ServerError},
+		},
+		"component overrides global": {
+			Config: map[string]interface{}{
+				"retry.max_attempts":       "7",
+				"retry.test.max_attempts":  "2",
+				"retry.test.max_backoff":   "1500ms",
+				"retry.test.retryable":     []string{"any"},
+				"retry.other.max_attempts": "9",
+			},
+			ExpectedAttempts:  2,
+			ExpectedBackoffs:  []time.Duration{time.Second, 1500 * time.Millisecond},
+			ExpectedRetryable: []ErrorClass{Any},
+		},
+		"attempts at least one": {
+			Config:            map[string]interface{}{"retry.test.max_attempts": "0"},
+			ExpectedAttempts:  1,
+			ExpectedBackoffs:  []time.Duration{time.Second},
+			ExpectedRetryable: []ErrorClass{Conflict},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			for k, v := range tc.Config {
+				viper.Set(k, v)
+				defer viper.Set(k, nil)
+			}
+
+			policy := NewPolicySet("retry.").Policy("test", "", defaults)
+
+			if actual := policy.MaxAttempts(); actual != tc.ExpectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tc.ExpectedAttempts, actual)
+			}
+
+			for i, expected := range tc.ExpectedBackoffs {
+				if actual := policy.Backoff(i + 1); actual != expected {
+					t.Errorf("Expected backoff %d to be %v, got %v", i+1, expected, actual)
+				}
+			}
+
+			if actual := policy.RetryableClasses(); !reflect.DeepEqual(actual, tc.ExpectedRetryable) {
+				t.Errorf("Expected retryable %v, got %v", tc.ExpectedRetryable, actual)
+			}
+		})
+	}
+}
+
+func TestErrorClass_Matches(t *testing.T) {
+	cases := map[string]struct {
+		Class    ErrorClass
+		Err      error
+		Expected bool
+	}{
+		"nil":                {Class: Any, Err: nil, Expected: false},
+		"any":                {Class: Any, Err: errors.New("boom"), Expected: true},
+		"conflict":           {Class: Conflict, Err: &googleapi.Error{Code: 409}, Expected: true},
+		"wrapped conflict":   {Class: Conflict, Err: fmt.Errorf("wrapped: %w", &googleapi.Error{Code: 409}), Expected: true},
+		"not conflict":       {Class: Conflict, Err: &googleapi.Error{Code: 404}, Expected: false},
+		"rate limit":         {Class: RateLimit, Err: &googleapi.Error{Code: 429}, Expected: true},
+		"server":             {Class: ServerError, Err: &googleapi.Error{Code: 503}, Expected: true},
+		"server client err":  {Class: ServerError, Err: &googleapi.Error{Code: 400}, Expected: false},
+		"network":            {Class: Network, Err: &net.OpError{Op: "dial", Err: errors.New("refused")}, Expected: true},
+		"network plain":      {Class: Network, Err: errors.New("boom"), Expected: false},
+		"unknown class":      {Class: ErrorClass("bogus"), Err: &googleapi.Error{Code: 500}, Expected: false},
+		"status coder error": {Class: RateLimit, Err: &responseError{resp: &http.Response{StatusCode: 429}}, Expected: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := tc.Class.Matches(tc.Err); actual != tc.Expected {
+				t.Errorf("Expected %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestPolicy_Do(t *testing.T) {
+	conflict := &googleapi.Error{Code: 409}
+	notFound := &googleapi.Error{Code: 404}
+
+	cases := map[string]struct {
+		Errors        []error
+		ExpectedCalls int
+		ExpectedErr   error
+	}{
+		"success": {
+			Errors:        []error{nil},
+			ExpectedCalls: 1,
+			ExpectedErr:   nil,
+		},
+		"retry then success": {
+			Errors:        []error{conflict, nil},
+			ExpectedCalls: 2,
+			ExpectedErr:   nil,
+		},
+		"not retryable": {
+			Errors:        []error{notFound},
+			ExpectedCalls: 1,
+			ExpectedErr:   notFound,
+		},
+		"exhausted": {
+			Errors:        []error{conflict, conflict, conflict},
+			ExpectedCalls: 3,
+			ExpectedErr:   &ExhaustedError{Policy: "test", Attempts: 3, Err: conflict},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			policy := NewPolicySet("test-retry.").Policy("test", "", Defaults{MaxAttempts: 3, Retryable: []ErrorClass{Conflict}})
+
+			calls := 0
+			err := policy.Do(context.Background(), func() error {
+				err := tc.Errors[calls]
+				calls++
+				return err
+			})
+
+			if calls != tc.ExpectedCalls {
+				t.Errorf("Expected %d calls, got %d", tc.ExpectedCalls, calls)
+			}
+
+			if !reflect.DeepEqual(err, tc.ExpectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.ExpectedErr, err)
+			}
+		})
+	}
+}
+
+func TestPolicy_DoCanceled(t *testing.T) {
+	conflict := &googleapi.Error{Code: 409}
+	notFound := &googleapi.Error{Code: 404}
+
+	cases := map[string]struct {
+		Err           error
+		ExpectedCalls int
+	}{
+		"retryable":     {Err: conflict, ExpectedCalls: 1},
+		"not retryable": {Err: notFound, ExpectedCalls: 1},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			policy := NewPolicySet("test-retry.").Policy("test", "", Defaults{MaxAttempts: 3, Retryable: []ErrorClass{Conflict}})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			calls := 0
+			err := policy.Do(ctx, func() error {
+				calls++
+				return tc.Err
+			})
+
+			if calls != tc.ExpectedCalls {
+				t.Errorf("Expected %d calls, got %d", tc.ExpectedCalls, calls)
+			}
+
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected a canceled error, got %v", err)
+			}
+
+			if !strings.Contains(err.Error(), tc.Err.Error()) {
+				t.Errorf("Expected the error to mention %q, got %v", tc.Err, err)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	cases := map[string]struct {
+		Method         string
+		Statuses       []int
+		ExpectedCalls  int
+		ExpectedStatus int
+	}{
+		"retries idempotent": {
+			Method:         http.MethodGet,
+			Statuses:       []int{http.StatusTooManyRequests, http.StatusOK},
+			ExpectedCalls:  2,
+			ExpectedStatus: http.StatusOK,
+		},
+		"returns last response when exhausted": {
+			Method:         http.MethodPut,
+			Statuses:       []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests},
+			ExpectedCalls:  3,
+			ExpectedStatus: http.StatusTooManyRequests,
+		},
+		"does not retry client errors": {
+			Method:         http.MethodGet,
+			Statuses:       []int{http.StatusNotFound},
+			ExpectedCalls:  1,
+			ExpectedStatus: http.StatusNotFound,
+		},
+		"does not retry non-idempotent": {
+			Method:         http.MethodPost,
+			Statuses:       []int{http.StatusTooManyRequests},
+			ExpectedCalls:  1,
+			ExpectedStatus: http.StatusTooManyRequests,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.Statuses[calls])
+				calls++
+			}))
+			defer server.Close()
+
+			policy := NewPolicySet("test-retry.").Policy("test", "", Defaults{MaxAttempts: 3, Retryable: []ErrorClass{RateLimit}})
+			client := &http.Client{Transport: NewTransport(policy, nil)}
+
+			req, err := http.NewRequest(tc.Method, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if calls != tc.ExpectedCalls {
+				t.Errorf("Expected %d calls, got %d", tc.ExpectedCalls, calls)
+			}
+
+			if resp.StatusCode != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.ExpectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// idempotentMethods holds the HTTP methods that are safe to send more than
+// once.
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// responseError converts a failed HTTP response into an error so it can be
+// classified by a policy.
+type responseError struct {
+	resp *http.Response
+}
+
+func (e *responseError) Error() string {
+	return fmt.Sprintf("unexpected response: %s", e.resp.Status)
+}
+
+// StatusCode implements StatusCoder.
+func (e *responseError) StatusCode() int {
+	return e.resp.StatusCode
+}
+
+// Transport is an http.RoundTripper that retries idempotent requests according
+// to a Policy. Responses with a retryable status code are retried; the last
+// response is returned to the caller if the policy runs out of attempts.
+type Transport struct {
+	Policy Policy
+	Base   http.RoundTripper
+}
+
+// NewTransport creates a Transport for the given policy. If base is nil then
+// http.DefaultTransport is used.
+func NewTransport(policy Policy, base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{Policy: policy, Base: base}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !idempotentMethods[req.Method] || (req.Body != nil && req.GetBody == nil) {
+		return t.Base.RoundTrip(req)
+	}
+
+	var resp *http.Response
+	tries := 0
+	err := t.Policy.Do(req.Context(), func() error {
+		tries++
+
+		attempt := req
+		if req.Body != nil && tries > 1 {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+
+			attempt = req.Clone(req.Context())
+			attempt.Body = body
+		}
+
+		if resp != nil {
+			// discard the previous failed response so the connection can be reused
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+
+		var err error
+		resp, err = t.Base.RoundTrip(attempt)
+		if err != nil {
+			resp = nil
+			return err
+		}
+
+		if resp.StatusCode >= 400 {
+			return &responseError{resp: resp}
+		}
+
+		return nil
+	})
+
+	if resp != nil {
+		return resp, nil
+	}
+
+	return nil, err
+}
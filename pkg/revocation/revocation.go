@@ -0,0 +1,83 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package revocation holds the types used to revoke binding credentials in
+// bulk, e.g. in response to a credential-exposure incident.
+package revocation
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// CredentialsRevokedEvent is the notification event sent to the owners of
+// revoked bindings.
+const CredentialsRevokedEvent = "credentials-revoked"
+
+// ErrEmptyFilter is returned if a revocation doesn't set any
+// criteria, to prevent accidentally revoking every binding.
+var ErrEmptyFilter = errors.New("at least one of service, plan, organization_guid, space_guid or created_before must be set")
+
+// Filter selects the bindings whose credentials get revoked. Bindings
+// must match every criteria that's set.
+type Filter struct {
+	// Service holds the name or ID of a service.
+	Service string `json:"service,omitempty"`
+	// Plan holds the name or ID of a plan.
+	Plan             string     `json:"plan,omitempty"`
+	OrganizationGuid string     `json:"organization_guid,omitempty"`
+	SpaceGuid        string     `json:"space_guid,omitempty"`
+	CreatedBefore    *time.Time `json:"created_before,omitempty"`
+
+	// Reason is passed on to the owners of the revoked bindings.
+	Reason string `json:"reason,omitempty"`
+}
+
+// IsEmpty returns true if the filter doesn't set any criteria.
+func (f *Filter) IsEmpty() bool {
+	return f.Service == "" && f.Plan == "" && f.OrganizationGuid == "" && f.SpaceGuid == "" && f.CreatedBefore == nil
+}
+
+// Binding describes a binding affected by a revocation.
+type Binding struct {
+	BindingId        string     `json:"binding_id"`
+	InstanceId       string     `json:"instance_id"`
+	ServiceId        string     `json:"service_id"`
+	PlanId           string     `json:"plan_id,omitempty"`
+	OrganizationGuid string     `json:"organization_guid,omitempty"`
+	SpaceGuid        string     `json:"space_guid,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	Error            string     `json:"error,omitempty"`
+}
+
+// Report summarizes the outcome of a revocation. Dry runs list the bindings
+// that would be revoked in Planned instead of revoking them.
+type Report struct {
+	DryRun             bool      `json:"dry_run,omitempty"`
+	Planned            []Binding `json:"planned,omitempty"`
+	Revoked            []Binding `json:"revoked"`
+	Failed             []Binding `json:"failed"`
+	NotificationErrors []string  `json:"notification_errors,omitempty"`
+}
+
+// Revoker revokes binding credentials in bulk.
+type Revoker interface {
+	// RevokeCredentials revokes the credentials of every binding matching the
+	// filter.
+	RevokeCredentials(ctx context.Context, filter Filter) (*Report, error)
+	// ListRevokedBindings lists bindings that were revoked and still need to be
+	// rotated.
+	ListRevokedBindings(ctx context.Context) ([]Binding, error)
+}
@@ -0,0 +1,172 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// CatalogCache serves the catalog of the wrapped ServiceBroker from memory.
+// The catalog is built once and again only when Refresh is called, so the
+// frequent catalog requests platforms make don't rebuild it from the
+// configuration each time.
+type CatalogCache struct {
+	brokerapi.ServiceBroker
+
+	// Check validates a rebuilt catalog before it replaces the cached one,
+	// it's skipped if nil.
+	Check func(ctx context.Context, services []brokerapi.Service) error
+
+	mu       sync.RWMutex
+	services []brokerapi.Service
+	err      error
+	etag     string
+	modified time.Time
+}
+
+// NewCatalogCache wraps the given ServiceBroker and builds its catalog. If
+// that fails, the error is returned by Services until a Refresh succeeds.
+func NewCatalogCache(ctx context.Context, wrapped brokerapi.ServiceBroker) *CatalogCache {
+	cache := &CatalogCache{ServiceBroker: wrapped}
+	cache.Refresh(ctx)
+	return cache
+}
+
+// Services returns the cached catalog.
+func (c *CatalogCache) Services(ctx context.Context) ([]brokerapi.Service, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return append([]brokerapi.Service(nil), c.services...), c.err
+}
+
+// Refresh rebuilds the catalog from the wrapped ServiceBroker and returns it.
+// If that or the Check fails, the previous catalog is kept. Requests served
+// while the catalog is rebuilt get the previous one.
+func (c *CatalogCache) Refresh(ctx context.Context) ([]brokerapi.Service, error) {
+	services, err := c.ServiceBroker.Services(ctx)
+	if err == nil && c.Check != nil {
+		err = c.Check(ctx, services)
+	}
+	if err == nil {
+		err = c.set(services)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil && c.services == nil {
+		c.err = err
+	}
+
+	return services, err
+}
+
+func (c *CatalogCache) set(services []brokerapi.Service) error {
+	body, err := json.Marshal(services)
+	if err != nil {
+		return err
+	}
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// an identical catalog wasn't modified, so it keeps its validators
+	if etag != c.etag {
+		c.etag = etag
+		c.modified = time.Now().UTC().Truncate(time.Second)
+	}
+	c.services = services
+	c.err = nil
+
+	return nil
+}
+
+// Validators returns the ETag and modification time of the cached catalog.
+func (c *CatalogCache) Validators() (etag string, modified time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.etag, c.modified
+}
+
+// Wrap adds the ETag and Last-Modified headers to responses for the catalog
+// and responds with 304 Not Modified to requests whose If-None-Match or
+// If-Modified-Since header shows they already have it.
+func (c *CatalogCache) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		etag, modified := c.Validators()
+		if req.Method != http.MethodGet || req.URL.Path != "/v2/catalog" || etag == "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		if notModified(req, etag, modified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// notModified follows RFC 7232: If-Modified-Since is ignored if the request
+// has an If-None-Match header.
+func notModified(req *http.Request, etag string, modified time.Time) bool {
+	if header := req.Header.Get("If-None-Match"); header != "" {
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	return err == nil && !modified.After(since)
+}
+
+// AddCatalogHandler adds an admin endpoint that refreshes the catalog, e.g.
+// after changing the configuration file. The refresh function returns the
+// new catalog.
+//
+// The wrap function is used to add authentication to the handler.
+func AddCatalogHandler(router *mux.Router, refresh func(context.Context) ([]brokerapi.Service, error), wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/catalog/refresh", wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		services, err := refresh(req.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(services)
+	}))).Methods(http.MethodPost)
+}
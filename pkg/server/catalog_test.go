@@ -0,0 +1,223 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/server/fakes"
+)
+
+func TestCatalogCache_Services(t *testing.T) {
+	catalog := []brokerapi.Service{{ID: "svc-1", Name: "db"}}
+	changed := []brokerapi.Service{{ID: "svc-1", Name: "db"}, {ID: "svc-2", Name: "cache"}}
+
+	wrapped := &fakes.FakeServiceBroker{}
+	wrapped.ServicesReturnsOnCall(0, catalog, nil)
+	wrapped.ServicesReturnsOnCall(1, nil, errors.New("bad plan"))
+	wrapped.ServicesReturnsOnCall(2, changed, nil)
+
+	cache := NewCatalogCache(context.Background(), wrapped)
+	for i := 0; i < 3; i++ {
+		cache.Services(context.Background())
+	}
+	if wrapped.ServicesCallCount() != 1 {
+		t.Errorf("Expected the catalog to be built once, got %d", wrapped.ServicesCallCount())
+	}
+	etag, _ := cache.Validators()
+
+	if _, err := cache.Refresh(context.Background()); err == nil {
+		t.Error("Expected refresh error")
+	}
+	if services, err := cache.Services(context.Background()); err != nil || len(services) != 1 {
+		t.Errorf("Expected the previous catalog to be kept, got %v, %v", services, err)
+	}
+
+	if _, err := cache.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if services, _ := cache.Services(context.Background()); len(services) != 2 {
+		t.Errorf("Expected the refreshed catalog, got %v", services)
+	}
+	if newEtag, _ := cache.Validators(); newEtag == etag {
+		t.Errorf("Expected the ETag to change, got %s", newEtag)
+	}
+}
+
+func TestCatalogCache_InitialError(t *testing.T) {
+	wrapped := &fakes.FakeServiceBroker{}
+	wrapped.ServicesReturns(nil, errors.New("bad plan"))
+
+	cache := NewCatalogCache(context.Background(), wrapped)
+	if _, err := cache.Services(context.Background()); err == nil {
+		t.Error("Expected the build error")
+	}
+
+	wrapped.ServicesReturns([]brokerapi.Service{{ID: "svc-1"}}, nil)
+	cache.Refresh(context.Background())
+	if _, err := cache.Services(context.Background()); err != nil {
+		t.Errorf("Expected no error after refreshing, got %v", err)
+	}
+}
+
+func TestCatalogCache_Check(t *testing.T) {
+	wrapped := &fakes.FakeServiceBroker{}
+	wrapped.ServicesReturns([]brokerapi.Service{{ID: "svc-1"}}, nil)
+
+	cache := NewCatalogCache(context.Background(), wrapped)
+	cache.Check = func(ctx context.Context, services []brokerapi.Service) error {
+		if len(services) > 1 {
+			return errors.New("plan ID changed")
+		}
+		return nil
+	}
+
+	wrapped.ServicesReturns([]brokerapi.Service{{ID: "svc-1"}, {ID: "svc-2"}}, nil)
+	if _, err := cache.Refresh(context.Background()); err == nil {
+		t.Error("Expected the check to fail")
+	}
+	if services, err := cache.Services(context.Background()); err != nil || len(services) != 1 {
+		t.Errorf("Expected the previous catalog to be kept, got %v, %v", services, err)
+	}
+}
+
+func TestCatalogCache_Wrap(t *testing.T) {
+	wrapped := &fakes.FakeServiceBroker{}
+	wrapped.ServicesReturns([]brokerapi.Service{{ID: "svc-1", Name: "db"}}, nil)
+	cache := NewCatalogCache(context.Background(), wrapped)
+	etag, modified := cache.Validators()
+
+	cases := map[string]struct {
+		Method         string
+		Path           string
+		Headers        map[string]string
+		ExpectedStatus int
+		ExpectedEtag   string
+	}{
+		"no validators": {
+			Path:           "/v2/catalog",
+			ExpectedStatus: http.StatusOK,
+			ExpectedEtag:   etag,
+		},
+		"matching etag": {
+			Path:           "/v2/catalog",
+			Headers:        map[string]string{"If-None-Match": `"other", ` + etag},
+			ExpectedStatus: http.StatusNotModified,
+			ExpectedEtag:   etag,
+		},
+		"weak etag": {
+			Path:           "/v2/catalog",
+			Headers:        map[string]string{"If-None-Match": "W/" + etag},
+			ExpectedStatus: http.StatusNotModified,
+			ExpectedEtag:   etag,
+		},
+		"stale etag": {
+			Path:           "/v2/catalog",
+			Headers:        map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": modified.Format(http.TimeFormat)},
+			ExpectedStatus: http.StatusOK,
+			ExpectedEtag:   etag,
+		},
+		"not modified since": {
+			Path:           "/v2/catalog",
+			Headers:        map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)},
+			ExpectedStatus: http.StatusNotModified,
+			ExpectedEtag:   etag,
+		},
+		"modified since": {
+			Path:           "/v2/catalog",
+			Headers:        map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)},
+			ExpectedStatus: http.StatusOK,
+			ExpectedEtag:   etag,
+		},
+		"other path": {
+			Path:           "/v2/service_instances/abc",
+			Headers:        map[string]string{"If-None-Match": etag},
+			ExpectedStatus: http.StatusOK,
+		},
+		"other method": {
+			Method:         http.MethodPut,
+			Path:           "/v2/catalog",
+			Headers:        map[string]string{"If-None-Match": etag},
+			ExpectedStatus: http.StatusOK,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			method := tc.Method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req := httptest.NewRequest(method, tc.Path, nil)
+			for k, v := range tc.Headers {
+				req.Header.Set(k, v)
+			}
+
+			w := httptest.NewRecorder()
+			ok := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { w.WriteHeader(http.StatusOK) })
+			cache.Wrap(ok).ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if actual := w.Header().Get("ETag"); actual != tc.ExpectedEtag {
+				t.Errorf("Expected ETag %q, got %q", tc.ExpectedEtag, actual)
+			}
+		})
+	}
+}
+
+func TestAddCatalogHandler(t *testing.T) {
+	cases := map[string]struct {
+		Refresh        func(context.Context) ([]brokerapi.Service, error)
+		ExpectedStatus int
+	}{
+		"refreshed": {
+			Refresh: func(ctx context.Context) ([]brokerapi.Service, error) {
+				return []brokerapi.Service{{ID: "svc-1"}}, nil
+			},
+			ExpectedStatus: http.StatusOK,
+		},
+		"error": {
+			Refresh: func(ctx context.Context) ([]brokerapi.Service, error) {
+				return nil, errors.New("can't read config")
+			},
+			ExpectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddCatalogHandler(router, tc.Refresh, func(h http.Handler) http.Handler { return h })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/catalog/refresh", nil))
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tc.ExpectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
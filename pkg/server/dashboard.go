@@ -0,0 +1,198 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/inventory"
+)
+
+// dashboardFailureLimit is the number of recent failures shown.
+const dashboardFailureLimit = 10
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+	<head>
+		<meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+		<title>Cloud Service Broker Dashboard</title>
+		<meta charset="utf-8" />
+		<link rel="stylesheet" href="https://maxcdn.bootstrapcdn.com/bootstrap/4.0.0/css/bootstrap.min.css" crossorigin="anonymous" />
+	</head>
+	<body>
+		<nav class="navbar navbar-expand navbar-dark sticky-top" style="background-color:#626975;">
+			<a class="navbar-brand" href="#">
+				Cloud Service Broker
+			</a>
+			<div>
+				<ul class="navbar-nav">
+					<li class="nav-item">
+						<a class="nav-link" href="/docs">Docs</a>
+					</li>
+					<li class="nav-item">
+						<a class="nav-link active" href="/admin/dashboard">Dashboard</a>
+					</li>
+				</ul>
+			</div>
+		</nav>
+		<div class="container" id="maincontent">
+			<br />
+			<h2>Database</h2>
+			{{ if .MigrationError }}
+			<div class="alert alert-danger" id="migrations">{{ .MigrationError }}</div>
+			{{ else }}
+			<div class="alert alert-success" id="migrations">Migrations are up to date.</div>
+			{{ end }}
+
+			<h2>Instances</h2>
+			<table class="table table-striped" id="instances">
+				<thead><tr><th>Service</th><th>Plan</th><th>Instances</th></tr></thead>
+				<tbody>
+				{{ range .Counts }}
+				<tr><td>{{ .Service }}</td><td>{{ .Plan }}</td><td>{{ .Count }}</td></tr>
+				{{ else }}
+				<tr><td colspan="3">No instances.</td></tr>
+				{{ end }}
+				</tbody>
+			</table>
+
+			<h2>Pending Operations</h2>
+			<table class="table table-striped" id="pending">
+				<thead><tr><th>Updated</th><th>Service</th><th>Instance</th><th>Binding</th><th>Type</th></tr></thead>
+				<tbody>
+				{{ range .Pending }}
+				<tr><td>{{ .UpdatedAt.Format "2006-01-02 15:04:05 MST" }}</td><td>{{ .ServiceName }}</td><td>{{ .InstanceId }}</td><td>{{ .BindingId }}</td><td>{{ .Type }}</td></tr>
+				{{ else }}
+				<tr><td colspan="5">No pending operations.</td></tr>
+				{{ end }}
+				</tbody>
+			</table>
+
+			<h2>Recent Failures</h2>
+			<table class="table table-striped" id="failures">
+				<thead><tr><th>Updated</th><th>Service</th><th>Instance</th><th>Binding</th><th>Type</th><th>Error</th></tr></thead>
+				<tbody>
+				{{ range .Failures }}
+				<tr><td>{{ .UpdatedAt.Format "2006-01-02 15:04:05 MST" }}</td><td>{{ .ServiceName }}</td><td>{{ .InstanceId }}</td><td>{{ .BindingId }}</td><td>{{ .Type }}</td><td><pre>{{ .Message }}</pre></td></tr>
+				{{ else }}
+				<tr><td colspan="6">No failed operations.</td></tr>
+				{{ end }}
+				</tbody>
+			</table>
+		</div>
+	</body>
+</html>
+`))
+
+// instanceCount is the number of instances of a plan.
+type instanceCount struct {
+	Service string
+	Plan    string
+	Count   int
+}
+
+// AddDashboardHandler adds an HTML dashboard at /admin/dashboard summarizing
+// instance counts per plan, pending operations, recent failures and whether
+// the database has pending migrations. It's built from the same data as the
+// admin API.
+//
+// The wrap function is used to add authentication to the handler.
+func AddDashboardHandler(router *mux.Router, lister inventory.Lister, checkMigrations func() error, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/dashboard", wrap(NewDashboardHandler(lister, checkMigrations))).Methods(http.MethodGet)
+}
+
+// NewDashboardHandler creates a handler that renders the operator dashboard.
+func NewDashboardHandler(lister inventory.Lister, checkMigrations func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+
+		instances, err := lister.ListInstances(ctx, inventory.Filter{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		pending, err := lister.ListOperations(ctx, inventory.Filter{State: inventory.StateInProgress})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		failures, err := lister.ListOperations(ctx, inventory.Filter{State: inventory.StateFailed, Limit: dashboardFailureLimit})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		migrationError := ""
+		if checkMigrations != nil {
+			if err := checkMigrations(); err != nil {
+				migrationError = err.Error()
+			}
+		}
+
+		buf := &bytes.Buffer{}
+		err = dashboardTemplate.Execute(buf, map[string]interface{}{
+			"MigrationError": migrationError,
+			"Counts":         countInstances(instances),
+			"Pending":        pending,
+			"Failures":       failures,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	}
+}
+
+// countInstances counts the instances of each plan, sorted by service then
+// plan. Services and plans no longer in the catalog are shown by ID.
+func countInstances(instances []inventory.Instance) []instanceCount {
+	counts := make(map[instanceCount]int)
+	for _, instance := range instances {
+		key := instanceCount{Service: instance.ServiceName, Plan: instance.PlanName}
+		if key.Service == "" {
+			key.Service = instance.ServiceId
+		}
+		if key.Plan == "" {
+			key.Plan = instance.PlanId
+		}
+		counts[key]++
+	}
+
+	out := []instanceCount{}
+	for key, count := range counts {
+		key.Count = count
+		out = append(out, key)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Service != out[j].Service {
+			return out[i].Service < out[j].Service
+		}
+		return out[i].Plan < out[j].Plan
+	})
+
+	return out
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/inventory"
+)
+
+func TestAddDashboardHandler(t *testing.T) {
+	cases := map[string]struct {
+		CheckMigrations func() error
+		ExpectedBody    []string
+	}{
+		"migrations current": {
+			CheckMigrations: func() error { return nil },
+			ExpectedBody:    []string{"Migrations are up to date.", "instance-1", "No pending operations."},
+		},
+		"migrations pending": {
+			CheckMigrations: func() error { return errors.New("2 migration(s) pending") },
+			ExpectedBody:    []string{"2 migration(s) pending"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddDashboardHandler(router, &fakeDashboardLister{}, tc.CheckMigrations, func(h http.Handler) http.Handler { return h })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/dashboard", nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+
+			if contentType := w.Header().Get("Content-Type"); contentType != "text/html" {
+				t.Errorf("Expected text/html content, got %q", contentType)
+			}
+
+			for _, expected := range tc.ExpectedBody {
+				if !strings.Contains(w.Body.String(), expected) {
+					t.Errorf("Expected body to contain %q, got %s", expected, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCountInstances(t *testing.T) {
+	instances := []inventory.Instance{
+		{ServiceName: "db", PlanName: "small"},
+		{ServiceName: "db", PlanName: "large"},
+		{ServiceName: "db", PlanName: "small"},
+		{ServiceId: "removed-service", PlanId: "removed-plan"},
+	}
+
+	expected := []instanceCount{
+		{Service: "db", Plan: "large", Count: 1},
+		{Service: "db", Plan: "small", Count: 2},
+		{Service: "removed-service", Plan: "removed-plan", Count: 1},
+	}
+
+	if actual := countInstances(instances); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected counts %v, got %v", expected, actual)
+	}
+}
+
+// fakeDashboardLister returns failed operations only when asked for them so
+// the pending table is empty.
+type fakeDashboardLister struct {
+	fakeInventoryLister
+}
+
+func (f *fakeDashboardLister) ListOperations(ctx context.Context, filter inventory.Filter) ([]inventory.Operation, error) {
+	if filter.State != inventory.StateFailed {
+		return nil, nil
+	}
+
+	return f.fakeInventoryLister.ListOperations(ctx, filter)
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/discovery"
+)
+
+// DiscoveryRefresh is the body of a request to refresh cached discovery
+// results. Every source is refreshed if no keys are given.
+type DiscoveryRefresh struct {
+	Keys []string `json:"keys"`
+}
+
+// AddDiscoveryHandler adds endpoints at /admin/discovery that list the cached
+// discovery results and, at /admin/discovery/refresh, fetch them again.
+//
+// The wrap function is used to add authentication to the handler.
+func AddDiscoveryHandler(router *mux.Router, cache *discovery.Cache, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/discovery", wrap(NewDiscoveryListHandler(cache))).Methods(http.MethodGet)
+	router.Handle("/admin/discovery/refresh", wrap(NewDiscoveryRefreshHandler(cache))).Methods(http.MethodPost)
+}
+
+// NewDiscoveryListHandler creates a handler that lists cached discovery
+// results.
+func NewDiscoveryListHandler(cache *discovery.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		entries, err := cache.Entries(req.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+// NewDiscoveryRefreshHandler creates a handler that fetches discovery results
+// again regardless of whether they've expired.
+func NewDiscoveryRefreshHandler(cache *discovery.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		refresh := DiscoveryRefresh{}
+		if req.ContentLength != 0 {
+			if err := json.NewDecoder(req.Body).Decode(&refresh); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		for _, key := range refresh.Keys {
+			if _, ok := cache.Sources[key]; !ok {
+				http.Error(w, "unknown discovery source: "+key, http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := cache.Refresh(req.Context(), refresh.Keys...); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		NewDiscoveryListHandler(cache)(w, req)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/discovery"
+)
+
+type fakeDiscoveryStore struct {
+	Entry *models.DiscoveryCacheEntry
+}
+
+func (f *fakeDiscoveryStore) GetDiscoveryCacheEntry(ctx context.Context, key string) (*models.DiscoveryCacheEntry, error) {
+	return f.Entry, nil
+}
+
+func (f *fakeDiscoveryStore) ListDiscoveryCacheEntries(ctx context.Context) ([]models.DiscoveryCacheEntry, error) {
+	if f.Entry == nil {
+		return nil, nil
+	}
+
+	return []models.DiscoveryCacheEntry{*f.Entry}, nil
+}
+
+func (f *fakeDiscoveryStore) SaveDiscoveryCacheEntry(ctx context.Context, entry *models.DiscoveryCacheEntry) error {
+	f.Entry = entry
+	return nil
+}
+
+func TestAddDiscoveryHandler(t *testing.T) {
+	cases := map[string]struct {
+		Method         string
+		Path           string
+		Body           string
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"list empty": {
+			Method:         http.MethodGet,
+			Path:           "/admin/discovery",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `[]`,
+		},
+		"refresh all": {
+			Method:         http.MethodPost,
+			Path:           "/admin/discovery/refresh",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `"key":"compute.regions"`,
+		},
+		"refresh key": {
+			Method:         http.MethodPost,
+			Path:           "/admin/discovery/refresh",
+			Body:           `{"keys":["compute.regions"]}`,
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `"value":["us-central1"]`,
+		},
+		"refresh unknown key": {
+			Method:         http.MethodPost,
+			Path:           "/admin/discovery/refresh",
+			Body:           `{"keys":["cloudsql.tiers"]}`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `unknown discovery source: cloudsql.tiers`,
+		},
+		"bad json": {
+			Method:         http.MethodPost,
+			Path:           "/admin/discovery/refresh",
+			Body:           `{`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `unexpected EOF`,
+		},
+		"method not allowed": {
+			Method:         http.MethodDelete,
+			Path:           "/admin/discovery",
+			ExpectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			cache := &discovery.Cache{
+				Sources: map[string]discovery.FetchFunc{
+					"compute.regions": func(ctx context.Context) (interface{}, error) {
+						return []string{"us-central1"}, nil
+					},
+				},
+				Store:  &fakeDiscoveryStore{},
+				Ttl:    time.Hour,
+				Logger: lager.NewLogger("test"),
+			}
+
+			router := mux.NewRouter()
+			AddDiscoveryHandler(router, cache, func(h http.Handler) http.Handler { return h })
+
+			req := httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(tc.Body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tc.ExpectedStatus, w.Code, w.Body.String())
+			}
+
+			if !strings.Contains(w.Body.String(), tc.ExpectedBody) {
+				t.Errorf("Expected body to contain %q, got %q", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -70,7 +70,9 @@ func AddDocsHandler(router *mux.Router, registry broker.BrokerRegistry) {
 	router.Handle("/", handler)
 }
 
-func renderAsPage(title, markdownContents string) http.HandlerFunc {
+// RenderDocsPage renders the markdown documentation as the HTML page served
+// at /docs.
+func RenderDocsPage(title, markdownContents string) ([]byte, error) {
 	renderer := blackfriday.HtmlRenderer(
 		blackfriday.EXTENSION_FENCED_CODE|
 			blackfriday.EXTENSION_AUTOLINK,
@@ -85,6 +87,11 @@ func renderAsPage(title, markdownContents string) http.HandlerFunc {
 		"Contents": template.HTML(page),
 	})
 
+	return buf.Bytes(), err
+}
+
+func renderAsPage(title, markdownContents string) http.HandlerFunc {
+	page, err := RenderDocsPage(title, markdownContents)
 	if err != nil {
 		return func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -95,6 +102,6 @@ func renderAsPage(title, markdownContents string) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
 		w.WriteHeader(200)
-		w.Write(buf.Bytes())
+		w.Write(page)
 	}
 }
@@ -0,0 +1,48 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+)
+
+// DryRunParam is the query parameter that makes a mutating admin endpoint
+// report the changes it would make without making them.
+const DryRunParam = "dry_run"
+
+// dryRunContext returns the request's context, marked as a dry run if the
+// request sets the dry_run query parameter to true.
+func dryRunContext(req *http.Request) (context.Context, error) {
+	value := req.URL.Query().Get(DryRunParam)
+	if value == "" {
+		return req.Context(), nil
+	}
+
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q, it must be true or false", DryRunParam, value)
+	}
+
+	if dryRun {
+		return planning.WithDryRun(req.Context()), nil
+	}
+
+	return req.Context(), nil
+}
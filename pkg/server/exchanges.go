@@ -0,0 +1,70 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/recorder"
+)
+
+// defaultExchangeLimit is the number of exchanges returned if no limit is
+// given.
+const defaultExchangeLimit = 20
+
+// ExchangeLister lists the OSB exchanges recorded for an instance.
+type ExchangeLister interface {
+	Exchanges(ctx context.Context, instanceId string, limit int) ([]recorder.Exchange, error)
+}
+
+// AddExchangeHandler adds an endpoint at /admin/instances/{instance_id}/exchanges
+// that lists the most recent OSB requests and responses recorded for the
+// instance and its bindings, newest first. The limit query parameter sets how
+// many are returned, defaulting to 20.
+//
+// The wrap function is used to add authentication to the handler.
+func AddExchangeHandler(router *mux.Router, lister ExchangeLister, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/instances/{instance_id}/exchanges", wrap(NewExchangeHandler(lister))).Methods(http.MethodGet)
+}
+
+// NewExchangeHandler creates a handler that lists the exchanges recorded for
+// the instance in the instance_id route variable.
+func NewExchangeHandler(lister ExchangeLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		limit := defaultExchangeLimit
+		if raw := req.URL.Query().Get("limit"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 1 {
+				http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+
+		exchanges, err := lister.Exchanges(req.Context(), mux.Vars(req)["instance_id"], limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(exchanges)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/recorder"
+)
+
+type fakeExchangeLister struct {
+	Err        error
+	InstanceId string
+	Limit      int
+}
+
+func (f *fakeExchangeLister) Exchanges(ctx context.Context, instanceId string, limit int) ([]recorder.Exchange, error) {
+	f.InstanceId = instanceId
+	f.Limit = limit
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	return []recorder.Exchange{{InstanceId: instanceId, Method: http.MethodPut, Status: http.StatusCreated}}, nil
+}
+
+func TestAddExchangeHandler(t *testing.T) {
+	cases := map[string]struct {
+		Method         string
+		Query          string
+		Err            error
+		ExpectedStatus int
+		ExpectedBody   string
+		ExpectedLimit  int
+	}{
+		"default limit": {
+			Method:         http.MethodGet,
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `"instance_id":"instance-1"`,
+			ExpectedLimit:  20,
+		},
+		"limit": {
+			Method:         http.MethodGet,
+			Query:          "?limit=5",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `"status":201`,
+			ExpectedLimit:  5,
+		},
+		"bad limit": {
+			Method:         http.MethodGet,
+			Query:          "?limit=0",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   "limit must be a positive integer",
+		},
+		"error": {
+			Method:         http.MethodGet,
+			Err:            errors.New("database is down"),
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedBody:   "database is down",
+			ExpectedLimit:  20,
+		},
+		"method not allowed": {
+			Method:         http.MethodPost,
+			ExpectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			lister := &fakeExchangeLister{Err: tc.Err}
+			router := mux.NewRouter()
+			AddExchangeHandler(router, lister, func(h http.Handler) http.Handler { return h })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tc.Method, "/admin/instances/instance-1/exchanges"+tc.Query, nil))
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tc.ExpectedStatus, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tc.ExpectedBody) {
+				t.Errorf("Expected body to contain %q, got %q", tc.ExpectedBody, w.Body.String())
+			}
+			if lister.Limit != tc.ExpectedLimit {
+				t.Errorf("Expected limit %d, got %d", tc.ExpectedLimit, lister.Limit)
+			}
+			if tc.ExpectedLimit != 0 && lister.InstanceId != "instance-1" {
+				t.Errorf("Expected instance-1 to be listed, got %q", lister.InstanceId)
+			}
+		})
+	}
+}
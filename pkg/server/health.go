@@ -16,23 +16,84 @@ package server
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/heptiolabs/healthcheck"
+	"golang.org/x/oauth2"
 )
 
+// checkTimeout is how long a readiness check may take before it fails.
+const checkTimeout = 2 * time.Second
+
 // AddHealthHandler creates a new handler for health and liveness checks and
-// adds it to the /live and /ready endpoints.
-func AddHealthHandler(router *mux.Router, db *sql.DB) healthcheck.Handler {
+// adds it to the /health, /live and /ready endpoints. The liveness endpoints
+// don't call any dependencies. The readiness endpoint pings the database, if
+// one is given, and runs the extra readiness checks. Add ?full=1 to a request
+// to get the result of each check. The details, e.g. how the broker
+// authenticates with its cloud, are added to the /health response.
+func AddHealthHandler(router *mux.Router, db *sql.DB, readinessChecks map[string]healthcheck.Check, details map[string]interface{}) healthcheck.Handler {
 	health := healthcheck.NewHandler()
 
 	if db != nil {
-		health.AddReadinessCheck("database", healthcheck.DatabasePingCheck(db, 2*time.Second))
+		health.AddReadinessCheck("database", healthcheck.DatabasePingCheck(db, checkTimeout))
+	}
+
+	for name, check := range readinessChecks {
+		health.AddReadinessCheck(name, healthcheck.Timeout(check, checkTimeout))
 	}
 
+	router.HandleFunc("/health", withDetails(health.LiveEndpoint, details))
 	router.HandleFunc("/live", health.LiveEndpoint)
 	router.HandleFunc("/ready", health.ReadyEndpoint)
 
 	return health
 }
+
+// withDetails adds the details to the JSON object the endpoint responds with.
+func withDetails(endpoint http.HandlerFunc, details map[string]interface{}) http.HandlerFunc {
+	if len(details) == 0 {
+		return endpoint
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := httptest.NewRecorder()
+		endpoint(recorder, r)
+
+		body := make(map[string]interface{})
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for key, value := range details {
+			body[key] = value
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(recorder.Code)
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+// TokenSourceCheck creates a check that fails if the token source can't get a
+// valid token, e.g. because the credentials it uses were revoked. Token
+// sources that reuse tokens only call their provider once the token expires.
+func TokenSourceCheck(source oauth2.TokenSource) healthcheck.Check {
+	return func() error {
+		token, err := source.Token()
+		if err != nil {
+			return err
+		}
+
+		if !token.Valid() {
+			return errors.New("token is invalid")
+		}
+
+		return nil
+	}
+}
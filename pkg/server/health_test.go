@@ -21,16 +21,16 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/jinzhu/gorm"
-
-	// Needed to open the sqlite3 database
-	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/heptiolabs/healthcheck"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"golang.org/x/oauth2"
 )
 
 func TestNewHealthHandler(t *testing.T) {
-	db, err := gorm.Open("sqlite3", "test.db")
+	db, err := db_service.OpenSqlite("test.db")
 	if err != nil {
 		t.Fatalf("couldn't create database: %v", err)
 	}
@@ -59,6 +59,11 @@ func TestNewHealthHandler(t *testing.T) {
 			ExpectedBody:   `{"test-live":"bad-value"}`,
 			LiveErr:        errors.New("bad-value"),
 		},
+		"health endpoint": {
+			Endpoint:       "/health?full=1",
+			ExpectedStatus: 200,
+			ExpectedBody:   `{"test-live":"OK"}`,
+		},
 		"ready endpoint minimal": {
 			Endpoint:       "/ready",
 			ExpectedStatus: 200,
@@ -86,7 +91,7 @@ func TestNewHealthHandler(t *testing.T) {
 	for tn, tc := range cases {
 		t.Run(tn, func(t *testing.T) {
 			router := mux.NewRouter()
-			handler := AddHealthHandler(router, db.DB())
+			handler := AddHealthHandler(router, db.DB(), nil, nil)
 			handler.AddLivenessCheck("test-live", func() error {
 				return tc.LiveErr
 			})
@@ -113,3 +118,116 @@ func TestNewHealthHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestAddHealthHandler_readinessChecks(t *testing.T) {
+	router := mux.NewRouter()
+	AddHealthHandler(router, nil, map[string]healthcheck.Check{
+		"migrations": func() error { return errors.New("2 migration(s) pending") },
+		"slow":       func() error { time.Sleep(time.Minute); return nil },
+	}, nil)
+
+	request := httptest.NewRequest(http.MethodGet, "/ready?full=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, request)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected response code: %v got: %v", http.StatusServiceUnavailable, w.Code)
+	}
+
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, w.Body.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"migrations":"2 migration(s) pending","slow":"timed out after 2s"}`
+	if compacted.String() != expected {
+		t.Fatalf("Expected response: %v got: %v", expected, compacted.String())
+	}
+}
+
+func TestAddHealthHandler_details(t *testing.T) {
+	router := mux.NewRouter()
+	handler := AddHealthHandler(router, nil, nil, map[string]interface{}{
+		"gcp_credentials": map[string]string{"source": "metadata_server"},
+	})
+	handler.AddLivenessCheck("test-live", func() error { return errors.New("bad-value") })
+
+	cases := map[string]struct {
+		Endpoint     string
+		ExpectedBody string
+	}{
+		"minimal": {
+			Endpoint:     "/health",
+			ExpectedBody: `{"gcp_credentials":{"source":"metadata_server"}}`,
+		},
+		"full": {
+			Endpoint:     "/health?full=1",
+			ExpectedBody: `{"gcp_credentials":{"source":"metadata_server"},"test-live":"bad-value"}`,
+		},
+		"live": {
+			Endpoint:     "/live",
+			ExpectedBody: `{}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tc.Endpoint, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, request)
+
+			if w.Code != http.StatusServiceUnavailable {
+				t.Fatalf("Expected response code: %v got: %v", http.StatusServiceUnavailable, w.Code)
+			}
+
+			compacted := &bytes.Buffer{}
+			if err := json.Compact(compacted, w.Body.Bytes()); err != nil {
+				t.Fatal(err)
+			}
+
+			if compacted.String() != tc.ExpectedBody {
+				t.Fatalf("Expected response: %v got: %v", tc.ExpectedBody, compacted.String())
+			}
+		})
+	}
+}
+
+type fakeTokenSource struct {
+	token *oauth2.Token
+	err   error
+}
+
+func (f fakeTokenSource) Token() (*oauth2.Token, error) {
+	return f.token, f.err
+}
+
+func TestTokenSourceCheck(t *testing.T) {
+	cases := map[string]struct {
+		Source      fakeTokenSource
+		ExpectedErr string
+	}{
+		"valid": {
+			Source: fakeTokenSource{token: &oauth2.Token{AccessToken: "abc", Expiry: time.Now().Add(time.Hour)}},
+		},
+		"expired": {
+			Source:      fakeTokenSource{token: &oauth2.Token{AccessToken: "abc", Expiry: time.Now().Add(-time.Hour)}},
+			ExpectedErr: "token is invalid",
+		},
+		"error": {
+			Source:      fakeTokenSource{err: errors.New("invalid_grant")},
+			ExpectedErr: "invalid_grant",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			err := TokenSourceCheck(tc.Source)()
+			switch {
+			case tc.ExpectedErr == "" && err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			case tc.ExpectedErr != "" && (err == nil || err.Error() != tc.ExpectedErr):
+				t.Errorf("Expected error %q, got %v", tc.ExpectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/imports"
+)
+
+// AddImportHandler adds an endpoint at /admin/imports. POSTing a JSON
+// imports.Request registers the existing resources it identifies as a service
+// instance and responds with the imports.Result. Asynchronous imports respond
+// with 202 Accepted and finish when their operation does.
+//
+// The wrap function is used to add authentication to the handler.
+func AddImportHandler(router *mux.Router, importer imports.Importer, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/imports", wrap(NewImportHandler(importer))).Methods(http.MethodPost)
+}
+
+// NewImportHandler creates a handler that imports existing resources as
+// service instances.
+func NewImportHandler(importer imports.Importer) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		request := imports.Request{}
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := importer.ImportInstance(req.Context(), request)
+		if err != nil {
+			http.Error(w, err.Error(), importErrorStatus(err))
+			return
+		}
+
+		status := http.StatusCreated
+		if result.Async {
+			status = http.StatusAccepted
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// importErrorStatus returns the HTTP status for an import error, requests that
+// can't be imported or conflict with an instance are the caller's fault.
+func importErrorStatus(err error) int {
+	switch e := err.(type) {
+	case *imports.InvalidRequestError:
+		return http.StatusBadRequest
+	case *brokerapi.FailureResponse:
+		return e.ValidatedStatusCode(nil)
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/imports"
+)
+
+type fakeImporter struct {
+	Request imports.Request
+}
+
+func (f *fakeImporter) ImportInstance(ctx context.Context, request imports.Request) (*imports.Result, error) {
+	f.Request = request
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch request.InstanceId {
+	case "existing":
+		return nil, brokerapi.ErrInstanceAlreadyExists
+	case "broken":
+		return nil, errors.New("database unavailable")
+	case "sync":
+		return &imports.Result{InstanceId: request.InstanceId, ServiceId: "svc-1", PlanId: "plan-1"}, nil
+	}
+
+	return &imports.Result{InstanceId: request.InstanceId, ServiceId: "svc-1", PlanId: "plan-1", Async: true, OperationId: "tf:" + request.InstanceId + ":"}, nil
+}
+
+func TestNewImportHandler(t *testing.T) {
+	request := func(instanceId string) string {
+		return `{"instance_id":"` + instanceId + `","service":"svc-1","plan":"plan-1","organization_guid":"org","space_guid":"space","parameters":{"name":"my-bucket"}}`
+	}
+
+	cases := map[string]struct {
+		Method         string
+		Body           string
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"async": {
+			Method:         http.MethodPost,
+			Body:           request("instance-1"),
+			ExpectedStatus: http.StatusAccepted,
+			ExpectedBody:   `{"instance_id":"instance-1","service_id":"svc-1","plan_id":"plan-1","async":true,"operation_id":"tf:instance-1:"}`,
+		},
+		"sync": {
+			Method:         http.MethodPost,
+			Body:           request("sync"),
+			ExpectedStatus: http.StatusCreated,
+			ExpectedBody:   `{"instance_id":"sync","service_id":"svc-1","plan_id":"plan-1","async":false}`,
+		},
+		"missing fields": {
+			Method:         http.MethodPost,
+			Body:           `{"instance_id":"instance-1","service":"svc-1"}`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `missing required fields: plan, organization_guid, space_guid`,
+		},
+		"instance exists": {
+			Method:         http.MethodPost,
+			Body:           request("existing"),
+			ExpectedStatus: http.StatusConflict,
+			ExpectedBody:   brokerapi.ErrInstanceAlreadyExists.Error(),
+		},
+		"failure": {
+			Method:         http.MethodPost,
+			Body:           request("broken"),
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedBody:   `database unavailable`,
+		},
+		"bad json": {
+			Method:         http.MethodPost,
+			Body:           `{`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `unexpected EOF`,
+		},
+		"method not allowed": {
+			Method:         http.MethodGet,
+			ExpectedStatus: http.StatusMethodNotAllowed,
+			ExpectedBody:   ``,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			importer := &fakeImporter{}
+			router := mux.NewRouter()
+			AddImportHandler(router, importer, func(h http.Handler) http.Handler { return h })
+
+			req := httptest.NewRequest(tc.Method, "/admin/imports", strings.NewReader(tc.Body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if actual := strings.TrimSpace(w.Body.String()); actual != tc.ExpectedBody {
+				t.Errorf("Expected body %s, got %s", tc.ExpectedBody, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/inventory"
+)
+
+// AddInventoryHandler adds endpoints at /admin/instances, /admin/bindings and
+// /admin/operations that list what the broker manages. Results are filtered
+// by the service, plan, organization_guid, space_guid and state query
+// parameters; instances are also filtered by the stuck query parameter and
+// operations are limited by the limit query parameter.
+//
+// The wrap function is used to add authentication to the handler.
+func AddInventoryHandler(router *mux.Router, lister inventory.Lister, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/instances", wrap(newInventoryHandler(func(ctx context.Context, filter inventory.Filter) (interface{}, error) {
+		return lister.ListInstances(ctx, filter)
+	}))).Methods(http.MethodGet)
+
+	router.Handle("/admin/bindings", wrap(newInventoryHandler(func(ctx context.Context, filter inventory.Filter) (interface{}, error) {
+		return lister.ListBindings(ctx, filter)
+	}))).Methods(http.MethodGet)
+
+	router.Handle("/admin/operations", wrap(newInventoryHandler(func(ctx context.Context, filter inventory.Filter) (interface{}, error) {
+		return lister.ListOperations(ctx, filter)
+	}))).Methods(http.MethodGet)
+}
+
+func newInventoryHandler(list func(context.Context, inventory.Filter) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		filter := inventory.Filter{
+			Service:          query.Get("service"),
+			Plan:             query.Get("plan"),
+			OrganizationGuid: query.Get("organization_guid"),
+			SpaceGuid:        query.Get("space_guid"),
+			State:            query.Get("state"),
+		}
+
+		if stuck := query.Get("stuck"); stuck != "" {
+			parsed, err := strconv.ParseBool(stuck)
+			if err != nil {
+				http.Error(w, "stuck must be true or false", http.StatusBadRequest)
+				return
+			}
+			filter.Stuck = parsed
+		}
+
+		if limit := query.Get("limit"); limit != "" {
+			parsed, err := strconv.Atoi(limit)
+			if err != nil || parsed < 1 {
+				http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			filter.Limit = parsed
+		}
+
+		resp, err := list(req.Context(), filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
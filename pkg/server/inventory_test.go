@@ -0,0 +1,126 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/inventory"
+)
+
+type fakeInventoryLister struct {
+	Filter inventory.Filter
+}
+
+func (f *fakeInventoryLister) ListInstances(ctx context.Context, filter inventory.Filter) ([]inventory.Instance, error) {
+	f.Filter = filter
+	return []inventory.Instance{{InstanceId: "instance-1", State: inventory.StateSucceeded}}, nil
+}
+
+func (f *fakeInventoryLister) ListBindings(ctx context.Context, filter inventory.Filter) ([]inventory.Binding, error) {
+	f.Filter = filter
+	return []inventory.Binding{{BindingId: "binding-1", InstanceId: "instance-1", State: inventory.StateActive}}, nil
+}
+
+func (f *fakeInventoryLister) ListOperations(ctx context.Context, filter inventory.Filter) ([]inventory.Operation, error) {
+	f.Filter = filter
+	return []inventory.Operation{{InstanceId: "instance-1", Type: "provision", State: inventory.StateFailed}}, nil
+}
+
+func TestAddInventoryHandler(t *testing.T) {
+	cases := map[string]struct {
+		Method         string
+		Path           string
+		ExpectedStatus int
+		ExpectedBody   string
+		ExpectedFilter inventory.Filter
+	}{
+		"instances": {
+			Method:         http.MethodGet,
+			Path:           "/admin/instances?service=db&plan=small&organization_guid=org&space_guid=space&state=failed",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `"instance_id":"instance-1"`,
+			ExpectedFilter: inventory.Filter{Service: "db", Plan: "small", OrganizationGuid: "org", SpaceGuid: "space", State: "failed"},
+		},
+		"stuck instances": {
+			Method:         http.MethodGet,
+			Path:           "/admin/instances?stuck=true",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `"instance_id":"instance-1"`,
+			ExpectedFilter: inventory.Filter{Stuck: true},
+		},
+		"bad stuck": {
+			Method:         http.MethodGet,
+			Path:           "/admin/instances?stuck=maybe",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   "stuck must be true or false",
+		},
+		"bindings": {
+			Method:         http.MethodGet,
+			Path:           "/admin/bindings?state=revoked",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `"binding_id":"binding-1"`,
+			ExpectedFilter: inventory.Filter{State: "revoked"},
+		},
+		"operations": {
+			Method:         http.MethodGet,
+			Path:           "/admin/operations?limit=10",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `"type":"provision"`,
+			ExpectedFilter: inventory.Filter{Limit: 10},
+		},
+		"bad limit": {
+			Method:         http.MethodGet,
+			Path:           "/admin/operations?limit=-1",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   "limit must be a positive integer",
+		},
+		"method not allowed": {
+			Method:         http.MethodPost,
+			Path:           "/admin/instances",
+			ExpectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			lister := &fakeInventoryLister{}
+			router := mux.NewRouter()
+			AddInventoryHandler(router, lister, func(h http.Handler) http.Handler { return h })
+
+			req := httptest.NewRequest(tc.Method, tc.Path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tc.ExpectedStatus, w.Code, w.Body.String())
+			}
+
+			if !strings.Contains(w.Body.String(), tc.ExpectedBody) {
+				t.Errorf("Expected body to contain %q, got %q", tc.ExpectedBody, w.Body.String())
+			}
+
+			if !reflect.DeepEqual(lister.Filter, tc.ExpectedFilter) {
+				t.Errorf("Expected filter %+v, got %+v", tc.ExpectedFilter, lister.Filter)
+			}
+		})
+	}
+}
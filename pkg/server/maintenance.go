@@ -0,0 +1,64 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/maintenance"
+)
+
+// AddMaintenanceHandler adds an endpoint at /admin/maintenance. GET responds
+// with the maintenance.Status of the broker instance; PUTting a JSON
+// maintenance.Change turns maintenance mode on or off and responds with the
+// new status. New provisions and bindings are rejected while it's on.
+//
+// The wrap function is used to add authentication to the handler.
+func AddMaintenanceHandler(router *mux.Router, mode *maintenance.Mode, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/maintenance", wrap(NewMaintenanceHandler(mode))).Methods(http.MethodGet, http.MethodPut)
+}
+
+// NewMaintenanceHandler creates a handler that gets and sets the maintenance
+// mode.
+func NewMaintenanceHandler(mode *maintenance.Mode) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		status := mode.Status()
+
+		if req.Method == http.MethodPut {
+			change := maintenance.Change{}
+			if err := json.NewDecoder(req.Body).Decode(&change); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			var err error
+			status, err = mode.Set(change)
+			if _, ok := err.(*maintenance.InvalidRequestError); ok {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(status)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/maintenance"
+)
+
+func TestNewMaintenanceHandler(t *testing.T) {
+	cases := map[string]struct {
+		Method         string
+		Body           string
+		ExpectedStatus int
+		ExpectedMode   maintenance.Status
+		ExpectedBody   string
+	}{
+		"get": {
+			Method:         http.MethodGet,
+			ExpectedStatus: http.StatusOK,
+			ExpectedMode:   maintenance.Status{RetryAfter: "5m0s"},
+		},
+		"enable": {
+			Method:         http.MethodPut,
+			Body:           `{"enabled":true,"message":"database upgrade","retry_after":"10m"}`,
+			ExpectedStatus: http.StatusOK,
+			ExpectedMode:   maintenance.Status{Enabled: true, Message: "database upgrade", RetryAfter: "10m0s"},
+		},
+		"missing enabled": {
+			Method:         http.MethodPut,
+			Body:           `{"message":"database upgrade"}`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `enabled is required`,
+		},
+		"bad retry after": {
+			Method:         http.MethodPut,
+			Body:           `{"enabled":true,"retry_after":"-1m"}`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `invalid retry_after: must be positive, got -1m`,
+		},
+		"bad json": {
+			Method:         http.MethodPut,
+			Body:           `{`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `unexpected EOF`,
+		},
+		"method not allowed": {
+			Method:         http.MethodDelete,
+			ExpectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			mode := &maintenance.Mode{}
+			if _, err := mode.Set(maintenance.Change{Enabled: new(bool), RetryAfter: "5m"}); err != nil {
+				t.Fatal(err)
+			}
+
+			router := mux.NewRouter()
+			AddMaintenanceHandler(router, mode, func(h http.Handler) http.Handler { return h })
+
+			req := httptest.NewRequest(tc.Method, "/admin/maintenance", strings.NewReader(tc.Body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if w.Code != http.StatusOK {
+				if actual := strings.TrimSpace(w.Body.String()); actual != tc.ExpectedBody {
+					t.Errorf("Expected body %s, got %s", tc.ExpectedBody, actual)
+				}
+				return
+			}
+
+			actual := maintenance.Status{}
+			if err := json.NewDecoder(w.Body).Decode(&actual); err != nil {
+				t.Fatal(err)
+			}
+			if tc.ExpectedMode.Enabled && (actual.Since == nil || time.Since(*actual.Since) > time.Minute) {
+				t.Errorf("Expected a recent since, got %v", actual.Since)
+			}
+			actual.Since = nil
+			if actual != tc.ExpectedMode {
+				t.Errorf("Expected status %+v, got %+v", tc.ExpectedMode, actual)
+			}
+		})
+	}
+}
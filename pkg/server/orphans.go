@@ -0,0 +1,55 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+)
+
+// OrphanPlanner plans deleting orphaned cloud resources.
+type OrphanPlanner interface {
+	Plan(ctx context.Context) (*planning.Plan, error)
+}
+
+// AddOrphanHandler adds an endpoint at /admin/orphans that lists the cloud
+// resources labeled with the ID of an instance the broker doesn't manage.
+// Listing never deletes them.
+//
+// The wrap function is used to add authentication to the handler.
+func AddOrphanHandler(router *mux.Router, planner OrphanPlanner, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/orphans", wrap(NewOrphanHandler(planner))).Methods(http.MethodGet)
+}
+
+// NewOrphanHandler creates a handler that reports orphaned resources as a dry
+// run of deleting them.
+func NewOrphanHandler(planner OrphanPlanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := planning.WithDryRun(req.Context())
+		changes, err := planner.Plan(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(changes.Execute(ctx))
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+)
+
+type fakeOrphanPlanner struct {
+	Err     error
+	Deleted bool
+}
+
+func (f *fakeOrphanPlanner) Plan(ctx context.Context) (*planning.Plan, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	changes := &planning.Plan{}
+	changes.Add("delete-orphan", "storage.bucket/bucket-1", map[string]interface{}{"instance_id": "deleted"}, func(ctx context.Context) error {
+		f.Deleted = true
+		return nil
+	})
+	return changes, nil
+}
+
+func TestAddOrphanHandler(t *testing.T) {
+	cases := map[string]struct {
+		Method         string
+		Err            error
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"list": {
+			Method:         http.MethodGet,
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `"target":"storage.bucket/bucket-1"`,
+		},
+		"error": {
+			Method:         http.MethodGet,
+			Err:            errors.New("listing instances: database is down"),
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedBody:   "database is down",
+		},
+		"method not allowed": {
+			Method:         http.MethodPost,
+			ExpectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			planner := &fakeOrphanPlanner{Err: tc.Err}
+			router := mux.NewRouter()
+			AddOrphanHandler(router, planner, func(h http.Handler) http.Handler { return h })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tc.Method, "/admin/orphans", nil))
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tc.ExpectedStatus, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tc.ExpectedBody) {
+				t.Errorf("Expected body to contain %q, got %q", tc.ExpectedBody, w.Body.String())
+			}
+			if planner.Deleted {
+				t.Error("Expected listing orphans not to delete them")
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/preview"
+)
+
+// AddPreviewHandler adds an endpoint at /admin/previews. POSTing a JSON
+// preview.Request runs it through the checks a provision does and responds
+// with a preview.Result listing the outcome of each check and the resources
+// the provision would create. Nothing is created; requests that would fail to
+// provision still respond with 200 OK and an invalid result.
+//
+// The wrap function is used to add authentication to the handler.
+func AddPreviewHandler(router *mux.Router, previewer preview.Previewer, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/previews", wrap(NewPreviewHandler(previewer))).Methods(http.MethodPost)
+}
+
+// NewPreviewHandler creates a handler that dry runs provision requests.
+func NewPreviewHandler(previewer preview.Previewer) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		request := preview.Request{}
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := previewer.PreviewProvision(req.Context(), request)
+		if _, ok := err.(*preview.InvalidRequestError); ok {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(result)
+	}
+}
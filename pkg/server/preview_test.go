@@ -0,0 +1,117 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/preview"
+)
+
+type fakePreviewer struct{}
+
+func (f *fakePreviewer) PreviewProvision(ctx context.Context, request preview.Request) (*preview.Result, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch request.Plan {
+	case "broken":
+		return nil, errors.New("database unavailable")
+	case "over-quota":
+		return &preview.Result{InstanceId: "preview-1", ServiceId: "svc-1", PlanId: "plan-2", Checks: []preview.Check{
+			{Name: "quota", Error: "quota exceeded", Code: "QuotaExceeded"},
+		}}, nil
+	}
+
+	count := 1
+	return &preview.Result{InstanceId: "preview-1", ServiceId: "svc-1", PlanId: "plan-1", Valid: true, Checks: []preview.Check{
+		{Name: "quota", Passed: true},
+	}, Resources: []preview.Resource{{Type: "google_storage_bucket", Name: "bucket", Count: &count}}}, nil
+}
+
+func TestNewPreviewHandler(t *testing.T) {
+	request := func(plan string) string {
+		return `{"service":"svc-1","plan":"` + plan + `","organization_guid":"org","space_guid":"space","parameters":{"name":"my-bucket"}}`
+	}
+
+	cases := map[string]struct {
+		Method         string
+		Body           string
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"valid": {
+			Method:         http.MethodPost,
+			Body:           request("plan-1"),
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"instance_id":"preview-1","service_id":"svc-1","plan_id":"plan-1","valid":true,"async":false,"checks":[{"name":"quota","passed":true}],"resources":[{"type":"google_storage_bucket","name":"bucket","count":1}]}`,
+		},
+		"would fail": {
+			Method:         http.MethodPost,
+			Body:           request("over-quota"),
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"instance_id":"preview-1","service_id":"svc-1","plan_id":"plan-2","valid":false,"async":false,"checks":[{"name":"quota","passed":false,"error":"quota exceeded","code":"QuotaExceeded"}]}`,
+		},
+		"missing fields": {
+			Method:         http.MethodPost,
+			Body:           `{"service":"svc-1"}`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `missing required fields: plan, organization_guid, space_guid`,
+		},
+		"failure": {
+			Method:         http.MethodPost,
+			Body:           request("broken"),
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedBody:   `database unavailable`,
+		},
+		"bad json": {
+			Method:         http.MethodPost,
+			Body:           `{`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `unexpected EOF`,
+		},
+		"method not allowed": {
+			Method:         http.MethodGet,
+			ExpectedStatus: http.StatusMethodNotAllowed,
+			ExpectedBody:   ``,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddPreviewHandler(router, &fakePreviewer{}, func(h http.Handler) http.Handler { return h })
+
+			req := httptest.NewRequest(tc.Method, "/admin/previews", strings.NewReader(tc.Body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if actual := strings.TrimSpace(w.Body.String()); actual != tc.ExpectedBody {
+				t.Errorf("Expected body %s, got %s", tc.ExpectedBody, actual)
+			}
+		})
+	}
+}
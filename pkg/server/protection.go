@@ -0,0 +1,73 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/protection"
+)
+
+// AddProtectionHandler adds an endpoint at
+// /admin/instances/{instance_id}/protection. GET responds with the
+// protection.Status of the instance; PUTting a JSON protection.Change protects
+// or unprotects the instance regardless of its plan and responds with the new
+// status. Deprovisioning a protected instance fails until it's unprotected.
+//
+// The wrap function is used to add authentication to the handler.
+func AddProtectionHandler(router *mux.Router, protector protection.Protector, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/instances/{instance_id}/protection", wrap(NewProtectionHandler(protector))).Methods(http.MethodGet, http.MethodPut)
+}
+
+// NewProtectionHandler creates a handler that gets and sets the deletion
+// protection of the instance in the instance_id route variable.
+func NewProtectionHandler(protector protection.Protector) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		instanceId := mux.Vars(req)["instance_id"]
+
+		var status *protection.Status
+		var err error
+
+		if req.Method == http.MethodGet {
+			status, err = protector.GetProtection(req.Context(), instanceId)
+		} else {
+			change := protection.Change{}
+			if err := json.NewDecoder(req.Body).Decode(&change); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			status, err = protector.SetProtection(req.Context(), instanceId, change)
+		}
+
+		switch {
+		case err == protection.ErrInstanceNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case err == protection.ErrMissingProtected:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(status)
+	}
+}
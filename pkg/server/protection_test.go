@@ -0,0 +1,151 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/protection"
+)
+
+type fakeProtector struct {
+	Protected map[string]bool
+}
+
+func (f *fakeProtector) GetProtection(ctx context.Context, instanceId string) (*protection.Status, error) {
+	switch instanceId {
+	case "unknown":
+		return nil, protection.ErrInstanceNotFound
+	case "broken":
+		return nil, errors.New("database unavailable")
+	}
+
+	status := &protection.Status{InstanceId: instanceId}
+	if protected, ok := f.Protected[instanceId]; ok {
+		status.Protected = protected
+		status.Source = protection.SourceInstance
+	}
+
+	return status, nil
+}
+
+func (f *fakeProtector) SetProtection(ctx context.Context, instanceId string, change protection.Change) (*protection.Status, error) {
+	if err := change.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := f.GetProtection(ctx, instanceId); err != nil {
+		return nil, err
+	}
+
+	f.Protected[instanceId] = *change.Protected
+	return f.GetProtection(ctx, instanceId)
+}
+
+func TestNewProtectionHandler(t *testing.T) {
+	cases := map[string]struct {
+		Method         string
+		InstanceId     string
+		Body           string
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"get unprotected": {
+			Method:         http.MethodGet,
+			InstanceId:     "instance-1",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"instance_id":"instance-1","protected":false}`,
+		},
+		"get protected": {
+			Method:         http.MethodGet,
+			InstanceId:     "protected",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"instance_id":"protected","protected":true,"source":"instance"}`,
+		},
+		"protect": {
+			Method:         http.MethodPut,
+			InstanceId:     "instance-1",
+			Body:           `{"protected":true,"reason":"production"}`,
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"instance_id":"instance-1","protected":true,"source":"instance"}`,
+		},
+		"unprotect": {
+			Method:         http.MethodPut,
+			InstanceId:     "protected",
+			Body:           `{"protected":false}`,
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"instance_id":"protected","protected":false,"source":"instance"}`,
+		},
+		"missing protected": {
+			Method:         http.MethodPut,
+			InstanceId:     "instance-1",
+			Body:           `{"reason":"production"}`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `protected must be set`,
+		},
+		"unknown instance": {
+			Method:         http.MethodGet,
+			InstanceId:     "unknown",
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   `instance not found`,
+		},
+		"failure": {
+			Method:         http.MethodPut,
+			InstanceId:     "broken",
+			Body:           `{"protected":true}`,
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedBody:   `database unavailable`,
+		},
+		"bad json": {
+			Method:         http.MethodPut,
+			InstanceId:     "instance-1",
+			Body:           `{`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `unexpected EOF`,
+		},
+		"method not allowed": {
+			Method:         http.MethodDelete,
+			InstanceId:     "instance-1",
+			ExpectedStatus: http.StatusMethodNotAllowed,
+			ExpectedBody:   ``,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			protector := &fakeProtector{Protected: map[string]bool{"protected": true}}
+			router := mux.NewRouter()
+			AddProtectionHandler(router, protector, func(h http.Handler) http.Handler { return h })
+
+			req := httptest.NewRequest(tc.Method, "/admin/instances/"+tc.InstanceId+"/protection", strings.NewReader(tc.Body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if actual := strings.TrimSpace(w.Body.String()); actual != tc.ExpectedBody {
+				t.Errorf("Expected body %s, got %s", tc.ExpectedBody, actual)
+			}
+		})
+	}
+}
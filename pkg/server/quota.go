@@ -0,0 +1,75 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/quota"
+)
+
+// QuotaResponse is the body returned by the quota endpoint.
+type QuotaResponse struct {
+	Rules []quota.Rule  `json:"rules"`
+	Usage []quota.Usage `json:"usage,omitempty"`
+}
+
+// AddQuotaHandler adds an endpoint at /admin/quotas that lists the configured
+// quota rules. If the organization_guid or space_guid query parameters are set
+// it also reports how much of each applicable rule is used, optionally
+// restricted to the service with the name or ID in the service parameter.
+//
+// The wrap function is used to add authentication to the handler.
+func AddQuotaHandler(router *mux.Router, registry broker.BrokerRegistry, enforcer *quota.Enforcer, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/quotas", wrap(NewQuotaHandler(registry, enforcer))).Methods(http.MethodGet)
+}
+
+// NewQuotaHandler creates a handler that reports quota rules and usage.
+func NewQuotaHandler(registry broker.BrokerRegistry, enforcer *quota.Enforcer) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		orgGuid := query.Get("organization_guid")
+		spaceGuid := query.Get("space_guid")
+		serviceFilter := query.Get("service")
+
+		resp := QuotaResponse{Rules: enforcer.Rules}
+		if resp.Rules == nil {
+			resp.Rules = []quota.Rule{}
+		}
+
+		if orgGuid != "" || spaceGuid != "" {
+			for _, svc := range registry.GetAllServices() {
+				if serviceFilter != "" && serviceFilter != svc.Name && serviceFilter != svc.Id {
+					continue
+				}
+
+				usage, err := enforcer.Usage(req.Context(), svc, orgGuid, spaceGuid)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				resp.Usage = append(resp.Usage, usage...)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/db_service/fakes"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/quota"
+)
+
+func TestNewQuotaHandler(t *testing.T) {
+	registry := broker.BrokerRegistry{
+		"svc-a": &broker.ServiceDefinition{Id: "a-id", Name: "svc-a"},
+		"svc-b": &broker.ServiceDefinition{Id: "b-id", Name: "svc-b"},
+	}
+
+	store := fakes.NewInMemoryDatastore()
+	for _, id := range []string{"instance-1", "instance-2", "instance-3"} {
+		instance := models.ServiceInstanceDetails{ID: id, ServiceId: "a-id", OrganizationGuid: "org-1"}
+		if err := store.CreateServiceInstanceDetails(context.Background(), &instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	enforcer := &quota.Enforcer{
+		Rules: []quota.Rule{{Service: "svc-a", Scope: quota.OrganizationScope, Limit: 5}},
+		Store: store,
+	}
+
+	cases := map[string]struct {
+		Endpoint       string
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"rules only": {
+			Endpoint:       "/admin/quotas",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"rules":[{"service":"svc-a","scope":"organization","limit":5}]}`,
+		},
+		"usage": {
+			Endpoint:       "/admin/quotas?organization_guid=org-1",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"rules":[{"service":"svc-a","scope":"organization","limit":5}],"usage":[{"rule":{"service":"svc-a","scope":"organization","limit":5},"service_name":"svc-a","scope_guid":"org-1","used":3}]}`,
+		},
+		"usage filtered to other service": {
+			Endpoint:       "/admin/quotas?organization_guid=org-1&service=svc-b",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"rules":[{"service":"svc-a","scope":"organization","limit":5}]}`,
+		},
+		"unauthorized": {
+			Endpoint:       "/admin/quotas?deny=true",
+			ExpectedStatus: http.StatusUnauthorized,
+			ExpectedBody:   ``,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddQuotaHandler(router, registry, enforcer, func(h http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Query().Get("deny") != "" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					h.ServeHTTP(w, r)
+				})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tc.Endpoint, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if actual := strings.TrimSpace(w.Body.String()); actual != tc.ExpectedBody {
+				t.Errorf("Expected body %s, got %s", tc.ExpectedBody, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/requeststats"
+)
+
+// AddRequestStatsHandler adds an endpoint at /admin/requests that responds
+// with the requeststats.Snapshot of the broker instance: the OSB API requests
+// in flight on each endpoint and the latest slow requests with the time they
+// spent authenticating, in the database and calling Google Cloud.
+//
+// The wrap function is used to add authentication to the handler.
+func AddRequestStatsHandler(router *mux.Router, tracker *requeststats.Tracker, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/requests", wrap(NewRequestStatsHandler(tracker))).Methods(http.MethodGet)
+}
+
+// NewRequestStatsHandler creates a handler that responds with the request
+// stats.
+func NewRequestStatsHandler(tracker *requeststats.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(tracker.Snapshot())
+	}
+}
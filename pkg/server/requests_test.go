@@ -0,0 +1,63 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/requeststats"
+)
+
+func TestNewRequestStatsHandler(t *testing.T) {
+	cases := map[string]struct {
+		Method         string
+		ExpectedStatus int
+	}{
+		"get":                {Method: http.MethodGet, ExpectedStatus: http.StatusOK},
+		"method not allowed": {Method: http.MethodPut, ExpectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			tracker := &requeststats.Tracker{SlowThreshold: 10 * time.Second}
+
+			router := mux.NewRouter()
+			AddRequestStatsHandler(router, tracker, func(h http.Handler) http.Handler { return h })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tc.Method, "/admin/requests", nil))
+
+			if w.Code != tc.ExpectedStatus {
+				t.Fatalf("Expected status %d, got %d", tc.ExpectedStatus, w.Code)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			snapshot := requeststats.Snapshot{}
+			if err := json.NewDecoder(w.Body).Decode(&snapshot); err != nil {
+				t.Fatal(err)
+			}
+			if snapshot.SlowThreshold != "10s" {
+				t.Errorf("Expected slow threshold 10s, got %q", snapshot.SlowThreshold)
+			}
+		})
+	}
+}
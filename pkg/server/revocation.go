@@ -0,0 +1,74 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/revocation"
+)
+
+// AddRevocationHandler adds an endpoint at /admin/revocations. POSTing a JSON
+// revocation.Filter revokes the credentials of every matching binding
+// and responds with a report of what was revoked; GET lists the bindings
+// that were revoked and still need to be rotated. POSTing with ?dry_run=true
+// reports the bindings that would be revoked without revoking them.
+//
+// The wrap function is used to add authentication to the handler.
+func AddRevocationHandler(router *mux.Router, revoker revocation.Revoker, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/revocations", wrap(NewRevocationHandler(revoker))).Methods(http.MethodGet, http.MethodPost)
+}
+
+// NewRevocationHandler creates a handler that revokes and lists revoked
+// binding credentials.
+func NewRevocationHandler(revoker revocation.Revoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var resp interface{}
+		var err error
+
+		if req.Method == http.MethodGet {
+			resp, err = revoker.ListRevokedBindings(req.Context())
+		} else {
+			filter := revocation.Filter{}
+			if err := json.NewDecoder(req.Body).Decode(&filter); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			ctx, dryRunErr := dryRunContext(req)
+			if dryRunErr != nil {
+				http.Error(w, dryRunErr.Error(), http.StatusBadRequest)
+				return
+			}
+
+			resp, err = revoker.RevokeCredentials(ctx, filter)
+		}
+
+		switch {
+		case err == revocation.ErrEmptyFilter:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+	"github.com/pivotal/cloud-service-broker/pkg/revocation"
+)
+
+type fakeRevoker struct {
+	Filter revocation.Filter
+}
+
+func (f *fakeRevoker) RevokeCredentials(ctx context.Context, filter revocation.Filter) (*revocation.Report, error) {
+	if filter.IsEmpty() {
+		return nil, revocation.ErrEmptyFilter
+	}
+
+	f.Filter = filter
+	if planning.IsDryRun(ctx) {
+		return &revocation.Report{
+			DryRun:  true,
+			Planned: []revocation.Binding{{BindingId: "binding-1", InstanceId: "instance-1", ServiceId: "svc-1"}},
+			Revoked: []revocation.Binding{},
+			Failed:  []revocation.Binding{},
+		}, nil
+	}
+
+	return &revocation.Report{
+		Revoked: []revocation.Binding{{BindingId: "binding-1", InstanceId: "instance-1", ServiceId: "svc-1"}},
+		Failed:  []revocation.Binding{},
+	}, nil
+}
+
+func (f *fakeRevoker) ListRevokedBindings(ctx context.Context) ([]revocation.Binding, error) {
+	return []revocation.Binding{{BindingId: "binding-2", InstanceId: "instance-2", ServiceId: "svc-1"}}, nil
+}
+
+func TestNewRevocationHandler(t *testing.T) {
+	cases := map[string]struct {
+		Method          string
+		Query           string
+		Body            string
+		ExpectedStatus  int
+		ExpectedBody    string
+		ExpectedService string
+	}{
+		"list": {
+			Method:         http.MethodGet,
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `[{"binding_id":"binding-2","instance_id":"instance-2","service_id":"svc-1"}]`,
+		},
+		"revoke": {
+			Method:          http.MethodPost,
+			Body:            `{"service":"svc-1","reason":"leaked"}`,
+			ExpectedStatus:  http.StatusOK,
+			ExpectedBody:    `{"revoked":[{"binding_id":"binding-1","instance_id":"instance-1","service_id":"svc-1"}],"failed":[]}`,
+			ExpectedService: "svc-1",
+		},
+		"dry run": {
+			Method:          http.MethodPost,
+			Query:           "?dry_run=true",
+			Body:            `{"service":"svc-1","reason":"leaked"}`,
+			ExpectedStatus:  http.StatusOK,
+			ExpectedBody:    `{"dry_run":true,"planned":[{"binding_id":"binding-1","instance_id":"instance-1","service_id":"svc-1"}],"revoked":[],"failed":[]}`,
+			ExpectedService: "svc-1",
+		},
+		"bad dry_run": {
+			Method:         http.MethodPost,
+			Query:          "?dry_run=maybe",
+			Body:           `{"service":"svc-1","reason":"leaked"}`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `invalid dry_run value "maybe", it must be true or false`,
+		},
+		"empty filter": {
+			Method:         http.MethodPost,
+			Body:           `{"reason":"leaked"}`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   revocation.ErrEmptyFilter.Error(),
+		},
+		"bad json": {
+			Method:         http.MethodPost,
+			Body:           `{`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `unexpected EOF`,
+		},
+		"method not allowed": {
+			Method:         http.MethodDelete,
+			ExpectedStatus: http.StatusMethodNotAllowed,
+			ExpectedBody:   ``,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			revoker := &fakeRevoker{}
+			router := mux.NewRouter()
+			AddRevocationHandler(router, revoker, func(h http.Handler) http.Handler { return h })
+
+			req := httptest.NewRequest(tc.Method, "/admin/revocations"+tc.Query, strings.NewReader(tc.Body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if actual := strings.TrimSpace(w.Body.String()); actual != tc.ExpectedBody {
+				t.Errorf("Expected body %s, got %s", tc.ExpectedBody, actual)
+			}
+
+			if revoker.Filter.Service != tc.ExpectedService {
+				t.Errorf("Expected service filter %q, got %q", tc.ExpectedService, revoker.Filter.Service)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/supportbundle"
+)
+
+// AddSupportBundleHandler adds an endpoint at /admin/support-bundle that
+// downloads a redacted support bundle as a gzipped tarball.
+//
+// The wrap function is used to add authentication to the handler.
+func AddSupportBundleHandler(router *mux.Router, registry broker.BrokerRegistry, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/support-bundle", wrap(NewSupportBundleHandler(registry))).Methods(http.MethodGet)
+}
+
+// NewSupportBundleHandler creates a handler that serves support bundles.
+func NewSupportBundleHandler(registry broker.BrokerRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		bundle, err := supportbundle.Collect(req.Context(), registry)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// buffer the tarball so errors can still be reported with a status code
+		buf := &bytes.Buffer{}
+		if err := bundle.WriteTarball(buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		filename := fmt.Sprintf("support-bundle-%s.tar.gz", bundle.GeneratedAt.Format("20060102T150405Z"))
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.WriteHeader(http.StatusOK)
+		buf.WriteTo(w)
+	}
+}
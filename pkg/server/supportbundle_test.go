@@ -0,0 +1,51 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+func TestNewSupportBundleHandler(t *testing.T) {
+	registry := broker.BrokerRegistry{
+		"svc-a": &broker.ServiceDefinition{Id: "a-id", Name: "svc-a"},
+	}
+
+	router := mux.NewRouter()
+	AddSupportBundleHandler(router, registry, func(h http.Handler) http.Handler { return h })
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/support-bundle", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if disposition := w.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, `attachment; filename="support-bundle-`) {
+		t.Errorf("Expected an attachment, got %q", disposition)
+	}
+
+	if _, err := gzip.NewReader(w.Body); err != nil {
+		t.Errorf("Expected a gzipped body, got %v", err)
+	}
+}
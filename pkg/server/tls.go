@@ -0,0 +1,115 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// TlsCertProp is the viper key of the PEM encoded certificate chain the
+	// broker serves. TLS is disabled if it's empty.
+	TlsCertProp = "api.tls.cert"
+
+	// TlsKeyProp is the viper key of the PEM encoded private key of the
+	// certificate.
+	TlsKeyProp = "api.tls.key"
+
+	// TlsClientCaProp is the viper key of the PEM encoded CA certificates
+	// used to verify client certificates.
+	TlsClientCaProp = "api.tls.client_ca"
+
+	// TlsClientAuthProp is the viper key of whether clients must present a
+	// certificate signed by the client CA, either require or optional.
+	TlsClientAuthProp = "api.tls.client_auth"
+
+	// TlsMinVersionProp is the viper key of the oldest TLS version clients
+	// may use.
+	TlsMinVersionProp = "api.tls.min_version"
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: TlsClientAuthProp, Kind: config.String, Default: "require", Allowed: []string{"require", "optional"}},
+		config.Property{Key: TlsMinVersionProp, Kind: config.String, Default: "1.2", Allowed: []string{"1.0", "1.1", "1.2", "1.3"}},
+	)
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewTlsConfigFromEnv creates the TLS configuration of the broker's listener
+// from viper. It returns nil if no certificate is configured so the broker
+// serves plain HTTP, e.g. behind a TLS terminating proxy.
+//
+// If client CA certificates are configured, clients are asked for a
+// certificate signed by one of them.
+func NewTlsConfigFromEnv() (*tls.Config, error) {
+	certPem, keyPem := viper.GetString(TlsCertProp), viper.GetString(TlsKeyProp)
+	clientCaPem := viper.GetString(TlsClientCaProp)
+
+	if certPem == "" && keyPem == "" {
+		if clientCaPem != "" {
+			return nil, fmt.Errorf("%s requires %s and %s to be set", TlsClientCaProp, TlsCertProp, TlsKeyProp)
+		}
+
+		return nil, nil
+	}
+
+	cert, err := tls.X509KeyPair([]byte(certPem), []byte(keyPem))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s and %s: %v", TlsCertProp, TlsKeyProp, err)
+	}
+
+	minVersion, ok := tlsVersions[viper.GetString(TlsMinVersionProp)]
+	if !ok {
+		return nil, fmt.Errorf("%s must be one of 1.0, 1.1, 1.2 or 1.3, got %q", TlsMinVersionProp, viper.GetString(TlsMinVersionProp))
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   minVersion,
+	}
+
+	if clientCaPem == "" {
+		return config, nil
+	}
+
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM([]byte(clientCaPem)) {
+		return nil, errors.New(TlsClientCaProp + " doesn't contain any PEM encoded certificates")
+	}
+
+	switch clientAuth := viper.GetString(TlsClientAuthProp); clientAuth {
+	case "require":
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("%s must be require or optional, got %q", TlsClientAuthProp, clientAuth)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,255 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// testCert is a PEM encoded certificate and key.
+type testCert struct {
+	Cert    *x509.Certificate
+	Key     *ecdsa.PrivateKey
+	CertPem string
+	KeyPem  string
+}
+
+// newTestCert creates a certificate signed by the parent, or a self signed CA
+// if parent is nil.
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signerCert, signerKey = parent.Cert, parent.Key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{
+		Cert:    cert,
+		Key:     key,
+		CertPem: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		KeyPem:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})),
+	}
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair([]byte(c.CertPem), []byte(c.KeyPem))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func setTlsProps(props map[string]interface{}) func() {
+	for k, v := range props {
+		viper.Set(k, v)
+	}
+
+	return func() {
+		for k := range props {
+			viper.Set(k, nil)
+		}
+	}
+}
+
+func TestNewTlsConfigFromEnv(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "broker", ca)
+
+	cases := map[string]struct {
+		Props              map[string]interface{}
+		ExpectNil          bool
+		ExpectedClientAuth tls.ClientAuthType
+		ExpectedMinVersion uint16
+		ExpectedErr        string
+	}{
+		"disabled": {
+			Props:     map[string]interface{}{},
+			ExpectNil: true,
+		},
+		"server only": {
+			Props:              map[string]interface{}{TlsCertProp: server.CertPem, TlsKeyProp: server.KeyPem},
+			ExpectedClientAuth: tls.NoClientCert,
+			ExpectedMinVersion: tls.VersionTLS12,
+		},
+		"mutual": {
+			Props:              map[string]interface{}{TlsCertProp: server.CertPem, TlsKeyProp: server.KeyPem, TlsClientCaProp: ca.CertPem, TlsMinVersionProp: "1.3"},
+			ExpectedClientAuth: tls.RequireAndVerifyClientCert,
+			ExpectedMinVersion: tls.VersionTLS13,
+		},
+		"optional client certificates": {
+			Props:              map[string]interface{}{TlsCertProp: server.CertPem, TlsKeyProp: server.KeyPem, TlsClientCaProp: ca.CertPem, TlsClientAuthProp: "optional"},
+			ExpectedClientAuth: tls.VerifyClientCertIfGiven,
+			ExpectedMinVersion: tls.VersionTLS12,
+		},
+		"client CA without certificate": {
+			Props:       map[string]interface{}{TlsClientCaProp: ca.CertPem},
+			ExpectedErr: "requires api.tls.cert and api.tls.key",
+		},
+		"mismatched key": {
+			Props:       map[string]interface{}{TlsCertProp: server.CertPem, TlsKeyProp: ca.KeyPem},
+			ExpectedErr: "couldn't parse api.tls.cert and api.tls.key",
+		},
+		"bad client CA": {
+			Props:       map[string]interface{}{TlsCertProp: server.CertPem, TlsKeyProp: server.KeyPem, TlsClientCaProp: "not pem"},
+			ExpectedErr: "doesn't contain any PEM encoded certificates",
+		},
+		"bad client auth": {
+			Props:       map[string]interface{}{TlsCertProp: server.CertPem, TlsKeyProp: server.KeyPem, TlsClientCaProp: ca.CertPem, TlsClientAuthProp: "sometimes"},
+			ExpectedErr: "must be require or optional",
+		},
+		"bad min version": {
+			Props:       map[string]interface{}{TlsCertProp: server.CertPem, TlsKeyProp: server.KeyPem, TlsMinVersionProp: "TLSv1.2"},
+			ExpectedErr: "api.tls.min_version must be one of",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			defer setTlsProps(tc.Props)()
+
+			config, err := NewTlsConfigFromEnv()
+			if tc.ExpectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ExpectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tc.ExpectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.ExpectNil {
+				if config != nil {
+					t.Errorf("Expected no TLS config, got %v", config)
+				}
+				return
+			}
+
+			if config.ClientAuth != tc.ExpectedClientAuth {
+				t.Errorf("Expected client auth %v, got %v", tc.ExpectedClientAuth, config.ClientAuth)
+			}
+
+			if config.MinVersion != tc.ExpectedMinVersion {
+				t.Errorf("Expected min version %x, got %x", tc.ExpectedMinVersion, config.MinVersion)
+			}
+		})
+	}
+}
+
+func TestNewTlsConfigFromEnv_mutualTls(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "broker", ca)
+	client := newTestCert(t, "platform", ca)
+	untrusted := newTestCert(t, "other", newTestCert(t, "other-ca", nil))
+
+	defer setTlsProps(map[string]interface{}{TlsCertProp: server.CertPem, TlsKeyProp: server.KeyPem, TlsClientCaProp: ca.CertPem})()
+
+	config, err := NewTlsConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = config
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+
+	cases := map[string]struct {
+		Certificates []tls.Certificate
+		ExpectErr    bool
+	}{
+		"trusted client":   {Certificates: []tls.Certificate{client.tlsCertificate(t)}},
+		"no certificate":   {ExpectErr: true},
+		"untrusted client": {Certificates: []tls.Certificate{untrusted.tlsCertificate(t)}, ExpectErr: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			httpClient := &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: tc.Certificates},
+			}}
+
+			resp, err := httpClient.Get(ts.URL)
+			if tc.ExpectErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Error("Expected the handshake to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
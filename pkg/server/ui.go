@@ -0,0 +1,245 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// uiPage is a single page app over the admin API. It only uses endpoints the
+// operator can already call, so it needs no state of its own.
+const uiPage = `<!DOCTYPE html>
+<html lang="en">
+	<head>
+		<meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+		<title>Cloud Service Broker</title>
+		<meta charset="utf-8" />
+		<link rel="stylesheet" href="https://maxcdn.bootstrapcdn.com/bootstrap/4.0.0/css/bootstrap.min.css" crossorigin="anonymous" />
+	</head>
+	<body>
+		<nav class="navbar navbar-expand navbar-dark sticky-top" style="background-color:#626975;">
+			<a class="navbar-brand" href="#instances">Cloud Service Broker</a>
+			<ul class="navbar-nav">
+				<li class="nav-item"><a class="nav-link" href="#instances">Instances</a></li>
+				<li class="nav-item"><a class="nav-link" href="#operations">Operations</a></li>
+				<li class="nav-item"><a class="nav-link" href="#upgrades">Upgrades</a></li>
+				<li class="nav-item"><a class="nav-link" href="#catalog">Catalog</a></li>
+				<li class="nav-item"><a class="nav-link" href="/docs">Docs</a></li>
+			</ul>
+		</nav>
+		<div class="container" id="maincontent">
+			<br />
+			<div class="alert alert-danger d-none" id="error"></div>
+			<h2 id="title"></h2>
+			<form class="form-inline mb-3" id="filters">
+				<input class="form-control mr-2 mb-2" name="service" placeholder="Service" />
+				<input class="form-control mr-2 mb-2" name="plan" placeholder="Plan" />
+				<input class="form-control mr-2 mb-2" name="organization_guid" placeholder="Organization GUID" />
+				<input class="form-control mr-2 mb-2" name="space_guid" placeholder="Space GUID" />
+				<select class="form-control mr-2 mb-2" name="state">
+					<option value="">Any state</option>
+					<option>in progress</option>
+					<option>succeeded</option>
+					<option>failed</option>
+				</select>
+				<button class="btn btn-secondary mb-2" type="submit">Filter</button>
+			</form>
+			<div id="content"></div>
+		</div>
+		<script>
+(function() {
+	var views = {
+		instances: {
+			title: "Instances",
+			filters: true,
+			url: "/admin/instances",
+			columns: ["instance_id", "service_name", "plan_name", "organization_guid", "space_guid", "state", "operation_type", "maintenance_version", "updated_at"],
+			link: function(row) { return "#timeline/" + row.instance_id; }
+		},
+		operations: {
+			title: "Recent Operations",
+			filters: true,
+			url: "/admin/operations",
+			columns: ["updated_at", "instance_id", "binding_id", "service_name", "type", "state", "message"],
+			link: function(row) { return "#timeline/" + row.instance_id; }
+		},
+		upgrades: {
+			title: "Instances Behind Their Service Version",
+			url: "/admin/upgrades",
+			columns: ["instance_id", "service_name", "plan_id", "policy", "current_version", "available_version", "state", "approved_at"],
+			link: function(row) { return "#timeline/" + row.instance_id; }
+		}
+	};
+
+	function el(tag, text, className) {
+		var e = document.createElement(tag);
+		if (text !== undefined && text !== null) {
+			e.textContent = String(text);
+		}
+		if (className) {
+			e.className = className;
+		}
+		return e;
+	}
+
+	function showError(message) {
+		var e = document.getElementById("error");
+		e.textContent = message;
+		e.classList.toggle("d-none", !message);
+	}
+
+	function get(url) {
+		return fetch(url, {credentials: "same-origin", headers: {"Accept": "application/json"}}).then(function(resp) {
+			if (!resp.ok) {
+				return resp.text().then(function(text) { throw new Error(url + ": " + resp.status + " " + text); });
+			}
+			return resp.json();
+		});
+	}
+
+	function table(rows, columns, link) {
+		if (!rows || rows.length === 0) {
+			return el("p", "Nothing to show.", "text-muted");
+		}
+
+		var t = el("table", null, "table table-sm table-striped");
+		var head = el("tr");
+		columns.forEach(function(c) { head.appendChild(el("th", c.replace(/_/g, " "))); });
+		t.appendChild(el("thead")).appendChild(head);
+
+		var body = t.appendChild(el("tbody"));
+		rows.forEach(function(row) {
+			var tr = body.appendChild(el("tr"));
+			columns.forEach(function(c, i) {
+				var td = tr.appendChild(el("td"));
+				if (i === 0 && link) {
+					var a = td.appendChild(el("a", row[c]));
+					a.href = link(row);
+				} else {
+					td.textContent = row[c] === undefined || row[c] === null ? "" : String(row[c]);
+				}
+			});
+		});
+		return t;
+	}
+
+	function query() {
+		var params = new URLSearchParams();
+		new FormData(document.getElementById("filters")).forEach(function(value, key) {
+			if (value) {
+				params.set(key, value);
+			}
+		});
+		return params.toString();
+	}
+
+	function render(title, filters, load) {
+		var content = document.getElementById("content");
+		document.getElementById("title").textContent = title;
+		document.getElementById("filters").classList.toggle("d-none", !filters);
+		showError("");
+		content.textContent = "Loading...";
+		load().then(function(node) {
+			content.textContent = "";
+			content.appendChild(node);
+		}).catch(function(err) {
+			content.textContent = "";
+			showError(err.message);
+		});
+	}
+
+	function timeline(instanceId) {
+		render("Operations on " + instanceId, false, function() {
+			return get("/admin/operations?limit=500").then(function(ops) {
+				var mine = ops.filter(function(op) { return op.instance_id === instanceId; });
+				return table(mine, ["updated_at", "binding_id", "type", "state", "message"]);
+			});
+		});
+	}
+
+	function catalog() {
+		render("Catalog", false, function() {
+			return get("/admin/catalog").then(function(services) {
+				var div = el("div");
+				services.forEach(function(svc) {
+					div.appendChild(el("h4", svc.name + " (" + svc.id + ")"));
+					div.appendChild(el("p", svc.description));
+					div.appendChild(table(svc.plans || [], ["name", "id", "description", "free"]));
+				});
+				return div;
+			});
+		});
+	}
+
+	function route() {
+		var hash = window.location.hash.replace(/^#/, "") || "instances";
+		if (hash.indexOf("timeline/") === 0) {
+			return timeline(decodeURIComponent(hash.substring("timeline/".length)));
+		}
+		if (hash === "catalog") {
+			return catalog();
+		}
+
+		var view = views[hash] || views.instances;
+		render(view.title, view.filters, function() {
+			var q = view.filters ? query() : "";
+			return get(view.url + (q ? "?" + q : "")).then(function(rows) {
+				return table(rows, view.columns, view.link);
+			});
+		});
+	}
+
+	document.getElementById("filters").addEventListener("submit", function(e) {
+		e.preventDefault();
+		route();
+	});
+	window.addEventListener("hashchange", route);
+	route();
+})();
+		</script>
+	</body>
+</html>
+`
+
+// AddUiHandler adds a single page admin UI at /admin/ui that shows instances,
+// operation timelines, instances behind their service's version and the
+// catalog using the admin API, and the /admin/catalog endpoint it previews
+// the catalog with. The catalog function returns the catalog platforms see.
+//
+// The wrap function is used to add authentication to the handlers.
+func AddUiHandler(router *mux.Router, catalog func(context.Context) ([]brokerapi.Service, error), wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/ui", wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(uiPage))
+	}))).Methods(http.MethodGet)
+
+	router.Handle("/admin/catalog", wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		services, err := catalog(req.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(services)
+	}))).Methods(http.MethodGet)
+}
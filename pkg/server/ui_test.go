@@ -0,0 +1,83 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf/brokerapi"
+)
+
+func TestAddUiHandler(t *testing.T) {
+	cases := map[string]struct {
+		Path                string
+		Catalog             func(context.Context) ([]brokerapi.Service, error)
+		ExpectedStatus      int
+		ExpectedContentType string
+		ExpectedBody        string
+	}{
+		"page": {
+			Path:                "/admin/ui",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "text/html",
+			ExpectedBody:        `get("/admin/catalog")`,
+		},
+		"catalog": {
+			Path: "/admin/catalog",
+			Catalog: func(ctx context.Context) ([]brokerapi.Service, error) {
+				return []brokerapi.Service{{ID: "svc-1", Name: "db", Plans: []brokerapi.ServicePlan{{ID: "plan-1", Name: "small"}}}}, nil
+			},
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "application/json",
+			ExpectedBody:        `"name":"small"`,
+		},
+		"catalog error": {
+			Path: "/admin/catalog",
+			Catalog: func(ctx context.Context) ([]brokerapi.Service, error) {
+				return nil, errors.New("brokerpak failed to load")
+			},
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedBody:   "brokerpak failed to load",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddUiHandler(router, tc.Catalog, func(h http.Handler) http.Handler { return h })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.Path, nil))
+
+			if w.Code != tc.ExpectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tc.ExpectedStatus, w.Code, w.Body.String())
+			}
+
+			if tc.ExpectedContentType != "" && w.Header().Get("Content-Type") != tc.ExpectedContentType {
+				t.Errorf("Expected %s content, got %q", tc.ExpectedContentType, w.Header().Get("Content-Type"))
+			}
+
+			if !strings.Contains(w.Body.String(), tc.ExpectedBody) {
+				t.Errorf("Expected body to contain %q, got %s", tc.ExpectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/upgrade"
+)
+
+// AddUpgradeHandler adds an endpoint at /admin/upgrades. GET lists the
+// instances that are behind their service's maintenance_info version; POSTing
+// a JSON upgrade.Approval approves upgrading the matching instances of
+// services with the manual upgrade policy and responds with a report. POSTing
+// with ?dry_run=true reports the instances that would be approved without
+// approving them.
+//
+// The wrap function is used to add authentication to the handler.
+func AddUpgradeHandler(router *mux.Router, manager upgrade.Manager, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/upgrades", wrap(NewUpgradeHandler(manager))).Methods(http.MethodGet, http.MethodPost)
+}
+
+// NewUpgradeHandler creates a handler that lists and approves instance
+// upgrades.
+func NewUpgradeHandler(manager upgrade.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var resp interface{}
+		var err error
+
+		if req.Method == http.MethodGet {
+			resp, err = manager.ListUpgrades(req.Context())
+		} else {
+			approval := upgrade.Approval{}
+			if err := json.NewDecoder(req.Body).Decode(&approval); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			ctx, dryRunErr := dryRunContext(req)
+			if dryRunErr != nil {
+				http.Error(w, dryRunErr.Error(), http.StatusBadRequest)
+				return
+			}
+
+			resp, err = manager.ApproveUpgrades(ctx, approval)
+		}
+
+		switch {
+		case err == upgrade.ErrEmptyApproval:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+	"github.com/pivotal/cloud-service-broker/pkg/upgrade"
+)
+
+type fakeUpgradeManager struct {
+	Approval upgrade.Approval
+}
+
+func (f *fakeUpgradeManager) ListUpgrades(ctx context.Context) ([]upgrade.Instance, error) {
+	return []upgrade.Instance{{InstanceId: "instance-1", ServiceId: "svc-1", ServiceName: "db", PlanId: "plan-1", Policy: upgrade.PolicyManual, CurrentVersion: "1", AvailableVersion: "2", State: upgrade.StatePending}}, nil
+}
+
+func (f *fakeUpgradeManager) ApproveUpgrades(ctx context.Context, approval upgrade.Approval) (*upgrade.Report, error) {
+	if approval.IsEmpty() {
+		return nil, upgrade.ErrEmptyApproval
+	}
+
+	f.Approval = approval
+	if planning.IsDryRun(ctx) {
+		return &upgrade.Report{
+			DryRun:   true,
+			Planned:  []upgrade.Instance{{InstanceId: "instance-1", ServiceId: "svc-1", ServiceName: "db", PlanId: "plan-1", Policy: upgrade.PolicyManual, CurrentVersion: "1", AvailableVersion: "2", State: upgrade.StatePending}},
+			Approved: []upgrade.Instance{},
+			Skipped:  []upgrade.Instance{},
+		}, nil
+	}
+
+	return &upgrade.Report{
+		Approved: []upgrade.Instance{{InstanceId: "instance-1", ServiceId: "svc-1", ServiceName: "db", PlanId: "plan-1", Policy: upgrade.PolicyManual, CurrentVersion: "1", AvailableVersion: "2", State: upgrade.StateApproved}},
+		Skipped:  []upgrade.Instance{},
+	}, nil
+}
+
+func TestNewUpgradeHandler(t *testing.T) {
+	cases := map[string]struct {
+		Method          string
+		Query           string
+		Body            string
+		ExpectedStatus  int
+		ExpectedBody    string
+		ExpectedService string
+	}{
+		"list": {
+			Method:         http.MethodGet,
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `[{"instance_id":"instance-1","service_id":"svc-1","service_name":"db","plan_id":"plan-1","policy":"manual","current_version":"1","available_version":"2","state":"pending"}]`,
+		},
+		"approve": {
+			Method:          http.MethodPost,
+			Body:            `{"service":"db"}`,
+			ExpectedStatus:  http.StatusOK,
+			ExpectedBody:    `{"approved":[{"instance_id":"instance-1","service_id":"svc-1","service_name":"db","plan_id":"plan-1","policy":"manual","current_version":"1","available_version":"2","state":"approved"}],"skipped":[]}`,
+			ExpectedService: "db",
+		},
+		"dry run": {
+			Method:          http.MethodPost,
+			Query:           "?dry_run=1",
+			Body:            `{"service":"db"}`,
+			ExpectedStatus:  http.StatusOK,
+			ExpectedBody:    `{"dry_run":true,"planned":[{"instance_id":"instance-1","service_id":"svc-1","service_name":"db","plan_id":"plan-1","policy":"manual","current_version":"1","available_version":"2","state":"pending"}],"approved":[],"skipped":[]}`,
+			ExpectedService: "db",
+		},
+		"empty approval": {
+			Method:         http.MethodPost,
+			Body:           `{}`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   upgrade.ErrEmptyApproval.Error(),
+		},
+		"bad json": {
+			Method:         http.MethodPost,
+			Body:           `{`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `unexpected EOF`,
+		},
+		"method not allowed": {
+			Method:         http.MethodDelete,
+			ExpectedStatus: http.StatusMethodNotAllowed,
+			ExpectedBody:   ``,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			manager := &fakeUpgradeManager{}
+			router := mux.NewRouter()
+			AddUpgradeHandler(router, manager, func(h http.Handler) http.Handler { return h })
+
+			req := httptest.NewRequest(tc.Method, "/admin/upgrades"+tc.Query, strings.NewReader(tc.Body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if actual := strings.TrimSpace(w.Body.String()); actual != tc.ExpectedBody {
+				t.Errorf("Expected body %s, got %s", tc.ExpectedBody, actual)
+			}
+
+			if manager.Approval.Service != tc.ExpectedService {
+				t.Errorf("Expected approved service %q, got %q", tc.ExpectedService, manager.Approval.Service)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/usage"
+)
+
+// AddUsageHandler adds an endpoint at /admin/usage that reports instance
+// counts and lifetimes for chargeback and capacity planning. The from, to
+// and group_by query parameters select the period and grouping, see
+// usage.ParseOptions, and format is either json, the default, or csv.
+//
+// The wrap function is used to add authentication to the handler.
+func AddUsageHandler(router *mux.Router, registry broker.BrokerRegistry, wrap func(http.Handler) http.Handler) {
+	router.Handle("/admin/usage", wrap(NewUsageHandler(registry))).Methods(http.MethodGet)
+}
+
+// NewUsageHandler creates a handler that serves usage reports.
+func NewUsageHandler(registry broker.BrokerRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		opts, err := usage.ParseOptions(query.Get("from"), query.Get("to"), query.Get("group_by"), time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		format := query.Get("format")
+		if format != "" && format != "json" && format != "csv" {
+			http.Error(w, fmt.Sprintf("invalid format %q, must be json or csv", format), http.StatusBadRequest)
+			return
+		}
+
+		report, err := usage.Generate(req.Context(), registry, opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if format == "csv" {
+			filename := fmt.Sprintf("usage-%s-%s.csv", opts.From.Format("20060102T150405Z"), opts.To.Format("20060102T150405Z"))
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+			w.WriteHeader(http.StatusOK)
+			usage.WriteCsv(w, report)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/usage"
+)
+
+func TestNewUsageHandler(t *testing.T) {
+	db, err := db_service.OpenSqlite(filepath.Join(t.TempDir(), "usage.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db_service.RunMigrations(db)
+
+	previous := db_service.DbConnection
+	db_service.DbConnection = db
+	defer func() { db_service.DbConnection = previous }()
+
+	ctx := context.Background()
+	for _, id := range []string{"active", "deleted"} {
+		instance := models.ServiceInstanceDetails{ID: id, ServiceId: "svc-id", PlanId: "plan-id", OrganizationGuid: "org"}
+		if err := db_service.CreateServiceInstanceDetails(ctx, &instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db_service.DeleteServiceInstanceDetailsById(ctx, "deleted"); err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	AddUsageHandler(router, broker.BrokerRegistry{}, func(h http.Handler) http.Handler { return h })
+
+	cases := map[string]struct {
+		Query        string
+		ExpectedCode int
+		Check        func(t *testing.T, w *httptest.ResponseRecorder)
+	}{
+		"json": {
+			Query:        "?group_by=organization",
+			ExpectedCode: http.StatusOK,
+			Check: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var report usage.Report
+				if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+					t.Fatal(err)
+				}
+
+				if len(report.Rows) != 1 || report.Rows[0].Instances != 2 || report.Rows[0].Deleted != 1 || report.Rows[0].Active != 1 {
+					t.Errorf("Expected one row with an active and a deleted instance, got %+v", report.Rows)
+				}
+			},
+		},
+		"csv": {
+			Query:        "?group_by=organization&format=csv",
+			ExpectedCode: http.StatusOK,
+			Check: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if !strings.HasPrefix(w.Body.String(), "organization_guid,instances,") || !strings.Contains(w.Body.String(), "\norg,2,2,1,1,") {
+					t.Errorf("Unexpected CSV:\n%s", w.Body.String())
+				}
+
+				if disposition := w.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, `attachment; filename="usage-`) {
+					t.Errorf("Expected an attachment, got %q", disposition)
+				}
+			},
+		},
+		"bad period": {Query: "?from=2026-10-01&to=2026-09-01", ExpectedCode: http.StatusBadRequest},
+		"bad format": {Query: "?format=xml", ExpectedCode: http.StatusBadRequest},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/usage"+tc.Query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tc.ExpectedCode, w.Code, w.Body.String())
+			}
+
+			if tc.Check != nil {
+				tc.Check(t, w)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceaccounts
+
+import (
+	"context"
+	"fmt"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/planning"
+)
+
+// Database lists the bindings that recorded service accounts.
+type Database interface {
+	ListServiceBindingCredentials(ctx context.Context, conditions models.ServiceBindingCredentials) ([]models.ServiceBindingCredentials, error)
+	ListDeletedServiceBindingCredentialsWithServiceAccounts(ctx context.Context) ([]models.ServiceBindingCredentials, error)
+}
+
+// databaseStore uses the broker's database.
+type databaseStore struct{}
+
+func (databaseStore) ListServiceBindingCredentials(ctx context.Context, conditions models.ServiceBindingCredentials) ([]models.ServiceBindingCredentials, error) {
+	return db_service.ListServiceBindingCredentials(ctx, conditions)
+}
+
+func (databaseStore) ListDeletedServiceBindingCredentialsWithServiceAccounts(ctx context.Context) ([]models.ServiceBindingCredentials, error) {
+	return db_service.ListDeletedServiceBindingCredentialsWithServiceAccounts(ctx)
+}
+
+// Cleaner deletes the service accounts of unbound bindings that are left
+// behind, e.g. because unbinding failed part way or predates the broker
+// recording service accounts. Accounts a bound binding still uses are kept.
+type Cleaner struct {
+	Manager  Manager
+	Database Database
+	Logger   lager.Logger
+}
+
+// NewCleanerFromEnv creates a Cleaner using the broker's database and Google
+// Cloud credentials.
+func NewCleanerFromEnv(logger lager.Logger) (*Cleaner, error) {
+	manager, err := NewManagerFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if manager == nil {
+		return nil, fmt.Errorf("no Google Cloud credentials are configured")
+	}
+
+	return &Cleaner{
+		Manager:  manager,
+		Database: databaseStore{},
+		Logger:   logger.Session("service-account-cleanup"),
+	}, nil
+}
+
+// Run deletes the orphaned service accounts, or only lists them if dryRun is
+// set. Deleting continues past failures, the first is returned.
+func (c *Cleaner) Run(ctx context.Context, dryRun bool) (*planning.Result, error) {
+	changes, err := c.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		ctx = planning.WithDryRun(ctx)
+	}
+
+	result := changes.Execute(ctx)
+	for _, outcome := range result.Outcomes {
+		c.Logger.Info("orphaned-service-account", lager.Data{"service_account": outcome.Target, "details": outcome.Details, "deleted": outcome.Applied, "error": outcome.Error})
+	}
+
+	return result, result.Err()
+}
+
+// Plan creates a change deleting each service account that was recorded by
+// an unbound binding, isn't used by a bound one, and still exists.
+func (c *Cleaner) Plan(ctx context.Context) (*planning.Plan, error) {
+	bound, err := c.Database.ListServiceBindingCredentials(ctx, models.ServiceBindingCredentials{})
+	if err != nil {
+		return nil, fmt.Errorf("listing bindings: %v", err)
+	}
+
+	unbound, err := c.Database.ListDeletedServiceBindingCredentialsWithServiceAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing unbound bindings: %v", err)
+	}
+
+	skip := make(map[string]bool)
+	for _, binding := range bound {
+		if binding.ServiceAccountEmail != "" {
+			skip[binding.ServiceAccountEmail] = true
+		}
+	}
+
+	changes := &planning.Plan{}
+	for _, binding := range unbound {
+		email, keyId := binding.ServiceAccountEmail, binding.ServiceAccountKeyId
+		if skip[email] {
+			continue
+		}
+		skip[email] = true
+
+		exists, err := c.Manager.Exists(ctx, email)
+		if err != nil {
+			return nil, fmt.Errorf("looking up service account %s: %v", email, err)
+		}
+		if !exists {
+			continue
+		}
+
+		details := map[string]interface{}{
+			"binding_id":  binding.BindingId,
+			"instance_id": binding.ServiceInstanceId,
+		}
+		changes.Add("delete-service-account", email, details, func(ctx context.Context) error {
+			return c.Manager.Delete(ctx, email, keyId)
+		})
+	}
+
+	return changes, nil
+}
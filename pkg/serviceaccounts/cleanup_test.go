@@ -0,0 +1,113 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceaccounts
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+type fakeDatabase struct {
+	Bound   []models.ServiceBindingCredentials
+	Unbound []models.ServiceBindingCredentials
+}
+
+func (f *fakeDatabase) ListServiceBindingCredentials(ctx context.Context, conditions models.ServiceBindingCredentials) ([]models.ServiceBindingCredentials, error) {
+	return f.Bound, nil
+}
+
+func (f *fakeDatabase) ListDeletedServiceBindingCredentialsWithServiceAccounts(ctx context.Context) ([]models.ServiceBindingCredentials, error) {
+	return f.Unbound, nil
+}
+
+type fakeManager struct {
+	Existing  map[string]bool
+	DeleteErr error
+	Deleted   []string
+}
+
+func (f *fakeManager) Exists(ctx context.Context, email string) (bool, error) {
+	return f.Existing[email], nil
+}
+
+func (f *fakeManager) Delete(ctx context.Context, email, keyId string) error {
+	if f.DeleteErr != nil {
+		return f.DeleteErr
+	}
+	f.Deleted = append(f.Deleted, email+"/"+keyId)
+	return nil
+}
+
+func TestCleaner_Run(t *testing.T) {
+	database := &fakeDatabase{
+		Bound: []models.ServiceBindingCredentials{
+			{BindingId: "bound", ServiceAccountEmail: "shared@p.iam.gserviceaccount.com"},
+			{BindingId: "no-account"},
+		},
+		Unbound: []models.ServiceBindingCredentials{
+			{BindingId: "orphan", ServiceAccountEmail: "orphan@p.iam.gserviceaccount.com", ServiceAccountKeyId: "key-1"},
+			{BindingId: "orphan-again", ServiceAccountEmail: "orphan@p.iam.gserviceaccount.com", ServiceAccountKeyId: "key-2"},
+			{BindingId: "shared", ServiceAccountEmail: "shared@p.iam.gserviceaccount.com"},
+			{BindingId: "gone", ServiceAccountEmail: "gone@p.iam.gserviceaccount.com"},
+		},
+	}
+	existing := map[string]bool{
+		"orphan@p.iam.gserviceaccount.com": true,
+		"shared@p.iam.gserviceaccount.com": true,
+	}
+
+	cases := map[string]struct {
+		DryRun          bool
+		DeleteErr       error
+		ExpectedDeleted []string
+		ExpectErr       bool
+	}{
+		"deletes": {
+			ExpectedDeleted: []string{"orphan@p.iam.gserviceaccount.com/key-1"},
+		},
+		"dry run": {
+			DryRun: true,
+		},
+		"delete fails": {
+			DeleteErr: errors.New("permission denied"),
+			ExpectErr: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			manager := &fakeManager{Existing: existing, DeleteErr: tc.DeleteErr}
+			cleaner := &Cleaner{Manager: manager, Database: database, Logger: lager.NewLogger("test")}
+
+			result, err := cleaner.Run(context.Background(), tc.DryRun)
+			if (err != nil) != tc.ExpectErr {
+				t.Fatalf("Expected error? %t got %v", tc.ExpectErr, err)
+			}
+
+			if len(result.Outcomes) != 1 || result.Outcomes[0].Target != "orphan@p.iam.gserviceaccount.com" {
+				t.Errorf("Expected only the orphaned account to be planned, got %+v", result.Outcomes)
+			}
+
+			if !reflect.DeepEqual(manager.Deleted, tc.ExpectedDeleted) {
+				t.Errorf("Expected deleted %v, got %v", tc.ExpectedDeleted, manager.Deleted)
+			}
+		})
+	}
+}
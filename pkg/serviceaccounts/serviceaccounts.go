@@ -0,0 +1,204 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package serviceaccounts tracks the Google Cloud service accounts bindings
+// create so they can be deleted with the binding, even if the service
+// provider fails to delete them.
+package serviceaccounts
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/pkg/gcpclient"
+	"github.com/pivotal/cloud-service-broker/pkg/retry"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"google.golang.org/api/googleapi"
+	iam "google.golang.org/api/iam/v1"
+)
+
+// deleteRetryPolicy controls retries of deleting the service account and key
+// of a binding.
+var deleteRetryPolicy = retry.Policies.Policy("service-account-delete", "Deleting the service account and key of a binding.", retry.Defaults{
+	MaxAttempts:    5,
+	InitialBackoff: 2 * time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Retryable:      []retry.ErrorClass{retry.Conflict, retry.RateLimit, retry.ServerError, retry.Network},
+})
+
+// encodedKeyOutputs are the binding outputs holding a base64 encoded service
+// account key file, credentialsOutput holds the decoded one.
+var encodedKeyOutputs = []string{"PrivateKeyData", "private_key"}
+
+const credentialsOutput = "Credentials"
+
+// keyFile holds the fields of a service account key file identifying the
+// account and key.
+type keyFile struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyId string `json:"private_key_id"`
+}
+
+// FromCredentials gets the email of the service account and the ID of the key
+// a binding created from its credentials. Both are empty if the credentials
+// don't hold a key, e.g. because the binding granted roles to a service
+// account the user supplied, which the broker must not delete.
+func FromCredentials(creds map[string]interface{}) (email, keyId string) {
+	_, key := parseKeyFile(creds)
+	return key.ClientEmail, key.PrivateKeyId
+}
+
+// KeyFile gets the service account key file a binding created from its
+// credentials, e.g. to authorize requests with it. It's nil if they don't
+// hold one.
+func KeyFile(creds map[string]interface{}) []byte {
+	raw, _ := parseKeyFile(creds)
+	return raw
+}
+
+func parseKeyFile(creds map[string]interface{}) ([]byte, keyFile) {
+	var candidates [][]byte
+	for _, name := range encodedKeyOutputs {
+		if encoded, ok := creds[name].(string); ok && encoded != "" {
+			if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+				candidates = append(candidates, decoded)
+			}
+		}
+	}
+
+	if decoded, ok := creds[credentialsOutput].(string); ok && decoded != "" {
+		candidates = append(candidates, []byte(decoded))
+	}
+
+	for _, candidate := range candidates {
+		var key keyFile
+		if err := json.Unmarshal(candidate, &key); err == nil && key.ClientEmail != "" {
+			return candidate, key
+		}
+	}
+
+	return nil, keyFile{}
+}
+
+// Manager looks up and deletes service accounts.
+type Manager interface {
+	// Exists returns true if the service account with the given email exists.
+	Exists(ctx context.Context, email string) (bool, error)
+
+	// Delete deletes the key with the given ID, if it's set, and then the
+	// service account. Keys and accounts that no longer exist are skipped.
+	Delete(ctx context.Context, email, keyId string) error
+}
+
+// NewManagerFromEnv creates a Manager using the broker's Google Cloud
+// credentials. It returns nil if none are configured.
+func NewManagerFromEnv() (Manager, error) {
+	if !utils.GcpCredentialsConfigured() {
+		return nil, nil
+	}
+
+	clients, err := gcpclient.NewFactoryFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return &IamManager{Clients: clients}, nil
+}
+
+// IamManager manages service accounts using the IAM API.
+type IamManager struct {
+	Clients *gcpclient.Factory
+}
+
+var _ Manager = (*IamManager)(nil)
+
+func (m *IamManager) service(ctx context.Context) (*iam.Service, error) {
+	service, err := iam.NewService(ctx, m.Clients.Options(ctx, "iam")...)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating IAM service: %s", err)
+	}
+
+	return service, nil
+}
+
+// Exists implements Manager.
+func (m *IamManager) Exists(ctx context.Context, email string) (bool, error) {
+	service, err := m.service(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = service.Projects.ServiceAccounts.Get(resourceName(email)).Context(ctx).Do()
+	switch {
+	case isNotFound(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	default:
+		return true, nil
+	}
+}
+
+// Delete implements Manager.
+func (m *IamManager) Delete(ctx context.Context, email, keyId string) error {
+	service, err := m.service(ctx)
+	if err != nil {
+		return err
+	}
+
+	account := resourceName(email)
+	if keyId != "" {
+		err := deleteRetryPolicy.Do(ctx, func() error {
+			_, err := service.Projects.ServiceAccounts.Keys.Delete(account + "/keys/" + keyId).Context(ctx).Do()
+			return ignoreNotFound(err)
+		})
+		if err != nil {
+			return fmt.Errorf("deleting key %s of service account %s: %v", keyId, email, err)
+		}
+	}
+
+	err = deleteRetryPolicy.Do(ctx, func() error {
+		_, err := service.Projects.ServiceAccounts.Delete(account).Context(ctx).Do()
+		return ignoreNotFound(err)
+	})
+	if err != nil {
+		return fmt.Errorf("deleting service account %s: %v", email, err)
+	}
+
+	return nil
+}
+
+// resourceName gets the IAM resource name of a service account, the project
+// is inferred from the email.
+func resourceName(email string) string {
+	return "projects/-/serviceAccounts/" + email
+}
+
+func isNotFound(err error) bool {
+	gerr, ok := err.(*googleapi.Error)
+	return ok && gerr.Code == http.StatusNotFound
+}
+
+func ignoreNotFound(err error) error {
+	if isNotFound(err) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceaccounts
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestFromCredentials(t *testing.T) {
+	keyFile := `{"type":"service_account","private_key_id":"key-1","client_email":"pcf-binding-abc@p.iam.gserviceaccount.com"}`
+	encoded := base64.StdEncoding.EncodeToString([]byte(keyFile))
+
+	cases := map[string]struct {
+		Creds         map[string]interface{}
+		ExpectedEmail string
+		ExpectedKeyId string
+	}{
+		"builtin": {
+			Creds:         map[string]interface{}{"Email": "pcf-binding-abc@p.iam.gserviceaccount.com", "PrivateKeyData": encoded},
+			ExpectedEmail: "pcf-binding-abc@p.iam.gserviceaccount.com",
+			ExpectedKeyId: "key-1",
+		},
+		"private key": {
+			Creds:         map[string]interface{}{"email": "pcf-binding-abc@p.iam.gserviceaccount.com", "private_key": encoded},
+			ExpectedEmail: "pcf-binding-abc@p.iam.gserviceaccount.com",
+			ExpectedKeyId: "key-1",
+		},
+		"decoded credentials": {
+			Creds:         map[string]interface{}{"Credentials": keyFile},
+			ExpectedEmail: "pcf-binding-abc@p.iam.gserviceaccount.com",
+			ExpectedKeyId: "key-1",
+		},
+		"supplied account": {
+			Creds: map[string]interface{}{"email": "app@p.iam.gserviceaccount.com", "private_key": ""},
+		},
+		"not base64": {
+			Creds: map[string]interface{}{"PrivateKeyData": "not base64!"},
+		},
+		"no key": {
+			Creds: map[string]interface{}{"uri": "mysql://host/db"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			email, keyId := FromCredentials(tc.Creds)
+			if email != tc.ExpectedEmail || keyId != tc.ExpectedKeyId {
+				t.Errorf("Expected %q, %q got %q, %q", tc.ExpectedEmail, tc.ExpectedKeyId, email, keyId)
+			}
+
+			key := KeyFile(tc.Creds)
+			if hasKey := tc.ExpectedEmail != ""; hasKey != (string(key) == keyFile) {
+				t.Errorf("Expected the key file %v, got %q", hasKey, key)
+			}
+		})
+	}
+}
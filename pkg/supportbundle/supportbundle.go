@@ -0,0 +1,176 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package supportbundle collects a redacted snapshot of a broker deployment
+// that users can attach to issues.
+package supportbundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/toggles"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Redacted replaces sensitive configuration values.
+	Redacted = config.Redacted
+
+	// recentErrorLimit is the maximum number of failed operations included.
+	recentErrorLimit = 25
+)
+
+// Service describes a registered service.
+type Service struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// Toggle describes a feature flag.
+type Toggle struct {
+	Name                string `json:"name"`
+	EnvironmentVariable string `json:"environment_variable"`
+	Active              bool   `json:"active"`
+}
+
+// Database describes the broker's datastore.
+type Database struct {
+	Type  string                     `json:"type"`
+	Stats *db_service.DatastoreStats `json:"stats,omitempty"`
+	Error string                     `json:"error,omitempty"`
+}
+
+// Bundle holds the contents of a support bundle.
+type Bundle struct {
+	GeneratedAt  time.Time                    `json:"generated_at"`
+	Version      string                       `json:"version"`
+	Services     []Service                    `json:"services"`
+	Toggles      []Toggle                     `json:"toggles"`
+	Config       map[string]interface{}       `json:"config"`
+	Database     Database                     `json:"database"`
+	RecentErrors []db_service.FailedOperation `json:"recent_errors"`
+}
+
+// Collect gathers a support bundle from the broker's configuration, registry
+// and database. Database failures are recorded in the bundle rather than
+// returned so a bundle can still be produced for a broker that can't reach
+// its database.
+func Collect(ctx context.Context, registry broker.BrokerRegistry) (*Bundle, error) {
+	bundle := &Bundle{
+		GeneratedAt:  time.Now().UTC(),
+		Version:      utils.Version,
+		Services:     []Service{},
+		Toggles:      []Toggle{},
+		Config:       Redact(viper.AllSettings()),
+		Database:     Database{Type: viper.GetString("db.type")},
+		RecentErrors: []db_service.FailedOperation{},
+	}
+
+	enabled, err := registry.GetEnabledServices()
+	if err != nil {
+		return nil, err
+	}
+	enabledIds := utils.NewStringSet()
+	for _, svc := range enabled {
+		enabledIds.Add(svc.Id)
+	}
+
+	for _, svc := range registry.GetAllServices() {
+		bundle.Services = append(bundle.Services, Service{Id: svc.Id, Name: svc.Name, Enabled: enabledIds.Contains(svc.Id)})
+	}
+
+	for _, toggle := range toggles.Features.Toggles() {
+		bundle.Toggles = append(bundle.Toggles, Toggle{
+			Name:                toggle.Name,
+			EnvironmentVariable: toggle.EnvironmentVariable(),
+			Active:              toggle.IsActive(),
+		})
+	}
+
+	if db_service.DbConnection == nil {
+		bundle.Database.Error = "not connected"
+		return bundle, nil
+	}
+
+	if bundle.Database.Stats, err = db_service.GetDatastoreStats(ctx); err != nil {
+		bundle.Database.Error = err.Error()
+		return bundle, nil
+	}
+
+	if bundle.RecentErrors, err = db_service.ListRecentFailedOperations(ctx, recentErrorLimit); err != nil {
+		bundle.Database.Error = err.Error()
+	}
+
+	return bundle, nil
+}
+
+// Redact returns a copy of the settings with sensitive values replaced.
+func Redact(settings map[string]interface{}) map[string]interface{} {
+	return config.Redact(settings)
+}
+
+// WriteTarball writes the bundle to w as a gzipped tarball containing one JSON
+// file per section.
+func (b *Bundle) WriteTarball(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{name: "version.json", content: map[string]interface{}{"version": b.Version, "generated_at": b.GeneratedAt}},
+		{name: "services.json", content: b.Services},
+		{name: "toggles.json", content: b.Toggles},
+		{name: "config.json", content: b.Config},
+		{name: "database.json", content: b.Database},
+		{name: "recent_errors.json", content: b.RecentErrors},
+	}
+
+	for _, file := range files {
+		contents, err := json.MarshalIndent(file.content, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{
+			Name:    "support-bundle/" + file.name,
+			Mode:    0644,
+			Size:    int64(len(contents)),
+			ModTime: b.GeneratedAt,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(contents); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
@@ -0,0 +1,123 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+func TestRedact(t *testing.T) {
+	settings := map[string]interface{}{
+		"api": map[string]interface{}{
+			"user":     "admin",
+			"password": "hunter2",
+			"port":     8080,
+		},
+		"gcp": map[string]interface{}{
+			"credentials": `{"type":"service_account"}`,
+			"project":     "my-project",
+			"projects":    `{"other":{"credentials":"{\"private_key\":\"abc\"}"}}`,
+		},
+		"db": map[string]interface{}{
+			"name": "enc:passphrase:abc",
+		},
+		"users": []interface{}{
+			map[string]interface{}{"name": "alice", "password": "hunter2"},
+			"plain",
+		},
+	}
+
+	expected := map[string]interface{}{
+		"api": map[string]interface{}{
+			"user":     "admin",
+			"password": Redacted,
+			"port":     8080,
+		},
+		"gcp": map[string]interface{}{
+			"credentials": Redacted,
+			"project":     "my-project",
+			"projects":    Redacted,
+		},
+		"db": map[string]interface{}{
+			"name": Redacted,
+		},
+		"users": []interface{}{
+			map[string]interface{}{"name": "alice", "password": Redacted},
+			"plain",
+		},
+	}
+
+	if actual := Redact(settings); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+}
+
+func TestBundle_WriteTarball(t *testing.T) {
+	registry := broker.BrokerRegistry{
+		"svc-a": &broker.ServiceDefinition{Id: "a-id", Name: "svc-a"},
+	}
+
+	bundle, err := Collect(context.Background(), registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bundle.Database.Error == "" {
+		t.Error("Expected the missing database to be reported")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := bundle.WriteTarball(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+
+	expected := []string{
+		"support-bundle/version.json",
+		"support-bundle/services.json",
+		"support-bundle/toggles.json",
+		"support-bundle/config.json",
+		"support-bundle/database.json",
+		"support-bundle/recent_errors.json",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected files %v, got %v", expected, names)
+	}
+}
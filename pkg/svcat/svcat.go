@@ -0,0 +1,56 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package svcat adapts the broker to the Kubernetes Service Catalog, which
+// implements an older reading of the OSB API than other platforms.
+package svcat
+
+import (
+	"net/http"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/ratelimit"
+	"github.com/spf13/viper"
+)
+
+// CompatibilityProp is the viper key of whether the broker runs in
+// Kubernetes Service Catalog compatibility mode.
+const CompatibilityProp = "compatibility.kubernetes_service_catalog"
+
+func init() {
+	config.Register(config.Property{Key: CompatibilityProp, Kind: config.Boolean, Default: false})
+}
+
+// Enabled returns true if the broker runs in compatibility mode. Every plan
+// then publishes its parameter schemas and bindings are always synchronous.
+func Enabled() bool {
+	return viper.GetBool(CompatibilityProp)
+}
+
+// Wrap makes bind and unbind requests synchronous in compatibility mode by
+// removing accepts_incomplete, the Service Catalog only polls bindings behind
+// a feature gate that's off by default. It must be added to the router the
+// OSB API routes are attached to so the endpoint can be identified.
+func Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		endpoint := ratelimit.Endpoint(req)
+		if Enabled() && (endpoint == "bind" || endpoint == "unbind") {
+			query := req.URL.Query()
+			query.Del("accepts_incomplete")
+			req.URL.RawQuery = query.Encode()
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package svcat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+)
+
+func TestWrap(t *testing.T) {
+	cases := map[string]struct {
+		Enabled       bool
+		Method        string
+		Path          string
+		ExpectedQuery string
+	}{
+		"bind":                  {Method: http.MethodPut, Path: "/v2/service_instances/instance-1/service_bindings/binding-1?accepts_incomplete=true", ExpectedQuery: "accepts_incomplete=true"},
+		"bind in compatibility": {Enabled: true, Method: http.MethodPut, Path: "/v2/service_instances/instance-1/service_bindings/binding-1?accepts_incomplete=true", ExpectedQuery: ""},
+		"unbind in compatibility": {
+			Enabled:       true,
+			Method:        http.MethodDelete,
+			Path:          "/v2/service_instances/instance-1/service_bindings/binding-1?accepts_incomplete=true&plan_id=plan-1",
+			ExpectedQuery: "plan_id=plan-1",
+		},
+		"provision in compatibility": {Enabled: true, Method: http.MethodPut, Path: "/v2/service_instances/instance-1?accepts_incomplete=true", ExpectedQuery: "accepts_incomplete=true"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(CompatibilityProp, tc.Enabled)
+			defer viper.Reset()
+
+			var query string
+			handler := func(w http.ResponseWriter, req *http.Request) {
+				query = req.URL.RawQuery
+			}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/v2/service_instances/{instance_id}", handler).Methods(http.MethodPut)
+			router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", handler).Methods(http.MethodPut, http.MethodDelete)
+			router.Use(Wrap)
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.Method, tc.Path, nil))
+
+			if query != tc.ExpectedQuery {
+				t.Errorf("Expected query %q, got %q", tc.ExpectedQuery, query)
+			}
+		})
+	}
+}
@@ -25,6 +25,7 @@ package toggles
 import (
 	"sort"
 
+	"github.com/pivotal/cloud-service-broker/pkg/config"
 	"github.com/pivotal/cloud-service-broker/utils"
 	"github.com/spf13/viper"
 )
@@ -88,7 +89,7 @@ func (set *ToggleSet) Toggle(name string, value bool, description string) Toggle
 	}
 
 	set.toggles = append(set.toggles, toggle)
-	viper.SetDefault(toggle.viperProperty(), value)
+	config.Register(config.Property{Key: toggle.viperProperty(), Kind: config.Boolean, Default: value})
 
 	return toggle
 }
@@ -0,0 +1,291 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+const (
+	// maxBatchSize is the number of spans that triggers an export before the
+	// flush interval.
+	maxBatchSize = 512
+
+	// maxBufferedSpans is the number of spans held while the collector is
+	// unavailable, newer spans are dropped beyond it.
+	maxBufferedSpans = 8 * maxBatchSize
+)
+
+// OTLP span kinds and status codes.
+const (
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+	otlpKindClient   = 3
+
+	otlpStatusUnset = 0
+	otlpStatusError = 2
+)
+
+// OtlpExporter batches finished spans and sends them to an OpenTelemetry
+// collector using OTLP/HTTP with JSON encoding.
+type OtlpExporter struct {
+	Endpoint    string
+	Headers     map[string]string
+	ServiceName string
+	Client      *http.Client
+
+	mu      sync.Mutex
+	pending []*trace.SpanData
+	dropped int
+}
+
+var _ trace.Exporter = (*OtlpExporter)(nil)
+
+// ExportSpan implements trace.Exporter. It never blocks on the network.
+func (e *OtlpExporter) ExportSpan(span *trace.SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.pending) >= maxBufferedSpans {
+		e.dropped++
+		return
+	}
+
+	e.pending = append(e.pending, span)
+}
+
+// Run flushes the exporter every interval until the context is done, then
+// flushes it a final time.
+func (e *OtlpExporter) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := e.Flush(context.Background()); err != nil {
+				onError(err)
+			}
+			return
+		case <-ticker.C:
+			if err := e.Flush(ctx); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Flush sends every pending span to the collector. Spans that fail to send
+// are kept to be retried on the next flush.
+func (e *OtlpExporter) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	batch := e.pending
+	dropped := e.dropped
+	e.pending = nil
+	e.dropped = 0
+	e.mu.Unlock()
+
+	for len(batch) > 0 {
+		size := len(batch)
+		if size > maxBatchSize {
+			size = maxBatchSize
+		}
+
+		if err := e.send(ctx, batch[:size]); err != nil {
+			e.requeue(batch)
+			return err
+		}
+
+		batch = batch[size:]
+	}
+
+	if dropped > 0 {
+		return fmt.Errorf("dropped %d spans because the collector was unavailable", dropped)
+	}
+
+	return nil
+}
+
+func (e *OtlpExporter) requeue(spans []*trace.SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	room := maxBufferedSpans - len(e.pending)
+	if room < len(spans) {
+		e.dropped += len(spans) - room
+		spans = spans[:room]
+	}
+
+	e.pending = append(spans, e.pending...)
+}
+
+func (e *OtlpExporter) send(ctx context.Context, spans []*trace.SpanData) error {
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("exporting spans to %s: unexpected response: %s", e.Endpoint, resp.Status)
+	}
+
+	return nil
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Name         string          `json:"name"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Events            []otlpEvent     `json:"events,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+// encode converts spans into an OTLP ExportTraceServiceRequest.
+func (e *OtlpExporter) encode(spans []*trace.SpanData) map[string]interface{} {
+	var encoded []otlpSpan
+	for _, span := range spans {
+		out := otlpSpan{
+			TraceId:           span.TraceID.String(),
+			SpanId:            span.SpanID.String(),
+			Name:              span.Name,
+			Kind:              otlpKind(span.SpanKind),
+			StartTimeUnixNano: unixNano(span.StartTime),
+			EndTimeUnixNano:   unixNano(span.EndTime),
+			Attributes:        otlpAttributes(span.Attributes),
+			Status:            otlpStatus{Code: otlpStatusUnset},
+		}
+
+		if span.ParentSpanID != (trace.SpanID{}) {
+			out.ParentSpanId = span.ParentSpanID.String()
+		}
+
+		if span.Code != trace.StatusCodeOK {
+			out.Status = otlpStatus{Code: otlpStatusError, Message: span.Message}
+		}
+
+		for _, annotation := range span.Annotations {
+			out.Events = append(out.Events, otlpEvent{
+				TimeUnixNano: unixNano(annotation.Time),
+				Name:         annotation.Message,
+				Attributes:   otlpAttributes(annotation.Attributes),
+			})
+		}
+
+		encoded = append(encoded, out)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]interface{}{"service.name": e.ServiceName}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": e.ServiceName},
+						"spans": encoded,
+					},
+				},
+			},
+		},
+	}
+}
+
+func otlpKind(kind int) int {
+	switch kind {
+	case trace.SpanKindServer:
+		return otlpKindServer
+	case trace.SpanKindClient:
+		return otlpKindClient
+	default:
+		return otlpKindInternal
+	}
+}
+
+func otlpAttributes(attributes map[string]interface{}) []otlpAttribute {
+	var out []otlpAttribute
+	for k, v := range attributes {
+		var value map[string]interface{}
+		switch typed := v.(type) {
+		case bool:
+			value = map[string]interface{}{"boolValue": typed}
+		case int64:
+			// OTLP JSON encodes 64 bit integers as strings
+			value = map[string]interface{}{"intValue": strconv.FormatInt(typed, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": typed}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprintf("%v", typed)}
+		}
+
+		out = append(out, otlpAttribute{Key: k, Value: value})
+	}
+
+	return out
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,142 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+type collectedSpan struct {
+	TraceId      string `json:"traceId"`
+	SpanId       string `json:"spanId"`
+	ParentSpanId string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	Status       struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+type collectedRequest struct {
+	ResourceSpans []struct {
+		ScopeSpans []struct {
+			Spans []collectedSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+func TestOtlpExporter_Flush(t *testing.T) {
+	var received []collectedSpan
+	var authHeader string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+
+		body, _ := ioutil.ReadAll(r.Body)
+		var req collectedRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("couldn't decode export request: %v", err)
+		}
+
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				received = append(received, ss.Spans...)
+			}
+		}
+	}))
+	defer collector.Close()
+
+	exporter := &OtlpExporter{
+		Endpoint:    collector.URL,
+		Headers:     map[string]string{"Authorization": "Bearer secret"},
+		ServiceName: "test-broker",
+	}
+	trace.RegisterExporter(exporter)
+	defer trace.UnregisterExporter(exporter)
+
+	ctx, parent := trace.StartSpan(context.Background(), "parent", trace.WithSampler(trace.AlwaysSample()))
+	_, child := StartSpan(ctx, "db GetServiceInstanceDetailsById")
+	EndSpan(child, errors.New("record not found"))
+	parent.End()
+
+	if err := exporter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if authHeader != "Bearer secret" {
+		t.Errorf("Expected configured headers to be sent, got Authorization %q", authHeader)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(received))
+	}
+
+	childSpan, parentSpan := received[0], received[1]
+	if childSpan.TraceId != parentSpan.TraceId {
+		t.Errorf("Expected spans to share a trace, got %q and %q", childSpan.TraceId, parentSpan.TraceId)
+	}
+
+	if childSpan.ParentSpanId != parentSpan.SpanId {
+		t.Errorf("Expected child's parent to be %q, got %q", parentSpan.SpanId, childSpan.ParentSpanId)
+	}
+
+	if childSpan.Status.Code != otlpStatusError || childSpan.Status.Message != "record not found" {
+		t.Errorf("Expected child to have an error status, got %+v", childSpan.Status)
+	}
+
+	if parentSpan.Status.Code != otlpStatusUnset || parentSpan.ParentSpanId != "" {
+		t.Errorf("Expected parent to be an unset root span, got %+v", parentSpan)
+	}
+
+	if err := exporter.Flush(context.Background()); err != nil || len(received) != 2 {
+		t.Errorf("Expected flushing again to send nothing, got %v and %d spans", err, len(received))
+	}
+}
+
+func TestOtlpExporter_FlushRetriesFailures(t *testing.T) {
+	fail := true
+	calls := 0
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer collector.Close()
+
+	exporter := &OtlpExporter{Endpoint: collector.URL}
+	exporter.ExportSpan(&trace.SpanData{Name: "span"})
+
+	if err := exporter.Flush(context.Background()); err == nil {
+		t.Fatal("Expected an error when the collector is unavailable")
+	}
+
+	fail = false
+	if err := exporter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected the span to be sent again, got %d calls", calls)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing records distributed traces of broker requests, the cloud
+// API calls and database operations they make, and exports them to an
+// OpenTelemetry collector.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/requeststats"
+	"github.com/spf13/viper"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/plugin/ochttp/propagation/tracecontext"
+	"go.opencensus.io/trace"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// OtlpEndpointProp is the OTLP/HTTP traces URL of the collector, e.g.
+	// http://localhost:4318/v1/traces. Tracing is disabled if it's empty.
+	OtlpEndpointProp = "tracing.otlp.endpoint"
+
+	// OtlpHeadersProp is a comma delimited list of key=value headers sent to
+	// the collector, e.g. for authentication.
+	OtlpHeadersProp = "tracing.otlp.headers"
+
+	// SampleRatioProp is the fraction of requests that are traced.
+	SampleRatioProp = "tracing.sample_ratio"
+
+	// ServiceNameProp is the service name traces are reported under.
+	ServiceNameProp = "tracing.service_name"
+
+	// flushInterval is how often finished spans are sent to the collector.
+	flushInterval = 5 * time.Second
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: OtlpEndpointProp, Kind: config.String},
+		config.Property{Key: OtlpHeadersProp, Kind: config.String, Sensitive: true},
+		config.Property{Key: SampleRatioProp, Kind: config.Number, Default: 1.0},
+		config.Property{Key: ServiceNameProp, Kind: config.String, Default: "cloud-service-broker"},
+	)
+}
+
+// SetupFromEnv starts exporting traces to the configured collector. The
+// returned function flushes any remaining spans and stops exporting; it's
+// safe to call even if tracing isn't configured.
+func SetupFromEnv(logger lager.Logger) (func(), error) {
+	endpoint := viper.GetString(OtlpEndpointProp)
+	if endpoint == "" {
+		return func() {}, nil
+	}
+
+	ratio := viper.GetFloat64(SampleRatioProp)
+	if ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("%s must be between 0 and 1, got %v", SampleRatioProp, ratio)
+	}
+
+	headers, err := parseHeaders(viper.GetString(OtlpHeadersProp))
+	if err != nil {
+		return nil, err
+	}
+
+	exporter := &OtlpExporter{
+		Endpoint:    endpoint,
+		Headers:     headers,
+		ServiceName: viper.GetString(ServiceNameProp),
+		Client:      &http.Client{Timeout: 30 * time.Second},
+	}
+	trace.RegisterExporter(exporter)
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(ratio)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		exporter.Run(ctx, flushInterval, func(err error) {
+			logger.Error("exporting-traces", err)
+		})
+	}()
+
+	logger.Info("tracing-enabled", lager.Data{"endpoint": endpoint, "sample_ratio": ratio})
+
+	return func() {
+		cancel()
+		<-done
+		trace.UnregisterExporter(exporter)
+	}, nil
+}
+
+func parseHeaders(raw string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("%s must be a comma delimited list of key=value pairs, got %q", OtlpHeadersProp, pair)
+		}
+
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	return headers, nil
+}
+
+// NewHandler wraps an HTTP handler so each request gets a server span. W3C
+// trace context headers sent by the platform are honored.
+func NewHandler(handler http.Handler) http.Handler {
+	return &ochttp.Handler{
+		Handler:        handler,
+		Propagation:    &tracecontext.HTTPFormat{},
+		FormatSpanName: SpanName,
+	}
+}
+
+// SpanName names a request's span after its method and route, replacing
+// instance and binding IDs so requests to the same endpoint share a name.
+func SpanName(r *http.Request) string {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for i := 1; i < len(segments); i++ {
+		switch segments[i-1] {
+		case "service_instances":
+			segments[i] = "{instance_id}"
+		case "service_bindings":
+			segments[i] = "{binding_id}"
+		}
+	}
+
+	return r.Method + " /" + strings.Join(segments, "/")
+}
+
+// NewTransport wraps an HTTP transport so each outgoing request gets a client
+// span that's a child of the span in the request's context.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	return &ochttp.Transport{Base: base, Propagation: &tracecontext.HTTPFormat{}}
+}
+
+// ClientContext returns a context that makes HTTP clients created by oauth2
+// configurations, like those used to call Google APIs, trace their requests
+// and count their time towards the broker API request in the context.
+func ClientContext(ctx context.Context) context.Context {
+	transport := requeststats.NewTransport(ctx, requeststats.PhaseGCP, NewTransport(nil))
+	return context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: transport})
+}
+
+// StartSpan starts a span that's a child of any span in the context.
+func StartSpan(ctx context.Context, name string, attributes ...trace.Attribute) (context.Context, *trace.Span) {
+	ctx, span := trace.StartSpan(ctx, name)
+	span.AddAttributes(attributes...)
+	return ctx, span
+}
+
+// EndSpan marks the span as failed if err isn't nil, then ends it.
+func EndSpan(span *trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+
+	span.End()
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/spf13/viper"
+)
+
+func TestSpanName(t *testing.T) {
+	cases := map[string]struct {
+		Method   string
+		Path     string
+		Expected string
+	}{
+		"catalog": {
+			Method:   "GET",
+			Path:     "/v2/catalog",
+			Expected: "GET /v2/catalog",
+		},
+		"instance": {
+			Method:   "PUT",
+			Path:     "/v2/service_instances/abc-123",
+			Expected: "PUT /v2/service_instances/{instance_id}",
+		},
+		"binding": {
+			Method:   "DELETE",
+			Path:     "/v2/service_instances/abc-123/service_bindings/def-456",
+			Expected: "DELETE /v2/service_instances/{instance_id}/service_bindings/{binding_id}",
+		},
+		"last operation": {
+			Method:   "GET",
+			Path:     "/v2/service_instances/abc-123/last_operation",
+			Expected: "GET /v2/service_instances/{instance_id}/last_operation",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := SpanName(httptest.NewRequest(tc.Method, tc.Path, nil))
+			if actual != tc.Expected {
+				t.Errorf("Expected span name %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	cases := map[string]struct {
+		Raw         string
+		Expected    map[string]string
+		ExpectedErr string
+	}{
+		"empty": {
+			Raw:      "",
+			Expected: map[string]string{},
+		},
+		"multiple": {
+			Raw:      "Authorization=Bearer abc=, x-team = data ,",
+			Expected: map[string]string{"Authorization": "Bearer abc=", "x-team": "data"},
+		},
+		"missing value": {
+			Raw:         "Authorization",
+			ExpectedErr: "key=value pairs",
+		},
+		"missing key": {
+			Raw:         "=value",
+			ExpectedErr: "key=value pairs",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := parseHeaders(tc.Raw)
+			switch {
+			case tc.ExpectedErr == "" && err != nil:
+				t.Fatalf("Expected no error, got %v", err)
+			case tc.ExpectedErr != "" && (err == nil || !strings.Contains(err.Error(), tc.ExpectedErr)):
+				t.Fatalf("Expected error containing %q, got %v", tc.ExpectedErr, err)
+			}
+
+			if tc.ExpectedErr == "" && !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected headers %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestSetupFromEnv(t *testing.T) {
+	logger := lager.NewLogger("test")
+
+	cases := map[string]struct {
+		Endpoint    string
+		Ratio       interface{}
+		ExpectedErr string
+	}{
+		"disabled": {},
+		"enabled": {
+			Endpoint: "http://localhost:4318/v1/traces",
+			Ratio:    "0.5",
+		},
+		"invalid ratio": {
+			Endpoint:    "http://localhost:4318/v1/traces",
+			Ratio:       "1.5",
+			ExpectedErr: "must be between 0 and 1",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(OtlpEndpointProp, tc.Endpoint)
+			viper.Set(SampleRatioProp, tc.Ratio)
+			defer viper.Set(OtlpEndpointProp, nil)
+			defer viper.Set(SampleRatioProp, nil)
+
+			shutdown, err := SetupFromEnv(logger)
+			switch {
+			case tc.ExpectedErr == "" && err != nil:
+				t.Fatalf("Expected no error, got %v", err)
+			case tc.ExpectedErr != "" && (err == nil || !strings.Contains(err.Error(), tc.ExpectedErr)):
+				t.Fatalf("Expected error containing %q, got %v", tc.ExpectedErr, err)
+			}
+
+			if shutdown != nil {
+				shutdown()
+			}
+		})
+	}
+}
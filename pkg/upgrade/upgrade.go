@@ -0,0 +1,107 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package upgrade holds the types used to control how instances are moved to
+// new maintenance_info versions of their service.
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Policy controls whether instances of a service are upgraded when the
+// platform asks for the service's latest maintenance_info version.
+type Policy string
+
+const (
+	// PolicyAutomatic upgrades instances whenever the platform asks.
+	PolicyAutomatic Policy = "automatic"
+	// PolicyManual upgrades instances only after an operator approves it.
+	PolicyManual Policy = "manual"
+	// PolicyPinned never upgrades instances.
+	PolicyPinned Policy = "pinned"
+)
+
+// ParsePolicy converts the string to a Policy, an empty string is automatic.
+func ParsePolicy(policy string) (Policy, error) {
+	switch Policy(policy) {
+	case "":
+		return PolicyAutomatic, nil
+	case PolicyAutomatic, PolicyManual, PolicyPinned:
+		return Policy(policy), nil
+	default:
+		return "", fmt.Errorf("unknown upgrade policy %q, must be one of %s, %s or %s", policy, PolicyAutomatic, PolicyManual, PolicyPinned)
+	}
+}
+
+// The states an upgrade goes through under the manual policy.
+const (
+	StatePending   = "pending"
+	StateApproved  = "approved"
+	StateCompleted = "completed"
+)
+
+// ErrEmptyApproval is returned if an approval doesn't select any instances,
+// to prevent accidentally approving every upgrade.
+var ErrEmptyApproval = errors.New("at least one of service or instance_ids must be set")
+
+// Approval selects the instances whose upgrades get approved. Instances must
+// match every criteria that's set.
+type Approval struct {
+	// Service holds the name or ID of a service.
+	Service     string   `json:"service,omitempty"`
+	InstanceIds []string `json:"instance_ids,omitempty"`
+}
+
+// IsEmpty returns true if the approval doesn't set any criteria.
+func (a *Approval) IsEmpty() bool {
+	return a.Service == "" && len(a.InstanceIds) == 0
+}
+
+// Instance describes an instance that's behind its service's maintenance_info
+// version.
+type Instance struct {
+	InstanceId       string     `json:"instance_id"`
+	ServiceId        string     `json:"service_id"`
+	ServiceName      string     `json:"service_name"`
+	PlanId           string     `json:"plan_id"`
+	Policy           Policy     `json:"policy"`
+	CurrentVersion   string     `json:"current_version"`
+	AvailableVersion string     `json:"available_version"`
+	State            string     `json:"state,omitempty"`
+	ApprovedAt       *time.Time `json:"approved_at,omitempty"`
+	Error            string     `json:"error,omitempty"`
+}
+
+// Report summarizes the outcome of an approval. Dry runs list the instances
+// that would be approved in Planned instead of approving them.
+type Report struct {
+	DryRun   bool       `json:"dry_run,omitempty"`
+	Planned  []Instance `json:"planned,omitempty"`
+	Approved []Instance `json:"approved"`
+	Skipped  []Instance `json:"skipped"`
+}
+
+// Manager lists and approves instance upgrades.
+type Manager interface {
+	// ListUpgrades lists the instances that are behind their service's
+	// maintenance_info version.
+	ListUpgrades(ctx context.Context) ([]Instance, error)
+	// ApproveUpgrades approves upgrading every outdated instance matching the
+	// approval whose service has the manual policy.
+	ApproveUpgrades(ctx context.Context, approval Approval) (*Report, error)
+}
@@ -0,0 +1,306 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package usage aggregates the service instances the broker manages, including
+// deleted ones, into reports operators use for chargeback and capacity
+// planning.
+package usage
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// The dimensions reports can be grouped by.
+const (
+	ByOrganization = "organization"
+	BySpace        = "space"
+	ByService      = "service"
+	ByPlan         = "plan"
+)
+
+// AllDimensions are the dimensions reports are grouped by by default.
+var AllDimensions = []string{ByOrganization, BySpace, ByService, ByPlan}
+
+// Options select the period a report covers and how it's grouped.
+type Options struct {
+	// From is the inclusive start of the period.
+	From time.Time
+	// To is the exclusive end of the period.
+	To time.Time
+	// GroupBy holds the dimensions rows are grouped by, in order.
+	GroupBy []string
+}
+
+// ParseOptions parses the options from the values given on the command line
+// or in a request. Times are either RFC 3339 timestamps or dates, which are
+// midnight UTC. The period defaults to the current month up to now and the
+// grouping to AllDimensions.
+func ParseOptions(from, to, groupBy string, now time.Time) (Options, error) {
+	now = now.UTC()
+	opts := Options{
+		From:    time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		To:      now,
+		GroupBy: AllDimensions,
+	}
+
+	var err error
+	if from != "" {
+		if opts.From, err = parseTime(from); err != nil {
+			return opts, fmt.Errorf("invalid from: %v", err)
+		}
+	}
+
+	if to != "" {
+		if opts.To, err = parseTime(to); err != nil {
+			return opts, fmt.Errorf("invalid to: %v", err)
+		}
+	}
+
+	if !opts.To.After(opts.From) {
+		return opts, fmt.Errorf("the period must end after it starts, got %s to %s", opts.From.Format(time.RFC3339), opts.To.Format(time.RFC3339))
+	}
+
+	if groupBy != "" {
+		opts.GroupBy = nil
+		for _, dimension := range strings.Split(groupBy, ",") {
+			dimension = strings.TrimSpace(dimension)
+			if !isDimension(dimension) {
+				return opts, fmt.Errorf("invalid group by %q, must be one of %s", dimension, strings.Join(AllDimensions, ", "))
+			}
+
+			opts.GroupBy = append(opts.GroupBy, dimension)
+		}
+	}
+
+	return opts, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+func isDimension(value string) bool {
+	for _, dimension := range AllDimensions {
+		if value == dimension {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Report aggregates the instances that existed during a period.
+type Report struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	GroupBy []string  `json:"group_by"`
+	Rows    []Row     `json:"rows"`
+}
+
+// Row aggregates the instances of a group. Only the fields of the dimensions
+// the report is grouped by are set.
+type Row struct {
+	OrganizationGuid string `json:"organization_guid,omitempty"`
+	SpaceGuid        string `json:"space_guid,omitempty"`
+	ServiceId        string `json:"service_id,omitempty"`
+	ServiceName      string `json:"service_name,omitempty"`
+	PlanId           string `json:"plan_id,omitempty"`
+	PlanName         string `json:"plan_name,omitempty"`
+
+	// Instances is the number of instances that existed at any time during
+	// the period.
+	Instances int `json:"instances"`
+	// Created is the number of instances created during the period.
+	Created int `json:"created"`
+	// Deleted is the number of instances deleted during the period.
+	Deleted int `json:"deleted"`
+	// Active is the number of instances that existed at the end of the
+	// period.
+	Active int `json:"active"`
+	// InstanceHours is the total time the instances existed during the
+	// period.
+	InstanceHours float64 `json:"instance_hours"`
+	// AverageLifetimeHours is the average time between the creation and
+	// deletion of the instances deleted during the period.
+	AverageLifetimeHours float64 `json:"average_lifetime_hours"`
+
+	lifetime time.Duration
+}
+
+// Generate creates a report of the instances in the database. The registry
+// is used to look up the names of services and plans.
+func Generate(ctx context.Context, registry broker.BrokerRegistry, opts Options) (*Report, error) {
+	instances, err := db_service.ListServiceInstanceHistory(ctx, opts.From)
+	if err != nil {
+		return nil, err
+	}
+
+	return Aggregate(instances, registry, opts), nil
+}
+
+// Aggregate creates a report of the given instances, instances that didn't
+// exist during the period are ignored.
+func Aggregate(instances []models.ServiceInstanceDetails, registry broker.BrokerRegistry, opts Options) *Report {
+	rows := make(map[Row]*Row)
+	for _, instance := range instances {
+		start, end := instance.CreatedAt, opts.To
+		if instance.DeletedAt != nil && instance.DeletedAt.Before(end) {
+			end = *instance.DeletedAt
+		}
+		if !start.Before(opts.To) || end.Before(opts.From) {
+			continue
+		}
+
+		key := groupKey(instance, registry, opts.GroupBy)
+		row, ok := rows[key]
+		if !ok {
+			row = &Row{}
+			*row = key
+			rows[key] = row
+		}
+
+		row.Instances++
+		if !start.Before(opts.From) {
+			row.Created++
+		}
+
+		if instance.DeletedAt != nil && instance.DeletedAt.Before(opts.To) {
+			row.Deleted++
+			row.lifetime += end.Sub(start)
+		} else {
+			row.Active++
+		}
+
+		if start.Before(opts.From) {
+			start = opts.From
+		}
+		row.InstanceHours += end.Sub(start).Hours()
+	}
+
+	report := &Report{From: opts.From, To: opts.To, GroupBy: opts.GroupBy, Rows: []Row{}}
+	for _, row := range rows {
+		if row.Deleted > 0 {
+			row.AverageLifetimeHours = row.lifetime.Hours() / float64(row.Deleted)
+		}
+
+		report.Rows = append(report.Rows, *row)
+	}
+
+	sort.Slice(report.Rows, func(i, j int) bool {
+		return strings.Join(report.Rows[i].keyFields(), "\x00") < strings.Join(report.Rows[j].keyFields(), "\x00")
+	})
+
+	return report
+}
+
+// groupKey creates a row with the fields of the given dimensions set from
+// the instance.
+func groupKey(instance models.ServiceInstanceDetails, registry broker.BrokerRegistry, groupBy []string) Row {
+	var key Row
+	for _, dimension := range groupBy {
+		switch dimension {
+		case ByOrganization:
+			key.OrganizationGuid = instance.OrganizationGuid
+		case BySpace:
+			key.SpaceGuid = instance.SpaceGuid
+		case ByService:
+			key.ServiceId = instance.ServiceId
+			if svc, err := registry.GetServiceById(instance.ServiceId); err == nil {
+				key.ServiceName = svc.Name
+			}
+		case ByPlan:
+			key.PlanId = instance.PlanId
+			if svc, err := registry.GetServiceById(instance.ServiceId); err == nil {
+				if plan, err := svc.GetPlanById(instance.PlanId); err == nil {
+					key.PlanName = plan.Name
+				}
+			}
+		}
+	}
+
+	return key
+}
+
+func (row Row) keyFields() []string {
+	return []string{row.OrganizationGuid, row.SpaceGuid, row.ServiceName, row.ServiceId, row.PlanName, row.PlanId}
+}
+
+// WriteCsv writes the rows of the report as CSV with a header. Only the
+// columns of the dimensions the report is grouped by are included.
+func WriteCsv(w io.Writer, report *Report) error {
+	var header []string
+	for _, dimension := range report.GroupBy {
+		switch dimension {
+		case ByOrganization:
+			header = append(header, "organization_guid")
+		case BySpace:
+			header = append(header, "space_guid")
+		case ByService:
+			header = append(header, "service_id", "service_name")
+		case ByPlan:
+			header = append(header, "plan_id", "plan_name")
+		}
+	}
+	header = append(header, "instances", "created", "deleted", "active", "instance_hours", "average_lifetime_hours")
+
+	out := csv.NewWriter(w)
+	if err := out.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range report.Rows {
+		var record []string
+		for _, dimension := range report.GroupBy {
+			switch dimension {
+			case ByOrganization:
+				record = append(record, row.OrganizationGuid)
+			case BySpace:
+				record = append(record, row.SpaceGuid)
+			case ByService:
+				record = append(record, row.ServiceId, row.ServiceName)
+			case ByPlan:
+				record = append(record, row.PlanId, row.PlanName)
+			}
+		}
+
+		record = append(record,
+			strconv.Itoa(row.Instances),
+			strconv.Itoa(row.Created),
+			strconv.Itoa(row.Deleted),
+			strconv.Itoa(row.Active),
+			strconv.FormatFloat(row.InstanceHours, 'f', 2, 64),
+			strconv.FormatFloat(row.AverageLifetimeHours, 'f', 2, 64))
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,199 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usage
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+var (
+	from = time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)
+	to   = time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func testRegistry() broker.BrokerRegistry {
+	return broker.BrokerRegistry{
+		"svc": &broker.ServiceDefinition{
+			Id:   "svc-id",
+			Name: "svc",
+			Plans: []broker.ServicePlan{
+				{ServicePlan: brokerapi.ServicePlan{ID: "small-id", Name: "small"}},
+			},
+		},
+	}
+}
+
+func instance(id, org string, created time.Time, deleted *time.Time) models.ServiceInstanceDetails {
+	return models.ServiceInstanceDetails{
+		ID:               id,
+		CreatedAt:        created,
+		DeletedAt:        deleted,
+		ServiceId:        "svc-id",
+		PlanId:           "small-id",
+		OrganizationGuid: org,
+		SpaceGuid:        org + "-space",
+	}
+}
+
+func at(t time.Time) *time.Time {
+	return &t
+}
+
+func TestAggregate(t *testing.T) {
+	instances := []models.ServiceInstanceDetails{
+		// existed for the whole period
+		instance("old", "org-a", from.Add(-24*time.Hour), nil),
+		// created and deleted during the period
+		instance("short", "org-a", from.Add(24*time.Hour), at(from.Add(36*time.Hour))),
+		// deleted before the period
+		instance("gone", "org-a", from.Add(-48*time.Hour), at(from.Add(-24*time.Hour))),
+		// created after the period
+		instance("future", "org-a", to.Add(time.Hour), nil),
+		// created during the period, deleted after it
+		instance("late", "org-b", to.Add(-10*time.Hour), at(to.Add(time.Hour))),
+	}
+
+	cases := map[string]struct {
+		GroupBy  []string
+		Expected []Row
+	}{
+		"everything": {
+			GroupBy: AllDimensions,
+			Expected: []Row{
+				{
+					OrganizationGuid:     "org-a",
+					SpaceGuid:            "org-a-space",
+					ServiceId:            "svc-id",
+					ServiceName:          "svc",
+					PlanId:               "small-id",
+					PlanName:             "small",
+					Instances:            2,
+					Created:              1,
+					Deleted:              1,
+					Active:               1,
+					InstanceHours:        30*24 + 12,
+					AverageLifetimeHours: 12,
+					lifetime:             12 * time.Hour,
+				},
+				{
+					OrganizationGuid: "org-b",
+					SpaceGuid:        "org-b-space",
+					ServiceId:        "svc-id",
+					ServiceName:      "svc",
+					PlanId:           "small-id",
+					PlanName:         "small",
+					Instances:        1,
+					Created:          1,
+					Active:           1,
+					InstanceHours:    10,
+				},
+			},
+		},
+		"by plan": {
+			GroupBy: []string{ByPlan},
+			Expected: []Row{
+				{
+					PlanId:               "small-id",
+					PlanName:             "small",
+					Instances:            3,
+					Created:              2,
+					Deleted:              1,
+					Active:               2,
+					InstanceHours:        30*24 + 22,
+					AverageLifetimeHours: 12,
+					lifetime:             12 * time.Hour,
+				},
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			report := Aggregate(instances, testRegistry(), Options{From: from, To: to, GroupBy: tc.GroupBy})
+			if !reflect.DeepEqual(report.Rows, tc.Expected) {
+				t.Errorf("Expected rows %+v, got %+v", tc.Expected, report.Rows)
+			}
+		})
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		From, To, GroupBy string
+		Expected          Options
+		ExpectErr         bool
+	}{
+		"defaults": {
+			Expected: Options{From: time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC), To: now, GroupBy: AllDimensions},
+		},
+		"dates": {
+			From:     "2026-09-01",
+			To:       "2026-10-01",
+			GroupBy:  "organization, service",
+			Expected: Options{From: from, To: to, GroupBy: []string{ByOrganization, ByService}},
+		},
+		"timestamps": {
+			From:     "2026-09-01T00:00:00Z",
+			To:       "2026-10-01T00:00:00Z",
+			Expected: Options{From: from, To: to, GroupBy: AllDimensions},
+		},
+		"bad time":      {From: "last month", ExpectErr: true},
+		"empty period":  {From: "2026-10-01", To: "2026-09-01", ExpectErr: true},
+		"bad dimension": {GroupBy: "org", ExpectErr: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := ParseOptions(tc.From, tc.To, tc.GroupBy, now)
+			if (err != nil) != tc.ExpectErr {
+				t.Fatalf("Expected error: %v, got %v", tc.ExpectErr, err)
+			}
+
+			if !tc.ExpectErr && !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected %+v, got %+v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestWriteCsv(t *testing.T) {
+	report := &Report{
+		GroupBy: []string{ByOrganization, ByPlan},
+		Rows: []Row{
+			{OrganizationGuid: "org-a", PlanId: "small-id", PlanName: "small", Instances: 2, Created: 1, Deleted: 1, Active: 1, InstanceHours: 732, AverageLifetimeHours: 12},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteCsv(buf, report); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `organization_guid,plan_id,plan_name,instances,created,deleted,active,instance_hours,average_lifetime_hours
+org-a,small-id,small,2,1,1,1,732.00,12.00
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
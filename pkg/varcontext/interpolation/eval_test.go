@@ -65,6 +65,7 @@ func TestEval(t *testing.T) {
 		"missing env var":       {Template: `${env("_MISSING")}`, ErrorContains: "Missing environment variable _MISSING"},
 		"config val":            {Template: `${config("config.val")}`, Expected: `foo`},
 		"missing config var":    {Template: `${config("config.missing")}`, ErrorContains: "Missing config value config.missing"},
+		"project credentials":   {Template: `${gcp.project_credentials("other-project")}`, Expected: `default-creds`},
 	}
 
 	for tn, tc := range tests {
@@ -73,6 +74,7 @@ func TestEval(t *testing.T) {
 		os.Setenv("FOO", "Bar")
 		defer os.Unsetenv("FOO")
 		viper.SetDefault("config.val", "foo")
+		viper.SetDefault("gcp.credentials", "default-creds")
 
 		t.Run(tn, func(t *testing.T) {
 			res, err := Eval(tc.Template, tc.Variables)
@@ -118,6 +120,25 @@ func TestHilFuncRandBase64(t *testing.T) {
 	}
 }
 
+func TestHilFuncRandomString(t *testing.T) {
+	result, err := Eval("${random_string(12)}", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	value := result.(string)
+	if len(value) != 12 {
+		t.Errorf("Expected length to be %d got %d", 12, len(value))
+	}
+	if strings.Trim(value, randomStringChars) != "" {
+		t.Errorf("Expected only lowercase letters and digits, got %q", value)
+	}
+
+	if _, err := Eval("${random_string(-1)}", nil); err == nil {
+		t.Error("Expected an error for a negative length")
+	}
+}
+
 func TestHilToInterface(t *testing.T) {
 	// This function tests hilToInterface operates correctly with regards to
 	// taking valid user inputs (i.e. only JSON values), converting them to HIL
@@ -30,6 +30,7 @@ import (
 	"github.com/hashicorp/hil"
 	"github.com/hashicorp/hil/ast"
 	"github.com/spf13/cast"
+	"github.com/pivotal/cloud-service-broker/pkg/projects"
 	"github.com/spf13/viper"
 )
 
@@ -45,11 +46,13 @@ func createStandardLibrary() map[string]ast.Function {
 		"regexp.matches":  hilFuncRegexpMatches(),
 		"counter.next":    hilFuncCounterNext(),
 		"rand.base64":     hilFuncRandBase64(),
+		"random_string":   hilFuncRandomString(),
 		"assert":          hilFuncAssert(),
 		"json.marshal":    hilFuncJSONMarshal(),
 		"map.flatten":     hilFuncMapFlatten(),
 		"env":             hilFuncEnv(),
 		"config":		   hilFuncConfig(),
+		"gcp.project_credentials": hilFuncGcpProjectCredentials(),
 	}
 }
 
@@ -67,6 +70,18 @@ func hilFuncConfig() ast.Function {
 	}
 }
 
+// hilFuncGcpProjectCredentials returns the credentials used to manage the given
+// GCP project
+func hilFuncGcpProjectCredentials() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			return projects.Credentials(args[0].(string))
+		},
+	}
+}
+
 // hilFuncEnv returns value of a given enironment variable
 func hilFuncEnv() ast.Function {
 	return ast.Function{
@@ -157,6 +172,36 @@ func hilFuncRandBase64() ast.Function {
 	}
 }
 
+// randomStringChars are the characters of random_string, they're allowed in
+// the names of most resources.
+const randomStringChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// hilFuncRandomString creates a cryptographically-secure random string of n
+// lowercase letters and digits random_string(8) -> "k3x9q0az".
+func hilFuncRandomString() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeInt},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			length := args[0].(int)
+			if length < 0 {
+				return "", fmt.Errorf("random_string length must not be negative, got %d", length)
+			}
+
+			rb := make([]byte, length)
+			if _, err := rand.Read(rb); err != nil {
+				return "", err
+			}
+
+			for i, b := range rb {
+				rb[i] = randomStringChars[int(b)%len(randomStringChars)]
+			}
+
+			return string(rb), nil
+		},
+	}
+}
+
 // hilFuncStrQueryEscape escapes a string suitable for embedding in a URL.
 func hilFuncStrQueryEscape() ast.Function {
 	return ast.Function{
@@ -0,0 +1,242 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	iamcredentials "google.golang.org/api/iamcredentials/v1"
+)
+
+const (
+	rootSaEnvVar       = "ROOT_SERVICE_ACCOUNT_JSON"
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+	// CredentialsSourceProp is the viper key of where the broker gets its
+	// Google Cloud credentials from.
+	CredentialsSourceProp = "google.credentials_source"
+
+	// ImpersonateProp is the viper key of the email of a service account the
+	// broker impersonates with its credentials, so they only need permission
+	// to create tokens for it.
+	ImpersonateProp = "google.impersonate_service_account"
+
+	// ProjectProp is the viper key of the broker's default project. It
+	// defaults to the project of the credentials.
+	ProjectProp = "google.project"
+)
+
+// Sources of Google Cloud credentials.
+const (
+	// ServiceAccountKeySource uses the service account key in google.account.
+	ServiceAccountKeySource = "service_account_key"
+
+	// ApplicationDefaultSource uses Application Default Credentials, e.g. the
+	// file in GOOGLE_APPLICATION_CREDENTIALS or the metadata server.
+	ApplicationDefaultSource = "application_default"
+
+	// MetadataServerSource is reported for Application Default Credentials
+	// from the GCE or GKE metadata server, including GKE Workload Identity.
+	MetadataServerSource = "metadata_server"
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: "google.account", Kind: config.String, Env: rootSaEnvVar, Sensitive: true},
+		config.Property{Key: CredentialsSourceProp, Kind: config.String, Default: ServiceAccountKeySource, Allowed: []string{ServiceAccountKeySource, ApplicationDefaultSource}},
+		config.Property{Key: ImpersonateProp, Kind: config.String},
+		config.Property{Key: ProjectProp, Kind: config.String},
+	)
+}
+
+// HttpConfig creates HTTP clients and token sources authorized to call Google
+// Cloud APIs as the broker. *jwt.Config implements it.
+type HttpConfig interface {
+	Client(ctx context.Context) *http.Client
+	TokenSource(ctx context.Context) oauth2.TokenSource
+}
+
+// CredentialMode describes the Google Cloud credentials the broker uses.
+type CredentialMode struct {
+	Source        string `json:"source"`
+	Impersonating string `json:"impersonating,omitempty"`
+}
+
+func (m CredentialMode) String() string {
+	if m.Impersonating == "" {
+		return m.Source
+	}
+
+	return fmt.Sprintf("%s impersonating %s", m.Source, m.Impersonating)
+}
+
+// GcpCredentialsConfigured returns true if the broker is configured with
+// Google Cloud credentials. Brokers for other clouds run without them.
+func GcpCredentialsConfigured() bool {
+	return viper.GetString(CredentialsSourceProp) == ApplicationDefaultSource || GetServiceAccountJson() != ""
+}
+
+// GetCredentialMode gets the source of the broker's Google Cloud credentials
+// and the service account it impersonates, if any.
+func GetCredentialMode() (CredentialMode, error) {
+	mode := CredentialMode{
+		Source:        viper.GetString(CredentialsSourceProp),
+		Impersonating: viper.GetString(ImpersonateProp),
+	}
+
+	if mode.Source == ApplicationDefaultSource {
+		creds, err := findDefaultCredentials()
+		if err != nil {
+			return mode, err
+		}
+
+		// credentials files are read into JSON, the metadata server has none
+		if creds.JSON == nil {
+			mode.Source = MetadataServerSource
+		}
+	}
+
+	return mode, nil
+}
+
+// GetAuthedConfig gets the configuration to call Google Cloud APIs with the
+// broker's credentials, impersonating the configured service account if
+// there is one.
+func GetAuthedConfig() (HttpConfig, error) {
+	var conf HttpConfig
+	switch viper.GetString(CredentialsSourceProp) {
+	case ApplicationDefaultSource:
+		creds, err := findDefaultCredentials()
+		if err != nil {
+			return nil, err
+		}
+		conf = &tokenSourceConfig{source: creds.TokenSource}
+
+	default:
+		jwtConf, err := google.JWTConfigFromJSON([]byte(GetServiceAccountJson()), cloudPlatformScope)
+		if err != nil {
+			return nil, fmt.Errorf("Error initializing config from credentials: %s", err)
+		}
+		conf = jwtConf
+	}
+
+	if account := viper.GetString(ImpersonateProp); account != "" {
+		source := &impersonatedTokenSource{base: conf, account: account}
+		conf = &tokenSourceConfig{source: oauth2.ReuseTokenSource(nil, source)}
+	}
+
+	return conf, nil
+}
+
+// GetDefaultProjectId gets the default project id for the service broker,
+// either the one configured or the one of its credentials.
+func GetDefaultProjectId() (string, error) {
+	if project := viper.GetString(ProjectProp); project != "" {
+		return project, nil
+	}
+
+	if viper.GetString(CredentialsSourceProp) == ApplicationDefaultSource {
+		creds, err := findDefaultCredentials()
+		if err != nil {
+			return "", err
+		}
+
+		if creds.ProjectID == "" {
+			return "", fmt.Errorf("the application default credentials have no project, set %s", ProjectProp)
+		}
+
+		return creds.ProjectID, nil
+	}
+
+	serviceAccount := make(map[string]string)
+	if err := json.Unmarshal([]byte(GetServiceAccountJson()), &serviceAccount); err != nil {
+		return "", fmt.Errorf("could not unmarshal service account details. %v", err)
+	}
+
+	return serviceAccount["project_id"], nil
+}
+
+// GetServiceAccountJson gets the raw JSON credentials of the Service Account
+// the service broker acts as.
+func GetServiceAccountJson() string {
+	return viper.GetString("google.account")
+}
+
+func findDefaultCredentials() (*google.Credentials, error) {
+	creds, err := google.FindDefaultCredentials(context.Background(), cloudPlatformScope)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding application default credentials: %s", err)
+	}
+
+	return creds, nil
+}
+
+// tokenSourceConfig is an HttpConfig for a token source that's shared by the
+// clients it creates.
+type tokenSourceConfig struct {
+	source oauth2.TokenSource
+}
+
+func (c *tokenSourceConfig) Client(ctx context.Context) *http.Client {
+	return oauth2.NewClient(ctx, c.source)
+}
+
+func (c *tokenSourceConfig) TokenSource(ctx context.Context) oauth2.TokenSource {
+	return c.source
+}
+
+// impersonatedTokenSource gets tokens for a service account from the IAM
+// Credentials API using the base credentials, which need the Service
+// Account Token Creator role on it.
+type impersonatedTokenSource struct {
+	base    HttpConfig
+	account string
+
+	// basePath overrides the API's URL in tests.
+	basePath string
+}
+
+func (s *impersonatedTokenSource) Token() (*oauth2.Token, error) {
+	ctx := context.Background()
+	service, err := iamcredentials.New(s.base.Client(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if s.basePath != "" {
+		service.BasePath = s.basePath
+	}
+
+	name := "projects/-/serviceAccounts/" + s.account
+	request := &iamcredentials.GenerateAccessTokenRequest{Scope: []string{cloudPlatformScope}}
+	response, err := service.Projects.ServiceAccounts.GenerateAccessToken(name, request).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't impersonate %s: %v", s.account, err)
+	}
+
+	expiry, err := time.Parse(time.RFC3339, response.ExpireTime)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse the expiry of the token for %s: %v", s.account, err)
+	}
+
+	return &oauth2.Token{AccessToken: response.AccessToken, TokenType: "Bearer", Expiry: expiry}, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+)
+
+func TestGetCredentialMode(t *testing.T) {
+	cases := map[string]struct {
+		Account          string
+		Impersonate      string
+		ExpectMode       CredentialMode
+		ExpectString     string
+		ExpectConfigured bool
+	}{
+		"unconfigured": {
+			ExpectMode:   CredentialMode{Source: ServiceAccountKeySource},
+			ExpectString: "service_account_key",
+		},
+		"key": {
+			Account:          `{"type": "service_account"}`,
+			ExpectMode:       CredentialMode{Source: ServiceAccountKeySource},
+			ExpectString:     "service_account_key",
+			ExpectConfigured: true,
+		},
+		"impersonation": {
+			Account:          `{"type": "service_account"}`,
+			Impersonate:      "broker@my-project.iam.gserviceaccount.com",
+			ExpectMode:       CredentialMode{Source: ServiceAccountKeySource, Impersonating: "broker@my-project.iam.gserviceaccount.com"},
+			ExpectString:     "service_account_key impersonating broker@my-project.iam.gserviceaccount.com",
+			ExpectConfigured: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set("google.account", tc.Account)
+			viper.Set(ImpersonateProp, tc.Impersonate)
+			defer viper.Set("google.account", nil)
+			defer viper.Set(ImpersonateProp, nil)
+
+			mode, err := GetCredentialMode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if mode != tc.ExpectMode {
+				t.Errorf("expected mode %v, got %v", tc.ExpectMode, mode)
+			}
+
+			if mode.String() != tc.ExpectString {
+				t.Errorf("expected %q, got %q", tc.ExpectString, mode.String())
+			}
+
+			if configured := GcpCredentialsConfigured(); configured != tc.ExpectConfigured {
+				t.Errorf("expected configured: %v, got: %v", tc.ExpectConfigured, configured)
+			}
+		})
+	}
+}
+
+func ExampleGetDefaultProjectId_configured() {
+	viper.Set(ProjectProp, "other-project")
+	defer viper.Set(ProjectProp, nil)
+
+	projectId, err := GetDefaultProjectId()
+	fmt.Printf("%s, %v\n", projectId, err)
+
+	// Output: other-project, <nil>
+}
+
+func TestImpersonatedTokenSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer base-token" {
+			t.Errorf("expected the base credentials, got %q", auth)
+		}
+
+		if expected := "/v1/projects/-/serviceAccounts/broker@my-project.iam.gserviceaccount.com:generateAccessToken"; r.URL.Path != expected {
+			t.Errorf("expected path %q, got %q", expected, r.URL.Path)
+		}
+
+		fmt.Fprint(w, `{"accessToken": "impersonated-token", "expireTime": "2030-01-02T03:04:05Z"}`)
+	}))
+	defer server.Close()
+
+	source := &impersonatedTokenSource{
+		base:     &tokenSourceConfig{source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "base-token"})},
+		account:  "broker@my-project.iam.gserviceaccount.com",
+		basePath: server.URL + "/",
+	}
+
+	token, err := source.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken != "impersonated-token" {
+		t.Errorf("expected the impersonated token, got %q", token.AccessToken)
+	}
+
+	if expected := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC); !token.Expiry.Equal(expected) {
+		t.Errorf("expected expiry %v, got %v", expected, token.Expiry)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// LogLevelProp is the minimum level logged to stdout, one of debug, info,
+	// error or fatal.
+	LogLevelProp = "log.level"
+
+	// LogFormatProp selects how log lines are written, either lager's default
+	// format or json with RFC 3339 timestamps and named levels.
+	LogFormatProp = "log.format"
+
+	// LogFormatLager writes lager's default JSON with epoch timestamps and
+	// numeric levels.
+	LogFormatLager = "lager"
+
+	// LogFormatJson writes JSON with RFC 3339 timestamps and named levels that
+	// log aggregators can index without extra parsing.
+	LogFormatJson = "json"
+
+	debugEnvVar = "GSB_DEBUG"
+)
+
+func init() {
+	config.Register(
+		config.Property{Key: LogLevelProp, Kind: config.String, Default: lager.INFO.String()},
+		config.Property{Key: LogFormatProp, Kind: config.String, Default: LogFormatLager},
+	)
+}
+
+// logLevelFromEnv returns the configured log level. Setting GSB_DEBUG turns on
+// debug logging regardless of the configured level for backwards
+// compatibility. Invalid levels fall back to info.
+func logLevelFromEnv() (lager.LogLevel, error) {
+	if _, debug := os.LookupEnv(debugEnvVar); debug {
+		return lager.DEBUG, nil
+	}
+
+	level, err := lager.LogLevelFromString(strings.ToLower(strings.TrimSpace(viper.GetString(LogLevelProp))))
+	if err != nil {
+		return lager.INFO, fmt.Errorf("%s: %v, using %s", LogLevelProp, err, lager.INFO)
+	}
+
+	return level, nil
+}
+
+// logSinkFromEnv returns a constructor for sinks that write the configured
+// log format. Unknown formats fall back to lager's default.
+func logSinkFromEnv() func(io.Writer, lager.LogLevel) lager.Sink {
+	if strings.EqualFold(viper.GetString(LogFormatProp), LogFormatJson) {
+		return lager.NewPrettySink
+	}
+
+	return lager.NewWriterSink
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"os"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/spf13/viper"
+)
+
+func TestLogLevelFromEnv(t *testing.T) {
+	cases := map[string]struct {
+		Level     interface{}
+		Debug     bool
+		Expected  lager.LogLevel
+		ExpectErr bool
+	}{
+		"default":       {Expected: lager.INFO},
+		"configured":    {Level: "ERROR", Expected: lager.ERROR},
+		"debug env var": {Level: "error", Debug: true, Expected: lager.DEBUG},
+		"invalid level": {Level: "verbose", Expected: lager.INFO, ExpectErr: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(LogLevelProp, tc.Level)
+			defer viper.Set(LogLevelProp, nil)
+
+			if tc.Debug {
+				os.Setenv(debugEnvVar, "true")
+				defer os.Unsetenv(debugEnvVar)
+			}
+
+			actual, err := logLevelFromEnv()
+			if (err != nil) != tc.ExpectErr {
+				t.Errorf("Expected error: %v, got: %v", tc.ExpectErr, err)
+			}
+
+			if actual != tc.Expected {
+				t.Errorf("Expected level %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
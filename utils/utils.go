@@ -24,15 +24,22 @@ import (
 
 	"code.cloudfoundry.org/lager"
 	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/config"
+	"github.com/pivotal/cloud-service-broker/pkg/platformcontext"
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
-	"golang.org/x/oauth2/google"
-	"golang.org/x/oauth2/jwt"
 )
 
 const (
 	EnvironmentVarPrefix = "gsb"
-	rootSaEnvVar         = "ROOT_SERVICE_ACCOUNT_JSON"
-	cloudPlatformScope   = "https://www.googleapis.com/auth/cloud-platform"
+
+	// StaticLabelsProp holds a JSON object of operator-defined labels that get
+	// applied to every resource the broker creates.
+	StaticLabelsProp = "provision.static_labels"
+
+	// InstanceIdLabel is the label holding the ID of the service instance a
+	// resource was created for.
+	InstanceIdLabel = "pcf-instance-id"
 )
 
 var (
@@ -44,16 +51,7 @@ var (
 )
 
 func init() {
-	viper.BindEnv("google.account", rootSaEnvVar)
-}
-
-func GetAuthedConfig() (*jwt.Config, error) {
-	rootCreds := GetServiceAccountJson()
-	conf, err := google.JWTConfigFromJSON([]byte(rootCreds), cloudPlatformScope)
-	if err != nil {
-		return nil, fmt.Errorf("Error initializing config from credentials: %s", err)
-	}
-	return conf, nil
+	config.Register(config.Property{Key: StaticLabelsProp, Kind: config.Json})
 }
 
 // PrettyPrintOrExit writes a JSON serialized version of the content to stdout.
@@ -81,12 +79,11 @@ func PrettyPrintOrErr(content interface{}) error {
 }
 
 func prettyPrint(content interface{}) error {
-	prettyResults, err := json.MarshalIndent(content, "", "    ")
-	if err == nil {
-		fmt.Println(string(prettyResults))
-	}
-
-	return err
+	// e.g. redacted values are shown as <redacted> rather than escaped
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(content)
 }
 
 // PropertyToEnv converts a Viper configuration property name into an
@@ -154,23 +151,6 @@ func jsonDiff(superset, subset json.RawMessage) ([]byte, error) {
 	return json.Marshal(remainder)
 }
 
-// GetDefaultProject gets the default project id for the service broker based
-// on the JSON Service Account key.
-func GetDefaultProjectId() (string, error) {
-	serviceAccount := make(map[string]string)
-	if err := json.Unmarshal([]byte(GetServiceAccountJson()), &serviceAccount); err != nil {
-		return "", fmt.Errorf("could not unmarshal service account details. %v", err)
-	}
-
-	return serviceAccount["project_id"], nil
-}
-
-// GetServiceAccountJson gets the raw JSON credentials of the Service Account
-// the service broker acts as.
-func GetServiceAccountJson() string {
-	return viper.GetString("google.account")
-}
-
 // ExtractDefaultLabels creates a map[string]string of labels that should be
 // applied to a resource on creation if the resource supports labels.
 // These include the organization, space, and instance id.
@@ -178,37 +158,101 @@ func ExtractDefaultProvisionLabels(instanceId string, details brokerapi.Provisio
 	labels := map[string]string{
 		"pcf-organization-guid": details.OrganizationGUID,
 		"pcf-space-guid":        details.SpaceGUID,
-		"pcf-instance-id":       instanceId,
+		InstanceIdLabel:         instanceId,
 	}
 
 	// After v 2.14 of the OSB the top-level organization_guid and space_guid are
 	// deprecated in favor of context, so we'll override those.
-	requestContext := map[string]string{}
-	json.Unmarshal(details.GetRawContext(), &requestContext) // explicitly ignore parse errors
-	if orgGuid, ok := requestContext["organization_guid"]; ok {
-		labels["pcf-organization-guid"] = orgGuid
-	}
-
-	if spaceGuid, ok := requestContext["space_guid"]; ok {
-		labels["pcf-space-guid"] = spaceGuid
+	requestContext := platformcontext.Parse(details.GetRawContext())
+	if requestContext.OrganizationGuid != "" {
+		labels["pcf-organization-guid"] = requestContext.OrganizationGuid
 	}
 
-	sanitized := map[string]string{}
-	for key, value := range labels {
-		sanitized[key] = invalidLabelChars.ReplaceAllString(value, "_")
+	if requestContext.SpaceGuid != "" {
+		labels["pcf-space-guid"] = requestContext.SpaceGuid
 	}
 
-	return sanitized
+	return SanitizeLabels(withStaticLabels(withContextLabels(labels, requestContext)))
 }
 
 func ExtractDefaultUpdateLabels(instanceId string, details brokerapi.UpdateDetails) map[string]string {
 	labels := map[string]string{
 		"pcf-organization-guid": details.PreviousValues.OrgID,
 		"pcf-space-guid":        details.PreviousValues.SpaceID,
-		"pcf-instance-id":       instanceId,
+		InstanceIdLabel:         instanceId,
+	}
+
+	requestContext := platformcontext.Parse(details.RawContext)
+	return SanitizeLabels(withStaticLabels(withContextLabels(labels, requestContext)))
+}
+
+// MergeLabels returns the recorded labels with the updated ones applied.
+// Labels whose updated value is empty keep their recorded value because
+// platforms often leave the previous values of updates blank.
+func MergeLabels(recorded, updated map[string]string) map[string]string {
+	merged := map[string]string{}
+	for key, value := range recorded {
+		merged[key] = value
 	}
+
+	for key, value := range updated {
+		if _, ok := merged[key]; ok && value == "" {
+			continue
+		}
+
+		merged[key] = value
+	}
+
+	return merged
+}
+
+// withContextLabels adds the labels of the names and platform in the
+// request's context object to the given set. Names are lowercased because
+// label values can't have capitals.
+func withContextLabels(labels map[string]string, requestContext platformcontext.Context) map[string]string {
+	for key, value := range requestContext.Labels() {
+		labels[key] = strings.ToLower(value)
+	}
+
+	return labels
+}
+
+// StaticLabels gets the operator-defined labels from the StaticLabelsProp
+// configuration value. Invalid configuration results in no labels.
+func StaticLabels() map[string]string {
+	raw := viper.Get(StaticLabelsProp)
+	if raw == nil || raw == "" {
+		return map[string]string{}
+	}
+
+	labels, err := cast.ToStringMapStringE(raw)
+	if err != nil {
+		log.Printf("couldn't parse %s, ignoring static labels: %v", StaticLabelsProp, err)
+		return map[string]string{}
+	}
+
+	return labels
+}
+
+// withStaticLabels adds the operator-defined static labels to the given set.
+// Labels computed by the broker take precedence so tooling can always rely on
+// them.
+func withStaticLabels(labels map[string]string) map[string]string {
+	for key, value := range StaticLabels() {
+		if _, ok := labels[key]; !ok {
+			labels[key] = value
+		}
+	}
+
+	return labels
+}
+
+// SanitizeLabels replaces the characters GCP doesn't allow in label keys and
+// values with underscores, and lowercases the keys.
+func SanitizeLabels(labels map[string]string) map[string]string {
 	sanitized := map[string]string{}
 	for key, value := range labels {
+		key = invalidLabelChars.ReplaceAllString(strings.ToLower(key), "_")
 		sanitized[key] = invalidLabelChars.ReplaceAllString(value, "_")
 	}
 
@@ -230,13 +274,15 @@ func SingleLineErrorFormatter(es []error) string {
 func NewLogger(name string) lager.Logger {
 	logger := lager.NewLogger(name)
 
-	logLevel := lager.INFO
-    if _, debug := os.LookupEnv("GSB_DEBUG"); debug {
-        logLevel = lager.DEBUG
+	logLevel, levelErr := logLevelFromEnv()
+	newSink := logSinkFromEnv()
+
+	logger.RegisterSink(newSink(os.Stderr, lager.ERROR))
+	logger.RegisterSink(newSink(os.Stdout, logLevel))
+
+	if levelErr != nil {
+		logger.Error("parsing-log-level", levelErr)
 	}
-		
-	logger.RegisterSink(lager.NewWriterSink(os.Stderr, lager.ERROR))
-	logger.RegisterSink(lager.NewWriterSink(os.Stdout, logLevel))
 
 	return logger
 }
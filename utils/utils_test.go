@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/pivotal-cf/brokerapi"
+	"github.com/spf13/viper"
 )
 
 func ExamplePropertyToEnv() {
@@ -89,9 +90,10 @@ func ExampleGetDefaultProjectId() {
 
 func TestExtractDefaultProvisionLabels(t *testing.T) {
 	tests := map[string]struct {
-		instanceId string
-		details    brokerapi.ProvisionDetails
-		expected   map[string]string
+		instanceId   string
+		details      brokerapi.ProvisionDetails
+		staticLabels string
+		expected     map[string]string
 	}{
 		"empty everything": {
 			instanceId: "",
@@ -124,6 +126,34 @@ func TestExtractDefaultProvisionLabels(t *testing.T) {
 				"pcf-instance-id":       "my-instance",
 			},
 		},
+		"cloud foundry context names": {
+			instanceId: "my-instance",
+			details: brokerapi.ProvisionDetails{
+				RawContext: json.RawMessage(`{"platform":"cloudfoundry","organization_guid":"org-guid","organization_name":"Eng","space_guid":"space-guid","space_name":"dev","organization_annotations":{}}`),
+			},
+			expected: map[string]string{
+				"pcf-organization-guid": "org-guid",
+				"pcf-space-guid":        "space-guid",
+				"pcf-instance-id":       "my-instance",
+				"platform":              "cloudfoundry",
+				"pcf-organization-name": "eng",
+				"pcf-space-name":        "dev",
+			},
+		},
+		"kubernetes context": {
+			instanceId: "my-instance",
+			details: brokerapi.ProvisionDetails{
+				RawContext: json.RawMessage(`{"platform":"kubernetes","namespace":"payments","clusterid":"cluster-1"}`),
+			},
+			expected: map[string]string{
+				"pcf-organization-guid": "",
+				"pcf-space-guid":        "",
+				"pcf-instance-id":       "my-instance",
+				"platform":              "kubernetes",
+				"k8s-namespace":         "payments",
+				"k8s-cluster-id":        "cluster-1",
+			},
+		},
 		"osb special characters": {
 			instanceId: "my~instance.",
 			details:    brokerapi.ProvisionDetails{},
@@ -133,9 +163,32 @@ func TestExtractDefaultProvisionLabels(t *testing.T) {
 				"pcf-instance-id":       "my_instance_",
 			},
 		},
+		"operator static labels": {
+			instanceId:   "my-instance",
+			details:      brokerapi.ProvisionDetails{},
+			staticLabels: `{"Cost-Center":"eng.42", "pcf-instance-id":"ignored"}`,
+			expected: map[string]string{
+				"pcf-organization-guid": "",
+				"pcf-space-guid":        "",
+				"pcf-instance-id":       "my-instance",
+				"cost-center":           "eng_42",
+			},
+		},
+		"invalid static labels": {
+			instanceId:   "my-instance",
+			details:      brokerapi.ProvisionDetails{},
+			staticLabels: `not-json`,
+			expected: map[string]string{
+				"pcf-organization-guid": "",
+				"pcf-space-guid":        "",
+				"pcf-instance-id":       "my-instance",
+			},
+		},
 	}
 
+	defer viper.Set(StaticLabelsProp, nil)
 	for tn, tc := range tests {
+		viper.Set(StaticLabelsProp, tc.staticLabels)
 		labels := ExtractDefaultProvisionLabels(tc.instanceId, tc.details)
 
 		if !reflect.DeepEqual(labels, tc.expected) {
@@ -144,6 +197,33 @@ func TestExtractDefaultProvisionLabels(t *testing.T) {
 	}
 }
 
+func TestMergeLabels(t *testing.T) {
+	recorded := map[string]string{
+		"pcf-organization-guid": "org-guid",
+		"pcf-space-guid":        "space-guid",
+		"pcf-instance-id":       "my-instance",
+		"cost-center":           "eng",
+	}
+	updated := map[string]string{
+		"pcf-organization-guid": "",
+		"pcf-space-guid":        "new-space-guid",
+		"pcf-instance-id":       "my-instance",
+		"team":                  "",
+	}
+
+	expected := map[string]string{
+		"pcf-organization-guid": "org-guid",
+		"pcf-space-guid":        "new-space-guid",
+		"pcf-instance-id":       "my-instance",
+		"cost-center":           "eng",
+		"team":                  "",
+	}
+
+	if actual := MergeLabels(recorded, updated); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected labels %v, got %v", expected, actual)
+	}
+}
+
 func TestSplitNewlineDelimitedList(t *testing.T) {
 	cases := map[string]struct {
 		Input    string